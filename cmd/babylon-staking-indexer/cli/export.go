@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/export"
+	"github.com/spf13/cobra"
+)
+
+// ExportCmd streams collections into files for offline analysis.
+// In order to run it you need to call binary with this command + config flag like this:
+// ./babylon-staking-indexer export --dataset delegations --format csv --state ACTIVE --config config.yml
+func ExportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export delegations, finality providers and stats into CSV, NDJSON or Parquet files",
+		Run:   runAndExit("Failed to export data", exportE),
+	}
+
+	cmd.Flags().StringSlice("dataset", []string{export.DatasetDelegations},
+		fmt.Sprintf("Datasets to export, one file per dataset (%s)", strings.Join(export.DatasetNames(), ", ")))
+	cmd.Flags().String("format", string(export.FormatCSV), "Output format (csv, ndjson, parquet)")
+	cmd.Flags().StringSlice("fields", nil, "Fields to export using dot notation for nested ones (default all fields)")
+	cmd.Flags().String("output-dir", ".", "Directory where files are written")
+	cmd.Flags().Bool("snapshot", true, "Read all datasets from the same point in time (export must finish within mongo snapshot history window)")
+	cmd.Flags().Int32("batch-size", 0, "Number of documents fetched from mongo in a single batch (0 means default)")
+
+	cmd.Flags().StringSlice("state", nil, "Filter by state")
+	cmd.Flags().String("fp", "", "Filter by finality provider BTC public key")
+	cmd.Flags().Uint32("params-version", 0, "Filter delegations by params version")
+	cmd.Flags().Uint64("from-height", 0, "Filter delegations created at or after this BBN height")
+	cmd.Flags().Uint64("to-height", 0, "Filter delegations created at or before this BBN height")
+
+	return cmd
+}
+
+func exportE(cmd *cobra.Command, _ []string) error {
+	ctx := cmd.Context()
+	flags := cmd.Flags()
+
+	datasets, err := flags.GetStringSlice("dataset")
+	if err != nil {
+		return err
+	}
+	formatStr, err := flags.GetString("format")
+	if err != nil {
+		return err
+	}
+	format, err := export.ParseFormat(formatStr)
+	if err != nil {
+		return err
+	}
+	fields, err := flags.GetStringSlice("fields")
+	if err != nil {
+		return err
+	}
+	outputDir, err := flags.GetString("output-dir")
+	if err != nil {
+		return err
+	}
+	snapshot, err := flags.GetBool("snapshot")
+	if err != nil {
+		return err
+	}
+	batchSize, err := flags.GetInt32("batch-size")
+	if err != nil {
+		return err
+	}
+
+	var filter export.Filter
+	filter.States, err = flags.GetStringSlice("state")
+	if err != nil {
+		return err
+	}
+	filter.FinalityProviderBtcPkHex, err = flags.GetString("fp")
+	if err != nil {
+		return err
+	}
+	if flags.Changed("params-version") {
+		version, err := flags.GetUint32("params-version")
+		if err != nil {
+			return err
+		}
+		filter.ParamsVersion = &version
+	}
+	filter.FromHeight, err = flags.GetUint64("from-height")
+	if err != nil {
+		return err
+	}
+	filter.ToHeight, err = flags.GetUint64("to-height")
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.New(GetConfigPath())
+	if err != nil {
+		return err
+	}
+
+	database, err := model.Connect(ctx, &cfg.Db)
+	if err != nil {
+		return err
+	}
+	defer database.Client().Disconnect(ctx) //nolint:errcheck
+
+	return export.Export(ctx, database, export.Options{
+		Datasets:  datasets,
+		Format:    format,
+		Fields:    fields,
+		Filter:    filter,
+		OutputDir: outputDir,
+		Snapshot:  snapshot,
+		BatchSize: batchSize,
+	})
+}
@@ -0,0 +1,87 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/spf13/cobra"
+)
+
+// IndexesCmd groups commands reconciling database indexes with definitions in the code.
+// In order to run it you need to call binary with this command + config flag like this:
+// ./babylon-staking-indexer indexes plan --config config.yml
+func IndexesCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "indexes",
+		Short: "Manage database indexes",
+	}
+
+	planCmd := &cobra.Command{
+		Use:   "plan",
+		Short: "Show index changes required to match definitions without applying them",
+		Run:   runAndExit("Failed to plan index changes", indexesPlanE),
+	}
+
+	applyCmd := &cobra.Command{
+		Use:   "apply",
+		Short: "Create, update and drop indexes to match definitions",
+		Run:   runAndExit("Failed to apply index changes", indexesApplyE),
+	}
+
+	cmd.AddCommand(planCmd, applyCmd)
+	return cmd
+}
+
+func indexesPlanE(cmd *cobra.Command, _ []string) error {
+	ctx := cmd.Context()
+
+	cfg, err := config.New(GetConfigPath())
+	if err != nil {
+		return err
+	}
+
+	database, err := model.Connect(ctx, &cfg.Db)
+	if err != nil {
+		return err
+	}
+	defer database.Client().Disconnect(ctx) //nolint:errcheck
+
+	changes, err := model.PlanIndexes(ctx, database)
+	if err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+	if len(changes) == 0 {
+		fmt.Fprintln(out, "Indexes are up to date")
+		return nil
+	}
+	for _, change := range changes {
+		fmt.Fprintln(out, change)
+	}
+
+	return nil
+}
+
+func indexesApplyE(cmd *cobra.Command, _ []string) error {
+	ctx := cmd.Context()
+
+	cfg, err := config.New(GetConfigPath())
+	if err != nil {
+		return err
+	}
+
+	database, err := model.Connect(ctx, &cfg.Db)
+	if err != nil {
+		return err
+	}
+	defer database.Client().Disconnect(ctx) //nolint:errcheck
+
+	changes, err := model.PlanIndexes(ctx, database)
+	if err != nil {
+		return err
+	}
+
+	return model.ApplyIndexes(ctx, database, changes)
+}
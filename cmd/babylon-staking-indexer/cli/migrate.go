@@ -0,0 +1,194 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/bbnclient"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/btcclient"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/migrations"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// MigrateCmd groups commands managing data migrations.
+// In order to run it you need to call binary with this command + config flag like this:
+// ./babylon-staking-indexer migrate up --config config.yml
+func MigrateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Manage data migrations",
+	}
+
+	statusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show applied and pending migrations",
+		Run:   runAndExit("Failed to get migrations status", migrateStatusE),
+	}
+
+	upCmd := &cobra.Command{
+		Use:   "up",
+		Short: "Apply pending migrations",
+		Run:   runAndExit("Failed to apply migrations", migrateUpE),
+	}
+	upCmd.Flags().Bool("dry-run", false, "Run in simulation mode without making changes")
+	upCmd.Flags().Int("batch-size", 0, "Number of documents processed in a single batch (0 means default)")
+	upCmd.Flags().Uint64("to", 0, "Apply migrations up to this version (0 means latest)")
+
+	downCmd := &cobra.Command{
+		Use:   "down",
+		Short: "Revert applied migrations",
+		Run:   runAndExit("Failed to revert migrations", migrateDownE),
+	}
+	downCmd.Flags().Bool("dry-run", false, "Run in simulation mode without making changes")
+	downCmd.Flags().Int("batch-size", 0, "Number of documents processed in a single batch (0 means default)")
+	downCmd.Flags().Uint64("to", 0, "Revert migrations with version greater than this one (0 means only the last applied)")
+
+	cmd.AddCommand(statusCmd, upCmd, downCmd)
+	return cmd
+}
+
+// runAndExit wraps command so the process stops after it's done,
+// because of current architecture otherwise existing main logic will be called
+func runAndExit(errMsg string, f func(cmd *cobra.Command, args []string) error) func(cmd *cobra.Command, args []string) {
+	return func(cmd *cobra.Command, args []string) {
+		err := f(cmd, args)
+		if err != nil {
+			log.Err(err).Msg(errMsg)
+			os.Exit(1)
+		}
+
+		os.Exit(0)
+	}
+}
+
+func migrateStatusE(cmd *cobra.Command, _ []string) error {
+	ctx := cmd.Context()
+
+	cfg, err := config.New(GetConfigPath())
+	if err != nil {
+		return err
+	}
+
+	runner, err := migrations.New(ctx, cfg.Db, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer runner.Close(ctx)
+
+	statuses, err := runner.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0) //nolint:mnd
+	fmt.Fprintln(w, "VERSION\tSTATUS\tAPPLIED AT\tDESCRIPTION")
+	for _, status := range statuses {
+		state, appliedAt := "pending", "-"
+		if status.Optional {
+			state = "pending (optional)"
+		}
+		switch {
+		case status.Applied:
+			state, appliedAt = "applied", status.AppliedAt.Format(time.RFC3339)
+		case status.InProgress:
+			state = "in progress"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", status.Version, state, appliedAt, status.Description)
+	}
+
+	return w.Flush()
+}
+
+func migrateUpE(cmd *cobra.Command, _ []string) error {
+	ctx := cmd.Context()
+
+	opts, err := runOptionsFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.New(GetConfigPath())
+	if err != nil {
+		return err
+	}
+
+	bbnClient, err := bbnclient.NewBBNClient(&cfg.BBN)
+	if err != nil {
+		return err
+	}
+
+	btcClient, err := btcclient.NewClient(&cfg.BTC)
+	if err != nil {
+		return err
+	}
+
+	runner, err := migrations.New(ctx, cfg.Db, bbnClient, btcClient)
+	if err != nil {
+		return err
+	}
+	defer runner.Close(ctx)
+
+	return runner.Up(ctx, opts)
+}
+
+func migrateDownE(cmd *cobra.Command, _ []string) error {
+	ctx := cmd.Context()
+
+	opts, err := runOptionsFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.New(GetConfigPath())
+	if err != nil {
+		return err
+	}
+
+	bbnClient, err := bbnclient.NewBBNClient(&cfg.BBN)
+	if err != nil {
+		return err
+	}
+
+	btcClient, err := btcclient.NewClient(&cfg.BTC)
+	if err != nil {
+		return err
+	}
+
+	runner, err := migrations.New(ctx, cfg.Db, bbnClient, btcClient)
+	if err != nil {
+		return err
+	}
+	defer runner.Close(ctx)
+
+	return runner.Down(ctx, opts)
+}
+
+func runOptionsFromFlags(cmd *cobra.Command) (migrations.RunOptions, error) {
+	dryRun, err := cmd.Flags().GetBool("dry-run")
+	if err != nil {
+		return migrations.RunOptions{}, err
+	}
+
+	batchSize, err := cmd.Flags().GetInt("batch-size")
+	if err != nil {
+		return migrations.RunOptions{}, err
+	}
+	if batchSize < 0 {
+		return migrations.RunOptions{}, fmt.Errorf("batch size must not be negative")
+	}
+
+	target, err := cmd.Flags().GetUint64("to")
+	if err != nil {
+		return migrations.RunOptions{}, err
+	}
+
+	return migrations.RunOptions{
+		Target:    target,
+		DryRun:    dryRun,
+		BatchSize: batchSize,
+	}, nil
+}
@@ -27,7 +27,10 @@ func Setup() error {
 
 	defaultConfigPath := getDefaultConfigFile(homePath, defaultConfigFileName)
 
-	rootCmd.AddCommand(FillStakerAddrCmd())
+	rootCmd.AddCommand(MigrateCmd())
+	rootCmd.AddCommand(IndexesCmd())
+	rootCmd.AddCommand(ExportCmd())
+	rootCmd.AddCommand(SnapshotCmd())
 	rootCmd.PersistentFlags().StringVar(&cfgPath, "config", defaultConfigPath, fmt.Sprintf("config file (default %s)", defaultConfigPath))
 	if err := rootCmd.Execute(); err != nil {
 		return err
@@ -0,0 +1,130 @@
+package cli
+
+import (
+	"errors"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/bbnclient"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/snapshot"
+	"github.com/spf13/cobra"
+)
+
+// SnapshotCmd groups commands creating and restoring database snapshots used to bootstrap new instances.
+// In order to run it you need to call binary with this command + config flag like this:
+// ./babylon-staking-indexer snapshot create --file snapshot.tar.gz --config config.yml
+func SnapshotCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "snapshot",
+		Short: "Create and restore database snapshots",
+	}
+
+	createCmd := &cobra.Command{
+		Use: "create",
+		Short: "Write consistent checksummed archive of all indexer collections " +
+			"(must finish within mongo snapshot history window)",
+		Run: runAndExit("Failed to create snapshot", snapshotCreateE),
+	}
+	createCmd.Flags().String("file", "", "Path of the archive to create")
+
+	restoreCmd := &cobra.Command{
+		Use:   "restore",
+		Short: "Load archive into empty database after verifying checksums and BBN chain ID",
+		Run:   runAndExit("Failed to restore snapshot", snapshotRestoreE),
+	}
+	restoreCmd.Flags().String("file", "", "Path of the archive to restore")
+
+	verifyCmd := &cobra.Command{
+		Use:   "verify",
+		Short: "Verify archive checksums without connecting to the database",
+		Run:   runAndExit("Failed to verify snapshot", snapshotVerifyE),
+	}
+	verifyCmd.Flags().String("file", "", "Path of the archive to verify")
+
+	cmd.AddCommand(createCmd, restoreCmd, verifyCmd)
+	return cmd
+}
+
+func snapshotFileFlag(cmd *cobra.Command) (string, error) {
+	file, err := cmd.Flags().GetString("file")
+	if err != nil {
+		return "", err
+	}
+	if file == "" {
+		return "", errors.New("file flag is required")
+	}
+	return file, nil
+}
+
+func snapshotCreateE(cmd *cobra.Command, _ []string) error {
+	ctx := cmd.Context()
+
+	file, err := snapshotFileFlag(cmd)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.New(GetConfigPath())
+	if err != nil {
+		return err
+	}
+
+	database, err := model.Connect(ctx, &cfg.Db)
+	if err != nil {
+		return err
+	}
+	defer database.Client().Disconnect(ctx) //nolint:errcheck
+
+	_, err = snapshot.Create(ctx, database, file)
+	return err
+}
+
+func snapshotRestoreE(cmd *cobra.Command, _ []string) error {
+	ctx := cmd.Context()
+
+	file, err := snapshotFileFlag(cmd)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.New(GetConfigPath())
+	if err != nil {
+		return err
+	}
+
+	database, err := model.Connect(ctx, &cfg.Db)
+	if err != nil {
+		return err
+	}
+	defer database.Client().Disconnect(ctx) //nolint:errcheck
+
+	bbnClient, err := bbnclient.NewBBNClient(&cfg.BBN)
+	if err != nil {
+		return err
+	}
+
+	// restored network info is checked against BBN node again on indexer startup
+	chainID, err := bbnClient.GetChainID(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = snapshot.Restore(ctx, database, file, chainID)
+	return err
+}
+
+func snapshotVerifyE(cmd *cobra.Command, _ []string) error {
+	file, err := snapshotFileFlag(cmd)
+	if err != nil {
+		return err
+	}
+
+	manifest, err := snapshot.Verify(file)
+	if err != nil {
+		return err
+	}
+
+	cmd.Printf("Snapshot is valid: chain ID %s, last processed height %d, created at %s\n",
+		manifest.ChainID, manifest.LastProcessedHeight, manifest.CreatedAt)
+	return nil
+}
@@ -3,21 +3,26 @@ package main
 import (
 	"context"
 	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/joho/godotenv"
 	"github.com/rs/zerolog/log"
 	"go.uber.org/zap"
 
 	"github.com/babylonlabs-io/babylon-staking-indexer/cmd/babylon-staking-indexer/cli"
+	"github.com/babylonlabs-io/babylon-staking-indexer/consumer"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/api"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/bbnclient"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/btcclient"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/migrations"
 	dbmodel "github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/tracing"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/services"
-	"github.com/babylonlabs-io/staking-queue-client/queuemngr"
 )
 
 func init() {
@@ -27,7 +32,9 @@ func init() {
 }
 
 func main() {
-	ctx := context.Background()
+	// cancelled on shutdown signal, so that the API server is shut down gracefully
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	ctx = tracing.InjectTraceID(ctx)
 	log := log.Ctx(ctx)
@@ -49,6 +56,36 @@ func main() {
 		log.Fatal().Err(err).Msg("error while setting up staking db model")
 	}
 
+	// refuse to start on outdated data, migrations must be applied with "migrate up" command first
+	migrationRunner, err := migrations.New(ctx, cfg.Db, nil, nil)
+	if err != nil {
+		log.Fatal().Err(err).Msg("error while creating migrations runner")
+	}
+	err = migrationRunner.Baseline(ctx)
+	if err != nil {
+		log.Fatal().Err(err).Msg("error while setting migrations baseline")
+	}
+	pendingMigrations, err := migrationRunner.Pending(ctx)
+	if err != nil {
+		log.Fatal().Err(err).Msg("error while checking pending migrations")
+	}
+	for _, m := range pendingMigrations {
+		if !m.Optional {
+			log.Fatal().
+				Uint64("version", m.Version).
+				Int("count", len(pendingMigrations)).
+				Msg("there are pending migrations, run \"migrate up\" command before starting the indexer")
+		}
+		log.Warn().
+			Uint64("version", m.Version).
+			Str("description", m.Description).
+			Msg("optional migration is pending, run \"migrate up\" command to apply it")
+	}
+	err = migrationRunner.Close(ctx)
+	if err != nil {
+		log.Fatal().Err(err).Msg("error while closing migrations runner")
+	}
+
 	// create new db client
 	var dbClient db.DbInterface
 	dbClient, err = db.New(ctx, cfg.Db)
@@ -68,16 +105,32 @@ func main() {
 		}
 	}()
 
-	queueConsumer, err := queuemngr.NewQueueManager(&cfg.Queue, zapLogger)
+	queueConsumer, err := consumer.NewQueueManager(&cfg.Queue, zapLogger)
 	if err != nil {
 		log.Fatal().Err(err).Msg("failed to initialize event consumer")
 	}
 
-	var btcClient btcclient.BtcInterface
-	btcClient, err = btcclient.NewBTCClient(&cfg.BTC)
+	btcClient, err := btcclient.NewClient(&cfg.BTC)
 	if err != nil {
 		log.Fatal().Err(err).Msg("error while creating btc client")
 	}
+
+	var btcNotifier services.BtcNotifier
+	switch cfg.BTC.GetBackend() {
+	case config.BTCBackendEsplora:
+		btcNotifier, err = btcclient.NewEsploraNotifier(&cfg.BTC)
+		if err != nil {
+			log.Fatal().Err(err).Msg("error while creating btc notifier")
+		}
+	default:
+		btcNotifier, err = btcclient.NewBTCNotifier(
+			&cfg.BTC,
+			&btcclient.EmptyHintCache{},
+		)
+		if err != nil {
+			log.Fatal().Err(err).Msg("error while creating btc notifier")
+		}
+	}
 	btcClient = btcclient.NewBTCClientWithMetrics(btcClient)
 
 	bbnClient, err := bbnclient.NewBBNClient(&cfg.BBN)
@@ -86,14 +139,6 @@ func main() {
 	}
 	bbnClient = bbnclient.NewBBNClientWithMetrics(bbnClient)
 
-	btcNotifier, err := btcclient.NewBTCNotifier(
-		&cfg.BTC,
-		&btcclient.EmptyHintCache{},
-	)
-	if err != nil {
-		log.Fatal().Err(err).Msg("error while creating btc notifier")
-	}
-
 	service := services.NewService(cfg, dbClient, btcClient, btcNotifier, bbnClient, queueConsumer)
 	if err != nil {
 		log.Fatal().Err(err).Msg("error while creating service")
@@ -103,8 +148,20 @@ func main() {
 	metricsPort := cfg.Metrics.GetMetricsPort()
 	metrics.Init(metricsPort)
 
+	var apiServer *api.Server
+	if cfg.API.Enabled() {
+		apiServer = api.New(&cfg.API, service, dbClient)
+		if err := apiServer.Start(ctx); err != nil {
+			log.Fatal().Err(err).Msg("error while starting api server")
+		}
+	}
+
 	err = service.StartIndexerSync(ctx)
-	if err != nil {
+	if err != nil && ctx.Err() == nil {
 		log.Fatal().Err(err).Msg("error while starting indexer sync")
 	}
+	if apiServer != nil {
+		stop()
+		<-apiServer.Done()
+	}
 }
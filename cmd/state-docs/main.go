@@ -0,0 +1,27 @@
+// Command state-docs generates delegation state transition docs from types.Transitions
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+)
+
+func main() {
+	out := flag.String("out", "docs/states/transitions.md", "path of the generated document")
+	flag.Parse()
+
+	var buf bytes.Buffer
+	if err := types.WriteTransitionDocs(&buf); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to render transition docs: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := os.WriteFile(*out, buf.Bytes(), 0o644); err != nil { //nolint:gosec
+		fmt.Fprintf(os.Stderr, "failed to write %s: %v\n", *out, err)
+		os.Exit(1)
+	}
+}
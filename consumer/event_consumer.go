@@ -11,5 +11,6 @@ type EventConsumer interface {
 	Start() error
 	PushActiveStakingEvent(ctx context.Context, ev *client.StakingEvent) error
 	PushUnbondingStakingEvent(ctx context.Context, ev *client.StakingEvent) error
+	PushExpiringSoonStakingEvent(ctx context.Context, ev *ExpiringSoonStakingEvent) error
 	Stop() error
 }
@@ -0,0 +1,75 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/babylonlabs-io/staking-queue-client/client"
+	"github.com/babylonlabs-io/staking-queue-client/config"
+	"github.com/babylonlabs-io/staking-queue-client/queuemngr"
+	"go.uber.org/zap"
+)
+
+const pingTimeout = 5 * time.Second
+
+// QueueManager adds the queues of the indexer to the staking queues of queuemngr.QueueManager
+type QueueManager struct {
+	*queuemngr.QueueManager
+	ExpiringSoonStakingQueue client.QueueClient
+	logger                   *zap.Logger
+}
+
+func NewQueueManager(cfg *config.QueueConfig, logger *zap.Logger) (*QueueManager, error) {
+	stakingQueues, err := queuemngr.NewQueueManager(cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	expiringSoonStakingQueue, err := client.NewQueueClient(cfg, ExpiringSoonStakingQueueName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create expiring soon staking queue: %w", err)
+	}
+
+	return &QueueManager{
+		QueueManager:             stakingQueues,
+		ExpiringSoonStakingQueue: expiringSoonStakingQueue,
+		logger:                   logger.With(zap.String("module", "queue consumer")),
+	}, nil
+}
+
+func (qm *QueueManager) PushExpiringSoonStakingEvent(ctx context.Context, ev *ExpiringSoonStakingEvent) error {
+	qm.logger.Debug("pushing expiring soon staking event", zap.String("tx_hash", ev.StakingTxHashHex))
+
+	jsonBytes, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	if err := qm.ExpiringSoonStakingQueue.SendMessage(ctx, string(jsonBytes)); err != nil {
+		return fmt.Errorf("failed to push expiring soon staking event: %w", err)
+	}
+
+	qm.logger.Debug("successfully pushed expiring soon staking event", zap.String("tx_hash", ev.StakingTxHashHex))
+	return nil
+}
+
+func (qm *QueueManager) Stop() error {
+	return errors.Join(qm.QueueManager.Stop(), qm.ExpiringSoonStakingQueue.Stop())
+}
+
+// Ping checks the health of the RabbitMQ infrastructure.
+func (qm *QueueManager) Ping() error {
+	if err := qm.QueueManager.Ping(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+	defer cancel()
+	if err := qm.ExpiringSoonStakingQueue.Ping(ctx); err != nil {
+		qm.logger.Error("ping failed", zap.String("queue", ExpiringSoonStakingQueueName), zap.Error(err))
+		return err
+	}
+	return nil
+}
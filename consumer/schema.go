@@ -0,0 +1,58 @@
+package consumer
+
+import "github.com/babylonlabs-io/staking-queue-client/client"
+
+const ExpiringSoonStakingQueueName string = "v2_expiring_soon_staking_queue"
+
+// ExpiringSoonStakingEventType continues event types of the staking queue client
+const ExpiringSoonStakingEventType client.EventType = 3
+
+// Event schema versions, only increment when the schema changes
+const ExpiringSoonStakingEventVersion int = 0
+
+// ExpiringSoonStakingEvent is emitted once ACTIVE delegation is within ThresholdBlocks
+// BTC blocks of its expire height (end height minus unbonding time, at which babylon expires it)
+type ExpiringSoonStakingEvent struct {
+	SchemaVersion             int              `json:"schema_version"`
+	EventType                 client.EventType `json:"event_type"`
+	StakingTxHashHex          string           `json:"staking_tx_hash_hex"`
+	StakerBtcPkHex            string           `json:"staker_btc_pk_hex"`
+	FinalityProviderBtcPksHex []string         `json:"finality_provider_btc_pks_hex"`
+	StakingAmount             uint64           `json:"staking_amount"`
+	EndHeight                 uint32           `json:"end_height"`
+	ExpireHeight              uint32           `json:"expire_height"`
+	BtcTipHeight              uint32           `json:"btc_tip_height"`
+	ThresholdBlocks           uint32           `json:"threshold_blocks"`
+}
+
+func (e ExpiringSoonStakingEvent) GetEventType() client.EventType {
+	return e.EventType
+}
+
+func (e ExpiringSoonStakingEvent) GetStakingTxHashHex() string {
+	return e.StakingTxHashHex
+}
+
+func NewExpiringSoonStakingEvent(
+	stakingTxHashHex string,
+	stakerBtcPkHex string,
+	finalityProviderBtcPksHex []string,
+	stakingAmount uint64,
+	endHeight uint32,
+	expireHeight uint32,
+	btcTipHeight uint32,
+	thresholdBlocks uint32,
+) ExpiringSoonStakingEvent {
+	return ExpiringSoonStakingEvent{
+		SchemaVersion:             ExpiringSoonStakingEventVersion,
+		EventType:                 ExpiringSoonStakingEventType,
+		StakingTxHashHex:          stakingTxHashHex,
+		StakerBtcPkHex:            stakerBtcPkHex,
+		FinalityProviderBtcPksHex: finalityProviderBtcPksHex,
+		StakingAmount:             stakingAmount,
+		EndHeight:                 endHeight,
+		ExpireHeight:              expireHeight,
+		BtcTipHeight:              btcTipHeight,
+		ThresholdBlocks:           thresholdBlocks,
+	}
+}
@@ -7,8 +7,8 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/babylonlabs-io/babylon-staking-indexer/consumer"
 	"github.com/babylonlabs-io/staking-queue-client/client"
-	"github.com/babylonlabs-io/staking-queue-client/queuemngr"
 	"github.com/rabbitmq/amqp091-go"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
@@ -16,7 +16,7 @@ import (
 	"github.com/babylonlabs-io/staking-queue-client/config"
 )
 
-func setupTestQueueConsumer(t *testing.T, cfg *config.QueueConfig) (*queuemngr.QueueManager, error) {
+func setupTestQueueConsumer(t *testing.T, cfg *config.QueueConfig) (*consumer.QueueManager, error) {
 	amqpURI := fmt.Sprintf("amqp://%s:%s@%s", cfg.QueueUser, cfg.QueuePassword, cfg.Url)
 	conn, err := amqp091.Dial(amqpURI)
 	if err != nil {
@@ -25,6 +25,7 @@ func setupTestQueueConsumer(t *testing.T, cfg *config.QueueConfig) (*queuemngr.Q
 	defer conn.Close()
 	err = purgeQueues(conn, []string{
 		client.ActiveStakingQueueName,
+		consumer.ExpiringSoonStakingQueueName,
 	})
 	if err != nil {
 		return nil, err
@@ -34,7 +35,7 @@ func setupTestQueueConsumer(t *testing.T, cfg *config.QueueConfig) (*queuemngr.Q
 
 	err = cfg.Validate()
 	require.NoError(t, err)
-	queues, err := queuemngr.NewQueueManager(cfg, zap.NewNop())
+	queues, err := consumer.NewQueueManager(cfg, zap.NewNop())
 	require.NoError(t, err)
 
 	return queues, nil
@@ -11,6 +11,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/babylonlabs-io/babylon-staking-indexer/consumer"
 	"github.com/babylonlabs-io/babylon-staking-indexer/e2etest/container"
 	indexerbbnclient "github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/bbnclient"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/btcclient"
@@ -28,7 +29,6 @@ import (
 	btclctypes "github.com/babylonlabs-io/babylon/v4/x/btclightclient/types"
 	queuecli "github.com/babylonlabs-io/staking-queue-client/client"
 	queuecfg "github.com/babylonlabs-io/staking-queue-client/config"
-	"github.com/babylonlabs-io/staking-queue-client/queuemngr"
 	"github.com/btcsuite/btcd/btcec/v2"
 	"github.com/btcsuite/btcd/btcutil"
 	"github.com/btcsuite/btcd/chaincfg"
@@ -56,7 +56,7 @@ type TestManager struct {
 	Config                    *config.Config
 	manager                   *container.Manager
 	DbClient                  *db.Database
-	QueueConsumer             *queuemngr.QueueManager
+	QueueConsumer             *consumer.QueueManager
 	ActiveStakingEventChan    <-chan queuecli.QueueMessage
 	UnbondingStakingEventChan <-chan queuecli.QueueMessage
 }
@@ -138,7 +138,7 @@ func StartManager(t *testing.T, numMatureOutputsInWallet uint32, epochInterval u
 	dbClient, err := db.New(ctx, cfg.Db)
 	require.NoError(t, err)
 
-	queueConsumer, err := queuemngr.NewQueueManager(&cfg.Queue, zap.NewNop())
+	queueConsumer, err := consumer.NewQueueManager(&cfg.Queue, zap.NewNop())
 	require.NoError(t, err)
 
 	btcNotifier, err := btcclient.NewBTCNotifier(
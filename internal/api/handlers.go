@@ -0,0 +1,92 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+type handler struct {
+	withdrawals WithdrawalPsbtBuilder
+	reader      IndexerReader
+}
+
+type withdrawalPsbtRequest struct {
+	DestinationAddress string `json:"destination_address"`
+	// Fee rate in sat/vB
+	FeeRate int64 `json:"fee_rate"`
+}
+
+type withdrawalPsbtResponse struct {
+	Psbt             string `json:"psbt"`
+	SpentTxHashHex   string `json:"spent_tx_hash_hex"`
+	SpentOutputIdx   uint32 `json:"spent_output_idx"`
+	SpentAmount      int64  `json:"spent_amount"`
+	WithdrawalAmount int64  `json:"withdrawal_amount"`
+	Fee              int64  `json:"fee"`
+}
+
+type errorResponse struct {
+	ErrorCode string `json:"error_code"`
+	Message   string `json:"message"`
+}
+
+func (h *handler) buildWithdrawalPsbt(w http.ResponseWriter, r *http.Request) {
+	var req withdrawalPsbtRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, types.NewErrorWithMsg(http.StatusBadRequest, types.BadRequest, "invalid request body"))
+		return
+	}
+
+	result, err := h.withdrawals.BuildWithdrawalPsbt(
+		r.Context(),
+		chi.URLParam(r, "staking_tx_hash"),
+		req.DestinationAddress,
+		req.FeeRate,
+	)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, withdrawalPsbtResponse{
+		Psbt:             result.Psbt,
+		SpentTxHashHex:   result.SpentTxHashHex,
+		SpentOutputIdx:   result.SpentOutputIdx,
+		SpentAmount:      result.SpentAmount,
+		WithdrawalAmount: result.WithdrawalAmount,
+		Fee:              result.Fee,
+	})
+}
+
+// writeError responds with the status and code of types.Error, other errors are internal
+func writeError(w http.ResponseWriter, err error) {
+	var apiErr *types.Error
+	if !errors.As(err, &apiErr) {
+		apiErr = types.NewInternalServiceError(err)
+	}
+
+	message := apiErr.Error()
+	if apiErr.StatusCode >= http.StatusInternalServerError {
+		log.Error().Err(err).Msg("api request failed")
+		// internal details are only logged
+		message = "internal service error"
+	}
+
+	writeJSON(w, apiErr.StatusCode, errorResponse{
+		ErrorCode: apiErr.ErrorCode.String(),
+		Message:   message,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Error().Err(err).Msg("failed to write api response")
+	}
+}
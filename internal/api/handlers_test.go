@@ -0,0 +1,99 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/services"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type withdrawalPsbtBuilderFunc func(ctx context.Context, stakingTxHashHex, destinationAddress string, feeRate int64) (*services.WithdrawalPsbt, error)
+
+func (f withdrawalPsbtBuilderFunc) BuildWithdrawalPsbt(
+	ctx context.Context,
+	stakingTxHashHex string,
+	destinationAddress string,
+	feeRate int64,
+) (*services.WithdrawalPsbt, error) {
+	return f(ctx, stakingTxHashHex, destinationAddress, feeRate)
+}
+
+func TestBuildWithdrawalPsbt(t *testing.T) {
+	post := func(t *testing.T, builder withdrawalPsbtBuilderFunc, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		router := newRouter(&handler{withdrawals: builder})
+		req := httptest.NewRequest(http.MethodPost, "/v1/delegations/abcd/withdrawal-psbt", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("success", func(t *testing.T) {
+		builder := func(_ context.Context, stakingTxHashHex, destinationAddress string, feeRate int64) (*services.WithdrawalPsbt, error) {
+			assert.Equal(t, "abcd", stakingTxHashHex)
+			assert.Equal(t, "tb1qaddr", destinationAddress)
+			assert.Equal(t, int64(3), feeRate)
+			return &services.WithdrawalPsbt{
+				Psbt:             "cHNidP8=",
+				SpentTxHashHex:   "abcd",
+				SpentOutputIdx:   1,
+				SpentAmount:      10000,
+				WithdrawalAmount: 9500,
+				Fee:              500,
+			}, nil
+		}
+
+		rec := post(t, builder, `{"destination_address":"tb1qaddr","fee_rate":3}`)
+		require.Equal(t, http.StatusOK, rec.Code)
+		var resp withdrawalPsbtResponse
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		assert.Equal(t, withdrawalPsbtResponse{
+			Psbt:             "cHNidP8=",
+			SpentTxHashHex:   "abcd",
+			SpentOutputIdx:   1,
+			SpentAmount:      10000,
+			WithdrawalAmount: 9500,
+			Fee:              500,
+		}, resp)
+	})
+	t.Run("invalid body", func(t *testing.T) {
+		builder := func(context.Context, string, string, int64) (*services.WithdrawalPsbt, error) {
+			t.Fatal("builder must not be called")
+			return nil, nil
+		}
+
+		rec := post(t, builder, `{`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+	t.Run("typed error", func(t *testing.T) {
+		builder := func(context.Context, string, string, int64) (*services.WithdrawalPsbt, error) {
+			return nil, types.NewErrorWithMsg(http.StatusNotFound, types.NotFound, "delegation abcd not found")
+		}
+
+		rec := post(t, builder, `{"destination_address":"tb1qaddr","fee_rate":3}`)
+		require.Equal(t, http.StatusNotFound, rec.Code)
+		var resp errorResponse
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		assert.Equal(t, errorResponse{ErrorCode: "NOT_FOUND", Message: "delegation abcd not found"}, resp)
+	})
+	t.Run("internal error is not exposed", func(t *testing.T) {
+		builder := func(context.Context, string, string, int64) (*services.WithdrawalPsbt, error) {
+			return nil, errors.New("mongo is down")
+		}
+
+		rec := post(t, builder, `{"destination_address":"tb1qaddr","fee_rate":3}`)
+		require.Equal(t, http.StatusInternalServerError, rec.Code)
+		var resp errorResponse
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		assert.Equal(t, "INTERNAL_SERVICE_ERROR", resp.ErrorCode)
+		assert.NotContains(t, resp.Message, "mongo")
+	})
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/pkg/sdk"
+	"github.com/go-chi/chi/v5"
+)
+
+// Read endpoints respond with the read models of the public sdk, sdk.HTTPReader is their client
+
+func (h *handler) getDelegation(w http.ResponseWriter, r *http.Request) {
+	stakingTxHash := chi.URLParam(r, "staking_tx_hash")
+	delegation, err := h.reader.GetBTCDelegationByStakingTxHash(r.Context(), stakingTxHash)
+	if err != nil {
+		writeError(w, notFoundError(err, "delegation %s not found", stakingTxHash))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, delegation.ToSDK())
+}
+
+func (h *handler) getFinalityProviderDelegations(w http.ResponseWriter, r *http.Request) {
+	afterStakingTxHash, limit, err := parsePageRequest(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	delegations, err := h.reader.FindDelegationsByFinalityProvider(
+		r.Context(), chi.URLParam(r, "btc_pk"), afterStakingTxHash, limit,
+	)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	resp := sdk.DelegationsResponse{Delegations: make([]*sdk.Delegation, 0, len(delegations))}
+	for _, delegation := range delegations {
+		resp.Delegations = append(resp.Delegations, delegation.ToSDK())
+	}
+	if len(delegations) > 0 {
+		resp.NextPageToken = sdk.NextPageToken(delegations[len(delegations)-1].StakingTxHashHex, len(delegations), limit)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *handler) getFinalityProvider(w http.ResponseWriter, r *http.Request) {
+	btcPk := chi.URLParam(r, "btc_pk")
+	fp, err := h.reader.GetFinalityProviderByBtcPk(r.Context(), btcPk)
+	if err != nil {
+		writeError(w, notFoundError(err, "finality provider %s not found", btcPk))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, fp.ToSDK())
+}
+
+func (h *handler) getFinalityProviders(w http.ResponseWriter, r *http.Request) {
+	afterBtcPk, limit, err := parsePageRequest(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	fps, err := h.reader.FindFinalityProviders(r.Context(), afterBtcPk, limit)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	resp := sdk.FinalityProvidersResponse{FinalityProviders: make([]*sdk.FinalityProvider, 0, len(fps))}
+	for _, fp := range fps {
+		resp.FinalityProviders = append(resp.FinalityProviders, fp.ToSDK())
+	}
+	if len(fps) > 0 {
+		resp.NextPageToken = sdk.NextPageToken(fps[len(fps)-1].BtcPk, len(fps), limit)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// parsePageRequest returns id the requested page follows and its limit from the limit
+// and page_token query parameters
+func parsePageRequest(r *http.Request) (string, int64, error) {
+	var page sdk.PageRequest
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		var err error
+		page.Limit, err = strconv.Atoi(limit)
+		if err != nil {
+			return "", 0, types.NewErrorWithMsg(http.StatusBadRequest, types.BadRequest, "invalid limit")
+		}
+	}
+	page.Token = r.URL.Query().Get("page_token")
+
+	afterID, limit, err := sdk.ParsePageRequest(page)
+	if err != nil {
+		return "", 0, types.NewErrorWithMsg(http.StatusBadRequest, types.BadRequest, err.Error())
+	}
+	return afterID, limit, nil
+}
+
+// notFoundError maps db.NotFoundError into the not found response, other errors are kept
+func notFoundError(err error, format string, args ...any) error {
+	if db.IsNotFoundError(err) {
+		return types.NewErrorWithMsg(http.StatusNotFound, types.NotFound, fmt.Sprintf(format, args...))
+	}
+	return err
+}
@@ -0,0 +1,140 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/pkg/sdk"
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// TestReadEndpoints checks the read endpoints with their sdk client
+func TestReadEndpoints(t *testing.T) {
+	ctx := t.Context()
+
+	delegation := &model.BTCDelegationDetails{
+		StakingTxHashHex:          "staking_tx",
+		StakingAmount:             1000,
+		FinalityProviderBtcPksHex: []string{"fp"},
+		State:                     types.StateActive,
+		StateHistory: []model.StateRecord{
+			{State: types.StatePending, BbnHeight: 10},
+			{State: types.StateActive, BtcHeight: 100},
+		},
+		BTCDelegationCreatedBlock: model.BTCDelegationCreatedBbnBlock{Height: 10, Timestamp: 1700000000},
+		Version:                   3,
+	}
+	fp := &model.FinalityProviderDetails{
+		BtcPk:       "fp",
+		Commission:  "0.1",
+		State:       string(sdk.FinalityProviderStateActive),
+		Description: model.Description{Moniker: "moniker"},
+	}
+
+	dbMock := mocks.NewDbInterface(t)
+	server := httptest.NewServer(newRouter(&handler{reader: dbMock}))
+	t.Cleanup(server.Close)
+	reader, err := sdk.NewHTTPReader(server.URL, nil)
+	require.NoError(t, err)
+
+	t.Run("delegation", func(t *testing.T) {
+		dbMock.On("GetBTCDelegationByStakingTxHash", mock.Anything, "staking_tx").Return(delegation, nil).Once()
+		actual, err := reader.Delegation(ctx, "staking_tx")
+		require.NoError(t, err)
+		assert.Equal(t, delegation.ToSDK(), actual)
+		assert.Equal(t, sdk.StateActive, actual.State)
+		assert.Len(t, actual.StateHistory, 2)
+
+		dbMock.On("GetBTCDelegationByStakingTxHash", mock.Anything, "unknown").
+			Return(nil, &db.NotFoundError{Key: "unknown", Message: "not found"}).Once()
+		_, err = reader.Delegation(ctx, "unknown")
+		assert.True(t, sdk.IsNotFound(err))
+	})
+	t.Run("finality provider delegations", func(t *testing.T) {
+		dbMock.On("FindDelegationsByFinalityProvider", mock.Anything, "fp", "", int64(sdk.DefaultPageLimit)).
+			Return([]*model.BTCDelegationDetails{delegation}, nil).Once()
+		actual, err := reader.DelegationsByFinalityProvider(ctx, "fp", sdk.PageRequest{})
+		require.NoError(t, err)
+		assert.Equal(t, []*sdk.Delegation{delegation.ToSDK()}, actual.Delegations)
+		assert.Empty(t, actual.NextPageToken)
+
+		dbMock.On("FindDelegationsByFinalityProvider", mock.Anything, "empty", "", int64(sdk.DefaultPageLimit)).
+			Return(nil, nil).Once()
+		actual, err = reader.DelegationsByFinalityProvider(ctx, "empty", sdk.PageRequest{})
+		require.NoError(t, err)
+		assert.Empty(t, actual.Delegations)
+	})
+	t.Run("pages", func(t *testing.T) {
+		// a full page is followed by the page after its last delegation
+		dbMock.On("FindDelegationsByFinalityProvider", mock.Anything, "fp", "", int64(1)).
+			Return([]*model.BTCDelegationDetails{delegation}, nil).Once()
+		first, err := reader.DelegationsByFinalityProvider(ctx, "fp", sdk.PageRequest{Limit: 1})
+		require.NoError(t, err)
+		require.NotEmpty(t, first.NextPageToken)
+
+		dbMock.On("FindDelegationsByFinalityProvider", mock.Anything, "fp", "staking_tx", int64(1)).
+			Return(nil, nil).Once()
+		second, err := reader.DelegationsByFinalityProvider(ctx, "fp", sdk.PageRequest{Limit: 1, Token: first.NextPageToken})
+		require.NoError(t, err)
+		assert.Empty(t, second.Delegations)
+		assert.Empty(t, second.NextPageToken)
+
+		dbMock.On("FindFinalityProviders", mock.Anything, "", int64(1)).
+			Return([]*model.FinalityProviderDetails{fp}, nil).Once()
+		fps, err := reader.FinalityProviders(ctx, sdk.PageRequest{Limit: 1})
+		require.NoError(t, err)
+		dbMock.On("FindFinalityProviders", mock.Anything, "fp", int64(1)).Return(nil, nil).Once()
+		_, err = reader.FinalityProviders(ctx, sdk.PageRequest{Limit: 1, Token: fps.NextPageToken})
+		require.NoError(t, err)
+	})
+	t.Run("invalid page", func(t *testing.T) {
+		for _, page := range []sdk.PageRequest{
+			{Limit: -1},
+			{Limit: sdk.MaxPageLimit + 1},
+			{Token: "not base64!"},
+		} {
+			_, err := reader.FinalityProviders(ctx, page)
+
+			var apiErr *sdk.APIError
+			require.ErrorAs(t, err, &apiErr, page)
+			assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode, page)
+		}
+	})
+	t.Run("finality provider", func(t *testing.T) {
+		dbMock.On("GetFinalityProviderByBtcPk", mock.Anything, "fp").Return(fp, nil).Once()
+		actual, err := reader.FinalityProvider(ctx, "fp")
+		require.NoError(t, err)
+		assert.Equal(t, fp.ToSDK(), actual)
+
+		dbMock.On("GetFinalityProviderByBtcPk", mock.Anything, "unknown").
+			Return(nil, &db.NotFoundError{Key: "unknown", Message: "not found"}).Once()
+		_, err = reader.FinalityProvider(ctx, "unknown")
+		assert.True(t, sdk.IsNotFound(err))
+	})
+	t.Run("finality providers", func(t *testing.T) {
+		dbMock.On("FindFinalityProviders", mock.Anything, "", int64(sdk.DefaultPageLimit)).
+			Return([]*model.FinalityProviderDetails{fp}, nil).Once()
+		actual, err := reader.FinalityProviders(ctx, sdk.PageRequest{})
+		require.NoError(t, err)
+		assert.Equal(t, []*sdk.FinalityProvider{fp.ToSDK()}, actual.FinalityProviders)
+	})
+	t.Run("internal error", func(t *testing.T) {
+		dbMock.On("FindFinalityProviders", mock.Anything, "", int64(sdk.DefaultPageLimit)).
+			Return(nil, errors.New("db is down")).Once()
+		_, err := reader.FinalityProviders(ctx, sdk.PageRequest{})
+
+		var apiErr *sdk.APIError
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, http.StatusInternalServerError, apiErr.StatusCode)
+		// internal details aren't exposed
+		assert.NotContains(t, apiErr.Message, "db is down")
+	})
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/services"
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	RequestTimeout     time.Duration = 10 * time.Second
+	RequestIdleTimeout time.Duration = 30 * time.Second
+	// ShutdownTimeout limits how long in-flight requests are waited for on shutdown
+	ShutdownTimeout time.Duration = 10 * time.Second
+)
+
+// WithdrawalPsbtBuilder builds unsigned withdrawal txs of withdrawable delegations
+type WithdrawalPsbtBuilder interface {
+	BuildWithdrawalPsbt(
+		ctx context.Context,
+		stakingTxHashHex string,
+		destinationAddress string,
+		feeRate int64,
+	) (*services.WithdrawalPsbt, error)
+}
+
+// IndexerReader reads the indexed data served by the API
+type IndexerReader interface {
+	GetBTCDelegationByStakingTxHash(ctx context.Context, stakingTxHash string) (*model.BTCDelegationDetails, error)
+	FindDelegationsByFinalityProvider(
+		ctx context.Context, fpBtcPkHex, afterStakingTxHash string, limit int64,
+	) ([]*model.BTCDelegationDetails, error)
+	GetFinalityProviderByBtcPk(ctx context.Context, btcPk string) (*model.FinalityProviderDetails, error)
+	FindFinalityProviders(ctx context.Context, afterBtcPk string, limit int64) ([]*model.FinalityProviderDetails, error)
+}
+
+type Server struct {
+	httpServer *http.Server
+	done       chan struct{}
+}
+
+// New creates the API server, it doesn't accept connections until Start is called
+func New(cfg *config.APIConfig, withdrawals WithdrawalPsbtBuilder, reader IndexerReader) *Server {
+	return &Server{
+		httpServer: &http.Server{
+			Addr:         fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
+			Handler:      newRouter(&handler{withdrawals: withdrawals, reader: reader}),
+			ReadTimeout:  RequestTimeout,
+			WriteTimeout: RequestTimeout,
+			IdleTimeout:  RequestIdleTimeout,
+		},
+		done: make(chan struct{}),
+	}
+}
+
+// Start listens on the configured address and serves the API in a separate goroutine until ctx
+// is cancelled, the server is then shut down gracefully. Listen errors are returned.
+func (s *Server) Start(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.httpServer.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.httpServer.Addr, err)
+	}
+	log.Info().Msgf("Starting api server on %s", listener.Addr())
+
+	go func() {
+		if err := s.httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error().Err(err).Msgf("Api server on %s stopped", listener.Addr())
+		}
+	}()
+	go func() {
+		defer close(s.done)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), ShutdownTimeout)
+		defer cancel()
+		if err := s.httpServer.Shutdown(shutdownCtx); err != nil {
+			log.Error().Err(err).Msg("Error shutting down api server")
+		}
+	}()
+
+	return nil
+}
+
+// Done is closed once the server has been shut down after ctx of Start is cancelled
+func (s *Server) Done() <-chan struct{} {
+	return s.done
+}
+
+func newRouter(h *handler) *chi.Mux {
+	router := chi.NewRouter()
+	router.Get("/v1/delegations/{staking_tx_hash}", h.getDelegation)
+	router.Post("/v1/delegations/{staking_tx_hash}/withdrawal-psbt", h.buildWithdrawalPsbt)
+	router.Get("/v1/finality-providers", h.getFinalityProviders)
+	router.Get("/v1/finality-providers/{btc_pk}", h.getFinalityProvider)
+	router.Get("/v1/finality-providers/{btc_pk}/delegations", h.getFinalityProviderDelegations)
+	return router
+}
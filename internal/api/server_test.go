@@ -0,0 +1,31 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer(t *testing.T) {
+	t.Run("shut down on context cancel", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		// port 0 picks a free port
+		server := New(&config.APIConfig{Host: "127.0.0.1", Port: 0}, nil, nil)
+		require.NoError(t, server.Start(ctx))
+
+		cancel()
+		select {
+		case <-server.Done():
+		case <-time.After(ShutdownTimeout):
+			t.Fatal("server wasn't shut down")
+		}
+	})
+	t.Run("listen error", func(t *testing.T) {
+		server := New(&config.APIConfig{Host: "256.0.0.1", Port: 8090}, nil, nil)
+		assert.Error(t, server.Start(t.Context()))
+	})
+}
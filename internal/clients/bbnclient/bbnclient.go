@@ -2,38 +2,48 @@ package bbnclient
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/avast/retry-go/v4"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
-	bbncfg "github.com/babylonlabs-io/babylon/v4/client/config"
-	"github.com/babylonlabs-io/babylon/v4/client/query"
 	btcctypes "github.com/babylonlabs-io/babylon/v4/x/btccheckpoint/types"
 	btcstakingtypes "github.com/babylonlabs-io/babylon/v4/x/btcstaking/types"
+	finalitytypes "github.com/babylonlabs-io/babylon/v4/x/finality/types"
 	ctypes "github.com/cometbft/cometbft/rpc/core/types"
+	"github.com/cosmos/cosmos-sdk/client"
+	grpctypes "github.com/cosmos/cosmos-sdk/types/grpc"
+	sdkquerytypes "github.com/cosmos/cosmos-sdk/types/query"
 	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/metadata"
 )
 
+// number of items requested per page in paginated queries
+const queryPageLimit = 1000
+
+// BBNClient sends requests to one of configured BBN endpoints choosing the healthiest one
+// and failing over to others on errors
 type BBNClient struct {
-	queryClient *query.QueryClient
-	cfg         *config.BBNConfig
+	endpoints []*endpoint
+	cfg       *config.BBNConfig
 }
 
 func NewBBNClient(cfg *config.BBNConfig) (BbnInterface, error) {
-	bbnQueryCfg := &bbncfg.BabylonQueryConfig{
-		RPCAddr: cfg.RPCAddr,
-		Timeout: cfg.Timeout,
+	var endpoints []*endpoint
+	for _, endpointCfg := range cfg.GetEndpoints() {
+		e, err := newEndpoint(endpointCfg, cfg.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create client for BBN endpoint %s: %w", endpointCfg.RPCAddr, err)
+		}
+		endpoints = append(endpoints, e)
 	}
 
-	queryClient, err := query.New(bbnQueryCfg)
-	if err != nil {
-		return nil, err
-	}
 	return &BBNClient{
-		queryClient: queryClient,
-		cfg:         cfg,
+		endpoints: endpoints,
+		cfg:       cfg,
 	}, nil
 }
 
@@ -56,15 +66,16 @@ func (c *BBNClient) GetLatestBlockNumber(ctx context.Context) (int64, error) {
 }
 
 func (c *BBNClient) getStatus(ctx context.Context) (*ctypes.ResultStatus, error) {
-	callForStatus := func() (*ctypes.ResultStatus, error) {
-		status, err := c.queryClient.RPCClient.Status(ctx)
+	callForStatus := func(e *endpoint) (*ctypes.ResultStatus, error) {
+		status, err := e.client.RPCClient.Status(ctx)
 		if err != nil {
 			return nil, err
 		}
+		e.updateHeights(status.SyncInfo.EarliestBlockHeight, status.SyncInfo.LatestBlockHeight)
 		return status, nil
 	}
 
-	status, err := clientCallWithRetry(ctx, callForStatus, c.cfg)
+	status, err := callWithFailover(ctx, c, request{method: "Status"}, callForStatus)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get status: %w", err)
 	}
@@ -73,15 +84,15 @@ func (c *BBNClient) getStatus(ctx context.Context) (*ctypes.ResultStatus, error)
 }
 
 func (c *BBNClient) GetCheckpointParams(ctx context.Context) (*CheckpointParams, error) {
-	callForCheckpointParams := func() (*btcctypes.QueryParamsResponse, error) {
-		params, err := c.queryClient.BTCCheckpointParams()
+	callForCheckpointParams := func(e *endpoint) (*btcctypes.QueryParamsResponse, error) {
+		params, err := e.client.BTCCheckpointParams()
 		if err != nil {
 			return nil, err
 		}
 		return params, nil
 	}
 
-	params, err := clientCallWithRetry(ctx, callForCheckpointParams, c.cfg)
+	params, err := callWithFailover(ctx, c, request{method: "BTCCheckpointParams"}, callForCheckpointParams)
 	if err != nil {
 		return nil, err
 	}
@@ -99,26 +110,20 @@ func (c *BBNClient) GetStakingParams(ctx context.Context, minVersion uint32) (ma
 	allParams := make(map[uint32]*StakingParams)
 
 	for version := minVersion; ; version++ {
-		// First try without retry to check for ErrParamsNotFound
-		params, err := c.queryClient.BTCStakingParamsByVersion(version)
+		callForStakingParams := func(e *endpoint) (*btcstakingtypes.QueryParamsByVersionResponse, error) {
+			return e.client.BTCStakingParamsByVersion(version)
+		}
+
+		// "params not found" is returned right away without retries, it's the only way to find the last version
+		params, err := callWithFailover(ctx, c, request{
+			method: "BTCStakingParamsByVersion",
+			final:  isParamsNotFoundError,
+		}, callForStakingParams)
 		if err != nil {
 			if isParamsNotFoundError(err) {
 				break // Exit loop if params not found
 			}
-
-			// Only retry for other errors
-			callForStakingParams := func() (*btcstakingtypes.QueryParamsByVersionResponse, error) {
-				return c.queryClient.BTCStakingParamsByVersion(version)
-			}
-
-			params, err = clientCallWithRetry(ctx, callForStakingParams, c.cfg)
-			if err != nil {
-				// Check again after retries - the error might be "params not found"
-				if isParamsNotFoundError(err) {
-					break
-				}
-				return nil, fmt.Errorf("failed to get staking params for version %d: %w", version, err)
-			}
+			return nil, fmt.Errorf("failed to get staking params for version %d: %w", version, err)
 		}
 
 		/*
@@ -139,15 +144,16 @@ func (c *BBNClient) GetStakingParams(ctx context.Context, minVersion uint32) (ma
 func (c *BBNClient) GetBlockResults(
 	ctx context.Context, blockHeight *int64,
 ) (*ctypes.ResultBlockResults, error) {
-	callForBlockResults := func() (*ctypes.ResultBlockResults, error) {
-		resp, err := c.queryClient.RPCClient.BlockResults(ctx, blockHeight)
+	callForBlockResults := func(e *endpoint) (*ctypes.ResultBlockResults, error) {
+		resp, err := e.client.RPCClient.BlockResults(ctx, blockHeight)
 		if err != nil {
 			return nil, err
 		}
 		return resp, nil
 	}
 
-	blockResults, err := clientCallWithRetry(ctx, callForBlockResults, c.cfg)
+	req := request{method: "BlockResults", height: heightOrLatest(blockHeight)}
+	blockResults, err := callWithFailover(ctx, c, req, callForBlockResults)
 	if err != nil {
 		return nil, err
 	}
@@ -155,8 +161,8 @@ func (c *BBNClient) GetBlockResults(
 }
 
 func (c *BBNClient) BabylonStakerAddress(ctx context.Context, stakingTxHashHex string) (string, error) {
-	call := func() (*string, error) {
-		resp, err := c.queryClient.BTCDelegation(stakingTxHashHex)
+	call := func(e *endpoint) (*string, error) {
+		resp, err := e.client.BTCDelegation(stakingTxHashHex)
 		if err != nil {
 			return nil, err
 		}
@@ -164,7 +170,7 @@ func (c *BBNClient) BabylonStakerAddress(ctx context.Context, stakingTxHashHex s
 		return &resp.BtcDelegation.StakerAddr, nil
 	}
 
-	stakerAddr, err := clientCallWithRetry(ctx, call, c.cfg)
+	stakerAddr, err := callWithFailover(ctx, c, request{method: "BTCDelegation"}, call)
 	if err != nil {
 		return "", err
 	}
@@ -172,22 +178,121 @@ func (c *BBNClient) BabylonStakerAddress(ctx context.Context, stakingTxHashHex s
 	return *stakerAddr, nil
 }
 
+func (c *BBNClient) GetFinalityProvidersAtHeight(
+	ctx context.Context, height int64,
+) ([]*btcstakingtypes.FinalityProviderResponse, error) {
+	var (
+		finalityProviders []*btcstakingtypes.FinalityProviderResponse
+		pageKey           []byte
+	)
+	for {
+		call := func(e *endpoint) (*btcstakingtypes.QueryFinalityProvidersResponse, error) {
+			queryCtx, cancel := c.queryContextAtHeight(ctx, height)
+			defer cancel()
+
+			return btcStakingQueryClient(e).FinalityProviders(queryCtx, &btcstakingtypes.QueryFinalityProvidersRequest{
+				Pagination: &sdkquerytypes.PageRequest{Key: pageKey, Limit: queryPageLimit},
+			})
+		}
+
+		resp, err := callWithFailover(ctx, c, request{method: "FinalityProviders", height: height}, call)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get finality providers at height %d: %w", height, err)
+		}
+
+		finalityProviders = append(finalityProviders, resp.FinalityProviders...)
+		if resp.Pagination == nil || len(resp.Pagination.NextKey) == 0 {
+			return finalityProviders, nil
+		}
+		pageKey = resp.Pagination.NextKey
+	}
+}
+
+func (c *BBNClient) GetActiveFinalityProvidersAtHeight(ctx context.Context, height int64) ([]string, error) {
+	var (
+		btcPks  []string
+		pageKey []byte
+	)
+	for {
+		call := func(e *endpoint) (*finalitytypes.QueryActiveFinalityProvidersAtHeightResponse, error) {
+			return e.client.ActiveFinalityProvidersAtHeight(
+				uint64(height),
+				&sdkquerytypes.PageRequest{Key: pageKey, Limit: queryPageLimit},
+			)
+		}
+
+		resp, err := callWithFailover(ctx, c, request{method: "ActiveFinalityProvidersAtHeight", height: height}, call)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get active finality providers at height %d: %w", height, err)
+		}
+
+		for _, fp := range resp.FinalityProviders {
+			btcPks = append(btcPks, fp.BtcPkHex.MarshalHex())
+		}
+		if resp.Pagination == nil || len(resp.Pagination.NextKey) == 0 {
+			return btcPks, nil
+		}
+		pageKey = resp.Pagination.NextKey
+	}
+}
+
+func (c *BBNClient) GetBTCDelegationsAtHeight(
+	ctx context.Context, height int64, pageKey []byte,
+) ([]*btcstakingtypes.BTCDelegationResponse, []byte, error) {
+	call := func(e *endpoint) (*btcstakingtypes.QueryBTCDelegationsResponse, error) {
+		queryCtx, cancel := c.queryContextAtHeight(ctx, height)
+		defer cancel()
+
+		return btcStakingQueryClient(e).BTCDelegations(queryCtx, &btcstakingtypes.QueryBTCDelegationsRequest{
+			Status:     btcstakingtypes.BTCDelegationStatus_ANY,
+			Pagination: &sdkquerytypes.PageRequest{Key: pageKey, Limit: queryPageLimit},
+		})
+	}
+
+	resp, err := callWithFailover(ctx, c, request{method: "BTCDelegations", height: height}, call)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get BTC delegations at height %d: %w", height, err)
+	}
+
+	var nextKey []byte
+	if resp.Pagination != nil {
+		nextKey = resp.Pagination.NextKey
+	}
+	return resp.BtcDelegations, nextKey, nil
+}
+
+// queryContextAtHeight returns context that makes grpc query read the state of the given height.
+// Height aware helpers of query.QueryClient can't be used for it: they append the header to the one
+// with height 0 (latest) that is always set, and the node takes the first value.
+func (c *BBNClient) queryContextAtHeight(ctx context.Context, height int64) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+	md := metadata.Pairs(grpctypes.GRPCBlockHeightHeader, strconv.FormatInt(height, 10))
+	return metadata.NewOutgoingContext(ctx, md), cancel
+}
+
+func btcStakingQueryClient(e *endpoint) btcstakingtypes.QueryClient {
+	return btcstakingtypes.NewQueryClient(client.Context{Client: e.client.RPCClient})
+}
+
 func (c *BBNClient) GetBlock(ctx context.Context, blockHeight *int64) (*ctypes.ResultBlock, error) {
-	callForBlock := func() (*ctypes.ResultBlock, error) {
-		resp, err := c.queryClient.RPCClient.Block(ctx, blockHeight)
+	callForBlock := func(e *endpoint) (*ctypes.ResultBlock, error) {
+		resp, err := e.client.RPCClient.Block(ctx, blockHeight)
 		if err != nil {
 			return nil, err
 		}
 		return resp, nil
 	}
 
-	block, err := clientCallWithRetry(ctx, callForBlock, c.cfg)
+	block, err := callWithFailover(ctx, c, request{method: "Block", height: heightOrLatest(blockHeight)}, callForBlock)
 	if err != nil {
 		return nil, err
 	}
 	return block, nil
 }
 
+// Subscribe subscribes to events on the healthiest endpoint. If no events are received within
+// maxEventWaitInterval or the subscription is closed, the endpoint is penalized and
+// the subscription is moved to the best endpoint at that moment.
 func (c *BBNClient) Subscribe(
 	ctx context.Context,
 	subscriber, query string,
@@ -197,27 +302,31 @@ func (c *BBNClient) Subscribe(
 ) (out <-chan ctypes.ResultEvent, err error) {
 	eventChan := make(chan ctypes.ResultEvent)
 
-	subscribe := func() (<-chan ctypes.ResultEvent, error) {
-		newChan, err := c.queryClient.RPCClient.Subscribe(
-			context.Background(),
-			subscriber,
-			query,
-			outCapacity...,
-		)
-		if err != nil {
-			return nil, fmt.Errorf(
-				"failed to subscribe babylon events for query %s: %w", query, err,
-			)
+	subscribe := func() (<-chan ctypes.ResultEvent, *endpoint, error) {
+		var errs []error
+		for _, e := range endpointsFor(c.endpoints, 0) {
+			newChan, err := e.subscribe(subscriber, query, outCapacity...)
+			if err != nil {
+				e.recordFailure()
+				errs = append(errs, fmt.Errorf("%s: %w", e.name, err))
+				continue
+			}
+			return newChan, e, nil
 		}
-		return newChan, nil
+
+		return nil, nil, fmt.Errorf(
+			"failed to subscribe babylon events for query %s: %w", query, errors.Join(errs...),
+		)
 	}
 
 	// Initial subscription
-	rawEventChan, err := subscribe()
+	rawEventChan, current, err := subscribe()
 	if err != nil {
 		close(eventChan)
 		return nil, err
 	}
+	recordSubscriptionEndpoint(current)
+
 	go func() {
 		defer close(eventChan)
 		timeoutTicker := time.NewTicker(healthCheckInterval)
@@ -225,14 +334,42 @@ func (c *BBNClient) Subscribe(
 		lastEventTime := time.Now()
 
 		log := log.Ctx(ctx)
+		resubscribe := func() {
+			if current != nil {
+				current.recordFailure()
+				if err := current.client.RPCClient.Unsubscribe(
+					context.Background(),
+					subscriber,
+					query,
+				); err != nil {
+					log.Error().Err(err).Str("endpoint", current.name).Msg("Failed to unsubscribe babylon events")
+				}
+			}
+
+			// Create new subscription, until it succeeds nil channel blocks and resubscribe is retried on next tick
+			newEventChan, newEndpoint, err := subscribe()
+			if err != nil {
+				log.Error().Err(err).Msg("Failed to resubscribe babylon events")
+				rawEventChan, current = nil, nil
+				return
+			}
+
+			// Replace the old channel with the new one
+			rawEventChan, current = newEventChan, newEndpoint
+			recordSubscriptionEndpoint(current)
+			log.Info().Str("endpoint", current.name).Msg("Resubscribed to babylon events")
+		}
+
 		for {
 			select {
 			case event, ok := <-rawEventChan:
 				if !ok {
-					log.Fatal().
+					log.Error().
 						Str("subscriber", subscriber).
 						Str("query", query).
-						Msg("Subscription channel closed, this shall not happen")
+						Msg("Subscription channel closed, attempting to resubscribe")
+					resubscribe()
+					continue
 				}
 				lastEventTime = time.Now()
 				eventChan <- event
@@ -243,23 +380,7 @@ func (c *BBNClient) Subscribe(
 						Str("query", query).
 						Msg("No events received, attempting to resubscribe")
 
-					if err := c.queryClient.RPCClient.Unsubscribe(
-						context.Background(),
-						subscriber,
-						query,
-					); err != nil {
-						log.Error().Err(err).Msg("Failed to unsubscribe babylon events")
-					}
-
-					// Create new subscription
-					newEventChan, err := subscribe()
-					if err != nil {
-						log.Error().Err(err).Msg("Failed to resubscribe babylon events")
-						continue
-					}
-
-					// Replace the old channel with the new one
-					rawEventChan = newEventChan
+					resubscribe()
 					// reset last event time
 					lastEventTime = time.Now()
 				}
@@ -271,15 +392,94 @@ func (c *BBNClient) Subscribe(
 }
 
 func (c *BBNClient) UnsubscribeAll(ctx context.Context, subscriber string) error {
-	return c.queryClient.RPCClient.UnsubscribeAll(ctx, subscriber)
+	var errs []error
+	for _, e := range c.endpoints {
+		if !e.client.IsRunning() {
+			continue
+		}
+		if err := e.client.RPCClient.UnsubscribeAll(ctx, subscriber); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", e.name, err))
+		}
+	}
+	return errors.Join(errs...)
 }
 
+// IsRunning returns true if at least one endpoint is running
 func (c *BBNClient) IsRunning() bool {
-	return c.queryClient.RPCClient.IsRunning()
+	for _, e := range c.endpoints {
+		if e.client.IsRunning() {
+			return true
+		}
+	}
+	return false
+}
+
+// Start starts all endpoints and their health checks. It fails only if none of endpoints can be started,
+// the rest are started again once they are selected for subscription. Health checks stop once ctx is done.
+func (c *BBNClient) Start(ctx context.Context) error {
+	var errs []error
+	for _, e := range c.endpoints {
+		if err := e.client.Start(); err != nil {
+			e.recordFailure()
+			errs = append(errs, fmt.Errorf("%s: %w", e.name, err))
+		}
+	}
+	if len(errs) == len(c.endpoints) {
+		return errors.Join(errs...)
+	}
+	for _, err := range errs {
+		log.Error().Err(err).Msg("failed to start BBN endpoint")
+	}
+
+	if c.cfg.HealthCheckInterval > 0 {
+		go c.checkHealth(ctx)
+	}
+
+	return nil
 }
 
-func (c *BBNClient) Start() error {
-	return c.queryClient.RPCClient.Start()
+// checkHealth periodically updates heights of every endpoint, so requests are routed
+// to endpoints that keep the requested height and lagging endpoints are avoided. It returns once ctx is done.
+func (c *BBNClient) checkHealth(ctx context.Context) {
+	ticker := time.NewTicker(c.cfg.HealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for _, e := range c.endpoints {
+			reqCtx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+			start := time.Now()
+			status, err := e.client.RPCClient.Status(reqCtx)
+			cancel()
+			if ctx.Err() != nil {
+				return
+			}
+
+			duration := time.Since(start)
+			recordEndpointCall(e, "Status", duration, err)
+			if err != nil {
+				e.recordFailure()
+				log.Debug().Err(err).Str("endpoint", e.name).Msg("BBN endpoint health check failed")
+			} else {
+				e.recordSuccess(duration)
+				e.updateHeights(status.SyncInfo.EarliestBlockHeight, status.SyncInfo.LatestBlockHeight)
+			}
+			recordEndpointHealth(e)
+		}
+	}
+}
+
+// heightOrLatest converts optional block height into request height
+func heightOrLatest(height *int64) int64 {
+	if height == nil {
+		return 0
+	}
+	return *height
 }
 
 func clientCallWithRetry[T any](
@@ -0,0 +1,241 @@
+package bbnclient
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/avast/retry-go/v4"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	bbncfg "github.com/babylonlabs-io/babylon/v4/client/config"
+	"github.com/babylonlabs-io/babylon/v4/client/query"
+	ctypes "github.com/cometbft/cometbft/rpc/core/types"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// endpoint is considered unhealthy after this number of failures in a row
+	maxConsecutiveFailures = 3
+	// endpoint is considered lagging if it's behind the highest known height by more than this number of blocks
+	maxHeightLag = 5
+	// requests of heights older than this number of blocks from the tip are historical and routed to archive nodes
+	recentBlocksWindow = 100
+	// weight of the latest measurement in exponentially weighted moving average of latency
+	latencyEWMAWeight = 0.2
+)
+
+// endpoint is a single BBN node together with its health statistics
+type endpoint struct {
+	// name is used in logs and metrics, it doesn't contain credentials or path of the address
+	name   string
+	role   config.BBNEndpointRole
+	client *query.QueryClient
+
+	mu                  sync.Mutex
+	latency             time.Duration
+	consecutiveFailures int
+	earliestHeight      int64
+	latestHeight        int64
+}
+
+func newEndpoint(cfg config.BBNEndpoint, timeout time.Duration) (*endpoint, error) {
+	u, err := url.Parse(cfg.RPCAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := query.New(&bbncfg.BabylonQueryConfig{
+		RPCAddr: cfg.RPCAddr,
+		Timeout: timeout,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &endpoint{
+		name:   u.Host,
+		role:   cfg.Role,
+		client: client,
+	}, nil
+}
+
+// subscribe subscribes to events over websocket starting the client if it's not running
+// (e.g. the node was down when the indexer started)
+func (e *endpoint) subscribe(subscriber, query string, outCapacity ...int) (<-chan ctypes.ResultEvent, error) {
+	if !e.client.IsRunning() {
+		if err := e.client.Start(); err != nil {
+			return nil, err
+		}
+	}
+
+	return e.client.RPCClient.Subscribe(context.Background(), subscriber, query, outCapacity...)
+}
+
+func (e *endpoint) recordSuccess(d time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.consecutiveFailures = 0
+	if e.latency == 0 {
+		e.latency = d
+	} else {
+		e.latency = time.Duration(latencyEWMAWeight*float64(d) + (1-latencyEWMAWeight)*float64(e.latency))
+	}
+}
+
+func (e *endpoint) recordFailure() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.consecutiveFailures++
+}
+
+func (e *endpoint) updateHeights(earliest, latest int64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.earliestHeight = earliest
+	e.latestHeight = latest
+}
+
+func (e *endpoint) health() endpointHealth {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return endpointHealth{
+		latency:        e.latency,
+		healthy:        e.consecutiveFailures < maxConsecutiveFailures,
+		earliestHeight: e.earliestHeight,
+		latestHeight:   e.latestHeight,
+	}
+}
+
+type endpointHealth struct {
+	latency        time.Duration
+	healthy        bool
+	earliestHeight int64
+	latestHeight   int64
+}
+
+// canServe reports whether endpoint keeps the state of the given height (0 means the latest state).
+// Unknown heights (health check hasn't succeeded yet) are treated optimistically.
+func (h endpointHealth) canServe(height int64) bool {
+	if height == 0 {
+		return true
+	}
+	if h.earliestHeight > 0 && height < h.earliestHeight {
+		return false
+	}
+	return h.latestHeight == 0 || height <= h.latestHeight
+}
+
+// endpointsFor returns all endpoints ordered by preference for request of the given height (0 means the latest state).
+// Endpoints that can't serve the height go last, then unhealthy and lagging ones. Among the rest
+// archive nodes are preferred for historical requests and tip nodes for others, ties are broken by latency.
+// All endpoints are returned, so the request is still attempted when health information is stale.
+func endpointsFor(endpoints []*endpoint, height int64) []*endpoint {
+	type candidate struct {
+		endpoint *endpoint
+		health   endpointHealth
+	}
+
+	var tip int64
+	candidates := make([]candidate, len(endpoints))
+	for i, e := range endpoints {
+		candidates[i] = candidate{endpoint: e, health: e.health()}
+		tip = max(tip, candidates[i].health.latestHeight)
+	}
+
+	preferredRole := config.BBNEndpointRoleTip
+	if height > 0 && tip-height > recentBlocksWindow {
+		preferredRole = config.BBNEndpointRoleArchive
+	}
+
+	// penalty returns values compared in order, lower is better
+	penalty := func(c candidate) [4]int {
+		var p [4]int
+		if !c.health.canServe(height) {
+			p[0] = 1
+		}
+		if !c.health.healthy {
+			p[1] = 1
+		}
+		if c.health.latestHeight > 0 && tip-c.health.latestHeight > maxHeightLag {
+			p[2] = 1
+		}
+		if c.endpoint.role != preferredRole {
+			p[3] = 1
+		}
+		return p
+	}
+
+	slices.SortStableFunc(candidates, func(a, b candidate) int {
+		pa, pb := penalty(a), penalty(b)
+		if c := slices.Compare(pa[:], pb[:]); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.health.latency, b.health.latency)
+	})
+
+	result := make([]*endpoint, len(candidates))
+	for i, c := range candidates {
+		result[i] = c.endpoint
+	}
+	return result
+}
+
+// request describes a call for the purpose of endpoint selection
+type request struct {
+	method string
+	// height of the requested block or state, 0 means the latest one
+	height int64
+	// final reports errors which are valid responses of a healthy node (e.g. not found),
+	// they are returned right away without trying other endpoints
+	final func(error) bool
+}
+
+// callWithFailover executes call against endpoints in order of preference until one of them succeeds.
+// If all endpoints fail the round is retried according to the config.
+func callWithFailover[T any](
+	ctx context.Context, c *BBNClient, req request, call func(e *endpoint) (*T, error),
+) (*T, error) {
+	return clientCallWithRetry(ctx, func() (*T, error) {
+		var errs []error
+		for i, e := range endpointsFor(c.endpoints, req.height) {
+			start := time.Now()
+			result, err := call(e)
+			duration := time.Since(start)
+
+			if err != nil && req.final != nil && req.final(err) {
+				// node responded properly, so it's counted as success
+				e.recordSuccess(duration)
+				recordEndpointCall(e, req.method, duration, nil)
+				return nil, retry.Unrecoverable(err)
+			}
+
+			recordEndpointCall(e, req.method, duration, err)
+			if err == nil {
+				e.recordSuccess(duration)
+				if i > 0 {
+					recordEndpointFailover(req.method)
+				}
+				return result, nil
+			}
+
+			log.Ctx(ctx).Debug().
+				Str("endpoint", e.name).
+				Str("method", req.method).
+				Err(err).
+				Msg("BBN endpoint call failed")
+			e.recordFailure()
+			errs = append(errs, fmt.Errorf("%s: %w", e.name, err))
+		}
+
+		return nil, errors.Join(errs...)
+	}, c.cfg)
+}
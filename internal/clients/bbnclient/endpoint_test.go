@@ -0,0 +1,144 @@
+package bbnclient
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testEndpoint(name string, role config.BBNEndpointRole, earliest, latest int64) *endpoint {
+	e := &endpoint{name: name, role: role}
+	e.updateHeights(earliest, latest)
+	return e
+}
+
+func endpointNames(endpoints []*endpoint) []string {
+	names := make([]string, len(endpoints))
+	for i, e := range endpoints {
+		names[i] = e.name
+	}
+	return names
+}
+
+func TestEndpointsFor(t *testing.T) {
+	t.Run("latest state prefers tip node", func(t *testing.T) {
+		archive := testEndpoint("archive", config.BBNEndpointRoleArchive, 1, 1000)
+		tip := testEndpoint("tip", config.BBNEndpointRoleTip, 900, 1000)
+
+		result := endpointsFor([]*endpoint{archive, tip}, 0)
+		assert.Equal(t, []string{"tip", "archive"}, endpointNames(result))
+	})
+	t.Run("historical height prefers archive node", func(t *testing.T) {
+		archive := testEndpoint("archive", config.BBNEndpointRoleArchive, 1, 1000)
+		tip := testEndpoint("tip", config.BBNEndpointRoleTip, 1, 1000)
+
+		result := endpointsFor([]*endpoint{tip, archive}, 10)
+		assert.Equal(t, []string{"archive", "tip"}, endpointNames(result))
+	})
+	t.Run("pruned height goes last", func(t *testing.T) {
+		archive := testEndpoint("archive", config.BBNEndpointRoleArchive, 500, 1000)
+		tip := testEndpoint("tip", config.BBNEndpointRoleTip, 1, 1000)
+
+		result := endpointsFor([]*endpoint{archive, tip}, 10)
+		assert.Equal(t, []string{"tip", "archive"}, endpointNames(result))
+	})
+	t.Run("unhealthy and lagging nodes go last", func(t *testing.T) {
+		unhealthy := testEndpoint("unhealthy", config.BBNEndpointRoleTip, 1, 1000)
+		for range maxConsecutiveFailures {
+			unhealthy.recordFailure()
+		}
+		lagging := testEndpoint("lagging", config.BBNEndpointRoleTip, 1, 1000-maxHeightLag-1)
+		archive := testEndpoint("archive", config.BBNEndpointRoleArchive, 1, 1000)
+
+		result := endpointsFor([]*endpoint{unhealthy, lagging, archive}, 0)
+		assert.Equal(t, []string{"archive", "lagging", "unhealthy"}, endpointNames(result))
+	})
+	t.Run("ties are broken by latency", func(t *testing.T) {
+		slow := testEndpoint("slow", config.BBNEndpointRoleTip, 1, 1000)
+		slow.recordSuccess(time.Second)
+		fast := testEndpoint("fast", config.BBNEndpointRoleTip, 1, 1000)
+		fast.recordSuccess(time.Millisecond)
+
+		result := endpointsFor([]*endpoint{slow, fast}, 0)
+		assert.Equal(t, []string{"fast", "slow"}, endpointNames(result))
+	})
+}
+
+func TestCallWithFailover(t *testing.T) {
+	cfg := &config.BBNConfig{MaxRetryTimes: 2, RetryInterval: time.Millisecond}
+	errNotFound := errors.New("not found")
+
+	newClient := func() (*BBNClient, *endpoint, *endpoint) {
+		primary := testEndpoint("primary", config.BBNEndpointRoleTip, 1, 1000)
+		secondary := testEndpoint("secondary", config.BBNEndpointRoleArchive, 1, 1000)
+		return &BBNClient{endpoints: []*endpoint{primary, secondary}, cfg: cfg}, primary, secondary
+	}
+
+	t.Run("fails over to the next endpoint", func(t *testing.T) {
+		client, primary, _ := newClient()
+
+		var calls []string
+		result, err := callWithFailover(context.Background(), client, request{method: "test"}, func(e *endpoint) (*string, error) {
+			calls = append(calls, e.name)
+			if e == primary {
+				return nil, errors.New("connection refused")
+			}
+			return &e.name, nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "secondary", *result)
+		assert.Equal(t, []string{"primary", "secondary"}, calls)
+		assert.Equal(t, 1, primary.consecutiveFailures)
+	})
+	t.Run("final error is returned without failover", func(t *testing.T) {
+		client, primary, _ := newClient()
+
+		var calls int
+		req := request{method: "test", final: func(err error) bool { return errors.Is(err, errNotFound) }}
+		_, err := callWithFailover(context.Background(), client, req, func(e *endpoint) (*string, error) {
+			calls++
+			return nil, errNotFound
+		})
+		require.ErrorIs(t, err, errNotFound)
+		assert.Equal(t, 1, calls)
+		assert.True(t, primary.health().healthy)
+	})
+	t.Run("all endpoints failing are retried", func(t *testing.T) {
+		client, primary, secondary := newClient()
+
+		var calls int
+		_, err := callWithFailover(context.Background(), client, request{method: "test"}, func(e *endpoint) (*string, error) {
+			calls++
+			return nil, errors.New("connection refused")
+		})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "primary")
+		assert.Contains(t, err.Error(), "secondary")
+		assert.Equal(t, 4, calls)
+		assert.Equal(t, 2, primary.consecutiveFailures)
+		assert.Equal(t, 2, secondary.consecutiveFailures)
+	})
+}
+
+func TestCheckHealthStopsWithContext(t *testing.T) {
+	client := &BBNClient{cfg: &config.BBNConfig{HealthCheckInterval: time.Millisecond, Timeout: time.Second}}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan struct{})
+	go func() {
+		client.checkHealth(ctx)
+		close(done)
+	}()
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("health check didn't stop after context cancellation")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	btcstakingtypes "github.com/babylonlabs-io/babylon/v4/x/btcstaking/types"
 	ctypes "github.com/cometbft/cometbft/rpc/core/types"
 )
 
@@ -18,6 +19,16 @@ type BbnInterface interface {
 	GetBlock(ctx context.Context, blockHeight *int64) (*ctypes.ResultBlock, error)
 	GetBlockResults(ctx context.Context, blockHeight *int64) (*ctypes.ResultBlockResults, error)
 	BabylonStakerAddress(ctx context.Context, stakingTxHashHex string) (string, error)
+	// GetFinalityProvidersAtHeight returns all finality providers registered as of the given BBN height
+	GetFinalityProvidersAtHeight(ctx context.Context, height int64) ([]*btcstakingtypes.FinalityProviderResponse, error)
+	// GetActiveFinalityProvidersAtHeight returns btc public keys of finality providers
+	// that have voting power at the given BBN height
+	GetActiveFinalityProvidersAtHeight(ctx context.Context, height int64) ([]string, error)
+	// GetBTCDelegationsAtHeight returns a single page of delegations (in any status) as of the given BBN height
+	// together with the key of the next page. Empty next key means there are no more pages.
+	GetBTCDelegationsAtHeight(
+		ctx context.Context, height int64, pageKey []byte,
+	) ([]*btcstakingtypes.BTCDelegationResponse, []byte, error)
 	Subscribe(
 		ctx context.Context,
 		subscriber, query string,
@@ -27,5 +38,6 @@ type BbnInterface interface {
 	) (out <-chan ctypes.ResultEvent, err error)
 	UnsubscribeAll(ctx context.Context, subscriber string) error
 	IsRunning() bool
-	Start() error
+	// Start starts the client, background work of the client stops once ctx is done
+	Start(ctx context.Context) error
 }
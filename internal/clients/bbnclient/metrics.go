@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+	btcstakingtypes "github.com/babylonlabs-io/babylon/v4/x/btcstaking/types"
 	ctypes "github.com/cometbft/cometbft/rpc/core/types"
 )
 
@@ -74,8 +75,38 @@ func (b *bbnClientWithMetrics) UnsubscribeAll(ctx context.Context, subscriber st
 	return err
 }
 
+func (b *bbnClientWithMetrics) GetFinalityProvidersAtHeight(
+	ctx context.Context, height int64,
+) ([]*btcstakingtypes.FinalityProviderResponse, error) {
+	return runBbnClientMethodWithMetrics("GetFinalityProvidersAtHeight", func() ([]*btcstakingtypes.FinalityProviderResponse, error) {
+		return b.bbn.GetFinalityProvidersAtHeight(ctx, height)
+	})
+}
+
+func (b *bbnClientWithMetrics) GetActiveFinalityProvidersAtHeight(ctx context.Context, height int64) ([]string, error) {
+	return runBbnClientMethodWithMetrics("GetActiveFinalityProvidersAtHeight", func() ([]string, error) {
+		return b.bbn.GetActiveFinalityProvidersAtHeight(ctx, height)
+	})
+}
+
+func (b *bbnClientWithMetrics) GetBTCDelegationsAtHeight(
+	ctx context.Context, height int64, pageKey []byte,
+) ([]*btcstakingtypes.BTCDelegationResponse, []byte, error) {
+	// auxiliary type in order to call runBbnClientMethodWithMetrics which returns only 2 values
+	type page struct {
+		delegations []*btcstakingtypes.BTCDelegationResponse
+		nextKey     []byte
+	}
+	p, err := runBbnClientMethodWithMetrics("GetBTCDelegationsAtHeight", func() (page, error) {
+		delegations, nextKey, err := b.bbn.GetBTCDelegationsAtHeight(ctx, height, pageKey)
+		return page{delegations: delegations, nextKey: nextKey}, err
+	})
+
+	return p.delegations, p.nextKey, err
+}
+
 func (b *bbnClientWithMetrics) BabylonStakerAddress(ctx context.Context, stakingTxHashHex string) (string, error) {
-	// we don't need to measure latency for this method (it's used only in FillStakerAddr script)
+	// we don't need to measure latency for this method (it's used only in staker address migration)
 	return b.bbn.BabylonStakerAddress(ctx, stakingTxHashHex)
 }
 
@@ -83,8 +114,8 @@ func (b *bbnClientWithMetrics) IsRunning() bool {
 	return b.bbn.IsRunning()
 }
 
-func (b *bbnClientWithMetrics) Start() error {
-	return b.bbn.Start()
+func (b *bbnClientWithMetrics) Start(ctx context.Context) error {
+	return b.bbn.Start(ctx)
 }
 
 func runBbnClientMethodWithMetrics[T any](method string, f func() (T, error)) (T, error) {
@@ -95,3 +126,22 @@ func runBbnClientMethodWithMetrics[T any](method string, f func() (T, error)) (T
 	metrics.RecordBBNClientLatency(duration, method, err != nil)
 	return v, err
 }
+
+// recordEndpointCall records latency of a single request to the endpoint,
+// unlike runBbnClientMethodWithMetrics it measures every failover attempt separately
+func recordEndpointCall(e *endpoint, method string, d time.Duration, err error) {
+	metrics.RecordBBNEndpointLatency(d, e.name, method, err != nil)
+}
+
+func recordEndpointHealth(e *endpoint) {
+	health := e.health()
+	metrics.RecordBBNEndpointHealth(e.name, string(e.role), health.healthy, health.latestHeight)
+}
+
+func recordEndpointFailover(method string) {
+	metrics.IncBBNEndpointFailover(method)
+}
+
+func recordSubscriptionEndpoint(e *endpoint) {
+	metrics.RecordBBNSubscriptionEndpoint(e.name)
+}
@@ -2,12 +2,16 @@ package btcclient
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
 
 	"github.com/avast/retry-go/v4"
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/rpcclient"
+	"github.com/btcsuite/btcd/wire"
 	"github.com/rs/zerolog/log"
 )
 
@@ -84,6 +88,68 @@ func (c *BTCClient) GetBlockTimestamp(ctx context.Context, height uint32) (int64
 	return response.timestamp, nil
 }
 
+func (c *BTCClient) GetBlockHash(ctx context.Context, height uint32) (string, error) {
+	callForBlockHash := func() (*string, error) {
+		hash, err := c.client.GetBlockHash(int64(height))
+		if err != nil {
+			return nil, err
+		}
+
+		hashStr := hash.String()
+		return &hashStr, nil
+	}
+
+	hash, err := clientCallWithRetry(ctx, callForBlockHash, c.cfg)
+	if err != nil {
+		return "", fmt.Errorf("failed to get block hash at height %d: %w", height, err)
+	}
+
+	return *hash, nil
+}
+
+func (c *BTCClient) GetBlockTimestampByHash(ctx context.Context, hash string) (int64, error) {
+	blockHash, err := chainhash.NewHashFromStr(hash)
+	if err != nil {
+		return 0, fmt.Errorf("invalid block hash %q: %w", hash, err)
+	}
+
+	callForBlockHeader := func() (*wire.BlockHeader, error) {
+		return c.client.GetBlockHeader(blockHash)
+	}
+
+	header, err := clientCallWithRetry(ctx, callForBlockHeader, c.cfg)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get block %s header: %w", hash, err)
+	}
+
+	return header.Timestamp.Unix(), nil
+}
+
+func (c *BTCClient) IsTxInMempool(ctx context.Context, txHash *chainhash.Hash) (bool, error) {
+	callForMempoolEntry := func() (*bool, error) {
+		_, err := c.client.GetMempoolEntry(txHash.String())
+		if err != nil {
+			var rpcErr *btcjson.RPCError
+			if errors.As(err, &rpcErr) && rpcErr.Code == btcjson.ErrRPCInvalidAddressOrKey {
+				// the tx is not in the mempool, it's not an error to retry
+				inMempool := false
+				return &inMempool, nil
+			}
+			return nil, err
+		}
+
+		inMempool := true
+		return &inMempool, nil
+	}
+
+	inMempool, err := clientCallWithRetry(ctx, callForMempoolEntry, c.cfg)
+	if err != nil {
+		return false, fmt.Errorf("failed to get mempool entry of tx %s: %w", txHash, err)
+	}
+
+	return *inMempool, nil
+}
+
 func clientCallWithRetry[T any](
 	ctx context.Context, call retry.RetryableFuncWithData[*T], cfg *config.BTCConfig,
 ) (*T, error) {
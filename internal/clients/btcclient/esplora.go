@@ -0,0 +1,243 @@
+package btcclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/utils"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+const (
+	esploraRequestTimeout = 30 * time.Second
+	// esplora responses are small (heights, hashes, block headers and single txs),
+	// the limit protects against misbehaving servers
+	esploraMaxResponseSize = 4 << 20
+)
+
+// EsploraClient implements BtcInterface on top of Esplora compatible REST API
+// (https://github.com/Blockstream/esplora/blob/master/API.md)
+type EsploraClient struct {
+	httpClient *http.Client
+	baseURL    string
+	cfg        *config.BTCConfig
+}
+
+func NewEsploraClient(cfg *config.BTCConfig) (*EsploraClient, error) {
+	if cfg.EsploraURL == "" {
+		return nil, fmt.Errorf("esplora url cannot be empty")
+	}
+
+	return &EsploraClient{
+		httpClient: &http.Client{Timeout: esploraRequestTimeout},
+		baseURL:    strings.TrimSuffix(cfg.EsploraURL, "/"),
+		cfg:        cfg,
+	}, nil
+}
+
+func (c *EsploraClient) GetTipHeight(ctx context.Context) (uint64, error) {
+	callForTipHeight := func() (*uint64, error) {
+		body, err := c.get(ctx, "/blocks/tip/height")
+		if err != nil {
+			return nil, err
+		}
+
+		height, err := strconv.ParseUint(strings.TrimSpace(string(body)), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tip height %q: %w", body, err)
+		}
+		return &height, nil
+	}
+
+	height, err := clientCallWithRetry(ctx, callForTipHeight, c.cfg)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get tip height: %w", err)
+	}
+
+	return *height, nil
+}
+
+func (c *EsploraClient) GetBlockTimestamp(ctx context.Context, height uint32) (int64, error) {
+	type blockResponse struct {
+		Timestamp int64 `json:"timestamp"`
+	}
+
+	callForBlockTimestamp := func() (*blockResponse, error) {
+		hash, err := c.get(ctx, fmt.Sprintf("/block-height/%d", height))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get block hash at height %d: %w", height, err)
+		}
+
+		var block blockResponse
+		if err := c.getJSON(ctx, "/block/"+strings.TrimSpace(string(hash)), &block); err != nil {
+			return nil, fmt.Errorf("failed to get block at height %d: %w", height, err)
+		}
+		return &block, nil
+	}
+
+	block, err := clientCallWithRetry(ctx, callForBlockTimestamp, c.cfg)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get block timestamp: %w", err)
+	}
+
+	return block.Timestamp, nil
+}
+
+func (c *EsploraClient) GetBlockHash(ctx context.Context, height uint32) (string, error) {
+	callForBlockHash := func() (*string, error) {
+		body, err := c.get(ctx, fmt.Sprintf("/block-height/%d", height))
+		if err != nil {
+			return nil, err
+		}
+
+		hash := strings.TrimSpace(string(body))
+		return &hash, nil
+	}
+
+	hash, err := clientCallWithRetry(ctx, callForBlockHash, c.cfg)
+	if err != nil {
+		return "", fmt.Errorf("failed to get block hash at height %d: %w", height, err)
+	}
+
+	return *hash, nil
+}
+
+func (c *EsploraClient) GetBlockTimestampByHash(ctx context.Context, hash string) (int64, error) {
+	type blockResponse struct {
+		Timestamp int64 `json:"timestamp"`
+	}
+
+	callForBlock := func() (*blockResponse, error) {
+		var block blockResponse
+		if err := c.getJSON(ctx, "/block/"+hash, &block); err != nil {
+			return nil, err
+		}
+		return &block, nil
+	}
+
+	block, err := clientCallWithRetry(ctx, callForBlock, c.cfg)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get block %s: %w", hash, err)
+	}
+
+	return block.Timestamp, nil
+}
+
+// esploraOutspend is the response of /tx/:txid/outspend/:vout
+type esploraOutspend struct {
+	Spent  bool   `json:"spent"`
+	TxID   string `json:"txid"`
+	Vin    uint32 `json:"vin"`
+	Status struct {
+		Confirmed   bool  `json:"confirmed"`
+		BlockHeight int32 `json:"block_height"`
+	} `json:"status"`
+}
+
+func (c *EsploraClient) getOutspend(ctx context.Context, outpoint *wire.OutPoint) (*esploraOutspend, error) {
+	return clientCallWithRetry(ctx, func() (*esploraOutspend, error) {
+		var outspend esploraOutspend
+		path := fmt.Sprintf("/tx/%s/outspend/%d", outpoint.Hash, outpoint.Index)
+		if err := c.getJSON(ctx, path, &outspend); err != nil {
+			return nil, err
+		}
+		return &outspend, nil
+	}, c.cfg)
+}
+
+func (c *EsploraClient) IsTxInMempool(ctx context.Context, txHash *chainhash.Hash) (bool, error) {
+	status, err := c.getTxStatus(ctx, txHash)
+	if err != nil {
+		return false, fmt.Errorf("failed to get status of tx %s: %w", txHash, err)
+	}
+
+	return status != nil && !status.Confirmed, nil
+}
+
+// esploraTxStatus is the response of /tx/:txid/status
+type esploraTxStatus struct {
+	Confirmed   bool   `json:"confirmed"`
+	BlockHeight uint32 `json:"block_height"`
+	BlockHash   string `json:"block_hash"`
+}
+
+// getTxStatus returns nil if the tx is neither in the mempool nor in the chain
+func (c *EsploraClient) getTxStatus(ctx context.Context, txHash *chainhash.Hash) (*esploraTxStatus, error) {
+	return clientCallWithRetry(ctx, func() (*esploraTxStatus, error) {
+		var status esploraTxStatus
+		if err := c.getJSON(ctx, fmt.Sprintf("/tx/%s/status", txHash), &status); err != nil {
+			var statusErr *esploraStatusError
+			if errors.As(err, &statusErr) && statusErr.statusCode == http.StatusNotFound {
+				return nil, nil
+			}
+			return nil, err
+		}
+		return &status, nil
+	}, c.cfg)
+}
+
+func (c *EsploraClient) getTx(ctx context.Context, txHash *chainhash.Hash) (*wire.MsgTx, error) {
+	return clientCallWithRetry(ctx, func() (*wire.MsgTx, error) {
+		txHex, err := c.get(ctx, fmt.Sprintf("/tx/%s/hex", txHash))
+		if err != nil {
+			return nil, err
+		}
+		return utils.DeserializeBtcTransactionFromHex(strings.TrimSpace(string(txHex)))
+	}, c.cfg)
+}
+
+func (c *EsploraClient) getJSON(ctx context.Context, path string, v any) error {
+	body, err := c.get(ctx, path)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("failed to decode response of %s: %w", path, err)
+	}
+	return nil
+}
+
+func (c *EsploraClient) get(ctx context.Context, path string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, esploraMaxResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response of %s: %w", path, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &esploraStatusError{path: path, statusCode: resp.StatusCode, body: strings.TrimSpace(string(body))}
+	}
+
+	return body, nil
+}
+
+// esploraStatusError is returned for responses with other status than 200 OK
+type esploraStatusError struct {
+	path       string
+	statusCode int
+	body       string
+}
+
+func (e *esploraStatusError) Error() string {
+	return fmt.Sprintf("unexpected status %d from %s: %s", e.statusCode, e.path, e.body)
+}
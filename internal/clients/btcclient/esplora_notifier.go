@@ -0,0 +1,313 @@
+package btcclient
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/chainntnfs"
+	"github.com/rs/zerolog/log"
+)
+
+// EsploraNotifier delivers spend notifications by polling outspends of the registered
+// outpoints in Esplora API. Spends are reported once the spending tx is confirmed,
+// same as bitcoind notifier does, and reorgs are reported if the spending tx
+// disappears from the chain before the registration is cancelled. Confirmations of
+// registered txs are polled the same way, they are reported once and not watched
+// for reorgs afterwards.
+type EsploraNotifier struct {
+	client       *EsploraClient
+	pollInterval time.Duration
+
+	mu            sync.Mutex
+	nextID        uint64
+	registrations map[uint64]*spendRegistration
+	confirmations map[uint64]*confRegistration
+
+	startOnce sync.Once
+	stopOnce  sync.Once
+	quit      chan struct{}
+	wg        sync.WaitGroup
+}
+
+type spendRegistration struct {
+	outpoint wire.OutPoint
+	event    *chainntnfs.SpendEvent
+	// delivered is the spend sent to the event, nil until outpoint is spent
+	delivered *chainntnfs.SpendDetail
+}
+
+type confRegistration struct {
+	txHash   chainhash.Hash
+	numConfs uint32
+	event    *chainntnfs.ConfirmationEvent
+}
+
+func NewEsploraNotifier(cfg *config.BTCConfig) (*EsploraNotifier, error) {
+	client, err := NewEsploraClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &EsploraNotifier{
+		client:        client,
+		pollInterval:  cfg.TxPollingInterval,
+		registrations: make(map[uint64]*spendRegistration),
+		confirmations: make(map[uint64]*confRegistration),
+		quit:          make(chan struct{}),
+	}, nil
+}
+
+func (n *EsploraNotifier) Start() error {
+	n.startOnce.Do(func() {
+		n.wg.Add(1)
+		go n.pollSpends()
+	})
+	return nil
+}
+
+func (n *EsploraNotifier) Stop() {
+	n.stopOnce.Do(func() {
+		close(n.quit)
+	})
+	n.wg.Wait()
+}
+
+// RegisterSpendNtfn registers outpoint for polling. pkScript and heightHint are not needed
+// as Esplora indexes spends by outpoint.
+func (n *EsploraNotifier) RegisterSpendNtfn(
+	outpoint *wire.OutPoint, _ []byte, _ uint32,
+) (*chainntnfs.SpendEvent, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	id := n.nextID
+	n.nextID++
+
+	event := chainntnfs.NewSpendEvent(func() {
+		n.mu.Lock()
+		defer n.mu.Unlock()
+		delete(n.registrations, id)
+	})
+	n.registrations[id] = &spendRegistration{outpoint: *outpoint, event: event}
+
+	return event, nil
+}
+
+// RegisterConfirmationsNtfn registers tx for polling until it has numConfs confirmations.
+// pkScript, heightHint and options are not needed as Esplora indexes txs by hash.
+func (n *EsploraNotifier) RegisterConfirmationsNtfn(
+	txHash *chainhash.Hash, _ []byte, numConfs, _ uint32, _ ...chainntnfs.NotifierOption,
+) (*chainntnfs.ConfirmationEvent, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	id := n.nextID
+	n.nextID++
+
+	event := chainntnfs.NewConfirmationEvent(numConfs, func() {
+		n.mu.Lock()
+		defer n.mu.Unlock()
+		delete(n.confirmations, id)
+	})
+	n.confirmations[id] = &confRegistration{txHash: *txHash, numConfs: max(numConfs, 1), event: event}
+
+	return event, nil
+}
+
+func (n *EsploraNotifier) pollSpends() {
+	defer n.wg.Done()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-n.quit
+		cancel()
+	}()
+
+	ticker := time.NewTicker(n.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			n.checkSpends(ctx)
+			n.checkConfirmations(ctx)
+		case <-n.quit:
+			return
+		}
+	}
+}
+
+// checkSpends checks every registered outpoint, failure of one of them doesn't affect the others
+// and it's checked again on the next tick
+func (n *EsploraNotifier) checkSpends(ctx context.Context) {
+	n.mu.Lock()
+	registrations := make([]*spendRegistration, 0, len(n.registrations))
+	for _, r := range n.registrations {
+		registrations = append(registrations, r)
+	}
+	n.mu.Unlock()
+
+	for _, r := range registrations {
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err := n.checkSpend(ctx, r); err != nil {
+			log.Error().
+				Stringer("outpoint", &r.outpoint).
+				Err(err).
+				Msg("failed to check outpoint spend")
+		}
+	}
+}
+
+// checkSpend delivers confirmed spend of the outpoint. Delivered spend keeps being checked
+// until the registration is cancelled, if it disappears from the chain reorg is reported
+// and the outpoint is watched for a new spend.
+func (n *EsploraNotifier) checkSpend(ctx context.Context, r *spendRegistration) error {
+	outspend, err := n.client.getOutspend(ctx, &r.outpoint)
+	if err != nil {
+		return err
+	}
+	confirmed := outspend.Spent && outspend.Status.Confirmed
+
+	n.mu.Lock()
+	delivered := r.delivered
+	n.mu.Unlock()
+
+	if delivered != nil {
+		if confirmed && outspend.TxID == delivered.SpenderTxHash.String() {
+			return nil
+		}
+
+		select {
+		case r.event.Reorg <- struct{}{}:
+			n.mu.Lock()
+			r.delivered = nil
+			n.mu.Unlock()
+		default:
+			// previous reorg hasn't been consumed yet, retry on the next tick
+		}
+		return nil
+	}
+
+	if !confirmed {
+		return nil
+	}
+
+	detail, err := n.spendDetail(ctx, &r.outpoint, outspend)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case r.event.Spend <- detail:
+		n.mu.Lock()
+		r.delivered = detail
+		n.mu.Unlock()
+	default:
+		// previous spend hasn't been consumed yet, retry on the next tick
+	}
+	return nil
+}
+
+func (n *EsploraNotifier) spendDetail(
+	ctx context.Context, outpoint *wire.OutPoint, outspend *esploraOutspend,
+) (*chainntnfs.SpendDetail, error) {
+	spenderTxHash, err := chainhash.NewHashFromStr(outspend.TxID)
+	if err != nil {
+		return nil, err
+	}
+	spendingTx, err := n.client.getTx(ctx, spenderTxHash)
+	if err != nil {
+		return nil, err
+	}
+
+	return &chainntnfs.SpendDetail{
+		SpentOutPoint:     outpoint,
+		SpenderTxHash:     spenderTxHash,
+		SpendingTx:        spendingTx,
+		SpenderInputIndex: outspend.Vin,
+		SpendingHeight:    outspend.Status.BlockHeight,
+	}, nil
+}
+
+// checkConfirmations checks every registered tx and delivers the confirmation once
+// the tx is deep enough, delivered registrations are removed
+func (n *EsploraNotifier) checkConfirmations(ctx context.Context) {
+	n.mu.Lock()
+	registrations := make(map[uint64]*confRegistration, len(n.confirmations))
+	for id, r := range n.confirmations {
+		registrations[id] = r
+	}
+	n.mu.Unlock()
+	if len(registrations) == 0 {
+		return
+	}
+
+	tipHeight, err := n.client.GetTipHeight(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to get tip height for tx confirmations")
+		return
+	}
+
+	for id, r := range registrations {
+		if ctx.Err() != nil {
+			return
+		}
+
+		confirmation, err := n.txConfirmation(ctx, r, tipHeight)
+		if err != nil {
+			log.Error().
+				Stringer("tx", &r.txHash).
+				Err(err).
+				Msg("failed to check tx confirmations")
+			continue
+		}
+		if confirmation == nil {
+			continue
+		}
+
+		r.event.Confirmed <- confirmation
+		n.mu.Lock()
+		delete(n.confirmations, id)
+		n.mu.Unlock()
+	}
+}
+
+// txConfirmation returns nil if the tx doesn't have the registered number of confirmations yet
+func (n *EsploraNotifier) txConfirmation(
+	ctx context.Context, r *confRegistration, tipHeight uint64,
+) (*chainntnfs.TxConfirmation, error) {
+	status, err := n.client.getTxStatus(ctx, &r.txHash)
+	if err != nil {
+		return nil, err
+	}
+	if status == nil || !status.Confirmed {
+		return nil, nil
+	}
+	// the block including the tx is the first confirmation
+	if tipHeight+1 < uint64(status.BlockHeight)+uint64(r.numConfs) {
+		return nil, nil
+	}
+
+	blockHash, err := chainhash.NewHashFromStr(status.BlockHash)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := n.client.getTx(ctx, &r.txHash)
+	if err != nil {
+		return nil, err
+	}
+
+	return &chainntnfs.TxConfirmation{
+		BlockHash:   blockHash,
+		BlockHeight: status.BlockHeight,
+		Tx:          tx,
+	}, nil
+}
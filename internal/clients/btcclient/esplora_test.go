@@ -0,0 +1,341 @@
+package btcclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeEsplora serves the subset of Esplora API used by the client and the notifier
+type fakeEsplora struct {
+	mu        sync.Mutex
+	tipHeight uint64
+	// height -> block timestamp, block hash is derived from the height
+	blocks map[uint32]int64
+	// outpoint -> outspend response
+	outspends map[string]*esploraOutspend
+	// txid -> raw tx hex
+	txs map[string]string
+	// txid -> tx status, unknown txs are not found
+	statuses map[string]*esploraTxStatus
+	// number of requests to fail before responding successfully
+	failures int
+}
+
+func newFakeEsplora(t *testing.T) (*fakeEsplora, *httptest.Server) {
+	f := &fakeEsplora{
+		blocks:    make(map[uint32]int64),
+		outspends: make(map[string]*esploraOutspend),
+		txs:       make(map[string]string),
+		statuses:  make(map[string]*esploraTxStatus),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /blocks/tip/height", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, f.tipHeight)
+	})
+	mux.HandleFunc("GET /block-height/{height}", func(w http.ResponseWriter, r *http.Request) {
+		var height uint32
+		fmt.Sscan(r.PathValue("height"), &height)
+		if _, ok := f.blocks[height]; !ok {
+			http.Error(w, "Block not found", http.StatusNotFound)
+			return
+		}
+		fmt.Fprintf(w, "hash%d", height)
+	})
+	mux.HandleFunc("GET /block/{hash}", func(w http.ResponseWriter, r *http.Request) {
+		var height uint32
+		fmt.Sscanf(r.PathValue("hash"), "hash%d", &height)
+		json.NewEncoder(w).Encode(map[string]any{"height": height, "timestamp": f.blocks[height]})
+	})
+	mux.HandleFunc("GET /tx/{txid}/outspend/{vout}", func(w http.ResponseWriter, r *http.Request) {
+		outspend, ok := f.outspends[r.PathValue("txid")+":"+r.PathValue("vout")]
+		if !ok {
+			outspend = &esploraOutspend{}
+		}
+		json.NewEncoder(w).Encode(outspend)
+	})
+	mux.HandleFunc("GET /tx/{txid}/hex", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, f.txs[r.PathValue("txid")])
+	})
+	mux.HandleFunc("GET /tx/{txid}/status", func(w http.ResponseWriter, r *http.Request) {
+		status, ok := f.statuses[r.PathValue("txid")]
+		if !ok {
+			http.Error(w, "Transaction not found", http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(status)
+	})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		if f.failures > 0 {
+			f.failures--
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	return f, server
+}
+
+func (f *fakeEsplora) spend(outpoint wire.OutPoint, tx *wire.MsgTx, vin uint32, height int32) {
+	var buf bytes.Buffer
+	if err := tx.Serialize(&buf); err != nil {
+		panic(err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	outspend := &esploraOutspend{Spent: true, TxID: tx.TxHash().String(), Vin: vin}
+	outspend.Status.Confirmed = height > 0
+	outspend.Status.BlockHeight = height
+	f.outspends[fmt.Sprintf("%s:%d", outpoint.Hash, outpoint.Index)] = outspend
+	f.txs[tx.TxHash().String()] = hex.EncodeToString(buf.Bytes())
+}
+
+// include stores the tx as included at height, zero height means the tx is in the mempool
+func (f *fakeEsplora) include(tx *wire.MsgTx, height uint32) {
+	var buf bytes.Buffer
+	if err := tx.Serialize(&buf); err != nil {
+		panic(err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	status := &esploraTxStatus{Confirmed: height > 0, BlockHeight: height}
+	if height > 0 {
+		status.BlockHash = chainhash.Hash{byte(height)}.String()
+	}
+	f.statuses[tx.TxHash().String()] = status
+	f.txs[tx.TxHash().String()] = hex.EncodeToString(buf.Bytes())
+}
+
+func testEsploraConfig(url string) *config.BTCConfig {
+	return &config.BTCConfig{
+		Backend:           config.BTCBackendEsplora,
+		EsploraURL:        url,
+		TxPollingInterval: 10 * time.Millisecond,
+		MaxRetryTimes:     3,
+		RetryInterval:     time.Millisecond,
+	}
+}
+
+func testSpendingTx(outpoint wire.OutPoint) *wire.MsgTx {
+	tx := wire.NewMsgTx(2)
+	tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Hash: chainhash.Hash{0xaa}}, nil, nil))
+	tx.AddTxIn(wire.NewTxIn(&outpoint, nil, nil))
+	tx.AddTxOut(wire.NewTxOut(1000, []byte{0x51}))
+	return tx
+}
+
+func TestEsploraClient(t *testing.T) {
+	fake, server := newFakeEsplora(t)
+	fake.tipHeight = 200
+	fake.blocks[150] = 1700000000
+
+	client, err := NewEsploraClient(testEsploraConfig(server.URL + "/"))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	t.Run("tip height", func(t *testing.T) {
+		height, err := client.GetTipHeight(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(200), height)
+	})
+	t.Run("block timestamp", func(t *testing.T) {
+		timestamp, err := client.GetBlockTimestamp(ctx, 150)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1700000000), timestamp)
+	})
+	t.Run("block hash", func(t *testing.T) {
+		hash, err := client.GetBlockHash(ctx, 150)
+		require.NoError(t, err)
+		assert.Equal(t, "hash150", hash)
+	})
+	t.Run("block timestamp by hash", func(t *testing.T) {
+		timestamp, err := client.GetBlockTimestampByHash(ctx, "hash150")
+		require.NoError(t, err)
+		assert.Equal(t, int64(1700000000), timestamp)
+	})
+	t.Run("unknown block", func(t *testing.T) {
+		_, err := client.GetBlockTimestamp(ctx, 151)
+		require.ErrorContains(t, err, "404")
+	})
+	t.Run("transient errors are retried", func(t *testing.T) {
+		fake.mu.Lock()
+		fake.failures = 2
+		fake.mu.Unlock()
+
+		height, err := client.GetTipHeight(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(200), height)
+	})
+	t.Run("tx in mempool", func(t *testing.T) {
+		mempoolTx := testSpendingTx(wire.OutPoint{Hash: chainhash.Hash{0x10}})
+		confirmedTx := testSpendingTx(wire.OutPoint{Hash: chainhash.Hash{0x11}})
+		unknownTx := testSpendingTx(wire.OutPoint{Hash: chainhash.Hash{0x12}})
+		fake.include(mempoolTx, 0)
+		fake.include(confirmedTx, 150)
+
+		for tx, expected := range map[*wire.MsgTx]bool{mempoolTx: true, confirmedTx: false, unknownTx: false} {
+			txHash := tx.TxHash()
+			inMempool, err := client.IsTxInMempool(ctx, &txHash)
+			require.NoError(t, err)
+			assert.Equal(t, expected, inMempool)
+		}
+	})
+}
+
+func TestEsploraNotifier(t *testing.T) {
+	fake, server := newFakeEsplora(t)
+
+	notifier, err := NewEsploraNotifier(testEsploraConfig(server.URL))
+	require.NoError(t, err)
+	require.NoError(t, notifier.Start())
+	t.Cleanup(notifier.Stop)
+
+	t.Run("confirmed spend is delivered", func(t *testing.T) {
+		outpoint := wire.OutPoint{Hash: chainhash.Hash{0x01}, Index: 1}
+		spendEv, err := notifier.RegisterSpendNtfn(&outpoint, nil, 0)
+		require.NoError(t, err)
+
+		spendingTx := testSpendingTx(outpoint)
+		// unconfirmed spend is not reported
+		fake.spend(outpoint, spendingTx, 1, 0)
+		select {
+		case <-spendEv.Spend:
+			t.Fatal("unconfirmed spend must not be delivered")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		fake.spend(outpoint, spendingTx, 1, 300)
+		select {
+		case detail := <-spendEv.Spend:
+			assert.Equal(t, outpoint, *detail.SpentOutPoint)
+			assert.Equal(t, spendingTx.TxHash(), *detail.SpenderTxHash)
+			assert.Equal(t, spendingTx.TxHash(), detail.SpendingTx.TxHash())
+			assert.Equal(t, uint32(1), detail.SpenderInputIndex)
+			assert.Equal(t, int32(300), detail.SpendingHeight)
+		case <-time.After(time.Second):
+			t.Fatal("spend was not delivered")
+		}
+	})
+	t.Run("reorged spend is reported", func(t *testing.T) {
+		outpoint := wire.OutPoint{Hash: chainhash.Hash{0x05}, Index: 0}
+		spendEv, err := notifier.RegisterSpendNtfn(&outpoint, nil, 0)
+		require.NoError(t, err)
+		t.Cleanup(spendEv.Cancel)
+
+		fake.spend(outpoint, testSpendingTx(outpoint), 1, 302)
+		select {
+		case <-spendEv.Spend:
+		case <-time.After(time.Second):
+			t.Fatal("spend was not delivered")
+		}
+
+		// spending tx is back in mempool
+		fake.spend(outpoint, testSpendingTx(outpoint), 1, 0)
+		select {
+		case <-spendEv.Reorg:
+		case <-time.After(time.Second):
+			t.Fatal("reorg was not delivered")
+		}
+
+		// the output is spent by a different tx in the new chain
+		otherTx := testSpendingTx(outpoint)
+		otherTx.TxOut[0].Value = 2000
+		fake.spend(outpoint, otherTx, 1, 303)
+		select {
+		case detail := <-spendEv.Spend:
+			assert.Equal(t, otherTx.TxHash(), *detail.SpenderTxHash)
+			assert.Equal(t, int32(303), detail.SpendingHeight)
+		case <-time.After(time.Second):
+			t.Fatal("new spend was not delivered")
+		}
+	})
+	t.Run("cancelled registration is not polled", func(t *testing.T) {
+		outpoint := wire.OutPoint{Hash: chainhash.Hash{0x02}, Index: 0}
+		spendEv, err := notifier.RegisterSpendNtfn(&outpoint, nil, 0)
+		require.NoError(t, err)
+		spendEv.Cancel()
+
+		fake.spend(outpoint, testSpendingTx(outpoint), 1, 300)
+		select {
+		case <-spendEv.Spend:
+			t.Fatal("cancelled spend must not be delivered")
+		case <-time.After(50 * time.Millisecond):
+		}
+	})
+	t.Run("confirmed tx is delivered", func(t *testing.T) {
+		fake.mu.Lock()
+		fake.tipHeight = 401
+		fake.mu.Unlock()
+
+		tx := testSpendingTx(wire.OutPoint{Hash: chainhash.Hash{0x06}})
+		txHash := tx.TxHash()
+		confEv, err := notifier.RegisterConfirmationsNtfn(&txHash, nil, 2, 0)
+		require.NoError(t, err)
+
+		// the tx has only one confirmation
+		fake.include(tx, 401)
+		select {
+		case <-confEv.Confirmed:
+			t.Fatal("tx must not be delivered before it has enough confirmations")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		fake.include(tx, 400)
+		select {
+		case confirmation := <-confEv.Confirmed:
+			assert.Equal(t, uint32(400), confirmation.BlockHeight)
+			fake.mu.Lock()
+			assert.Equal(t, fake.statuses[txHash.String()].BlockHash, confirmation.BlockHash.String())
+			fake.mu.Unlock()
+			assert.Equal(t, txHash, confirmation.Tx.TxHash())
+		case <-time.After(time.Second):
+			t.Fatal("confirmation was not delivered")
+		}
+	})
+	t.Run("failing outpoint doesn't block others", func(t *testing.T) {
+		broken := wire.OutPoint{Hash: chainhash.Hash{0x03}, Index: 0}
+		_, err := notifier.RegisterSpendNtfn(&broken, nil, 0)
+		require.NoError(t, err)
+
+		fake.mu.Lock()
+		fake.outspends[fmt.Sprintf("%s:0", broken.Hash)] = &esploraOutspend{Spent: true, TxID: "invalid"}
+		fake.outspends[fmt.Sprintf("%s:0", broken.Hash)].Status.Confirmed = true
+		fake.mu.Unlock()
+
+		outpoint := wire.OutPoint{Hash: chainhash.Hash{0x04}, Index: 0}
+		spendEv, err := notifier.RegisterSpendNtfn(&outpoint, nil, 0)
+		require.NoError(t, err)
+		fake.spend(outpoint, testSpendingTx(outpoint), 1, 301)
+
+		select {
+		case detail := <-spendEv.Spend:
+			assert.Equal(t, int32(301), detail.SpendingHeight)
+		case <-time.After(time.Second):
+			t.Fatal("spend was not delivered")
+		}
+	})
+}
@@ -1,9 +1,33 @@
 package btcclient
 
-import "context"
+import (
+	"context"
+	"fmt"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
 
 //go:generate mockery --name=BtcInterface --output=../../../tests/mocks --outpkg=mocks --filename=mock_btc_client.go
 type BtcInterface interface {
 	GetTipHeight(ctx context.Context) (uint64, error)
 	GetBlockTimestamp(ctx context.Context, height uint32) (int64, error)
+	GetBlockHash(ctx context.Context, height uint32) (string, error)
+	// GetBlockTimestampByHash returns timestamp of the block, unlike the height the hash
+	// can't refer to another block after reorg
+	GetBlockTimestampByHash(ctx context.Context, hash string) (int64, error)
+	// IsTxInMempool returns true if the tx is in the mempool, confirmed and unknown txs are not
+	IsTxInMempool(ctx context.Context, txHash *chainhash.Hash) (bool, error)
+}
+
+// NewClient creates client of the configured backend
+func NewClient(cfg *config.BTCConfig) (BtcInterface, error) {
+	switch cfg.GetBackend() {
+	case config.BTCBackendEsplora:
+		return NewEsploraClient(cfg)
+	case config.BTCBackendBitcoind:
+		return NewBTCClient(cfg)
+	default:
+		return nil, fmt.Errorf("unsupported btc backend %q", cfg.GetBackend())
+	}
 }
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
 )
 
 type btcClientWithMetrics struct {
@@ -27,6 +28,24 @@ func (b *btcClientWithMetrics) GetBlockTimestamp(ctx context.Context, height uin
 	})
 }
 
+func (b *btcClientWithMetrics) GetBlockHash(ctx context.Context, height uint32) (string, error) {
+	return runBtcClientMethodWithMetrics("GetBlockHash", func() (string, error) {
+		return b.btc.GetBlockHash(ctx, height)
+	})
+}
+
+func (b *btcClientWithMetrics) GetBlockTimestampByHash(ctx context.Context, hash string) (int64, error) {
+	return runBtcClientMethodWithMetrics("GetBlockTimestampByHash", func() (int64, error) {
+		return b.btc.GetBlockTimestampByHash(ctx, hash)
+	})
+}
+
+func (b *btcClientWithMetrics) IsTxInMempool(ctx context.Context, txHash *chainhash.Hash) (bool, error) {
+	return runBtcClientMethodWithMetrics("IsTxInMempool", func() (bool, error) {
+		return b.btc.IsTxInMempool(ctx, txHash)
+	})
+}
+
 func runBtcClientMethodWithMetrics[T any](method string, f func() (T, error)) (T, error) {
 	startTime := time.Now()
 	v, err := f()
@@ -0,0 +1,35 @@
+package config
+
+import (
+	"fmt"
+	"net"
+)
+
+// APIConfig defines the configuration of the HTTP API server
+type APIConfig struct {
+	// IP of the API server
+	Host string `mapstructure:"host"`
+	// Port of the API server, the server is disabled when it's not set
+	Port int `mapstructure:"port"`
+}
+
+func (cfg *APIConfig) Enabled() bool {
+	return cfg.Port != 0
+}
+
+func (cfg *APIConfig) Validate() error {
+	if !cfg.Enabled() {
+		return nil
+	}
+
+	if cfg.Port < 1024 || cfg.Port > 65535 {
+		return fmt.Errorf("api server port must be between 1024 and 65535 (inclusive)")
+	}
+
+	ip := net.ParseIP(cfg.Host)
+	if ip == nil {
+		return fmt.Errorf("invalid api server host: %v", cfg.Host)
+	}
+
+	return nil
+}
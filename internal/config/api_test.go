@@ -0,0 +1,28 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIConfig_Validate(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		cfg := &APIConfig{}
+		assert.False(t, cfg.Enabled())
+		assert.NoError(t, cfg.Validate())
+	})
+	t.Run("valid", func(t *testing.T) {
+		cfg := &APIConfig{Host: "0.0.0.0", Port: 8090}
+		assert.True(t, cfg.Enabled())
+		assert.NoError(t, cfg.Validate())
+	})
+	t.Run("invalid port", func(t *testing.T) {
+		cfg := &APIConfig{Host: "0.0.0.0", Port: 80}
+		assert.Error(t, cfg.Validate())
+	})
+	t.Run("invalid host", func(t *testing.T) {
+		cfg := &APIConfig{Host: "localhost", Port: 8090}
+		assert.Error(t, cfg.Validate())
+	})
+}
@@ -3,19 +3,82 @@ package config
 import (
 	"fmt"
 	"net/url"
+	"slices"
 	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+)
+
+const (
+	// defaultBBNHealthCheckInterval is the default interval of BBN endpoints health checks
+	defaultBBNHealthCheckInterval = 10 * time.Second
+)
+
+// BBNEndpointRole tells which requests BBN endpoint is suitable for
+type BBNEndpointRole string
+
+const (
+	// BBNEndpointRoleArchive is a node keeping the whole history, historical requests are routed to it
+	BBNEndpointRoleArchive BBNEndpointRole = "archive"
+	// BBNEndpointRoleTip is a pruned node, it's preferred for requests of the latest state and the subscription
+	BBNEndpointRoleTip BBNEndpointRole = "tip"
 )
 
+type BBNEndpoint struct {
+	RPCAddr string          `mapstructure:"rpc-addr"`
+	Role    BBNEndpointRole `mapstructure:"role"`
+}
+
+// EventSchemaUpgrade makes events from Height on decoded with the schema Version
+type EventSchemaUpgrade struct {
+	Height  int64                    `mapstructure:"height"`
+	Version types.EventSchemaVersion `mapstructure:"version"`
+}
+
 type BBNConfig struct {
-	RPCAddr       string        `mapstructure:"rpc-addr"`
-	Timeout       time.Duration `mapstructure:"timeout"`
-	MaxRetryTimes uint          `mapstructure:"maxretrytimes"`
-	RetryInterval time.Duration `mapstructure:"retryinterval"`
+	// RPCAddr is a single endpoint used when Endpoints are not set, it's treated as archive node
+	RPCAddr   string        `mapstructure:"rpc-addr"`
+	Endpoints []BBNEndpoint `mapstructure:"endpoints"`
+	// HealthCheckInterval is how often latest height of every endpoint is checked
+	HealthCheckInterval time.Duration `mapstructure:"health-check-interval"`
+	Timeout             time.Duration `mapstructure:"timeout"`
+	MaxRetryTimes       uint          `mapstructure:"maxretrytimes"`
+	RetryInterval       time.Duration `mapstructure:"retryinterval"`
+	// BootstrapHeight makes indexer seed empty database with the chain state at this height
+	// instead of processing all blocks from genesis. 0 means indexing from genesis.
+	BootstrapHeight uint64 `mapstructure:"bootstrap-height"`
+	// EventSchemaUpgrades are ordered by height. Events below the first upgrade are decoded
+	// with the first schema version, without upgrades all events are decoded with the latest one.
+	EventSchemaUpgrades []EventSchemaUpgrade `mapstructure:"event-schema-upgrades"`
+}
+
+// GetEndpoints returns configured endpoints falling back to RPCAddr if there are none
+func (cfg *BBNConfig) GetEndpoints() []BBNEndpoint {
+	if len(cfg.Endpoints) > 0 {
+		return cfg.Endpoints
+	}
+
+	return []BBNEndpoint{{RPCAddr: cfg.RPCAddr, Role: BBNEndpointRoleArchive}}
 }
 
 func (cfg *BBNConfig) Validate() error {
-	if _, err := url.Parse(cfg.RPCAddr); err != nil {
-		return fmt.Errorf("cfg.RPCAddr is not correctly formatted: %w", err)
+	if len(cfg.Endpoints) == 0 && cfg.RPCAddr == "" {
+		return fmt.Errorf("either cfg.RPCAddr or cfg.Endpoints must be set")
+	}
+
+	for i, endpoint := range cfg.GetEndpoints() {
+		if endpoint.RPCAddr == "" {
+			return fmt.Errorf("cfg.Endpoints[%d].RPCAddr must be set", i)
+		}
+		if _, err := url.Parse(endpoint.RPCAddr); err != nil {
+			return fmt.Errorf("cfg.Endpoints[%d].RPCAddr is not correctly formatted: %w", i, err)
+		}
+
+		switch endpoint.Role {
+		case BBNEndpointRoleArchive, BBNEndpointRoleTip:
+		default:
+			return fmt.Errorf("cfg.Endpoints[%d].Role must be one of %q, %q", i, BBNEndpointRoleArchive, BBNEndpointRoleTip)
+		}
 	}
 
 	if cfg.Timeout <= 0 {
@@ -30,5 +93,27 @@ func (cfg *BBNConfig) Validate() error {
 		return fmt.Errorf("cfg.RetryInterval must be positive")
 	}
 
+	for i, upgrade := range cfg.EventSchemaUpgrades {
+		version := slices.Index(types.EventSchemaVersions, upgrade.Version)
+		if version < 0 {
+			return fmt.Errorf("cfg.EventSchemaUpgrades[%d].Version %q is unknown", i, upgrade.Version)
+		}
+		if i == 0 {
+			continue
+		}
+		previous := cfg.EventSchemaUpgrades[i-1]
+		if upgrade.Height <= previous.Height {
+			return fmt.Errorf("cfg.EventSchemaUpgrades[%d].Height must be greater than the previous one", i)
+		}
+		if version <= slices.Index(types.EventSchemaVersions, previous.Version) {
+			return fmt.Errorf("cfg.EventSchemaUpgrades[%d].Version must be newer than the previous one", i)
+		}
+	}
+
+	// Set default for health check interval if not configured
+	if cfg.HealthCheckInterval <= 0 {
+		cfg.HealthCheckInterval = defaultBBNHealthCheckInterval
+	}
+
 	return nil
 }
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBBNConfig_ValidateEventSchemaUpgrades(t *testing.T) {
+	newConfig := func(upgrades ...EventSchemaUpgrade) *BBNConfig {
+		return &BBNConfig{
+			RPCAddr:             "http://localhost:26657",
+			Timeout:             time.Second,
+			MaxRetryTimes:       1,
+			RetryInterval:       time.Second,
+			EventSchemaUpgrades: upgrades,
+		}
+	}
+
+	t.Run("not set", func(t *testing.T) {
+		assert.NoError(t, newConfig().Validate())
+	})
+	t.Run("valid", func(t *testing.T) {
+		cfg := newConfig(
+			EventSchemaUpgrade{Height: 0, Version: types.EventSchemaV1},
+			EventSchemaUpgrade{Height: 1000, Version: types.EventSchemaV2},
+		)
+		assert.NoError(t, cfg.Validate())
+	})
+	t.Run("unknown version", func(t *testing.T) {
+		cfg := newConfig(EventSchemaUpgrade{Height: 1000, Version: "v0"})
+		assert.ErrorContains(t, cfg.Validate(), "unknown")
+	})
+	t.Run("heights not increasing", func(t *testing.T) {
+		cfg := newConfig(
+			EventSchemaUpgrade{Height: 1000, Version: types.EventSchemaV1},
+			EventSchemaUpgrade{Height: 1000, Version: types.EventSchemaV2},
+		)
+		assert.ErrorContains(t, cfg.Validate(), "Height must be greater")
+	})
+	t.Run("older version", func(t *testing.T) {
+		cfg := newConfig(
+			EventSchemaUpgrade{Height: 0, Version: types.EventSchemaV2},
+			EventSchemaUpgrade{Height: 1000, Version: types.EventSchemaV1},
+		)
+		assert.ErrorContains(t, cfg.Validate(), "Version must be newer")
+	})
+}
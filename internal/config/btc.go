@@ -2,14 +2,29 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"time"
 
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/utils"
 	"github.com/btcsuite/btcd/rpcclient"
 )
 
+// BTCBackend is the source of Bitcoin chain data used by the client and the notifier
+type BTCBackend string
+
+const (
+	// BTCBackendBitcoind uses bitcoind RPC (default)
+	BTCBackendBitcoind BTCBackend = "bitcoind"
+	// BTCBackendEsplora uses Esplora compatible REST API (e.g. blockstream.info or mempool.space)
+	BTCBackendEsplora BTCBackend = "esplora"
+)
+
 // BTCConfig defines configuration for the Bitcoin client
 type BTCConfig struct {
+	// Backend is either "bitcoind" or "esplora", empty value means "bitcoind"
+	Backend BTCBackend `mapstructure:"backend"`
+	// EsploraURL is the base url of Esplora API (e.g. https://mempool.space/signet/api), used by esplora backend
+	EsploraURL              string        `mapstructure:"esploraurl"`
 	RPCHost                 string        `mapstructure:"rpchost"`
 	RPCUser                 string        `mapstructure:"rpcuser"`
 	RPCPass                 string        `mapstructure:"rpcpass"`
@@ -44,15 +59,35 @@ func (cfg *BTCConfig) ToConnConfig() (*rpcclient.ConnConfig, error) {
 	}, nil
 }
 
-func (cfg *BTCConfig) Validate() error {
-	if cfg.RPCHost == "" {
-		return fmt.Errorf("RPC host cannot be empty")
+// GetBackend returns configured backend defaulting to bitcoind
+func (cfg *BTCConfig) GetBackend() BTCBackend {
+	if cfg.Backend == "" {
+		return BTCBackendBitcoind
 	}
-	if cfg.RPCUser == "" {
-		return fmt.Errorf("RPC user cannot be empty")
-	}
-	if cfg.RPCPass == "" {
-		return fmt.Errorf("RPC password cannot be empty")
+	return cfg.Backend
+}
+
+func (cfg *BTCConfig) Validate() error {
+	switch cfg.GetBackend() {
+	case BTCBackendBitcoind:
+		if cfg.RPCHost == "" {
+			return fmt.Errorf("RPC host cannot be empty")
+		}
+		if cfg.RPCUser == "" {
+			return fmt.Errorf("RPC user cannot be empty")
+		}
+		if cfg.RPCPass == "" {
+			return fmt.Errorf("RPC password cannot be empty")
+		}
+	case BTCBackendEsplora:
+		if cfg.EsploraURL == "" {
+			return fmt.Errorf("esplora url cannot be empty")
+		}
+		if _, err := url.ParseRequestURI(cfg.EsploraURL); err != nil {
+			return fmt.Errorf("esplora url is not correctly formatted: %w", err)
+		}
+	default:
+		return fmt.Errorf("backend should be one of %q, %q", BTCBackendBitcoind, BTCBackendEsplora)
 	}
 
 	if cfg.BlockPollingInterval <= 0 {
@@ -16,6 +16,7 @@ type Config struct {
 	Poller  PollerConfig      `mapstructure:"poller"`
 	Queue   queue.QueueConfig `mapstructure:"queue"`
 	Metrics MetricsConfig     `mapstructure:"metrics"`
+	API     APIConfig         `mapstructure:"api"`
 }
 
 func (cfg *Config) Validate() error {
@@ -35,6 +36,10 @@ func (cfg *Config) Validate() error {
 		return err
 	}
 
+	if err := cfg.API.Validate(); err != nil {
+		return err
+	}
+
 	if err := cfg.Queue.Validate(); err != nil {
 		return err
 	}
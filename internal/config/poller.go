@@ -8,13 +8,57 @@ import (
 const (
 	// defaultStatsPollingInterval is the default interval for stats polling (2 minute)
 	defaultStatsPollingInterval = 2 * time.Minute
+	// defaultUnlockProjectionPollingInterval is the default interval for unlock projections refresh
+	defaultUnlockProjectionPollingInterval = 10 * time.Minute
+	// defaultUnlockProjectionBucketBlocks is the default size of projection bucket (~1 day of BTC blocks)
+	defaultUnlockProjectionBucketBlocks = 144
+	// defaultUnlockProjectionBuckets is the default number of projection buckets (~30 days)
+	defaultUnlockProjectionBuckets = 30
+	// defaultStakingTxPollingInterval is the default interval for staking tx tracking
+	defaultStakingTxPollingInterval = time.Minute
+	// defaultStakingTxStaleBlocks is the default number of BTC blocks after which not included
+	// staking tx is flagged as stale (~1 day)
+	defaultStakingTxStaleBlocks = 144
+	// defaultStakingTxPageSize is the default number of delegations tracked per page
+	defaultStakingTxPageSize = 100
+	// defaultExpiringSoonPollingInterval is the default interval for expiring soon events
+	defaultExpiringSoonPollingInterval = time.Minute
+	// defaultExpiringSoonPageSize is the default number of delegations notified per page
+	defaultExpiringSoonPageSize = 100
 )
 
+// defaultExpiringSoonThresholds are the default numbers of BTC blocks before the expire height
+// of ACTIVE delegation at which expiring soon events are emitted (~1 week and ~1 day)
+var defaultExpiringSoonThresholds = []uint32{1008, 144}
+
+// PollerConfig configures periodic jobs. Expiry checker processes expired delegations in pages of
+// ExpiredDelegationsLimit until none are left, a delegation expires once the BTC tip is
+// ExpiryConfirmationDepth blocks past its expire height. Unlock projections are split into
+// UnlockProjectionBuckets buckets of UnlockProjectionBucketBlocks BTC blocks each.
+// Every ExpiringSoonPollingInterval expiring soon event is emitted once ACTIVE delegation is within
+// each of ExpiringSoonThresholds BTC blocks of its expire height, delegations are notified in pages
+// of ExpiringSoonPageSize. Staking txs of PENDING and VERIFIED
+// delegations are tracked every StakingTxPollingInterval in pages of StakingTxPageSize, a staking tx
+// which isn't included within StakingTxStaleBlocks BTC blocks since the delegation was created is
+// flagged as stale and no longer tracked.
 type PollerConfig struct {
 	ParamPollingInterval         time.Duration `mapstructure:"param-polling-interval"`
 	ExpiryCheckerPollingInterval time.Duration `mapstructure:"expiry-checker-polling-interval"`
 	ExpiredDelegationsLimit      uint64        `mapstructure:"expired-delegations-limit"`
+	ExpiryConfirmationDepth      uint32        `mapstructure:"expiry-confirmation-depth"`
 	StatsPollingInterval         time.Duration `mapstructure:"stats-polling-interval"`
+
+	UnlockProjectionPollingInterval time.Duration `mapstructure:"unlock-projection-polling-interval"`
+	UnlockProjectionBucketBlocks    uint32        `mapstructure:"unlock-projection-bucket-blocks"`
+	UnlockProjectionBuckets         uint32        `mapstructure:"unlock-projection-buckets"`
+
+	StakingTxPollingInterval time.Duration `mapstructure:"staking-tx-polling-interval"`
+	StakingTxStaleBlocks     uint32        `mapstructure:"staking-tx-stale-blocks"`
+	StakingTxPageSize        uint32        `mapstructure:"staking-tx-page-size"`
+
+	ExpiringSoonPollingInterval time.Duration `mapstructure:"expiring-soon-polling-interval"`
+	ExpiringSoonThresholds      []uint32      `mapstructure:"expiring-soon-thresholds"`
+	ExpiringSoonPageSize        uint32        `mapstructure:"expiring-soon-page-size"`
 }
 
 func (cfg *PollerConfig) Validate() error {
@@ -30,10 +74,45 @@ func (cfg *PollerConfig) Validate() error {
 		return errors.New("expired-delegations-limit must be positive")
 	}
 
+	if cfg.ExpiringSoonPollingInterval <= 0 {
+		cfg.ExpiringSoonPollingInterval = defaultExpiringSoonPollingInterval
+	}
+	if len(cfg.ExpiringSoonThresholds) == 0 {
+		cfg.ExpiringSoonThresholds = defaultExpiringSoonThresholds
+	}
+	for _, threshold := range cfg.ExpiringSoonThresholds {
+		if threshold == 0 {
+			return errors.New("expiring-soon-thresholds must be positive")
+		}
+	}
+	if cfg.ExpiringSoonPageSize == 0 {
+		cfg.ExpiringSoonPageSize = defaultExpiringSoonPageSize
+	}
+
 	// Set default for stats polling interval if not configured
 	if cfg.StatsPollingInterval <= 0 {
 		cfg.StatsPollingInterval = defaultStatsPollingInterval
 	}
 
+	if cfg.UnlockProjectionPollingInterval <= 0 {
+		cfg.UnlockProjectionPollingInterval = defaultUnlockProjectionPollingInterval
+	}
+	if cfg.UnlockProjectionBucketBlocks == 0 {
+		cfg.UnlockProjectionBucketBlocks = defaultUnlockProjectionBucketBlocks
+	}
+	if cfg.UnlockProjectionBuckets == 0 {
+		cfg.UnlockProjectionBuckets = defaultUnlockProjectionBuckets
+	}
+
+	if cfg.StakingTxPollingInterval <= 0 {
+		cfg.StakingTxPollingInterval = defaultStakingTxPollingInterval
+	}
+	if cfg.StakingTxStaleBlocks == 0 {
+		cfg.StakingTxStaleBlocks = defaultStakingTxStaleBlocks
+	}
+	if cfg.StakingTxPageSize == 0 {
+		cfg.StakingTxPageSize = defaultStakingTxPageSize
+	}
+
 	return nil
 }
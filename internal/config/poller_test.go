@@ -78,4 +78,47 @@ func TestPollerConfig_Validate(t *testing.T) {
 		require.Error(t, err)
 		assert.Contains(t, err.Error(), "expired-delegations-limit must be positive")
 	})
+
+	t.Run("unlock projection settings not set - should use defaults", func(t *testing.T) {
+		cfg := &PollerConfig{
+			ParamPollingInterval:         1 * time.Minute,
+			ExpiryCheckerPollingInterval: 2 * time.Minute,
+			ExpiredDelegationsLimit:      100,
+		}
+		err := cfg.Validate()
+		require.NoError(t, err)
+		assert.Equal(t, defaultUnlockProjectionPollingInterval, cfg.UnlockProjectionPollingInterval)
+		assert.Equal(t, uint32(defaultUnlockProjectionBucketBlocks), cfg.UnlockProjectionBucketBlocks)
+		assert.Equal(t, uint32(defaultUnlockProjectionBuckets), cfg.UnlockProjectionBuckets)
+	})
+
+	t.Run("staking tx tracking settings not set - should use defaults", func(t *testing.T) {
+		cfg := &PollerConfig{
+			ParamPollingInterval:         1 * time.Minute,
+			ExpiryCheckerPollingInterval: 2 * time.Minute,
+			ExpiredDelegationsLimit:      100,
+		}
+		err := cfg.Validate()
+		require.NoError(t, err)
+		assert.Equal(t, defaultStakingTxPollingInterval, cfg.StakingTxPollingInterval)
+		assert.Equal(t, uint32(defaultStakingTxStaleBlocks), cfg.StakingTxStaleBlocks)
+		assert.Equal(t, uint32(defaultStakingTxPageSize), cfg.StakingTxPageSize)
+	})
+
+	t.Run("expiring soon thresholds", func(t *testing.T) {
+		cfg := &PollerConfig{
+			ParamPollingInterval:         1 * time.Minute,
+			ExpiryCheckerPollingInterval: 2 * time.Minute,
+			ExpiredDelegationsLimit:      100,
+		}
+		require.NoError(t, cfg.Validate())
+		assert.Equal(t, defaultExpiringSoonPollingInterval, cfg.ExpiringSoonPollingInterval)
+		assert.Equal(t, defaultExpiringSoonThresholds, cfg.ExpiringSoonThresholds)
+		assert.Equal(t, uint32(defaultExpiringSoonPageSize), cfg.ExpiringSoonPageSize)
+
+		cfg.ExpiringSoonThresholds = []uint32{1000, 0}
+		err := cfg.Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "expiring-soon-thresholds must be positive")
+	})
 }
@@ -0,0 +1,50 @@
+package db
+
+import (
+	"context"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SaveAnomaly stores the anomaly unless the same anomaly of the delegation has been stored
+// already, it happens when the spend is delivered again after restart. It reports whether
+// the anomaly has been stored by this call.
+func (db *Database) SaveAnomaly(ctx context.Context, anomaly *model.Anomaly) (bool, error) {
+	filter := bson.M{
+		"staking_tx_hash_hex": anomaly.StakingTxHashHex,
+		"spending_tx_hash":    anomaly.SpendingTxHash,
+		"type":                anomaly.Type,
+	}
+	res, err := db.collection(model.AnomaliesCollection).UpdateOne(
+		ctx,
+		filter,
+		bson.M{"$setOnInsert": anomaly},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.UpsertedCount > 0, nil
+}
+
+// GetAnomalies returns anomalies of the delegation ordered by creation time
+func (db *Database) GetAnomalies(ctx context.Context, stakingTxHashHex string) ([]model.Anomaly, error) {
+	cursor, err := db.collection(model.AnomaliesCollection).Find(
+		ctx,
+		bson.M{"staking_tx_hash_hex": stakingTxHashHex},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var anomalies []model.Anomaly
+	if err := cursor.All(ctx, &anomalies); err != nil {
+		return nil, err
+	}
+
+	return anomalies, nil
+}
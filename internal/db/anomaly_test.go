@@ -0,0 +1,52 @@
+//go:build integration
+
+package db_test
+
+import (
+	"testing"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnomalies(t *testing.T) {
+	ctx := t.Context()
+	t.Cleanup(func() {
+		resetDatabase(t)
+	})
+
+	const stakingTxHash = "staking_tx_hash"
+	newAnomaly := func(spendingTxHash string) *model.Anomaly {
+		return model.NewUnknownSpendAnomaly(stakingTxHash, &model.UnknownSpend{
+			Reason:      "reason",
+			SpentOutput: model.SpentOutputStaking,
+			Details:     &model.BtcSpendDetails{TxHash: spendingTxHash, BlockHeight: 100},
+		})
+	}
+
+	save := func(spendingTxHash string) bool {
+		stored, err := testDB.SaveAnomaly(ctx, newAnomaly(spendingTxHash))
+		require.NoError(t, err)
+		return stored
+	}
+
+	assert.True(t, save("spending_tx_1"))
+	// the same spend delivered again after restart
+	assert.False(t, save("spending_tx_1"))
+	// spend in the new chain after reorg
+	assert.True(t, save("spending_tx_2"))
+
+	anomalies, err := testDB.GetAnomalies(ctx, stakingTxHash)
+	require.NoError(t, err)
+	require.Len(t, anomalies, 2)
+	assert.Equal(t, "spending_tx_1", anomalies[0].SpendingTxHash)
+	assert.Equal(t, uint32(100), anomalies[0].SpendingHeight)
+	assert.Equal(t, model.AnomalyTypeUnknownSpend, anomalies[0].Type)
+	assert.Equal(t, model.SpentOutputStaking, anomalies[0].UnknownSpend.SpentOutput)
+	assert.Equal(t, "spending_tx_2", anomalies[1].SpendingTxHash)
+
+	anomalies, err = testDB.GetAnomalies(ctx, "other")
+	require.NoError(t, err)
+	assert.Empty(t, anomalies)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
 
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
@@ -11,6 +12,7 @@ import (
 	"github.com/rs/zerolog/log"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // UpdateOption is a function that modifies update options
@@ -21,25 +23,30 @@ type updateOptions struct {
 	subState                *types.DelegationSubState
 	bbnHeight               *int64
 	btcHeight               *uint32
+	bbnTimestamp            *int64
+	btcTimestamp            *int64
 	stakingSlashingTxInfo   *slashingTxInfo
 	unbondingSlashingTxInfo *slashingTxInfo
-	withdrawalTxInfo        *withdrawalTxInfo
+	withdrawalTx            *model.WithdrawalTx
+	unbondingTxDetails      *model.BtcSpendDetails
 	stakingStartHeight      *uint32
 	stakingEndHeight        *uint32
 	stakingBTCTimestamp     *int64
 	unbondingBTCTimestamp   *int64
 	unbondingStartHeight    *uint32
 	bbnEventType            *types.EventType
+	bbnTx                   *model.BbnTx
+	nextStakingTxHash       *string
+	unknownSpend            *model.UnknownSpend
+	expectedVersion         *int64
+	noProvisionalSpends     bool
 }
 
 type slashingTxInfo struct {
 	txHex          string
 	spendingHeight uint32
 	btcTimestamp   int64
-}
-
-type withdrawalTxInfo struct {
-	txHash string
+	details        *model.SlashingTxDetails
 }
 
 // WithSubState sets the sub-state option
@@ -63,6 +70,20 @@ func WithBtcHeight(height uint32) UpdateOption {
 	}
 }
 
+// WithBbnTimestamp sets time of the BBN block the transition happened in
+func WithBbnTimestamp(timestamp int64) UpdateOption {
+	return func(opts *updateOptions) {
+		opts.bbnTimestamp = &timestamp
+	}
+}
+
+// WithBtcTimestamp sets time of the BTC block the transition happened in
+func WithBtcTimestamp(timestamp int64) UpdateOption {
+	return func(opts *updateOptions) {
+		opts.btcTimestamp = &timestamp
+	}
+}
+
 // WithStakingStartHeight sets the staking start height option
 func WithStakingStartHeight(height uint32) UpdateOption {
 	return func(opts *updateOptions) {
@@ -99,32 +120,44 @@ func WithUnbondingBTCTimestamp(btcTimestamp int64) UpdateOption {
 }
 
 // WithStakingSlashingTx sets the staking slashing transaction details
-func WithStakingSlashingTx(txHex string, spendingHeight uint32, btcTimestamp int64) UpdateOption {
+func WithStakingSlashingTx(
+	txHex string, spendingHeight uint32, btcTimestamp int64, details *model.SlashingTxDetails,
+) UpdateOption {
 	return func(opts *updateOptions) {
 		opts.stakingSlashingTxInfo = &slashingTxInfo{
 			txHex:          txHex,
 			spendingHeight: spendingHeight,
 			btcTimestamp:   btcTimestamp,
+			details:        details,
 		}
 	}
 }
 
 // WithUnbondingSlashingTx sets the unbonding slashing transaction details
-func WithUnbondingSlashingTx(txHex string, spendingHeight uint32, btcTimestamp int64) UpdateOption {
+func WithUnbondingSlashingTx(
+	txHex string, spendingHeight uint32, btcTimestamp int64, details *model.SlashingTxDetails,
+) UpdateOption {
 	return func(opts *updateOptions) {
 		opts.unbondingSlashingTxInfo = &slashingTxInfo{
 			txHex:          txHex,
 			spendingHeight: spendingHeight,
 			btcTimestamp:   btcTimestamp,
+			details:        details,
 		}
 	}
 }
 
-func WithWithdrawalTx(txHash string) UpdateOption {
+// WithWithdrawalTx sets the withdrawal transaction
+func WithWithdrawalTx(withdrawalTx *model.WithdrawalTx) UpdateOption {
 	return func(opts *updateOptions) {
-		opts.withdrawalTxInfo = &withdrawalTxInfo{
-			txHash: txHash,
-		}
+		opts.withdrawalTx = withdrawalTx
+	}
+}
+
+// WithUnbondingTxDetails sets details of the unbonding tx included in BTC
+func WithUnbondingTxDetails(details *model.BtcSpendDetails) UpdateOption {
+	return func(opts *updateOptions) {
+		opts.unbondingTxDetails = details
 	}
 }
 
@@ -135,6 +168,43 @@ func WithBbnEventType(eventType types.EventType) UpdateOption {
 	}
 }
 
+// WithBbnTx sets the Babylon tx that emitted the event causing the transition
+func WithBbnTx(bbnTx *model.BbnTx) UpdateOption {
+	return func(opts *updateOptions) {
+		opts.bbnTx = bbnTx
+	}
+}
+
+// WithNextStakingTxHash links the delegation to the delegation it was expanded into
+func WithNextStakingTxHash(stakingTxHash string) UpdateOption {
+	return func(opts *updateOptions) {
+		opts.nextStakingTxHash = &stakingTxHash
+	}
+}
+
+// WithUnknownSpend sets details of the tx that spent the delegation output in an unrecognized way
+func WithUnknownSpend(unknownSpend *model.UnknownSpend) UpdateOption {
+	return func(opts *updateOptions) {
+		opts.unknownSpend = unknownSpend
+	}
+}
+
+// WithExpectedVersion makes the update fail with ConflictError if the delegation
+// version is not the one it had when it was read
+func WithExpectedVersion(version int64) UpdateOption {
+	return func(opts *updateOptions) {
+		opts.expectedVersion = &version
+	}
+}
+
+// WithoutProvisionalSpends makes the update fail with ConflictError if the delegation has
+// unconfirmed provisional spends, a reorg would revert them under the transition
+func WithoutProvisionalSpends() UpdateOption {
+	return func(opts *updateOptions) {
+		opts.noProvisionalSpends = true
+	}
+}
+
 func (db *Database) SaveNewBTCDelegation(
 	ctx context.Context, delegationDoc *model.BTCDelegationDetails,
 ) error {
@@ -157,27 +227,283 @@ func (db *Database) SaveNewBTCDelegation(
 	return nil
 }
 
+// UpdateBTCDelegationState applies the state transition caused by trigger. The transition is looked up
+// in types.Transitions by the new state and sub state, the delegation is updated only if it's in one
+// of the transition's source states and the guard holds. Otherwise, the rejected transition is stored
+// for investigation and NotFoundError is returned. If WithExpectedVersion is passed and the delegation
+// has been updated since it was read, or WithoutProvisionalSpends is passed and the delegation has
+// provisional spends, ConflictError is returned instead. The transition to UNKNOWN_SPEND also deletes
+// timelock expires of the delegation.
 func (db *Database) UpdateBTCDelegationState(
 	ctx context.Context,
 	stakingTxHash string,
-	qualifiedPreviousStates []types.DelegationState,
+	trigger types.Trigger,
 	newState types.DelegationState,
 	opts ...UpdateOption, // Can pass multiple optional parameters
 ) error {
-	if len(qualifiedPreviousStates) == 0 {
-		return fmt.Errorf("qualified previous states array cannot be empty")
+	u, err := buildStateUpdate(stakingTxHash, trigger, newState, opts...)
+	if err != nil {
+		return err
 	}
 
-	qualifiedStateStrs := make([]string, len(qualifiedPreviousStates))
-	for i, state := range qualifiedPreviousStates {
-		qualifiedStateStrs[i] = state.String()
+	res := db.collection(model.BTCDelegationDetailsCollection).
+		FindOneAndUpdate(ctx, u.filter, u.update)
+
+	if err := res.Err(); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return db.handleUnmatchedStateUpdate(ctx, u)
+		}
+		return err
+	}
+
+	if u.deleteTimeLocks {
+		filter := bson.M{"staking_tx_hash_hex": stakingTxHash}
+		if _, err := db.collection(model.TimeLockCollection).DeleteMany(ctx, filter); err != nil {
+			return fmt.Errorf("failed to delete timelock expires of %s: %w", stakingTxHash, err)
+		}
+	}
+
+	return nil
+}
+
+// BTCDelegationStateUpdate is a single state transition applied by BulkUpdateBTCDelegationState
+type BTCDelegationStateUpdate struct {
+	StakingTxHash string
+	Trigger       types.Trigger
+	NewState      types.DelegationState
+	Options       []UpdateOption
+}
+
+// BulkUpdateBTCDelegationState applies the state updates in a single unordered bulk write.
+// The returned slice holds error of each update by index. Unlike UpdateBTCDelegationState,
+// an update of the delegation that doesn't exist or is not in qualified states is skipped
+// without an error, it's still stored as rejected transition. The update that conflicts with
+// the delegation (see UpdateBTCDelegationState) fails with ConflictError. The second return
+// value is set only if the bulk write failed as a whole.
+func (db *Database) BulkUpdateBTCDelegationState(
+	ctx context.Context, updates []BTCDelegationStateUpdate,
+) ([]error, error) {
+	errs := make([]error, len(updates))
+	if len(updates) == 0 {
+		return errs, nil
+	}
+
+	// invalid updates are not sent, index maps write model back to the update
+	var (
+		writeModels  []mongo.WriteModel
+		stateUpdates []*stateUpdate
+		index        []int
+	)
+	for i, u := range updates {
+		su, err := buildStateUpdate(u.StakingTxHash, u.Trigger, u.NewState, u.Options...)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		writeModels = append(writeModels, mongo.NewUpdateOneModel().SetFilter(su.filter).SetUpdate(su.update))
+		stateUpdates = append(stateUpdates, su)
+		index = append(index, i)
+	}
+	if len(writeModels) == 0 {
+		return errs, nil
+	}
+
+	res, err := db.collection(model.BTCDelegationDetailsCollection).
+		BulkWrite(ctx, writeModels, options.BulkWrite().SetOrdered(false))
+	failed := make(map[int]bool)
+	if err != nil {
+		var bulkErr mongo.BulkWriteException
+		if !errors.As(err, &bulkErr) || bulkErr.WriteConcernError != nil {
+			return nil, err
+		}
+		for _, writeErr := range bulkErr.WriteErrors {
+			errs[index[writeErr.Index]] = writeErr
+			failed[writeErr.Index] = true
+		}
+	}
+
+	if res != nil && res.MatchedCount+int64(len(failed)) < int64(len(writeModels)) {
+		for i, err := range db.handleUnmatchedBulkUpdates(ctx, stateUpdates, failed) {
+			if err != nil {
+				errs[index[i]] = err
+			}
+		}
+	}
+
+	return errs, nil
+}
+
+// handleUnmatchedBulkUpdates returns conflicts of the bulk write by update index and stores
+// rejected transitions. Bulk write result doesn't tell which updates haven't matched, so the
+// update is considered unmatched if the delegation isn't in the state the transition leads to.
+func (db *Database) handleUnmatchedBulkUpdates(
+	ctx context.Context, updates []*stateUpdate, failed map[int]bool,
+) []error {
+	errs := make([]error, len(updates))
+	hashes := make([]string, len(updates))
+	for i, u := range updates {
+		hashes[i] = u.stakingTxHash
+	}
+
+	cursor, err := db.collection(model.BTCDelegationDetailsCollection).Find(
+		ctx,
+		bson.M{"_id": bson.M{"$in": hashes}},
+		options.Find().SetProjection(bson.M{"state": 1, "sub_state": 1, "version": 1, "provisional_spends": 1}),
+	)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msg("failed to find delegations of rejected transitions")
+		return errs
+	}
+	var delegations []model.BTCDelegationDetails
+	if err := cursor.All(ctx, &delegations); err != nil {
+		log.Ctx(ctx).Error().Err(err).Msg("failed to find delegations of rejected transitions")
+		return errs
+	}
+	byHash := make(map[string]*model.BTCDelegationDetails, len(delegations))
+	for i := range delegations {
+		byHash[delegations[i].StakingTxHashHex] = &delegations[i]
+	}
+
+	for i, u := range updates {
+		if failed[i] {
+			continue
+		}
+		delegation := byHash[u.stakingTxHash]
+		if delegation != nil && delegation.State == u.transition.To && delegation.SubState == u.transition.SubState {
+			continue
+		}
+		if delegation != nil {
+			if errs[i] = u.conflict(delegation); errs[i] != nil {
+				continue
+			}
+		}
+		db.insertRejectedTransition(ctx, u, delegation)
+	}
+
+	return errs
+}
+
+// handleUnmatchedStateUpdate returns error of the state update that matched no delegation.
+// If the update conflicts with the delegation, ConflictError is returned. Otherwise, the transition
+// is rejected and stored for investigation, failure to store it is only logged.
+func (db *Database) handleUnmatchedStateUpdate(ctx context.Context, u *stateUpdate) error {
+	delegation, err := db.GetBTCDelegationByStakingTxHash(ctx, u.stakingTxHash)
+	switch {
+	case err == nil:
+		if err := u.conflict(delegation); err != nil {
+			return err
+		}
+		db.insertRejectedTransition(ctx, u, delegation)
+	case IsNotFoundError(err):
+		db.insertRejectedTransition(ctx, u, nil)
+	default:
+		log.Ctx(ctx).Error().
+			Err(err).
+			Str("staking_tx", u.stakingTxHash).
+			Msg("failed to get delegation of rejected transition")
+	}
+
+	return &NotFoundError{
+		Key:     u.stakingTxHash,
+		Message: "BTC delegation not found or current state is not qualified states",
+	}
+}
+
+func (db *Database) insertRejectedTransition(
+	ctx context.Context, u *stateUpdate, delegation *model.BTCDelegationDetails,
+) {
+	rejected := model.NewRejectedTransition(delegation, u.stakingTxHash, u.transition)
+	rejected.BbnHeight = u.record.BbnHeight
+	rejected.BtcHeight = u.record.BtcHeight
+
+	if err := db.SaveRejectedTransition(ctx, rejected); err != nil {
+		log.Ctx(ctx).Error().
+			Err(err).
+			Str("staking_tx", u.stakingTxHash).
+			Msg("failed to save rejected transition")
 	}
+}
+
+// SaveRejectedTransition stores the state transition the delegation couldn't take
+func (db *Database) SaveRejectedTransition(ctx context.Context, rejected *model.RejectedTransition) error {
+	_, err := db.collection(model.RejectedTransitionsCollection).InsertOne(ctx, rejected)
+	return err
+}
 
+// GetRejectedTransitions returns rejected transitions of the delegation ordered by creation time
+func (db *Database) GetRejectedTransitions(
+	ctx context.Context, stakingTxHashHex string,
+) ([]model.RejectedTransition, error) {
+	cursor, err := db.collection(model.RejectedTransitionsCollection).Find(
+		ctx,
+		bson.M{"staking_tx_hash_hex": stakingTxHashHex},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var rejected []model.RejectedTransition
+	if err := cursor.All(ctx, &rejected); err != nil {
+		return nil, err
+	}
+
+	return rejected, nil
+}
+
+// stateUpdate is the state transition translated into delegation update
+type stateUpdate struct {
+	stakingTxHash   string
+	transition      types.Transition
+	record          model.StateRecord
+	expectedVersion *int64
+	// the update requires the delegation to have no provisional spends
+	noProvisionalSpends bool
+	filter              bson.M
+	update              bson.M
+	// timelock expires of the delegation are deleted once the update is applied
+	deleteTimeLocks bool
+}
+
+// guardFilters express transition guards as conditions on the delegation document,
+// they must be kept in line with model.BTCDelegationDetails.SatisfiesGuard
+var guardFilters = map[types.Guard]bson.M{
+	types.GuardNoInclusionProof: {
+		"$or": bson.A{bson.M{"start_height": 0}, bson.M{"end_height": 0}},
+	},
+	types.GuardHasInclusionProof: {
+		"start_height": bson.M{"$gt": 0},
+		"end_height":   bson.M{"$gt": 0},
+	},
+}
+
+// buildStateUpdate builds filter and update of the state transition
+func buildStateUpdate(
+	stakingTxHash string,
+	trigger types.Trigger,
+	newState types.DelegationState,
+	opts ...UpdateOption,
+) (*stateUpdate, error) {
 	options := &updateOptions{}
 	for _, opt := range opts {
 		opt(options)
 	}
 
+	var subState types.DelegationSubState
+	if options.subState != nil {
+		subState = *options.subState
+	}
+	transition, err := types.FindTransition(trigger, newState, subState)
+	if err != nil {
+		return nil, err
+	}
+
+	qualifiedStateStrs := make([]string, len(transition.From))
+	for i, state := range transition.From {
+		qualifiedStateStrs[i] = state.String()
+	}
+
 	stateRecord := model.StateRecord{
 		State: newState,
 	}
@@ -186,6 +512,15 @@ func (db *Database) UpdateBTCDelegationState(
 		"_id":   stakingTxHash,
 		"state": bson.M{"$in": qualifiedStateStrs},
 	}
+	for key, value := range guardFilters[transition.Guard] {
+		filter[key] = value
+	}
+	if options.expectedVersion != nil {
+		filter["version"] = versionFilter(*options.expectedVersion)
+	}
+	if options.noProvisionalSpends {
+		filter["provisional_spends.0"] = bson.M{"$exists": false}
+	}
 
 	updateFields := bson.M{
 		"state": newState.String(),
@@ -199,6 +534,14 @@ func (db *Database) UpdateBTCDelegationState(
 		stateRecord.BtcHeight = *options.btcHeight
 	}
 
+	if options.bbnTimestamp != nil {
+		stateRecord.BbnTimestamp = *options.bbnTimestamp
+	}
+
+	if options.btcTimestamp != nil {
+		stateRecord.BtcTimestamp = *options.btcTimestamp
+	}
+
 	if options.subState != nil {
 		stateRecord.SubState = *options.subState
 		updateFields["sub_state"] = options.subState.String()
@@ -208,16 +551,26 @@ func (db *Database) UpdateBTCDelegationState(
 		updateFields["slashing_tx.slashing_tx_hex"] = options.stakingSlashingTxInfo.txHex
 		updateFields["slashing_tx.spending_height"] = options.stakingSlashingTxInfo.spendingHeight
 		updateFields["slashing_tx.slashing_btc_timestamp"] = options.stakingSlashingTxInfo.btcTimestamp
+		if options.stakingSlashingTxInfo.details != nil {
+			updateFields["slashing_tx.slashing_tx_details"] = options.stakingSlashingTxInfo.details
+		}
 	}
 
 	if options.unbondingSlashingTxInfo != nil {
 		updateFields["slashing_tx.unbonding_slashing_tx_hex"] = options.unbondingSlashingTxInfo.txHex
 		updateFields["slashing_tx.spending_height"] = options.unbondingSlashingTxInfo.spendingHeight
 		updateFields["slashing_tx.unbonding_slashing_btc_timestamp"] = options.unbondingSlashingTxInfo.btcTimestamp
+		if options.unbondingSlashingTxInfo.details != nil {
+			updateFields["slashing_tx.unbonding_slashing_tx_details"] = options.unbondingSlashingTxInfo.details
+		}
+	}
+
+	if options.withdrawalTx != nil {
+		updateFields["withdrawal_tx"] = options.withdrawalTx
 	}
 
-	if options.withdrawalTxInfo != nil {
-		updateFields["withdrawal_tx.tx_hash"] = options.withdrawalTxInfo.txHash
+	if options.unbondingTxDetails != nil {
+		updateFields["unbonding_tx_details"] = options.unbondingTxDetails
 	}
 
 	if options.stakingStartHeight != nil {
@@ -244,27 +597,112 @@ func (db *Database) UpdateBTCDelegationState(
 		stateRecord.BbnEventType = options.bbnEventType.ShortName()
 	}
 
+	if options.bbnTx != nil {
+		stateRecord.BbnTx = options.bbnTx
+	}
+
+	if options.nextStakingTxHash != nil {
+		updateFields["next_staking_tx_hash_hex"] = *options.nextStakingTxHash
+	}
+
+	if options.unknownSpend != nil {
+		updateFields["unknown_spend"] = options.unknownSpend
+	}
+
 	update := bson.M{
 		"$set": updateFields,
 		"$push": bson.M{
 			"state_history": stateRecord,
 		},
+		"$inc": incVersion,
 	}
 
-	res := db.collection(model.BTCDelegationDetailsCollection).
-		FindOneAndUpdate(ctx, filter, update)
+	return &stateUpdate{
+		stakingTxHash:       stakingTxHash,
+		transition:          transition,
+		record:              stateRecord,
+		expectedVersion:     options.expectedVersion,
+		noProvisionalSpends: options.noProvisionalSpends,
+		filter:              filter,
+		update:              update,
+		// outputs spent by unknown tx don't unlock through their timelock
+		deleteTimeLocks: newState == types.StateUnknownSpend,
+	}, nil
+}
 
-	if err := res.Err(); err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
-			return &NotFoundError{
+// conflict returns ConflictError if the update hasn't matched the delegation because it has been
+// changed concurrently rather than because the delegation isn't in a qualified state
+func (u *stateUpdate) conflict(delegation *model.BTCDelegationDetails) error {
+	if u.expectedVersion != nil && delegation.Version != *u.expectedVersion {
+		return &ConflictError{
+			Key:     u.stakingTxHash,
+			Message: "BTC delegation has been updated since it was read",
+		}
+	}
+	if u.noProvisionalSpends && len(delegation.ProvisionalSpends) > 0 {
+		return &ConflictError{
+			Key:     u.stakingTxHash,
+			Message: "BTC delegation has unconfirmed provisional spends",
+		}
+	}
+	return nil
+}
+
+// incVersion is added to every update of the delegation
+var incVersion = bson.M{"version": 1}
+
+// versionFilter matches delegations with the version, delegations stored
+// before versioning was introduced have no version field
+func versionFilter(version int64) any {
+	if version == 0 {
+		return bson.M{"$in": bson.A{0, nil}}
+	}
+	return version
+}
+
+// updateDelegationFields applies the update to the delegation if it matches the filter and increments
+// its version. Only WithExpectedVersion of the options is applied, with it ConflictError is returned
+// if the delegation has been updated since it was read. NotFoundError with the message is returned
+// if the delegation doesn't exist or doesn't match the filter.
+func (db *Database) updateDelegationFields(
+	ctx context.Context, stakingTxHash string, filter, update bson.M, notFoundMessage string, opts ...UpdateOption,
+) error {
+	options := &updateOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	filter["_id"] = stakingTxHash
+	if options.expectedVersion != nil {
+		filter["version"] = versionFilter(*options.expectedVersion)
+	}
+	update["$inc"] = incVersion
+
+	res, err := db.collection(model.BTCDelegationDetailsCollection).UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount > 0 {
+		return nil
+	}
+
+	if options.expectedVersion != nil {
+		delegation, err := db.GetBTCDelegationByStakingTxHash(ctx, stakingTxHash)
+		if err == nil && delegation.Version != *options.expectedVersion {
+			return &ConflictError{
 				Key:     stakingTxHash,
-				Message: "BTC delegation not found or current state is not qualified states",
+				Message: "BTC delegation has been updated since it was read",
 			}
 		}
-		return err
+		if err != nil && !IsNotFoundError(err) {
+			return err
+		}
 	}
 
-	return nil
+	return &NotFoundError{
+		Key:     stakingTxHash,
+		Message: notFoundMessage,
+	}
 }
 
 func (db *Database) GetBTCDelegationState(
@@ -277,8 +715,7 @@ func (db *Database) GetBTCDelegationState(
 	return &delegation.State, nil
 }
 
-func (db *Database) SaveBTCDelegationCovenantSignature(ctx context.Context, stakingTxHash string, covenantBtcPkHex string, signatureHex string, stakeExpansionSignatureHex string) error {
-	filter := bson.M{"_id": stakingTxHash}
+func (db *Database) SaveBTCDelegationCovenantSignature(ctx context.Context, stakingTxHash string, covenantBtcPkHex string, signatureHex string, stakeExpansionSignatureHex string, bbnTx *model.BbnTx, opts ...UpdateOption) error {
 	values := bson.M{
 		"covenant_btc_pk_hex": covenantBtcPkHex,
 		"signature_hex":       signatureHex,
@@ -286,6 +723,9 @@ func (db *Database) SaveBTCDelegationCovenantSignature(ctx context.Context, stak
 	if stakeExpansionSignatureHex != "" {
 		values["stake_expansion_signature_hex"] = stakeExpansionSignatureHex
 	}
+	if bbnTx != nil {
+		values["bbn_tx"] = bbnTx
+	}
 
 	update := bson.M{
 		"$push": bson.M{
@@ -293,10 +733,10 @@ func (db *Database) SaveBTCDelegationCovenantSignature(ctx context.Context, stak
 			"covenant_unbonding_signatures": values,
 		},
 	}
-	_, err := db.collection(model.BTCDelegationDetailsCollection).
-		UpdateOne(ctx, filter, update)
 
-	return err
+	return db.updateDelegationFields(
+		ctx, stakingTxHash, bson.M{}, update, "BTC delegation not found when saving covenant signature", opts...,
+	)
 }
 
 func (db *Database) GetBTCDelegationByStakingTxHash(
@@ -322,6 +762,31 @@ func (db *Database) GetBTCDelegationByStakingTxHash(
 	return &delegationDoc, nil
 }
 
+// FindDelegationsByFinalityProvider returns delegations to the finality provider with staking tx
+// hash greater than afterStakingTxHash, ordered by the hash, so the caller can page through them
+func (db *Database) FindDelegationsByFinalityProvider(
+	ctx context.Context, fpBtcPkHex, afterStakingTxHash string, limit int64,
+) ([]*model.BTCDelegationDetails, error) {
+	filter := bson.M{
+		"_id":                           bson.M{"$gt": afterStakingTxHash},
+		"finality_provider_btc_pks_hex": fpBtcPkHex,
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(limit)
+
+	cursor, err := db.collection(model.BTCDelegationDetailsCollection).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find delegations: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var delegations []*model.BTCDelegationDetails
+	if err := cursor.All(ctx, &delegations); err != nil {
+		return nil, fmt.Errorf("failed to decode delegations: %w", err)
+	}
+
+	return delegations, nil
+}
+
 func (db *Database) GetDelegationsByFinalityProvider(
 	ctx context.Context,
 	fpBTCPKHex string,
@@ -354,28 +819,16 @@ func (db *Database) SaveBTCDelegationSlashingTxHex(
 	stakingTxHash string,
 	slashingTxHex string,
 	spendingHeight uint32,
+	opts ...UpdateOption,
 ) error {
-	filter := bson.M{"_id": stakingTxHash}
 	update := bson.M{
 		"$set": bson.M{
 			"slashing_tx.slashing_tx_hex": slashingTxHex,
 			"slashing_tx.spending_height": spendingHeight,
 		},
 	}
-	result, err := db.collection(model.BTCDelegationDetailsCollection).
-		UpdateOne(ctx, filter, update)
-	if err != nil {
-		return err
-	}
 
-	if result.MatchedCount == 0 {
-		return &NotFoundError{
-			Key:     stakingTxHash,
-			Message: "BTC delegation not found when updating slashing tx hex",
-		}
-	}
-
-	return nil
+	return db.updateDelegationFields(ctx, stakingTxHash, bson.M{}, update, "BTC delegation not found when updating slashing tx hex", opts...)
 }
 
 func (db *Database) SaveBTCDelegationUnbondingSlashingTxHex(
@@ -383,28 +836,16 @@ func (db *Database) SaveBTCDelegationUnbondingSlashingTxHex(
 	stakingTxHash string,
 	unbondingSlashingTxHex string,
 	spendingHeight uint32,
+	opts ...UpdateOption,
 ) error {
-	filter := bson.M{"_id": stakingTxHash}
 	update := bson.M{
 		"$set": bson.M{
 			"slashing_tx.unbonding_slashing_tx_hex": unbondingSlashingTxHex,
 			"slashing_tx.spending_height":           spendingHeight,
 		},
 	}
-	result, err := db.collection(model.BTCDelegationDetailsCollection).
-		UpdateOne(ctx, filter, update)
-	if err != nil {
-		return err
-	}
 
-	if result.MatchedCount == 0 {
-		return &NotFoundError{
-			Key:     stakingTxHash,
-			Message: "BTC delegation not found when updating unbonding slashing tx hex",
-		}
-	}
-
-	return nil
+	return db.updateDelegationFields(ctx, stakingTxHash, bson.M{}, update, "BTC delegation not found when updating unbonding slashing tx hex", opts...)
 }
 
 func (db *Database) GetBTCDelegationsByStates(
@@ -434,39 +875,322 @@ func (db *Database) GetBTCDelegationsByStates(
 	return delegations, nil
 }
 
-func (db *Database) GetDelegationsWithEmptyStakerAddress(ctx context.Context) ([]model.BTCDelegationDetails, error) {
-	// either staker_babylon_address doesn't exist or contains empty string
+// FindStakingTxTrackedDelegations returns PENDING and VERIFIED delegations without inclusion proof
+// whose staking tx hasn't been flagged as stale. Only delegations with staking tx hash greater than
+// afterStakingTxHash are returned, ordered by the hash, so the caller can page through them.
+func (db *Database) FindStakingTxTrackedDelegations(
+	ctx context.Context, afterStakingTxHash string, limit int64,
+) ([]*model.BTCDelegationDetails, error) {
 	filter := bson.M{
-		"$or": []bson.M{
-			{"staker_babylon_address": bson.M{"$exists": false}},
-			{"staker_babylon_address": ""},
-		},
+		"_id": bson.M{"$gt": afterStakingTxHash},
+		"state": bson.M{"$in": []string{
+			types.StatePending.String(),
+			types.StateVerified.String(),
+		}},
+		"staking_tx_confirmation.stale": bson.M{"$ne": true},
+	}
+	for key, value := range guardFilters[types.GuardNoInclusionProof] {
+		filter[key] = value
 	}
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(limit)
 
-	cursor, err := db.collection(model.BTCDelegationDetailsCollection).
-		Find(ctx, filter)
+	cursor, err := db.collection(model.BTCDelegationDetailsCollection).Find(ctx, filter, opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to find delegations: %w", err)
+		return nil, err
 	}
 	defer cursor.Close(ctx)
 
-	var delegations []model.BTCDelegationDetails
+	var delegations []*model.BTCDelegationDetails
 	if err := cursor.All(ctx, &delegations); err != nil {
-		return nil, fmt.Errorf("failed to decode delegations: %w", err)
+		return nil, err
 	}
 
 	return delegations, nil
 }
 
-func (db *Database) UpdateDelegationStakerBabylonAddress(ctx context.Context, stakingTxHash, stakerAddr string) error {
-	filter := bson.M{"_id": stakingTxHash}
+// CountStaleStakingTxs returns number of PENDING and VERIFIED delegations whose staking tx
+// has been flagged as stale
+func (db *Database) CountStaleStakingTxs(ctx context.Context) (int64, error) {
+	filter := bson.M{
+		"state": bson.M{"$in": []string{
+			types.StatePending.String(),
+			types.StateVerified.String(),
+		}},
+		"staking_tx_confirmation.stale": true,
+	}
+	return db.collection(model.BTCDelegationDetailsCollection).CountDocuments(ctx, filter)
+}
+
+// FindExpiringSoonDelegations returns ACTIVE delegations expiring (end height minus unbonding time)
+// within threshold BTC blocks after btcTip that haven't been notified for the threshold yet,
+// ordered by end height
+func (db *Database) FindExpiringSoonDelegations(
+	ctx context.Context, btcTip, threshold uint32, limit int64,
+) ([]*model.BTCDelegationDetails, error) {
+	expireHeight := bson.M{"$subtract": bson.A{"$end_height", "$unbonding_time"}}
+	filter := bson.M{
+		"state": types.StateActive.String(),
+		// expire height is below the end height, so the index narrows the scan
+		"end_height": bson.M{"$gt": btcTip},
+		"$expr": bson.M{"$and": bson.A{
+			bson.M{"$gt": bson.A{expireHeight, btcTip}},
+			bson.M{"$lte": bson.A{expireHeight, int64(btcTip) + int64(threshold)}},
+		}},
+		"expiring_soon_notified_thresholds": bson.M{"$ne": threshold},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "end_height", Value: 1}, {Key: "_id", Value: 1}}).SetLimit(limit)
+
+	cursor, err := db.collection(model.BTCDelegationDetailsCollection).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var delegations []*model.BTCDelegationDetails
+	if err := cursor.All(ctx, &delegations); err != nil {
+		return nil, err
+	}
+
+	return delegations, nil
+}
+
+// MarkExpiringSoonNotified records that expiring soon events of the thresholds have been emitted
+func (db *Database) MarkExpiringSoonNotified(
+	ctx context.Context, stakingTxHash string, thresholds []uint32, opts ...UpdateOption,
+) error {
 	update := bson.M{
-		"$set": bson.M{
-			"staker_babylon_address": stakerAddr,
+		"$addToSet": bson.M{"expiring_soon_notified_thresholds": bson.M{"$each": thresholds}},
+	}
+
+	return db.updateDelegationFields(
+		ctx, stakingTxHash, bson.M{}, update, "BTC delegation not found when marking expiring soon notification", opts...,
+	)
+}
+
+// UpdateStakingTxConfirmation stores BTC status of the staking tx of the delegation. It's only
+// updated while the delegation is PENDING or VERIFIED, NotFoundError is returned otherwise.
+func (db *Database) UpdateStakingTxConfirmation(
+	ctx context.Context, stakingTxHash string, confirmation *model.StakingTxConfirmation, opts ...UpdateOption,
+) error {
+	filter := bson.M{
+		"state": bson.M{"$in": []string{
+			types.StatePending.String(),
+			types.StateVerified.String(),
+		}},
+	}
+	update := bson.M{
+		"$set": bson.M{"staking_tx_confirmation": confirmation},
+	}
+
+	return db.updateDelegationFields(
+		ctx, stakingTxHash, filter, update,
+		"BTC delegation not found or not in pending or verified state when updating staking tx confirmation",
+		opts...,
+	)
+}
+
+// SetNextStakingTxHash links the delegation to the delegation it was expanded into
+func (db *Database) SetNextStakingTxHash(
+	ctx context.Context, stakingTxHash string, nextStakingTxHash string, opts ...UpdateOption,
+) error {
+	update := bson.M{
+		"$set": bson.M{"next_staking_tx_hash_hex": nextStakingTxHash},
+	}
+
+	return db.updateDelegationFields(
+		ctx, stakingTxHash, bson.M{}, update, "BTC delegation not found when setting next staking tx hash", opts...,
+	)
+}
+
+func (db *Database) SetUnbondingTxDetails(
+	ctx context.Context, stakingTxHash string, details *model.BtcSpendDetails, opts ...UpdateOption,
+) error {
+	update := bson.M{
+		"$set": bson.M{"unbonding_tx_details": details},
+	}
+
+	return db.updateDelegationFields(
+		ctx, stakingTxHash, bson.M{}, update, "BTC delegation not found when setting unbonding tx details", opts...,
+	)
+}
+
+// expansionChainMember is a delegation found by following the expansion links
+type expansionChainMember struct {
+	model.BTCDelegationDetails `bson:",inline"`
+	// 0 for the delegation linked directly to the queried one
+	Depth int64 `bson:"lineage_depth"`
+}
+
+// GetExpansionChain returns all delegations of the stake expansion chain the delegation belongs to,
+// ordered from the original delegation to the latest expansion. Earlier delegations are followed
+// through previous_staking_tx_hash_hex and later ones through next_staking_tx_hash_hex.
+func (db *Database) GetExpansionChain(
+	ctx context.Context, stakingTxHash string,
+) ([]*model.BTCDelegationDetails, error) {
+	pipeline := bson.A{
+		bson.M{"$match": bson.M{"_id": stakingTxHash}},
+		bson.M{"$graphLookup": bson.M{
+			"from":             model.BTCDelegationDetailsCollection,
+			"startWith":        "$previous_staking_tx_hash_hex",
+			"connectFromField": "previous_staking_tx_hash_hex",
+			"connectToField":   "_id",
+			"as":               "previous",
+			"depthField":       "lineage_depth",
+		}},
+		bson.M{"$graphLookup": bson.M{
+			"from":             model.BTCDelegationDetailsCollection,
+			"startWith":        "$next_staking_tx_hash_hex",
+			"connectFromField": "next_staking_tx_hash_hex",
+			"connectToField":   "_id",
+			"as":               "next",
+			"depthField":       "lineage_depth",
+		}},
+	}
+
+	cursor, err := db.collection(model.BTCDelegationDetailsCollection).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		model.BTCDelegationDetails `bson:",inline"`
+		Previous                   []expansionChainMember `bson:"previous"`
+		Next                       []expansionChainMember `bson:"next"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, &NotFoundError{
+			Key:     stakingTxHash,
+			Message: "BTC delegation not found when getting expansion chain",
+		}
+	}
+	result := results[0]
+
+	// the deepest previous delegation is the original one
+	sort.Slice(result.Previous, func(i, j int) bool { return result.Previous[i].Depth > result.Previous[j].Depth })
+	sort.Slice(result.Next, func(i, j int) bool { return result.Next[i].Depth < result.Next[j].Depth })
+
+	chain := make([]*model.BTCDelegationDetails, 0, len(result.Previous)+1+len(result.Next))
+	for i := range result.Previous {
+		chain = append(chain, &result.Previous[i].BTCDelegationDetails)
+	}
+	chain = append(chain, &result.BTCDelegationDetails)
+	for i := range result.Next {
+		chain = append(chain, &result.Next[i].BTCDelegationDetails)
+	}
+
+	return chain, nil
+}
+
+func (db *Database) SaveProvisionalSpend(
+	ctx context.Context, stakingTxHash string, spend model.ProvisionalSpend, opts ...UpdateOption,
+) error {
+	update := bson.M{
+		"$push": bson.M{"provisional_spends": spend},
+	}
+
+	return db.updateDelegationFields(
+		ctx, stakingTxHash, bson.M{}, update, "BTC delegation not found when saving provisional spend", opts...,
+	)
+}
+
+func (db *Database) ConfirmProvisionalSpend(
+	ctx context.Context, stakingTxHash string, spendingTxHash string, opts ...UpdateOption,
+) error {
+	update := bson.M{
+		"$pull": bson.M{
+			"provisional_spends": bson.M{"spending_tx_hash": spendingTxHash},
 		},
 	}
-	_, err := db.collection(model.BTCDelegationDetailsCollection).
+
+	return db.updateDelegationFields(
+		ctx, stakingTxHash, bson.M{}, update, "BTC delegation not found when confirming provisional spend", opts...,
+	)
+}
+
+func (db *Database) RevertProvisionalSpend(
+	ctx context.Context, stakingTxHash string, spendingTxHash string,
+) ([]model.ProvisionalSpend, error) {
+	delegation, err := db.GetBTCDelegationByStakingTxHash(ctx, stakingTxHash)
+	if err != nil {
+		return nil, err
+	}
+
+	idx := delegation.FindProvisionalSpend(spendingTxHash)
+	if idx < 0 {
+		return nil, &NotFoundError{
+			Key:     stakingTxHash,
+			Message: "provisional spend " + spendingTxHash + " not found",
+		}
+	}
+
+	// spends applied after the reverted one depend on it, so they are reverted as well
+	// and the delegation gets back to the state before the reverted spend
+	reverted := delegation.ProvisionalSpends[idx:]
+	spend := reverted[0]
+
+	// transitions made after the provisional spends (e.g. timelock expiry) would be lost by the revert
+	last := reverted[len(reverted)-1]
+	if delegation.State != last.State || delegation.SubState != last.SubState {
+		return nil, &SupersededError{
+			Key: stakingTxHash,
+			Message: fmt.Sprintf(
+				"provisional spend %s is followed by transition to %s", spendingTxHash, delegation.State,
+			),
+		}
+	}
+
+	stateHistory := delegation.StateHistory
+	if spend.PreviousStateHistoryLength < len(stateHistory) {
+		stateHistory = stateHistory[:spend.PreviousStateHistoryLength]
+	}
+
+	set := bson.M{
+		"state":                   spend.PreviousState.String(),
+		"state_history":           stateHistory,
+		"unbonding_start_height":  spend.PreviousUnbondingStartHeight,
+		"unbonding_btc_timestamp": spend.PreviousUnbondingBTCTimestamp,
+		"unbonding_tx_details":    spend.PreviousUnbondingTxDetails,
+		"slashing_tx":             spend.PreviousSlashingTx,
+		"withdrawal_tx":           spend.PreviousWithdrawalTx,
+		"provisional_spends":      delegation.ProvisionalSpends[:idx],
+	}
+	unset := bson.M{}
+	update := bson.M{"$set": set, "$inc": incVersion}
+	if spend.PreviousSubState != "" {
+		set["sub_state"] = spend.PreviousSubState.String()
+	} else {
+		unset["sub_state"] = ""
+	}
+	// unknown spend is terminal, so it can only be the last of the reverted spends
+	if reverted[len(reverted)-1].State == types.StateUnknownSpend {
+		unset["unknown_spend"] = ""
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	// filter by version protects from concurrent updates made after the delegation was read
+	filter := bson.M{
+		"_id":     stakingTxHash,
+		"version": versionFilter(delegation.Version),
+	}
+
+	result, err := db.collection(model.BTCDelegationDetailsCollection).
 		UpdateOne(ctx, filter, update)
+	if err != nil {
+		return nil, err
+	}
 
-	return err
+	if result.MatchedCount == 0 {
+		return nil, &ConflictError{
+			Key:     stakingTxHash,
+			Message: "BTC delegation changed while reverting provisional spend",
+		}
+	}
+
+	return reverted, nil
 }
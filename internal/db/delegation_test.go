@@ -3,6 +3,7 @@
 package db_test
 
 import (
+	"slices"
 	"testing"
 
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
@@ -11,6 +12,8 @@ import (
 	"github.com/brianvoe/gofakeit/v7"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 func TestDelegation(t *testing.T) {
@@ -38,6 +41,26 @@ func TestDelegation(t *testing.T) {
 			require.NoError(t, err)
 			assert.Contains(t, items, delegation)
 		})
+		t.Run("by finality provider in pages", func(t *testing.T) {
+			fpBtcPkHex := randomBTCpk(t)
+			for _, stakingTxHash := range []string{"c", "a", "b"} {
+				delegation := createDelegation(t)
+				delegation.StakingTxHashHex = stakingTxHash
+				delegation.FinalityProviderBtcPksHex = []string{fpBtcPkHex}
+				require.NoError(t, testDB.SaveNewBTCDelegation(ctx, delegation))
+			}
+
+			first, err := testDB.FindDelegationsByFinalityProvider(ctx, fpBtcPkHex, "", 2)
+			require.NoError(t, err)
+			require.Len(t, first, 2)
+			assert.Equal(t, "a", first[0].StakingTxHashHex)
+			assert.Equal(t, "b", first[1].StakingTxHashHex)
+
+			second, err := testDB.FindDelegationsByFinalityProvider(ctx, fpBtcPkHex, "b", 2)
+			require.NoError(t, err)
+			require.Len(t, second, 1)
+			assert.Equal(t, "c", second[0].StakingTxHashHex)
+		})
 		t.Run("by states", func(t *testing.T) {
 			delegation := createDelegation(t)
 			delegation.State = types.StatePending
@@ -144,11 +167,18 @@ func TestDelegation(t *testing.T) {
 
 		signatures := []model.CovenantSignature{
 			{SignatureHex: "signature_hex_1", CovenantBtcPkHex: "covenant_btc_pk_hex_1"},
-			{SignatureHex: "signature_hex_2", CovenantBtcPkHex: "covenant_btc_pk_hex_2", StakeExpansionSignatureHex: "some_stake_expansion_signature_hex"},
+			{
+				SignatureHex:               "signature_hex_2",
+				CovenantBtcPkHex:           "covenant_btc_pk_hex_2",
+				StakeExpansionSignatureHex: "some_stake_expansion_signature_hex",
+				BbnTx:                      &model.BbnTx{Hash: "bbn_tx_hash", Index: 1, Sender: "bbn_sender"},
+			},
 		}
 		// idea is to update (push) signatures one by one and compare them with expected result (append to delegation struct)
 		for i, sig := range signatures {
-			err = testDB.SaveBTCDelegationCovenantSignature(ctx, delegation.StakingTxHashHex, sig.CovenantBtcPkHex, sig.SignatureHex, sig.StakeExpansionSignatureHex)
+			err = testDB.SaveBTCDelegationCovenantSignature(
+				ctx, delegation.StakingTxHashHex, sig.CovenantBtcPkHex, sig.SignatureHex, sig.StakeExpansionSignatureHex, sig.BbnTx,
+			)
 			require.NoError(t, err)
 
 			details, err := testDB.GetBTCDelegationByStakingTxHash(ctx, delegation.StakingTxHashHex)
@@ -156,44 +186,677 @@ func TestDelegation(t *testing.T) {
 
 			// on every iteration we expect to receive from db only already seen signatures
 			delegation.CovenantSignatures = signatures[:i+1]
+			delegation.Version++
 			assert.Equal(t, delegation, details)
 		}
 	})
 	t.Run("update state", func(t *testing.T) {
-		// empty qualified previous states
-		err := testDB.UpdateBTCDelegationState(ctx, "non-existent-staking-tx-hash", nil, types.StateActive)
+		// transition is not defined
+		err := testDB.UpdateBTCDelegationState(ctx, "non-existent-staking-tx-hash", types.TriggerExpired, types.StateActive)
 		assert.Error(t, err)
+		assert.False(t, db.IsNotFoundError(err))
 
 		// no records found
-		qualifiedStates := []types.DelegationState{types.StatePending}
-		err = testDB.UpdateBTCDelegationState(ctx, "non-existent-staking-tx-hash", qualifiedStates, types.StateActive)
+		err = testDB.UpdateBTCDelegationState(
+			ctx, "non-existent-staking-tx-hash", types.TriggerCovenantQuorumReached, types.StateActive,
+		)
 		require.Error(t, err)
 		assert.True(t, db.IsNotFoundError(err))
+		t.Run("rejected transition is stored", func(t *testing.T) {
+			delegation := createDelegation(t)
+			delegation.State = types.StateWithdrawn
+			require.NoError(t, testDB.SaveNewBTCDelegation(ctx, delegation))
+
+			err := testDB.UpdateBTCDelegationState(
+				ctx, delegation.StakingTxHashHex, types.TriggerTimelockExpiry, types.StateWithdrawable,
+				db.WithSubState(types.SubStateTimelock),
+				db.WithBtcHeight(300),
+			)
+			require.True(t, db.IsNotFoundError(err))
+
+			rejected, err := testDB.GetRejectedTransitions(ctx, delegation.StakingTxHashHex)
+			require.NoError(t, err)
+			require.Len(t, rejected, 1)
+			assert.Equal(t, types.TriggerTimelockExpiry, rejected[0].Trigger)
+			assert.Equal(t, types.StateWithdrawn, rejected[0].CurrentState)
+			assert.Equal(t, types.StateWithdrawable, rejected[0].NewState)
+			assert.Equal(t, types.SubStateTimelock, rejected[0].NewSubState)
+			assert.Equal(t, uint32(300), rejected[0].BtcHeight)
+			assert.Equal(t, model.RejectedReasonNotQualified, rejected[0].Reason)
+		})
+		t.Run("guard", func(t *testing.T) {
+			delegation := createDelegation(t)
+			delegation.State = types.StatePending
+			delegation.StartHeight, delegation.EndHeight = 0, 0
+			require.NoError(t, testDB.SaveNewBTCDelegation(ctx, delegation))
+
+			// old flow requires inclusion proof
+			err := testDB.UpdateBTCDelegationState(
+				ctx, delegation.StakingTxHashHex, types.TriggerCovenantQuorumReached, types.StateActive,
+			)
+			require.True(t, db.IsNotFoundError(err))
+
+			err = testDB.UpdateBTCDelegationState(
+				ctx, delegation.StakingTxHashHex, types.TriggerCovenantQuorumReached, types.StateVerified,
+			)
+			require.NoError(t, err)
+
+			rejected, err := testDB.GetRejectedTransitions(ctx, delegation.StakingTxHashHex)
+			require.NoError(t, err)
+			require.Len(t, rejected, 1)
+			assert.Equal(t, model.RejectedReasonGuard, rejected[0].Reason)
+		})
+		t.Run("expected version", func(t *testing.T) {
+			delegation := createDelegation(t)
+			delegation.State = types.StateActive
+			require.NoError(t, testDB.SaveNewBTCDelegation(ctx, delegation))
+
+			err := testDB.UpdateBTCDelegationState(
+				ctx, delegation.StakingTxHashHex, types.TriggerExpired, types.StateUnbonding,
+				db.WithSubState(types.SubStateTimelock),
+				db.WithExpectedVersion(delegation.Version),
+			)
+			require.NoError(t, err)
+
+			// delegation read before the update is stale
+			err = testDB.UpdateBTCDelegationState(
+				ctx, delegation.StakingTxHashHex, types.TriggerTimelockExpiry, types.StateWithdrawable,
+				db.WithSubState(types.SubStateTimelock),
+				db.WithExpectedVersion(delegation.Version),
+			)
+			require.True(t, db.IsConflictError(err))
+
+			rejected, err := testDB.GetRejectedTransitions(ctx, delegation.StakingTxHashHex)
+			require.NoError(t, err)
+			assert.Empty(t, rejected)
+
+			actual, err := testDB.GetBTCDelegationByStakingTxHash(ctx, delegation.StakingTxHashHex)
+			require.NoError(t, err)
+			assert.Equal(t, delegation.Version+1, actual.Version)
+
+			err = testDB.UpdateBTCDelegationState(
+				ctx, delegation.StakingTxHashHex, types.TriggerTimelockExpiry, types.StateWithdrawable,
+				db.WithSubState(types.SubStateTimelock),
+				db.WithExpectedVersion(actual.Version),
+			)
+			require.NoError(t, err)
+		})
+		t.Run("delegation without version", func(t *testing.T) {
+			delegation := createDelegation(t)
+			delegation.State = types.StateActive
+			require.NoError(t, testDB.SaveNewBTCDelegation(ctx, delegation))
+
+			// delegations stored before versioning was introduced have no version field
+			_, err := mongoDB.Collection(model.BTCDelegationDetailsCollection).UpdateByID(
+				ctx, delegation.StakingTxHashHex, bson.M{"$unset": bson.M{"version": ""}},
+			)
+			require.NoError(t, err)
+
+			actual, err := testDB.GetBTCDelegationByStakingTxHash(ctx, delegation.StakingTxHashHex)
+			require.NoError(t, err)
+			require.Zero(t, actual.Version)
+
+			err = testDB.UpdateBTCDelegationState(
+				ctx, delegation.StakingTxHashHex, types.TriggerExpired, types.StateUnbonding,
+				db.WithSubState(types.SubStateTimelock),
+				db.WithExpectedVersion(actual.Version),
+			)
+			require.NoError(t, err)
+
+			actual, err = testDB.GetBTCDelegationByStakingTxHash(ctx, delegation.StakingTxHashHex)
+			require.NoError(t, err)
+			assert.EqualValues(t, 1, actual.Version)
+		})
 		t.Run("with withdrawal tx option", func(t *testing.T) {
 			delegation := createDelegation(t)
 			delegation.State = types.StateActive
 			err := testDB.SaveNewBTCDelegation(ctx, delegation)
 			require.NoError(t, err)
 
-			withdrawalTxHash := "withdrawal_tx_hash"
+			fee := int64(500)
+			withdrawalTx := &model.WithdrawalTx{
+				TxHash:             "withdrawal_tx_hash",
+				DestinationAddress: "tb1qdestination",
+				Details: &model.BtcSpendDetails{
+					TxHash:         "withdrawal_tx_hash",
+					BlockHeight:    300,
+					BlockHash:      "block_hash",
+					BlockTimestamp: 1700000000,
+					SpentValue:     10000,
+					Fee:            &fee,
+					Outputs:        []model.BtcTxOutput{{Address: "tb1qdestination", Value: 9500}},
+				},
+			}
 
 			err = testDB.UpdateBTCDelegationState(
 				ctx,
 				delegation.StakingTxHashHex,
-				[]types.DelegationState{types.StateActive},
+				types.TriggerWithdrawalSpend,
 				types.StateWithdrawn,
-				db.WithWithdrawalTx(withdrawalTxHash),
+				db.WithSubState(types.SubStateTimelock),
+				db.WithWithdrawalTx(withdrawalTx),
 			)
 			require.NoError(t, err)
 
 			item, err := testDB.GetBTCDelegationByStakingTxHash(ctx, delegation.StakingTxHashHex)
 			require.NoError(t, err)
 			assert.Equal(t, types.StateWithdrawn, item.State)
-			assert.Equal(t, withdrawalTxHash, item.WithdrawalTx.TxHash)
+			assert.Equal(t, *withdrawalTx, item.WithdrawalTx)
+		})
+		t.Run("with bbn tx and timestamp options", func(t *testing.T) {
+			delegation := createDelegation(t)
+			delegation.State = types.StatePending
+			delegation.StartHeight, delegation.EndHeight = 0, 0
+			require.NoError(t, testDB.SaveNewBTCDelegation(ctx, delegation))
+
+			bbnTx := &model.BbnTx{Hash: "bbn_tx_hash", Index: 2, Sender: "bbn_sender"}
+			err := testDB.UpdateBTCDelegationState(
+				ctx,
+				delegation.StakingTxHashHex,
+				types.TriggerCovenantQuorumReached,
+				types.StateVerified,
+				db.WithBbnTx(bbnTx),
+				db.WithBbnTimestamp(1700000000),
+				db.WithBtcTimestamp(1700000600),
+			)
+			require.NoError(t, err)
+
+			item, err := testDB.GetBTCDelegationByStakingTxHash(ctx, delegation.StakingTxHashHex)
+			require.NoError(t, err)
+			require.NotEmpty(t, item.StateHistory)
+			record := item.StateHistory[len(item.StateHistory)-1]
+			assert.Equal(t, bbnTx, record.BbnTx)
+			assert.Equal(t, int64(1700000000), record.BbnTimestamp)
+			assert.Equal(t, int64(1700000600), record.BtcTimestamp)
 		})
 	})
 }
 
+func TestExpiringSoonDelegations(t *testing.T) {
+	ctx := t.Context()
+	t.Cleanup(func() {
+		resetDatabase(t)
+	})
+
+	const unbondingTime = uint32(100)
+	// delegations are saved by the height babylon expires them at
+	save := func(state types.DelegationState, expireHeight uint32) *model.BTCDelegationDetails {
+		delegation := createDelegation(t)
+		delegation.State = state
+		delegation.EndHeight = expireHeight + unbondingTime
+		delegation.UnbondingTime = unbondingTime
+		delegation.ExpiringSoonNotifiedThresholds = nil
+		require.NoError(t, testDB.SaveNewBTCDelegation(ctx, delegation))
+		return delegation
+	}
+
+	const btcTip = uint32(1000)
+	second := save(types.StateActive, btcTip+144)
+	first := save(types.StateActive, btcTip+1)
+	// already expired, beyond the threshold or not active
+	save(types.StateActive, btcTip)
+	save(types.StateActive, btcTip+145)
+	save(types.StateUnbonding, btcTip+1)
+	// end height is within the threshold, but it has expired already
+	save(types.StateActive, btcTip+144-unbondingTime)
+
+	delegations, err := testDB.FindExpiringSoonDelegations(ctx, btcTip, 144, 10)
+	require.NoError(t, err)
+	require.Len(t, delegations, 2)
+	assert.Equal(t, first.StakingTxHashHex, delegations[0].StakingTxHashHex)
+	assert.Equal(t, second.StakingTxHashHex, delegations[1].StakingTxHashHex)
+
+	require.NoError(t, testDB.MarkExpiringSoonNotified(ctx, first.StakingTxHashHex, []uint32{144, 1008}))
+	// marking again doesn't duplicate thresholds
+	require.NoError(t, testDB.MarkExpiringSoonNotified(ctx, first.StakingTxHashHex, []uint32{144}))
+
+	delegations, err = testDB.FindExpiringSoonDelegations(ctx, btcTip, 144, 10)
+	require.NoError(t, err)
+	require.Len(t, delegations, 1)
+	assert.Equal(t, second.StakingTxHashHex, delegations[0].StakingTxHashHex)
+
+	marked, err := testDB.GetBTCDelegationByStakingTxHash(ctx, first.StakingTxHashHex)
+	require.NoError(t, err)
+	assert.Equal(t, []uint32{144, 1008}, marked.ExpiringSoonNotifiedThresholds)
+	assert.Equal(t, int64(2), marked.Version)
+
+	err = testDB.MarkExpiringSoonNotified(ctx, "non-existent", []uint32{144})
+	assert.True(t, db.IsNotFoundError(err))
+}
+
+func TestExpansionChain(t *testing.T) {
+	ctx := t.Context()
+	t.Cleanup(func() {
+		resetDatabase(t)
+	})
+
+	save := func(state types.DelegationState, previous string) *model.BTCDelegationDetails {
+		delegation := createDelegation(t)
+		delegation.State = state
+		delegation.PreviousStakingTxHashHex = previous
+		delegation.NextStakingTxHashHex = ""
+		require.NoError(t, testDB.SaveNewBTCDelegation(ctx, delegation))
+		return delegation
+	}
+
+	first := save(types.StateExpanded, "")
+	second := save(types.StateExpanded, first.StakingTxHashHex)
+	third := save(types.StateActive, second.StakingTxHashHex)
+	// expansion that wasn't included on BTC is not linked from the expanded delegation
+	save(types.StateVerified, third.StakingTxHashHex)
+	require.NoError(t, testDB.SetNextStakingTxHash(ctx, first.StakingTxHashHex, second.StakingTxHashHex))
+	require.NoError(t, testDB.SetNextStakingTxHash(ctx, second.StakingTxHashHex, third.StakingTxHashHex))
+
+	expected := []string{first.StakingTxHashHex, second.StakingTxHashHex, third.StakingTxHashHex}
+	for _, member := range expected {
+		chain, err := testDB.GetExpansionChain(ctx, member)
+		require.NoError(t, err)
+		hashes := make([]string, len(chain))
+		for i, delegation := range chain {
+			hashes[i] = delegation.StakingTxHashHex
+		}
+		assert.Equal(t, expected, hashes)
+	}
+
+	linked, err := testDB.GetBTCDelegationByStakingTxHash(ctx, first.StakingTxHashHex)
+	require.NoError(t, err)
+	assert.Equal(t, second.StakingTxHashHex, linked.NextStakingTxHashHex)
+	assert.Equal(t, int64(1), linked.Version)
+
+	single := save(types.StateActive, "")
+	chain, err := testDB.GetExpansionChain(ctx, single.StakingTxHashHex)
+	require.NoError(t, err)
+	require.Len(t, chain, 1)
+	assert.Equal(t, single.StakingTxHashHex, chain[0].StakingTxHashHex)
+
+	_, err = testDB.GetExpansionChain(ctx, "non-existent")
+	assert.True(t, db.IsNotFoundError(err))
+	err = testDB.SetNextStakingTxHash(ctx, "non-existent", single.StakingTxHashHex)
+	assert.True(t, db.IsNotFoundError(err))
+}
+
+func TestSetUnbondingTxDetails(t *testing.T) {
+	ctx := t.Context()
+	t.Cleanup(func() {
+		resetDatabase(t)
+	})
+
+	// the state was changed by the BBN event before the unbonding tx was seen on BTC
+	delegation := createDelegation(t)
+	delegation.State = types.StateUnbonding
+	delegation.SubState = types.SubStateEarlyUnbonding
+	require.NoError(t, testDB.SaveNewBTCDelegation(ctx, delegation))
+
+	details := &model.BtcSpendDetails{TxHash: "unbonding_tx_hash", BlockHeight: 300, BlockHash: "block_hash"}
+	require.NoError(t, testDB.SetUnbondingTxDetails(ctx, delegation.StakingTxHashHex, details))
+
+	actual, err := testDB.GetBTCDelegationByStakingTxHash(ctx, delegation.StakingTxHashHex)
+	require.NoError(t, err)
+	assert.Equal(t, details, actual.UnbondingTxDetails)
+	assert.Equal(t, types.StateUnbonding, actual.State)
+	assert.Equal(t, delegation.Version+1, actual.Version)
+
+	err = testDB.SetUnbondingTxDetails(ctx, "non-existent", details)
+	assert.True(t, db.IsNotFoundError(err))
+
+	// the delegation has been updated since the version was read
+	err = testDB.SetUnbondingTxDetails(ctx, delegation.StakingTxHashHex, details, db.WithExpectedVersion(delegation.Version))
+	assert.True(t, db.IsConflictError(err))
+	err = testDB.SetUnbondingTxDetails(ctx, delegation.StakingTxHashHex, details, db.WithExpectedVersion(actual.Version))
+	require.NoError(t, err)
+	err = testDB.SetUnbondingTxDetails(ctx, "non-existent", details, db.WithExpectedVersion(0))
+	assert.True(t, db.IsNotFoundError(err))
+}
+
+func TestUpdateStakingTxConfirmation(t *testing.T) {
+	ctx := t.Context()
+	t.Cleanup(func() {
+		resetDatabase(t)
+	})
+
+	save := func(state types.DelegationState) *model.BTCDelegationDetails {
+		delegation := createDelegation(t)
+		delegation.State = state
+		delegation.StakingTxConfirmation = nil
+		require.NoError(t, testDB.SaveNewBTCDelegation(ctx, delegation))
+		return delegation
+	}
+
+	verified := save(types.StateVerified)
+	confirmation := &model.StakingTxConfirmation{
+		TrackingStartHeight: 100,
+		BlockHeight:         102,
+		BlockHash:           "block_hash",
+		Confirmations:       3,
+		UpdatedAt:           1700000000,
+	}
+	require.NoError(t, testDB.UpdateStakingTxConfirmation(ctx, verified.StakingTxHashHex, confirmation))
+
+	updated, err := testDB.GetBTCDelegationByStakingTxHash(ctx, verified.StakingTxHashHex)
+	require.NoError(t, err)
+	assert.Equal(t, confirmation, updated.StakingTxConfirmation)
+	assert.Equal(t, int64(1), updated.Version)
+
+	// only pre-approval states are tracked
+	active := save(types.StateActive)
+	err = testDB.UpdateStakingTxConfirmation(ctx, active.StakingTxHashHex, confirmation)
+	assert.True(t, db.IsNotFoundError(err))
+	err = testDB.UpdateStakingTxConfirmation(ctx, "non-existent", confirmation)
+	assert.True(t, db.IsNotFoundError(err))
+}
+
+func TestFindStakingTxTrackedDelegations(t *testing.T) {
+	ctx := t.Context()
+	t.Cleanup(func() {
+		resetDatabase(t)
+	})
+
+	save := func(stakingTxHash string, state types.DelegationState, startHeight uint32, stale bool) {
+		delegation := createDelegation(t)
+		delegation.StakingTxHashHex = stakingTxHash
+		delegation.State = state
+		delegation.StartHeight = startHeight
+		delegation.EndHeight = startHeight
+		delegation.StakingTxConfirmation = &model.StakingTxConfirmation{TrackingStartHeight: 100, Stale: stale}
+		require.NoError(t, testDB.SaveNewBTCDelegation(ctx, delegation))
+	}
+	save("a", types.StatePending, 0, false)
+	save("b", types.StateVerified, 0, false)
+	save("c", types.StateVerified, 0, true)
+	save("d", types.StateVerified, 0, false)
+	// has inclusion proof
+	save("e", types.StateVerified, 100, false)
+	save("f", types.StateActive, 0, false)
+	save("g", types.StatePending, 0, true)
+
+	hashes := func(delegations []*model.BTCDelegationDetails) []string {
+		var result []string
+		for _, delegation := range delegations {
+			result = append(result, delegation.StakingTxHashHex)
+		}
+		return result
+	}
+
+	page, err := testDB.FindStakingTxTrackedDelegations(ctx, "", 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, hashes(page))
+
+	page, err = testDB.FindStakingTxTrackedDelegations(ctx, "b", 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"d"}, hashes(page))
+
+	stale, err := testDB.CountStaleStakingTxs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), stale)
+}
+
+func TestProvisionalSpend(t *testing.T) {
+	ctx := t.Context()
+	t.Cleanup(func() {
+		resetDatabase(t)
+	})
+
+	// saveUnbondingDelegation saves delegation and spends its staking output through unbonding path
+	saveUnbondingDelegation := func(t *testing.T) (*model.BTCDelegationDetails, *model.BTCDelegationDetails) {
+		delegation := createDelegation(t)
+		delegation.State = types.StateActive
+		delegation.SubState = ""
+		delegation.StateHistory = []model.StateRecord{{State: types.StatePending}, {State: types.StateActive}}
+		delegation.ProvisionalSpends = nil
+		delegation.UnbondingStartHeight = 0
+		delegation.WithdrawalTx = model.WithdrawalTx{}
+		delegation.UnknownSpend = nil
+		require.NoError(t, testDB.SaveNewBTCDelegation(ctx, delegation))
+
+		err := testDB.UpdateBTCDelegationState(ctx, delegation.StakingTxHashHex,
+			types.TriggerUnbondingSpend, types.StateUnbonding,
+			db.WithSubState(types.SubStateEarlyUnbonding),
+			db.WithUnbondingStartHeight(100),
+		)
+		require.NoError(t, err)
+		require.NoError(t, testDB.SaveNewTimeLockExpire(ctx, delegation.StakingTxHashHex, 200, types.SubStateEarlyUnbonding))
+
+		after, err := testDB.GetBTCDelegationByStakingTxHash(ctx, delegation.StakingTxHashHex)
+		require.NoError(t, err)
+
+		spend := model.NewProvisionalSpend(delegation, after, "unbonding", 100)
+		require.NoError(t, testDB.SaveProvisionalSpend(ctx, delegation.StakingTxHashHex, spend))
+
+		return delegation, after
+	}
+
+	t.Run("confirm", func(t *testing.T) {
+		delegation, _ := saveUnbondingDelegation(t)
+
+		err := testDB.ConfirmProvisionalSpend(ctx, delegation.StakingTxHashHex, "unbonding")
+		require.NoError(t, err)
+
+		actual, err := testDB.GetBTCDelegationByStakingTxHash(ctx, delegation.StakingTxHashHex)
+		require.NoError(t, err)
+		assert.Empty(t, actual.ProvisionalSpends)
+		assert.Equal(t, types.StateUnbonding, actual.State)
+	})
+	t.Run("revert", func(t *testing.T) {
+		delegation, unbonding := saveUnbondingDelegation(t)
+
+		// withdrawal depends on the unbonding, so both are reverted
+		err := testDB.UpdateBTCDelegationState(ctx, delegation.StakingTxHashHex,
+			types.TriggerWithdrawalSpend, types.StateWithdrawn,
+			db.WithSubState(types.SubStateEarlyUnbonding),
+			db.WithWithdrawalTx(&model.WithdrawalTx{TxHash: "withdrawal"}),
+		)
+		require.NoError(t, err)
+		withdrawn, err := testDB.GetBTCDelegationByStakingTxHash(ctx, delegation.StakingTxHashHex)
+		require.NoError(t, err)
+		spend := model.NewProvisionalSpend(unbonding, withdrawn, "withdrawal", 101)
+		require.NoError(t, testDB.SaveProvisionalSpend(ctx, delegation.StakingTxHashHex, spend))
+
+		reverted, err := testDB.RevertProvisionalSpend(ctx, delegation.StakingTxHashHex, "unbonding")
+		require.NoError(t, err)
+		require.Len(t, reverted, 2)
+		assert.Equal(t, "unbonding", reverted[0].SpendingTxHash)
+		assert.Equal(t, "withdrawal", reverted[1].SpendingTxHash)
+
+		actual, err := testDB.GetBTCDelegationByStakingTxHash(ctx, delegation.StakingTxHashHex)
+		require.NoError(t, err)
+		assert.Equal(t, types.StateActive, actual.State)
+		assert.Empty(t, actual.SubState)
+		assert.Equal(t, delegation.StateHistory, actual.StateHistory)
+		assert.Zero(t, actual.UnbondingStartHeight)
+		assert.Empty(t, actual.WithdrawalTx.TxHash)
+		assert.Empty(t, actual.ProvisionalSpends)
+
+		// the other watcher gets reorg notification as well
+		_, err = testDB.RevertProvisionalSpend(ctx, delegation.StakingTxHashHex, "withdrawal")
+		assert.True(t, db.IsNotFoundError(err))
+	})
+	t.Run("revert superseded spend", func(t *testing.T) {
+		delegation, _ := saveUnbondingDelegation(t)
+
+		// timelock of the unbonding output expired before the reorg was noticed
+		err := testDB.UpdateBTCDelegationState(ctx, delegation.StakingTxHashHex,
+			types.TriggerTimelockExpiry, types.StateWithdrawable,
+			db.WithSubState(types.SubStateEarlyUnbonding),
+		)
+		require.NoError(t, err)
+
+		_, err = testDB.RevertProvisionalSpend(ctx, delegation.StakingTxHashHex, "unbonding")
+		assert.True(t, db.IsSupersededError(err))
+
+		actual, err := testDB.GetBTCDelegationByStakingTxHash(ctx, delegation.StakingTxHashHex)
+		require.NoError(t, err)
+		assert.Equal(t, types.StateWithdrawable, actual.State)
+		assert.Len(t, actual.ProvisionalSpends, 1)
+	})
+	t.Run("reorg after expiry", func(t *testing.T) {
+		delegation, _ := saveUnbondingDelegation(t)
+
+		// timelock of the unbonding output expires before the unbonding tx is confirmed
+		docs, err := testDB.FindExpiredDelegations(ctx, 200, primitive.NilObjectID, 100)
+		require.NoError(t, err)
+		for _, doc := range docs {
+			assert.NotEqual(t, delegation.StakingTxHashHex, doc.StakingTxHashHex)
+		}
+		errs, err := testDB.BulkUpdateBTCDelegationState(ctx, []db.BTCDelegationStateUpdate{{
+			StakingTxHash: delegation.StakingTxHashHex,
+			Trigger:       types.TriggerTimelockExpiry,
+			NewState:      types.StateWithdrawable,
+			Options: []db.UpdateOption{
+				db.WithSubState(types.SubStateEarlyUnbonding),
+				db.WithoutProvisionalSpends(),
+			},
+		}})
+		require.NoError(t, err)
+		require.Len(t, errs, 1)
+		assert.True(t, db.IsConflictError(errs[0]))
+
+		// so the reorg still reverts the unbonding
+		_, err = testDB.RevertProvisionalSpend(ctx, delegation.StakingTxHashHex, "unbonding")
+		require.NoError(t, err)
+
+		actual, err := testDB.GetBTCDelegationByStakingTxHash(ctx, delegation.StakingTxHashHex)
+		require.NoError(t, err)
+		assert.Equal(t, types.StateActive, actual.State)
+		rejected, err := testDB.GetRejectedTransitions(ctx, delegation.StakingTxHashHex)
+		require.NoError(t, err)
+		assert.Empty(t, rejected)
+	})
+	t.Run("expiry after confirmation", func(t *testing.T) {
+		delegation, _ := saveUnbondingDelegation(t)
+		require.NoError(t, testDB.ConfirmProvisionalSpend(ctx, delegation.StakingTxHashHex, "unbonding"))
+
+		docs, err := testDB.FindExpiredDelegations(ctx, 200, primitive.NilObjectID, 100)
+		require.NoError(t, err)
+		assert.True(t, slices.ContainsFunc(docs, func(doc model.TimeLockDocument) bool {
+			return doc.StakingTxHashHex == delegation.StakingTxHashHex
+		}))
+	})
+	t.Run("revert unknown spend", func(t *testing.T) {
+		delegation, unbonding := saveUnbondingDelegation(t)
+		require.NoError(t, testDB.ConfirmProvisionalSpend(ctx, delegation.StakingTxHashHex, "unbonding"))
+		unbonding, err := testDB.GetBTCDelegationByStakingTxHash(ctx, unbonding.StakingTxHashHex)
+		require.NoError(t, err)
+
+		unknownSpend := &model.UnknownSpend{
+			Reason:      "reason",
+			SpentOutput: model.SpentOutputUnbonding,
+			Details:     &model.BtcSpendDetails{TxHash: "unknown", BlockHeight: 101},
+		}
+		err = testDB.UpdateBTCDelegationState(ctx, delegation.StakingTxHashHex,
+			types.TriggerUnknownSpend, types.StateUnknownSpend,
+			db.WithUnknownSpend(unknownSpend),
+		)
+		require.NoError(t, err)
+		// the spent unbonding output doesn't unlock anymore
+		docs, err := testDB.FindExpiredDelegations(ctx, 200, primitive.NilObjectID, 100)
+		require.NoError(t, err)
+		for _, doc := range docs {
+			assert.NotEqual(t, delegation.StakingTxHashHex, doc.StakingTxHashHex)
+		}
+		spent, err := testDB.GetBTCDelegationByStakingTxHash(ctx, delegation.StakingTxHashHex)
+		require.NoError(t, err)
+		assert.Equal(t, unknownSpend, spent.UnknownSpend)
+		spend := model.NewProvisionalSpend(unbonding, spent, "unknown", 101)
+		require.NoError(t, testDB.SaveProvisionalSpend(ctx, delegation.StakingTxHashHex, spend))
+
+		_, err = testDB.RevertProvisionalSpend(ctx, delegation.StakingTxHashHex, "unknown")
+		require.NoError(t, err)
+
+		actual, err := testDB.GetBTCDelegationByStakingTxHash(ctx, delegation.StakingTxHashHex)
+		require.NoError(t, err)
+		assert.Equal(t, types.StateUnbonding, actual.State)
+		assert.Equal(t, types.SubStateEarlyUnbonding, actual.SubState)
+		assert.Nil(t, actual.UnknownSpend)
+	})
+	t.Run("delete timelock expire", func(t *testing.T) {
+		delegation, _ := saveUnbondingDelegation(t)
+
+		err := testDB.DeleteTimeLockExpire(ctx, delegation.StakingTxHashHex, types.SubStateEarlyUnbonding)
+		require.NoError(t, err)
+
+		docs, err := testDB.FindExpiredDelegations(ctx, 200, primitive.NilObjectID, 100)
+		require.NoError(t, err)
+		for _, doc := range docs {
+			assert.NotEqual(t, delegation.StakingTxHashHex, doc.StakingTxHashHex)
+		}
+
+		// nothing to delete is not an error
+		err = testDB.DeleteTimeLockExpire(ctx, delegation.StakingTxHashHex, types.SubStateEarlyUnbonding)
+		require.NoError(t, err)
+	})
+}
+
+func TestBulkUpdateBTCDelegationState(t *testing.T) {
+	ctx := t.Context()
+	t.Cleanup(func() {
+		resetDatabase(t)
+	})
+
+	saveDelegation := func(t *testing.T, state types.DelegationState) *model.BTCDelegationDetails {
+		delegation := createDelegation(t)
+		delegation.State = state
+		delegation.StateHistory = []model.StateRecord{{State: state}}
+		require.NoError(t, testDB.SaveNewBTCDelegation(ctx, delegation))
+		return delegation
+	}
+
+	t.Run("empty", func(t *testing.T) {
+		errs, err := testDB.BulkUpdateBTCDelegationState(ctx, nil)
+		require.NoError(t, err)
+		assert.Empty(t, errs)
+	})
+	t.Run("update", func(t *testing.T) {
+		unbonding := saveDelegation(t, types.StateUnbonding)
+		withdrawn := saveDelegation(t, types.StateWithdrawn)
+
+		withdrawable := func(stakingTxHash string) db.BTCDelegationStateUpdate {
+			return db.BTCDelegationStateUpdate{
+				StakingTxHash: stakingTxHash,
+				Trigger:       types.TriggerTimelockExpiry,
+				NewState:      types.StateWithdrawable,
+				Options: []db.UpdateOption{
+					db.WithSubState(types.SubStateEarlyUnbonding),
+					db.WithBtcHeight(200),
+				},
+			}
+		}
+		updates := []db.BTCDelegationStateUpdate{
+			withdrawable(unbonding.StakingTxHashHex),
+			// not in qualified states
+			withdrawable(withdrawn.StakingTxHashHex),
+			// doesn't exist
+			withdrawable(randomStakingTxHashHex(t)),
+			// invalid update
+			{StakingTxHash: unbonding.StakingTxHashHex, NewState: types.StateWithdrawn},
+		}
+
+		errs, err := testDB.BulkUpdateBTCDelegationState(ctx, updates)
+		require.NoError(t, err)
+		require.Len(t, errs, len(updates))
+		assert.NoError(t, errs[0])
+		assert.NoError(t, errs[1])
+		assert.NoError(t, errs[2])
+		assert.Error(t, errs[3])
+
+		actual, err := testDB.GetBTCDelegationByStakingTxHash(ctx, unbonding.StakingTxHashHex)
+		require.NoError(t, err)
+		assert.Equal(t, types.StateWithdrawable, actual.State)
+		assert.Equal(t, types.SubStateEarlyUnbonding, actual.SubState)
+		require.Len(t, actual.StateHistory, 2)
+		assert.Equal(t, uint32(200), actual.StateHistory[1].BtcHeight)
+
+		actual, err = testDB.GetBTCDelegationByStakingTxHash(ctx, withdrawn.StakingTxHashHex)
+		require.NoError(t, err)
+		assert.Equal(t, withdrawn.State, actual.State)
+		assert.Equal(t, withdrawn.StateHistory, actual.StateHistory)
+
+		rejected, err := testDB.GetRejectedTransitions(ctx, withdrawn.StakingTxHashHex)
+		require.NoError(t, err)
+		require.Len(t, rejected, 1)
+		assert.Equal(t, types.TriggerTimelockExpiry, rejected[0].Trigger)
+	})
+}
+
 func createDelegation(t *testing.T) *model.BTCDelegationDetails {
 	var delegation model.BTCDelegationDetails
 	err := gofakeit.Struct(&delegation)
@@ -204,6 +867,7 @@ func createDelegation(t *testing.T) *model.BTCDelegationDetails {
 	delegation.StakingAmount = 0
 	delegation.StakingBTCTimestamp = 0
 	delegation.UnbondingBTCTimestamp = 0
+	delegation.Version = 0
 
 	return &delegation
 }
@@ -42,3 +42,43 @@ func (e *NotFoundError) Is(target error) bool {
 func IsNotFoundError(err error) bool {
 	return errors.Is(err, &NotFoundError{})
 }
+
+// ConflictError is returned when the document was changed by another writer
+// after it was read, the caller should read it again and retry
+type ConflictError struct {
+	Key     string
+	Message string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Message, e.Key)
+}
+
+func (e *ConflictError) Is(target error) bool {
+	_, ok := target.(*ConflictError)
+	return ok
+}
+
+func IsConflictError(err error) bool {
+	return errors.Is(err, &ConflictError{})
+}
+
+// SupersededError is returned when the change is no longer applicable because
+// the document has moved on since, e.g. a later transition follows the one being reverted
+type SupersededError struct {
+	Key     string
+	Message string
+}
+
+func (e *SupersededError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Message, e.Key)
+}
+
+func (e *SupersededError) Is(target error) bool {
+	_, ok := target.(*SupersededError)
+	return ok
+}
+
+func IsSupersededError(err error) bool {
+	return errors.Is(err, &SupersededError{})
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 func (db *Database) SaveNewFinalityProvider(
@@ -123,6 +124,28 @@ func (db *Database) GetFinalityProviderByBtcPk(
 	return &fpDoc, nil
 }
 
+// FindFinalityProviders returns finality providers with BTC public key greater than afterBtcPk,
+// ordered by the key, so the caller can page through them
+func (db *Database) FindFinalityProviders(
+	ctx context.Context, afterBtcPk string, limit int64,
+) ([]*model.FinalityProviderDetails, error) {
+	filter := bson.M{"_id": bson.M{"$gt": afterBtcPk}}
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(limit)
+
+	cursor, err := db.collection(model.FinalityProviderDetailsCollection).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var finalityProviders []*model.FinalityProviderDetails
+	if err = cursor.All(ctx, &finalityProviders); err != nil {
+		return nil, err
+	}
+
+	return finalityProviders, nil
+}
+
 // GetAllFinalityProviders retrieves all finality providers from the database.
 // Note: MongoDB has a 16MB limit on document size for cursor results.
 // If the total size of all finality providers exceeds this limit,
@@ -144,3 +167,74 @@ func (db *Database) GetAllFinalityProviders(
 
 	return finalityProviders, nil
 }
+
+// SaveFinalityProviderHistory stores the finality provider state at the record height.
+// If the height already has a record, e.g. when the height is processed again or several
+// events changed the finality provider, it's replaced and the event types are merged.
+func (db *Database) SaveFinalityProviderHistory(
+	ctx context.Context, record *model.FinalityProviderHistoryRecord,
+) error {
+	update := bson.M{
+		"$set": bson.M{
+			"bbn_timestamp": record.BbnTimestamp,
+			"commission":    record.Commission,
+			"state":         record.State,
+			"description":   record.Description,
+			"bbn_tx":        record.BbnTx,
+		},
+		"$addToSet": bson.M{"event_types": bson.M{"$each": record.EventTypes}},
+	}
+	_, err := db.collection(model.FinalityProviderHistoryCollection).UpdateOne(
+		ctx,
+		bson.M{"btc_pk": record.BtcPk, "bbn_height": record.BbnHeight},
+		update,
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// GetFinalityProviderHistory returns history records of the finality provider ordered by height
+func (db *Database) GetFinalityProviderHistory(
+	ctx context.Context, btcPk string,
+) ([]model.FinalityProviderHistoryRecord, error) {
+	cursor, err := db.collection(model.FinalityProviderHistoryCollection).Find(
+		ctx,
+		bson.M{"btc_pk": btcPk},
+		options.Find().SetSort(bson.D{{Key: "bbn_height", Value: 1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var history []model.FinalityProviderHistoryRecord
+	if err := cursor.All(ctx, &history); err != nil {
+		return nil, err
+	}
+
+	return history, nil
+}
+
+// GetFinalityProviderCommissionAtHeight returns commission of the finality provider at the end
+// of the given BBN height, i.e. the commission of the last history record at or below it
+func (db *Database) GetFinalityProviderCommissionAtHeight(
+	ctx context.Context, btcPk string, bbnHeight int64,
+) (string, error) {
+	var record model.FinalityProviderHistoryRecord
+	err := db.collection(model.FinalityProviderHistoryCollection).FindOne(
+		ctx,
+		bson.M{"btc_pk": btcPk, "bbn_height": bson.M{"$lte": bbnHeight}},
+		options.FindOne().SetSort(bson.D{{Key: "bbn_height", Value: -1}}),
+	).Decode(&record)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return "", &NotFoundError{
+				Key:     btcPk,
+				Message: "finality provider has no history at the height",
+			}
+		}
+		return "", err
+	}
+
+	return record.Commission, nil
+}
@@ -137,6 +137,46 @@ func TestFinalityProvider(t *testing.T) {
 			assert.Equal(t, fpUpdate.Description, foundFP.Description)
 		})
 	})
+	t.Run("history", func(t *testing.T) {
+		btcPk := randomBTCpk(t)
+		fp := &model.FinalityProviderDetails{
+			BtcPk:      btcPk,
+			Commission: "0.1",
+			State:      bbntypes.FinalityProviderStatus_FINALITY_PROVIDER_STATUS_INACTIVE.String(),
+		}
+		created := model.NewFinalityProviderHistoryRecord(fp, "created", 10, 1000, nil)
+		require.NoError(t, testDB.SaveFinalityProviderHistory(ctx, created))
+
+		fp.Commission = "0.2"
+		fp.Description.Moniker = "moniker"
+		edited := model.NewFinalityProviderHistoryRecord(fp, "edited", 20, 2000, &model.BbnTx{Hash: "hash"})
+		require.NoError(t, testDB.SaveFinalityProviderHistory(ctx, edited))
+
+		// another event of the same height replaces the record
+		fp.State = bbntypes.FinalityProviderStatus_FINALITY_PROVIDER_STATUS_ACTIVE.String()
+		statusChanged := model.NewFinalityProviderHistoryRecord(fp, "status", 20, 2000, nil)
+		require.NoError(t, testDB.SaveFinalityProviderHistory(ctx, statusChanged))
+		// processing the height again doesn't duplicate event types
+		require.NoError(t, testDB.SaveFinalityProviderHistory(ctx, statusChanged))
+
+		history, err := testDB.GetFinalityProviderHistory(ctx, btcPk)
+		require.NoError(t, err)
+		require.Len(t, history, 2)
+		assert.Equal(t, *created, history[0])
+		assert.Equal(t, int64(20), history[1].BbnHeight)
+		assert.Equal(t, fp.State, history[1].State)
+		assert.Equal(t, fp.Description, history[1].Description)
+		assert.Equal(t, []string{"edited", "status"}, history[1].EventTypes)
+		assert.Nil(t, history[1].BbnTx)
+
+		_, err = testDB.GetFinalityProviderCommissionAtHeight(ctx, btcPk, 9)
+		assert.True(t, db.IsNotFoundError(err))
+		for height, commission := range map[int64]string{10: "0.1", 19: "0.1", 20: "0.2", 100: "0.2"} {
+			found, err := testDB.GetFinalityProviderCommissionAtHeight(ctx, btcPk, height)
+			require.NoError(t, err)
+			assert.Equal(t, commission, found, "height %d", height)
+		}
+	})
 }
 
 func randomBTCpk(t *testing.T) string {
@@ -6,6 +6,7 @@ import (
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/bbnclient"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // FinalityProviderStatsResult represents aggregated stats for a finality provider
@@ -15,6 +16,16 @@ type FinalityProviderStatsResult struct {
 	ActiveDelegations uint64
 }
 
+// UnlockBucketResult represents aggregated unlocks of one kind in a projection bucket,
+// FpBtcPkHex is empty for unlocks of all delegations
+type UnlockBucketResult struct {
+	FpBtcPkHex  string
+	Bucket      uint32
+	Kind        string
+	Amount      uint64
+	Delegations uint64
+}
+
 //go:generate mockery --name=DbInterface --output=../../tests/mocks --outpkg=mocks --filename=mock_db_client.go
 type DbInterface interface {
 	/**
@@ -64,6 +75,37 @@ type DbInterface interface {
 	GetFinalityProviderByBtcPk(
 		ctx context.Context, btcPk string,
 	) (*model.FinalityProviderDetails, error)
+	/**
+	 * SaveFinalityProviderHistory saves the finality provider state at the BBN height.
+	 * An existing record of the same height is replaced and its event types are merged.
+	 * @param ctx The context
+	 * @param record The history record
+	 * @return An error if the operation failed
+	 */
+	SaveFinalityProviderHistory(
+		ctx context.Context, record *model.FinalityProviderHistoryRecord,
+	) error
+	/**
+	 * GetFinalityProviderHistory retrieves the history of the finality provider.
+	 * @param ctx The context
+	 * @param btcPk The BTC public key
+	 * @return The history records ordered by BBN height or an error
+	 */
+	GetFinalityProviderHistory(
+		ctx context.Context, btcPk string,
+	) ([]model.FinalityProviderHistoryRecord, error)
+	/**
+	 * GetFinalityProviderCommissionAtHeight retrieves the commission of the finality provider
+	 * at the end of the BBN height. If there is no history record at or below the height,
+	 * a NotFoundError will be returned.
+	 * @param ctx The context
+	 * @param btcPk The BTC public key
+	 * @param bbnHeight The BBN height
+	 * @return The commission or an error
+	 */
+	GetFinalityProviderCommissionAtHeight(
+		ctx context.Context, btcPk string, bbnHeight int64,
+	) (string, error)
 	/**
 	 * SaveStakingParams saves the staking parameters to the database.
 	 * @param ctx The context
@@ -90,6 +132,12 @@ type DbInterface interface {
 	SaveCheckpointParams(
 		ctx context.Context, params *bbnclient.CheckpointParams,
 	) error
+	/**
+	 * GetCheckpointParams retrieves the checkpoint parameters.
+	 * @param ctx The context
+	 * @return The checkpoint parameters or an error
+	 */
+	GetCheckpointParams(ctx context.Context) (*bbnclient.CheckpointParams, error)
 	/**
 	 * SaveNewBTCDelegation saves a new BTC delegation to the database.
 	 * If the BTC delegation already exists, DuplicateKeyError will be returned.
@@ -101,10 +149,13 @@ type DbInterface interface {
 		ctx context.Context, delegationDoc *model.BTCDelegationDetails,
 	) error
 	/**
-	 * UpdateBTCDelegationState updates a BTC delegation state in the database.
+	 * UpdateBTCDelegationState applies the state transition caused by the trigger.
+	 * Rejected transitions are stored and NotFoundError is returned. If the delegation
+	 * changed since the version passed with WithExpectedVersion, ConflictError is returned.
+	 * Timelock expires of the delegation are deleted by the transition to UNKNOWN_SPEND.
 	 * @param ctx The context
 	 * @param stakingTxHash The staking transaction hash
-	 * @param qualifiedPreviousStates The previous states that qualify for this update
+	 * @param trigger The trigger of the transition
 	 * @param newState The new state to update to
 	 * @param opts Optional parameters for the update
 	 * @return An error if the operation failed
@@ -112,10 +163,122 @@ type DbInterface interface {
 	UpdateBTCDelegationState(
 		ctx context.Context,
 		stakingTxHash string,
-		qualifiedPreviousStates []types.DelegationState,
+		trigger types.Trigger,
 		newState types.DelegationState,
 		opts ...UpdateOption,
 	) error
+	/**
+	 * BulkUpdateBTCDelegationState applies multiple BTC delegation state updates in a single
+	 * bulk write. Updates of delegations not in qualified states are skipped, updates
+	 * conflicting with the delegation fail with ConflictError.
+	 * @param ctx The context
+	 * @param updates The state updates
+	 * @return The error of each update by index or an error if the bulk write failed
+	 */
+	BulkUpdateBTCDelegationState(ctx context.Context, updates []BTCDelegationStateUpdate) ([]error, error)
+	/**
+	 * FindStakingTxTrackedDelegations retrieves PENDING and VERIFIED delegations without
+	 * inclusion proof whose staking tx isn't stale, for paging through them.
+	 * @param ctx The context
+	 * @param afterStakingTxHash Only delegations with greater staking tx hash are returned
+	 * @param limit The maximum number of delegations
+	 * @return The delegations ordered by staking tx hash or an error
+	 */
+	FindStakingTxTrackedDelegations(
+		ctx context.Context, afterStakingTxHash string, limit int64,
+	) ([]*model.BTCDelegationDetails, error)
+	/**
+	 * CountStaleStakingTxs counts PENDING and VERIFIED delegations with stale staking tx.
+	 * @param ctx The context
+	 * @return The number of delegations or an error
+	 */
+	CountStaleStakingTxs(ctx context.Context) (int64, error)
+	/**
+	 * FindExpiringSoonDelegations retrieves ACTIVE delegations expiring (end height minus unbonding
+	 * time) within threshold BTC blocks after the BTC tip that haven't been notified for the threshold yet.
+	 * @param ctx The context
+	 * @param btcTip The BTC tip height
+	 * @param threshold The threshold in BTC blocks
+	 * @param limit The maximum number of delegations
+	 * @return The delegations ordered by end height or an error
+	 */
+	FindExpiringSoonDelegations(
+		ctx context.Context, btcTip, threshold uint32, limit int64,
+	) ([]*model.BTCDelegationDetails, error)
+	/**
+	 * MarkExpiringSoonNotified records the thresholds for which expiring soon event was emitted.
+	 * @param ctx The context
+	 * @param stakingTxHash The staking tx hash
+	 * @param thresholds The thresholds in BTC blocks
+	 * @param opts Optional parameters, only WithExpectedVersion is applied
+	 * @return An error if the operation failed
+	 */
+	MarkExpiringSoonNotified(ctx context.Context, stakingTxHash string, thresholds []uint32, opts ...UpdateOption) error
+	/**
+	 * UpdateStakingTxConfirmation stores BTC status of the staking tx of PENDING or VERIFIED delegation.
+	 * @param ctx The context
+	 * @param stakingTxHash The staking tx hash
+	 * @param confirmation The staking tx status
+	 * @param opts Optional parameters, only WithExpectedVersion is applied
+	 * @return An error if the operation failed
+	 */
+	UpdateStakingTxConfirmation(
+		ctx context.Context, stakingTxHash string, confirmation *model.StakingTxConfirmation, opts ...UpdateOption,
+	) error
+	/**
+	 * SetNextStakingTxHash links the delegation to the delegation it was expanded into.
+	 * @param ctx The context
+	 * @param stakingTxHash The staking tx hash of the expanded delegation
+	 * @param nextStakingTxHash The staking tx hash of the expansion delegation
+	 * @param opts Optional parameters, only WithExpectedVersion is applied
+	 * @return An error if the operation failed
+	 */
+	SetNextStakingTxHash(ctx context.Context, stakingTxHash string, nextStakingTxHash string, opts ...UpdateOption) error
+	/**
+	 * SetUnbondingTxDetails stores details of the unbonding tx included in BTC regardless of
+	 * the delegation state, e.g. when the state was already changed by the BBN event.
+	 * @param ctx The context
+	 * @param stakingTxHash The staking tx hash
+	 * @param details The unbonding tx details
+	 * @param opts Optional parameters, only WithExpectedVersion is applied
+	 * @return An error if the operation failed
+	 */
+	SetUnbondingTxDetails(ctx context.Context, stakingTxHash string, details *model.BtcSpendDetails, opts ...UpdateOption) error
+	/**
+	 * GetExpansionChain retrieves all delegations of the stake expansion chain of the delegation.
+	 * @param ctx The context
+	 * @param stakingTxHash The staking tx hash of any delegation in the chain
+	 * @return The delegations from the original one to the latest expansion or an error
+	 */
+	GetExpansionChain(ctx context.Context, stakingTxHash string) ([]*model.BTCDelegationDetails, error)
+	/**
+	 * SaveRejectedTransition stores the state transition the delegation couldn't take.
+	 * @param ctx The context
+	 * @param rejected The rejected transition
+	 * @return An error if the operation failed
+	 */
+	SaveRejectedTransition(ctx context.Context, rejected *model.RejectedTransition) error
+	/**
+	 * GetRejectedTransitions retrieves the rejected transitions of the delegation.
+	 * @param ctx The context
+	 * @param stakingTxHashHex The staking tx hash hex
+	 * @return The rejected transitions ordered by creation time or an error
+	 */
+	GetRejectedTransitions(ctx context.Context, stakingTxHashHex string) ([]model.RejectedTransition, error)
+	/**
+	 * SaveAnomaly stores the anomaly, the same anomaly of the delegation is stored only once.
+	 * @param ctx The context
+	 * @param anomaly The anomaly
+	 * @return Whether the anomaly has been stored by this call or an error
+	 */
+	SaveAnomaly(ctx context.Context, anomaly *model.Anomaly) (bool, error)
+	/**
+	 * GetAnomalies retrieves the anomalies of the delegation.
+	 * @param ctx The context
+	 * @param stakingTxHashHex The staking tx hash hex
+	 * @return The anomalies ordered by creation time or an error
+	 */
+	GetAnomalies(ctx context.Context, stakingTxHashHex string) ([]model.Anomaly, error)
 	/**
 	 * GetAllFinalityProviders retrieves all finality providers from the database.
 	 * @param ctx The context
@@ -124,6 +287,16 @@ type DbInterface interface {
 	GetAllFinalityProviders(ctx context.Context) (
 		[]*model.FinalityProviderDetails, error,
 	)
+	/**
+	 * FindFinalityProviders retrieves finality providers, for paging through them.
+	 * @param ctx The context
+	 * @param afterBtcPk Only finality providers with greater BTC public key are returned
+	 * @param limit The maximum number of finality providers
+	 * @return The finality providers ordered by BTC public key or an error
+	 */
+	FindFinalityProviders(
+		ctx context.Context, afterBtcPk string, limit int64,
+	) ([]*model.FinalityProviderDetails, error)
 	/**
 	 * SaveBTCDelegationUnbondingCovenantSignature saves a BTC delegation
 	 * unbonding covenant signature to the database.
@@ -132,9 +305,11 @@ type DbInterface interface {
 	 * @param covenantBtcPkHex The covenant BTC public key
 	 * @param signatureHex The signature
 	 * @param stakeExpansionSignatureHex Signature of stake expansion
+	 * @param bbnTx The Babylon tx that submitted the signature, can be nil
+	 * @param opts Optional parameters, only WithExpectedVersion is applied
 	 * @return An error if the operation failed
 	 */
-	SaveBTCDelegationCovenantSignature(ctx context.Context, stakingTxHash string, covenantBtcPkHex string, signatureHex string, stakeExpansionSignatureHex string) error
+	SaveBTCDelegationCovenantSignature(ctx context.Context, stakingTxHash string, covenantBtcPkHex string, signatureHex string, stakeExpansionSignatureHex string, bbnTx *model.BbnTx, opts ...UpdateOption) error
 	/**
 	 * GetBTCDelegationState retrieves the BTC delegation state.
 	 * @param ctx The context
@@ -159,6 +334,47 @@ type DbInterface interface {
 	 * @return The BTC delegations or an error
 	 */
 	GetDelegationsByFinalityProvider(ctx context.Context, fpBtcPkHex string) ([]*model.BTCDelegationDetails, error)
+	/**
+	 * FindDelegationsByFinalityProvider retrieves the BTC delegations to the finality provider,
+	 * for paging through them.
+	 * @param ctx The context
+	 * @param fpBtcPkHex The finality provider public key
+	 * @param afterStakingTxHash Only delegations with greater staking tx hash are returned
+	 * @param limit The maximum number of delegations
+	 * @return The BTC delegations ordered by staking tx hash or an error
+	 */
+	FindDelegationsByFinalityProvider(
+		ctx context.Context, fpBtcPkHex, afterStakingTxHash string, limit int64,
+	) ([]*model.BTCDelegationDetails, error)
+	/**
+	 * SaveProvisionalSpend appends a provisional spend to the BTC delegation.
+	 * @param ctx The context
+	 * @param stakingTxHash The staking tx hash
+	 * @param spend The provisional spend
+	 * @param opts Optional parameters, only WithExpectedVersion is applied
+	 * @return An error if the operation failed
+	 */
+	SaveProvisionalSpend(ctx context.Context, stakingTxHash string, spend model.ProvisionalSpend, opts ...UpdateOption) error
+	/**
+	 * ConfirmProvisionalSpend removes the provisional spend once it has enough confirmations.
+	 * @param ctx The context
+	 * @param stakingTxHash The staking tx hash
+	 * @param spendingTxHash The spending tx hash
+	 * @param opts Optional parameters, only WithExpectedVersion is applied
+	 * @return An error if the operation failed
+	 */
+	ConfirmProvisionalSpend(ctx context.Context, stakingTxHash string, spendingTxHash string, opts ...UpdateOption) error
+	/**
+	 * RevertProvisionalSpend restores the BTC delegation to the state before the provisional spend.
+	 * Provisional spends applied after it are reverted as well. If the last of them isn't the latest
+	 * transition of the delegation anymore, SupersededError is returned. If the delegation changes
+	 * while it's being reverted, ConflictError is returned.
+	 * @param ctx The context
+	 * @param stakingTxHash The staking tx hash
+	 * @param spendingTxHash The spending tx hash
+	 * @return The reverted provisional spends or an error
+	 */
+	RevertProvisionalSpend(ctx context.Context, stakingTxHash string, spendingTxHash string) ([]model.ProvisionalSpend, error)
 	/**
 	 * SaveNewTimeLockExpire saves a new timelock expire to the database.
 	 * If the timelock expire already exists, DuplicateKeyError will be returned.
@@ -175,12 +391,31 @@ type DbInterface interface {
 		subState types.DelegationSubState,
 	) error
 	/**
-	 * FindExpiredDelegations finds the expired delegations.
+	 * FindExpiredDelegations finds the expired delegations ordered by id. Delegations with
+	 * unconfirmed provisional spends are skipped.
 	 * @param ctx The context
 	 * @param btcTipHeight The BTC tip height
+	 * @param afterID Only delegations with greater id are returned
+	 * @param limit The maximum number of delegations to return
 	 * @return The expired delegations or an error
 	 */
-	FindExpiredDelegations(ctx context.Context, btcTipHeight, limit uint64) ([]model.TimeLockDocument, error)
+	FindExpiredDelegations(
+		ctx context.Context, btcTipHeight uint64, afterID primitive.ObjectID, limit uint64,
+	) ([]model.TimeLockDocument, error)
+	/**
+	 * CountExpiredDelegations counts the expired delegations.
+	 * @param ctx The context
+	 * @param btcTipHeight The BTC tip height
+	 * @return The number of expired delegations or an error
+	 */
+	CountExpiredDelegations(ctx context.Context, btcTipHeight uint64) (int64, error)
+	/**
+	 * DeleteTimeLockExpires deletes the timelock expires by ids.
+	 * @param ctx The context
+	 * @param ids The ids of the timelock expires
+	 * @return An error if the operation failed
+	 */
+	DeleteTimeLockExpires(ctx context.Context, ids []primitive.ObjectID) error
 	/**
 	 * DeleteExpiredDelegation deletes an expired delegation.
 	 * @param ctx The context
@@ -188,6 +423,14 @@ type DbInterface interface {
 	 * @return An error if the operation failed
 	 */
 	DeleteExpiredDelegation(ctx context.Context, stakingTxHashHex string) error
+	/**
+	 * DeleteTimeLockExpire deletes the timelock expire of the delegation with the given sub state.
+	 * @param ctx The context
+	 * @param stakingTxHashHex The staking tx hash hex
+	 * @param subState The delegation sub state
+	 * @return An error if the operation failed
+	 */
+	DeleteTimeLockExpire(ctx context.Context, stakingTxHashHex string, subState types.DelegationSubState) error
 	/**
 	 * GetLastProcessedBbnHeight retrieves the last processed BBN height.
 	 * @param ctx The context
@@ -209,8 +452,6 @@ type DbInterface interface {
 	 */
 	GetBTCDelegationsByStates(ctx context.Context, states []types.DelegationState) ([]*model.BTCDelegationDetails, error)
 
-	GetDelegationsWithEmptyStakerAddress(ctx context.Context) ([]model.BTCDelegationDetails, error)
-	UpdateDelegationStakerBabylonAddress(ctx context.Context, stakingTxHash, stakerBabylonAddress string) error
 	GetNetworkInfo(ctx context.Context) (*model.NetworkInfo, error)
 	UpsertNetworkInfo(ctx context.Context, networkInfo *model.NetworkInfo) error
 	/**
@@ -237,4 +478,29 @@ type DbInterface interface {
 	 * @return overallTvl, overallDelegations, fpStats array, error
 	 */
 	CalculateActiveStatsAggregated(ctx context.Context) (uint64, uint64, []*FinalityProviderStatsResult, error)
+	/**
+	 * CalculateUnlockBuckets aggregates upcoming unlocks of timelock entries and ACTIVE delegations
+	 * into buckets of bucketBlocks BTC heights starting at fromHeight, overall and per finality provider.
+	 * @param ctx The context
+	 * @param fromHeight The first height of the first bucket
+	 * @param bucketBlocks The number of heights in a bucket
+	 * @param buckets The number of buckets
+	 * @return The aggregated unlocks by bucket and kind or an error
+	 */
+	CalculateUnlockBuckets(ctx context.Context, fromHeight, bucketBlocks, buckets uint32) ([]UnlockBucketResult, error)
+	/**
+	 * ReplaceUnlockProjections stores the unlock projections and removes all other projections.
+	 * @param ctx The context
+	 * @param projections The projections
+	 * @return An error if the operation failed
+	 */
+	ReplaceUnlockProjections(ctx context.Context, projections []*model.UnlockProjectionDocument) error
+	/**
+	 * GetUnlockProjection retrieves the overall unlock projection or the projection of the finality provider.
+	 * If the projection does not exist, a NotFoundError will be returned.
+	 * @param ctx The context
+	 * @param id The projection id (model.OverallUnlockProjectionID or finality provider BTC public key)
+	 * @return The projection or an error
+	 */
+	GetUnlockProjection(ctx context.Context, id string) (*model.UnlockProjectionDocument, error)
 }
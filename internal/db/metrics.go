@@ -8,6 +8,7 @@ import (
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type DbWithMetrics struct {
@@ -70,21 +71,158 @@ func (d *DbWithMetrics) SaveCheckpointParams(ctx context.Context, params *bbncli
 	})
 }
 
+func (d *DbWithMetrics) GetCheckpointParams(ctx context.Context) (result *bbnclient.CheckpointParams, err error) {
+	//nolint:errcheck
+	d.run("GetCheckpointParams", func() error {
+		result, err = d.db.GetCheckpointParams(ctx)
+		return err
+	})
+	return result, err
+}
+
 func (d *DbWithMetrics) SaveNewBTCDelegation(ctx context.Context, delegationDoc *model.BTCDelegationDetails) error {
 	return d.run("SaveNewBTCDelegation", func() error {
 		return d.db.SaveNewBTCDelegation(ctx, delegationDoc)
 	})
 }
 
-func (d *DbWithMetrics) UpdateBTCDelegationState(ctx context.Context, stakingTxHash string, qualifiedPreviousStates []types.DelegationState, newState types.DelegationState, opts ...UpdateOption) error {
+func (d *DbWithMetrics) UpdateBTCDelegationState(ctx context.Context, stakingTxHash string, trigger types.Trigger, newState types.DelegationState, opts ...UpdateOption) error {
 	return d.run("UpdateBTCDelegationState", func() error {
-		return d.db.UpdateBTCDelegationState(ctx, stakingTxHash, qualifiedPreviousStates, newState, opts...)
+		return d.db.UpdateBTCDelegationState(ctx, stakingTxHash, trigger, newState, opts...)
+	})
+}
+
+func (d *DbWithMetrics) SaveFinalityProviderHistory(ctx context.Context, record *model.FinalityProviderHistoryRecord) error {
+	return d.run("SaveFinalityProviderHistory", func() error {
+		return d.db.SaveFinalityProviderHistory(ctx, record)
+	})
+}
+
+func (d *DbWithMetrics) GetFinalityProviderHistory(ctx context.Context, btcPk string) (result []model.FinalityProviderHistoryRecord, err error) {
+	//nolint:errcheck
+	d.run("GetFinalityProviderHistory", func() error {
+		result, err = d.db.GetFinalityProviderHistory(ctx, btcPk)
+		return err
+	})
+	return result, err
+}
+
+func (d *DbWithMetrics) GetFinalityProviderCommissionAtHeight(ctx context.Context, btcPk string, bbnHeight int64) (result string, err error) {
+	//nolint:errcheck
+	d.run("GetFinalityProviderCommissionAtHeight", func() error {
+		result, err = d.db.GetFinalityProviderCommissionAtHeight(ctx, btcPk, bbnHeight)
+		return err
+	})
+	return result, err
+}
+
+func (d *DbWithMetrics) FindStakingTxTrackedDelegations(ctx context.Context, afterStakingTxHash string, limit int64) (result []*model.BTCDelegationDetails, err error) {
+	//nolint:errcheck
+	d.run("FindStakingTxTrackedDelegations", func() error {
+		result, err = d.db.FindStakingTxTrackedDelegations(ctx, afterStakingTxHash, limit)
+		return err
+	})
+	return result, err
+}
+
+func (d *DbWithMetrics) CountStaleStakingTxs(ctx context.Context) (result int64, err error) {
+	//nolint:errcheck
+	d.run("CountStaleStakingTxs", func() error {
+		result, err = d.db.CountStaleStakingTxs(ctx)
+		return err
+	})
+	return result, err
+}
+
+func (d *DbWithMetrics) FindExpiringSoonDelegations(ctx context.Context, btcTip, threshold uint32, limit int64) (result []*model.BTCDelegationDetails, err error) {
+	//nolint:errcheck
+	d.run("FindExpiringSoonDelegations", func() error {
+		result, err = d.db.FindExpiringSoonDelegations(ctx, btcTip, threshold, limit)
+		return err
+	})
+	return result, err
+}
+
+func (d *DbWithMetrics) MarkExpiringSoonNotified(ctx context.Context, stakingTxHash string, thresholds []uint32, opts ...UpdateOption) error {
+	return d.run("MarkExpiringSoonNotified", func() error {
+		return d.db.MarkExpiringSoonNotified(ctx, stakingTxHash, thresholds, opts...)
+	})
+}
+
+func (d *DbWithMetrics) UpdateStakingTxConfirmation(
+	ctx context.Context, stakingTxHash string, confirmation *model.StakingTxConfirmation, opts ...UpdateOption,
+) error {
+	return d.run("UpdateStakingTxConfirmation", func() error {
+		return d.db.UpdateStakingTxConfirmation(ctx, stakingTxHash, confirmation, opts...)
+	})
+}
+
+func (d *DbWithMetrics) SetNextStakingTxHash(ctx context.Context, stakingTxHash string, nextStakingTxHash string, opts ...UpdateOption) error {
+	return d.run("SetNextStakingTxHash", func() error {
+		return d.db.SetNextStakingTxHash(ctx, stakingTxHash, nextStakingTxHash, opts...)
+	})
+}
+
+func (d *DbWithMetrics) SetUnbondingTxDetails(ctx context.Context, stakingTxHash string, details *model.BtcSpendDetails, opts ...UpdateOption) error {
+	return d.run("SetUnbondingTxDetails", func() error {
+		return d.db.SetUnbondingTxDetails(ctx, stakingTxHash, details, opts...)
+	})
+}
+
+func (d *DbWithMetrics) GetExpansionChain(ctx context.Context, stakingTxHash string) (result []*model.BTCDelegationDetails, err error) {
+	//nolint:errcheck
+	d.run("GetExpansionChain", func() error {
+		result, err = d.db.GetExpansionChain(ctx, stakingTxHash)
+		return err
+	})
+	return
+}
+
+func (d *DbWithMetrics) GetRejectedTransitions(ctx context.Context, stakingTxHashHex string) (result []model.RejectedTransition, err error) {
+	//nolint:errcheck
+	d.run("GetRejectedTransitions", func() error {
+		result, err = d.db.GetRejectedTransitions(ctx, stakingTxHashHex)
+		return err
+	})
+	return result, err
+}
+
+func (d *DbWithMetrics) SaveRejectedTransition(ctx context.Context, rejected *model.RejectedTransition) error {
+	return d.run("SaveRejectedTransition", func() error {
+		return d.db.SaveRejectedTransition(ctx, rejected)
+	})
+}
+
+func (d *DbWithMetrics) SaveAnomaly(ctx context.Context, anomaly *model.Anomaly) (result bool, err error) {
+	//nolint:errcheck
+	d.run("SaveAnomaly", func() error {
+		result, err = d.db.SaveAnomaly(ctx, anomaly)
+		return err
 	})
+	return result, err
+}
+
+func (d *DbWithMetrics) GetAnomalies(ctx context.Context, stakingTxHashHex string) (result []model.Anomaly, err error) {
+	//nolint:errcheck
+	d.run("GetAnomalies", func() error {
+		result, err = d.db.GetAnomalies(ctx, stakingTxHashHex)
+		return err
+	})
+	return result, err
+}
+
+func (d *DbWithMetrics) BulkUpdateBTCDelegationState(ctx context.Context, updates []BTCDelegationStateUpdate) (result []error, err error) {
+	//nolint:errcheck
+	d.run("BulkUpdateBTCDelegationState", func() error {
+		result, err = d.db.BulkUpdateBTCDelegationState(ctx, updates)
+		return err
+	})
+	return result, err
 }
 
-func (d *DbWithMetrics) SaveBTCDelegationCovenantSignature(ctx context.Context, stakingTxHash string, covenantBtcPkHex string, signatureHex string, stakeExpansionSignatureHex string) error {
+func (d *DbWithMetrics) SaveBTCDelegationCovenantSignature(ctx context.Context, stakingTxHash string, covenantBtcPkHex string, signatureHex string, stakeExpansionSignatureHex string, bbnTx *model.BbnTx, opts ...UpdateOption) error {
 	return d.run("SaveBTCDelegationCovenantSignature", func() error {
-		return d.db.SaveBTCDelegationCovenantSignature(ctx, stakingTxHash, covenantBtcPkHex, signatureHex, stakeExpansionSignatureHex)
+		return d.db.SaveBTCDelegationCovenantSignature(ctx, stakingTxHash, covenantBtcPkHex, signatureHex, stakeExpansionSignatureHex, bbnTx, opts...)
 	})
 }
 
@@ -115,27 +253,78 @@ func (d *DbWithMetrics) GetDelegationsByFinalityProvider(ctx context.Context, fp
 	return result, err
 }
 
+func (d *DbWithMetrics) FindDelegationsByFinalityProvider(ctx context.Context, fpBtcPkHex, afterStakingTxHash string, limit int64) (result []*model.BTCDelegationDetails, err error) {
+	//nolint:errcheck
+	d.run("FindDelegationsByFinalityProvider", func() error {
+		result, err = d.db.FindDelegationsByFinalityProvider(ctx, fpBtcPkHex, afterStakingTxHash, limit)
+		return err
+	})
+	return result, err
+}
+
 func (d *DbWithMetrics) SaveNewTimeLockExpire(ctx context.Context, stakingTxHashHex string, expireHeight uint32, subState types.DelegationSubState) error {
 	return d.run("SaveNewTimeLockExpire", func() error {
 		return d.db.SaveNewTimeLockExpire(ctx, stakingTxHashHex, expireHeight, subState)
 	})
 }
 
-func (d *DbWithMetrics) FindExpiredDelegations(ctx context.Context, btcTipHeight, limit uint64) (result []model.TimeLockDocument, err error) {
+func (d *DbWithMetrics) FindExpiredDelegations(ctx context.Context, btcTipHeight uint64, afterID primitive.ObjectID, limit uint64) (result []model.TimeLockDocument, err error) {
 	//nolint:errcheck
 	d.run("FindExpiredDelegations", func() error {
-		result, err = d.db.FindExpiredDelegations(ctx, btcTipHeight, limit)
+		result, err = d.db.FindExpiredDelegations(ctx, btcTipHeight, afterID, limit)
 		return err
 	})
 	return result, err
 }
 
+func (d *DbWithMetrics) CountExpiredDelegations(ctx context.Context, btcTipHeight uint64) (result int64, err error) {
+	//nolint:errcheck
+	d.run("CountExpiredDelegations", func() error {
+		result, err = d.db.CountExpiredDelegations(ctx, btcTipHeight)
+		return err
+	})
+	return result, err
+}
+
+func (d *DbWithMetrics) DeleteTimeLockExpires(ctx context.Context, ids []primitive.ObjectID) error {
+	return d.run("DeleteTimeLockExpires", func() error {
+		return d.db.DeleteTimeLockExpires(ctx, ids)
+	})
+}
+
 func (d *DbWithMetrics) DeleteExpiredDelegation(ctx context.Context, stakingTxHashHex string) error {
 	return d.run("DeleteExpiredDelegation", func() error {
 		return d.db.DeleteExpiredDelegation(ctx, stakingTxHashHex)
 	})
 }
 
+func (d *DbWithMetrics) DeleteTimeLockExpire(ctx context.Context, stakingTxHashHex string, subState types.DelegationSubState) error {
+	return d.run("DeleteTimeLockExpire", func() error {
+		return d.db.DeleteTimeLockExpire(ctx, stakingTxHashHex, subState)
+	})
+}
+
+func (d *DbWithMetrics) SaveProvisionalSpend(ctx context.Context, stakingTxHash string, spend model.ProvisionalSpend, opts ...UpdateOption) error {
+	return d.run("SaveProvisionalSpend", func() error {
+		return d.db.SaveProvisionalSpend(ctx, stakingTxHash, spend, opts...)
+	})
+}
+
+func (d *DbWithMetrics) ConfirmProvisionalSpend(ctx context.Context, stakingTxHash string, spendingTxHash string, opts ...UpdateOption) error {
+	return d.run("ConfirmProvisionalSpend", func() error {
+		return d.db.ConfirmProvisionalSpend(ctx, stakingTxHash, spendingTxHash, opts...)
+	})
+}
+
+func (d *DbWithMetrics) RevertProvisionalSpend(ctx context.Context, stakingTxHash string, spendingTxHash string) (result []model.ProvisionalSpend, err error) {
+	//nolint:errcheck
+	d.run("RevertProvisionalSpend", func() error {
+		result, err = d.db.RevertProvisionalSpend(ctx, stakingTxHash, spendingTxHash)
+		return err
+	})
+	return result, err
+}
+
 func (d *DbWithMetrics) GetLastProcessedBbnHeight(ctx context.Context) (result uint64, err error) {
 	//nolint:errcheck
 	d.run("GetLastProcessedBbnHeight", func() error {
@@ -175,16 +364,6 @@ func (d *DbWithMetrics) UpsertNetworkInfo(ctx context.Context, networkInfo *mode
 	})
 }
 
-func (d *DbWithMetrics) UpdateDelegationStakerBabylonAddress(ctx context.Context, stakingTxHash, stakerBabylonAddress string) error {
-	return d.run("UpdateDelegationStakerBabylonAddress", func() error {
-		return d.db.UpdateDelegationStakerBabylonAddress(ctx, stakingTxHash, stakerBabylonAddress)
-	})
-}
-
-func (d *DbWithMetrics) GetDelegationsWithEmptyStakerAddress(ctx context.Context) ([]model.BTCDelegationDetails, error) {
-	return d.db.GetDelegationsWithEmptyStakerAddress(ctx)
-}
-
 func (d *DbWithMetrics) GetAllFinalityProviders(
 	ctx context.Context,
 ) (result []*model.FinalityProviderDetails, err error) {
@@ -196,6 +375,17 @@ func (d *DbWithMetrics) GetAllFinalityProviders(
 	return result, err
 }
 
+func (d *DbWithMetrics) FindFinalityProviders(
+	ctx context.Context, afterBtcPk string, limit int64,
+) (result []*model.FinalityProviderDetails, err error) {
+	//nolint:errcheck
+	d.run("FindFinalityProviders", func() error {
+		result, err = d.db.FindFinalityProviders(ctx, afterBtcPk, limit)
+		return err
+	})
+	return result, err
+}
+
 func (d *DbWithMetrics) UpsertOverallStats(ctx context.Context, activeTvl uint64, activeDelegations uint64) error {
 	return d.run("UpsertOverallStats", func() error {
 		return d.db.UpsertOverallStats(ctx, activeTvl, activeDelegations)
@@ -217,6 +407,30 @@ func (d *DbWithMetrics) CalculateActiveStatsAggregated(ctx context.Context) (tvl
 	return tvl, delegations, fpStats, err
 }
 
+func (d *DbWithMetrics) CalculateUnlockBuckets(ctx context.Context, fromHeight, bucketBlocks, buckets uint32) (result []UnlockBucketResult, err error) {
+	//nolint:errcheck
+	d.run("CalculateUnlockBuckets", func() error {
+		result, err = d.db.CalculateUnlockBuckets(ctx, fromHeight, bucketBlocks, buckets)
+		return err
+	})
+	return result, err
+}
+
+func (d *DbWithMetrics) ReplaceUnlockProjections(ctx context.Context, projections []*model.UnlockProjectionDocument) error {
+	return d.run("ReplaceUnlockProjections", func() error {
+		return d.db.ReplaceUnlockProjections(ctx, projections)
+	})
+}
+
+func (d *DbWithMetrics) GetUnlockProjection(ctx context.Context, id string) (result *model.UnlockProjectionDocument, err error) {
+	//nolint:errcheck
+	d.run("GetUnlockProjection", func() error {
+		result, err = d.db.GetUnlockProjection(ctx, id)
+		return err
+	})
+	return result, err
+}
+
 // run is private method that executes passed lambda function and send metrics data with spent time, method name
 // and an error if any. It returns the error from the lambda function for convenience
 func (d *DbWithMetrics) run(method string, f func() error) error {
@@ -0,0 +1,87 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/pkg"
+	"github.com/rs/zerolog/log"
+	"github.com/sourcegraph/conc/pool"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// number of concurrent requests to BBN within a single batch
+const fillStakerAddressWorkers = 10
+
+// fillStakerAddressMigration fills staker_babylon_address field in delegations
+// created before the field was introduced, the value is queried from BBN
+var fillStakerAddressMigration = Migration{
+	Version:     1,
+	Description: "fill empty staker_babylon_address in delegations",
+	Up:          fillStakerAddressUp,
+	// the field isn't used for indexing, so it can be backfilled while the indexer is running
+	Optional: true,
+}
+
+func fillStakerAddressUp(ctx context.Context, env *Env) error {
+	if env.BBN == nil {
+		return errors.New("bbn client is required")
+	}
+
+	// either staker_babylon_address doesn't exist or contains empty string
+	filter := bson.M{
+		"$or": []bson.M{
+			{"staker_babylon_address": bson.M{"$exists": false}},
+			{"staker_babylon_address": ""},
+		},
+	}
+
+	collection := env.Database.Collection(model.BTCDelegationDetailsCollection)
+	return env.ForEachBatch(ctx, model.BTCDelegationDetailsCollection, filter, func(ctx context.Context, docs []bson.Raw) error {
+		p := pool.New().WithErrors().WithContext(ctx).WithCancelOnError().WithMaxGoroutines(fillStakerAddressWorkers)
+		for _, doc := range docs {
+			stakingTxHashHex, ok := doc.Lookup("_id").StringValueOK()
+			if !ok {
+				return fmt.Errorf("unexpected _id type in delegation %s", doc.Lookup("_id"))
+			}
+
+			p.Go(func(ctx context.Context) error {
+				bbnAddress, err := env.BBN.BabylonStakerAddress(ctx, stakingTxHashHex)
+				if err != nil {
+					return err
+				}
+
+				if bbnAddress == "" {
+					return fmt.Errorf("empty staker address for tx %s", stakingTxHashHex)
+				}
+
+				// double check
+				err = pkg.ValidateBabylonAddress(bbnAddress)
+				if err != nil {
+					return err
+				}
+
+				if env.DryRun {
+					log.Ctx(ctx).Info().Msgf("Dry run: would update record %s with address %s", stakingTxHashHex, bbnAddress)
+					return nil
+				}
+
+				update := bson.M{
+					"$set": bson.M{
+						"staker_babylon_address": bbnAddress,
+					},
+				}
+				_, err = collection.UpdateOne(ctx, bson.M{"_id": stakingTxHashHex}, update)
+				if err != nil {
+					return fmt.Errorf("failed to updated %s delegation staker addr: %w", stakingTxHashHex, err)
+				}
+
+				return nil
+			})
+		}
+
+		return p.Wait()
+	})
+}
@@ -0,0 +1,161 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/utils/blocktime"
+	"github.com/rs/zerolog/log"
+	"github.com/sourcegraph/conc/pool"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	// number of delegations updated concurrently within a single batch
+	stateHistoryTimestampsWorkers = 10
+	// number of block timestamps kept per chain, delegations of a batch mostly share heights
+	stateHistoryTimestampsCacheSize = 10000
+)
+
+// stateHistoryTimestampsMigration fills BBN and BTC block timestamps of state history
+// records created before the timestamps were stored, they are queried from the nodes
+var stateHistoryTimestampsMigration = Migration{
+	Version:     2,
+	Description: "fill block timestamps of state history records",
+	Up:          stateHistoryTimestampsUp,
+	Down:        stateHistoryTimestampsDown,
+}
+
+// records with a height but without the timestamp of the block at that height
+var missingStateHistoryTimestampsFilter = bson.M{
+	"state_history": bson.M{"$elemMatch": bson.M{"$or": bson.A{
+		bson.M{"bbn_height": bson.M{"$gt": 0}, "bbn_timestamp": bson.M{"$exists": false}},
+		bson.M{"btc_height": bson.M{"$gt": 0}, "btc_timestamp": bson.M{"$exists": false}},
+	}}},
+}
+
+// blockTimestamps fills block timestamps of state history records
+type blockTimestamps struct {
+	resolver *blocktime.Resolver
+}
+
+func newBlockTimestamps(env *Env) *blockTimestamps {
+	return &blockTimestamps{resolver: blocktime.NewResolver(env.BBN, env.BTC, stateHistoryTimestampsCacheSize)}
+}
+
+// fill sets missing timestamps of the records, it returns whether any record was changed
+func (t *blockTimestamps) fill(ctx context.Context, records []model.StateRecord) (bool, error) {
+	var changed bool
+	for i := range records {
+		record := &records[i]
+		if record.BbnHeight > 0 && record.BbnTimestamp == 0 {
+			timestamp, err := t.resolver.BbnTimestamp(ctx, record.BbnHeight)
+			if err != nil {
+				return false, err
+			}
+			record.BbnTimestamp = timestamp
+			changed = true
+		}
+		if record.BtcHeight > 0 && record.BtcTimestamp == 0 {
+			timestamp, err := t.resolver.BtcTimestamp(ctx, record.BtcHeight)
+			if err != nil {
+				return false, err
+			}
+			record.BtcTimestamp = timestamp
+			changed = true
+		}
+	}
+
+	return changed, nil
+}
+
+func stateHistoryTimestampsUp(ctx context.Context, env *Env) error {
+	if env.BBN == nil {
+		return errors.New("bbn client is required")
+	}
+	if env.BTC == nil {
+		return errors.New("btc client is required")
+	}
+
+	timestamps := newBlockTimestamps(env)
+	collection := env.Database.Collection(model.BTCDelegationDetailsCollection)
+	return env.ForEachBatch(
+		ctx, model.BTCDelegationDetailsCollection, missingStateHistoryTimestampsFilter,
+		func(ctx context.Context, docs []bson.Raw) error {
+			p := pool.New().WithErrors().WithContext(ctx).WithCancelOnError().WithMaxGoroutines(stateHistoryTimestampsWorkers)
+			for _, doc := range docs {
+				var delegation struct {
+					StakingTxHashHex string              `bson:"_id"`
+					StateHistory     []model.StateRecord `bson:"state_history"`
+					Version          int64               `bson:"version"`
+				}
+				if err := bson.Unmarshal(doc, &delegation); err != nil {
+					return fmt.Errorf("failed to decode delegation %s: %w", doc.Lookup("_id"), err)
+				}
+
+				p.Go(func(ctx context.Context) error {
+					changed, err := timestamps.fill(ctx, delegation.StateHistory)
+					if err != nil {
+						return fmt.Errorf("delegation %s: %w", delegation.StakingTxHashHex, err)
+					}
+					if !changed {
+						return nil
+					}
+
+					if env.DryRun {
+						log.Ctx(ctx).Info().Msgf("Dry run: would fill state history timestamps of %s", delegation.StakingTxHashHex)
+						return nil
+					}
+
+					// the whole state history is replaced, so records pushed since it was read must not be lost
+					res, err := collection.UpdateOne(
+						ctx,
+						bson.M{"_id": delegation.StakingTxHashHex, "version": versionFilter(delegation.Version)},
+						bson.M{
+							"$set": bson.M{"state_history": delegation.StateHistory},
+							"$inc": bson.M{"version": 1},
+						},
+					)
+					if err != nil {
+						return fmt.Errorf("failed to update %s delegation state history: %w", delegation.StakingTxHashHex, err)
+					}
+					if res.MatchedCount == 0 {
+						return fmt.Errorf("delegation %s has been updated during the migration, it has to be run again",
+							delegation.StakingTxHashHex)
+					}
+
+					return nil
+				})
+			}
+
+			return p.Wait()
+		},
+	)
+}
+
+// versionFilter matches delegations with the version, delegations stored
+// before versioning was introduced have no version field
+func versionFilter(version int64) any {
+	if version == 0 {
+		return bson.M{"$in": bson.A{0, nil}}
+	}
+	return version
+}
+
+func stateHistoryTimestampsDown(ctx context.Context, env *Env) error {
+	if env.DryRun {
+		return nil
+	}
+
+	_, err := env.Database.Collection(model.BTCDelegationDetailsCollection).UpdateMany(
+		ctx,
+		bson.M{"state_history.0": bson.M{"$exists": true}},
+		bson.M{"$unset": bson.M{
+			"state_history.$[].bbn_timestamp": "",
+			"state_history.$[].btc_timestamp": "",
+		}},
+	)
+	return err
+}
@@ -0,0 +1,82 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// expansionLinksMigration links expanded delegations to their expansions, only the
+// expansions had the link to the delegation they expanded before
+var expansionLinksMigration = Migration{
+	Version:     3,
+	Description: "link expanded delegations to their expansions",
+	Up:          expansionLinksUp,
+	Down:        expansionLinksDown,
+}
+
+// expansions that got BTC inclusion, i.e. their staking tx spent the expanded delegation
+var includedExpansionsFilter = bson.M{
+	"previous_staking_tx_hash_hex": bson.M{"$exists": true, "$ne": ""},
+	"start_height":                 bson.M{"$gt": 0},
+}
+
+func expansionLinksUp(ctx context.Context, env *Env) error {
+	collection := env.Database.Collection(model.BTCDelegationDetailsCollection)
+	return env.ForEachBatch(
+		ctx, model.BTCDelegationDetailsCollection, includedExpansionsFilter,
+		func(ctx context.Context, docs []bson.Raw) error {
+			for _, doc := range docs {
+				var expansion struct {
+					StakingTxHashHex         string `bson:"_id"`
+					PreviousStakingTxHashHex string `bson:"previous_staking_tx_hash_hex"`
+				}
+				if err := bson.Unmarshal(doc, &expansion); err != nil {
+					return fmt.Errorf("failed to decode delegation %s: %w", doc.Lookup("_id"), err)
+				}
+
+				if env.DryRun {
+					log.Ctx(ctx).Info().Msgf("Dry run: would link %s to expansion %s",
+						expansion.PreviousStakingTxHashHex, expansion.StakingTxHashHex)
+					continue
+				}
+
+				_, err := collection.UpdateOne(
+					ctx,
+					bson.M{
+						"_id":                      expansion.PreviousStakingTxHashHex,
+						"state":                    types.StateExpanded.String(),
+						"next_staking_tx_hash_hex": bson.M{"$exists": false},
+					},
+					bson.M{
+						"$set": bson.M{"next_staking_tx_hash_hex": expansion.StakingTxHashHex},
+						"$inc": bson.M{"version": 1},
+					},
+				)
+				if err != nil {
+					return fmt.Errorf("failed to link %s to expansion %s: %w",
+						expansion.PreviousStakingTxHashHex, expansion.StakingTxHashHex, err)
+				}
+			}
+
+			return nil
+		},
+	)
+}
+
+func expansionLinksDown(ctx context.Context, env *Env) error {
+	if env.DryRun {
+		return nil
+	}
+
+	_, err := env.Database.Collection(model.BTCDelegationDetailsCollection).UpdateMany(
+		ctx,
+		bson.M{"next_staking_tx_hash_hex": bson.M{"$exists": true}},
+		bson.M{"$unset": bson.M{"next_staking_tx_hash_hex": ""}},
+	)
+	return err
+}
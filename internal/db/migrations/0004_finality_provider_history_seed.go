@@ -0,0 +1,103 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// finalityProviderHistorySeedMigration stores a history record of every finality provider
+// indexed before the history was kept, so its current commission, description and status
+// can be looked up by height. The record is at the last processed BBN height, the state of
+// the finality provider at earlier heights stays unknown.
+var finalityProviderHistorySeedMigration = Migration{
+	Version:     4,
+	Description: "seed finality provider history from current finality providers",
+	Up:          finalityProviderHistorySeedUp,
+	Down:        finalityProviderHistorySeedDown,
+}
+
+func finalityProviderHistorySeedUp(ctx context.Context, env *Env) error {
+	if env.BBN == nil {
+		return errors.New("bbn client is required")
+	}
+
+	var lastProcessed model.LastProcessedHeight
+	err := env.Database.Collection(model.LastProcessedHeightCollection).
+		FindOne(ctx, bson.M{}).Decode(&lastProcessed)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return fmt.Errorf("failed to get last processed bbn height: %w", err)
+	}
+	if lastProcessed.Height == 0 {
+		// nothing has been indexed yet, finality providers get history as they're created
+		log.Ctx(ctx).Info().Msg("no bbn height has been processed, there is no history to seed")
+		return nil
+	}
+	bbnHeight := int64(lastProcessed.Height)
+
+	block, err := env.BBN.GetBlock(ctx, &bbnHeight)
+	if err != nil {
+		return fmt.Errorf("failed to get bbn block %d: %w", bbnHeight, err)
+	}
+	bbnTimestamp := block.Block.Time.Unix()
+
+	history := env.Database.Collection(model.FinalityProviderHistoryCollection)
+	return env.ForEachBatch(
+		ctx, model.FinalityProviderDetailsCollection, bson.M{},
+		func(ctx context.Context, docs []bson.Raw) error {
+			for _, doc := range docs {
+				var fp model.FinalityProviderDetails
+				if err := bson.Unmarshal(doc, &fp); err != nil {
+					return fmt.Errorf("failed to decode finality provider %s: %w", doc.Lookup("_id"), err)
+				}
+
+				// finality providers changed since the history is kept have their records already
+				count, err := history.CountDocuments(ctx, bson.M{"btc_pk": fp.BtcPk}, options.Count().SetLimit(1))
+				if err != nil {
+					return fmt.Errorf("failed to count finality provider %s history: %w", fp.BtcPk, err)
+				}
+				if count > 0 {
+					continue
+				}
+
+				if env.DryRun {
+					log.Ctx(ctx).Info().Msgf("Dry run: would seed history of finality provider %s", fp.BtcPk)
+					continue
+				}
+
+				record := model.NewFinalityProviderHistoryRecord(
+					&fp, types.MigrationEventType, bbnHeight, bbnTimestamp, nil,
+				)
+				_, err = history.UpdateOne(
+					ctx,
+					bson.M{"btc_pk": record.BtcPk, "bbn_height": record.BbnHeight},
+					bson.M{"$setOnInsert": record},
+					options.Update().SetUpsert(true),
+				)
+				if err != nil {
+					return fmt.Errorf("failed to seed finality provider %s history: %w", fp.BtcPk, err)
+				}
+			}
+
+			return nil
+		},
+	)
+}
+
+func finalityProviderHistorySeedDown(ctx context.Context, env *Env) error {
+	if env.DryRun {
+		return nil
+	}
+
+	_, err := env.Database.Collection(model.FinalityProviderHistoryCollection).DeleteMany(
+		ctx, bson.M{"event_types": bson.A{types.MigrationEventType}},
+	)
+	return err
+}
@@ -0,0 +1,53 @@
+//go:build integration
+
+package migrations
+
+import (
+	"context"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/testutil"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// mongo connected to test database, used by runner and for preparing/cleaning data
+var mongoDB *mongo.Database
+
+func TestMain(m *testing.M) {
+	// first setup container with MongoDb
+	dbConfig, cleanup, err := testutil.SetupMongoContainer()
+	if err != nil {
+		log.Fatalf("failed to setup mongo container: %v", err)
+	}
+
+	err = model.Setup(context.Background(), dbConfig)
+	if err != nil {
+		cleanup()
+		log.Fatalf("failed to init mongo database: %v", err)
+	}
+
+	mongoDB, err = testutil.ConnectMongo(dbConfig)
+	if err != nil {
+		cleanup()
+		log.Fatalf("failed to setup mongo client: %v", err)
+	}
+
+	// integration tests run on this line
+	code := m.Run()
+	cleanup()
+
+	os.Exit(code)
+}
+
+func resetDatabase(t *testing.T) {
+	testutil.ResetCollections(t, mongoDB,
+		model.BTCDelegationDetailsCollection,
+		model.FinalityProviderDetailsCollection,
+		model.FinalityProviderHistoryCollection,
+		model.LastProcessedHeightCollection,
+		model.SchemaMigrationsCollection,
+	)
+}
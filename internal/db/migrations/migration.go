@@ -0,0 +1,147 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/bbnclient"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/btcclient"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const defaultBatchSize = 500
+
+// MigrationFunc applies (or reverts) a single migration using dependencies provided by Env
+type MigrationFunc func(ctx context.Context, env *Env) error
+
+// Migration describes a single versioned change of the data stored by the indexer.
+// Versions must be unique and strictly increasing in the registry.
+type Migration struct {
+	Version     uint64
+	Description string
+	Up          MigrationFunc
+	// Down is optional, migrations without it are irreversible
+	Down MigrationFunc
+	// Optional migrations only backfill data the indexer can run without,
+	// so pending ones don't block startup (e.g. when they need BBN which isn't reachable yet)
+	Optional bool
+}
+
+// Env holds everything a migration might need during execution
+type Env struct {
+	Database *mongo.Database
+	// BBN is optional, migrations that need chain data must check it's not nil
+	BBN bbnclient.BbnInterface
+	// BTC is optional as well
+	BTC       btcclient.BtcInterface
+	DryRun    bool
+	BatchSize int
+
+	// version of currently executed migration, used to store checkpoints
+	version uint64
+}
+
+// ForEachBatch iterates over documents of the collection matching the filter in batches ordered by _id.
+// After every successfully processed batch the last seen _id is stored as a checkpoint, so if the
+// migration is interrupted it continues from the same place on the next run.
+// Checkpoints are not stored in dry run mode.
+func (e *Env) ForEachBatch(
+	ctx context.Context,
+	collection string,
+	filter bson.M,
+	f func(ctx context.Context, docs []bson.Raw) error,
+) error {
+	checkpoint, err := e.loadCheckpoint(ctx, collection)
+	if err != nil {
+		return err
+	}
+
+	batchSize := e.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+
+	log := log.Ctx(ctx)
+	for {
+		batchFilter := bson.M{}
+		for k, v := range filter {
+			batchFilter[k] = v
+		}
+		if checkpoint != nil {
+			batchFilter["_id"] = bson.M{"$gt": checkpoint}
+		}
+
+		opts := options.Find().
+			SetSort(bson.D{{Key: "_id", Value: 1}}).
+			SetLimit(int64(batchSize))
+		cursor, err := e.Database.Collection(collection).Find(ctx, batchFilter, opts)
+		if err != nil {
+			return fmt.Errorf("failed to find documents in %s: %w", collection, err)
+		}
+
+		var docs []bson.Raw
+		if err := cursor.All(ctx, &docs); err != nil {
+			return fmt.Errorf("failed to decode documents from %s: %w", collection, err)
+		}
+		if len(docs) == 0 {
+			return nil
+		}
+
+		if err := f(ctx, docs); err != nil {
+			return err
+		}
+
+		lastID := docs[len(docs)-1].Lookup("_id")
+		checkpoint = lastID
+		if !e.DryRun {
+			if err := e.saveCheckpoint(ctx, collection, lastID); err != nil {
+				return err
+			}
+		}
+
+		log.Info().
+			Uint64("version", e.version).
+			Str("collection", collection).
+			Int("batch_size", len(docs)).
+			Msg("migration batch processed")
+
+		if len(docs) < batchSize {
+			return nil
+		}
+	}
+}
+
+func (e *Env) loadCheckpoint(ctx context.Context, collection string) (any, error) {
+	var doc model.SchemaMigration
+	err := e.Database.Collection(model.SchemaMigrationsCollection).
+		FindOne(ctx, bson.M{"_id": e.version}).
+		Decode(&doc)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to load checkpoint for migration %d: %w", e.version, err)
+	}
+
+	value, ok := doc.Checkpoints[collection]
+	if !ok {
+		return nil, nil
+	}
+	return value, nil
+}
+
+func (e *Env) saveCheckpoint(ctx context.Context, collection string, value bson.RawValue) error {
+	update := bson.M{
+		"$set": bson.M{"checkpoints." + collection: value},
+	}
+	_, err := e.Database.Collection(model.SchemaMigrationsCollection).
+		UpdateOne(ctx, bson.M{"_id": e.version}, update, options.Update().SetUpsert(true))
+	if err != nil {
+		return fmt.Errorf("failed to save checkpoint for migration %d: %w", e.version, err)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package migrations
+
+import "fmt"
+
+// registry contains all migrations ordered by version.
+// New migrations must be appended to the end with a version greater than the last one,
+// already released migrations must never be changed or removed.
+var registry = []Migration{
+	fillStakerAddressMigration,
+	stateHistoryTimestampsMigration,
+	expansionLinksMigration,
+	finalityProviderHistorySeedMigration,
+}
+
+// Registry returns copy of all known migrations ordered by version
+func Registry() []Migration {
+	return append([]Migration(nil), registry...)
+}
+
+func validate(migrations []Migration) error {
+	var prev uint64
+	for i, m := range migrations {
+		if m.Version == 0 {
+			return fmt.Errorf("migration at position %d has zero version", i)
+		}
+		if m.Version <= prev {
+			return fmt.Errorf("migration %d is out of order: previous version is %d", m.Version, prev)
+		}
+		if m.Description == "" {
+			return fmt.Errorf("migration %d has empty description", m.Version)
+		}
+		if m.Up == nil {
+			return fmt.Errorf("migration %d has no up function", m.Version)
+		}
+		prev = m.Version
+	}
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package migrations
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	require.NoError(t, validate(Registry()))
+}
+
+func TestValidate(t *testing.T) {
+	up := func(context.Context, *Env) error { return nil }
+
+	t.Run("ok", func(t *testing.T) {
+		err := validate([]Migration{
+			{Version: 1, Description: "first", Up: up},
+			{Version: 5, Description: "second", Up: up},
+		})
+		assert.NoError(t, err)
+	})
+	t.Run("zero version", func(t *testing.T) {
+		err := validate([]Migration{{Version: 0, Description: "first", Up: up}})
+		assert.ErrorContains(t, err, "zero version")
+	})
+	t.Run("out of order", func(t *testing.T) {
+		err := validate([]Migration{
+			{Version: 2, Description: "first", Up: up},
+			{Version: 1, Description: "second", Up: up},
+		})
+		assert.ErrorContains(t, err, "out of order")
+	})
+	t.Run("duplicate version", func(t *testing.T) {
+		err := validate([]Migration{
+			{Version: 1, Description: "first", Up: up},
+			{Version: 1, Description: "second", Up: up},
+		})
+		assert.ErrorContains(t, err, "out of order")
+	})
+	t.Run("empty description", func(t *testing.T) {
+		err := validate([]Migration{{Version: 1, Up: up}})
+		assert.ErrorContains(t, err, "empty description")
+	})
+	t.Run("no up function", func(t *testing.T) {
+		err := validate([]Migration{{Version: 1, Description: "first"}})
+		assert.ErrorContains(t, err, "no up function")
+	})
+}
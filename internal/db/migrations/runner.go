@@ -0,0 +1,311 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/bbnclient"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/btcclient"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrIrreversible is returned when Down is requested for a migration that doesn't define it
+var ErrIrreversible = errors.New("migration is irreversible")
+
+// Status describes state of a single migration in the database
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+	// InProgress is true if migration was started but didn't finish (e.g. it was interrupted)
+	InProgress bool
+}
+
+// RunOptions configures Up and Down execution
+type RunOptions struct {
+	// Target is the version to migrate to. For Up zero means the latest version,
+	// for Down zero means reverting only the last applied migration.
+	Target    uint64
+	DryRun    bool
+	BatchSize int
+}
+
+type Runner struct {
+	database   *mongo.Database
+	migrations []Migration
+	bbn        bbnclient.BbnInterface
+	btc        btcclient.BtcInterface
+}
+
+// New connects to the database and returns Runner for the default registry.
+// BBN and BTC clients are optional, but migrations that require them will fail without them.
+// Close must be called to disconnect from the database once the runner is not needed.
+func New(
+	ctx context.Context, cfg config.DbConfig, bbn bbnclient.BbnInterface, btc btcclient.BtcInterface,
+) (*Runner, error) {
+	database, err := model.Connect(ctx, &cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return newRunner(database, registry, bbn, btc)
+}
+
+func newRunner(
+	database *mongo.Database, migrations []Migration, bbn bbnclient.BbnInterface, btc btcclient.BtcInterface,
+) (*Runner, error) {
+	if err := validate(migrations); err != nil {
+		return nil, err
+	}
+
+	return &Runner{
+		database:   database,
+		migrations: migrations,
+		bbn:        bbn,
+		btc:        btc,
+	}, nil
+}
+
+// Status returns state of every known migration ordered by version
+func (r *Runner) Status(ctx context.Context) ([]Status, error) {
+	records, err := r.records(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(r.migrations))
+	for _, m := range r.migrations {
+		status := Status{Migration: m}
+		if record, ok := records[m.Version]; ok {
+			status.Applied = record.Applied
+			status.AppliedAt = record.AppliedAt
+			status.InProgress = !record.Applied
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// Pending returns migrations that are not applied yet ordered by version
+func (r *Runner) Pending(ctx context.Context) ([]Migration, error) {
+	statuses, err := r.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, status := range statuses {
+		if !status.Applied {
+			pending = append(pending, status.Migration)
+		}
+	}
+
+	return pending, nil
+}
+
+// Close disconnects from the database
+func (r *Runner) Close(ctx context.Context) error {
+	return r.database.Client().Disconnect(ctx)
+}
+
+// Up applies pending migrations in order up to (and including) opts.Target.
+// In dry run mode migrations are executed with Env.DryRun set and are not marked as applied.
+func (r *Runner) Up(ctx context.Context, opts RunOptions) error {
+	pending, err := r.Pending(ctx)
+	if err != nil {
+		return err
+	}
+
+	log := log.Ctx(ctx)
+	for _, m := range pending {
+		if opts.Target != 0 && m.Version > opts.Target {
+			break
+		}
+
+		log.Info().
+			Uint64("version", m.Version).
+			Str("description", m.Description).
+			Bool("dry_run", opts.DryRun).
+			Msg("applying migration")
+
+		if !opts.DryRun {
+			if err := r.markStarted(ctx, m); err != nil {
+				return err
+			}
+		}
+
+		if err := m.Up(ctx, r.env(m, opts)); err != nil {
+			return fmt.Errorf("failed to apply migration %d: %w", m.Version, err)
+		}
+
+		if !opts.DryRun {
+			if err := r.markApplied(ctx, m); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Down reverts applied migrations with version greater than opts.Target in reverse order.
+// If opts.Target is zero only the last applied migration is reverted.
+func (r *Runner) Down(ctx context.Context, opts RunOptions) error {
+	statuses, err := r.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	var toRevert []Migration
+	for i := len(statuses) - 1; i >= 0; i-- {
+		status := statuses[i]
+		if !status.Applied && !status.InProgress {
+			continue
+		}
+		if opts.Target == 0 && len(toRevert) == 1 {
+			break
+		}
+		if opts.Target != 0 && status.Version <= opts.Target {
+			break
+		}
+		toRevert = append(toRevert, status.Migration)
+	}
+
+	// check all migrations first so we don't end up with partially reverted range
+	for _, m := range toRevert {
+		if m.Down == nil {
+			return fmt.Errorf("can't revert migration %d: %w", m.Version, ErrIrreversible)
+		}
+	}
+
+	log := log.Ctx(ctx)
+	for _, m := range toRevert {
+		log.Info().
+			Uint64("version", m.Version).
+			Str("description", m.Description).
+			Bool("dry_run", opts.DryRun).
+			Msg("reverting migration")
+
+		if err := m.Down(ctx, r.env(m, opts)); err != nil {
+			return fmt.Errorf("failed to revert migration %d: %w", m.Version, err)
+		}
+
+		if !opts.DryRun {
+			_, err := r.collection().DeleteOne(ctx, bson.M{"_id": m.Version})
+			if err != nil {
+				return fmt.Errorf("failed to delete migration %d record: %w", m.Version, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Baseline marks all migrations as applied if the database is empty (indexer was never started).
+// Migrations only transform existing data so there is nothing to apply in this case.
+func (r *Runner) Baseline(ctx context.Context) error {
+	records, err := r.records(ctx)
+	if err != nil {
+		return err
+	}
+	if len(records) != 0 {
+		return nil
+	}
+
+	lastHeight, err := r.database.Collection(model.LastProcessedHeightCollection).
+		CountDocuments(ctx, bson.M{})
+	if err != nil {
+		return err
+	}
+	delegations, err := r.database.Collection(model.BTCDelegationDetailsCollection).
+		CountDocuments(ctx, bson.M{})
+	if err != nil {
+		return err
+	}
+	if lastHeight != 0 || delegations != 0 {
+		return nil
+	}
+
+	for _, m := range r.migrations {
+		if err := r.markApplied(ctx, m); err != nil {
+			return err
+		}
+	}
+
+	log.Ctx(ctx).Info().Int("count", len(r.migrations)).Msg("empty database, migrations marked as applied")
+	return nil
+}
+
+func (r *Runner) env(m Migration, opts RunOptions) *Env {
+	return &Env{
+		Database:  r.database,
+		BBN:       r.bbn,
+		BTC:       r.btc,
+		DryRun:    opts.DryRun,
+		BatchSize: opts.BatchSize,
+		version:   m.Version,
+	}
+}
+
+func (r *Runner) records(ctx context.Context) (map[uint64]model.SchemaMigration, error) {
+	cursor, err := r.collection().Find(ctx, bson.M{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find schema migrations: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var docs []model.SchemaMigration
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("failed to decode schema migrations: %w", err)
+	}
+
+	records := make(map[uint64]model.SchemaMigration, len(docs))
+	for _, doc := range docs {
+		records[doc.Version] = doc
+	}
+
+	return records, nil
+}
+
+func (r *Runner) markStarted(ctx context.Context, m Migration) error {
+	update := bson.M{
+		"$set": bson.M{
+			"description": m.Description,
+			"applied":     false,
+		},
+	}
+	_, err := r.collection().UpdateOne(ctx, bson.M{"_id": m.Version}, update, options.Update().SetUpsert(true))
+	if err != nil {
+		return fmt.Errorf("failed to mark migration %d as started: %w", m.Version, err)
+	}
+	return nil
+}
+
+func (r *Runner) markApplied(ctx context.Context, m Migration) error {
+	update := bson.M{
+		"$set": bson.M{
+			"description": m.Description,
+			"applied":     true,
+			"applied_at":  time.Now().UTC(),
+		},
+		// checkpoints are needed only while migration is in progress
+		"$unset": bson.M{"checkpoints": ""},
+	}
+	_, err := r.collection().UpdateOne(ctx, bson.M{"_id": m.Version}, update, options.Update().SetUpsert(true))
+	if err != nil {
+		return fmt.Errorf("failed to mark migration %d as applied: %w", m.Version, err)
+	}
+	return nil
+}
+
+func (r *Runner) collection() *mongo.Collection {
+	return r.database.Collection(model.SchemaMigrationsCollection)
+}
@@ -0,0 +1,380 @@
+//go:build integration
+
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/pkg"
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/mocks"
+	ctypes "github.com/cometbft/cometbft/rpc/core/types"
+	cmttypes "github.com/cometbft/cometbft/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestRunner(t *testing.T) {
+	ctx := context.Background()
+
+	insertDelegations := func(t *testing.T, n int) {
+		for i := range n {
+			_, err := mongoDB.Collection(model.BTCDelegationDetailsCollection).InsertOne(ctx, bson.M{
+				"_id": fmt.Sprintf("%03d", i),
+			})
+			require.NoError(t, err)
+		}
+	}
+	countMarked := func(t *testing.T) int64 {
+		count, err := mongoDB.Collection(model.BTCDelegationDetailsCollection).
+			CountDocuments(ctx, bson.M{"marked": true})
+		require.NoError(t, err)
+		return count
+	}
+
+	// mark sets "marked" field in every delegation, fails after failAfter batches if it's positive
+	mark := func(failAfter *int) MigrationFunc {
+		return func(ctx context.Context, env *Env) error {
+			batches := 0
+			return env.ForEachBatch(ctx, model.BTCDelegationDetailsCollection, bson.M{}, func(ctx context.Context, docs []bson.Raw) error {
+				if *failAfter > 0 && batches == *failAfter {
+					return errors.New("interrupted")
+				}
+				batches++
+
+				if env.DryRun {
+					return nil
+				}
+				for _, doc := range docs {
+					_, err := env.Database.Collection(model.BTCDelegationDetailsCollection).
+						UpdateOne(ctx, bson.M{"_id": doc.Lookup("_id")}, bson.M{"$set": bson.M{"marked": true}})
+					if err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		}
+	}
+	unmark := func(ctx context.Context, env *Env) error {
+		_, err := env.Database.Collection(model.BTCDelegationDetailsCollection).
+			UpdateMany(ctx, bson.M{}, bson.M{"$unset": bson.M{"marked": ""}})
+		return err
+	}
+	noop := func(context.Context, *Env) error { return nil }
+
+	t.Run("up and down", func(t *testing.T) {
+		resetDatabase(t)
+		insertDelegations(t, 10)
+
+		failAfter := 0
+		runner, err := newRunner(mongoDB, []Migration{
+			{Version: 1, Description: "mark", Up: mark(&failAfter), Down: unmark},
+			{Version: 2, Description: "noop", Up: noop, Down: noop},
+		}, nil, nil)
+		require.NoError(t, err)
+
+		pending, err := runner.Pending(ctx)
+		require.NoError(t, err)
+		assert.Len(t, pending, 2)
+
+		err = runner.Up(ctx, RunOptions{Target: 1, BatchSize: 3})
+		require.NoError(t, err)
+		assert.EqualValues(t, 10, countMarked(t))
+
+		statuses, err := runner.Status(ctx)
+		require.NoError(t, err)
+		require.Len(t, statuses, 2)
+		assert.True(t, statuses[0].Applied)
+		assert.False(t, statuses[1].Applied)
+
+		err = runner.Up(ctx, RunOptions{})
+		require.NoError(t, err)
+		pending, err = runner.Pending(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, pending)
+
+		// reverts only the last one
+		err = runner.Down(ctx, RunOptions{})
+		require.NoError(t, err)
+		assert.EqualValues(t, 10, countMarked(t))
+
+		err = runner.Up(ctx, RunOptions{})
+		require.NoError(t, err)
+
+		// reverts everything one by one
+		err = runner.Down(ctx, RunOptions{})
+		require.NoError(t, err)
+		err = runner.Down(ctx, RunOptions{})
+		require.NoError(t, err)
+		assert.EqualValues(t, 0, countMarked(t))
+
+		pending, err = runner.Pending(ctx)
+		require.NoError(t, err)
+		assert.Len(t, pending, 2)
+	})
+	t.Run("resume from checkpoint", func(t *testing.T) {
+		resetDatabase(t)
+		insertDelegations(t, 10)
+
+		failAfter := 2
+		runner, err := newRunner(mongoDB, []Migration{
+			{Version: 1, Description: "mark", Up: mark(&failAfter)},
+		}, nil, nil)
+		require.NoError(t, err)
+
+		err = runner.Up(ctx, RunOptions{BatchSize: 3})
+		require.ErrorContains(t, err, "interrupted")
+		assert.EqualValues(t, 6, countMarked(t))
+
+		statuses, err := runner.Status(ctx)
+		require.NoError(t, err)
+		assert.True(t, statuses[0].InProgress)
+
+		// second run starts after the last processed document
+		var processed []string
+		runner.migrations[0].Up = func(ctx context.Context, env *Env) error {
+			return env.ForEachBatch(ctx, model.BTCDelegationDetailsCollection, bson.M{}, func(ctx context.Context, docs []bson.Raw) error {
+				for _, doc := range docs {
+					processed = append(processed, doc.Lookup("_id").StringValue())
+				}
+				return nil
+			})
+		}
+		err = runner.Up(ctx, RunOptions{BatchSize: 3})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"006", "007", "008", "009"}, processed)
+
+		pending, err := runner.Pending(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, pending)
+
+		// checkpoints are removed after migration is applied
+		var record model.SchemaMigration
+		err = mongoDB.Collection(model.SchemaMigrationsCollection).FindOne(ctx, bson.M{"_id": 1}).Decode(&record)
+		require.NoError(t, err)
+		assert.Empty(t, record.Checkpoints)
+	})
+	t.Run("dry run", func(t *testing.T) {
+		resetDatabase(t)
+		insertDelegations(t, 10)
+
+		failAfter := 0
+		runner, err := newRunner(mongoDB, []Migration{
+			{Version: 1, Description: "mark", Up: mark(&failAfter)},
+		}, nil, nil)
+		require.NoError(t, err)
+
+		err = runner.Up(ctx, RunOptions{DryRun: true, BatchSize: 3})
+		require.NoError(t, err)
+		assert.EqualValues(t, 0, countMarked(t))
+
+		pending, err := runner.Pending(ctx)
+		require.NoError(t, err)
+		assert.Len(t, pending, 1)
+	})
+	t.Run("irreversible", func(t *testing.T) {
+		resetDatabase(t)
+
+		runner, err := newRunner(mongoDB, []Migration{
+			{Version: 1, Description: "noop", Up: noop},
+		}, nil, nil)
+		require.NoError(t, err)
+
+		require.NoError(t, runner.Up(ctx, RunOptions{}))
+		err = runner.Down(ctx, RunOptions{})
+		require.ErrorIs(t, err, ErrIrreversible)
+	})
+	t.Run("baseline", func(t *testing.T) {
+		resetDatabase(t)
+
+		runner, err := newRunner(mongoDB, []Migration{
+			{Version: 1, Description: "noop", Up: noop},
+		}, nil, nil)
+		require.NoError(t, err)
+
+		// database with data is not baselined
+		insertDelegations(t, 1)
+		require.NoError(t, runner.Baseline(ctx))
+		pending, err := runner.Pending(ctx)
+		require.NoError(t, err)
+		assert.Len(t, pending, 1)
+
+		resetDatabase(t)
+		require.NoError(t, runner.Baseline(ctx))
+		pending, err = runner.Pending(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, pending)
+	})
+}
+
+func TestFillStakerAddressMigration(t *testing.T) {
+	ctx := context.Background()
+	resetDatabase(t)
+
+	const stakerAddr = "bbn1dppj9xellvzrh7x60vft4u8cpkyrvv3camt8ps"
+	docs := []any{
+		bson.M{"_id": "tx1"},
+		bson.M{"_id": "tx2", "staker_babylon_address": ""},
+		bson.M{"_id": "tx3", "staker_babylon_address": stakerAddr},
+	}
+	_, err := mongoDB.Collection(model.BTCDelegationDetailsCollection).InsertMany(ctx, docs)
+	require.NoError(t, err)
+
+	bbn := mocks.NewBbnInterface(t)
+	bbn.On("BabylonStakerAddress", mock.Anything, "tx1").Return(stakerAddr, nil).Once()
+	bbn.On("BabylonStakerAddress", mock.Anything, "tx2").Return(stakerAddr, nil).Once()
+
+	runner, err := newRunner(mongoDB, []Migration{fillStakerAddressMigration}, bbn, nil)
+	require.NoError(t, err)
+
+	err = runner.Up(ctx, RunOptions{BatchSize: 1})
+	require.NoError(t, err)
+
+	count, err := mongoDB.Collection(model.BTCDelegationDetailsCollection).
+		CountDocuments(ctx, bson.M{"staker_babylon_address": stakerAddr})
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, count)
+}
+
+func TestStateHistoryTimestampsMigration(t *testing.T) {
+	ctx := context.Background()
+	resetDatabase(t)
+
+	docs := []any{
+		bson.M{"_id": "tx1", "state_history": bson.A{
+			bson.M{"state": "PENDING", "bbn_height": int64(10)},
+			bson.M{"state": "ACTIVE", "bbn_height": int64(20), "btc_height": int64(100)},
+			bson.M{"state": "WITHDRAWABLE", "btc_height": int64(200)},
+		}},
+		// already filled records are not queried again
+		bson.M{"_id": "tx2", "state_history": bson.A{
+			bson.M{"state": "PENDING", "bbn_height": int64(10), "bbn_timestamp": int64(1)},
+		}},
+	}
+	collection := mongoDB.Collection(model.BTCDelegationDetailsCollection)
+	_, err := collection.InsertMany(ctx, docs)
+	require.NoError(t, err)
+
+	bbn := mocks.NewBbnInterface(t)
+	for height, timestamp := range map[int64]int64{10: 1010, 20: 1020} {
+		bbn.On("GetBlock", mock.Anything, pkg.Ptr(height)).Return(&ctypes.ResultBlock{
+			Block: &cmttypes.Block{Header: cmttypes.Header{Time: time.Unix(timestamp, 0)}},
+		}, nil).Once()
+	}
+	btc := mocks.NewBtcInterface(t)
+	for height, timestamp := range map[uint32]int64{100: 2100, 200: 2200} {
+		hash := fmt.Sprintf("hash%d", height)
+		btc.On("GetBlockHash", mock.Anything, height).Return(hash, nil)
+		btc.On("GetBlockTimestampByHash", mock.Anything, hash).Return(timestamp, nil).Once()
+	}
+
+	runner, err := newRunner(mongoDB, []Migration{stateHistoryTimestampsMigration}, bbn, btc)
+	require.NoError(t, err)
+	require.NoError(t, runner.Up(ctx, RunOptions{}))
+
+	var delegation model.BTCDelegationDetails
+	require.NoError(t, collection.FindOne(ctx, bson.M{"_id": "tx1"}).Decode(&delegation))
+	require.Len(t, delegation.StateHistory, 3)
+	assert.EqualValues(t, 1010, delegation.StateHistory[0].BbnTimestamp)
+	assert.EqualValues(t, 1020, delegation.StateHistory[1].BbnTimestamp)
+	assert.EqualValues(t, 2100, delegation.StateHistory[1].BtcTimestamp)
+	assert.Zero(t, delegation.StateHistory[2].BbnTimestamp)
+	assert.EqualValues(t, 2200, delegation.StateHistory[2].BtcTimestamp)
+	assert.EqualValues(t, 1, delegation.Version)
+
+	require.NoError(t, collection.FindOne(ctx, bson.M{"_id": "tx2"}).Decode(&delegation))
+	assert.EqualValues(t, 1, delegation.StateHistory[0].BbnTimestamp)
+
+	require.NoError(t, runner.Down(ctx, RunOptions{}))
+	count, err := collection.CountDocuments(ctx, missingStateHistoryTimestampsFilter)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, count)
+}
+
+func TestExpansionLinksMigration(t *testing.T) {
+	ctx := context.Background()
+	resetDatabase(t)
+
+	docs := []any{
+		bson.M{"_id": "tx1", "state": "EXPANDED"},
+		bson.M{"_id": "tx2", "state": "ACTIVE", "previous_staking_tx_hash_hex": "tx1", "start_height": int64(100)},
+		// expansion that never got BTC inclusion doesn't link its previous delegation
+		bson.M{"_id": "tx3", "state": "ACTIVE"},
+		bson.M{"_id": "tx4", "state": "VERIFIED", "previous_staking_tx_hash_hex": "tx3", "start_height": int64(0)},
+	}
+	collection := mongoDB.Collection(model.BTCDelegationDetailsCollection)
+	_, err := collection.InsertMany(ctx, docs)
+	require.NoError(t, err)
+
+	runner, err := newRunner(mongoDB, []Migration{expansionLinksMigration}, nil, nil)
+	require.NoError(t, err)
+	require.NoError(t, runner.Up(ctx, RunOptions{}))
+
+	var delegation model.BTCDelegationDetails
+	require.NoError(t, collection.FindOne(ctx, bson.M{"_id": "tx1"}).Decode(&delegation))
+	assert.Equal(t, "tx2", delegation.NextStakingTxHashHex)
+	assert.EqualValues(t, 1, delegation.Version)
+	require.NoError(t, collection.FindOne(ctx, bson.M{"_id": "tx3"}).Decode(&delegation))
+	assert.Empty(t, delegation.NextStakingTxHashHex)
+
+	require.NoError(t, runner.Down(ctx, RunOptions{}))
+	count, err := collection.CountDocuments(ctx, bson.M{"next_staking_tx_hash_hex": bson.M{"$exists": true}})
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
+
+func TestFinalityProviderHistorySeedMigration(t *testing.T) {
+	ctx := context.Background()
+	resetDatabase(t)
+
+	_, err := mongoDB.Collection(model.LastProcessedHeightCollection).InsertOne(ctx, bson.M{"height": uint64(50)})
+	require.NoError(t, err)
+	fps := []any{
+		&model.FinalityProviderDetails{BtcPk: "fp1", Commission: "0.1", State: "FINALITY_PROVIDER_STATUS_ACTIVE"},
+		&model.FinalityProviderDetails{BtcPk: "fp2", Commission: "0.2", State: "FINALITY_PROVIDER_STATUS_ACTIVE"},
+	}
+	_, err = mongoDB.Collection(model.FinalityProviderDetailsCollection).InsertMany(ctx, fps)
+	require.NoError(t, err)
+	// finality provider with history isn't seeded
+	history := mongoDB.Collection(model.FinalityProviderHistoryCollection)
+	_, err = history.InsertOne(ctx, &model.FinalityProviderHistoryRecord{
+		BtcPk: "fp2", BbnHeight: 40, Commission: "0.2", EventTypes: []string{"EventFinalityProviderEdited"},
+	})
+	require.NoError(t, err)
+
+	bbn := mocks.NewBbnInterface(t)
+	bbn.On("GetBlock", mock.Anything, pkg.Ptr(int64(50))).Return(&ctypes.ResultBlock{
+		Block: &cmttypes.Block{Header: cmttypes.Header{Time: time.Unix(1050, 0)}},
+	}, nil).Once()
+
+	runner, err := newRunner(mongoDB, []Migration{finalityProviderHistorySeedMigration}, bbn, nil)
+	require.NoError(t, err)
+	require.NoError(t, runner.Up(ctx, RunOptions{}))
+
+	var records []model.FinalityProviderHistoryRecord
+	cursor, err := history.Find(ctx, bson.M{"btc_pk": "fp1"})
+	require.NoError(t, err)
+	require.NoError(t, cursor.All(ctx, &records))
+	require.Len(t, records, 1)
+	assert.EqualValues(t, 50, records[0].BbnHeight)
+	assert.EqualValues(t, 1050, records[0].BbnTimestamp)
+	assert.Equal(t, "0.1", records[0].Commission)
+	assert.Equal(t, []string{types.MigrationEventType}, records[0].EventTypes)
+
+	count, err := history.CountDocuments(ctx, bson.M{"btc_pk": "fp2"})
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, count)
+
+	require.NoError(t, runner.Down(ctx, RunOptions{}))
+	count, err = history.CountDocuments(ctx, bson.M{})
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, count)
+}
@@ -0,0 +1,53 @@
+package model
+
+import "time"
+
+// Types of anomalies
+const (
+	// AnomalyTypeUnknownSpend is the staking or unbonding output spent by a tx that is
+	// neither unbonding, withdrawal, slashing nor stake expansion
+	AnomalyTypeUnknownSpend = "unknown_spend"
+)
+
+// Outputs of the delegation an unknown spend can spend
+const (
+	SpentOutputStaking   = "staking"
+	SpentOutputUnbonding = "unbonding"
+)
+
+// UnknownSpend describes the tx that spent an output of the delegation in an unrecognized way
+type UnknownSpend struct {
+	// Reason why the spend couldn't be classified
+	Reason string `bson:"reason"`
+	// SpentOutput is either SpentOutputStaking or SpentOutputUnbonding
+	SpentOutput string `bson:"spent_output"`
+	// SpendingTxHex is the raw spending tx, it's needed to investigate how the output was unlocked
+	SpendingTxHex string           `bson:"spending_tx_hex"`
+	Details       *BtcSpendDetails `bson:"details"`
+}
+
+// Anomaly is an unexpected on-chain event related to the delegation, stored for alerting and
+// investigation. Anomalies are kept even if the transition they caused was reverted by BTC reorg.
+type Anomaly struct {
+	StakingTxHashHex string        `bson:"staking_tx_hash_hex"`
+	Type             string        `bson:"type"`
+	SpendingTxHash   string        `bson:"spending_tx_hash"`
+	SpendingHeight   uint32        `bson:"spending_height"`
+	UnknownSpend     *UnknownSpend `bson:"unknown_spend,omitempty"`
+	CreatedAt        time.Time     `bson:"created_at"`
+}
+
+// NewUnknownSpendAnomaly records the unknown spend of the delegation output
+func NewUnknownSpendAnomaly(stakingTxHashHex string, spend *UnknownSpend) *Anomaly {
+	anomaly := &Anomaly{
+		StakingTxHashHex: stakingTxHashHex,
+		Type:             AnomalyTypeUnknownSpend,
+		UnknownSpend:     spend,
+		CreatedAt:        time.Now().UTC(),
+	}
+	if spend.Details != nil {
+		anomaly.SpendingTxHash = spend.Details.TxHash
+		anomaly.SpendingHeight = spend.Details.BlockHeight
+	}
+	return anomaly
+}
@@ -2,6 +2,7 @@ package model
 
 import (
 	"fmt"
+	"slices"
 
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/utils"
@@ -10,15 +11,29 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
+// BbnTx identifies Babylon transaction that emitted the event
+type BbnTx struct {
+	Hash string `bson:"hash"`
+	// Index of the tx in the block
+	Index uint32 `bson:"index"`
+	// Sender (signer) of the message that emitted the event
+	Sender string `bson:"sender,omitempty"`
+}
+
 type CovenantSignature struct {
 	CovenantBtcPkHex           string `bson:"covenant_btc_pk_hex"`
 	SignatureHex               string `bson:"signature_hex"`
 	StakeExpansionSignatureHex string `bson:"stake_expansion_signature_hex,omitempty"`
+	// Babylon tx that submitted the signature, it's unknown for signatures received before it was recorded
+	BbnTx *BbnTx `bson:"bbn_tx,omitempty"`
 }
 
 type BTCDelegationCreatedBbnBlock struct {
 	Height    int64 `bson:"height"`
 	Timestamp int64 `bson:"timestamp"` // epoch time in seconds
+	// Babylon tx that created the delegation, it's unknown for delegations
+	// created before it was recorded or bootstrapped from the chain state
+	Tx *BbnTx `bson:"tx,omitempty"`
 }
 
 type SlashingTx struct {
@@ -27,18 +42,86 @@ type SlashingTx struct {
 	SlashingBTCTimestamp          int64  `bson:"slashing_btc_timestamp"`
 	UnbondingSlashingTxHex        string `bson:"unbonding_slashing_tx_hex"`
 	UnbondingSlashingBTCTimestamp int64  `bson:"unbonding_slashing_btc_timestamp"`
+	// Details of the slashing tx, they are unknown for slashing recorded before they were stored
+	SlashingTxDetails          *SlashingTxDetails `bson:"slashing_tx_details,omitempty"`
+	UnbondingSlashingTxDetails *SlashingTxDetails `bson:"unbonding_slashing_tx_details,omitempty"`
+}
+
+// BtcSpendDetails describes BTC tx that spent an output of the delegation
+type BtcSpendDetails struct {
+	TxHash         string `bson:"tx_hash"`
+	BlockHeight    uint32 `bson:"block_height"`
+	BlockHash      string `bson:"block_hash"`
+	BlockTimestamp int64  `bson:"block_timestamp"`
+	// Value of the spent output in satoshis
+	SpentValue int64 `bson:"spent_value"`
+	// Fee in satoshis, nil if the tx spends other outputs as well, as their values are unknown
+	Fee     *int64        `bson:"fee,omitempty"`
+	Outputs []BtcTxOutput `bson:"outputs"`
+}
+
+// Confirmations returns confirmations of the tx at the given BTC tip height, the block
+// including the tx is the first confirmation
+func (d *BtcSpendDetails) Confirmations(tipHeight uint64) uint32 {
+	if tipHeight < uint64(d.BlockHeight) {
+		return 0
+	}
+	return uint32(tipHeight - uint64(d.BlockHeight) + 1)
+}
+
+type BtcTxOutput struct {
+	// Address is empty for scripts that don't encode a single address
+	Address string `bson:"address,omitempty"`
+	Value   int64  `bson:"value"`
+}
+
+// SlashingTxDetails is BtcSpendDetails of slashing tx, which sends the slashed part of the
+// spent output to the slashing address and the rest to the timelocked change output
+type SlashingTxDetails struct {
+	BtcSpendDetails `bson:",inline"`
+	SlashedAmount   int64 `bson:"slashed_amount"`
+	ChangeAmount    int64 `bson:"change_amount"`
 }
 
 type StateRecord struct {
 	State        types.DelegationState    `bson:"state"`
 	SubState     types.DelegationSubState `bson:"sub_state,omitempty"`
-	BbnHeight    int64                    `bson:"bbn_height,omitempty"` // Babylon block height when applicable
-	BtcHeight    uint32                   `bson:"btc_height,omitempty"` // Bitcoin block height when applicable
+	BbnHeight    int64                    `bson:"bbn_height,omitempty"`    // Babylon block height when applicable
+	BtcHeight    uint32                   `bson:"btc_height,omitempty"`    // Bitcoin block height when applicable
+	BbnTimestamp int64                    `bson:"bbn_timestamp,omitempty"` // Unix time of the Babylon block
+	BtcTimestamp int64                    `bson:"btc_timestamp,omitempty"` // Unix time of the Bitcoin block
 	BbnEventType string                   `bson:"bbn_event_type,omitempty"`
+	// Babylon tx that emitted the event, empty for block events and BTC transitions
+	BbnTx *BbnTx `bson:"bbn_tx,omitempty"`
 }
 
 type WithdrawalTx struct {
 	TxHash string `bson:"tx_hash"`
+	// Address of the first output of the withdrawal tx, details below are unknown
+	// for withdrawals recorded before they were stored
+	DestinationAddress string           `bson:"destination_address,omitempty"`
+	Details            *BtcSpendDetails `bson:"details,omitempty"`
+}
+
+// StakingTxConfirmation is the BTC status of the staking tx of a pre-approval delegation,
+// it's tracked while the delegation is PENDING or VERIFIED without inclusion proof
+type StakingTxConfirmation struct {
+	// BTC tip height when tracking of the staking tx started
+	TrackingStartHeight uint32 `bson:"tracking_start_height"`
+	InMempool           bool   `bson:"in_mempool"`
+	// Height and hash of the block including the staking tx, zero if it's not included
+	BlockHeight   uint32 `bson:"block_height,omitempty"`
+	BlockHash     string `bson:"block_hash,omitempty"`
+	Confirmations uint32 `bson:"confirmations"`
+	// Stale is set if the staking tx wasn't included within the configured number of
+	// BTC blocks since TrackingStartHeight
+	Stale     bool  `bson:"stale"`
+	UpdatedAt int64 `bson:"updated_at"`
+}
+
+// IsIncluded returns true if the staking tx is included in a BTC block
+func (c *StakingTxConfirmation) IsIncluded() bool {
+	return c.BlockHeight > 0
 }
 
 type BTCDelegationDetails struct {
@@ -61,6 +144,8 @@ type BTCDelegationDetails struct {
 	UnbondingTx               string                   `bson:"unbonding_tx"`
 	UnbondingStartHeight      uint32                   `bson:"unbonding_start_height"`
 	UnbondingBTCTimestamp     int64                    `bson:"unbonding_btc_timestamp"`
+	// Unbonding tx as it was included in BTC, it's only known if the spend was seen by the indexer
+	UnbondingTxDetails *BtcSpendDetails `bson:"unbonding_tx_details,omitempty"`
 	// Initially, we stored only unbonding signatures in this field. Now, other data from covenant signatures
 	// is stored here as well, but we keep the previous field name to avoid migrations.
 	CovenantSignatures        []CovenantSignature          `bson:"covenant_unbonding_signatures"`
@@ -70,12 +155,76 @@ type BTCDelegationDetails struct {
 	// Only expanded delegation has this field. It points to the previous staking
 	// tx hash in which the delegation was expanded. i.e this field is optional.
 	PreviousStakingTxHashHex string `bson:"previous_staking_tx_hash_hex,omitempty"`
+	// Only expanded delegation has this field. It points to the staking tx hash of
+	// the delegation it was expanded into, i.e. the reverse of PreviousStakingTxHashHex.
+	NextStakingTxHashHex string `bson:"next_staking_tx_hash_hex,omitempty"`
+	// BTC spends applied to the delegation that haven't reached BtcConfirmationDepth yet,
+	// ordered by application. Every spend depends on the previous ones.
+	ProvisionalSpends []ProvisionalSpend `bson:"provisional_spends,omitempty"`
+	// Thresholds (in BTC blocks before EndHeight) for which expiring soon event has been emitted
+	ExpiringSoonNotifiedThresholds []uint32 `bson:"expiring_soon_notified_thresholds,omitempty"`
+	// Only set for delegations in UNKNOWN_SPEND state, it describes the spending tx
+	UnknownSpend *UnknownSpend `bson:"unknown_spend,omitempty"`
+	// BTC status of the staking tx, only pre-approval delegations have this field
+	StakingTxConfirmation *StakingTxConfirmation `bson:"staking_tx_confirmation,omitempty"`
+	// Version is incremented by every update of the delegation. Updates made on behalf
+	// of the read delegation check it hasn't changed since. The exceptions are timelock
+	// expiry, whose bulk update doesn't read the delegation and is guarded by its state and
+	// provisional spends instead, and the expansion links migration, which only sets the link
+	// that isn't set yet.
+	Version int64 `bson:"version"`
+}
+
+// ProvisionalSpend is a state transition caused by BTC spend that can still be reorged out.
+// It keeps the fields of the delegation as they were before the transition so it can be reverted.
+type ProvisionalSpend struct {
+	SpendingTxHash string                   `bson:"spending_tx_hash"`
+	SpendingHeight uint32                   `bson:"spending_height"`
+	State          types.DelegationState    `bson:"state"`
+	SubState       types.DelegationSubState `bson:"sub_state,omitempty"`
+
+	PreviousState                 types.DelegationState    `bson:"previous_state"`
+	PreviousSubState              types.DelegationSubState `bson:"previous_sub_state,omitempty"`
+	PreviousStateHistoryLength    int                      `bson:"previous_state_history_length"`
+	PreviousUnbondingStartHeight  uint32                   `bson:"previous_unbonding_start_height"`
+	PreviousUnbondingBTCTimestamp int64                    `bson:"previous_unbonding_btc_timestamp"`
+	PreviousUnbondingTxDetails    *BtcSpendDetails         `bson:"previous_unbonding_tx_details,omitempty"`
+	PreviousSlashingTx            SlashingTx               `bson:"previous_slashing_tx"`
+	PreviousWithdrawalTx          WithdrawalTx             `bson:"previous_withdrawal_tx,omitempty"`
+}
+
+// NewProvisionalSpend records transition of the delegation from before to after state caused by the spending tx
+func NewProvisionalSpend(
+	before, after *BTCDelegationDetails, spendingTxHash string, spendingHeight uint32,
+) ProvisionalSpend {
+	return ProvisionalSpend{
+		SpendingTxHash:                spendingTxHash,
+		SpendingHeight:                spendingHeight,
+		State:                         after.State,
+		SubState:                      after.SubState,
+		PreviousState:                 before.State,
+		PreviousSubState:              before.SubState,
+		PreviousStateHistoryLength:    len(before.StateHistory),
+		PreviousUnbondingStartHeight:  before.UnbondingStartHeight,
+		PreviousUnbondingBTCTimestamp: before.UnbondingBTCTimestamp,
+		PreviousUnbondingTxDetails:    before.UnbondingTxDetails,
+		PreviousSlashingTx:            before.SlashingTx,
+		PreviousWithdrawalTx:          before.WithdrawalTx,
+	}
+}
+
+// FindProvisionalSpend returns index of provisional spend by the spending tx hash or -1 if there is none
+func (d *BTCDelegationDetails) FindProvisionalSpend(spendingTxHash string) int {
+	return slices.IndexFunc(d.ProvisionalSpends, func(spend ProvisionalSpend) bool {
+		return spend.SpendingTxHash == spendingTxHash
+	})
 }
 
 func FromEventBTCDelegationCreated(
-	event *bbntypes.EventBTCDelegationCreated,
+	event *types.BTCDelegationCreatedEvent,
 	bbnBlockHeight,
 	bbnBlockTime int64,
+	bbnTx *BbnTx,
 ) (*BTCDelegationDetails, error) {
 	stakingOutputIdx, err := utils.ParseUint32(event.StakingOutputIndex)
 	if err != nil {
@@ -127,23 +276,116 @@ func FromEventBTCDelegationCreated(
 		BTCDelegationCreatedBlock: BTCDelegationCreatedBbnBlock{
 			Height:    bbnBlockHeight,
 			Timestamp: bbnBlockTime,
+			Tx:        bbnTx,
 		},
 		StateHistory: []StateRecord{
 			{
 				State:        types.StatePending,
 				BbnHeight:    bbnBlockHeight,
+				BbnTimestamp: bbnBlockTime,
 				BbnEventType: types.EventBTCDelegationCreated.ShortName(),
+				BbnTx:        bbnTx,
 			},
 		},
 		PreviousStakingTxHashHex: event.PreviousStakingTxHashHex,
 	}, nil
 }
 
+// FromBTCDelegationResponse maps delegation returned by babylon query into the document.
+// State, sub state and state history are left empty - they depend on the context the query was made in.
+// As the block the delegation was created in is unknown, the given (queried) block is used instead.
+func FromBTCDelegationResponse(
+	resp *bbntypes.BTCDelegationResponse,
+	bbnBlockHeight,
+	bbnBlockTime int64,
+) (*BTCDelegationDetails, error) {
+	stakingTx, err := utils.DeserializeBtcTransactionFromHex(resp.StakingTxHex)
+	if err != nil {
+		return nil, fmt.Errorf("failed to deserialize staking tx: %w", err)
+	}
+
+	fpBtcPks := make([]string, len(resp.FpBtcPkList))
+	for i := range resp.FpBtcPkList {
+		fpBtcPks[i] = resp.FpBtcPkList[i].MarshalHex()
+	}
+
+	delegation := &BTCDelegationDetails{
+		StakingTxHashHex:          stakingTx.TxHash().String(),
+		StakingTxHex:              resp.StakingTxHex,
+		StakingTime:               resp.StakingTime,
+		StakingAmount:             resp.TotalSat,
+		StakingOutputIdx:          resp.StakingOutputIdx,
+		StakerBtcPkHex:            resp.BtcPk.MarshalHex(),
+		StakerBabylonAddress:      resp.StakerAddr,
+		FinalityProviderBtcPksHex: fpBtcPks,
+		StartHeight:               resp.StartHeight,
+		EndHeight:                 resp.EndHeight,
+		ParamsVersion:             resp.ParamsVersion,
+		UnbondingTime:             resp.UnbondingTime,
+		CovenantSignatures:        []CovenantSignature{},
+		BTCDelegationCreatedBlock: BTCDelegationCreatedBbnBlock{
+			Height:    bbnBlockHeight,
+			Timestamp: bbnBlockTime,
+		},
+	}
+
+	if resp.UndelegationResponse != nil {
+		delegation.UnbondingTx = resp.UndelegationResponse.UnbondingTxHex
+		for _, sig := range resp.UndelegationResponse.CovenantUnbondingSigList {
+			delegation.CovenantSignatures = append(delegation.CovenantSignatures, CovenantSignature{
+				CovenantBtcPkHex: sig.Pk.MarshalHex(),
+				SignatureHex:     sig.Sig.ToHexStr(),
+			})
+		}
+	}
+
+	if resp.StkExp != nil {
+		delegation.PreviousStakingTxHashHex = resp.StkExp.PreviousStakingTxHashHex
+		// stake expansion signatures are stored together with unbonding ones from the same covenant member
+		for _, sig := range resp.StkExp.PreviousStkCovenantSigs {
+			covenantBtcPkHex := sig.Pk.MarshalHex()
+			idx := slices.IndexFunc(delegation.CovenantSignatures, func(s CovenantSignature) bool {
+				return s.CovenantBtcPkHex == covenantBtcPkHex
+			})
+			if idx == -1 {
+				delegation.CovenantSignatures = append(delegation.CovenantSignatures, CovenantSignature{
+					CovenantBtcPkHex: covenantBtcPkHex,
+				})
+				idx = len(delegation.CovenantSignatures) - 1
+			}
+			delegation.CovenantSignatures[idx].StakeExpansionSignatureHex = sig.Sig.ToHexStr()
+		}
+	}
+
+	return delegation, nil
+}
+
 func (d *BTCDelegationDetails) HasInclusionProof() bool {
 	// Ref: https://github.com/babylonlabs-io/babylon/blob/b1a4b483f60458fcf506adf1d80aaa6c8c10f8a4/x/btcstaking/types/btc_delegation.go#L47
 	return d.StartHeight > 0 && d.EndHeight > 0
 }
 
+// ExpireHeight returns BTC height at which babylon expires the delegation,
+// it's expired once BTC tip + unbonding time reaches the end height
+func (d *BTCDelegationDetails) ExpireHeight() uint32 {
+	if d.EndHeight < d.UnbondingTime {
+		return 0
+	}
+	return d.EndHeight - d.UnbondingTime
+}
+
+// SatisfiesGuard checks the transition guard against the delegation
+func (d *BTCDelegationDetails) SatisfiesGuard(guard types.Guard) bool {
+	switch guard {
+	case types.GuardNoInclusionProof:
+		return !d.HasInclusionProof()
+	case types.GuardHasInclusionProof:
+		return d.HasInclusionProof()
+	default:
+		return true
+	}
+}
+
 func ToStateStrings(stateHistory []StateRecord) []string {
 	states := make([]string, len(stateHistory))
 	for i, record := range stateHistory {
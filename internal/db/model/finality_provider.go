@@ -1,6 +1,7 @@
 package model
 
 import (
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
 	bbntypes "github.com/babylonlabs-io/babylon/v4/x/btcstaking/types"
 )
 
@@ -22,7 +23,7 @@ type Description struct {
 }
 
 func FromEventFinalityProviderCreated(
-	event *bbntypes.EventFinalityProviderCreated,
+	event *types.FinalityProviderCreatedEvent,
 ) *FinalityProviderDetails {
 	return &FinalityProviderDetails{
 		BtcPk:          event.BtcPkHex,
@@ -40,7 +41,7 @@ func FromEventFinalityProviderCreated(
 }
 
 func FromEventFinalityProviderEdited(
-	event *bbntypes.EventFinalityProviderEdited,
+	event *types.FinalityProviderEditedEvent,
 ) *FinalityProviderDetails {
 	return &FinalityProviderDetails{
 		BtcPk: event.BtcPkHex,
@@ -54,3 +55,28 @@ func FromEventFinalityProviderEdited(
 		Commission: event.Commission,
 	}
 }
+
+// FromFinalityProviderResponse maps finality provider returned by babylon query into the document
+func FromFinalityProviderResponse(
+	resp *bbntypes.FinalityProviderResponse, state string,
+) *FinalityProviderDetails {
+	fp := &FinalityProviderDetails{
+		BtcPk:          resp.BtcPk.MarshalHex(),
+		BabylonAddress: resp.Addr,
+		State:          state,
+	}
+	if resp.Commission != nil {
+		fp.Commission = resp.Commission.String()
+	}
+	if resp.Description != nil {
+		fp.Description = Description{
+			Moniker:         resp.Description.Moniker,
+			Identity:        resp.Description.Identity,
+			Website:         resp.Description.Website,
+			SecurityContact: resp.Description.SecurityContact,
+			Details:         resp.Description.Details,
+		}
+	}
+
+	return fp
+}
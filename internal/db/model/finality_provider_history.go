@@ -0,0 +1,32 @@
+package model
+
+// FinalityProviderHistoryRecord is the finality provider as it was at the end of the BBN height.
+// There is one record per height in which the finality provider was created, edited or
+// changed its status, records are never changed by later heights.
+type FinalityProviderHistoryRecord struct {
+	BtcPk        string      `bson:"btc_pk"`
+	BbnHeight    int64       `bson:"bbn_height"`
+	BbnTimestamp int64       `bson:"bbn_timestamp"`
+	Commission   string      `bson:"commission"`
+	State        string      `bson:"state"`
+	Description  Description `bson:"description"`
+	// Types of the events that changed the finality provider at this height
+	EventTypes []string `bson:"event_types"`
+	// Tx of the last event, nil for block events and bootstrap
+	BbnTx *BbnTx `bson:"bbn_tx,omitempty"`
+}
+
+func NewFinalityProviderHistoryRecord(
+	fp *FinalityProviderDetails, eventType string, bbnHeight, bbnTimestamp int64, bbnTx *BbnTx,
+) *FinalityProviderHistoryRecord {
+	return &FinalityProviderHistoryRecord{
+		BtcPk:        fp.BtcPk,
+		BbnHeight:    bbnHeight,
+		BbnTimestamp: bbnTimestamp,
+		Commission:   fp.Commission,
+		State:        fp.State,
+		Description:  fp.Description,
+		EventTypes:   []string{eventType},
+		BbnTx:        bbnTx,
+	}
+}
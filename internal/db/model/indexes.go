@@ -0,0 +1,296 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// name of the index mongo creates on _id field for every collection, it can't be dropped
+const defaultIDIndexName = "_id_"
+
+// error code returned by mongo when listing indexes of non-existing collection
+const namespaceNotFoundCode = 26
+
+// Index is a declarative definition of collection index.
+// Indexes are matched with existing ones by name, so renaming an index results in
+// dropping the old one and creating the new one.
+type Index struct {
+	Name string
+	// Keys are ordered, it matters for compound indexes
+	Keys   bson.D
+	Unique bool
+	// Sparse index skips documents that don't contain indexed field
+	Sparse bool
+	// PartialFilter limits index to documents matching the filter
+	PartialFilter bson.D
+	// ExpireAfter turns index into TTL index, zero value means no expiration
+	ExpireAfter time.Duration
+}
+
+func (i Index) model() mongo.IndexModel {
+	opts := options.Index().SetName(i.Name)
+	if i.Unique {
+		opts.SetUnique(true)
+	}
+	if i.Sparse {
+		opts.SetSparse(true)
+	}
+	if len(i.PartialFilter) > 0 {
+		opts.SetPartialFilterExpression(i.PartialFilter)
+	}
+	if i.ExpireAfter > 0 {
+		opts.SetExpireAfterSeconds(int32(i.ExpireAfter.Seconds()))
+	}
+
+	return mongo.IndexModel{
+		Keys:    i.Keys,
+		Options: opts,
+	}
+}
+
+// existingIndex is an index specification as returned by listIndexes command
+type existingIndex struct {
+	Name               string `bson:"name"`
+	Keys               bson.D `bson:"key"`
+	Unique             bool   `bson:"unique"`
+	Sparse             bool   `bson:"sparse"`
+	PartialFilter      bson.D `bson:"partialFilterExpression"`
+	ExpireAfterSeconds *int32 `bson:"expireAfterSeconds"`
+}
+
+// diff returns description of differences between existing and desired index, empty string means they are equal
+func (e existingIndex) diff(desired Index) string {
+	var diffs []string
+	if !equalDocs(e.Keys, desired.Keys) {
+		diffs = append(diffs, fmt.Sprintf("keys %s -> %s", docString(e.Keys), docString(desired.Keys)))
+	}
+	if e.Unique != desired.Unique {
+		diffs = append(diffs, fmt.Sprintf("unique %t -> %t", e.Unique, desired.Unique))
+	}
+	if e.Sparse != desired.Sparse {
+		diffs = append(diffs, fmt.Sprintf("sparse %t -> %t", e.Sparse, desired.Sparse))
+	}
+	if !equalDocs(e.PartialFilter, desired.PartialFilter) {
+		diffs = append(diffs, fmt.Sprintf(
+			"partial filter %s -> %s", docString(e.PartialFilter), docString(desired.PartialFilter),
+		))
+	}
+	var existingTTL int32
+	if e.ExpireAfterSeconds != nil {
+		existingTTL = *e.ExpireAfterSeconds
+	}
+	if desiredTTL := int32(desired.ExpireAfter.Seconds()); existingTTL != desiredTTL {
+		diffs = append(diffs, fmt.Sprintf("expire after %ds -> %ds", existingTTL, desiredTTL))
+	}
+
+	return strings.Join(diffs, ", ")
+}
+
+type IndexAction string
+
+const (
+	IndexActionCreate IndexAction = "create"
+	IndexActionUpdate IndexAction = "update"
+	IndexActionDrop   IndexAction = "drop"
+)
+
+// IndexChange is a single step required to bring indexes of the collection in line with definitions
+type IndexChange struct {
+	Collection string
+	Action     IndexAction
+	// Name of the index being created, updated or dropped
+	Name string
+	// Index is the desired definition, it's empty for drop action
+	Index Index
+	// Reason explains why the change is needed
+	Reason string
+}
+
+func (c IndexChange) String() string {
+	s := fmt.Sprintf("%s %s.%s", c.Action, c.Collection, c.Name)
+	if c.Action != IndexActionDrop {
+		s += " " + docString(c.Index.Keys)
+	}
+	if c.Reason != "" {
+		s += " (" + c.Reason + ")"
+	}
+	return s
+}
+
+// PlanIndexes compares indexes defined for every collection with existing ones
+// and returns changes required to reconcile them. Indexes that are not defined are planned to be dropped.
+func PlanIndexes(ctx context.Context, database *mongo.Database) ([]IndexChange, error) {
+	var changes []IndexChange
+	// iterate collections in stable order so the plan is deterministic
+	for _, name := range CollectionNames() {
+		existing, err := listIndexes(ctx, database.Collection(name))
+		if err != nil {
+			return nil, err
+		}
+
+		changes = append(changes, planCollectionIndexes(name, collections[name], existing)...)
+	}
+
+	return changes, nil
+}
+
+func planCollectionIndexes(collection string, desired []Index, existing []existingIndex) []IndexChange {
+	existingByName := make(map[string]existingIndex, len(existing))
+	for _, idx := range existing {
+		existingByName[idx.Name] = idx
+	}
+
+	var drops, updates, creates []IndexChange
+	desiredNames := make(map[string]struct{}, len(desired))
+	for _, idx := range desired {
+		desiredNames[idx.Name] = struct{}{}
+
+		current, ok := existingByName[idx.Name]
+		if !ok {
+			creates = append(creates, IndexChange{
+				Collection: collection,
+				Action:     IndexActionCreate,
+				Name:       idx.Name,
+				Index:      idx,
+				Reason:     "missing",
+			})
+			continue
+		}
+
+		if diff := current.diff(idx); diff != "" {
+			updates = append(updates, IndexChange{
+				Collection: collection,
+				Action:     IndexActionUpdate,
+				Name:       idx.Name,
+				Index:      idx,
+				Reason:     diff,
+			})
+		}
+	}
+
+	for _, idx := range existing {
+		if idx.Name == defaultIDIndexName {
+			continue
+		}
+		if _, ok := desiredNames[idx.Name]; ok {
+			continue
+		}
+		drops = append(drops, IndexChange{
+			Collection: collection,
+			Action:     IndexActionDrop,
+			Name:       idx.Name,
+			Reason:     "not defined",
+		})
+	}
+
+	// drops go first so new indexes with the same keys don't conflict with obsolete ones
+	changes := append(drops, updates...)
+	return append(changes, creates...)
+}
+
+// ApplyIndexes executes changes returned by PlanIndexes. It stops on the first failure.
+// Update is performed as drop followed by create because mongo doesn't allow to modify index options in place.
+func ApplyIndexes(ctx context.Context, database *mongo.Database, changes []IndexChange) error {
+	log := log.Ctx(ctx)
+	for _, change := range changes {
+		indexes := database.Collection(change.Collection).Indexes()
+
+		if change.Action == IndexActionDrop || change.Action == IndexActionUpdate {
+			if _, err := indexes.DropOne(ctx, change.Name); err != nil {
+				return fmt.Errorf("failed to %s: %w", change, err)
+			}
+		}
+
+		if change.Action == IndexActionCreate || change.Action == IndexActionUpdate {
+			if _, err := indexes.CreateOne(ctx, change.Index.model()); err != nil {
+				return fmt.Errorf("failed to %s: %w", change, err)
+			}
+		}
+
+		log.Info().Stringer("change", change).Msg("index change applied")
+	}
+
+	return nil
+}
+
+func listIndexes(ctx context.Context, collection *mongo.Collection) ([]existingIndex, error) {
+	cursor, err := collection.Indexes().List(ctx)
+	if err != nil {
+		var cmdErr mongo.CommandError
+		if errors.As(err, &cmdErr) && cmdErr.Code == namespaceNotFoundCode {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to list indexes of %s: %w", collection.Name(), err)
+	}
+	defer cursor.Close(ctx)
+
+	var indexes []existingIndex
+	if err := cursor.All(ctx, &indexes); err != nil {
+		return nil, fmt.Errorf("failed to decode indexes of %s: %w", collection.Name(), err)
+	}
+
+	return indexes, nil
+}
+
+// equalDocs compares documents ignoring numeric types (mongo may return int32 for value defined as int64 etc.)
+func equalDocs(a, b bson.D) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+	return reflect.DeepEqual(normalize(a), normalize(b))
+}
+
+func normalize(v any) any {
+	switch v := v.(type) {
+	case bson.D:
+		result := make(bson.D, len(v))
+		for i, e := range v {
+			result[i] = bson.E{Key: e.Key, Value: normalize(e.Value)}
+		}
+		return result
+	case bson.A:
+		result := make(bson.A, len(v))
+		for i, e := range v {
+			result[i] = normalize(e)
+		}
+		return result
+	case []any:
+		return normalize(bson.A(v))
+	case bson.M:
+		result := make(bson.M, len(v))
+		for k, e := range v {
+			result[k] = normalize(e)
+		}
+		return result
+	case int:
+		return float64(v)
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case float32:
+		return float64(v)
+	default:
+		return v
+	}
+}
+
+func docString(d bson.D) string {
+	if len(d) == 0 {
+		return "{}"
+	}
+	ext, err := bson.MarshalExtJSON(d, false, false)
+	if err != nil {
+		return fmt.Sprintf("%v", d)
+	}
+	return string(ext)
+}
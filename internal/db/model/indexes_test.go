@@ -0,0 +1,77 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestPlanCollectionIndexes(t *testing.T) {
+	const collection = "test"
+	ttl := int32(60)
+
+	desired := []Index{
+		{Name: "a_1", Keys: bson.D{{Key: "a", Value: 1}}},
+		{Name: "b_1_c_-1", Keys: bson.D{{Key: "b", Value: 1}, {Key: "c", Value: -1}}, Unique: true},
+		{Name: "d_1", Keys: bson.D{{Key: "d", Value: 1}}, Sparse: true},
+		{
+			Name:          "e_1",
+			Keys:          bson.D{{Key: "e", Value: 1}},
+			PartialFilter: bson.D{{Key: "e", Value: bson.D{{Key: "$exists", Value: true}}}},
+		},
+		{Name: "created_at_1", Keys: bson.D{{Key: "created_at", Value: 1}}, ExpireAfter: time.Minute},
+	}
+
+	t.Run("empty collection", func(t *testing.T) {
+		changes := planCollectionIndexes(collection, desired, nil)
+		assert.Len(t, changes, len(desired))
+		for i, change := range changes {
+			assert.Equal(t, IndexActionCreate, change.Action)
+			assert.Equal(t, desired[i].Name, change.Name)
+		}
+	})
+	t.Run("up to date", func(t *testing.T) {
+		existing := []existingIndex{
+			{Name: defaultIDIndexName, Keys: bson.D{{Key: "_id", Value: int32(1)}}},
+			{Name: "a_1", Keys: bson.D{{Key: "a", Value: int32(1)}}},
+			{Name: "b_1_c_-1", Keys: bson.D{{Key: "b", Value: int32(1)}, {Key: "c", Value: int32(-1)}}, Unique: true},
+			{Name: "d_1", Keys: bson.D{{Key: "d", Value: int32(1)}}, Sparse: true},
+			{
+				Name:          "e_1",
+				Keys:          bson.D{{Key: "e", Value: int32(1)}},
+				PartialFilter: bson.D{{Key: "e", Value: bson.D{{Key: "$exists", Value: true}}}},
+			},
+			{Name: "created_at_1", Keys: bson.D{{Key: "created_at", Value: int64(1)}}, ExpireAfterSeconds: &ttl},
+		}
+		changes := planCollectionIndexes(collection, desired, existing)
+		assert.Empty(t, changes)
+	})
+	t.Run("create, update and drop", func(t *testing.T) {
+		existing := []existingIndex{
+			{Name: defaultIDIndexName, Keys: bson.D{{Key: "_id", Value: int32(1)}}},
+			// not unique
+			{Name: "b_1_c_-1", Keys: bson.D{{Key: "b", Value: int32(1)}, {Key: "c", Value: int32(-1)}}},
+			// different keys
+			{Name: "d_1", Keys: bson.D{{Key: "c", Value: int32(1)}}, Sparse: true},
+			{Name: "obsolete_1", Keys: bson.D{{Key: "obsolete", Value: int32(1)}}},
+		}
+		changes := planCollectionIndexes(collection, desired[:3], existing)
+
+		expected := []struct {
+			action IndexAction
+			name   string
+		}{
+			{IndexActionDrop, "obsolete_1"},
+			{IndexActionUpdate, "b_1_c_-1"},
+			{IndexActionUpdate, "d_1"},
+			{IndexActionCreate, "a_1"},
+		}
+		assert.Len(t, changes, len(expected))
+		for i, e := range expected {
+			assert.Equal(t, e.action, changes[i].Action)
+			assert.Equal(t, e.name, changes[i].Name)
+		}
+	})
+}
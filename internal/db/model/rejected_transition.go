@@ -0,0 +1,63 @@
+package model
+
+import (
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	RejectedReasonNotFound     = "delegation not found"
+	RejectedReasonNotQualified = "current state is not qualified"
+	RejectedReasonGuard        = "guard doesn't hold"
+)
+
+// RejectedTransition is a state transition that didn't match the delegation, stored for investigation
+type RejectedTransition struct {
+	ID               primitive.ObjectID       `bson:"_id,omitempty"`
+	StakingTxHashHex string                   `bson:"staking_tx_hash_hex"`
+	Trigger          types.Trigger            `bson:"trigger"`
+	CurrentState     types.DelegationState    `bson:"current_state,omitempty"`
+	CurrentSubState  types.DelegationSubState `bson:"current_sub_state,omitempty"`
+	NewState         types.DelegationState    `bson:"new_state"`
+	NewSubState      types.DelegationSubState `bson:"new_sub_state,omitempty"`
+	Guard            types.Guard              `bson:"guard,omitempty"`
+	Reason           string                   `bson:"reason"`
+	BbnHeight        int64                    `bson:"bbn_height,omitempty"`
+	BtcHeight        uint32                   `bson:"btc_height,omitempty"`
+	CreatedAt        time.Time                `bson:"created_at"`
+}
+
+// NewRejectedTransition describes why the delegation can't take the transition.
+// Delegation is nil if it doesn't exist.
+func NewRejectedTransition(
+	delegation *BTCDelegationDetails, stakingTxHashHex string, transition types.Transition,
+) *RejectedTransition {
+	rejected := &RejectedTransition{
+		StakingTxHashHex: stakingTxHashHex,
+		Trigger:          transition.Trigger,
+		NewState:         transition.To,
+		NewSubState:      transition.SubState,
+		Guard:            transition.Guard,
+		CreatedAt:        time.Now().UTC(),
+	}
+
+	switch {
+	case delegation == nil:
+		rejected.Reason = RejectedReasonNotFound
+	case !transition.Allows(delegation.State):
+		rejected.Reason = RejectedReasonNotQualified
+	case !delegation.SatisfiesGuard(transition.Guard):
+		rejected.Reason = RejectedReasonGuard
+	default:
+		// delegation has changed after the update was rejected
+		rejected.Reason = RejectedReasonNotQualified
+	}
+	if delegation != nil {
+		rejected.CurrentState = delegation.State
+		rejected.CurrentSubState = delegation.SubState
+	}
+
+	return rejected
+}
@@ -0,0 +1,39 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRejectedTransition(t *testing.T) {
+	transition, err := types.FindTransition(types.TriggerCovenantQuorumReached, types.StateActive, "")
+	require.NoError(t, err)
+
+	t.Run("not found", func(t *testing.T) {
+		rejected := NewRejectedTransition(nil, "tx", transition)
+		assert.Equal(t, RejectedReasonNotFound, rejected.Reason)
+		assert.Equal(t, "tx", rejected.StakingTxHashHex)
+		assert.Equal(t, types.TriggerCovenantQuorumReached, rejected.Trigger)
+		assert.Equal(t, types.StateActive, rejected.NewState)
+		assert.Empty(t, rejected.CurrentState)
+	})
+	t.Run("not qualified", func(t *testing.T) {
+		delegation := &BTCDelegationDetails{State: types.StateVerified}
+		rejected := NewRejectedTransition(delegation, "tx", transition)
+		assert.Equal(t, RejectedReasonNotQualified, rejected.Reason)
+		assert.Equal(t, types.StateVerified, rejected.CurrentState)
+	})
+	t.Run("guard", func(t *testing.T) {
+		delegation := &BTCDelegationDetails{State: types.StatePending}
+		rejected := NewRejectedTransition(delegation, "tx", transition)
+		assert.Equal(t, RejectedReasonGuard, rejected.Reason)
+		assert.Equal(t, types.GuardHasInclusionProof, rejected.Guard)
+
+		delegation.StartHeight, delegation.EndHeight = 100, 200
+		assert.True(t, delegation.SatisfiesGuard(rejected.Guard))
+		assert.False(t, delegation.SatisfiesGuard(types.GuardNoInclusionProof))
+	})
+}
@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+type SchemaMigration struct {
+	Version     uint64    `bson:"_id"`
+	Description string    `bson:"description"`
+	AppliedAt   time.Time `bson:"applied_at"`
+	// Applied is false while migration is in progress (or was interrupted)
+	Applied bool `bson:"applied"`
+	// Checkpoints stores last processed _id per collection for resumable batch processing
+	Checkpoints map[string]any `bson:"checkpoints,omitempty"`
+}
@@ -0,0 +1,66 @@
+package model
+
+import (
+	"github.com/babylonlabs-io/babylon-staking-indexer/pkg/sdk"
+)
+
+// ToSDK maps the delegation document into the read model of the public sdk
+func (d *BTCDelegationDetails) ToSDK() *sdk.Delegation {
+	history := make([]sdk.StateRecord, 0, len(d.StateHistory))
+	for _, record := range d.StateHistory {
+		history = append(history, sdk.StateRecord{
+			State:        sdk.DelegationState(record.State),
+			SubState:     sdk.DelegationSubState(record.SubState),
+			BbnHeight:    record.BbnHeight,
+			BtcHeight:    record.BtcHeight,
+			BbnTimestamp: record.BbnTimestamp,
+			BtcTimestamp: record.BtcTimestamp,
+		})
+	}
+
+	return &sdk.Delegation{
+		StakingTxHashHex:          d.StakingTxHashHex,
+		StakingTxHex:              d.StakingTxHex,
+		StakingOutputIdx:          d.StakingOutputIdx,
+		StakingAmount:             d.StakingAmount,
+		StakingTime:               d.StakingTime,
+		StakingBtcTimestamp:       d.StakingBTCTimestamp,
+		StakerBtcPkHex:            d.StakerBtcPkHex,
+		StakerBabylonAddress:      d.StakerBabylonAddress,
+		FinalityProviderBtcPksHex: d.FinalityProviderBtcPksHex,
+		ParamsVersion:             d.ParamsVersion,
+		StartHeight:               d.StartHeight,
+		EndHeight:                 d.EndHeight,
+		State:                     sdk.DelegationState(d.State),
+		SubState:                  sdk.DelegationSubState(d.SubState),
+		StateHistory:              history,
+		UnbondingTime:             d.UnbondingTime,
+		UnbondingTxHex:            d.UnbondingTx,
+		UnbondingStartHeight:      d.UnbondingStartHeight,
+		UnbondingBtcTimestamp:     d.UnbondingBTCTimestamp,
+		CreatedBbnHeight:          d.BTCDelegationCreatedBlock.Height,
+		CreatedBbnTimestamp:       d.BTCDelegationCreatedBlock.Timestamp,
+		SlashingTxHex:             d.SlashingTx.SlashingTxHex,
+		UnbondingSlashingTxHex:    d.SlashingTx.UnbondingSlashingTxHex,
+		WithdrawalTxHash:          d.WithdrawalTx.TxHash,
+		PreviousStakingTxHashHex:  d.PreviousStakingTxHashHex,
+		NextStakingTxHashHex:      d.NextStakingTxHashHex,
+	}
+}
+
+// ToSDK maps the finality provider document into the read model of the public sdk
+func (fp *FinalityProviderDetails) ToSDK() *sdk.FinalityProvider {
+	return &sdk.FinalityProvider{
+		BtcPkHex:       fp.BtcPk,
+		BabylonAddress: fp.BabylonAddress,
+		Commission:     fp.Commission,
+		State:          sdk.FinalityProviderState(fp.State),
+		Description: sdk.Description{
+			Moniker:         fp.Description.Moniker,
+			Identity:        fp.Description.Identity,
+			Website:         fp.Description.Website,
+			SecurityContact: fp.Description.SecurityContact,
+			Details:         fp.Description.Details,
+		},
+	}
+}
@@ -3,6 +3,7 @@ package model
 import (
 	"context"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
@@ -22,71 +23,164 @@ const (
 	LastProcessedHeightCollection     = "last_processed_height"
 	NetworkInfoCollection             = "network_info"
 	StatsCollection                   = "stats"
+	SchemaMigrationsCollection        = "schema_migrations"
+	RejectedTransitionsCollection     = "rejected_transitions"
+	FinalityProviderHistoryCollection = "finality_provider_history"
+	UnlockProjectionsCollection       = "unlock_projections"
+	AnomaliesCollection               = "anomalies"
 )
 
-type index struct {
-	Indexes map[string]int
-	Unique  bool
-}
-
-var collections = map[string][]index{
+// collections maps every collection to its indexes.
+// Indexes not listed here are dropped by "indexes apply" command (except default _id index).
+var collections = map[string][]Index{
 	FinalityProviderDetailsCollection: {},
 	FinalityProviderStatsCollection:   {},
+	FinalityProviderHistoryCollection: {
+		{
+			Name:   "btc_pk_1_bbn_height_1",
+			Keys:   bson.D{{Key: "btc_pk", Value: 1}, {Key: "bbn_height", Value: 1}},
+			Unique: true,
+		},
+	},
 	BTCDelegationDetailsCollection: {
 		{
-			Indexes: map[string]int{
-				"staker_btc_pk_hex":                       1,
-				"btc_delegation_created_bbn_block.height": -1,
-				"_id": 1,
+			Name: "staker_btc_pk_hex_1_btc_delegation_created_bbn_block.height_-1__id_1",
+			Keys: bson.D{
+				{Key: "staker_btc_pk_hex", Value: 1},
+				{Key: "btc_delegation_created_bbn_block.height", Value: -1},
+				{Key: "_id", Value: 1},
 			},
-			Unique: false,
 		},
 		{
 			// Index on state field for efficient stats aggregation queries
-			Indexes: map[string]int{
-				"state": 1,
-			},
-			Unique: false,
+			Name: "state_1",
+			Keys: bson.D{{Key: "state", Value: 1}},
+		},
+		{
+			// ACTIVE delegations approaching their end height, used by expiring soon events
+			Name: "state_1_end_height_1",
+			Keys: bson.D{{Key: "state", Value: 1}, {Key: "end_height", Value: 1}},
+		},
+		{
+			Name: "staker_babylon_address_1",
+			Keys: bson.D{{Key: "staker_babylon_address", Value: 1}},
+		},
+		{
+			// multikey index used for querying delegations by finality provider
+			Name: "finality_provider_btc_pks_hex_1",
+			Keys: bson.D{{Key: "finality_provider_btc_pks_hex", Value: 1}},
+		},
+		{
+			// the field is set only for expansion delegations
+			Name:   "previous_staking_tx_hash_hex_1",
+			Keys:   bson.D{{Key: "previous_staking_tx_hash_hex", Value: 1}},
+			Sparse: true,
+		},
+		{
+			// the field is set only for expanded delegations
+			Name:   "next_staking_tx_hash_hex_1",
+			Keys:   bson.D{{Key: "next_staking_tx_hash_hex", Value: 1}},
+			Sparse: true,
 		},
 	},
 	TimeLockCollection: {
-		{Indexes: map[string]int{"expire_height": 1}, Unique: false},
+		{Name: "expire_height_1", Keys: bson.D{{Key: "expire_height", Value: 1}}},
 	},
 	GlobalParamsCollection: {
-		{Indexes: map[string]int{"type": 1, "version": 1}, Unique: true},
+		{Name: "type_1_version_1", Keys: bson.D{{Key: "type", Value: 1}, {Key: "version", Value: 1}}, Unique: true},
+	},
+	LastProcessedHeightCollection: {},
+	NetworkInfoCollection:         {},
+	StatsCollection:               {},
+	UnlockProjectionsCollection:   {},
+	SchemaMigrationsCollection:    {},
+	RejectedTransitionsCollection: {
+		{
+			Name: "staking_tx_hash_hex_1_created_at_1",
+			Keys: bson.D{{Key: "staking_tx_hash_hex", Value: 1}, {Key: "created_at", Value: 1}},
+		},
+	},
+	AnomaliesCollection: {
+		{
+			// the same spend is delivered again after restart, it's recorded once
+			Name: "staking_tx_hash_hex_1_spending_tx_hash_1_type_1",
+			Keys: bson.D{
+				{Key: "staking_tx_hash_hex", Value: 1},
+				{Key: "spending_tx_hash", Value: 1},
+				{Key: "type", Value: 1},
+			},
+			Unique: true,
+		},
+		{
+			Name: "type_1_created_at_-1",
+			Keys: bson.D{{Key: "type", Value: 1}, {Key: "created_at", Value: -1}},
+		},
 	},
-	LastProcessedHeightCollection: {{Indexes: map[string]int{}}},
-	NetworkInfoCollection:         {{Indexes: map[string]int{}}},
-	StatsCollection:               {{Indexes: map[string]int{}}},
 }
 
-func Setup(ctx context.Context, cfg *config.DbConfig) error {
+// CollectionNames returns names of all collections managed by the indexer in alphabetical order
+func CollectionNames() []string {
+	names := make([]string, 0, len(collections))
+	for name := range collections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Connect connects to mongo and returns database from the config
+func Connect(ctx context.Context, cfg *config.DbConfig) (*mongo.Database, error) {
 	credential := options.Credential{
 		Username: cfg.Username,
 		Password: cfg.Password,
 	}
 	clientOps := options.Client().ApplyURI(cfg.Address).SetAuth(credential)
 	client, err := mongo.Connect(ctx, clientOps)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.Database(cfg.DbName), nil
+}
+
+// Setup creates collections and missing indexes. Changed and obsolete indexes are only reported,
+// rebuilding or dropping them is left to "indexes apply" command so startup never removes an index.
+func Setup(ctx context.Context, cfg *config.DbConfig) error {
+	// Access a database and create collections.
+	database, err := Connect(ctx, cfg)
 	if err != nil {
 		return err
 	}
+	defer database.Client().Disconnect(ctx) //nolint:errcheck
 
 	// Create a context with timeout.
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second) //nolint:mnd
+	// It's quite long because building of a new index on existing data may take a while
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute) //nolint:mnd
 	defer cancel()
 
-	// Access a database and create collections.
-	database := client.Database(cfg.DbName)
-
 	// Create collections.
 	for collection := range collections {
 		createCollection(ctx, database, collection)
 	}
 
-	for name, idxs := range collections {
-		for _, idx := range idxs {
-			createIndex(ctx, database, name, idx)
+	changes, err := PlanIndexes(ctx, database)
+	if err != nil {
+		return err
+	}
+
+	var creates []IndexChange
+	for _, change := range changes {
+		if change.Action == IndexActionCreate {
+			creates = append(creates, change)
+			continue
 		}
+		log.Ctx(ctx).Warn().Stringer("change", change).
+			Msg("index differs from definition, run \"indexes apply\" command to reconcile it")
+	}
+
+	// index is required for correct (or efficient) queries, so we don't start if it can't be built
+	if err := ApplyIndexes(ctx, database, creates); err != nil {
+		return err
 	}
 
 	log.Ctx(ctx).Info().Msg("Collections and Indexes created successfully.")
@@ -109,27 +203,3 @@ func createCollection(ctx context.Context, database *mongo.Database, collectionN
 
 	log.Debug().Msg("Collection created successfully: " + collectionName)
 }
-
-func createIndex(ctx context.Context, database *mongo.Database, collectionName string, idx index) {
-	if len(idx.Indexes) == 0 {
-		return
-	}
-	log := log.Ctx(ctx)
-
-	indexKeys := bson.D{}
-	for k, v := range idx.Indexes {
-		indexKeys = append(indexKeys, bson.E{Key: k, Value: v})
-	}
-
-	index := mongo.IndexModel{
-		Keys:    indexKeys,
-		Options: options.Index().SetUnique(idx.Unique),
-	}
-
-	if _, err := database.Collection(collectionName).Indexes().CreateOne(ctx, index); err != nil {
-		log.Debug().Msg(fmt.Sprintf("Failed to create index on collection '%s': %v", collectionName, err))
-		return
-	}
-
-	log.Debug().Msg("Index created successfully on collection: " + collectionName)
-}
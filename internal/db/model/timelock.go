@@ -1,8 +1,12 @@
 package model
 
-import "github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+import (
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
 
 type TimeLockDocument struct {
+	ID                 primitive.ObjectID       `bson:"_id,omitempty"`
 	StakingTxHashHex   string                   `bson:"staking_tx_hash_hex"`
 	ExpireHeight       uint32                   `bson:"expire_height"`
 	DelegationSubState types.DelegationSubState `bson:"delegation_sub_state"`
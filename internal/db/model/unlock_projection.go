@@ -0,0 +1,41 @@
+package model
+
+// OverallUnlockProjectionID is the id of the projection of all delegations, other
+// projections are identified by the finality provider BTC public key (lowercase)
+const OverallUnlockProjectionID = "overall"
+
+// Kinds of upcoming unlocks
+const (
+	// ACTIVE delegations reaching their end height and expired delegations in timelock
+	UnlockKindExpiry = "expiry"
+	// early unbonded delegations reaching the end of the unbonding time
+	UnlockKindUnbonding = "unbonding"
+	// change outputs of slashed delegations reaching the end of their timelock
+	UnlockKindSlashing = "slashing"
+)
+
+// UnlockProjectionDocument is the amount of BTC that becomes withdrawable in the upcoming
+// BTC heights, split into buckets of BucketBlocks blocks starting right after TipHeight
+type UnlockProjectionDocument struct {
+	ID           string         `bson:"_id"`
+	TipHeight    uint32         `bson:"tip_height"`
+	TipTimestamp int64          `bson:"tip_timestamp"`
+	BucketBlocks uint32         `bson:"bucket_blocks"`
+	Buckets      []UnlockBucket `bson:"buckets"`
+	LastUpdated  int64          `bson:"last_updated"` // Unix timestamp of last update
+}
+
+// UnlockBucket holds unlocks of heights StartHeight..EndHeight (inclusive). The first bucket
+// also holds unlocks at heights up to the tip that haven't been processed yet.
+// Times are estimated from the tip block time assuming 10 minute blocks.
+type UnlockBucket struct {
+	StartHeight        uint32 `bson:"start_height"`
+	EndHeight          uint32 `bson:"end_height"`
+	EstimatedStartTime int64  `bson:"estimated_start_time"`
+	EstimatedEndTime   int64  `bson:"estimated_end_time"`
+	Amount             uint64 `bson:"amount"`
+	Delegations        uint64 `bson:"delegations"`
+	ExpiryAmount       uint64 `bson:"expiry_amount"`
+	UnbondingAmount    uint64 `bson:"unbonding_amount"`
+	SlashingAmount     uint64 `bson:"slashing_amount"`
+}
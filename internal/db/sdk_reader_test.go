@@ -0,0 +1,60 @@
+//go:build integration
+
+package db_test
+
+import (
+	"testing"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/pkg/sdk"
+	"github.com/babylonlabs-io/babylon-staking-indexer/pkg/sdk/mongoreader"
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSDKMongoReader checks the sdk reads stored documents as the indexer maps them
+func TestSDKMongoReader(t *testing.T) {
+	ctx := t.Context()
+	t.Cleanup(func() {
+		resetDatabase(t)
+	})
+	reader := mongoreader.New(mongoDB)
+
+	t.Run("delegation", func(t *testing.T) {
+		delegation := createDelegation(t)
+		delegation.StakingAmount = 10000
+		require.NoError(t, testDB.SaveNewBTCDelegation(ctx, delegation))
+		stored, err := testDB.GetBTCDelegationByStakingTxHash(ctx, delegation.StakingTxHashHex)
+		require.NoError(t, err)
+
+		actual, err := reader.Delegation(ctx, delegation.StakingTxHashHex)
+		require.NoError(t, err)
+		assert.Equal(t, stored.ToSDK(), actual)
+
+		require.NotEmpty(t, delegation.FinalityProviderBtcPksHex)
+		page, err := reader.DelegationsByFinalityProvider(ctx, delegation.FinalityProviderBtcPksHex[0], sdk.PageRequest{})
+		require.NoError(t, err)
+		assert.Equal(t, []*sdk.Delegation{stored.ToSDK()}, page.Delegations)
+		assert.Empty(t, page.NextPageToken)
+
+		_, err = reader.Delegation(ctx, randomStakingTxHashHex(t))
+		assert.True(t, sdk.IsNotFound(err))
+	})
+	t.Run("finality provider", func(t *testing.T) {
+		var fp model.FinalityProviderDetails
+		require.NoError(t, gofakeit.Struct(&fp))
+		require.NoError(t, testDB.SaveNewFinalityProvider(ctx, &fp))
+
+		actual, err := reader.FinalityProvider(ctx, fp.BtcPk)
+		require.NoError(t, err)
+		assert.Equal(t, fp.ToSDK(), actual)
+
+		page, err := reader.FinalityProviders(ctx, sdk.PageRequest{})
+		require.NoError(t, err)
+		assert.Equal(t, []*sdk.FinalityProvider{fp.ToSDK()}, page.FinalityProviders)
+
+		_, err = reader.FinalityProvider(ctx, randomBTCpk(t))
+		assert.True(t, sdk.IsNotFound(err))
+	})
+}
@@ -0,0 +1,39 @@
+//go:build integration
+
+package db_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// TestSetupKeepsUndefinedIndexes checks startup doesn't drop indexes, it's left to "indexes apply" command
+func TestSetupKeepsUndefinedIndexes(t *testing.T) {
+	ctx := t.Context()
+	indexes := mongoDB.Collection(model.StatsCollection).Indexes()
+
+	name, err := indexes.CreateOne(ctx, mongo.IndexModel{Keys: bson.D{{Key: "undefined", Value: 1}}})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		// test context is already canceled during cleanup
+		_, err := indexes.DropOne(context.Background(), name)
+		require.NoError(t, err)
+	})
+
+	require.NoError(t, model.Setup(ctx, testDBConfig))
+
+	changes, err := model.PlanIndexes(ctx, mongoDB)
+	require.NoError(t, err)
+	assert.Equal(t, []model.IndexChange{{
+		Collection: model.StatsCollection,
+		Action:     model.IndexActionDrop,
+		Name:       name,
+		Reason:     "not defined",
+	}}, changes)
+}
@@ -13,13 +13,7 @@ func (db *Database) CalculateActiveStatsAggregated(ctx context.Context) (uint64,
 	collection := db.collection(model.BTCDelegationDetailsCollection)
 
 	// Pipeline to calculate overall stats
-	overallPipeline := bson.A{
-		// Match only ACTIVE delegations
-		bson.M{
-			"$match": bson.M{
-				"state": "ACTIVE",
-			},
-		},
+	overallPipeline := append(activeStatsStages(),
 		// Group to calculate totals
 		bson.M{
 			"$group": bson.M{
@@ -28,7 +22,7 @@ func (db *Database) CalculateActiveStatsAggregated(ctx context.Context) (uint64,
 				"total_delegations": bson.M{"$sum": 1},
 			},
 		},
-	}
+	)
 
 	// Execute overall stats aggregation
 	cursor, err := collection.Aggregate(ctx, overallPipeline)
@@ -58,13 +52,7 @@ func (db *Database) CalculateActiveStatsAggregated(ctx context.Context) (uint64,
 	}
 
 	// Pipeline to calculate per-FP stats
-	fpPipeline := bson.A{
-		// Match only ACTIVE delegations
-		bson.M{
-			"$match": bson.M{
-				"state": "ACTIVE",
-			},
-		},
+	fpPipeline := append(activeStatsStages(),
 		// Unwind the finality_provider_btc_pks_hex array to process each FP separately
 		bson.M{
 			"$unwind": "$finality_provider_btc_pks_hex",
@@ -83,7 +71,7 @@ func (db *Database) CalculateActiveStatsAggregated(ctx context.Context) (uint64,
 				"active_delegations": bson.M{"$sum": 1},
 			},
 		},
-	}
+	)
 
 	// Execute FP stats aggregation
 	fpCursor, err := collection.Aggregate(ctx, fpPipeline)
@@ -113,3 +101,36 @@ func (db *Database) CalculateActiveStatsAggregated(ctx context.Context) (uint64,
 
 	return overallTvl, overallDelegations, fpStats, nil
 }
+
+// activeStatsStages matches ACTIVE delegations counted in the stats. The staking output of
+// an expanded delegation is spent by its expansion, so the expansion's amount already covers
+// it. Until the expanded delegation leaves ACTIVE state both might be ACTIVE, during that
+// window only the expansion is counted.
+func activeStatsStages() bson.A {
+	return bson.A{
+		// Match only ACTIVE delegations
+		bson.M{
+			"$match": bson.M{
+				"state": "ACTIVE",
+			},
+		},
+		// Find ACTIVE expansions of the delegation through the previous_staking_tx_hash_hex index
+		bson.M{
+			"$lookup": bson.M{
+				"from":         model.BTCDelegationDetailsCollection,
+				"localField":   "_id",
+				"foreignField": "previous_staking_tx_hash_hex",
+				"pipeline": bson.A{
+					bson.M{"$match": bson.M{"state": "ACTIVE"}},
+					bson.M{"$project": bson.M{"_id": 1}},
+				},
+				"as": "active_expansions",
+			},
+		},
+		bson.M{
+			"$match": bson.M{
+				"active_expansions": bson.M{"$size": 0},
+			},
+		},
+	}
+}
@@ -257,4 +257,79 @@ func TestStats(t *testing.T) {
 		assert.Equal(t, uint64(300000), fpStats[0].ActiveTvl)
 		assert.Equal(t, uint64(2), fpStats[0].ActiveDelegations)
 	})
+
+	t.Run("CalculateActiveStatsAggregated - expansion overlap", func(t *testing.T) {
+		resetDatabase(t)
+
+		fpPk := randomBTCpk(t)
+		expanded := createDelegation(t)
+		expanded.State = types.StateActive
+		expanded.StakingAmount = 100000
+		expanded.FinalityProviderBtcPksHex = []string{fpPk}
+		expanded.PreviousStakingTxHashHex = ""
+		err := testDB.SaveNewBTCDelegation(ctx, expanded)
+		require.NoError(t, err)
+
+		// expansion spends the expanded staking output, so its amount includes it
+		expansion := createDelegation(t)
+		expansion.State = types.StateVerified
+		expansion.StakingAmount = 150000
+		expansion.FinalityProviderBtcPksHex = []string{fpPk}
+		expansion.PreviousStakingTxHashHex = expanded.StakingTxHashHex
+		// inclusion proof isn't received yet
+		expansion.StartHeight = 0
+		err = testDB.SaveNewBTCDelegation(ctx, expansion)
+		require.NoError(t, err)
+
+		// expansion that is not active yet doesn't replace the expanded delegation
+		tvl, delegations, fpStats, err := testDB.CalculateActiveStatsAggregated(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(100000), tvl)
+		assert.Equal(t, uint64(1), delegations)
+		require.Len(t, fpStats, 1)
+		assert.Equal(t, uint64(100000), fpStats[0].ActiveTvl)
+
+		// both are active until the expanded delegation is moved to EXPANDED
+		err = testDB.UpdateBTCDelegationState(
+			ctx, expansion.StakingTxHashHex, types.TriggerInclusionProofReceived, types.StateActive,
+		)
+		require.NoError(t, err)
+
+		tvl, delegations, fpStats, err = testDB.CalculateActiveStatsAggregated(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(150000), tvl)
+		assert.Equal(t, uint64(1), delegations)
+		require.Len(t, fpStats, 1)
+		assert.Equal(t, uint64(150000), fpStats[0].ActiveTvl)
+		assert.Equal(t, uint64(1), fpStats[0].ActiveDelegations)
+	})
+
+	t.Run("CalculateActiveStatsAggregated - unknown spend", func(t *testing.T) {
+		resetDatabase(t)
+
+		fpPk := randomBTCpk(t)
+		for _, amount := range []uint64{100000, 200000} {
+			delegation := createDelegation(t)
+			delegation.State = types.StateActive
+			delegation.StakingAmount = amount
+			delegation.FinalityProviderBtcPksHex = []string{fpPk}
+			delegation.PreviousStakingTxHashHex = ""
+			require.NoError(t, testDB.SaveNewBTCDelegation(ctx, delegation))
+
+			if amount == 200000 {
+				err := testDB.UpdateBTCDelegationState(
+					ctx, delegation.StakingTxHashHex, types.TriggerUnknownSpend, types.StateUnknownSpend,
+				)
+				require.NoError(t, err)
+			}
+		}
+
+		// staking output spent by unknown tx is not staked anymore
+		tvl, delegations, fpStats, err := testDB.CalculateActiveStatsAggregated(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(100000), tvl)
+		assert.Equal(t, uint64(1), delegations)
+		require.Len(t, fpStats, 1)
+		assert.Equal(t, uint64(100000), fpStats[0].ActiveTvl)
+	})
 }
@@ -7,7 +7,7 @@ import (
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
 	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 func (db *Database) SaveNewTimeLockExpire(
@@ -21,13 +21,36 @@ func (db *Database) SaveNewTimeLockExpire(
 	return err
 }
 
+// FindExpiredDelegations returns timelock documents expired at btcHeight ordered by id.
+// Only documents with id greater than afterID are returned, so the caller can page through
+// them even if some of the documents stay in the collection. Timelocks of delegations with
+// unconfirmed provisional spends are skipped until the spends are confirmed or reverted,
+// as the reorg of a spend can't be reverted once the delegation has expired.
 // todo change type from uint64 to something else cause bson.M{"$lte": math.MaxUint64} fails during marshaling
-func (db *Database) FindExpiredDelegations(ctx context.Context, btcTipHeight, limit uint64) ([]model.TimeLockDocument, error) {
+func (db *Database) FindExpiredDelegations(
+	ctx context.Context, btcHeight uint64, afterID primitive.ObjectID, limit uint64,
+) ([]model.TimeLockDocument, error) {
 	client := db.collection(model.TimeLockCollection)
-	filter := bson.M{"expire_height": bson.M{"$lte": btcTipHeight}}
+	filter := bson.M{
+		"expire_height": bson.M{"$lte": btcHeight},
+		"_id":           bson.M{"$gt": afterID},
+	}
 
-	opts := options.Find().SetLimit(int64(limit))
-	cursor, err := client.Find(ctx, filter, opts)
+	pipeline := bson.A{
+		bson.M{"$match": filter},
+		bson.M{"$sort": bson.M{"_id": 1}},
+		bson.M{"$lookup": bson.M{
+			"from":         model.BTCDelegationDetailsCollection,
+			"localField":   "staking_tx_hash_hex",
+			"foreignField": "_id",
+			"pipeline":     bson.A{bson.M{"$project": bson.M{"provisional_spends": 1}}},
+			"as":           "delegation",
+		}},
+		bson.M{"$match": bson.M{"delegation.provisional_spends.0": bson.M{"$exists": false}}},
+		bson.M{"$limit": int64(limit)},
+		bson.M{"$project": bson.M{"delegation": 0}},
+	}
+	cursor, err := client.Aggregate(ctx, pipeline)
 	if err != nil {
 		return nil, err
 	}
@@ -41,6 +64,26 @@ func (db *Database) FindExpiredDelegations(ctx context.Context, btcTipHeight, li
 	return delegations, nil
 }
 
+// CountExpiredDelegations returns number of timelock documents expired at btcHeight
+func (db *Database) CountExpiredDelegations(ctx context.Context, btcHeight uint64) (int64, error) {
+	filter := bson.M{"expire_height": bson.M{"$lte": btcHeight}}
+	return db.collection(model.TimeLockCollection).CountDocuments(ctx, filter)
+}
+
+// DeleteTimeLockExpires deletes timelock documents by ids
+func (db *Database) DeleteTimeLockExpires(ctx context.Context, ids []primitive.ObjectID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	filter := bson.M{"_id": bson.M{"$in": ids}}
+	if _, err := db.collection(model.TimeLockCollection).DeleteMany(ctx, filter); err != nil {
+		return fmt.Errorf("failed to delete timelock expires: %w", err)
+	}
+
+	return nil
+}
+
 func (db *Database) DeleteExpiredDelegation(ctx context.Context, stakingTxHashHex string) error {
 	client := db.collection(model.TimeLockCollection)
 	filter := bson.M{"staking_tx_hash_hex": stakingTxHashHex}
@@ -57,3 +100,23 @@ func (db *Database) DeleteExpiredDelegation(ctx context.Context, stakingTxHashHe
 
 	return nil
 }
+
+// DeleteTimeLockExpire deletes timelock expire of the delegation with the given sub state.
+// It's not an error if there is no such timelock expire (e.g. it has been processed already)
+func (db *Database) DeleteTimeLockExpire(
+	ctx context.Context,
+	stakingTxHashHex string,
+	subState types.DelegationSubState,
+) error {
+	filter := bson.M{
+		"staking_tx_hash_hex":  stakingTxHashHex,
+		"delegation_sub_state": subState,
+	}
+
+	_, err := db.collection(model.TimeLockCollection).DeleteMany(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("failed to delete timelock expire with stakingTxHashHex %v: %w", stakingTxHashHex, err)
+	}
+
+	return nil
+}
@@ -11,12 +11,13 @@ import (
 	"github.com/babylonlabs-io/babylon-staking-indexer/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 func TestTimeLock(t *testing.T) {
 	ctx := t.Context()
 	t.Run("no documents", func(t *testing.T) {
-		docs, err := testDB.FindExpiredDelegations(ctx, math.MaxInt64, 10)
+		docs, err := testDB.FindExpiredDelegations(ctx, math.MaxInt64, primitive.NilObjectID, 10)
 		require.NoError(t, err)
 		assert.Nil(t, docs)
 	})
@@ -51,11 +52,59 @@ func TestTimeLock(t *testing.T) {
 		// double check that expiredDelegation1 ExpireHeight field is less than chosen btcTipHeight
 		require.Less(t, expiredDelegation1.ExpireHeight, btcTipHeight)
 
-		docs, err := testDB.FindExpiredDelegations(ctx, uint64(btcTipHeight), 10)
+		docs, err := testDB.FindExpiredDelegations(ctx, uint64(btcTipHeight), primitive.NilObjectID, 10)
 		require.NoError(t, err)
+		require.Len(t, docs, 2)
+		// ids are assigned by the database
+		for i := range docs {
+			assert.False(t, docs[i].ID.IsZero())
+			docs[i].ID = primitive.NilObjectID
+		}
 
 		expectedDocs := []model.TimeLockDocument{expiredDelegation1, expiredDelegation2}
 		assert.Equal(t, expectedDocs, docs)
+
+		count, err := testDB.CountExpiredDelegations(ctx, uint64(btcTipHeight))
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), count)
+	})
+	t.Run("pages", func(t *testing.T) {
+		defer resetDatabase(t)
+
+		for range 5 {
+			err := testDB.SaveNewTimeLockExpire(ctx, randomStakingTxHashHex(t), 1, types.SubStateTimelock)
+			require.NoError(t, err)
+		}
+
+		var (
+			afterID primitive.ObjectID
+			found   []primitive.ObjectID
+		)
+		for {
+			docs, err := testDB.FindExpiredDelegations(ctx, 1, afterID, 2)
+			require.NoError(t, err)
+			if len(docs) == 0 {
+				break
+			}
+			for _, doc := range docs {
+				found = append(found, doc.ID)
+			}
+			afterID = docs[len(docs)-1].ID
+		}
+		require.Len(t, found, 5)
+
+		// processed documents are deleted, the rest are found again on the next run
+		err := testDB.DeleteTimeLockExpires(ctx, found[:3])
+		require.NoError(t, err)
+
+		docs, err := testDB.FindExpiredDelegations(ctx, 1, primitive.NilObjectID, 10)
+		require.NoError(t, err)
+		require.Len(t, docs, 2)
+		assert.Equal(t, found[3], docs[0].ID)
+		assert.Equal(t, found[4], docs[1].ID)
+
+		// nothing to delete is not an error
+		require.NoError(t, testDB.DeleteTimeLockExpires(ctx, nil))
 	})
 	t.Run("delete", func(t *testing.T) {
 		// first check deletion of non existing delegation
@@ -74,7 +123,7 @@ func TestTimeLock(t *testing.T) {
 		err = testDB.DeleteExpiredDelegation(ctx, doc.StakingTxHashHex)
 		require.NoError(t, err)
 
-		docs, err := testDB.FindExpiredDelegations(ctx, uint64(doc.ExpireHeight+1), 1)
+		docs, err := testDB.FindExpiredDelegations(ctx, uint64(doc.ExpireHeight+1), primitive.NilObjectID, 1)
 		require.NoError(t, err)
 		require.Empty(t, docs)
 	})
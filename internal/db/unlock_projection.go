@@ -0,0 +1,201 @@
+package db
+
+import (
+	"context"
+	"errors"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CalculateUnlockBuckets aggregates unlocks at heights up to fromHeight + bucketBlocks*buckets - 1
+// into buckets of bucketBlocks heights starting at fromHeight. Unlocks are taken from timelock
+// entries of UNBONDING and SLASHED delegations and from end heights of ACTIVE delegations,
+// unlocks below fromHeight fall into the first bucket. Amounts are staking amounts except for
+// slashing, which unlocks the change output (staking amount is used if the slashing tx details
+// are not stored).
+func (db *Database) CalculateUnlockBuckets(
+	ctx context.Context, fromHeight, bucketBlocks, buckets uint32,
+) ([]UnlockBucketResult, error) {
+	toHeight := int64(fromHeight) + int64(bucketBlocks)*int64(buckets) - 1
+
+	activeDelegations := bson.A{
+		bson.M{"$match": bson.M{
+			"state":      types.StateActive,
+			"end_height": bson.M{"$gt": 0, "$lte": toHeight},
+		}},
+		bson.M{"$project": bson.M{
+			"_id":    0,
+			"height": "$end_height",
+			"fps":    "$finality_provider_btc_pks_hex",
+			"kind":   bson.M{"$literal": model.UnlockKindExpiry},
+			"amount": "$staking_amount",
+		}},
+	}
+
+	pipeline := bson.A{
+		bson.M{"$match": bson.M{"expire_height": bson.M{"$lte": toHeight}}},
+		bson.M{"$lookup": bson.M{
+			"from":         model.BTCDelegationDetailsCollection,
+			"localField":   "staking_tx_hash_hex",
+			"foreignField": "_id",
+			"as":           "delegation",
+		}},
+		bson.M{"$unwind": "$delegation"},
+		// entries left behind by delegations that have left the timelock state (e.g. unknown spend)
+		// don't unlock anything
+		bson.M{"$match": bson.M{
+			"delegation.state": bson.M{"$in": bson.A{types.StateUnbonding, types.StateSlashed}},
+			"$expr":            bson.M{"$eq": bson.A{"$delegation.sub_state", "$delegation_sub_state"}},
+		}},
+		bson.M{"$project": bson.M{
+			"_id":    0,
+			"height": "$expire_height",
+			"fps":    "$delegation.finality_provider_btc_pks_hex",
+			"kind": bson.M{"$switch": bson.M{
+				"branches": bson.A{
+					subStateBranch(types.SubStateTimelock, model.UnlockKindExpiry),
+					subStateBranch(types.SubStateEarlyUnbonding, model.UnlockKindUnbonding),
+				},
+				"default": model.UnlockKindSlashing,
+			}},
+			"amount": bson.M{"$switch": bson.M{
+				"branches": bson.A{
+					subStateBranch(types.SubStateTimelockSlashing, bson.M{"$ifNull": bson.A{
+						"$delegation.slashing_tx.slashing_tx_details.change_amount", "$delegation.staking_amount",
+					}}),
+					subStateBranch(types.SubStateEarlyUnbondingSlashing, bson.M{"$ifNull": bson.A{
+						"$delegation.slashing_tx.unbonding_slashing_tx_details.change_amount", "$delegation.staking_amount",
+					}}),
+				},
+				"default": "$delegation.staking_amount",
+			}},
+		}},
+		bson.M{"$unionWith": bson.M{
+			"coll":     model.BTCDelegationDetailsCollection,
+			"pipeline": activeDelegations,
+		}},
+		bson.M{"$addFields": bson.M{
+			"bucket": bson.M{"$toInt": bson.M{"$floor": bson.M{"$divide": bson.A{
+				bson.M{"$max": bson.A{bson.M{"$subtract": bson.A{"$height", fromHeight}}, 0}},
+				bucketBlocks,
+			}}}},
+		}},
+		bson.M{"$facet": bson.M{
+			"overall": bson.A{
+				bson.M{"$group": bson.M{
+					"_id":         bson.M{"bucket": "$bucket", "kind": "$kind"},
+					"amount":      bson.M{"$sum": "$amount"},
+					"delegations": bson.M{"$sum": 1},
+				}},
+			},
+			"finality_providers": bson.A{
+				bson.M{"$unwind": "$fps"},
+				bson.M{"$group": bson.M{
+					"_id": bson.M{
+						"fp":     bson.M{"$toLower": "$fps"},
+						"bucket": "$bucket",
+						"kind":   "$kind",
+					},
+					"amount":      bson.M{"$sum": "$amount"},
+					"delegations": bson.M{"$sum": 1},
+				}},
+			},
+		}},
+	}
+
+	cursor, err := db.collection(model.TimeLockCollection).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	type group struct {
+		ID struct {
+			Fp     string `bson:"fp"`
+			Bucket uint32 `bson:"bucket"`
+			Kind   string `bson:"kind"`
+		} `bson:"_id"`
+		Amount      uint64 `bson:"amount"`
+		Delegations uint64 `bson:"delegations"`
+	}
+	var facets []struct {
+		Overall           []group `bson:"overall"`
+		FinalityProviders []group `bson:"finality_providers"`
+	}
+	if err := cursor.All(ctx, &facets); err != nil {
+		return nil, err
+	}
+
+	var results []UnlockBucketResult
+	for _, facet := range facets {
+		for _, g := range append(facet.Overall, facet.FinalityProviders...) {
+			results = append(results, UnlockBucketResult{
+				FpBtcPkHex:  g.ID.Fp,
+				Bucket:      g.ID.Bucket,
+				Kind:        g.ID.Kind,
+				Amount:      g.Amount,
+				Delegations: g.Delegations,
+			})
+		}
+	}
+
+	return results, nil
+}
+
+func subStateBranch(subState types.DelegationSubState, then any) bson.M {
+	return bson.M{
+		"case": bson.M{"$eq": bson.A{"$delegation_sub_state", subState}},
+		"then": then,
+	}
+}
+
+// ReplaceUnlockProjections stores the projections and removes projections that are not among them,
+// e.g. of finality providers that have no upcoming unlocks anymore
+func (db *Database) ReplaceUnlockProjections(
+	ctx context.Context, projections []*model.UnlockProjectionDocument,
+) error {
+	collection := db.collection(model.UnlockProjectionsCollection)
+
+	ids := make([]string, len(projections))
+	writes := make([]mongo.WriteModel, len(projections))
+	for i, projection := range projections {
+		ids[i] = projection.ID
+		writes[i] = mongo.NewReplaceOneModel().
+			SetFilter(bson.M{"_id": projection.ID}).
+			SetReplacement(projection).
+			SetUpsert(true)
+	}
+
+	if len(writes) > 0 {
+		if _, err := collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false)); err != nil {
+			return err
+		}
+	}
+	_, err := collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$nin": ids}})
+	return err
+}
+
+// GetUnlockProjection returns the overall projection or the projection of the finality provider
+func (db *Database) GetUnlockProjection(
+	ctx context.Context, id string,
+) (*model.UnlockProjectionDocument, error) {
+	var projection model.UnlockProjectionDocument
+	err := db.collection(model.UnlockProjectionsCollection).
+		FindOne(ctx, bson.M{"_id": id}).
+		Decode(&projection)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, &NotFoundError{
+				Key:     id,
+				Message: "unlock projection not found",
+			}
+		}
+		return nil, err
+	}
+
+	return &projection, nil
+}
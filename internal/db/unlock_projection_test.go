@@ -0,0 +1,100 @@
+//go:build integration
+
+package db_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnlockProjections(t *testing.T) {
+	ctx := t.Context()
+	t.Cleanup(func() {
+		resetDatabase(t)
+	})
+
+	t.Run("calculate buckets", func(t *testing.T) {
+		resetDatabase(t)
+
+		fpPk := randomBTCpk(t)
+		save := func(
+			state types.DelegationState, subState types.DelegationSubState, amount uint64, endHeight uint32,
+			modify ...func(*model.BTCDelegationDetails),
+		) *model.BTCDelegationDetails {
+			delegation := createDelegation(t)
+			for _, f := range modify {
+				f(delegation)
+			}
+			delegation.State = state
+			delegation.SubState = subState
+			delegation.StakingAmount = amount
+			delegation.EndHeight = endHeight
+			delegation.FinalityProviderBtcPksHex = []string{fpPk}
+			require.NoError(t, testDB.SaveNewBTCDelegation(ctx, delegation))
+			return delegation
+		}
+
+		// active delegations expire at their end height
+		save(types.StateActive, "", 1000, 105)
+		save(types.StateActive, "", 2000, 115)
+		// beyond the last bucket
+		save(types.StateActive, "", 4000, 200)
+		// not an unlock
+		save(types.StatePending, "", 8000, 105)
+
+		unbonding := save(types.StateUnbonding, types.SubStateEarlyUnbonding, 10000, 500)
+		require.NoError(t, testDB.SaveNewTimeLockExpire(ctx, unbonding.StakingTxHashHex, 95, unbonding.SubState))
+
+		// slashing unlocks the change output
+		slashed := save(types.StateSlashed, types.SubStateTimelockSlashing, 20000, 500, func(d *model.BTCDelegationDetails) {
+			d.SlashingTx.SlashingTxDetails = &model.SlashingTxDetails{ChangeAmount: 18000}
+		})
+		require.NoError(t, testDB.SaveNewTimeLockExpire(ctx, slashed.StakingTxHashHex, 112, slashed.SubState))
+
+		// timelock entries of delegations that aren't locked anymore
+		withdrawn := save(types.StateWithdrawn, types.SubStateEarlyUnbonding, 40000, 500)
+		require.NoError(t, testDB.SaveNewTimeLockExpire(ctx, withdrawn.StakingTxHashHex, 96, withdrawn.SubState))
+		unknownSpend := save(types.StateUnknownSpend, "", 80000, 500)
+		require.NoError(t, testDB.SaveNewTimeLockExpire(ctx, unknownSpend.StakingTxHashHex, 97, types.SubStateEarlyUnbonding))
+
+		results, err := testDB.CalculateUnlockBuckets(ctx, 100, 10, 2)
+		require.NoError(t, err)
+
+		fp := strings.ToLower(fpPk)
+		assert.ElementsMatch(t, []db.UnlockBucketResult{
+			{Bucket: 0, Kind: model.UnlockKindExpiry, Amount: 1000, Delegations: 1},
+			{Bucket: 0, Kind: model.UnlockKindUnbonding, Amount: 10000, Delegations: 1},
+			{Bucket: 1, Kind: model.UnlockKindExpiry, Amount: 2000, Delegations: 1},
+			{Bucket: 1, Kind: model.UnlockKindSlashing, Amount: 18000, Delegations: 1},
+			{FpBtcPkHex: fp, Bucket: 0, Kind: model.UnlockKindExpiry, Amount: 1000, Delegations: 1},
+			{FpBtcPkHex: fp, Bucket: 0, Kind: model.UnlockKindUnbonding, Amount: 10000, Delegations: 1},
+			{FpBtcPkHex: fp, Bucket: 1, Kind: model.UnlockKindExpiry, Amount: 2000, Delegations: 1},
+			{FpBtcPkHex: fp, Bucket: 1, Kind: model.UnlockKindSlashing, Amount: 18000, Delegations: 1},
+		}, results)
+	})
+	t.Run("replace projections", func(t *testing.T) {
+		resetDatabase(t)
+
+		overall := &model.UnlockProjectionDocument{ID: model.OverallUnlockProjectionID, TipHeight: 100}
+		fp := &model.UnlockProjectionDocument{ID: "fp", TipHeight: 100}
+		require.NoError(t, testDB.ReplaceUnlockProjections(ctx, []*model.UnlockProjectionDocument{overall, fp}))
+
+		overall.TipHeight = 101
+		overall.Buckets = []model.UnlockBucket{{StartHeight: 102, EndHeight: 111, Amount: 1000}}
+		require.NoError(t, testDB.ReplaceUnlockProjections(ctx, []*model.UnlockProjectionDocument{overall}))
+
+		found, err := testDB.GetUnlockProjection(ctx, model.OverallUnlockProjectionID)
+		require.NoError(t, err)
+		assert.Equal(t, overall, found)
+
+		// finality provider has no unlocks anymore
+		_, err = testDB.GetUnlockProjection(ctx, "fp")
+		assert.True(t, db.IsNotFoundError(err))
+	})
+}
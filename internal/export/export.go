@@ -0,0 +1,163 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	defaultBatchSize = 1000
+	// how often progress is logged
+	logEveryDocs = 100_000
+)
+
+type Options struct {
+	Datasets []string
+	Format   Format
+	// Fields to export, empty means all fields. Can be used only with a single dataset
+	Fields    []string
+	Filter    Filter
+	OutputDir string
+	// Snapshot makes all datasets read from the same point in time.
+	// Note that mongo keeps snapshot history only for minSnapshotHistoryWindowInSeconds (5 minutes by default)
+	Snapshot  bool
+	BatchSize int32
+}
+
+// Export streams every requested dataset into <OutputDir>/<dataset>.<format> file.
+// Documents are read with a cursor ordered by _id, so memory usage doesn't depend on collection size.
+func Export(ctx context.Context, database *mongo.Database, opts Options) error {
+	if len(opts.Datasets) == 0 {
+		return errors.New("at least one dataset is required")
+	}
+	if len(opts.Fields) > 0 && len(opts.Datasets) > 1 {
+		return errors.New("fields can be selected only when exporting a single dataset")
+	}
+
+	// validate everything before reading any data
+	jobs := make([]job, 0, len(opts.Datasets))
+	for _, name := range opts.Datasets {
+		dataset, err := GetDataset(name)
+		if err != nil {
+			return err
+		}
+		fields, err := dataset.SelectFields(opts.Fields)
+		if err != nil {
+			return err
+		}
+		filter, err := opts.Filter.toBSON(dataset)
+		if err != nil {
+			return err
+		}
+
+		jobs = append(jobs, job{
+			dataset: dataset,
+			fields:  fields,
+			filter:  filter,
+			path:    filepath.Join(opts.OutputDir, fmt.Sprintf("%s.%s", dataset.Name, opts.Format)),
+		})
+	}
+
+	run := func(ctx context.Context) error {
+		for _, j := range jobs {
+			if err := j.run(ctx, database, opts); err != nil {
+				return fmt.Errorf("failed to export %s: %w", j.dataset.Name, err)
+			}
+		}
+		return nil
+	}
+
+	if !opts.Snapshot {
+		return run(ctx)
+	}
+
+	session, err := database.Client().StartSession(options.Session().SetSnapshot(true))
+	if err != nil {
+		return fmt.Errorf("failed to start snapshot session: %w", err)
+	}
+	defer session.EndSession(ctx)
+
+	return mongo.WithSession(ctx, session, func(sc mongo.SessionContext) error {
+		return run(sc)
+	})
+}
+
+type job struct {
+	dataset Dataset
+	fields  []Field
+	filter  bson.M
+	path    string
+}
+
+func (j job) run(ctx context.Context, database *mongo.Database, opts Options) (err error) {
+	file, err := os.Create(j.path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	writer, err := newRowWriter(opts.Format, file, j.fields)
+	if err != nil {
+		return err
+	}
+
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+	findOpts := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetBatchSize(batchSize)
+
+	cursor, err := database.Collection(j.dataset.Collection).Find(ctx, j.filter, findOpts)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	log := log.Ctx(ctx)
+	values := make([]any, len(j.fields))
+	var count int
+	for cursor.Next(ctx) {
+		for i, f := range j.fields {
+			values[i], err = f.value(cursor.Current)
+			if err != nil {
+				return fmt.Errorf("document %s: %w", cursor.Current.Lookup("_id"), err)
+			}
+		}
+		if err := writer.WriteRow(values); err != nil {
+			return err
+		}
+
+		count++
+		if count%logEveryDocs == 0 {
+			log.Info().Str("dataset", j.dataset.Name).Int("count", count).Msg("export in progress")
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	log.Info().
+		Str("dataset", j.dataset.Name).
+		Str("path", j.path).
+		Int("count", count).
+		Msg("dataset exported")
+	return nil
+}
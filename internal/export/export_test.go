@@ -0,0 +1,167 @@
+package export
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestDatasetFields(t *testing.T) {
+	dataset, err := GetDataset(DatasetDelegations)
+	require.NoError(t, err)
+
+	kinds := make(map[string]Kind)
+	for _, f := range dataset.Fields {
+		kinds[f.Path] = f.Kind
+	}
+	assert.Equal(t, KindString, kinds["_id"])
+	assert.Equal(t, KindString, kinds["state"])
+	assert.Equal(t, KindInt, kinds["staking_amount"])
+	assert.Equal(t, KindInt, kinds["btc_delegation_created_bbn_block.height"])
+	assert.Equal(t, KindJSON, kinds["finality_provider_btc_pks_hex"])
+	assert.Equal(t, KindJSON, kinds["state_history"])
+
+	fields, err := dataset.SelectFields([]string{"state", "_id"})
+	require.NoError(t, err)
+	assert.Equal(t, []Field{{Path: "state", Kind: KindString}, {Path: "_id", Kind: KindString}}, fields)
+
+	_, err = dataset.SelectFields([]string{"unknown"})
+	assert.Error(t, err)
+
+	_, err = GetDataset("unknown")
+	assert.Error(t, err)
+}
+
+func TestFieldValue(t *testing.T) {
+	doc, err := bson.Marshal(bson.D{
+		{Key: "s", Value: "value"},
+		{Key: "n", Value: int32(5)},
+		{Key: "b", Value: true},
+		{Key: "nested", Value: bson.D{{Key: "n", Value: int64(7)}}},
+		{Key: "arr", Value: bson.A{"a", "b"}},
+		{Key: "null", Value: nil},
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		field    Field
+		expected any
+	}{
+		{Field{Path: "s", Kind: KindString}, "value"},
+		{Field{Path: "n", Kind: KindInt}, int64(5)},
+		{Field{Path: "b", Kind: KindBool}, true},
+		{Field{Path: "nested.n", Kind: KindInt}, int64(7)},
+		{Field{Path: "arr", Kind: KindJSON}, []byte(`["a","b"]`)},
+		{Field{Path: "null", Kind: KindString}, nil},
+		{Field{Path: "missing", Kind: KindString}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.field.Path, func(t *testing.T) {
+			value, err := tt.field.value(doc)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, value)
+		})
+	}
+
+	_, err = Field{Path: "s", Kind: KindInt}.value(doc)
+	assert.Error(t, err)
+}
+
+func TestFilter(t *testing.T) {
+	delegations, err := GetDataset(DatasetDelegations)
+	require.NoError(t, err)
+	stats, err := GetDataset(DatasetStats)
+	require.NoError(t, err)
+
+	version := uint32(2)
+	filter := Filter{
+		States:                   []string{"ACTIVE"},
+		FinalityProviderBtcPkHex: "fp",
+		ParamsVersion:            &version,
+		FromHeight:               10,
+		ToHeight:                 20,
+	}
+	actual, err := filter.toBSON(delegations)
+	require.NoError(t, err)
+	assert.Equal(t, bson.M{
+		"state":                         bson.M{"$in": []string{"ACTIVE"}},
+		"finality_provider_btc_pks_hex": "fp",
+		"params_version":                uint32(2),
+		"btc_delegation_created_bbn_block.height": bson.M{"$gte": uint64(10), "$lte": uint64(20)},
+	}, actual)
+
+	_, err = filter.toBSON(stats)
+	assert.ErrorContains(t, err, "doesn't support")
+
+	_, err = Filter{FromHeight: 20, ToHeight: 10}.toBSON(delegations)
+	assert.Error(t, err)
+}
+
+func TestRowWriters(t *testing.T) {
+	fields := []Field{
+		{Path: "_id", Kind: KindString},
+		{Path: "amount", Kind: KindInt},
+		{Path: "flag", Kind: KindBool},
+		{Path: "list", Kind: KindJSON},
+	}
+	rows := [][]any{
+		{"a", int64(1), true, []byte(`["x"]`)},
+		{"b", nil, false, nil},
+	}
+
+	write := func(t *testing.T, format Format) []byte {
+		var buf bytes.Buffer
+		w, err := newRowWriter(format, &buf, fields)
+		require.NoError(t, err)
+		for _, row := range rows {
+			require.NoError(t, w.WriteRow(row))
+		}
+		require.NoError(t, w.Close())
+		return buf.Bytes()
+	}
+
+	t.Run("csv", func(t *testing.T) {
+		expected := "_id,amount,flag,list\n" +
+			"a,1,true,\"[\"\"x\"\"]\"\n" +
+			"b,,false,\n"
+		assert.Equal(t, expected, string(write(t, FormatCSV)))
+	})
+	t.Run("ndjson", func(t *testing.T) {
+		expected := `{"_id":"a","amount":1,"flag":true,"list":["x"]}` + "\n" +
+			`{"_id":"b","amount":null,"flag":false,"list":null}` + "\n"
+		assert.Equal(t, expected, string(write(t, FormatNDJSON)))
+	})
+	t.Run("parquet", func(t *testing.T) {
+		data := write(t, FormatParquet)
+
+		file, err := parquet.OpenFile(bytes.NewReader(data), int64(len(data)))
+		require.NoError(t, err)
+		assert.EqualValues(t, len(rows), file.NumRows())
+
+		type parquetRow struct {
+			ID     *string `parquet:"_id,optional"`
+			Amount *int64  `parquet:"amount,optional"`
+			Flag   *bool   `parquet:"flag,optional"`
+			List   *string `parquet:"list,optional"`
+		}
+		reader := parquet.NewGenericReader[parquetRow](file)
+		defer reader.Close()
+
+		actual := make([]parquetRow, len(rows))
+		n, _ := reader.Read(actual)
+		require.Equal(t, len(rows), n)
+
+		assert.Equal(t, "a", *actual[0].ID)
+		assert.EqualValues(t, 1, *actual[0].Amount)
+		assert.True(t, *actual[0].Flag)
+		assert.Equal(t, `["x"]`, *actual[0].List)
+		assert.Equal(t, "b", *actual[1].ID)
+		assert.Nil(t, actual[1].Amount)
+		assert.False(t, *actual[1].Flag)
+		assert.Nil(t, actual[1].List)
+	})
+}
@@ -0,0 +1,204 @@
+package export
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+)
+
+type Kind int
+
+const (
+	KindString Kind = iota
+	KindInt
+	KindBool
+	// KindJSON is used for arrays and other values that can't be flattened into a single column
+	KindJSON
+)
+
+// Field is a single exported column, nested documents are flattened using dot notation
+type Field struct {
+	Path string
+	Kind Kind
+}
+
+// Dataset describes exportable collection
+type Dataset struct {
+	Name       string
+	Collection string
+	Fields     []Field
+	// filters maps supported filter to the document field it's applied to
+	filters map[filterKey]string
+}
+
+type filterKey int
+
+const (
+	filterState filterKey = iota
+	filterFinalityProvider
+	filterParamsVersion
+	filterHeight
+)
+
+const (
+	DatasetDelegations           = "delegations"
+	DatasetFinalityProviders     = "finality-providers"
+	DatasetFinalityProviderStats = "finality-provider-stats"
+	DatasetStats                 = "stats"
+)
+
+// datasets are built from the model types, so newly added model fields are exported automatically
+var datasets = map[string]Dataset{
+	DatasetDelegations: {
+		Name:       DatasetDelegations,
+		Collection: model.BTCDelegationDetailsCollection,
+		Fields:     fieldsOf(reflect.TypeOf(model.BTCDelegationDetails{}), ""),
+		filters: map[filterKey]string{
+			filterState:            "state",
+			filterFinalityProvider: "finality_provider_btc_pks_hex",
+			filterParamsVersion:    "params_version",
+			filterHeight:           "btc_delegation_created_bbn_block.height",
+		},
+	},
+	DatasetFinalityProviders: {
+		Name:       DatasetFinalityProviders,
+		Collection: model.FinalityProviderDetailsCollection,
+		Fields:     fieldsOf(reflect.TypeOf(model.FinalityProviderDetails{}), ""),
+		filters: map[filterKey]string{
+			filterState:            "state",
+			filterFinalityProvider: "_id",
+		},
+	},
+	DatasetFinalityProviderStats: {
+		Name:       DatasetFinalityProviderStats,
+		Collection: model.FinalityProviderStatsCollection,
+		Fields:     fieldsOf(reflect.TypeOf(model.FinalityProviderStatsDocument{}), ""),
+		filters: map[filterKey]string{
+			filterFinalityProvider: "_id",
+		},
+	},
+	DatasetStats: {
+		Name:       DatasetStats,
+		Collection: model.StatsCollection,
+		Fields:     fieldsOf(reflect.TypeOf(model.OverallStatsDocument{}), ""),
+	},
+}
+
+// DatasetNames returns names of all exportable datasets
+func DatasetNames() []string {
+	return []string{DatasetDelegations, DatasetFinalityProviders, DatasetFinalityProviderStats, DatasetStats}
+}
+
+// GetDataset returns dataset by its name
+func GetDataset(name string) (Dataset, error) {
+	dataset, ok := datasets[name]
+	if !ok {
+		return Dataset{}, fmt.Errorf("unknown dataset %q, expected one of %s", name, strings.Join(DatasetNames(), ", "))
+	}
+	return dataset, nil
+}
+
+// SelectFields returns dataset fields with given paths in the same order, empty paths means all fields
+func (d Dataset) SelectFields(paths []string) ([]Field, error) {
+	if len(paths) == 0 {
+		return d.Fields, nil
+	}
+
+	byPath := make(map[string]Field, len(d.Fields))
+	for _, f := range d.Fields {
+		byPath[f.Path] = f
+	}
+
+	fields := make([]Field, 0, len(paths))
+	for _, path := range paths {
+		f, ok := byPath[path]
+		if !ok {
+			return nil, fmt.Errorf("unknown field %q in dataset %s", path, d.Name)
+		}
+		fields = append(fields, f)
+	}
+
+	return fields, nil
+}
+
+func fieldsOf(t reflect.Type, prefix string) []Field {
+	var fields []Field
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		name, _, _ := strings.Cut(sf.Tag.Get("bson"), ",")
+		if name == "-" || !sf.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(sf.Name)
+		}
+		path := prefix + name
+
+		switch sf.Type.Kind() {
+		case reflect.String:
+			fields = append(fields, Field{Path: path, Kind: KindString})
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			fields = append(fields, Field{Path: path, Kind: KindInt})
+		case reflect.Bool:
+			fields = append(fields, Field{Path: path, Kind: KindBool})
+		case reflect.Struct:
+			fields = append(fields, fieldsOf(sf.Type, path+".")...)
+		default:
+			fields = append(fields, Field{Path: path, Kind: KindJSON})
+		}
+	}
+
+	return fields
+}
+
+// value extracts field value from the document, missing values are returned as nil
+func (f Field) value(doc bson.Raw) (any, error) {
+	rv, err := doc.LookupErr(strings.Split(f.Path, ".")...)
+	if err != nil {
+		if errors.Is(err, bsoncore.ErrElementNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to lookup field %s: %w", f.Path, err)
+	}
+	if rv.Type == bson.TypeNull || rv.Type == bson.TypeUndefined {
+		return nil, nil
+	}
+
+	switch f.Kind {
+	case KindString:
+		if s, ok := rv.StringValueOK(); ok {
+			return s, nil
+		}
+	case KindInt:
+		if n, ok := rv.AsInt64OK(); ok {
+			return n, nil
+		}
+	case KindBool:
+		if b, ok := rv.BooleanOK(); ok {
+			return b, nil
+		}
+	case KindJSON:
+		return toJSON(rv)
+	}
+
+	return nil, fmt.Errorf("unexpected type %s of field %s", rv.Type, f.Path)
+}
+
+// toJSON converts bson value into relaxed extended JSON
+func toJSON(rv bson.RawValue) ([]byte, error) {
+	const prefix = `{"v":`
+
+	// extended json can be produced only for documents, so we wrap the value
+	data, err := bson.MarshalExtJSON(bson.D{{Key: "v", Value: rv}}, false, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return data[len(prefix) : len(data)-1], nil
+}
@@ -0,0 +1,75 @@
+package export
+
+import (
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Filter limits exported documents, zero values mean no filtering.
+// Not every dataset supports every filter, see Dataset.filters.
+type Filter struct {
+	States                   []string
+	FinalityProviderBtcPkHex string
+	ParamsVersion            *uint32
+	// FromHeight and ToHeight are inclusive bounds of BBN height at which delegation was created
+	FromHeight uint64
+	ToHeight   uint64
+}
+
+func (f Filter) toBSON(dataset Dataset) (bson.M, error) {
+	filter := bson.M{}
+
+	field := func(key filterKey, name string) (string, error) {
+		path, ok := dataset.filters[key]
+		if !ok {
+			return "", fmt.Errorf("dataset %s doesn't support %s filter", dataset.Name, name)
+		}
+		return path, nil
+	}
+
+	if len(f.States) > 0 {
+		path, err := field(filterState, "state")
+		if err != nil {
+			return nil, err
+		}
+		filter[path] = bson.M{"$in": f.States}
+	}
+
+	if f.FinalityProviderBtcPkHex != "" {
+		path, err := field(filterFinalityProvider, "finality provider")
+		if err != nil {
+			return nil, err
+		}
+		filter[path] = f.FinalityProviderBtcPkHex
+	}
+
+	if f.ParamsVersion != nil {
+		path, err := field(filterParamsVersion, "params version")
+		if err != nil {
+			return nil, err
+		}
+		filter[path] = *f.ParamsVersion
+	}
+
+	if f.FromHeight != 0 || f.ToHeight != 0 {
+		path, err := field(filterHeight, "height")
+		if err != nil {
+			return nil, err
+		}
+		if f.ToHeight != 0 && f.FromHeight > f.ToHeight {
+			return nil, fmt.Errorf("from height %d is greater than to height %d", f.FromHeight, f.ToHeight)
+		}
+
+		heightRange := bson.M{}
+		if f.FromHeight != 0 {
+			heightRange["$gte"] = f.FromHeight
+		}
+		if f.ToHeight != 0 {
+			heightRange["$lte"] = f.ToHeight
+		}
+		filter[path] = heightRange
+	}
+
+	return filter, nil
+}
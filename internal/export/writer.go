@@ -0,0 +1,221 @@
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+type Format string
+
+const (
+	FormatCSV     Format = "csv"
+	FormatNDJSON  Format = "ndjson"
+	FormatParquet Format = "parquet"
+)
+
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(s); f {
+	case FormatCSV, FormatNDJSON, FormatParquet:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unknown format %q, expected one of csv, ndjson, parquet", s)
+	}
+}
+
+// rowWriter writes rows with values in the same order as fields it was created with.
+// Values are nil, string, int64, bool or []byte (JSON)
+type rowWriter interface {
+	WriteRow(values []any) error
+	// Close flushes buffered data, it doesn't close underlying writer
+	Close() error
+}
+
+func newRowWriter(format Format, w io.Writer, fields []Field) (rowWriter, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w, fields)
+	case FormatNDJSON:
+		return newNDJSONWriter(w, fields), nil
+	case FormatParquet:
+		return newParquetWriter(w, fields), nil
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+}
+
+type csvWriter struct {
+	w       *csv.Writer
+	columns []string
+}
+
+func newCSVWriter(w io.Writer, fields []Field) (*csvWriter, error) {
+	header := make([]string, len(fields))
+	for i, f := range fields {
+		header[i] = f.Path
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return nil, err
+	}
+
+	return &csvWriter{
+		w:       cw,
+		columns: make([]string, len(fields)),
+	}, nil
+}
+
+func (c *csvWriter) WriteRow(values []any) error {
+	for i, v := range values {
+		switch v := v.(type) {
+		case nil:
+			c.columns[i] = ""
+		case string:
+			c.columns[i] = v
+		case int64:
+			c.columns[i] = strconv.FormatInt(v, 10)
+		case bool:
+			c.columns[i] = strconv.FormatBool(v)
+		case []byte:
+			c.columns[i] = string(v)
+		default:
+			return fmt.Errorf("unexpected value type %T", v)
+		}
+	}
+
+	return c.w.Write(c.columns)
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type ndjsonWriter struct {
+	w    *bufio.Writer
+	keys [][]byte
+}
+
+func newNDJSONWriter(w io.Writer, fields []Field) *ndjsonWriter {
+	keys := make([][]byte, len(fields))
+	for i, f := range fields {
+		// field paths are bson keys, so marshaling can't fail
+		keys[i], _ = json.Marshal(f.Path)
+	}
+
+	return &ndjsonWriter{
+		w:    bufio.NewWriter(w),
+		keys: keys,
+	}
+}
+
+// WriteRow writes values as JSON object, keys are written manually to preserve fields order
+func (n *ndjsonWriter) WriteRow(values []any) error {
+	n.w.WriteByte('{')
+	for i, v := range values {
+		if i > 0 {
+			n.w.WriteByte(',')
+		}
+		n.w.Write(n.keys[i])
+		n.w.WriteByte(':')
+
+		var data []byte
+		switch v := v.(type) {
+		case []byte:
+			data = v
+		default:
+			var err error
+			data, err = json.Marshal(v)
+			if err != nil {
+				return err
+			}
+		}
+		n.w.Write(data)
+	}
+	n.w.WriteByte('}')
+	// bufio.Writer keeps the first error, so it's enough to check the last write
+	return n.w.WriteByte('\n')
+}
+
+func (n *ndjsonWriter) Close() error {
+	return n.w.Flush()
+}
+
+type parquetWriter struct {
+	w *parquet.Writer
+	// columnIndexes maps field position to parquet column index (parquet orders columns by name)
+	columnIndexes []int
+}
+
+func newParquetWriter(w io.Writer, fields []Field) *parquetWriter {
+	group := parquet.Group{}
+	for _, f := range fields {
+		var node parquet.Node
+		switch f.Kind {
+		case KindInt:
+			node = parquet.Int(64)
+		case KindBool:
+			node = parquet.Leaf(parquet.BooleanType)
+		case KindJSON:
+			node = parquet.JSON()
+		default:
+			node = parquet.String()
+		}
+		// every value might be missing in the document
+		group[f.Path] = parquet.Optional(node)
+	}
+	schema := parquet.NewSchema("export", group)
+
+	indexByName := make(map[string]int)
+	for i, path := range schema.Columns() {
+		indexByName[path[0]] = i
+	}
+	columnIndexes := make([]int, len(fields))
+	for i, f := range fields {
+		columnIndexes[i] = indexByName[f.Path]
+	}
+
+	return &parquetWriter{
+		w:             parquet.NewWriter(w, schema),
+		columnIndexes: columnIndexes,
+	}
+}
+
+func (p *parquetWriter) WriteRow(values []any) error {
+	row := make(parquet.Row, len(values))
+	for i, v := range values {
+		var value parquet.Value
+		definitionLevel := 1
+		switch v := v.(type) {
+		case nil:
+			value = parquet.NullValue()
+			definitionLevel = 0
+		case string:
+			value = parquet.ByteArrayValue([]byte(v))
+		case int64:
+			value = parquet.Int64Value(v)
+		case bool:
+			value = parquet.BooleanValue(v)
+		case []byte:
+			value = parquet.ByteArrayValue(v)
+		default:
+			return fmt.Errorf("unexpected value type %T", v)
+		}
+
+		columnIndex := p.columnIndexes[i]
+		row[columnIndex] = value.Level(0, definitionLevel, columnIndex)
+	}
+
+	_, err := p.w.WriteRows([]parquet.Row{row})
+	return err
+}
+
+func (p *parquetWriter) Close() error {
+	return p.w.Close()
+}
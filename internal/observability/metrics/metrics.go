@@ -31,13 +31,29 @@ var (
 	metricsRouter                   *chi.Mux
 	btcClientLatency                *prometheus.HistogramVec
 	bbnClientLatency                *prometheus.HistogramVec
+	bbnEndpointLatency              *prometheus.HistogramVec
+	bbnEndpointHealthyGauge         *prometheus.GaugeVec
+	bbnEndpointLatestHeightGauge    *prometheus.GaugeVec
+	bbnEndpointFailoverCounter      *prometheus.CounterVec
+	bbnSubscriptionEndpointGauge    *prometheus.GaugeVec
 	queueSendErrorCounter           prometheus.Counter
 	clientRequestDurationHistogram  *prometheus.HistogramVec
 	pollerDurationHistogram         *prometheus.HistogramVec
 	expiredDelegationsGauge         prometheus.Gauge
+	expiredDelegationsBacklogGauge  prometheus.Gauge
+	expiryCheckerFailuresCounter    *prometheus.CounterVec
+	expiringSoonEventsCounter       *prometheus.CounterVec
 	bbnEventProcessingDuration      *prometheus.HistogramVec
+	unhandledBbnEventsCounter       *prometheus.CounterVec
 	btcNotifierRegisterSpendCounter *prometheus.CounterVec
 	btcTipHeightGauge               prometheus.Gauge
+	delegationQueueDepthGauge       *prometheus.GaugeVec
+	delegationQueueWaitHistogram    *prometheus.HistogramVec
+	delegationConflictsCounter      prometheus.Counter
+	btcSpendReorgCounter            *prometheus.CounterVec
+	revertedTransitionsCounter      *prometheus.CounterVec
+	unknownSpendsCounter            *prometheus.CounterVec
+	trackedStakingTxsGauge          *prometheus.GaugeVec
 	dbLatency                       *prometheus.HistogramVec
 	activeTvlGauge                  prometheus.Gauge
 	activeDelegationsGauge          prometheus.Gauge
@@ -108,6 +124,47 @@ func registerMetrics() {
 		[]string{"method", "status"},
 	)
 
+	bbnEndpointLatency = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "bbn_endpoint_latency_seconds",
+			Help:    "Histogram of requests durations to every bbn endpoint in seconds.",
+			Buckets: defaultHistogramBucketsSeconds,
+		},
+		[]string{"endpoint", "method", "status"},
+	)
+
+	bbnEndpointHealthyGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "bbn_endpoint_healthy",
+			Help: "1 if bbn endpoint is healthy, 0 otherwise",
+		},
+		[]string{"endpoint", "role"},
+	)
+
+	bbnEndpointLatestHeightGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "bbn_endpoint_latest_height",
+			Help: "Latest block height reported by bbn endpoint",
+		},
+		[]string{"endpoint"},
+	)
+
+	bbnEndpointFailoverCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "bbn_endpoint_failover_count",
+			Help: "Number of bbn requests served by other than the preferred endpoint",
+		},
+		[]string{"method"},
+	)
+
+	bbnSubscriptionEndpointGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "bbn_subscription_endpoint",
+			Help: "1 for bbn endpoint the events subscription is currently connected to",
+		},
+		[]string{"endpoint"},
+	)
+
 	// add a counter for the number of errors from the fail to push message into queue
 	queueSendErrorCounter = prometheus.NewCounter(
 		prometheus.CounterOpts{
@@ -132,6 +189,29 @@ func registerMetrics() {
 		},
 	)
 
+	expiredDelegationsBacklogGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "expired_delegations_backlog",
+			Help: "Number of expired delegations waiting to be processed by expiry checker",
+		},
+	)
+
+	expiryCheckerFailuresCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "expiry_checker_failures_count",
+			Help: "Number of expired delegations expiry checker failed to process",
+		},
+		[]string{"reason"},
+	)
+
+	expiringSoonEventsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "expiring_soon_events_count",
+			Help: "Number of expiring soon events emitted by threshold in BTC blocks",
+		},
+		[]string{"threshold"},
+	)
+
 	bbnEventProcessingDuration = prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
 			Name:    "bbn_event_processing_duration_seconds",
@@ -141,6 +221,14 @@ func registerMetrics() {
 		[]string{"event_type", "status", "retry"},
 	)
 
+	unhandledBbnEventsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "bbn_unhandled_events_count",
+			Help: "Number of Babylon events without a registered handler",
+		},
+		[]string{"event_type"},
+	)
+
 	btcNotifierRegisterSpendCounter = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "btc_notifier_register_spend_count",
@@ -149,6 +237,40 @@ func registerMetrics() {
 		[]string{"status"},
 	)
 
+	btcSpendReorgCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "btc_spend_reorg_count",
+			Help: "Number of reorg notifications of watched BTC spends",
+		},
+		// provisional is true if the reorged spend caused state transition that has to be reverted
+		[]string{"provisional"},
+	)
+
+	revertedTransitionsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "reverted_state_transitions_count",
+			Help: "Number of delegation state transitions reverted because of BTC reorg",
+		},
+		[]string{"from_state", "to_state"},
+	)
+
+	unknownSpendsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "unknown_spends_count",
+			Help: "Number of delegation outputs spent by unrecognized BTC tx",
+		},
+		[]string{"spent_output"},
+	)
+
+	trackedStakingTxsGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "tracked_staking_txs",
+			Help: "Number of tracked staking txs of pre-approval delegations by BTC status",
+		},
+		// status is one of included, mempool, missing or stale
+		[]string{"status"},
+	)
+
 	btcTipHeightGauge = prometheus.NewGauge(
 		prometheus.GaugeOpts{
 			Name: "btc_tip_height",
@@ -156,6 +278,31 @@ func registerMetrics() {
 		},
 	)
 
+	delegationQueueDepthGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "delegation_queue_depth",
+			Help: "Number of tasks running or waiting for other tasks of the same delegation",
+		},
+		// source is either bbn (BBN events) or btc (BTC spends)
+		[]string{"source"},
+	)
+
+	delegationQueueWaitHistogram = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "delegation_queue_wait_seconds",
+			Help:    "Time tasks wait for other tasks of the same delegation in seconds",
+			Buckets: defaultHistogramBucketsSeconds,
+		},
+		[]string{"source"},
+	)
+
+	delegationConflictsCounter = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "delegation_update_conflicts_count",
+			Help: "Number of delegation updates retried because the delegation was updated concurrently",
+		},
+	)
+
 	dbLatency = prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
 			Name: "db_latency_seconds",
@@ -181,13 +328,29 @@ func registerMetrics() {
 	prometheus.MustRegister(
 		btcClientLatency,
 		bbnClientLatency,
+		bbnEndpointLatency,
+		bbnEndpointHealthyGauge,
+		bbnEndpointLatestHeightGauge,
+		bbnEndpointFailoverCounter,
+		bbnSubscriptionEndpointGauge,
 		queueSendErrorCounter,
 		clientRequestDurationHistogram,
 		pollerDurationHistogram,
 		expiredDelegationsGauge,
+		expiredDelegationsBacklogGauge,
+		expiryCheckerFailuresCounter,
+		expiringSoonEventsCounter,
 		bbnEventProcessingDuration,
+		unhandledBbnEventsCounter,
 		btcNotifierRegisterSpendCounter,
 		btcTipHeightGauge,
+		btcSpendReorgCounter,
+		revertedTransitionsCounter,
+		unknownSpendsCounter,
+		trackedStakingTxsGauge,
+		delegationQueueDepthGauge,
+		delegationQueueWaitHistogram,
+		delegationConflictsCounter,
 		dbLatency,
 		activeTvlGauge,
 		activeDelegationsGauge,
@@ -212,6 +375,44 @@ func RecordBBNClientLatency(d time.Duration, method string, failure bool) {
 	bbnClientLatency.WithLabelValues(method, status.String()).Observe(d.Seconds())
 }
 
+func RecordBBNEndpointLatency(d time.Duration, endpoint, method string, failure bool) {
+	status := Success
+	if failure {
+		status = Error
+	}
+
+	// bbn client is used without metrics in tests and cli commands
+	if bbnEndpointLatency != nil {
+		bbnEndpointLatency.WithLabelValues(endpoint, method, status.String()).Observe(d.Seconds())
+	}
+}
+
+func RecordBBNEndpointHealth(endpoint, role string, healthy bool, latestHeight int64) {
+	if bbnEndpointHealthyGauge == nil {
+		return
+	}
+
+	var value float64
+	if healthy {
+		value = 1
+	}
+	bbnEndpointHealthyGauge.WithLabelValues(endpoint, role).Set(value)
+	bbnEndpointLatestHeightGauge.WithLabelValues(endpoint).Set(float64(latestHeight))
+}
+
+func IncBBNEndpointFailover(method string) {
+	if bbnEndpointFailoverCounter != nil {
+		bbnEndpointFailoverCounter.WithLabelValues(method).Inc()
+	}
+}
+
+func RecordBBNSubscriptionEndpoint(endpoint string) {
+	if bbnSubscriptionEndpointGauge != nil {
+		bbnSubscriptionEndpointGauge.Reset()
+		bbnSubscriptionEndpointGauge.WithLabelValues(endpoint).Set(1)
+	}
+}
+
 func RecordDbLatency(d time.Duration, method string, failure bool) {
 	status := Success
 	if failure {
@@ -222,7 +423,10 @@ func RecordDbLatency(d time.Duration, method string, failure bool) {
 }
 
 func RecordBtcTipHeight(height uint64) {
-	btcTipHeightGauge.Set(float64(height))
+	// expiry checker is used without metrics in tests
+	if btcTipHeightGauge != nil {
+		btcTipHeightGauge.Set(float64(height))
+	}
 }
 
 func IncBtcNotifierRegisterSpend(failure bool) {
@@ -234,8 +438,75 @@ func IncBtcNotifierRegisterSpend(failure bool) {
 	btcNotifierRegisterSpendCounter.WithLabelValues(status.String()).Inc()
 }
 
+func IncBtcSpendReorg(provisional bool) {
+	// spend watchers are used without metrics in tests
+	if btcSpendReorgCounter != nil {
+		btcSpendReorgCounter.WithLabelValues(strconv.FormatBool(provisional)).Inc()
+	}
+}
+
+// IncRevertedStateTransition records reverted transition, fromState is the state
+// the delegation had because of the reorged spend and toState is the restored one
+func IncRevertedStateTransition(fromState, toState string) {
+	if revertedTransitionsCounter != nil {
+		revertedTransitionsCounter.WithLabelValues(fromState, toState).Inc()
+	}
+}
+
+// IncUnknownSpend records the spend of staking or unbonding output by unrecognized tx
+func IncUnknownSpend(spentOutput string) {
+	if unknownSpendsCounter != nil {
+		unknownSpendsCounter.WithLabelValues(spentOutput).Inc()
+	}
+}
+
+// AddDelegationQueueDepth changes number of queued delegation tasks of the source by delta
+func AddDelegationQueueDepth(source string, delta int) {
+	if delegationQueueDepthGauge != nil {
+		delegationQueueDepthGauge.WithLabelValues(source).Add(float64(delta))
+	}
+}
+
+func RecordDelegationQueueWait(source string, d time.Duration) {
+	if delegationQueueWaitHistogram != nil {
+		delegationQueueWaitHistogram.WithLabelValues(source).Observe(d.Seconds())
+	}
+}
+
+func IncDelegationUpdateConflict() {
+	if delegationConflictsCounter != nil {
+		delegationConflictsCounter.Inc()
+	}
+}
+
 func RecordExpiredDelegationsCount(count int) {
-	expiredDelegationsGauge.Set(float64(count))
+	if expiredDelegationsGauge != nil {
+		expiredDelegationsGauge.Set(float64(count))
+	}
+}
+
+func RecordTrackedStakingTxs(status string, count int) {
+	if trackedStakingTxsGauge != nil {
+		trackedStakingTxsGauge.WithLabelValues(status).Set(float64(count))
+	}
+}
+
+func RecordExpiredDelegationsBacklog(count int64) {
+	if expiredDelegationsBacklogGauge != nil {
+		expiredDelegationsBacklogGauge.Set(float64(count))
+	}
+}
+
+func IncExpiryCheckerFailure(reason string) {
+	if expiryCheckerFailuresCounter != nil {
+		expiryCheckerFailuresCounter.WithLabelValues(reason).Inc()
+	}
+}
+
+func IncExpiringSoonEvents(threshold uint32) {
+	if expiringSoonEventsCounter != nil {
+		expiringSoonEventsCounter.WithLabelValues(strconv.FormatUint(uint64(threshold), 10)).Inc()
+	}
 }
 
 func RecordBbnEventProcessingDuration(d time.Duration, eventType string, retry int, failure bool) {
@@ -252,6 +523,12 @@ func RecordBbnEventProcessingDuration(d time.Duration, eventType string, retry i
 	}
 }
 
+func IncUnhandledBbnEvents(eventType string, count int) {
+	if unhandledBbnEventsCounter != nil {
+		unhandledBbnEventsCounter.WithLabelValues(eventType).Add(float64(count))
+	}
+}
+
 // StartClientRequestDurationTimer starts a timer to measure outgoing client request duration.
 func StartClientRequestDurationTimer(baseUrl, method, path string) func(statusCode int) {
 	startTime := time.Now()
@@ -0,0 +1,24 @@
+package services
+
+import (
+	"context"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/utils/blocktime"
+)
+
+// number of block timestamps kept per chain, state transitions mostly happen at recent heights
+const blockTimeCacheSize = 4096
+
+func (s *Service) newBlockTimeResolver() *blocktime.Resolver {
+	return blocktime.NewResolver(s.bbn, s.btc, blockTimeCacheSize)
+}
+
+// bbnBlockTime returns unix timestamp of the BBN block at the given height
+func (s *Service) bbnBlockTime(ctx context.Context, height int64) (int64, error) {
+	return s.blockTimes.BbnTimestamp(ctx, height)
+}
+
+// btcBlockTime returns unix timestamp of the BTC block at the given height
+func (s *Service) btcBlockTime(ctx context.Context, height uint32) (int64, error) {
+	return s.blockTimes.BtcTimestamp(ctx, height)
+}
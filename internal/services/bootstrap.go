@@ -2,11 +2,8 @@ package services
 
 import (
 	"context"
-	"errors"
 	"fmt"
-	"sync"
 
-	"github.com/babylonlabs-io/babylon-staking-indexer/pkg"
 	"github.com/rs/zerolog/log"
 	"github.com/sourcegraph/conc"
 )
@@ -26,93 +23,6 @@ func (s *Service) StartBbnBlockProcessor(ctx context.Context) error {
 	return nil
 }
 
-// FillStakerAddr is temporary method to backfill staker_addr data in the database.
-func (s *Service) FillStakerAddr(ctx context.Context, numWorkers int, dryRun bool) error {
-	records, err := s.db.GetDelegationsWithEmptyStakerAddress(ctx)
-	if err != nil {
-		return err
-	}
-
-	ctx, cancel := context.WithCancel(ctx)
-	defer cancel()
-
-	hashes := make(chan string, 100)
-
-	wg := conc.NewWaitGroup()
-	wg.Go(func() {
-		defer close(hashes)
-
-		for _, record := range records {
-			select {
-			case hashes <- record.StakingTxHashHex:
-				// do nothing
-			case <-ctx.Done():
-				return
-			}
-		}
-	})
-
-	var (
-		errs    []error
-		errorMx sync.Mutex
-	)
-	addError := func(err error) {
-		errorMx.Lock()
-		errs = append(errs, err)
-		errorMx.Unlock()
-		// in case of error cancel outer context
-		cancel()
-	}
-
-	for range numWorkers {
-		wg.Go(func() {
-			for {
-				select {
-				case <-ctx.Done():
-					return
-				case stakingTxHashHex, ok := <-hashes:
-					if !ok {
-						return
-					}
-
-					bbnAddress, err := s.bbn.BabylonStakerAddress(ctx, stakingTxHashHex)
-					if err != nil {
-						addError(err)
-						return
-					}
-
-					if bbnAddress == "" {
-						addError(fmt.Errorf("empty staker address for tx %s", stakingTxHashHex))
-						return
-					}
-
-					// double check
-					err = pkg.ValidateBabylonAddress(bbnAddress)
-					if err != nil {
-						addError(err)
-						return
-					}
-
-					if !dryRun {
-						if dbErr := s.db.UpdateDelegationStakerBabylonAddress(
-							ctx, stakingTxHashHex, bbnAddress,
-						); dbErr != nil {
-							addError(fmt.Errorf("failed to updated %s delegation staker addr: %w", stakingTxHashHex, dbErr))
-							return
-						}
-						log.Info().Msgf("Record %s updated with address %s", stakingTxHashHex, bbnAddress)
-					} else {
-						log.Info().Msgf("Dry run: would update record %s with address %s", stakingTxHashHex, bbnAddress)
-					}
-				}
-			}
-		})
-	}
-	wg.Wait()
-
-	return errors.Join(errs...)
-}
-
 // processBlocksSequentially processes BBN blockchain blocks in sequential order,
 // starting from the last processed height up to the latest chain height.
 // It extracts events from each block and forwards them to the event processor.
@@ -150,6 +60,7 @@ func (s *Service) processBlocksSequentially(ctx context.Context) error {
 					return
 				}
 			}
+			s.recordUnhandledEvents(ctx, item.blockHeight, item.events)
 
 			dbErr := s.db.UpdateLastProcessedBbnHeight(ctx, uint64(item.blockHeight))
 			if dbErr != nil {
@@ -227,9 +138,25 @@ func (s *Service) getEventsFromBlock(
 		return nil, fmt.Errorf("failed to get block results: %w", err)
 	}
 	// Append transaction-level events
-	for _, txResult := range blockResult.TxsResults {
-		for _, event := range txResult.Events {
-			events = append(events, NewBbnEvent(TxCategory, event))
+	if len(blockResult.TxsResults) != 0 {
+		// block results don't contain txs, their hashes are taken from the block
+		block, err := s.bbn.GetBlock(ctx, &blockHeight)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get block: %w", err)
+		}
+		// the block is fetched anyway, its time is used by the transitions at this height
+		s.blockTimes.AddBbnTimestamp(blockHeight, block.Block.Time.Unix())
+
+		txs := block.Block.Txs
+		if len(txs) != len(blockResult.TxsResults) {
+			return nil, fmt.Errorf(
+				"block %d has %d txs but %d tx results", blockHeight, len(txs), len(blockResult.TxsResults),
+			)
+		}
+
+		for i, txResult := range blockResult.TxsResults {
+			txHash := fmt.Sprintf("%X", txs[i].Hash())
+			events = append(events, newTxEvents(txHash, uint32(i), txResult.Events)...)
 		}
 	}
 	// Append finalize-block-level events
@@ -0,0 +1,293 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/utils"
+	bbntypes "github.com/babylonlabs-io/babylon/v4/x/btcstaking/types"
+	"github.com/rs/zerolog/log"
+)
+
+// bootstrapFromHeight seeds empty database with finality providers and delegations as they are
+// at the configured BBN height, so block processing can start right after it instead of genesis.
+// Every delegation gets a single synthetic state history record with types.BootstrapEventType.
+// Last processed height is updated in the end, so if bootstrap is interrupted it's executed
+// again on the next start (already saved documents are skipped).
+//
+// Spend notifications are not registered here: ResubscribeToMissedBtcNotifications that runs
+// afterwards registers them for every non-terminal delegation with inclusion proof.
+func (s *Service) bootstrapFromHeight(ctx context.Context) error {
+	height := s.cfg.BBN.BootstrapHeight
+	if height == 0 {
+		return nil
+	}
+
+	log := log.Ctx(ctx)
+
+	lastProcessedHeight, err := s.db.GetLastProcessedBbnHeight(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get last processed height: %w", err)
+	}
+	if lastProcessedHeight != 0 {
+		log.Info().
+			Uint64("bootstrap_height", height).
+			Uint64("last_processed_height", lastProcessedHeight).
+			Msg("database is already initialized, skipping bootstrap")
+		return nil
+	}
+
+	log.Info().Uint64("bootstrap_height", height).Msg("bootstrapping indexer state from BBN height")
+
+	// delegations reference staking params, so they must be available before any spend is handled
+	if err := s.fetchAndSaveParams(ctx); err != nil {
+		return err
+	}
+
+	bbnHeight := int64(height)
+	bbnBlock, err := s.bbn.GetBlock(ctx, &bbnHeight)
+	if err != nil {
+		return fmt.Errorf("failed to get block: %w", err)
+	}
+
+	if err := s.bootstrapFinalityProviders(ctx, bbnHeight, bbnBlock.Block.Time.Unix()); err != nil {
+		return err
+	}
+	if err := s.bootstrapDelegations(ctx, bbnHeight, bbnBlock.Block.Time.Unix()); err != nil {
+		return err
+	}
+
+	if err := s.db.UpdateLastProcessedBbnHeight(ctx, height); err != nil {
+		return fmt.Errorf("failed to update last processed height: %w", err)
+	}
+
+	log.Info().Uint64("bootstrap_height", height).Msg("indexer state bootstrapped")
+	return nil
+}
+
+func (s *Service) bootstrapFinalityProviders(ctx context.Context, bbnHeight, bbnBlockTime int64) error {
+	finalityProviders, err := s.bbn.GetFinalityProvidersAtHeight(ctx, bbnHeight)
+	if err != nil {
+		return err
+	}
+	activeBtcPks, err := s.bbn.GetActiveFinalityProvidersAtHeight(ctx, bbnHeight)
+	if err != nil {
+		return err
+	}
+
+	active := make(map[string]bool, len(activeBtcPks))
+	for _, btcPk := range activeBtcPks {
+		active[btcPk] = true
+	}
+
+	for _, fp := range finalityProviders {
+		state := finalityProviderStateAtHeight(fp, active[fp.BtcPk.MarshalHex()])
+		doc := model.FromFinalityProviderResponse(fp, state.String())
+
+		if err := s.db.SaveNewFinalityProvider(ctx, doc); err != nil && !db.IsDuplicateKeyError(err) {
+			return fmt.Errorf("failed to save finality provider %s: %w", doc.BtcPk, err)
+		}
+		record := model.NewFinalityProviderHistoryRecord(doc, types.BootstrapEventType, bbnHeight, bbnBlockTime, nil)
+		if err := s.db.SaveFinalityProviderHistory(ctx, record); err != nil {
+			return fmt.Errorf("failed to save finality provider %s history: %w", doc.BtcPk, err)
+		}
+	}
+
+	log.Ctx(ctx).Info().Int("count", len(finalityProviders)).Msg("finality providers bootstrapped")
+	return nil
+}
+
+func finalityProviderStateAtHeight(fp *bbntypes.FinalityProviderResponse, active bool) bbntypes.FinalityProviderStatus {
+	switch {
+	case fp.SlashedBabylonHeight > 0 || fp.SlashedBtcHeight > 0:
+		return bbntypes.FinalityProviderStatus_FINALITY_PROVIDER_STATUS_SLASHED
+	case fp.Jailed:
+		return bbntypes.FinalityProviderStatus_FINALITY_PROVIDER_STATUS_JAILED
+	case active:
+		return bbntypes.FinalityProviderStatus_FINALITY_PROVIDER_STATUS_ACTIVE
+	default:
+		return bbntypes.FinalityProviderStatus_FINALITY_PROVIDER_STATUS_INACTIVE
+	}
+}
+
+func (s *Service) bootstrapDelegations(ctx context.Context, bbnHeight, bbnBlockTime int64) error {
+	// Staking output of an UNBONDED delegation is spent either by unbonding tx or by stake expansion tx.
+	// In order to tell them apart, delegations spent by non-unbonding tx are saved after all pages are read
+	// and we know which staking tx every expansion points to.
+	var (
+		// staking tx hash -> hash of the tx that spent its staking output (other than unbonding tx)
+		spentByOtherTx = make(map[string]string)
+		pendingDocs    []*model.BTCDelegationDetails
+		// previous staking tx hash -> expansion staking tx hash
+		expansions = make(map[string]string)
+		count      int
+		pageKey    []byte
+	)
+
+	for {
+		delegations, nextKey, err := s.bbn.GetBTCDelegationsAtHeight(ctx, bbnHeight, pageKey)
+		if err != nil {
+			return err
+		}
+
+		for _, delegation := range delegations {
+			doc, err := model.FromBTCDelegationResponse(delegation, bbnHeight, bbnBlockTime)
+			if err != nil {
+				return err
+			}
+
+			if doc.HasInclusionProof() {
+				// many delegations share the same start height, the lookup is cached
+				timestamp, err := s.btcBlockTime(ctx, doc.StartHeight)
+				if err != nil {
+					return fmt.Errorf("failed to get block timestamp: %w", err)
+				}
+				doc.StakingBTCTimestamp = timestamp
+			}
+
+			if doc.PreviousStakingTxHashHex != "" {
+				expansions[doc.PreviousStakingTxHashHex] = doc.StakingTxHashHex
+			}
+
+			state, subState, err := delegationStateAtHeight(delegation)
+			if err != nil {
+				return fmt.Errorf("delegation %s: %w", doc.StakingTxHashHex, err)
+			}
+			setBootstrapState(doc, state, subState, bbnHeight, bbnBlockTime)
+
+			spendTxHash, err := stakeSpendingTxHash(delegation)
+			if err != nil {
+				return fmt.Errorf("delegation %s: %w", doc.StakingTxHashHex, err)
+			}
+			if spendTxHash != "" {
+				spentByOtherTx[doc.StakingTxHashHex] = spendTxHash
+				pendingDocs = append(pendingDocs, doc)
+				continue
+			}
+
+			if err := s.saveBootstrappedDelegation(ctx, doc); err != nil {
+				return err
+			}
+			count++
+		}
+
+		if len(nextKey) == 0 {
+			break
+		}
+		pageKey = nextKey
+	}
+
+	for _, doc := range pendingDocs {
+		if expansions[doc.StakingTxHashHex] == spentByOtherTx[doc.StakingTxHashHex] {
+			setBootstrapState(doc, types.StateExpanded, "", bbnHeight, bbnBlockTime)
+			doc.NextStakingTxHashHex = expansions[doc.StakingTxHashHex]
+		}
+
+		if err := s.saveBootstrappedDelegation(ctx, doc); err != nil {
+			return err
+		}
+		count++
+	}
+
+	log.Ctx(ctx).Info().Int("count", count).Msg("delegations bootstrapped")
+	return nil
+}
+
+func (s *Service) saveBootstrappedDelegation(ctx context.Context, doc *model.BTCDelegationDetails) error {
+	if err := s.db.SaveNewBTCDelegation(ctx, doc); err != nil {
+		if db.IsDuplicateKeyError(err) {
+			// saved by previous (interrupted) bootstrap
+			return nil
+		}
+		return fmt.Errorf("failed to save BTC delegation %s: %w", doc.StakingTxHashHex, err)
+	}
+
+	// expired delegation gets the same timelock expire as on EventBTCDelegationExpired. For early unbonding
+	// it's saved once the btc notifier discovers unbonding tx, as its inclusion height is unknown here
+	if doc.State == types.StateUnbonding && doc.SubState == types.SubStateTimelock {
+		if err := s.db.SaveNewTimeLockExpire(ctx, doc.StakingTxHashHex, doc.EndHeight, doc.SubState); err != nil {
+			return fmt.Errorf("failed to save timelock expire: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func setBootstrapState(
+	doc *model.BTCDelegationDetails,
+	state types.DelegationState,
+	subState types.DelegationSubState,
+	bbnHeight int64,
+	bbnBlockTime int64,
+) {
+	doc.State = state
+	doc.SubState = subState
+	doc.StateHistory = []model.StateRecord{
+		{
+			State:        state,
+			SubState:     subState,
+			BbnHeight:    bbnHeight,
+			BbnTimestamp: bbnBlockTime,
+			BbnEventType: types.BootstrapEventType,
+		},
+	}
+}
+
+// delegationStateAtHeight maps babylon delegation status into indexer state, stake expansion is handled by the caller.
+//
+// Babylon reports UNBONDED in two cases: the delegator unbonded early (it's the only case babylon stores
+// unbonding info) and inclusion proof was received, but its btc light client hasn't reached the start height yet.
+// The latter isn't spent, so it's mapped to ACTIVE as the indexer does on inclusion proof received event.
+//
+// Status of a delegation of a slashed finality provider isn't affected by the slashing, neither in babylon nor in
+// the indexer: it's mapped as reported and moves to SLASHED once the btc watcher sees the slashing tx, the same way
+// as for delegations indexed from events.
+func delegationStateAtHeight(
+	delegation *bbntypes.BTCDelegationResponse,
+) (types.DelegationState, types.DelegationSubState, error) {
+	switch delegation.StatusDesc {
+	case bbntypes.BTCDelegationStatus_PENDING.String():
+		return types.StatePending, "", nil
+	case bbntypes.BTCDelegationStatus_VERIFIED.String():
+		return types.StateVerified, "", nil
+	case bbntypes.BTCDelegationStatus_ACTIVE.String():
+		return types.StateActive, "", nil
+	case bbntypes.BTCDelegationStatus_EXPIRED.String():
+		return types.StateUnbonding, types.SubStateTimelock, nil
+	case bbntypes.BTCDelegationStatus_UNBONDED.String():
+		undelegation := delegation.UndelegationResponse
+		if undelegation != nil && undelegation.DelegatorUnbondingInfoResponse != nil {
+			return types.StateUnbonding, types.SubStateEarlyUnbonding, nil
+		}
+		if delegation.StartHeight > 0 && delegation.EndHeight > 0 {
+			return types.StateActive, "", nil
+		}
+		return "", "", fmt.Errorf("unbonded delegation has neither unbonding info nor inclusion proof")
+	default:
+		return "", "", fmt.Errorf("unexpected delegation status %q", delegation.StatusDesc)
+	}
+}
+
+// stakeSpendingTxHash returns hash of the tx that spent staking output if it's not the unbonding tx
+// registered in babylon (babylon stores such tx only in this case), otherwise empty string is returned
+func stakeSpendingTxHash(delegation *bbntypes.BTCDelegationResponse) (string, error) {
+	undelegation := delegation.UndelegationResponse
+	if undelegation == nil || undelegation.DelegatorUnbondingInfoResponse == nil {
+		return "", nil
+	}
+
+	spendTxHex := undelegation.DelegatorUnbondingInfoResponse.SpendStakeTxHex
+	if spendTxHex == "" {
+		return "", nil
+	}
+
+	spendTx, err := utils.DeserializeBtcTransactionFromHex(spendTxHex)
+	if err != nil {
+		return "", fmt.Errorf("failed to deserialize stake spending tx: %w", err)
+	}
+
+	return spendTx.TxHash().String(), nil
+}
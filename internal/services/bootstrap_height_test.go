@@ -0,0 +1,267 @@
+package services
+
+import (
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/bbnclient"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/utils"
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/mocks"
+	bbn "github.com/babylonlabs-io/babylon/v4/types"
+	bbntypes "github.com/babylonlabs-io/babylon/v4/x/btcstaking/types"
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/wire"
+	ctypes "github.com/cometbft/cometbft/rpc/core/types"
+	cmttypes "github.com/cometbft/cometbft/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDelegationStateAtHeight(t *testing.T) {
+	unbondedEarly := &bbntypes.BTCUndelegationResponse{
+		DelegatorUnbondingInfoResponse: &bbntypes.DelegatorUnbondingInfoResponse{},
+	}
+	tests := []struct {
+		name             string
+		delegation       bbntypes.BTCDelegationResponse
+		expectedState    types.DelegationState
+		expectedSubState types.DelegationSubState
+	}{
+		{
+			name:          "pending",
+			delegation:    bbntypes.BTCDelegationResponse{StatusDesc: bbntypes.BTCDelegationStatus_PENDING.String()},
+			expectedState: types.StatePending,
+		},
+		{
+			name:          "verified",
+			delegation:    bbntypes.BTCDelegationResponse{StatusDesc: bbntypes.BTCDelegationStatus_VERIFIED.String()},
+			expectedState: types.StateVerified,
+		},
+		{
+			name:          "active",
+			delegation:    bbntypes.BTCDelegationResponse{StatusDesc: bbntypes.BTCDelegationStatus_ACTIVE.String()},
+			expectedState: types.StateActive,
+		},
+		{
+			name:             "expired",
+			delegation:       bbntypes.BTCDelegationResponse{StatusDesc: bbntypes.BTCDelegationStatus_EXPIRED.String()},
+			expectedState:    types.StateUnbonding,
+			expectedSubState: types.SubStateTimelock,
+		},
+		{
+			name: "unbonded early",
+			delegation: bbntypes.BTCDelegationResponse{
+				StatusDesc:           bbntypes.BTCDelegationStatus_UNBONDED.String(),
+				StartHeight:          100,
+				EndHeight:            200,
+				UndelegationResponse: unbondedEarly,
+			},
+			expectedState:    types.StateUnbonding,
+			expectedSubState: types.SubStateEarlyUnbonding,
+		},
+		{
+			name: "unbonded before start height",
+			delegation: bbntypes.BTCDelegationResponse{
+				StatusDesc:           bbntypes.BTCDelegationStatus_UNBONDED.String(),
+				StartHeight:          100,
+				EndHeight:            200,
+				UndelegationResponse: &bbntypes.BTCUndelegationResponse{},
+			},
+			expectedState: types.StateActive,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, subState, err := delegationStateAtHeight(&tt.delegation)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedState, state)
+			assert.Equal(t, tt.expectedSubState, subState)
+		})
+	}
+
+	_, _, err := delegationStateAtHeight(&bbntypes.BTCDelegationResponse{
+		StatusDesc: bbntypes.BTCDelegationStatus_UNBONDED.String(),
+	})
+	assert.Error(t, err)
+	_, _, err = delegationStateAtHeight(&bbntypes.BTCDelegationResponse{
+		StatusDesc: bbntypes.BTCDelegationStatus_ANY.String(),
+	})
+	assert.Error(t, err)
+}
+
+func TestFinalityProviderStateAtHeight(t *testing.T) {
+	tests := []struct {
+		name     string
+		fp       bbntypes.FinalityProviderResponse
+		active   bool
+		expected bbntypes.FinalityProviderStatus
+	}{
+		{
+			name:     "slashed",
+			fp:       bbntypes.FinalityProviderResponse{SlashedBabylonHeight: 10, Jailed: true},
+			active:   true,
+			expected: bbntypes.FinalityProviderStatus_FINALITY_PROVIDER_STATUS_SLASHED,
+		},
+		{
+			name:     "jailed",
+			fp:       bbntypes.FinalityProviderResponse{Jailed: true},
+			active:   true,
+			expected: bbntypes.FinalityProviderStatus_FINALITY_PROVIDER_STATUS_JAILED,
+		},
+		{
+			name:     "active",
+			active:   true,
+			expected: bbntypes.FinalityProviderStatus_FINALITY_PROVIDER_STATUS_ACTIVE,
+		},
+		{
+			name:     "inactive",
+			expected: bbntypes.FinalityProviderStatus_FINALITY_PROVIDER_STATUS_INACTIVE,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, finalityProviderStateAtHeight(&tt.fp, tt.active))
+		})
+	}
+}
+
+func TestBootstrapFromHeight(t *testing.T) {
+	ctx := t.Context()
+	const (
+		bootstrapHeight = 1000
+		blockTime       = 1700000000
+		btcBlockTime    = 1600000000
+	)
+
+	newPk := func(t *testing.T) *bbn.BIP340PubKey {
+		key, err := btcec.NewPrivateKey()
+		require.NoError(t, err)
+		return bbn.NewBIP340PubKeyFromBTCPK(key.PubKey())
+	}
+	// every tx is unique because of the lock time
+	newTxHex := func(t *testing.T, lockTime uint32) string {
+		tx := wire.NewMsgTx(wire.TxVersion)
+		tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{}, nil, nil))
+		tx.AddTxOut(wire.NewTxOut(10000, nil))
+		tx.LockTime = lockTime
+		txBytes, err := utils.SerializeBtcTransaction(tx)
+		require.NoError(t, err)
+		return hex.EncodeToString(txBytes)
+	}
+
+	fpPk, slashedFpPk, stakerPk := newPk(t), newPk(t), newPk(t)
+	finalityProviders := []*bbntypes.FinalityProviderResponse{
+		{BtcPk: fpPk},
+		{BtcPk: slashedFpPk, SlashedBabylonHeight: 900},
+	}
+
+	newDelegation := func(lockTime uint32, status bbntypes.BTCDelegationStatus, fpPk *bbn.BIP340PubKey) *bbntypes.BTCDelegationResponse {
+		delegation := &bbntypes.BTCDelegationResponse{
+			StakingTxHex: newTxHex(t, lockTime),
+			BtcPk:        stakerPk,
+			FpBtcPkList:  []bbn.BIP340PubKey{*fpPk},
+			StatusDesc:   status.String(),
+		}
+		if status != bbntypes.BTCDelegationStatus_PENDING && status != bbntypes.BTCDelegationStatus_VERIFIED {
+			delegation.StartHeight = 100
+			delegation.EndHeight = 200
+		}
+		return delegation
+	}
+
+	pending := newDelegation(1, bbntypes.BTCDelegationStatus_PENDING, fpPk)
+	slashedFpActive := newDelegation(2, bbntypes.BTCDelegationStatus_ACTIVE, slashedFpPk)
+	expired := newDelegation(3, bbntypes.BTCDelegationStatus_EXPIRED, fpPk)
+	unbondedEarly := newDelegation(4, bbntypes.BTCDelegationStatus_UNBONDED, fpPk)
+	unbondedEarly.UndelegationResponse = &bbntypes.BTCUndelegationResponse{
+		DelegatorUnbondingInfoResponse: &bbntypes.DelegatorUnbondingInfoResponse{},
+	}
+	notStarted := newDelegation(5, bbntypes.BTCDelegationStatus_UNBONDED, fpPk)
+	expanded := newDelegation(6, bbntypes.BTCDelegationStatus_UNBONDED, fpPk)
+	expansion := newDelegation(7, bbntypes.BTCDelegationStatus_VERIFIED, fpPk)
+	expansion.StkExp = &bbntypes.StakeExpansionResponse{PreviousStakingTxHashHex: txHash(t, expanded)}
+	expanded.UndelegationResponse = &bbntypes.BTCUndelegationResponse{
+		DelegatorUnbondingInfoResponse: &bbntypes.DelegatorUnbondingInfoResponse{SpendStakeTxHex: expansion.StakingTxHex},
+	}
+
+	bbnClient := mocks.NewBbnInterface(t)
+	bbnClient.On("GetCheckpointParams", mock.Anything).Return(&bbnclient.CheckpointParams{}, nil).Once()
+	bbnClient.On("GetStakingParams", mock.Anything, uint32(0)).Return(map[uint32]*bbnclient.StakingParams{}, nil).Once()
+	bbnClient.On("GetBlock", mock.Anything, mock.MatchedBy(func(height *int64) bool { return *height == bootstrapHeight })).
+		Return(&ctypes.ResultBlock{Block: &cmttypes.Block{Header: cmttypes.Header{Time: time.Unix(blockTime, 0)}}}, nil).Once()
+	bbnClient.On("GetFinalityProvidersAtHeight", mock.Anything, int64(bootstrapHeight)).Return(finalityProviders, nil).Once()
+	bbnClient.On("GetActiveFinalityProvidersAtHeight", mock.Anything, int64(bootstrapHeight)).
+		Return([]string{fpPk.MarshalHex()}, nil).Once()
+	// delegations are read in two pages and the expansion comes after the expanded delegation
+	bbnClient.On("GetBTCDelegationsAtHeight", mock.Anything, int64(bootstrapHeight), []byte(nil)).
+		Return([]*bbntypes.BTCDelegationResponse{pending, slashedFpActive, expired, unbondedEarly}, []byte("next"), nil).Once()
+	bbnClient.On("GetBTCDelegationsAtHeight", mock.Anything, int64(bootstrapHeight), []byte("next")).
+		Return([]*bbntypes.BTCDelegationResponse{notStarted, expanded, expansion}, []byte(nil), nil).Once()
+
+	btcClient := mocks.NewBtcInterface(t)
+	btcClient.On("GetBlockHash", mock.Anything, uint32(100)).Return("block_hash", nil)
+	btcClient.On("GetBlockTimestampByHash", mock.Anything, "block_hash").Return(int64(btcBlockTime), nil).Once()
+
+	saved := make(map[string]*model.BTCDelegationDetails)
+	fpStates := make(map[string]string)
+	dbClient := mocks.NewDbInterface(t)
+	dbClient.On("GetLastProcessedBbnHeight", mock.Anything).Return(uint64(0), nil).Once()
+	dbClient.On("SaveCheckpointParams", mock.Anything, mock.Anything).Return(nil).Once()
+	dbClient.On("SaveNewFinalityProvider", mock.Anything, mock.Anything).Return(nil).
+		Run(func(args mock.Arguments) {
+			fp := args.Get(1).(*model.FinalityProviderDetails)
+			fpStates[fp.BtcPk] = fp.State
+		}).Twice()
+	dbClient.On("SaveFinalityProviderHistory", mock.Anything, mock.Anything).Return(nil).Twice()
+	dbClient.On("SaveNewBTCDelegation", mock.Anything, mock.Anything).Return(nil).
+		Run(func(args mock.Arguments) {
+			doc := args.Get(1).(*model.BTCDelegationDetails)
+			saved[doc.StakingTxHashHex] = doc
+		}).Times(7)
+	dbClient.On("SaveNewTimeLockExpire", mock.Anything, txHash(t, expired), uint32(200), types.SubStateTimelock).
+		Return(nil).Once()
+	dbClient.On("UpdateLastProcessedBbnHeight", mock.Anything, uint64(bootstrapHeight)).Return(nil).Once()
+
+	cfg := &config.Config{BBN: config.BBNConfig{BootstrapHeight: bootstrapHeight}}
+	srv := NewService(cfg, dbClient, btcClient, nil, bbnClient, nil)
+	require.NoError(t, srv.bootstrapFromHeight(ctx))
+
+	assert.Equal(t, map[string]string{
+		fpPk.MarshalHex():        bbntypes.FinalityProviderStatus_FINALITY_PROVIDER_STATUS_ACTIVE.String(),
+		slashedFpPk.MarshalHex(): bbntypes.FinalityProviderStatus_FINALITY_PROVIDER_STATUS_SLASHED.String(),
+	}, fpStates)
+
+	assertState := func(t *testing.T, delegation *bbntypes.BTCDelegationResponse, state types.DelegationState, subState types.DelegationSubState) {
+		doc, ok := saved[txHash(t, delegation)]
+		require.True(t, ok)
+		assert.Equal(t, state, doc.State)
+		assert.Equal(t, subState, doc.SubState)
+		require.Len(t, doc.StateHistory, 1)
+		assert.Equal(t, types.BootstrapEventType, doc.StateHistory[0].BbnEventType)
+		assert.Equal(t, int64(bootstrapHeight), doc.StateHistory[0].BbnHeight)
+		assert.Equal(t, int64(blockTime), doc.StateHistory[0].BbnTimestamp)
+	}
+	assertState(t, pending, types.StatePending, "")
+	// slashing of the finality provider is picked up by the btc watcher once slashing tx is seen
+	assertState(t, slashedFpActive, types.StateActive, "")
+	assertState(t, expired, types.StateUnbonding, types.SubStateTimelock)
+	assertState(t, unbondedEarly, types.StateUnbonding, types.SubStateEarlyUnbonding)
+	assertState(t, notStarted, types.StateActive, "")
+	assertState(t, expanded, types.StateExpanded, "")
+	assertState(t, expansion, types.StateVerified, "")
+
+	assert.Equal(t, txHash(t, expansion), saved[txHash(t, expanded)].NextStakingTxHashHex)
+	assert.Equal(t, int64(btcBlockTime), saved[txHash(t, expired)].StakingBTCTimestamp)
+	assert.Zero(t, saved[txHash(t, pending)].StakingBTCTimestamp)
+}
+
+func txHash(t *testing.T, delegation *bbntypes.BTCDelegationResponse) string {
+	tx, err := utils.DeserializeBtcTransactionFromHex(delegation.StakingTxHex)
+	require.NoError(t, err)
+	return tx.TxHash().String()
+}
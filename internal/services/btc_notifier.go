@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/avast/retry-go/v4"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/wire"
 	"github.com/lightningnetwork/lnd/chainntnfs"
 )
@@ -19,6 +20,9 @@ const (
 type BtcNotifier interface {
 	Start() error
 	RegisterSpendNtfn(outpoint *wire.OutPoint, pkScript []byte, heightHint uint32) (*chainntnfs.SpendEvent, error)
+	RegisterConfirmationsNtfn(
+		txid *chainhash.Hash, pkScript []byte, numConfs, heightHint uint32, opts ...chainntnfs.NotifierOption,
+	) (*chainntnfs.ConfirmationEvent, error)
 }
 
 // btcNotifierWithRetries is a wrapper around a BtcNotifier
@@ -54,3 +58,18 @@ func (b *btcNotifierWithRetries) RegisterSpendNtfn(outpoint *wire.OutPoint, pkSc
 
 	return result, err
 }
+
+func (b *btcNotifierWithRetries) RegisterConfirmationsNtfn(
+	txid *chainhash.Hash, pkScript []byte, numConfs, heightHint uint32, opts ...chainntnfs.NotifierOption,
+) (*chainntnfs.ConfirmationEvent, error) {
+	f := func() (*chainntnfs.ConfirmationEvent, error) {
+		return b.notifier.RegisterConfirmationsNtfn(txid, pkScript, numConfs, heightHint, opts...)
+	}
+
+	return retry.DoWithData(
+		f,
+		retry.Attempts(uint(b.maxRetries)),
+		retry.Delay(retryInitialDelay),
+		retry.MaxDelay(retryMaxAllowedDelay),
+	)
+}
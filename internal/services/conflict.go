@@ -0,0 +1,49 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/avast/retry-go/v4"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+	"github.com/rs/zerolog/log"
+)
+
+const conflictMaxRetries = 5
+
+// retryOnConflict runs fn again right away if it failed because the delegation was updated
+// after fn read it. fn must read the delegation again on every run.
+func (s *Service) retryOnConflict(ctx context.Context, fn func() error) error {
+	return retry.Do(
+		fn,
+		retry.Context(ctx),
+		retry.Attempts(conflictMaxRetries),
+		retry.Delay(0),
+		retry.LastErrorOnly(true),
+		retry.RetryIf(db.IsConflictError),
+		retry.OnRetry(func(n uint, err error) {
+			metrics.IncDelegationUpdateConflict()
+			log.Ctx(ctx).Debug().
+				Err(err).
+				Uint("attempt", n+1).
+				Msg("delegation updated concurrently, retrying")
+		}),
+	)
+}
+
+// updateDelegation reads the delegation and passes it to update, which must do only the versioned
+// update based on it. Both are repeated on conflict, side effects of the update (consumer events,
+// timelock expire, notifications) go after updateDelegation returns, so they run once.
+func (s *Service) updateDelegation(
+	ctx context.Context, stakingTxHashHex string, update func(current *model.BTCDelegationDetails) error,
+) error {
+	return s.retryOnConflict(ctx, func() error {
+		current, err := s.db.GetBTCDelegationByStakingTxHash(ctx, stakingTxHashHex)
+		if err != nil {
+			return fmt.Errorf("failed to get BTC delegation by staking tx hash: %w", err)
+		}
+		return update(current)
+	})
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryOnConflict(t *testing.T) {
+	ctx := context.Background()
+	s := &Service{}
+	conflictErr := &db.ConflictError{Key: testStakingTxHash, Message: "conflict"}
+
+	t.Run("conflict is retried", func(t *testing.T) {
+		attempts := 0
+		err := s.retryOnConflict(ctx, func() error {
+			attempts++
+			if attempts < 3 {
+				return conflictErr
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 3, attempts)
+	})
+	t.Run("other errors are not retried", func(t *testing.T) {
+		attempts := 0
+		otherErr := errors.New("other error")
+		err := s.retryOnConflict(ctx, func() error {
+			attempts++
+			return otherErr
+		})
+		require.ErrorIs(t, err, otherErr)
+		assert.Equal(t, 1, attempts)
+	})
+	t.Run("gives up after max retries", func(t *testing.T) {
+		attempts := 0
+		err := s.retryOnConflict(ctx, func() error {
+			attempts++
+			return conflictErr
+		})
+		require.True(t, db.IsConflictError(err))
+		assert.Equal(t, conflictMaxRetries, attempts)
+	})
+}
+
+func TestUpdateDelegation(t *testing.T) {
+	ctx := context.Background()
+	conflictErr := &db.ConflictError{Key: testStakingTxHash, Message: "conflict"}
+
+	s, m := newTestService(t)
+	m.db.On("GetBTCDelegationByStakingTxHash", ctx, testStakingTxHash).
+		Return(&model.BTCDelegationDetails{StakingTxHashHex: testStakingTxHash, Version: 1}, nil).Once()
+	m.db.On("GetBTCDelegationByStakingTxHash", ctx, testStakingTxHash).
+		Return(&model.BTCDelegationDetails{StakingTxHashHex: testStakingTxHash, Version: 2}, nil).Once()
+
+	// the delegation is read again for every attempt
+	var versions []int64
+	err := s.updateDelegation(ctx, testStakingTxHash, func(current *model.BTCDelegationDetails) error {
+		versions = append(versions, current.Version)
+		if current.Version == 1 {
+			return conflictErr
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 2}, versions)
+}
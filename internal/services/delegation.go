@@ -8,33 +8,18 @@ import (
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/utils"
-	bbntypes "github.com/babylonlabs-io/babylon/v4/x/btcstaking/types"
-	abcitypes "github.com/cometbft/cometbft/abci/types"
 	"github.com/rs/zerolog/log"
 )
 
-func (s *Service) processNewBTCDelegationEvent(
-	ctx context.Context, event abcitypes.Event, bbnBlockHeight int64,
+func (s *Service) applyNewBTCDelegationEvent(
+	ctx context.Context, newDelegation *types.BTCDelegationCreatedEvent, bbnBlockHeight int64, bbnTx *model.BbnTx,
 ) error {
-	newDelegation, err := parseEvent[*bbntypes.EventBTCDelegationCreated](
-		types.EventBTCDelegationCreated, event,
-	)
-	if err != nil {
-		return err
-	}
-
-	if err := s.validateBTCDelegationCreatedEvent(newDelegation); err != nil {
-		return err
-	}
-
-	// Get block info to get timestamp
-	bbnBlock, bbnErr := s.bbn.GetBlock(ctx, &bbnBlockHeight)
+	bbnBlockTime, bbnErr := s.bbnBlockTime(ctx, bbnBlockHeight)
 	if bbnErr != nil {
 		return fmt.Errorf("failed to get block: %w", bbnErr)
 	}
-	bbnBlockTime := bbnBlock.Block.Time.Unix()
 
-	delegationDoc, err := model.FromEventBTCDelegationCreated(newDelegation, bbnBlockHeight, bbnBlockTime)
+	delegationDoc, err := model.FromEventBTCDelegationCreated(newDelegation, bbnBlockHeight, bbnBlockTime, bbnTx)
 	if err != nil {
 		return err
 	}
@@ -52,38 +37,32 @@ func (s *Service) processNewBTCDelegationEvent(
 	return nil
 }
 
-func (s *Service) processCovenantSignatureReceivedEvent(
-	ctx context.Context, event abcitypes.Event,
+func (s *Service) applyCovenantSignatureReceivedEvent(
+	ctx context.Context, covenantSignatureReceivedEvent *types.CovenantSignatureReceivedEvent, _ int64, bbnTx *model.BbnTx,
 ) error {
-	covenantSignatureReceivedEvent, err := parseEvent[*bbntypes.EventCovenantSignatureReceived](
-		types.EventCovenantSignatureReceived, event,
-	)
-	if err != nil {
-		return err
-	}
 	stakingTxHash := covenantSignatureReceivedEvent.StakingTxHash
-	delegation, dbErr := s.db.GetBTCDelegationByStakingTxHash(ctx, stakingTxHash)
-	if dbErr != nil {
-		return fmt.Errorf("failed to get BTC delegation by staking tx hash: %w", dbErr)
-	}
-	// Check if the covenant signature already exists, if it does, ignore the event
-	for _, signature := range delegation.CovenantSignatures {
-		if signature.CovenantBtcPkHex == covenantSignatureReceivedEvent.CovenantBtcPkHex {
-			return nil
-		}
-	}
 	// Breakdown the covenantSignatureReceivedEvent into individual fields
 	covenantBtcPkHex := covenantSignatureReceivedEvent.CovenantBtcPkHex
 	signatureHex := covenantSignatureReceivedEvent.CovenantUnbondingSignatureHex
 	stakeExpansionSignatureHex := covenantSignatureReceivedEvent.CovenantStakeExpansionSignatureHex
 
-	if dbErr := s.db.SaveBTCDelegationCovenantSignature(
-		ctx,
-		stakingTxHash,
-		covenantBtcPkHex,
-		signatureHex,
-		stakeExpansionSignatureHex,
-	); dbErr != nil {
+	if dbErr := s.updateDelegation(ctx, stakingTxHash, func(current *model.BTCDelegationDetails) error {
+		// Check if the covenant signature already exists, if it does, ignore the event
+		for _, signature := range current.CovenantSignatures {
+			if signature.CovenantBtcPkHex == covenantBtcPkHex {
+				return nil
+			}
+		}
+		return s.db.SaveBTCDelegationCovenantSignature(
+			ctx,
+			stakingTxHash,
+			covenantBtcPkHex,
+			signatureHex,
+			stakeExpansionSignatureHex,
+			bbnTx,
+			db.WithExpectedVersion(current.Version),
+		)
+	}); dbErr != nil {
 		return fmt.Errorf(
 			"failed to save BTC delegation unbonding covenant signature: %w for staking tx hash %s",
 			dbErr, stakingTxHash,
@@ -93,25 +72,9 @@ func (s *Service) processCovenantSignatureReceivedEvent(
 	return nil
 }
 
-func (s *Service) processCovenantQuorumReachedEvent(
-	ctx context.Context, event abcitypes.Event, bbnBlockHeight int64,
+func (s *Service) applyCovenantQuorumReachedEvent(
+	ctx context.Context, covenantQuorumReachedEvent *types.CovenantQuorumReachedEvent, bbnBlockHeight int64, bbnTx *model.BbnTx,
 ) error {
-	covenantQuorumReachedEvent, err := parseEvent[*bbntypes.EventCovenantQuorumReached](
-		types.EventCovenantQuorumReached, event,
-	)
-	if err != nil {
-		return err
-	}
-
-	shouldProcess, err := s.validateCovenantQuorumReachedEvent(ctx, covenantQuorumReachedEvent)
-	if err != nil {
-		return err
-	}
-	if !shouldProcess {
-		// Ignore the event silently
-		return nil
-	}
-
 	// Emit event and register spend notification
 	delegation, dbErr := s.db.GetBTCDelegationByStakingTxHash(ctx, covenantQuorumReachedEvent.StakingTxHash)
 	if dbErr != nil {
@@ -120,7 +83,37 @@ func (s *Service) processCovenantQuorumReachedEvent(
 
 	log := log.Ctx(ctx)
 
-	newState := types.DelegationState(covenantQuorumReachedEvent.NewState)
+	bbnBlockTime, err := s.bbnBlockTime(ctx, bbnBlockHeight)
+	if err != nil {
+		return fmt.Errorf("failed to get block: %w", err)
+	}
+
+	// Update delegation state, the event state matches the transition, it's checked by
+	// validateCovenantQuorumReachedEvent
+	var newState types.DelegationState
+	if dbErr := s.updateDelegation(ctx, delegation.StakingTxHashHex, func(current *model.BTCDelegationDetails) error {
+		transition, err := types.ResolveTransition(
+			types.TriggerCovenantQuorumReached, current.State, current.SatisfiesGuard,
+		)
+		if err != nil {
+			return err
+		}
+		newState = transition.To
+		return s.db.UpdateBTCDelegationState(
+			ctx,
+			covenantQuorumReachedEvent.StakingTxHash,
+			transition.Trigger,
+			newState,
+			db.WithBbnHeight(bbnBlockHeight),
+			db.WithBbnTimestamp(bbnBlockTime),
+			db.WithBbnEventType(types.EventCovenantQuorumReached),
+			db.WithBbnTx(bbnTx),
+			db.WithExpectedVersion(current.Version),
+		)
+	}); dbErr != nil {
+		return fmt.Errorf("failed to update BTC delegation state: %w", dbErr)
+	}
+
 	if newState == types.StateActive {
 		log.Debug().
 			Str("staking_tx", covenantQuorumReachedEvent.StakingTxHash).
@@ -128,7 +121,7 @@ func (s *Service) processCovenantQuorumReachedEvent(
 			Stringer("event_type", types.EventCovenantQuorumReached).
 			Msg("handling active state")
 
-		err = s.emitActiveDelegationEvent(
+		err := s.emitActiveDelegationEvent(
 			ctx,
 			delegation,
 		)
@@ -147,40 +140,13 @@ func (s *Service) processCovenantQuorumReachedEvent(
 		}
 	}
 
-	// Update delegation state
-	if dbErr := s.db.UpdateBTCDelegationState(
-		ctx,
-		covenantQuorumReachedEvent.StakingTxHash,
-		types.QualifiedStatesForCovenantQuorumReached(covenantQuorumReachedEvent.NewState),
-		newState,
-		db.WithBbnHeight(bbnBlockHeight),
-		db.WithBbnEventType(types.EventCovenantQuorumReached),
-	); dbErr != nil {
-		return fmt.Errorf("failed to update BTC delegation state: %w", dbErr)
-	}
-
 	return nil
 }
 
-func (s *Service) processBTCDelegationInclusionProofReceivedEvent(
-	ctx context.Context, event abcitypes.Event, bbnBlockHeight int64,
+func (s *Service) applyBTCDelegationInclusionProofReceivedEvent(
+	ctx context.Context, inclusionProofEvent *types.BTCDelegationInclusionProofReceivedEvent,
+	bbnBlockHeight int64, bbnTx *model.BbnTx,
 ) error {
-	inclusionProofEvent, err := parseEvent[*bbntypes.EventBTCDelegationInclusionProofReceived](
-		types.EventBTCDelegationInclusionProofReceived, event,
-	)
-	if err != nil {
-		return err
-	}
-
-	shouldProcess, err := s.validateBTCDelegationInclusionProofReceivedEvent(ctx, inclusionProofEvent)
-	if err != nil {
-		return err
-	}
-	if !shouldProcess {
-		// Ignore the event silently
-		return nil
-	}
-
 	log := log.Ctx(ctx)
 
 	// Emit event and register spend notification
@@ -188,17 +154,63 @@ func (s *Service) processBTCDelegationInclusionProofReceivedEvent(
 	if dbErr != nil {
 		return fmt.Errorf("failed to get BTC delegation by staking tx hash: %w", dbErr)
 	}
-	newState := types.DelegationState(inclusionProofEvent.NewState)
-	if newState == types.StateActive {
-		stakingStartHeight, _ := utils.ParseUint32(inclusionProofEvent.StartHeight)
 
+	stakingStartHeight, _ := utils.ParseUint32(inclusionProofEvent.StartHeight)
+	stakingEndHeight, _ := utils.ParseUint32(inclusionProofEvent.EndHeight)
+	stakingBtcTimestamp, err := s.btcBlockTime(ctx, stakingStartHeight)
+	if err != nil {
+		return fmt.Errorf("failed to get block timestamp: %w", err)
+	}
+	bbnBlockTime, err := s.bbnBlockTime(ctx, bbnBlockHeight)
+	if err != nil {
+		return fmt.Errorf("failed to get block: %w", err)
+	}
+
+	// Note on state history:
+	// In the old staking flow, EventBTCDelegationInclusionProofReceived emits a PENDING state.
+	// This creates duplicate PENDING entries in state_history:
+	// 1. First PENDING: From EventBTCDelegationCreated
+	// 2. Second PENDING: From EventBTCDelegationInclusionProofReceived
+	//
+	// This duplicate entry is expected and maintains consistency with Babylon's state transitions.
+	// The event state matches the transition, it's checked by validateBTCDelegationInclusionProofReceivedEvent
+	var newState types.DelegationState
+	if dbErr := s.updateDelegation(ctx, delegation.StakingTxHashHex, func(current *model.BTCDelegationDetails) error {
+		transition, err := types.ResolveTransition(
+			types.TriggerInclusionProofReceived, current.State, current.SatisfiesGuard,
+		)
+		if err != nil {
+			return err
+		}
+		newState = transition.To
+		return s.db.UpdateBTCDelegationState(
+			ctx,
+			inclusionProofEvent.StakingTxHash,
+			transition.Trigger,
+			newState,
+			db.WithBbnHeight(bbnBlockHeight),
+			db.WithBbnTimestamp(bbnBlockTime),
+			db.WithBtcHeight(stakingStartHeight),
+			db.WithBtcTimestamp(stakingBtcTimestamp),
+			db.WithStakingStartHeight(stakingStartHeight),
+			db.WithStakingEndHeight(stakingEndHeight),
+			db.WithStakingBTCTimestamp(stakingBtcTimestamp),
+			db.WithBbnEventType(types.EventBTCDelegationInclusionProofReceived),
+			db.WithBbnTx(bbnTx),
+			db.WithExpectedVersion(current.Version),
+		)
+	}); dbErr != nil {
+		return fmt.Errorf("failed to update BTC delegation state: %w", dbErr)
+	}
+
+	if newState == types.StateActive {
 		log.Debug().
 			Str("staking_tx", inclusionProofEvent.StakingTxHash).
 			Str("staking_start_height", inclusionProofEvent.StartHeight).
 			Stringer("event_type", types.EventBTCDelegationInclusionProofReceived).
 			Msg("handling active state")
 
-		err = s.emitActiveDelegationEvent(
+		err := s.emitActiveDelegationEvent(
 			ctx,
 			delegation,
 		)
@@ -216,80 +228,34 @@ func (s *Service) processBTCDelegationInclusionProofReceivedEvent(
 		}
 	}
 
-	stakingStartHeight, _ := utils.ParseUint32(inclusionProofEvent.StartHeight)
-	stakingEndHeight, _ := utils.ParseUint32(inclusionProofEvent.EndHeight)
-	stakingBtcTimestamp, err := s.btc.GetBlockTimestamp(ctx, stakingStartHeight)
-	if err != nil {
-		return fmt.Errorf("failed to get block timestamp: %w", err)
-	}
-
-	// Note on state history:
-	// In the old staking flow, EventBTCDelegationInclusionProofReceived emits a PENDING state.
-	// This creates duplicate PENDING entries in state_history:
-	// 1. First PENDING: From EventBTCDelegationCreated
-	// 2. Second PENDING: From EventBTCDelegationInclusionProofReceived
-	//
-	// This duplicate entry is expected and maintains consistency with Babylon's state transitions.
-	if dbErr := s.db.UpdateBTCDelegationState(
-		ctx,
-		inclusionProofEvent.StakingTxHash,
-		types.QualifiedStatesForInclusionProofReceived(inclusionProofEvent.NewState),
-		newState,
-		db.WithBbnHeight(bbnBlockHeight),
-		db.WithStakingStartHeight(stakingStartHeight),
-		db.WithStakingEndHeight(stakingEndHeight),
-		db.WithStakingBTCTimestamp(stakingBtcTimestamp),
-		db.WithBbnEventType(types.EventBTCDelegationInclusionProofReceived),
-	); dbErr != nil {
-		return fmt.Errorf("failed to update BTC delegation state: %w", dbErr)
-	}
-
 	return nil
 }
 
-// TODO: Indexer doesn't need to intercept processBTCDelegationUnbondedEarlyEvent
+// TODO: Indexer doesn't need to intercept EventBTCDelgationUnbondedEarly
 // as the unbonding tx will be discovered by the btc notifier
 // we are keeping it for now to avoid breaking changes, but if the btc notifier has already identified
 // then this event will be silently ignored with help of validateBTCDelegationUnbondedEarlyEvent
-func (s *Service) processBTCDelegationUnbondedEarlyEvent(
-	ctx context.Context, event abcitypes.Event, bbnBlockHeight int64,
+func (s *Service) applyBTCDelegationUnbondedEarlyEvent(
+	ctx context.Context, unbondedEarlyEvent *types.BTCDelegationUnbondedEarlyEvent, bbnBlockHeight int64, bbnTx *model.BbnTx,
 ) error {
-	unbondedEarlyEvent, err := parseEvent[*bbntypes.EventBTCDelgationUnbondedEarly](
-		types.EventBTCDelegationUnbondedEarly,
-		event,
-	)
-	if err != nil {
-		return err
-	}
-
-	shouldProcess, err := s.validateBTCDelegationUnbondedEarlyEvent(ctx, unbondedEarlyEvent)
-	if err != nil {
-		return err
-	}
-	if !shouldProcess {
-		// Event is valid but should be skipped
-		return nil
-	}
-
 	delegation, dbErr := s.db.GetBTCDelegationByStakingTxHash(ctx, unbondedEarlyEvent.StakingTxHash)
 	if dbErr != nil {
 		return fmt.Errorf("failed to get BTC delegation by staking tx hash: %w", dbErr)
 	}
 
-	// Emit consumer event
-	if err := s.emitUnbondingDelegationEvent(ctx, delegation); err != nil {
-		return err
-	}
-
 	unbondingStartHeight, parseErr := utils.ParseUint32(unbondedEarlyEvent.StartHeight)
 	if parseErr != nil {
 		return fmt.Errorf("failed to parse start height: %w", parseErr)
 	}
 
-	unbondingBtcTimestamp, err := s.btc.GetBlockTimestamp(ctx, unbondingStartHeight)
+	unbondingBtcTimestamp, err := s.btcBlockTime(ctx, unbondingStartHeight)
 	if err != nil {
 		return fmt.Errorf("failed to get block timestamp: %w", err)
 	}
+	bbnBlockTime, err := s.bbnBlockTime(ctx, bbnBlockHeight)
+	if err != nil {
+		return fmt.Errorf("failed to get block: %w", err)
+	}
 
 	subState := types.SubStateEarlyUnbonding
 	newState := types.StateUnbonding
@@ -300,16 +266,7 @@ func (s *Service) processBTCDelegationUnbondedEarlyEvent(
 	if delegationExpansion {
 		newState = types.StateExpanded
 	} else {
-		// Save timelock expire
 		unbondingExpireHeight = unbondingStartHeight + delegation.UnbondingTime
-		if err := s.db.SaveNewTimeLockExpire(
-			ctx,
-			delegation.StakingTxHashHex,
-			unbondingExpireHeight,
-			subState,
-		); err != nil {
-			return fmt.Errorf("failed to save timelock expire: %w", err)
-		}
 	}
 
 	log := log.Ctx(ctx)
@@ -324,23 +281,35 @@ func (s *Service) processBTCDelegationUnbondedEarlyEvent(
 		Stringer("event_type", types.EventBTCDelegationUnbondedEarly).
 		Msg("updating delegation state")
 
-	// Update delegation state
-	if err := s.db.UpdateBTCDelegationState(
-		ctx,
-		unbondedEarlyEvent.StakingTxHash,
-		types.QualifiedStatesForUnbondedEarly(),
-		newState,
+	updateOpts := []db.UpdateOption{
 		db.WithSubState(subState),
 		db.WithBbnHeight(bbnBlockHeight),
+		db.WithBbnTimestamp(bbnBlockTime),
+		db.WithBtcHeight(unbondingStartHeight),
+		db.WithBtcTimestamp(unbondingBtcTimestamp),
 		db.WithUnbondingBTCTimestamp(unbondingBtcTimestamp),
 		db.WithUnbondingStartHeight(unbondingStartHeight),
 		db.WithBbnEventType(types.EventBTCDelegationUnbondedEarly),
-	); err != nil {
+		db.WithBbnTx(bbnTx),
+	}
+	if delegationExpansion {
+		updateOpts = append(updateOpts, db.WithNextStakingTxHash(unbondedEarlyEvent.StakeExpansionTxHash))
+	}
+
+	// Update delegation state
+	if err := s.updateDelegation(ctx, delegation.StakingTxHashHex, func(current *model.BTCDelegationDetails) error {
+		return s.db.UpdateBTCDelegationState(
+			ctx,
+			unbondedEarlyEvent.StakingTxHash,
+			types.TriggerUnbondedEarly,
+			newState,
+			append(updateOpts, db.WithExpectedVersion(current.Version))...,
+		)
+	}); err != nil {
 		if db.IsNotFoundError(err) {
 			// maybe the btc notifier has already identified the unbonding tx and updated the state
 			log.Debug().
 				Str("staking_tx", delegation.StakingTxHashHex).
-				Interface("qualified_states", types.QualifiedStatesForUnbondedEarly()).
 				Msg("delegation not in qualified states for early unbonding update")
 			return nil
 		}
@@ -348,32 +317,57 @@ func (s *Service) processBTCDelegationUnbondedEarlyEvent(
 		return fmt.Errorf("failed to update BTC delegation state: %w", err)
 	}
 
+	// Emit consumer event
+	if err := s.emitUnbondingDelegationEvent(ctx, delegation); err != nil {
+		return err
+	}
+
+	if !delegationExpansion {
+		// Save timelock expire
+		if err := s.db.SaveNewTimeLockExpire(
+			ctx,
+			delegation.StakingTxHashHex,
+			unbondingExpireHeight,
+			subState,
+		); err != nil {
+			return fmt.Errorf("failed to save timelock expire: %w", err)
+		}
+	}
+
 	return nil
 }
 
-func (s *Service) processBTCDelegationExpiredEvent(
-	ctx context.Context, event abcitypes.Event, bbnBlockHeight int64,
+func (s *Service) applyBTCDelegationExpiredEvent(
+	ctx context.Context, expiredEvent *types.BTCDelegationExpiredEvent, bbnBlockHeight int64, bbnTx *model.BbnTx,
 ) error {
-	expiredEvent, err := parseEvent[*bbntypes.EventBTCDelegationExpired](
-		types.EventBTCDelegationExpired,
-		event,
-	)
-	if err != nil {
-		return err
+	delegation, dbErr := s.db.GetBTCDelegationByStakingTxHash(ctx, expiredEvent.StakingTxHash)
+	if dbErr != nil {
+		return fmt.Errorf("failed to get BTC delegation by staking tx hash: %w", dbErr)
 	}
 
-	shouldProcess, err := s.validateBTCDelegationExpiredEvent(ctx, expiredEvent)
+	subState := types.SubStateTimelock
+
+	bbnBlockTime, err := s.bbnBlockTime(ctx, bbnBlockHeight)
 	if err != nil {
-		return err
-	}
-	if !shouldProcess {
-		// Event is valid but should be skipped
-		return nil
+		return fmt.Errorf("failed to get block: %w", err)
 	}
 
-	delegation, dbErr := s.db.GetBTCDelegationByStakingTxHash(ctx, expiredEvent.StakingTxHash)
-	if dbErr != nil {
-		return fmt.Errorf("failed to get BTC delegation by staking tx hash: %w", dbErr)
+	// Update delegation state
+	if err := s.updateDelegation(ctx, delegation.StakingTxHashHex, func(current *model.BTCDelegationDetails) error {
+		return s.db.UpdateBTCDelegationState(
+			ctx,
+			delegation.StakingTxHashHex,
+			types.TriggerExpired,
+			types.StateUnbonding,
+			db.WithSubState(subState),
+			db.WithBbnHeight(bbnBlockHeight),
+			db.WithBbnTimestamp(bbnBlockTime),
+			db.WithBbnEventType(types.EventBTCDelegationExpired),
+			db.WithBbnTx(bbnTx),
+			db.WithExpectedVersion(current.Version),
+		)
+	}); err != nil {
+		return fmt.Errorf("failed to update BTC delegation state: %w", err)
 	}
 
 	// Emit consumer event
@@ -381,8 +375,6 @@ func (s *Service) processBTCDelegationExpiredEvent(
 		return err
 	}
 
-	subState := types.SubStateTimelock
-
 	// Save timelock expire
 	if err := s.db.SaveNewTimeLockExpire(
 		ctx,
@@ -393,18 +385,5 @@ func (s *Service) processBTCDelegationExpiredEvent(
 		return fmt.Errorf("failed to save timelock expire: %w", err)
 	}
 
-	// Update delegation state
-	if err := s.db.UpdateBTCDelegationState(
-		ctx,
-		delegation.StakingTxHashHex,
-		types.QualifiedStatesForExpired(),
-		types.StateUnbonding,
-		db.WithSubState(subState),
-		db.WithBbnHeight(bbnBlockHeight),
-		db.WithBbnEventType(types.EventBTCDelegationExpired),
-	); err != nil {
-		return fmt.Errorf("failed to update BTC delegation state: %w", err)
-	}
-
 	return nil
 }
@@ -39,7 +39,9 @@ func (s *Service) registerUnbondingSpendNotification(
 		Index: 0, // unbonding tx has only 1 output
 	}
 
+	ctx, done := s.spendWatches.start(ctx, unbondingTx.TxHash().String())
 	go func() {
+		defer done()
 		spendEv, btcErr := s.btcNotifier.RegisterSpendNtfn(
 			&unbondingOutpoint,
 			unbondingTx.TxOut[0].PkScript,
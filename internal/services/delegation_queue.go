@@ -0,0 +1,52 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/utils"
+	abcitypes "github.com/cometbft/cometbft/abci/types"
+)
+
+// sources of the delegation tasks, used as metric labels
+const (
+	delegationTaskSourceBbn = "bbn"
+	delegationTaskSourceBtc = "btc"
+)
+
+// runForDelegation runs fn once all BBN events and BTC spends of the delegation submitted
+// earlier are processed, so they never update the delegation concurrently. Tasks of other
+// delegations don't wait for each other.
+func (s *Service) runForDelegation(ctx context.Context, source, stakingTxHashHex string, fn func() error) error {
+	metrics.AddDelegationQueueDepth(source, 1)
+	defer metrics.AddDelegationQueueDepth(source, -1)
+
+	submittedAt := time.Now()
+	return s.delegationExecutor.Do(ctx, stakingTxHashHex, func() error {
+		metrics.RecordDelegationQueueWait(source, time.Since(submittedAt))
+		return fn()
+	})
+}
+
+// eventStakingTxHash returns staking tx hash of the delegation the BBN event belongs to
+// or empty string if the event isn't related to a delegation
+func eventStakingTxHash(event abcitypes.Event) string {
+	for _, attr := range event.Attributes {
+		value := strings.Trim(attr.Value, `"`)
+		switch attr.Key {
+		case "staking_tx_hash":
+			return value
+		case "staking_tx_hex":
+			// new delegation event only has the staking tx, invalid staking tx is reported by the event processing
+			stakingTx, err := utils.DeserializeBtcTransactionFromHex(value)
+			if err != nil {
+				return ""
+			}
+			return stakingTx.TxHash().String()
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/btcsuite/btcd/wire"
+	abcitypes "github.com/cometbft/cometbft/abci/types"
+	notifier "github.com/lightningnetwork/lnd/chainntnfs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// testDelegationEvent is BBN event of the delegation without processor, so processing it only
+// goes through the delegation queue
+func testDelegationEvent(stakingTxHash string) BbnEvent {
+	return NewBbnEvent(TxCategory, abcitypes.Event{
+		Type: "test.EventDelegation",
+		Attributes: []abcitypes.EventAttribute{
+			{Key: "staking_tx_hash", Value: fmt.Sprintf("%q", stakingTxHash)},
+		},
+	})
+}
+
+func runProcessEvent(s *Service, event BbnEvent) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = s.processEvent(context.Background(), event, 1)
+	}()
+	return done
+}
+
+func waitQueueDepth(t *testing.T, s *Service, stakingTxHash string, depth int) {
+	require.Eventually(t, func() bool {
+		return s.delegationExecutor.QueueDepth(stakingTxHash) == depth
+	}, time.Second, time.Millisecond)
+}
+
+func TestDelegationQueue(t *testing.T) {
+	active := &model.BTCDelegationDetails{
+		StakingTxHashHex: testStakingTxHash,
+		State:            types.StateActive,
+	}
+
+	t.Run("BBN event waits for BTC spend of the delegation", func(t *testing.T) {
+		s, m := newTestService(t, provisionalSpendTestConfig)
+		m.db.On("GetBTCDelegationByStakingTxHash", mock.Anything, testStakingTxHash).Return(active, nil).Twice()
+
+		handling := make(chan struct{})
+		release := make(chan struct{})
+		handler := func(context.Context, *notifier.SpendDetail) error {
+			close(handling)
+			<-release
+			return nil
+		}
+		spendEvent := notifier.NewSpendEvent(nil)
+		watchDone := runWatchSpend(s, spendEvent, handler)
+		spendEvent.Spend <- testSpendDetail(100)
+		<-handling
+
+		eventDone := runProcessEvent(s, testDelegationEvent(testStakingTxHash))
+		waitQueueDepth(t, s, testStakingTxHash, 2)
+
+		// event of another delegation is not blocked
+		<-runProcessEvent(s, testDelegationEvent("other"))
+
+		select {
+		case <-eventDone:
+			t.Fatal("event processed while spend is being handled")
+		default:
+		}
+
+		close(release)
+		waitDone(t, watchDone)
+		waitDone(t, eventDone)
+		waitQueueDepth(t, s, testStakingTxHash, 0)
+	})
+	t.Run("BTC spend waits for BBN event of the delegation", func(t *testing.T) {
+		s, m := newTestService(t, provisionalSpendTestConfig)
+
+		processing := make(chan struct{})
+		release := make(chan struct{})
+		eventDone := make(chan struct{})
+		go func() {
+			defer close(eventDone)
+			_ = s.runForDelegation(context.Background(), delegationTaskSourceBbn, testStakingTxHash, func() error {
+				close(processing)
+				<-release
+				return nil
+			})
+		}()
+		<-processing
+
+		spendEvent := notifier.NewSpendEvent(nil)
+		watchDone := runWatchSpend(s, spendEvent, func(context.Context, *notifier.SpendDetail) error { return nil })
+		spendEvent.Spend <- testSpendDetail(100)
+		waitQueueDepth(t, s, testStakingTxHash, 2)
+		m.db.AssertNotCalled(t, "GetBTCDelegationByStakingTxHash", mock.Anything, testStakingTxHash)
+
+		m.db.On("GetBTCDelegationByStakingTxHash", mock.Anything, testStakingTxHash).Return(active, nil).Twice()
+		close(release)
+		waitDone(t, eventDone)
+		waitDone(t, watchDone)
+	})
+}
+
+func TestEventStakingTxHash(t *testing.T) {
+	stakingTx := wire.NewMsgTx(2)
+	stakingTx.AddTxIn(wire.NewTxIn(&wire.OutPoint{}, nil, nil))
+	stakingTx.AddTxOut(wire.NewTxOut(1000, []byte{0x51}))
+	var buf bytes.Buffer
+	require.NoError(t, stakingTx.Serialize(&buf))
+
+	cases := []struct {
+		name     string
+		event    abcitypes.Event
+		expected string
+	}{
+		{
+			name: "delegation event",
+			event: abcitypes.Event{
+				Type:       string(types.EventCovenantQuorumReached),
+				Attributes: []abcitypes.EventAttribute{{Key: "staking_tx_hash", Value: `"` + testStakingTxHash + `"`}},
+			},
+			expected: testStakingTxHash,
+		},
+		{
+			name: "new delegation event",
+			event: abcitypes.Event{
+				Type: string(types.EventBTCDelegationCreated),
+				Attributes: []abcitypes.EventAttribute{
+					{Key: "staking_tx_hex", Value: `"` + hex.EncodeToString(buf.Bytes()) + `"`},
+				},
+			},
+			expected: stakingTx.TxHash().String(),
+		},
+		{
+			name: "finality provider event",
+			event: abcitypes.Event{
+				Type:       string(types.EventFinalityProviderCreatedType),
+				Attributes: []abcitypes.EventAttribute{{Key: "btc_pk_hex", Value: `"abcd"`}},
+			},
+			expected: "",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, eventStakingTxHash(tc.event))
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	abcitypes "github.com/cometbft/cometbft/abci/types"
+	"github.com/rs/zerolog/log"
+)
+
+// eventSchema describes how the event differs in an older schema version from the latest one.
+// Babylon emits every attribute of its event schema, attributes of the latest schema missing
+// from the event fail the decoding unless its schema doesn't have them.
+type eventSchema struct {
+	// missingAttributes of the latest schema the event doesn't have, they are left empty when decoded
+	missingAttributes []string
+}
+
+// schemas of versions the events processed by the indexer differ in, events without
+// schemas have every attribute of the latest one in every version
+var (
+	btcDelegationCreatedSchemas = map[types.EventSchemaVersion]eventSchema{
+		types.EventSchemaV1: {missingAttributes: []string{"previous_staking_tx_hash_hex"}},
+	}
+	covenantSignatureReceivedSchemas = map[types.EventSchemaVersion]eventSchema{
+		types.EventSchemaV1: {missingAttributes: []string{"covenant_stake_expansion_signature_hex"}},
+	}
+	btcDelegationUnbondedEarlySchemas = map[types.EventSchemaVersion]eventSchema{
+		types.EventSchemaV1: {missingAttributes: []string{"stake_expansion_tx_hash"}},
+	}
+)
+
+// eventDecoder decodes the event of every schema version into the indexer struct T
+type eventDecoder[T types.EventMessage] struct {
+	eventType types.EventType
+	// attributes of the latest schema, json tags of T fields
+	attributes []string
+	versions   *eventSchemas
+	// schemas of versions the event differs in, other versions decode as the latest one
+	schemas map[types.EventSchemaVersion]eventSchema
+}
+
+// newEventDecoder returns decoder of events into the struct pointer T, versions pick the schema by BBN height
+func newEventDecoder[T types.EventMessage](
+	versions *eventSchemas, schemas map[types.EventSchemaVersion]eventSchema,
+) *eventDecoder[T] {
+	var msg T
+	structType := reflect.TypeOf(msg).Elem()
+	attributes := make([]string, 0, structType.NumField())
+	for i := range structType.NumField() {
+		name, _, _ := strings.Cut(structType.Field(i).Tag.Get("json"), ",")
+		attributes = append(attributes, name)
+	}
+
+	return &eventDecoder[T]{
+		eventType:  msg.EventType(),
+		attributes: attributes,
+		versions:   versions,
+		schemas:    schemas,
+	}
+}
+
+// eventSchemas picks schema version of the events by BBN height
+type eventSchemas struct {
+	// upgrades ordered by height
+	upgrades []config.EventSchemaUpgrade
+}
+
+func newEventSchemas(upgrades []config.EventSchemaUpgrade) *eventSchemas {
+	upgrades = append([]config.EventSchemaUpgrade(nil), upgrades...)
+	sort.Slice(upgrades, func(i, j int) bool {
+		return upgrades[i].Height < upgrades[j].Height
+	})
+	return &eventSchemas{upgrades: upgrades}
+}
+
+// versionAt returns schema version of the events emitted at the height
+func (s *eventSchemas) versionAt(height int64) types.EventSchemaVersion {
+	if s == nil || len(s.upgrades) == 0 {
+		return types.LatestEventSchema
+	}
+
+	// the chain started with the first schema version
+	version := types.EventSchemaVersions[0]
+	for _, upgrade := range s.upgrades {
+		if upgrade.Height > height {
+			break
+		}
+		version = upgrade.Version
+	}
+	return version
+}
+
+// decode decodes the event emitted at the BBN height with the schema of the height
+func (d *eventDecoder[T]) decode(ctx context.Context, event abcitypes.Event, height int64) (T, error) {
+	var result T
+	expectedType := d.eventType
+
+	// Check if the event type matches the expected type
+	if types.EventType(event.Type) != expectedType {
+		return result, fmt.Errorf(
+			"unexpected event type: %s received when processing %s",
+			event.Type,
+			expectedType,
+		)
+	}
+
+	// Check if the event has attributes
+	if len(event.Attributes) == 0 {
+		return result, fmt.Errorf(
+			"no attributes found in the %s event",
+			expectedType,
+		)
+	}
+
+	// Sanitize the event attributes before parsing
+	sanitizedEvent := sanitizeEvent(event)
+	attributes := make(map[string]json.RawMessage, len(sanitizedEvent.Attributes))
+	for _, attr := range sanitizedEvent.Attributes {
+		attributes[attr.Key] = json.RawMessage(attr.Value)
+	}
+
+	version := d.versions.versionAt(height)
+	schema := d.schemas[version]
+	var missing, unexpected []string
+	for _, name := range d.attributes {
+		_, ok := attributes[name]
+		switch {
+		case !ok && !slices.Contains(schema.missingAttributes, name):
+			missing = append(missing, name)
+		case ok && slices.Contains(schema.missingAttributes, name):
+			unexpected = append(unexpected, name)
+		}
+	}
+	if len(missing) > 0 {
+		return result, fmt.Errorf(
+			"%s event doesn't have attributes %v of schema %s, check event schema upgrades",
+			expectedType, missing, version,
+		)
+	}
+	if len(unexpected) > 0 {
+		// attributes of newer schema usually mean the schema upgrades are misconfigured,
+		// they are decoded as the latest schema has them
+		log.Ctx(ctx).Warn().
+			Stringer("event_type", expectedType).
+			Int64("height", height).
+			Str("schema", string(version)).
+			Strs("attributes", unexpected).
+			Msg("Event has attributes its schema doesn't have, check event schema upgrades")
+	}
+
+	// attributes of other schemas are ignored
+	data, err := json.Marshal(attributes)
+	if err != nil {
+		return result, fmt.Errorf("failed to encode %s event attributes: %w", expectedType, err)
+	}
+	msg := reflect.New(reflect.TypeOf(result).Elem()).Interface().(T)
+	if err := json.Unmarshal(data, msg); err != nil {
+		log.Ctx(ctx).Debug().Interface("raw_event", event).Msg("Raw event data")
+		return result, fmt.Errorf("failed to parse %s event with schema %s: %w", expectedType, version, err)
+	}
+
+	return msg, nil
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	bstypes "github.com/babylonlabs-io/babylon/v4/x/btcstaking/types"
+	abcitypes "github.com/cometbft/cometbft/abci/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	proto "github.com/cosmos/gogoproto/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// eventSchemaFixture is an event as emitted by Babylon with the schema version and its decoded message
+type eventSchemaFixture struct {
+	Name     string          `json:"name"`
+	Height   int64           `json:"height"`
+	Event    abcitypes.Event `json:"event"`
+	Expected json.RawMessage `json:"expected"`
+}
+
+// testEventSchemaUpgrades are the upgrades fixtures heights are chosen for
+var testEventSchemaUpgrades = []config.EventSchemaUpgrade{
+	{Height: 1000, Version: types.EventSchemaV2},
+	{Height: 0, Version: types.EventSchemaV1},
+}
+
+func loadEventSchemaFixtures(t *testing.T, version types.EventSchemaVersion) []eventSchemaFixture {
+	t.Helper()
+
+	data, err := os.ReadFile(fmt.Sprintf("./testdata/event_schemas/%s.json", version))
+	require.NoError(t, err)
+
+	var fixtures []eventSchemaFixture
+	require.NoError(t, json.Unmarshal(data, &fixtures))
+	require.NotEmpty(t, fixtures)
+	return fixtures
+}
+
+// decodeTestEvent decodes the event into the struct of its type
+func decodeTestEvent(
+	ctx context.Context, schemas *eventSchemas, event abcitypes.Event, height int64,
+) (types.EventMessage, error) {
+	eventType := types.EventType(event.Type)
+	switch eventType {
+	case types.EventBTCDelegationCreated:
+		return newEventDecoder[*types.BTCDelegationCreatedEvent](schemas, btcDelegationCreatedSchemas).
+			decode(ctx, event, height)
+	case types.EventCovenantSignatureReceived:
+		return newEventDecoder[*types.CovenantSignatureReceivedEvent](schemas, covenantSignatureReceivedSchemas).
+			decode(ctx, event, height)
+	case types.EventCovenantQuorumReached:
+		return newEventDecoder[*types.CovenantQuorumReachedEvent](schemas, nil).decode(ctx, event, height)
+	case types.EventBTCDelegationInclusionProofReceived:
+		return newEventDecoder[*types.BTCDelegationInclusionProofReceivedEvent](schemas, nil).decode(ctx, event, height)
+	case types.EventBTCDelegationUnbondedEarly:
+		return newEventDecoder[*types.BTCDelegationUnbondedEarlyEvent](schemas, btcDelegationUnbondedEarlySchemas).
+			decode(ctx, event, height)
+	default:
+		return nil, fmt.Errorf("unexpected event type %s", eventType)
+	}
+}
+
+func TestParseEventSchemas(t *testing.T) {
+	ctx := context.Background()
+	schemas := newEventSchemas(testEventSchemaUpgrades)
+
+	for _, version := range types.EventSchemaVersions {
+		t.Run(string(version), func(t *testing.T) {
+			for _, fixture := range loadEventSchemaFixtures(t, version) {
+				require.Equal(t, version, schemas.versionAt(fixture.Height), fixture.Name)
+
+				msg, err := decodeTestEvent(ctx, schemas, fixture.Event, fixture.Height)
+				require.NoError(t, err, fixture.Name)
+				assert.Equal(t, types.EventType(fixture.Event.Type), msg.EventType(), fixture.Name)
+
+				actual, err := json.Marshal(msg)
+				require.NoError(t, err)
+				assert.JSONEq(t, string(fixture.Expected), string(actual), fixture.Name)
+			}
+		})
+	}
+
+	t.Run("newer schema event at older schema height", func(t *testing.T) {
+		// attributes of the newer schema are decoded anyway
+		for _, fixture := range loadEventSchemaFixtures(t, types.EventSchemaV2) {
+			msg, err := decodeTestEvent(ctx, schemas, fixture.Event, 500)
+			require.NoError(t, err, fixture.Name)
+
+			actual, err := json.Marshal(msg)
+			require.NoError(t, err)
+			assert.JSONEq(t, string(fixture.Expected), string(actual), fixture.Name)
+		}
+	})
+	t.Run("older schema event at latest schema height", func(t *testing.T) {
+		// attributes the latest schema added are missing, e.g. delegation expanding another
+		// one must not be decoded as a new delegation
+		for _, fixture := range loadEventSchemaFixtures(t, types.EventSchemaV1) {
+			_, err := decodeTestEvent(ctx, schemas, fixture.Event, 1500)
+			if types.EventType(fixture.Event.Type) == types.EventCovenantQuorumReached {
+				// the event is the same in both schemas
+				require.NoError(t, err, fixture.Name)
+				continue
+			}
+			require.ErrorContains(t, err, "check event schema upgrades", fixture.Name)
+		}
+	})
+	t.Run("older schema event without schema table", func(t *testing.T) {
+		for _, fixture := range loadEventSchemaFixtures(t, types.EventSchemaV1) {
+			if types.EventType(fixture.Event.Type) != types.EventCovenantSignatureReceived {
+				continue
+			}
+
+			decoder := newEventDecoder[*types.CovenantSignatureReceivedEvent](schemas, nil)
+			_, err := decoder.decode(ctx, fixture.Event, fixture.Height)
+			require.ErrorContains(t, err, "covenant_stake_expansion_signature_hex", fixture.Name)
+		}
+	})
+	t.Run("event of other type", func(t *testing.T) {
+		decoder := newEventDecoder[*types.BTCDelegationExpiredEvent](schemas, nil)
+		assert.Equal(t, types.EventBTCDelegationExpired, decoder.eventType)
+
+		event := abcitypes.Event{
+			Type:       string(types.EventCovenantQuorumReached),
+			Attributes: []abcitypes.EventAttribute{{Key: "staking_tx_hash", Value: `"2a"`}},
+		}
+		_, err := decoder.decode(ctx, event, 1500)
+		require.ErrorContains(t, err, "unexpected event type")
+	})
+}
+
+// TestEventDecoderAttributes checks the latest schema is the one of the Babylon version
+// the indexer is built with, babylon encodes the events as JSON of their messages
+func TestEventDecoderAttributes(t *testing.T) {
+	tests := []struct {
+		attributes []string
+		msg        proto.Message
+	}{
+		{newEventDecoder[*types.FinalityProviderCreatedEvent](nil, nil).attributes, &bstypes.EventFinalityProviderCreated{}},
+		{newEventDecoder[*types.FinalityProviderEditedEvent](nil, nil).attributes, &bstypes.EventFinalityProviderEdited{}},
+		{newEventDecoder[*types.FinalityProviderStatusChangeEvent](nil, nil).attributes, &bstypes.EventFinalityProviderStatusChange{}},
+		{newEventDecoder[*types.BTCDelegationCreatedEvent](nil, nil).attributes, &bstypes.EventBTCDelegationCreated{}},
+		{newEventDecoder[*types.CovenantSignatureReceivedEvent](nil, nil).attributes, &bstypes.EventCovenantSignatureReceived{}},
+		{newEventDecoder[*types.CovenantQuorumReachedEvent](nil, nil).attributes, &bstypes.EventCovenantQuorumReached{}},
+		{
+			newEventDecoder[*types.BTCDelegationInclusionProofReceivedEvent](nil, nil).attributes,
+			&bstypes.EventBTCDelegationInclusionProofReceived{},
+		},
+		{newEventDecoder[*types.BTCDelegationUnbondedEarlyEvent](nil, nil).attributes, &bstypes.EventBTCDelgationUnbondedEarly{}},
+		{newEventDecoder[*types.BTCDelegationExpiredEvent](nil, nil).attributes, &bstypes.EventBTCDelegationExpired{}},
+	}
+	for _, tt := range tests {
+		event, err := sdk.TypedEventToEvent(tt.msg)
+		require.NoError(t, err)
+
+		var emitted []string
+		for _, attr := range event.Attributes {
+			emitted = append(emitted, attr.Key)
+		}
+		assert.ElementsMatch(t, emitted, tt.attributes, event.Type)
+	}
+}
+
+func TestEventSchemasVersionAt(t *testing.T) {
+	var noUpgrades *eventSchemas
+	assert.Equal(t, types.LatestEventSchema, noUpgrades.versionAt(100))
+	assert.Equal(t, types.LatestEventSchema, newEventSchemas(nil).versionAt(100))
+
+	schemas := newEventSchemas([]config.EventSchemaUpgrade{{Height: 1000, Version: types.EventSchemaV2}})
+	// the chain started with the first schema
+	assert.Equal(t, types.EventSchemaV1, schemas.versionAt(1))
+	assert.Equal(t, types.EventSchemaV1, schemas.versionAt(999))
+	assert.Equal(t, types.EventSchemaV2, schemas.versionAt(1000))
+	assert.Equal(t, types.EventSchemaV2, schemas.versionAt(5000))
+}
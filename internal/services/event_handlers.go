@@ -0,0 +1,267 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	abcitypes "github.com/cometbft/cometbft/abci/types"
+	"github.com/rs/zerolog/log"
+)
+
+// babylonEventPrefix is the prefix of events emitted by Babylon modules, only such
+// events without a handler are reported as unhandled
+const babylonEventPrefix = "babylon."
+
+// EventHandler processes BBN events of a single type
+type EventHandler interface {
+	EventType() types.EventType
+	// Handle processes the event emitted at the BBN height. It returns the decoded
+	// message or nil if the event was ignored.
+	Handle(ctx context.Context, event BbnEvent, blockHeight int64) (types.EventMessage, error)
+}
+
+// EventObserver is notified after the handler of its event type processed the event. Events
+// are replayed on failures, so an observer can be notified about the same event again.
+type EventObserver func(ctx context.Context, event BbnEvent, blockHeight int64, msg types.EventMessage)
+
+// TypedEventHandler is EventHandler of events decoded into struct T
+type TypedEventHandler[T types.EventMessage] struct {
+	Type types.EventType
+	// Decode decodes the event emitted at the BBN height
+	Decode func(ctx context.Context, event abcitypes.Event, blockHeight int64) (T, error)
+	// Validate returns false if the event should be ignored, nil Validate accepts every event
+	Validate func(ctx context.Context, msg T) (bool, error)
+	// Apply processes the decoded and validated event
+	Apply func(ctx context.Context, msg T, blockHeight int64, bbnTx *model.BbnTx) error
+}
+
+func (h *TypedEventHandler[T]) EventType() types.EventType {
+	return h.Type
+}
+
+func (h *TypedEventHandler[T]) Handle(ctx context.Context, event BbnEvent, blockHeight int64) (types.EventMessage, error) {
+	msg, err := h.Decode(ctx, event.Event, blockHeight)
+	if err != nil {
+		return nil, err
+	}
+
+	if h.Validate != nil {
+		shouldProcess, err := h.Validate(ctx, msg)
+		if err != nil {
+			return nil, err
+		}
+		if !shouldProcess {
+			// Ignore the event silently
+			return nil, nil
+		}
+	}
+
+	if err := h.Apply(ctx, msg, blockHeight, event.Tx); err != nil {
+		return nil, err
+	}
+
+	return msg, nil
+}
+
+// EventHandlerRegistry dispatches BBN events to the handler registered for their type
+type EventHandlerRegistry struct {
+	mu        sync.RWMutex
+	handlers  map[types.EventType]EventHandler
+	observers map[types.EventType][]EventObserver
+}
+
+func NewEventHandlerRegistry() *EventHandlerRegistry {
+	return &EventHandlerRegistry{
+		handlers:  make(map[types.EventType]EventHandler),
+		observers: make(map[types.EventType][]EventObserver),
+	}
+}
+
+// Register adds the handler, there can be only one handler of every event type
+func (r *EventHandlerRegistry) Register(handler EventHandler) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	eventType := handler.EventType()
+	if eventType == "" {
+		return fmt.Errorf("handler of %T has no event type", handler)
+	}
+	if _, ok := r.handlers[eventType]; ok {
+		return fmt.Errorf("handler of %s event is already registered", eventType)
+	}
+	r.handlers[eventType] = handler
+	return nil
+}
+
+// Observe adds the observer of events of the type, observers of event types
+// without a handler are never notified
+func (r *EventHandlerRegistry) Observe(eventType types.EventType, observer EventObserver) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.observers[eventType] = append(r.observers[eventType], observer)
+}
+
+// IsHandled returns true if there is a handler of the event type
+func (r *EventHandlerRegistry) IsHandled(eventType types.EventType) bool {
+	if r == nil {
+		return false
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, ok := r.handlers[eventType]
+	return ok
+}
+
+// Dispatch processes the event with the handler of its type and notifies the observers.
+// It returns false if there is no handler of the event type.
+func (r *EventHandlerRegistry) Dispatch(ctx context.Context, event BbnEvent, blockHeight int64) (bool, error) {
+	if r == nil {
+		return false, nil
+	}
+
+	eventType := types.EventType(event.Event.Type)
+
+	r.mu.RLock()
+	handler, ok := r.handlers[eventType]
+	observers := r.observers[eventType]
+	r.mu.RUnlock()
+	if !ok {
+		return false, nil
+	}
+
+	msg, err := handler.Handle(ctx, event, blockHeight)
+	if err != nil {
+		return true, err
+	}
+	if msg == nil {
+		return true, nil
+	}
+
+	for _, observer := range observers {
+		observer(ctx, event, blockHeight, msg)
+	}
+	return true, nil
+}
+
+// unhandledEventTypes counts Babylon events without a handler by their type
+func (r *EventHandlerRegistry) unhandledEventTypes(events []BbnEvent) map[string]int {
+	unhandled := make(map[string]int)
+	for _, event := range events {
+		eventType := event.Event.Type
+		if !strings.HasPrefix(eventType, babylonEventPrefix) || r.IsHandled(types.EventType(eventType)) {
+			continue
+		}
+		unhandled[eventType]++
+	}
+	return unhandled
+}
+
+// recordUnhandledEvents reports Babylon events of the block nothing is registered to handle,
+// such events are usually emitted by a newer Babylon version
+func (s *Service) recordUnhandledEvents(ctx context.Context, blockHeight int64, events []BbnEvent) {
+	for eventType, count := range s.eventHandlers.unhandledEventTypes(events) {
+		log.Ctx(ctx).Debug().
+			Str("event_type", eventType).
+			Int("count", count).
+			Int64("block_height", blockHeight).
+			Msg("No handler registered for event")
+		metrics.IncUnhandledBbnEvents(eventType, count)
+	}
+}
+
+// newSchemaEventHandler returns handler of events the decoder decodes with the schema of their height
+func newSchemaEventHandler[T types.EventMessage](
+	decoder *eventDecoder[T],
+	validate func(ctx context.Context, msg T) (bool, error),
+	apply func(ctx context.Context, msg T, blockHeight int64, bbnTx *model.BbnTx) error,
+) *TypedEventHandler[T] {
+	return &TypedEventHandler[T]{
+		Type:     decoder.eventType,
+		Decode:   decoder.decode,
+		Validate: validate,
+		Apply:    apply,
+	}
+}
+
+// rejectInvalid adapts validation that only rejects invalid events
+func rejectInvalid[T types.EventMessage](validate func(msg T) error) func(context.Context, T) (bool, error) {
+	return func(_ context.Context, msg T) (bool, error) {
+		if err := validate(msg); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+}
+
+// newEventHandlerRegistry returns registry with handlers of the events processed by the indexer
+func (s *Service) newEventHandlerRegistry() *EventHandlerRegistry {
+	registry := NewEventHandlerRegistry()
+	handlers := []EventHandler{
+		newSchemaEventHandler(
+			newEventDecoder[*types.FinalityProviderCreatedEvent](s.eventSchemas, nil),
+			rejectInvalid(s.validateFinalityProviderCreatedEvent),
+			s.applyNewFinalityProviderEvent,
+		),
+		newSchemaEventHandler(
+			newEventDecoder[*types.FinalityProviderEditedEvent](s.eventSchemas, nil),
+			rejectInvalid(s.validateFinalityProviderEditedEvent),
+			s.applyFinalityProviderEditedEvent,
+		),
+		newSchemaEventHandler(
+			newEventDecoder[*types.FinalityProviderStatusChangeEvent](s.eventSchemas, nil),
+			s.validateFinalityProviderStateChangeEvent,
+			s.applyFinalityProviderStateChangeEvent,
+		),
+		newSchemaEventHandler(
+			newEventDecoder[*types.BTCDelegationCreatedEvent](s.eventSchemas, btcDelegationCreatedSchemas),
+			rejectInvalid(s.validateBTCDelegationCreatedEvent),
+			s.applyNewBTCDelegationEvent,
+		),
+		newSchemaEventHandler(
+			newEventDecoder[*types.CovenantQuorumReachedEvent](s.eventSchemas, nil),
+			s.validateCovenantQuorumReachedEvent,
+			s.applyCovenantQuorumReachedEvent,
+		),
+		newSchemaEventHandler(
+			newEventDecoder[*types.CovenantSignatureReceivedEvent](s.eventSchemas, covenantSignatureReceivedSchemas),
+			nil,
+			s.applyCovenantSignatureReceivedEvent,
+		),
+		newSchemaEventHandler(
+			newEventDecoder[*types.BTCDelegationInclusionProofReceivedEvent](s.eventSchemas, nil),
+			s.validateBTCDelegationInclusionProofReceivedEvent,
+			s.applyBTCDelegationInclusionProofReceivedEvent,
+		),
+		newSchemaEventHandler(
+			newEventDecoder[*types.BTCDelegationUnbondedEarlyEvent](s.eventSchemas, btcDelegationUnbondedEarlySchemas),
+			s.validateBTCDelegationUnbondedEarlyEvent,
+			s.applyBTCDelegationUnbondedEarlyEvent,
+		),
+		newSchemaEventHandler(
+			newEventDecoder[*types.BTCDelegationExpiredEvent](s.eventSchemas, nil),
+			s.validateBTCDelegationExpiredEvent,
+			s.applyBTCDelegationExpiredEvent,
+		),
+	}
+	for _, handler := range handlers {
+		if err := registry.Register(handler); err != nil {
+			panic(err)
+		}
+	}
+
+	return registry
+}
+
+// EventHandlers returns the registry BBN events are dispatched through, additional
+// handlers and observers have to be registered before the indexer sync is started
+func (s *Service) EventHandlers() *EventHandlerRegistry {
+	return s.eventHandlers
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	abcitypes "github.com/cometbft/cometbft/abci/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventHandlerRegistry(t *testing.T) {
+	ctx := context.Background()
+	const height = int64(100)
+	expiredEvent := BbnEvent{
+		Event: abcitypes.Event{Type: string(types.EventBTCDelegationExpired)},
+		Tx:    &model.BbnTx{Hash: "tx_hash"},
+	}
+
+	// newHandler returns handler of expired events, apply records the applied messages
+	newHandler := func(accept bool, applyErr error, applied *[]string) *TypedEventHandler[*types.BTCDelegationExpiredEvent] {
+		return &TypedEventHandler[*types.BTCDelegationExpiredEvent]{
+			Type: types.EventBTCDelegationExpired,
+			Decode: func(_ context.Context, _ abcitypes.Event, _ int64) (*types.BTCDelegationExpiredEvent, error) {
+				return &types.BTCDelegationExpiredEvent{StakingTxHash: "staking_tx"}, nil
+			},
+			Validate: func(_ context.Context, _ *types.BTCDelegationExpiredEvent) (bool, error) {
+				return accept, nil
+			},
+			Apply: func(_ context.Context, msg *types.BTCDelegationExpiredEvent, blockHeight int64, bbnTx *model.BbnTx) error {
+				assert.Equal(t, height, blockHeight)
+				assert.Equal(t, expiredEvent.Tx, bbnTx)
+				*applied = append(*applied, msg.StakingTxHash)
+				return applyErr
+			},
+		}
+	}
+
+	t.Run("duplicate handler", func(t *testing.T) {
+		var applied []string
+		registry := NewEventHandlerRegistry()
+		require.NoError(t, registry.Register(newHandler(true, nil, &applied)))
+		require.ErrorContains(t, registry.Register(newHandler(true, nil, &applied)), "already registered")
+	})
+	t.Run("handler without event type", func(t *testing.T) {
+		var applied []string
+		handler := newHandler(true, nil, &applied)
+		handler.Type = ""
+		require.ErrorContains(t, NewEventHandlerRegistry().Register(handler), "has no event type")
+	})
+	t.Run("unhandled event", func(t *testing.T) {
+		registry := NewEventHandlerRegistry()
+		handled, err := registry.Dispatch(ctx, expiredEvent, height)
+		require.NoError(t, err)
+		assert.False(t, handled)
+
+		var noRegistry *EventHandlerRegistry
+		handled, err = noRegistry.Dispatch(ctx, expiredEvent, height)
+		require.NoError(t, err)
+		assert.False(t, handled)
+	})
+	t.Run("observers are notified", func(t *testing.T) {
+		var applied []string
+		registry := NewEventHandlerRegistry()
+		require.NoError(t, registry.Register(newHandler(true, nil, &applied)))
+
+		var observed []types.EventMessage
+		registry.Observe(types.EventBTCDelegationExpired, func(_ context.Context, event BbnEvent, blockHeight int64, msg types.EventMessage) {
+			assert.Equal(t, expiredEvent, event)
+			assert.Equal(t, height, blockHeight)
+			observed = append(observed, msg)
+		})
+
+		handled, err := registry.Dispatch(ctx, expiredEvent, height)
+		require.NoError(t, err)
+		assert.True(t, handled)
+		assert.Equal(t, []string{"staking_tx"}, applied)
+		require.Len(t, observed, 1)
+		assert.Equal(t, &types.BTCDelegationExpiredEvent{StakingTxHash: "staking_tx"}, observed[0])
+	})
+	t.Run("ignored event", func(t *testing.T) {
+		var applied []string
+		registry := NewEventHandlerRegistry()
+		require.NoError(t, registry.Register(newHandler(false, nil, &applied)))
+		registry.Observe(types.EventBTCDelegationExpired, func(context.Context, BbnEvent, int64, types.EventMessage) {
+			t.Fatal("observer of ignored event must not be notified")
+		})
+
+		handled, err := registry.Dispatch(ctx, expiredEvent, height)
+		require.NoError(t, err)
+		assert.True(t, handled)
+		assert.Empty(t, applied)
+	})
+	t.Run("failed event", func(t *testing.T) {
+		var applied []string
+		applyErr := errors.New("apply failed")
+		registry := NewEventHandlerRegistry()
+		require.NoError(t, registry.Register(newHandler(true, applyErr, &applied)))
+		registry.Observe(types.EventBTCDelegationExpired, func(context.Context, BbnEvent, int64, types.EventMessage) {
+			t.Fatal("observer of failed event must not be notified")
+		})
+
+		handled, err := registry.Dispatch(ctx, expiredEvent, height)
+		require.ErrorIs(t, err, applyErr)
+		assert.True(t, handled)
+	})
+}
+
+func TestServiceEventHandlers(t *testing.T) {
+	s := &Service{}
+	registry := s.newEventHandlerRegistry()
+
+	// every event type processed by the indexer has a handler
+	for _, eventType := range []types.EventType{
+		types.EventFinalityProviderCreatedType,
+		types.EventFinalityProviderEditedType,
+		types.EventFinalityProviderStatusChange,
+		types.EventBTCDelegationCreated,
+		types.EventCovenantQuorumReached,
+		types.EventCovenantSignatureReceived,
+		types.EventBTCDelegationInclusionProofReceived,
+		types.EventBTCDelegationUnbondedEarly,
+		types.EventBTCDelegationExpired,
+	} {
+		assert.True(t, registry.IsHandled(eventType), eventType)
+	}
+}
+
+func TestUnhandledEventTypes(t *testing.T) {
+	s := &Service{}
+	s.eventHandlers = s.newEventHandlerRegistry()
+
+	events := []BbnEvent{
+		{Event: abcitypes.Event{Type: string(types.EventBTCDelegationCreated)}},
+		{Event: abcitypes.Event{Type: "babylon.btcstaking.v1.EventPowerDistUpdate"}},
+		{Event: abcitypes.Event{Type: "babylon.btcstaking.v1.EventPowerDistUpdate"}},
+		{Event: abcitypes.Event{Type: "babylon.finality.v1.EventJailedFinalityProvider"}},
+		// events of other modules are expected to be unhandled
+		{Event: abcitypes.Event{Type: "coin_received"}},
+	}
+	assert.Equal(t, map[string]int{
+		"babylon.btcstaking.v1.EventPowerDistUpdate":      2,
+		"babylon.finality.v1.EventJailedFinalityProvider": 1,
+	}, s.eventHandlers.unhandledEventTypes(events))
+}
@@ -4,11 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"slices"
 	"strings"
 	"time"
 
 	"github.com/avast/retry-go/v4"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/tracing"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
@@ -16,7 +16,6 @@ import (
 	bstypes "github.com/babylonlabs-io/babylon/v4/x/btcstaking/types"
 	abcitypes "github.com/cometbft/cometbft/abci/types"
 	sdk "github.com/cosmos/cosmos-sdk/types"
-	proto "github.com/cosmos/gogoproto/proto"
 	"github.com/rs/zerolog/log"
 )
 
@@ -25,11 +24,16 @@ const (
 	TxCategory    types.EventCategory = "tx"
 
 	processEventMaxRetries = 3
+
+	// attribute SDK adds to every event emitted by the tx message
+	msgIndexAttributeKey = "msg_index"
 )
 
 type BbnEvent struct {
 	Category types.EventCategory
 	Event    abcitypes.Event
+	// Tx that emitted the event, nil for block events
+	Tx *model.BbnTx
 }
 
 func NewBbnEvent(category types.EventCategory, event abcitypes.Event) BbnEvent {
@@ -39,6 +43,53 @@ func NewBbnEvent(category types.EventCategory, event abcitypes.Event) BbnEvent {
 	}
 }
 
+// newTxEvents converts events emitted by the tx at txIndex in the block, every event
+// is assigned the sender of the message that emitted it
+func newTxEvents(txHash string, txIndex uint32, txEvents []abcitypes.Event) []BbnEvent {
+	senders := msgSenders(txEvents)
+
+	events := make([]BbnEvent, len(txEvents))
+	for i, event := range txEvents {
+		events[i] = NewBbnEvent(TxCategory, event)
+		events[i].Tx = &model.BbnTx{
+			Hash:   txHash,
+			Index:  txIndex,
+			Sender: senders[eventAttribute(event, msgIndexAttributeKey)],
+		}
+	}
+	return events
+}
+
+// msgSenders maps index of the message in the tx to its sender. SDK emits message event
+// with the sender (first signer) of every message before the events of the message.
+func msgSenders(txEvents []abcitypes.Event) map[string]string {
+	senders := make(map[string]string)
+	for _, event := range txEvents {
+		if event.Type != sdk.EventTypeMessage {
+			continue
+		}
+		msgIndex := eventAttribute(event, msgIndexAttributeKey)
+		sender := eventAttribute(event, sdk.AttributeKeySender)
+		if msgIndex == "" || sender == "" {
+			continue
+		}
+		if _, ok := senders[msgIndex]; !ok {
+			senders[msgIndex] = sender
+		}
+	}
+	return senders
+}
+
+// eventAttribute returns unquoted value of the event attribute or empty string if there is none
+func eventAttribute(event abcitypes.Event, key string) string {
+	for _, attr := range event.Attributes {
+		if attr.Key == key {
+			return strings.Trim(attr.Value, `"`)
+		}
+	}
+	return ""
+}
+
 // Entry point for processing events with retries
 func (s *Service) processEvent(
 	ctx context.Context,
@@ -50,14 +101,21 @@ func (s *Service) processEvent(
 	}
 
 	// by default exponential delay is going to be used
-	err := retry.Do(
-		f,
-		retry.Attempts(processEventMaxRetries),
-		retry.Delay(retryInitialDelay),
-		retry.MaxDelay(retryMaxAllowedDelay),
-	)
+	processWithRetries := func() error {
+		return retry.Do(
+			f,
+			retry.Attempts(processEventMaxRetries),
+			retry.Delay(retryInitialDelay),
+			retry.MaxDelay(retryMaxAllowedDelay),
+		)
+	}
 
-	return err
+	stakingTxHash := eventStakingTxHash(event.Event)
+	if stakingTxHash == "" {
+		return processWithRetries()
+	}
+	// retries are included so BTC spends of the delegation can't be applied in between
+	return s.runForDelegation(ctx, delegationTaskSourceBbn, stakingTxHash, processWithRetries)
 }
 
 func (s *Service) doProcessEvent(
@@ -74,36 +132,9 @@ func (s *Service) doProcessEvent(
 	ctx = tracing.InjectTraceID(ctx)
 	log := log.Ctx(ctx)
 
-	var err error
-
-	switch types.EventType(bbnEvent.Type) {
-	case types.EventFinalityProviderCreatedType:
-		log.Debug().Msg("Processing new finality provider event")
-		err = s.processNewFinalityProviderEvent(ctx, bbnEvent)
-	case types.EventFinalityProviderEditedType:
-		log.Debug().Msg("Processing finality provider edited event")
-		err = s.processFinalityProviderEditedEvent(ctx, bbnEvent)
-	case types.EventFinalityProviderStatusChange:
-		log.Debug().Msg("Processing finality provider status change event")
-		err = s.processFinalityProviderStateChangeEvent(ctx, bbnEvent)
-	case types.EventBTCDelegationCreated:
-		log.Debug().Msg("Processing new BTC delegation event")
-		err = s.processNewBTCDelegationEvent(ctx, bbnEvent, blockHeight)
-	case types.EventCovenantQuorumReached:
-		log.Debug().Msg("Processing covenant quorum reached event")
-		err = s.processCovenantQuorumReachedEvent(ctx, bbnEvent, blockHeight)
-	case types.EventCovenantSignatureReceived:
-		log.Debug().Msg("Processing covenant signature received event")
-		err = s.processCovenantSignatureReceivedEvent(ctx, bbnEvent)
-	case types.EventBTCDelegationInclusionProofReceived:
-		log.Debug().Msg("Processing BTC delegation inclusion proof received event")
-		err = s.processBTCDelegationInclusionProofReceivedEvent(ctx, bbnEvent, blockHeight)
-	case types.EventBTCDelegationUnbondedEarly:
-		log.Debug().Msg("Processing BTC delegation unbonded early event")
-		err = s.processBTCDelegationUnbondedEarlyEvent(ctx, bbnEvent, blockHeight)
-	case types.EventBTCDelegationExpired:
-		log.Debug().Msg("Processing BTC delegation expired event")
-		err = s.processBTCDelegationExpiredEvent(ctx, bbnEvent, blockHeight)
+	handled, err := s.eventHandlers.Dispatch(ctx, event, blockHeight)
+	if !handled {
+		return nil
 	}
 
 	duration := time.Since(startTime)
@@ -116,53 +147,12 @@ func (s *Service) doProcessEvent(
 			Msg("Failed to process event")
 		return err
 	}
+	log.Debug().Str("event_type", bbnEvent.Type).Dur("duration", duration).Msg("Processed event")
 
 	return nil
 }
 
-func parseEvent[T proto.Message](
-	expectedType types.EventType,
-	event abcitypes.Event,
-) (T, error) {
-	var result T
-
-	// Check if the event type matches the expected type
-	if types.EventType(event.Type) != expectedType {
-		return result, fmt.Errorf(
-			"unexpected event type: %s received when processing %s",
-			event.Type,
-			expectedType,
-		)
-	}
-
-	// Check if the event has attributes
-	if len(event.Attributes) == 0 {
-		return result, fmt.Errorf(
-			"no attributes found in the %s event",
-			expectedType,
-		)
-	}
-
-	// Sanitize the event attributes before parsing
-	sanitizedEvent := sanitizeEvent(event)
-
-	// Use the SDK's ParseTypedEvent function
-	protoMsg, err := sdk.ParseTypedEvent(sanitizedEvent)
-	if err != nil {
-		log.Debug().Interface("raw_event", event).Msg("Raw event data")
-		return result, fmt.Errorf("failed to parse typed event: %w", err)
-	}
-
-	// Type assertion to ensure we have the correct concrete type
-	concreteMsg, ok := protoMsg.(T)
-	if !ok {
-		return result, fmt.Errorf("parsed event type %T does not match expected type %T", protoMsg, result)
-	}
-
-	return concreteMsg, nil
-}
-
-func (s *Service) validateBTCDelegationCreatedEvent(event *bstypes.EventBTCDelegationCreated) error {
+func (s *Service) validateBTCDelegationCreatedEvent(event *types.BTCDelegationCreatedEvent) error {
 	// Check if the staking tx hex is present
 	if event.StakingTxHex == "" {
 		return fmt.Errorf("new BTC delegation event missing staking tx hex")
@@ -180,7 +170,7 @@ func (s *Service) validateBTCDelegationCreatedEvent(event *bstypes.EventBTCDeleg
 	return nil
 }
 
-func (s *Service) validateCovenantQuorumReachedEvent(ctx context.Context, event *bstypes.EventCovenantQuorumReached) (bool, error) {
+func (s *Service) validateCovenantQuorumReachedEvent(ctx context.Context, event *types.CovenantQuorumReachedEvent) (bool, error) {
 	// Check if the staking tx hash is present
 	if event.StakingTxHash == "" {
 		return false, fmt.Errorf("covenant quorum reached event missing staking tx hash")
@@ -192,54 +182,28 @@ func (s *Service) validateCovenantQuorumReachedEvent(ctx context.Context, event
 		return false, fmt.Errorf("failed to get BTC delegation by staking tx hash: %w", dbErr)
 	}
 
-	// Retrieve the qualified states for the intended transition
-	qualifiedStates := types.QualifiedStatesForCovenantQuorumReached(event.NewState)
-	if qualifiedStates == nil {
+	// Retrieve the transition for the intended state
+	transition, err := types.FindTransition(
+		types.TriggerCovenantQuorumReached, types.DelegationState(event.NewState), "",
+	)
+	if err != nil {
 		return false, fmt.Errorf("invalid delegation state from Babylon: %s", event.NewState)
 	}
 
-	log := log.Ctx(ctx)
-	// Check if the current state is qualified for the transition
-	if !slices.Contains(qualifiedStates, delegation.State) {
-		log.Debug().
-			Str("stakingTxHashHex", event.StakingTxHash).
-			Stringer("currentState", delegation.State).
-			Str("newState", event.NewState).
-			Msg("Ignoring EventCovenantQuorumReached because current state is not qualified for transition")
+	// Check if the current state is qualified for the transition.
+	// VERIFIED state will only happen if the staker is following the new pre-approval flow,
+	// so the delegation should not have the inclusion proof yet.
+	// For more info read https://github.com/babylonlabs-io/pm/blob/main/rfc/rfc-008-staking-transaction-pre-approval.md#handling-of-the-modified--msgcreatebtcdelegation-message
+	// ACTIVE state will happen if the inclusion proof is received in MsgCreateBTCDelegation,
+	// i.e the staker is following the old flow, so the delegation should have the inclusion proof.
+	if !s.canTransition(ctx, delegation, transition) {
 		return false, nil // Ignore the event silently
 	}
 
-	if event.NewState == bstypes.BTCDelegationStatus_VERIFIED.String() {
-		// This will only happen if the staker is following the new pre-approval flow.
-		// For more info read https://github.com/babylonlabs-io/pm/blob/main/rfc/rfc-008-staking-transaction-pre-approval.md#handling-of-the-modified--msgcreatebtcdelegation-message
-
-		// Delegation should not have the inclusion proof yet
-		if delegation.HasInclusionProof() {
-			log.Debug().
-				Str("stakingTxHashHex", event.StakingTxHash).
-				Stringer("currentState", delegation.State).
-				Str("newState", event.NewState).
-				Msg("Ignoring EventCovenantQuorumReached because inclusion proof already received")
-			return false, nil
-		}
-	} else if event.NewState == bstypes.BTCDelegationStatus_ACTIVE.String() {
-		// This will happen if the inclusion proof is received in MsgCreateBTCDelegation, i.e the staker is following the old flow
-
-		// Delegation should have the inclusion proof
-		if !delegation.HasInclusionProof() {
-			log.Debug().
-				Str("stakingTxHashHex", event.StakingTxHash).
-				Stringer("currentState", delegation.State).
-				Str("newState", event.NewState).
-				Msg("Ignoring EventCovenantQuorumReached because inclusion proof not received")
-			return false, nil
-		}
-	}
-
 	return true, nil
 }
 
-func (s *Service) validateBTCDelegationInclusionProofReceivedEvent(ctx context.Context, event *bstypes.EventBTCDelegationInclusionProofReceived) (bool, error) {
+func (s *Service) validateBTCDelegationInclusionProofReceivedEvent(ctx context.Context, event *types.BTCDelegationInclusionProofReceivedEvent) (bool, error) {
 	// Check if the staking tx hash is present
 	if event.StakingTxHash == "" {
 		return false, fmt.Errorf("inclusion proof received event missing staking tx hash")
@@ -266,38 +230,25 @@ func (s *Service) validateBTCDelegationInclusionProofReceivedEvent(ctx context.C
 		return false, fmt.Errorf("failed to get BTC delegation by staking tx hash: %w", dbErr)
 	}
 
-	// Retrieve the qualified states for the intended transition
-	qualifiedStates := types.QualifiedStatesForInclusionProofReceived(event.NewState)
-	if qualifiedStates == nil {
+	// Retrieve the transition for the intended state
+	transition, err := types.FindTransition(
+		types.TriggerInclusionProofReceived, types.DelegationState(event.NewState), "",
+	)
+	if err != nil {
 		return false, fmt.Errorf("no qualified states defined for new state: %s", event.NewState)
 	}
 
-	log := log.Ctx(ctx)
-	// Check if the current state is qualified for the transition
-	if !slices.Contains(qualifiedStates, delegation.State) {
-		log.Debug().
-			Str("stakingTxHashHex", event.StakingTxHash).
-			Stringer("currentState", delegation.State).
-			Str("newState", event.NewState).
-			Msg("Ignoring EventBTCDelegationInclusionProofReceived because current state is not qualified for transition")
-		return false, nil
-	}
-
-	// Delegation should not have the inclusion proof yet
-	// After this event is processed, the inclusion proof will be set
-	if delegation.HasInclusionProof() {
-		log.Debug().
-			Str("stakingTxHashHex", event.StakingTxHash).
-			Stringer("currentState", delegation.State).
-			Str("newState", event.NewState).
-			Msg("Ignoring EventBTCDelegationInclusionProofReceived because inclusion proof already received")
+	// Check if the current state is qualified for the transition.
+	// Delegation should not have the inclusion proof yet, after this event is processed
+	// the inclusion proof will be set
+	if !s.canTransition(ctx, delegation, transition) {
 		return false, nil
 	}
 
 	return true, nil
 }
 
-func (s *Service) validateBTCDelegationUnbondedEarlyEvent(ctx context.Context, event *bstypes.EventBTCDelgationUnbondedEarly) (bool, error) {
+func (s *Service) validateBTCDelegationUnbondedEarlyEvent(ctx context.Context, event *types.BTCDelegationUnbondedEarlyEvent) (bool, error) {
 	// Check if the staking tx hash is present
 	if event.StakingTxHash == "" {
 		return false, fmt.Errorf("unbonded early event missing staking tx hash")
@@ -314,19 +265,24 @@ func (s *Service) validateBTCDelegationUnbondedEarlyEvent(ctx context.Context, e
 		return false, fmt.Errorf("failed to get BTC delegation by staking tx hash: %w", dbErr)
 	}
 
+	newState := types.StateUnbonding
+	if event.StakeExpansionTxHash != "" {
+		newState = types.StateExpanded
+	}
+	transition, err := types.FindTransition(types.TriggerUnbondedEarly, newState, types.SubStateEarlyUnbonding)
+	if err != nil {
+		return false, err
+	}
+
 	// Check if the current state is qualified for the transition
-	if !slices.Contains(types.QualifiedStatesForUnbondedEarly(), delegation.State) {
-		log.Ctx(ctx).Debug().
-			Str("stakingTxHashHex", event.StakingTxHash).
-			Stringer("currentState", delegation.State).
-			Msg("Ignoring EventBTCDelegationUnbondedEarly because current state is not qualified for transition")
+	if !s.canTransition(ctx, delegation, transition) {
 		return false, nil
 	}
 
 	return true, nil
 }
 
-func (s *Service) validateBTCDelegationExpiredEvent(ctx context.Context, event *bstypes.EventBTCDelegationExpired) (bool, error) {
+func (s *Service) validateBTCDelegationExpiredEvent(ctx context.Context, event *types.BTCDelegationExpiredEvent) (bool, error) {
 	// Check if the staking tx hash is present
 	if event.StakingTxHash == "" {
 		return false, fmt.Errorf("expired event missing staking tx hash")
@@ -343,12 +299,13 @@ func (s *Service) validateBTCDelegationExpiredEvent(ctx context.Context, event *
 		return false, fmt.Errorf("failed to get BTC delegation by staking tx hash: %w", dbErr)
 	}
 
+	transition, err := types.FindTransition(types.TriggerExpired, types.StateUnbonding, types.SubStateTimelock)
+	if err != nil {
+		return false, err
+	}
+
 	// Check if the current state is qualified for the transition
-	if !slices.Contains(types.QualifiedStatesForExpired(), delegation.State) {
-		log.Ctx(ctx).Debug().
-			Str("stakingTxHashHex", event.StakingTxHash).
-			Stringer("currentState", delegation.State).
-			Msg("Ignoring EventBTCDelegationExpired because current state is not qualified for transition")
+	if !s.canTransition(ctx, delegation, transition) {
 		return false, nil
 	}
 
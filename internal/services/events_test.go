@@ -24,7 +24,6 @@ import (
 	"github.com/btcsuite/btcd/wire"
 	abcitypes "github.com/cometbft/cometbft/abci/types"
 	ctypes "github.com/cometbft/cometbft/rpc/core/types"
-	sdk "github.com/cosmos/cosmos-sdk/types"
 	"github.com/lightningnetwork/lnd/chainntnfs"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -50,15 +49,15 @@ func Test_sanitizeEvent(t *testing.T) {
 		},
 	}
 
+	decoder := newEventDecoder[*types.FinalityProviderEditedEvent](nil, nil)
 	for _, cse := range cases {
 		var event abcitypes.Event
 		err := json.Unmarshal([]byte(cse.event), &event)
 		require.NoError(t, err)
 
-		sanitizedEvent := sanitizeEvent(event)
-
-		_, err = sdk.ParseTypedEvent(sanitizedEvent)
-		assert.NoError(t, err)
+		// the decoder sanitizes the event
+		_, err = decoder.decode(t.Context(), event, 0)
+		assert.NoError(t, err, cse.name)
 	}
 }
 
@@ -146,8 +145,10 @@ func Test_DelegationExpansion(t *testing.T) {
 	metrics.Init(9999)
 
 	btc := mocks.NewBtcInterface(t)
-	btc.On("GetBlockTimestamp", internalCtx, startHeight).Return(int64(1753970681), nil)
-	btc.On("GetBlockTimestamp", internalCtx, expansionStartHeight).Return(int64(1753977985), nil)
+	btc.On("GetBlockHash", internalCtx, startHeight).Return("block_hash", nil)
+	btc.On("GetBlockTimestampByHash", internalCtx, "block_hash").Return(int64(1753970681), nil)
+	btc.On("GetBlockHash", internalCtx, expansionStartHeight).Return("expansion_block_hash", nil)
+	btc.On("GetBlockTimestampByHash", internalCtx, "expansion_block_hash").Return(int64(1753977985), nil)
 
 	btcNotifier := mocks.NewBtcNotifier(t)
 	// delegation spend notification registration
@@ -313,6 +314,7 @@ func Test_DelegationExpansion(t *testing.T) {
 		},
 		SlashingTx:               model.SlashingTx{},
 		PreviousStakingTxHashHex: "",
+		NextStakingTxHashHex:     expansionStakingTxHashHex,
 	}
 	assert.Equal(t, expectedDelegation, delegation)
 
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/consumer"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/utils/poller"
+	"github.com/rs/zerolog/log"
+)
+
+// StartExpiringSoonNotifier starts periodic emission of expiring soon events
+func (s *Service) StartExpiringSoonNotifier(ctx context.Context) {
+	notifierPoller := poller.NewPoller(
+		s.cfg.Poller.ExpiringSoonPollingInterval,
+		metrics.RecordPollerDuration("expiring_soon", s.checkExpiringSoon),
+	)
+	go notifierPoller.Start(ctx)
+}
+
+func (s *Service) checkExpiringSoon(ctx context.Context) error {
+	btcTip, err := s.btc.GetTipHeight(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get BTC tip height: %w", err)
+	}
+
+	return s.notifyExpiringSoon(ctx, uint32(btcTip))
+}
+
+// notifyExpiringSoon emits expiring soon event for ACTIVE delegations that got within a threshold
+// of their expire height. Babylon expires a delegation once the BTC tip reaches its end height
+// minus the unbonding time, so that's the height thresholds are measured against. Thresholds are
+// processed from the smallest one and a delegation is marked as notified for all thresholds not
+// smaller than the one it was notified for, so a delegation that skipped some thresholds (e.g. it
+// has been activated close to its end height) gets a single event of the smallest threshold it's within.
+func (s *Service) notifyExpiringSoon(ctx context.Context, btcTip uint32) error {
+	thresholds := slices.Clone(s.cfg.Poller.ExpiringSoonThresholds)
+	slices.Sort(thresholds)
+	limit := int64(s.cfg.Poller.ExpiringSoonPageSize)
+
+	for i, threshold := range thresholds {
+		for {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			delegations, err := s.db.FindExpiringSoonDelegations(ctx, btcTip, threshold, limit)
+			if err != nil {
+				return fmt.Errorf("failed to find delegations expiring within %d blocks: %w", threshold, err)
+			}
+			for _, delegation := range delegations {
+				if err := s.emitExpiringSoonEvent(ctx, delegation, btcTip, threshold); err != nil {
+					return err
+				}
+				// notified delegations no longer match, so the next page starts from the beginning
+				if err := s.updateDelegation(ctx, delegation.StakingTxHashHex, func(current *model.BTCDelegationDetails) error {
+					return s.db.MarkExpiringSoonNotified(
+						ctx, delegation.StakingTxHashHex, thresholds[i:], db.WithExpectedVersion(current.Version),
+					)
+				}); err != nil {
+					return fmt.Errorf("failed to mark delegation %s as notified: %w", delegation.StakingTxHashHex, err)
+				}
+				metrics.IncExpiringSoonEvents(threshold)
+			}
+
+			if int64(len(delegations)) < limit {
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *Service) emitExpiringSoonEvent(
+	ctx context.Context, delegation *model.BTCDelegationDetails, btcTip, threshold uint32,
+) error {
+	log.Ctx(ctx).Debug().
+		Str("staking_tx", delegation.StakingTxHashHex).
+		Uint32("end_height", delegation.EndHeight).
+		Uint32("expire_height", delegation.ExpireHeight()).
+		Uint32("threshold", threshold).
+		Msg("delegation is expiring soon")
+
+	ev := consumer.NewExpiringSoonStakingEvent(
+		delegation.StakingTxHashHex,
+		delegation.StakerBtcPkHex,
+		delegation.FinalityProviderBtcPksHex,
+		delegation.StakingAmount,
+		delegation.EndHeight,
+		delegation.ExpireHeight(),
+		btcTip,
+		threshold,
+	)
+	if err := s.queueManager.PushExpiringSoonStakingEvent(ctx, &ev); err != nil {
+		return fmt.Errorf("failed to push the expiring soon event to the queue: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/consumer"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotifyExpiringSoon(t *testing.T) {
+	ctx := context.Background()
+	const btcTip = uint32(1000)
+
+	const unbondingTime = uint32(101)
+
+	newDelegation := func(stakingTxHash string, expireHeight uint32) *model.BTCDelegationDetails {
+		return &model.BTCDelegationDetails{
+			StakingTxHashHex:          stakingTxHash,
+			StakerBtcPkHex:            "staker",
+			FinalityProviderBtcPksHex: []string{"fp"},
+			StakingAmount:             1000,
+			EndHeight:                 expireHeight + unbondingTime,
+			UnbondingTime:             unbondingTime,
+		}
+	}
+	newEvent := func(delegation *model.BTCDelegationDetails, threshold uint32) *consumer.ExpiringSoonStakingEvent {
+		ev := consumer.NewExpiringSoonStakingEvent(
+			delegation.StakingTxHashHex, "staker", []string{"fp"}, 1000,
+			delegation.EndHeight, delegation.EndHeight-unbondingTime, btcTip, threshold,
+		)
+		return &ev
+	}
+	pollerConfig := withPollerConfig(config.PollerConfig{
+		ExpiringSoonThresholds: []uint32{1008, 144},
+		ExpiringSoonPageSize:   2,
+	})
+
+	t.Run("smallest threshold first", func(t *testing.T) {
+		s, m := newTestService(t, pollerConfig)
+
+		soon := newDelegation("soon", btcTip+100)
+		later := newDelegation("later", btcTip+500)
+		// delegations that skipped 1008 blocks threshold are marked for both thresholds
+		m.db.On("FindExpiringSoonDelegations", ctx, btcTip, uint32(144), int64(2)).
+			Return([]*model.BTCDelegationDetails{soon}, nil).Once()
+		m.consumer.On("PushExpiringSoonStakingEvent", ctx, newEvent(soon, 144)).Return(nil).Once()
+		m.db.On("GetBTCDelegationByStakingTxHash", ctx, "soon").Return(soon, nil).Once()
+		m.db.On("MarkExpiringSoonNotified", ctx, "soon", []uint32{144, 1008}, mock.Anything).Return(nil).Once()
+
+		m.db.On("FindExpiringSoonDelegations", ctx, btcTip, uint32(1008), int64(2)).
+			Return([]*model.BTCDelegationDetails{later}, nil).Once()
+		m.consumer.On("PushExpiringSoonStakingEvent", ctx, newEvent(later, 1008)).Return(nil).Once()
+		m.db.On("GetBTCDelegationByStakingTxHash", ctx, "later").Return(later, nil).Once()
+		m.db.On("MarkExpiringSoonNotified", ctx, "later", []uint32{1008}, mock.Anything).Return(nil).Once()
+
+		require.NoError(t, s.notifyExpiringSoon(ctx, btcTip))
+	})
+	t.Run("drains all pages", func(t *testing.T) {
+		s, m := newTestService(t, pollerConfig)
+		s.cfg.Poller.ExpiringSoonThresholds = []uint32{144}
+
+		page := []*model.BTCDelegationDetails{newDelegation("a", btcTip+1), newDelegation("b", btcTip+2)}
+		m.db.On("FindExpiringSoonDelegations", ctx, btcTip, uint32(144), int64(2)).Return(page, nil).Once()
+		m.db.On("FindExpiringSoonDelegations", ctx, btcTip, uint32(144), int64(2)).Return(nil, nil).Once()
+		for _, delegation := range page {
+			m.consumer.On("PushExpiringSoonStakingEvent", ctx, newEvent(delegation, 144)).Return(nil).Once()
+			m.db.On("GetBTCDelegationByStakingTxHash", ctx, delegation.StakingTxHashHex).Return(delegation, nil).Once()
+			m.db.On("MarkExpiringSoonNotified", ctx, delegation.StakingTxHashHex, []uint32{144}, mock.Anything).
+				Return(nil).Once()
+		}
+
+		require.NoError(t, s.notifyExpiringSoon(ctx, btcTip))
+	})
+	t.Run("delegation isn't marked if event wasn't pushed", func(t *testing.T) {
+		s, m := newTestService(t, pollerConfig)
+
+		delegation := newDelegation("soon", btcTip+100)
+		m.db.On("FindExpiringSoonDelegations", ctx, btcTip, uint32(144), int64(2)).
+			Return([]*model.BTCDelegationDetails{delegation}, nil).Once()
+		m.consumer.On("PushExpiringSoonStakingEvent", ctx, newEvent(delegation, 144)).Return(errors.New("queue error")).Once()
+
+		require.Error(t, s.notifyExpiringSoon(ctx, btcTip))
+	})
+}
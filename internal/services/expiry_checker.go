@@ -5,10 +5,12 @@ import (
 	"fmt"
 
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/utils/poller"
 	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 func (s *Service) StartExpiryChecker(ctx context.Context) {
@@ -19,6 +21,9 @@ func (s *Service) StartExpiryChecker(ctx context.Context) {
 	go expiryCheckerPoller.Start(ctx)
 }
 
+// checkExpiry makes delegations withdrawable once BTC tip is ExpiryConfirmationDepth blocks
+// past their expire height. Expired delegations are processed in pages until none are left,
+// delegations that failed to be processed stay in the collection and are retried on the next run.
 func (s *Service) checkExpiry(ctx context.Context) error {
 	btcTip, err := s.btc.GetTipHeight(ctx)
 	if err != nil {
@@ -26,62 +31,143 @@ func (s *Service) checkExpiry(ctx context.Context) error {
 	}
 	metrics.RecordBtcTipHeight(btcTip)
 
-	expiredDelegations, err := s.db.FindExpiredDelegations(ctx, btcTip, s.cfg.Poller.ExpiredDelegationsLimit)
+	depth := uint64(s.cfg.Poller.ExpiryConfirmationDepth)
+	if btcTip < depth {
+		return nil
+	}
+	expiredHeight := btcTip - depth
+
+	backlog, err := s.db.CountExpiredDelegations(ctx, expiredHeight)
 	if err != nil {
-		return fmt.Errorf("failed to find expired delegations: %w", err)
+		return fmt.Errorf("failed to count expired delegations: %w", err)
 	}
+	metrics.RecordExpiredDelegationsBacklog(backlog)
 
-	log := log.Ctx(ctx)
+	limit := s.cfg.Poller.ExpiredDelegationsLimit
+	var (
+		afterID   primitive.ObjectID
+		processed int
+	)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 
-	if len(expiredDelegations) != 0 {
-		metrics.RecordExpiredDelegationsCount(len(expiredDelegations))
-	}
-	for _, tlDoc := range expiredDelegations {
-		delegation, err := s.db.GetBTCDelegationByStakingTxHash(ctx, tlDoc.StakingTxHashHex)
+		expiredDelegations, err := s.db.FindExpiredDelegations(ctx, expiredHeight, afterID, limit)
+		if err != nil {
+			return fmt.Errorf("failed to find expired delegations: %w", err)
+		}
+		if len(expiredDelegations) == 0 {
+			break
+		}
+		afterID = expiredDelegations[len(expiredDelegations)-1].ID
+
+		n, err := s.processExpiredDelegations(ctx, expiredDelegations)
 		if err != nil {
-			return fmt.Errorf("failed to get BTC delegation by staking tx hash: %w", err)
+			return err
+		}
+		processed += n
+		metrics.RecordExpiredDelegationsBacklog(max(backlog-int64(processed), 0))
+
+		if uint64(len(expiredDelegations)) < limit {
+			break
 		}
+	}
+
+	if processed != 0 {
+		metrics.RecordExpiredDelegationsCount(processed)
+	}
+
+	return nil
+}
 
+// processExpiredDelegations updates state of the delegations to withdrawable in a single bulk write
+// and deletes timelock documents of the processed ones. Failure of a single delegation is logged
+// and doesn't affect the others, the returned error means the whole page failed. Timelocks of the
+// delegations that have been spent provisionally in the meantime are kept for the next run.
+func (s *Service) processExpiredDelegations(ctx context.Context, tlDocs []model.TimeLockDocument) (int, error) {
+	log := log.Ctx(ctx)
+
+	var (
+		updates []db.BTCDelegationStateUpdate
+		ids     []primitive.ObjectID
+	)
+	for _, tlDoc := range tlDocs {
 		log.Debug().
-			Str("staking_tx", delegation.StakingTxHashHex).
-			Stringer("current_state", delegation.State).
+			Str("staking_tx", tlDoc.StakingTxHashHex).
 			Stringer("new_sub_state", tlDoc.DelegationSubState).
 			Uint32("expire_height", tlDoc.ExpireHeight).
-			Msg("checking if delegation is expired")
+			Msg("delegation is expired")
 
-		qualifiedStates, err := types.QualifiedStatesForWithdrawable(tlDoc.DelegationSubState)
-		if err != nil {
-			return fmt.Errorf("failed to get qualified states for withdrawable: %w", err)
+		if _, err := types.FindTransition(
+			types.TriggerTimelockExpiry, types.StateWithdrawable, tlDoc.DelegationSubState,
+		); err != nil {
+			log.Error().
+				Err(err).
+				Str("staking_tx", tlDoc.StakingTxHashHex).
+				Msg("failed to find transition to withdrawable")
+			metrics.IncExpiryCheckerFailure("transition")
+			continue
 		}
 
-		stateUpdateErr := s.db.UpdateBTCDelegationState(
-			ctx,
-			delegation.StakingTxHashHex,
-			qualifiedStates,
-			types.StateWithdrawable,
-			db.WithSubState(tlDoc.DelegationSubState),
-			db.WithBtcHeight(tlDoc.ExpireHeight),
-		)
-		if stateUpdateErr != nil {
-			if db.IsNotFoundError(stateUpdateErr) {
-				log.Debug().
-					Str("staking_tx", delegation.StakingTxHashHex).
-					Msg("skip updating BTC delegation state to withdrawable as the state is not qualified")
-			} else {
-				log.Error().
-					Str("staking_tx", delegation.StakingTxHashHex).
-					Msg("failed to update BTC delegation state to withdrawable")
-				return fmt.Errorf("failed to update BTC delegation state to withdrawable: %w", err)
-			}
+		// timelocks of the page mostly expire at the same heights, the lookup is cached
+		expireBtcTimestamp, err := s.btcBlockTime(ctx, tlDoc.ExpireHeight)
+		if err != nil {
+			log.Error().
+				Err(err).
+				Str("staking_tx", tlDoc.StakingTxHashHex).
+				Msg("failed to get timestamp of expire height")
+			metrics.IncExpiryCheckerFailure("timestamp")
+			continue
 		}
 
-		if err := s.db.DeleteExpiredDelegation(ctx, delegation.StakingTxHashHex); err != nil {
+		// delegations not in qualified states are skipped by the update, their timelock is deleted anyway.
+		// The delegation spent provisionally since it was found waits for the spend to be confirmed
+		updates = append(updates, db.BTCDelegationStateUpdate{
+			StakingTxHash: tlDoc.StakingTxHashHex,
+			Trigger:       types.TriggerTimelockExpiry,
+			NewState:      types.StateWithdrawable,
+			Options: []db.UpdateOption{
+				db.WithSubState(tlDoc.DelegationSubState),
+				db.WithBtcHeight(tlDoc.ExpireHeight),
+				db.WithBtcTimestamp(expireBtcTimestamp),
+				db.WithoutProvisionalSpends(),
+			},
+		})
+		ids = append(ids, tlDoc.ID)
+	}
+	if len(updates) == 0 {
+		return 0, nil
+	}
+
+	updateErrs, err := s.db.BulkUpdateBTCDelegationState(ctx, updates)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update BTC delegations state to withdrawable: %w", err)
+	}
+
+	processedIDs := make([]primitive.ObjectID, 0, len(ids))
+	for i, updateErr := range updateErrs {
+		if db.IsConflictError(updateErr) {
+			log.Debug().
+				Err(updateErr).
+				Str("staking_tx", updates[i].StakingTxHash).
+				Msg("expiry of BTC delegation is retried on the next run")
+			continue
+		}
+		if updateErr != nil {
 			log.Error().
-				Str("staking_tx", delegation.StakingTxHashHex).
-				Msg("failed to delete expired delegation")
-			return fmt.Errorf("failed to delete expired delegation: %w", err)
+				Err(updateErr).
+				Str("staking_tx", updates[i].StakingTxHash).
+				Msg("failed to update BTC delegation state to withdrawable")
+			metrics.IncExpiryCheckerFailure("update")
+			continue
 		}
+		processedIDs = append(processedIDs, ids[i])
 	}
 
-	return nil
+	if err := s.db.DeleteTimeLockExpires(ctx, processedIDs); err != nil {
+		return 0, fmt.Errorf("failed to delete expired delegations: %w", err)
+	}
+
+	return len(processedIDs), nil
 }
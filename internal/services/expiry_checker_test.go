@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func testTimeLockDocs(n int, subState types.DelegationSubState) []model.TimeLockDocument {
+	docs := make([]model.TimeLockDocument, n)
+	for i := range docs {
+		docs[i] = model.TimeLockDocument{
+			ID:                 primitive.NewObjectID(),
+			StakingTxHashHex:   primitive.NewObjectID().Hex(),
+			ExpireHeight:       100,
+			DelegationSubState: subState,
+		}
+	}
+	return docs
+}
+
+func idsOf(docs ...model.TimeLockDocument) []primitive.ObjectID {
+	ids := make([]primitive.ObjectID, len(docs))
+	for i, doc := range docs {
+		ids[i] = doc.ID
+	}
+	return ids
+}
+
+func TestCheckExpiry(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("drains all pages", func(t *testing.T) {
+		s, m := newTestService(t, withPollerConfig(config.PollerConfig{ExpiredDelegationsLimit: 2}))
+		docs := testTimeLockDocs(3, types.SubStateTimelock)
+
+		m.btc.On("GetTipHeight", ctx).Return(uint64(100), nil).Once()
+		m.db.On("CountExpiredDelegations", ctx, uint64(100)).Return(int64(3), nil).Once()
+		// all timelocks expire at the same height, so its timestamp is queried once
+		m.btc.On("GetBlockHash", ctx, uint32(100)).Return("block_hash", nil)
+		m.btc.On("GetBlockTimestampByHash", ctx, "block_hash").Return(int64(1000), nil).Once()
+		m.db.On("FindExpiredDelegations", ctx, uint64(100), primitive.NilObjectID, uint64(2)).Return(docs[:2], nil).Once()
+		m.db.On("FindExpiredDelegations", ctx, uint64(100), docs[1].ID, uint64(2)).Return(docs[2:], nil).Once()
+		m.db.On("BulkUpdateBTCDelegationState", ctx, mock.MatchedBy(func(updates []db.BTCDelegationStateUpdate) bool {
+			for _, u := range updates {
+				if u.NewState != types.StateWithdrawable {
+					return false
+				}
+			}
+			return len(updates) > 0
+		})).Return(func(_ context.Context, updates []db.BTCDelegationStateUpdate) ([]error, error) {
+			return make([]error, len(updates)), nil
+		}).Twice()
+		m.db.On("DeleteTimeLockExpires", ctx, idsOf(docs[:2]...)).Return(nil).Once()
+		m.db.On("DeleteTimeLockExpires", ctx, idsOf(docs[2])).Return(nil).Once()
+
+		require.NoError(t, s.checkExpiry(ctx))
+	})
+	t.Run("failures are isolated", func(t *testing.T) {
+		s, m := newTestService(t, withPollerConfig(config.PollerConfig{ExpiredDelegationsLimit: 10}))
+		docs := testTimeLockDocs(3, types.SubStateTimelock)
+		// there are no qualified states for this sub state, so it's not updated
+		docs[0].DelegationSubState = "invalid"
+
+		m.btc.On("GetTipHeight", ctx).Return(uint64(100), nil).Once()
+		m.db.On("CountExpiredDelegations", ctx, uint64(100)).Return(int64(3), nil).Once()
+		m.db.On("FindExpiredDelegations", ctx, uint64(100), primitive.NilObjectID, uint64(10)).Return(docs, nil).Once()
+		m.btc.On("GetBlockHash", ctx, uint32(100)).Return("block_hash", nil)
+		m.btc.On("GetBlockTimestampByHash", ctx, "block_hash").Return(int64(1000), nil).Once()
+		m.db.On("BulkUpdateBTCDelegationState", ctx, mock.MatchedBy(func(updates []db.BTCDelegationStateUpdate) bool {
+			return len(updates) == 2 &&
+				updates[0].StakingTxHash == docs[1].StakingTxHashHex &&
+				updates[1].StakingTxHash == docs[2].StakingTxHashHex
+		})).Return([]error{errors.New("write error"), nil}, nil).Once()
+		// only the delegation updated successfully is removed, the others are retried on the next run
+		m.db.On("DeleteTimeLockExpires", ctx, idsOf(docs[2])).Return(nil).Once()
+
+		require.NoError(t, s.checkExpiry(ctx))
+	})
+	t.Run("provisionally spent delegation", func(t *testing.T) {
+		s, m := newTestService(t, withPollerConfig(config.PollerConfig{ExpiredDelegationsLimit: 10}))
+		docs := testTimeLockDocs(2, types.SubStateTimelock)
+
+		m.btc.On("GetTipHeight", ctx).Return(uint64(100), nil).Once()
+		m.db.On("CountExpiredDelegations", ctx, uint64(100)).Return(int64(2), nil).Once()
+		m.db.On("FindExpiredDelegations", ctx, uint64(100), primitive.NilObjectID, uint64(10)).Return(docs, nil).Once()
+		m.btc.On("GetBlockHash", ctx, uint32(100)).Return("block_hash", nil)
+		m.btc.On("GetBlockTimestampByHash", ctx, "block_hash").Return(int64(1000), nil).Once()
+		// the first delegation has been spent provisionally after it was found
+		conflict := &db.ConflictError{Key: docs[0].StakingTxHashHex, Message: "provisional spends"}
+		m.db.On("BulkUpdateBTCDelegationState", ctx, mock.Anything).Return([]error{conflict, nil}, nil).Once()
+		// its timelock is kept, so it expires once the spend is confirmed or reverted
+		m.db.On("DeleteTimeLockExpires", ctx, idsOf(docs[1])).Return(nil).Once()
+
+		require.NoError(t, s.checkExpiry(ctx))
+	})
+	t.Run("bulk write failure", func(t *testing.T) {
+		s, m := newTestService(t, withPollerConfig(config.PollerConfig{ExpiredDelegationsLimit: 10}))
+		docs := testTimeLockDocs(1, types.SubStateTimelock)
+
+		m.btc.On("GetTipHeight", ctx).Return(uint64(100), nil).Once()
+		m.db.On("CountExpiredDelegations", ctx, uint64(100)).Return(int64(1), nil).Once()
+		m.db.On("FindExpiredDelegations", ctx, uint64(100), primitive.NilObjectID, uint64(10)).Return(docs, nil).Once()
+		m.btc.On("GetBlockHash", ctx, uint32(100)).Return("block_hash", nil)
+		m.btc.On("GetBlockTimestampByHash", ctx, "block_hash").Return(int64(1000), nil).Once()
+		m.db.On("BulkUpdateBTCDelegationState", ctx, mock.Anything).Return(nil, errors.New("connection error")).Once()
+
+		require.Error(t, s.checkExpiry(ctx))
+	})
+	t.Run("timestamp failure", func(t *testing.T) {
+		s, m := newTestService(t, withPollerConfig(config.PollerConfig{ExpiredDelegationsLimit: 10}))
+		docs := testTimeLockDocs(1, types.SubStateTimelock)
+
+		m.btc.On("GetTipHeight", ctx).Return(uint64(100), nil).Once()
+		m.db.On("CountExpiredDelegations", ctx, uint64(100)).Return(int64(1), nil).Once()
+		m.db.On("FindExpiredDelegations", ctx, uint64(100), primitive.NilObjectID, uint64(10)).Return(docs, nil).Once()
+		m.btc.On("GetBlockHash", ctx, uint32(100)).Return("block_hash", nil)
+		m.btc.On("GetBlockTimestampByHash", ctx, "block_hash").Return(int64(0), errors.New("rpc error")).Once()
+
+		// the delegation keeps its timelock and is retried on the next run
+		require.NoError(t, s.checkExpiry(ctx))
+		m.db.AssertNotCalled(t, "BulkUpdateBTCDelegationState", mock.Anything, mock.Anything)
+		m.db.AssertNotCalled(t, "DeleteTimeLockExpires", mock.Anything, mock.Anything)
+	})
+	t.Run("confirmation depth", func(t *testing.T) {
+		s, m := newTestService(t, withPollerConfig(config.PollerConfig{ExpiredDelegationsLimit: 10, ExpiryConfirmationDepth: 6}))
+
+		m.btc.On("GetTipHeight", ctx).Return(uint64(105), nil).Once()
+		m.db.On("CountExpiredDelegations", ctx, uint64(99)).Return(int64(0), nil).Once()
+		m.db.On("FindExpiredDelegations", ctx, uint64(99), primitive.NilObjectID, uint64(10)).
+			Return([]model.TimeLockDocument{}, nil).Once()
+
+		require.NoError(t, s.checkExpiry(ctx))
+	})
+	t.Run("tip below confirmation depth", func(t *testing.T) {
+		s, m := newTestService(t, withPollerConfig(config.PollerConfig{ExpiredDelegationsLimit: 10, ExpiryConfirmationDepth: 6}))
+
+		m.btc.On("GetTipHeight", ctx).Return(uint64(5), nil).Once()
+
+		require.NoError(t, s.checkExpiry(ctx))
+	})
+}
@@ -2,34 +2,21 @@ package services
 
 import (
 	"context"
-	"errors"
 	"fmt"
 
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
 	bbntypes "github.com/babylonlabs-io/babylon/v4/x/btcstaking/types"
-	abcitypes "github.com/cometbft/cometbft/abci/types"
 	"github.com/rs/zerolog/log"
 )
 
-func (s *Service) processNewFinalityProviderEvent(
-	ctx context.Context, event abcitypes.Event,
+func (s *Service) applyNewFinalityProviderEvent(
+	ctx context.Context, newFinalityProvider *types.FinalityProviderCreatedEvent, bbnHeight int64, bbnTx *model.BbnTx,
 ) error {
-	newFinalityProvider, err := parseEvent[*bbntypes.EventFinalityProviderCreated](
-		types.EventFinalityProviderCreatedType, event,
-	)
-	if err != nil {
-		return err
-	}
-
 	log := log.Ctx(ctx)
 	log.Info().Interface("event", newFinalityProvider).Msg("FinalityProvider created")
 
-	if validationErr := s.validateFinalityProviderCreatedEvent(newFinalityProvider); validationErr != nil {
-		return validationErr
-	}
-
 	if dbErr := s.db.SaveNewFinalityProvider(
 		ctx, model.FromEventFinalityProviderCreated(newFinalityProvider),
 	); dbErr != nil {
@@ -43,70 +30,71 @@ func (s *Service) processNewFinalityProviderEvent(
 		return fmt.Errorf("failed to save new finality provider: %w", dbErr)
 	}
 
-	return nil
+	return s.saveFinalityProviderHistory(
+		ctx, newFinalityProvider.BtcPkHex, types.EventFinalityProviderCreatedType, bbnHeight, bbnTx,
+	)
 }
 
-func (s *Service) processFinalityProviderEditedEvent(
-	ctx context.Context, event abcitypes.Event,
+func (s *Service) applyFinalityProviderEditedEvent(
+	ctx context.Context, finalityProviderEdited *types.FinalityProviderEditedEvent, bbnHeight int64, bbnTx *model.BbnTx,
 ) error {
-	finalityProviderEdited, err := parseEvent[*bbntypes.EventFinalityProviderEdited](
-		types.EventFinalityProviderEditedType, event,
-	)
-	if err != nil {
-		return err
-	}
 	log.Ctx(ctx).Info().Interface("event", finalityProviderEdited).Msg("FinalityProvider edited")
 
-	if validationErr := s.validateFinalityProviderEditedEvent(finalityProviderEdited); validationErr != nil {
-		return validationErr
-	}
-
 	if dbErr := s.db.UpdateFinalityProviderDetailsFromEvent(
 		ctx, model.FromEventFinalityProviderEdited(finalityProviderEdited),
 	); dbErr != nil {
 		return fmt.Errorf("failed to update finality provider details: %w", dbErr)
 	}
 
-	return nil
+	return s.saveFinalityProviderHistory(
+		ctx, finalityProviderEdited.BtcPkHex, types.EventFinalityProviderEditedType, bbnHeight, bbnTx,
+	)
 }
 
-func (s *Service) processFinalityProviderStateChangeEvent(
-	ctx context.Context, event abcitypes.Event,
+func (s *Service) applyFinalityProviderStateChangeEvent(
+	ctx context.Context, finalityProviderStateChange *types.FinalityProviderStatusChangeEvent,
+	bbnHeight int64, bbnTx *model.BbnTx,
 ) error {
-	finalityProviderStateChange, err := parseEvent[*bbntypes.EventFinalityProviderStatusChange](
-		types.EventFinalityProviderStatusChange, event,
-	)
-	if err != nil {
-		return err
-	}
-
 	log.Ctx(ctx).Info().Interface("event", finalityProviderStateChange).Msg("FinalityProvider status changed")
 
-	if validationErr := s.validateFinalityProviderStateChangeEvent(ctx, finalityProviderStateChange); validationErr != nil {
-		if errors.Is(validationErr, types.ErrFinalityProviderAlreadySlashed) {
-			log.Ctx(ctx).Warn().
-				Str("btcPk", finalityProviderStateChange.BtcPk).
-				Str("newState", finalityProviderStateChange.NewState).
-				Err(validationErr).
-				Msg("Finality provider is already slashed, cannot change state, ignoring event")
-			return nil
-		}
-		return validationErr
-	}
-
 	// If all validations pass, update the finality provider state
 	if dbErr := s.db.UpdateFinalityProviderState(
 		ctx, finalityProviderStateChange.BtcPk, finalityProviderStateChange.NewState,
 	); dbErr != nil {
 		return fmt.Errorf("failed to update finality provider state: %w", dbErr)
 	}
+
+	return s.saveFinalityProviderHistory(
+		ctx, finalityProviderStateChange.BtcPk, types.EventFinalityProviderStatusChange, bbnHeight, bbnTx,
+	)
+}
+
+// saveFinalityProviderHistory records the finality provider as it is after the event
+// at the given height, so earlier commission, description and status are kept
+func (s *Service) saveFinalityProviderHistory(
+	ctx context.Context, btcPk string, eventType types.EventType, bbnHeight int64, bbnTx *model.BbnTx,
+) error {
+	fp, err := s.db.GetFinalityProviderByBtcPk(ctx, btcPk)
+	if err != nil {
+		return fmt.Errorf("failed to get finality provider: %w", err)
+	}
+	bbnTimestamp, err := s.bbnBlockTime(ctx, bbnHeight)
+	if err != nil {
+		return fmt.Errorf("failed to get bbn block timestamp: %w", err)
+	}
+
+	record := model.NewFinalityProviderHistoryRecord(fp, string(eventType), bbnHeight, bbnTimestamp, bbnTx)
+	if err := s.db.SaveFinalityProviderHistory(ctx, record); err != nil {
+		return fmt.Errorf("failed to save finality provider history: %w", err)
+	}
+
 	return nil
 }
 
 // validateFinalityProviderCreatedEvent validates properties of
 // the new finality provider event and returns an error if the event is invalid.
 func (s *Service) validateFinalityProviderCreatedEvent(
-	fpCreated *bbntypes.EventFinalityProviderCreated,
+	fpCreated *types.FinalityProviderCreatedEvent,
 ) error {
 	if fpCreated.BtcPkHex == "" {
 		return fmt.Errorf("finality provider created event missing btc public key")
@@ -117,7 +105,7 @@ func (s *Service) validateFinalityProviderCreatedEvent(
 // validateFinalityProviderEditedEvent validates properties of
 // the finality provider edited event and returns an error if the event is invalid.
 func (s *Service) validateFinalityProviderEditedEvent(
-	fpEdited *bbntypes.EventFinalityProviderEdited,
+	fpEdited *types.FinalityProviderEditedEvent,
 ) error {
 	if fpEdited.BtcPkHex == "" {
 		return fmt.Errorf("finality provider edited event missing btc public key")
@@ -126,28 +114,35 @@ func (s *Service) validateFinalityProviderEditedEvent(
 	return nil
 }
 
+// validateFinalityProviderStateChangeEvent validates the finality provider status change
+// event, it returns false if the event should be ignored
 func (s *Service) validateFinalityProviderStateChangeEvent(
 	ctx context.Context,
-	fpStateChange *bbntypes.EventFinalityProviderStatusChange,
-) error {
+	fpStateChange *types.FinalityProviderStatusChangeEvent,
+) (bool, error) {
 	// Check FP exists
 	fp, dbErr := s.db.GetFinalityProviderByBtcPk(ctx, fpStateChange.BtcPk)
 	if dbErr != nil {
-		return fmt.Errorf("failed to get finality provider by btc public key: %w", dbErr)
+		return false, fmt.Errorf("failed to get finality provider by btc public key: %w", dbErr)
 	}
 
 	if fpStateChange.BtcPk == "" {
-		return fmt.Errorf("finality provider State change event missing btc public key")
+		return false, fmt.Errorf("finality provider State change event missing btc public key")
 	}
 	if fpStateChange.NewState == "" {
-		return fmt.Errorf("finality provider State change event missing State")
+		return false, fmt.Errorf("finality provider State change event missing State")
 	}
 
 	// Check if the finality provider is already slashed. No point in changing
 	// the state of a slashed finality provider.
 	if fp.State == bbntypes.FinalityProviderStatus_FINALITY_PROVIDER_STATUS_SLASHED.String() {
-		return types.ErrFinalityProviderAlreadySlashed
+		log.Ctx(ctx).Warn().
+			Str("btcPk", fpStateChange.BtcPk).
+			Str("newState", fpStateChange.NewState).
+			Err(types.ErrFinalityProviderAlreadySlashed).
+			Msg("Finality provider is already slashed, cannot change state, ignoring event")
+		return false, nil
 	}
 
-	return nil
+	return true, nil
 }
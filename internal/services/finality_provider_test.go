@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/pkg"
+	bbntypes "github.com/babylonlabs-io/babylon/v4/x/btcstaking/types"
+	abcitypes "github.com/cometbft/cometbft/abci/types"
+	ctypes "github.com/cometbft/cometbft/rpc/core/types"
+	cmttypes "github.com/cometbft/cometbft/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessFinalityProviderEditedEvent(t *testing.T) {
+	ctx := context.Background()
+	const (
+		btcPk  = "fp_btc_pk"
+		height = int64(100)
+	)
+	blockTime := time.Unix(1700000000, 0)
+
+	event, err := sdk.TypedEventToEvent(&bbntypes.EventFinalityProviderEdited{
+		BtcPkHex:   btcPk,
+		Commission: "0.2",
+		Moniker:    "moniker",
+	})
+	require.NoError(t, err)
+
+	fp := &model.FinalityProviderDetails{
+		BtcPk:       btcPk,
+		Commission:  "0.2",
+		State:       bbntypes.FinalityProviderStatus_FINALITY_PROVIDER_STATUS_ACTIVE.String(),
+		Description: model.Description{Moniker: "moniker", Website: "website"},
+	}
+	bbnTx := &model.BbnTx{Hash: "tx_hash", Index: 1, Sender: "bbn1sender"}
+
+	s, m := newTestService(t)
+	m.db.On("UpdateFinalityProviderDetailsFromEvent", ctx, &model.FinalityProviderDetails{
+		BtcPk:       btcPk,
+		Commission:  "0.2",
+		Description: model.Description{Moniker: "moniker"},
+	}).Return(nil).Once()
+	m.db.On("GetFinalityProviderByBtcPk", ctx, btcPk).Return(fp, nil).Once()
+	m.db.On("SaveFinalityProviderHistory", ctx, &model.FinalityProviderHistoryRecord{
+		BtcPk:        btcPk,
+		BbnHeight:    height,
+		BbnTimestamp: blockTime.Unix(),
+		Commission:   fp.Commission,
+		State:        fp.State,
+		Description:  fp.Description,
+		EventTypes:   []string{string(types.EventFinalityProviderEditedType)},
+		BbnTx:        bbnTx,
+	}).Return(nil).Once()
+
+	m.bbn.On("GetBlock", ctx, pkg.Ptr(height)).Return(&ctypes.ResultBlock{
+		Block: &cmttypes.Block{Header: cmttypes.Header{Time: blockTime}},
+	}, nil).Once()
+
+	s.eventHandlers = s.newEventHandlerRegistry()
+	handled, err := s.eventHandlers.Dispatch(ctx, BbnEvent{Event: abcitypes.Event(event), Tx: bbnTx}, height)
+	require.NoError(t, err)
+	require.True(t, handled)
+}
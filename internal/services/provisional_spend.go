@@ -0,0 +1,322 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	notifier "github.com/lightningnetwork/lnd/chainntnfs"
+	"github.com/rs/zerolog/log"
+)
+
+// defaultSpendConfirmationCheckInterval is used if BTC block polling interval is not configured
+const defaultSpendConfirmationCheckInterval = 30 * time.Second
+
+// spendHandler applies the spend of a watched output to the delegation
+type spendHandler func(ctx context.Context, spendDetail *notifier.SpendDetail) error
+
+// spendWatches holds watchers of outputs created by spending txs (unbonding tx output,
+// slashing change output) keyed by the spending tx hash, so they can be cancelled
+// once the spending tx is reorged out
+type spendWatches struct {
+	mu      sync.Mutex
+	watches map[string]*spendWatch
+}
+
+type spendWatch struct {
+	cancel context.CancelFunc
+}
+
+func newSpendWatches() *spendWatches {
+	return &spendWatches{watches: make(map[string]*spendWatch)}
+}
+
+// start returns context of a new watch of the output created by the spending tx.
+// The returned function must be called once the watch is done.
+func (w *spendWatches) start(ctx context.Context, spendingTxHash string) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	if w == nil {
+		return ctx, cancel
+	}
+	watch := &spendWatch{cancel: cancel}
+
+	w.mu.Lock()
+	// the same spend is handled again when it's delivered after it was applied
+	if previous, ok := w.watches[spendingTxHash]; ok {
+		previous.cancel()
+	}
+	w.watches[spendingTxHash] = watch
+	w.mu.Unlock()
+
+	return ctx, func() {
+		cancel()
+
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		if w.watches[spendingTxHash] == watch {
+			delete(w.watches, spendingTxHash)
+		}
+	}
+}
+
+// cancel stops the watch of the output created by the spending tx if there is one
+func (w *spendWatches) cancel(spendingTxHash string) {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if watch, ok := w.watches[spendingTxHash]; ok {
+		watch.cancel()
+		delete(w.watches, spendingTxHash)
+	}
+}
+
+// watchSpend handles the spend of a watched output and keeps the resulting state transition
+// provisional until the spending tx reaches BtcConfirmationDepth. If the notifier reports
+// that the spending tx has been reorged out, the transition is reverted and the output is
+// watched again, as it can be spent by another tx in the new chain. Updates of the delegation
+// are serialized with its BBN events and other spends.
+func (s *Service) watchSpend(
+	ctx context.Context,
+	spendEvent *notifier.SpendEvent,
+	stakingTxHashHex string,
+	handle spendHandler,
+) {
+	quitCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if spendEvent.Cancel != nil {
+		defer spendEvent.Cancel()
+	}
+
+	log := log.Ctx(ctx)
+
+	ticker := time.NewTicker(s.spendConfirmationCheckInterval())
+	defer ticker.Stop()
+
+	// spend whose transition is provisional
+	var pending *notifier.SpendDetail
+	for {
+		select {
+		case spendDetail := <-spendEvent.Spend:
+			var provisional bool
+			err := s.runForDelegation(quitCtx, delegationTaskSourceBtc, stakingTxHashHex, func() (err error) {
+				provisional, err = s.applySpend(quitCtx, stakingTxHashHex, spendDetail, handle)
+				return err
+			})
+			if err != nil {
+				log.Error().
+					Err(err).
+					Str("staking_tx", stakingTxHashHex).
+					Stringer("spending_tx", spendDetail.SpendingTx.TxHash()).
+					Msg("failed to handle spend")
+				return
+			}
+			if !provisional {
+				return
+			}
+			pending = spendDetail
+
+		case <-spendEvent.Reorg:
+			metrics.IncBtcSpendReorg(pending != nil)
+			if pending == nil {
+				continue
+			}
+
+			spendingTxHash := pending.SpendingTx.TxHash().String()
+			log.Warn().
+				Str("staking_tx", stakingTxHashHex).
+				Str("spending_tx", spendingTxHash).
+				Int32("spending_height", pending.SpendingHeight).
+				Msg("spending tx has been reorged out, reverting state transition")
+
+			if err := s.runForDelegation(quitCtx, delegationTaskSourceBtc, stakingTxHashHex, func() error {
+				return s.revertSpend(quitCtx, stakingTxHashHex, spendingTxHash)
+			}); err != nil {
+				log.Error().
+					Err(err).
+					Str("staking_tx", stakingTxHashHex).
+					Str("spending_tx", spendingTxHash).
+					Msg("failed to revert provisional spend")
+				return
+			}
+			pending = nil
+
+		case <-ticker.C:
+			if pending == nil {
+				continue
+			}
+
+			confirmed, err := s.isSpendConfirmed(quitCtx, pending)
+			if err != nil {
+				log.Warn().
+					Err(err).
+					Str("staking_tx", stakingTxHashHex).
+					Msg("failed to check spend confirmations")
+				continue
+			}
+			if !confirmed {
+				continue
+			}
+
+			if err := s.runForDelegation(quitCtx, delegationTaskSourceBtc, stakingTxHashHex, func() error {
+				return s.updateDelegation(quitCtx, stakingTxHashHex, func(current *model.BTCDelegationDetails) error {
+					return s.db.ConfirmProvisionalSpend(
+						quitCtx, stakingTxHashHex, pending.SpendingTx.TxHash().String(),
+						db.WithExpectedVersion(current.Version),
+					)
+				})
+			}); err != nil {
+				log.Error().
+					Err(err).
+					Str("staking_tx", stakingTxHashHex).
+					Msg("failed to confirm provisional spend")
+			}
+			return
+
+		case <-quitCtx.Done():
+			return
+		}
+	}
+}
+
+// applySpend handles the spend and records the state transition it caused as provisional.
+// It returns false if the spend hasn't changed the delegation state, so there is nothing to revert.
+func (s *Service) applySpend(
+	ctx context.Context,
+	stakingTxHashHex string,
+	spendDetail *notifier.SpendDetail,
+	handle spendHandler,
+) (bool, error) {
+	spendingTxHash := spendDetail.SpendingTx.TxHash().String()
+
+	before, err := s.db.GetBTCDelegationByStakingTxHash(ctx, stakingTxHashHex)
+	if err != nil {
+		return false, fmt.Errorf("failed to get BTC delegation by staking tx hash: %w", err)
+	}
+	// spend applied before restart is delivered again when notifications are resubscribed.
+	// It's still handled to register notifications of the outputs it creates
+	applied := before.FindProvisionalSpend(spendingTxHash) >= 0
+
+	if err := handle(ctx, spendDetail); err != nil {
+		if !applied {
+			return false, err
+		}
+		log.Ctx(ctx).Debug().
+			Err(err).
+			Str("staking_tx", stakingTxHashHex).
+			Str("spending_tx", spendingTxHash).
+			Msg("failed to handle already applied spend")
+	}
+	if applied {
+		return true, nil
+	}
+
+	var changed bool
+	err = s.updateDelegation(ctx, stakingTxHashHex, func(after *model.BTCDelegationDetails) error {
+		changed = after.State != before.State || after.SubState != before.SubState
+		if !changed {
+			return nil
+		}
+		spend := model.NewProvisionalSpend(before, after, spendingTxHash, uint32(spendDetail.SpendingHeight))
+		return s.db.SaveProvisionalSpend(ctx, stakingTxHashHex, spend, db.WithExpectedVersion(after.Version))
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to save provisional spend: %w", err)
+	}
+
+	return changed, nil
+}
+
+// revertSpend restores the delegation to the state before the spend, removes timelock expire
+// and stops watching the output created by the spend and notifies consumers if the delegation
+// becomes active again
+func (s *Service) revertSpend(ctx context.Context, stakingTxHashHex, spendingTxHash string) error {
+	var reverted []model.ProvisionalSpend
+	err := s.retryOnConflict(ctx, func() (err error) {
+		reverted, err = s.db.RevertProvisionalSpend(ctx, stakingTxHashHex, spendingTxHash)
+		return err
+	})
+	if err != nil {
+		if db.IsSupersededError(err) {
+			// the delegation has moved on from the reorged state, reverting would lose the later transition
+			log.Ctx(ctx).Error().
+				Err(err).
+				Str("staking_tx", stakingTxHashHex).
+				Str("spending_tx", spendingTxHash).
+				Msg("reorged spend is followed by another transition, it can't be reverted")
+			return nil
+		}
+		if db.IsNotFoundError(err) {
+			// already reverted together with the spend it depends on
+			log.Ctx(ctx).Debug().
+				Err(err).
+				Str("staking_tx", stakingTxHashHex).
+				Str("spending_tx", spendingTxHash).
+				Msg("provisional spend not found")
+			return nil
+		}
+		return err
+	}
+
+	for _, spend := range reverted {
+		metrics.IncRevertedStateTransition(spend.State.String(), spend.PreviousState.String())
+		s.spendWatches.cancel(spend.SpendingTxHash)
+
+		// unbonding and slashing spends start timelock of the new output
+		if spend.State == types.StateUnbonding || spend.State == types.StateSlashed {
+			if err := s.db.DeleteTimeLockExpire(ctx, stakingTxHashHex, spend.SubState); err != nil {
+				return err
+			}
+		}
+		// unknown spend deleted timelock of the output it spent
+		if spend.State == types.StateUnknownSpend &&
+			(spend.PreviousState == types.StateUnbonding || spend.PreviousState == types.StateSlashed) {
+			if err := s.restoreTimeLockExpire(ctx, stakingTxHashHex); err != nil {
+				return err
+			}
+		}
+	}
+
+	// consumers were notified about unbonding when the staking output was spent
+	if reverted[0].PreviousState == types.StateActive {
+		delegation, err := s.db.GetBTCDelegationByStakingTxHash(ctx, stakingTxHashHex)
+		if err != nil {
+			return fmt.Errorf("failed to get BTC delegation by staking tx hash: %w", err)
+		}
+		if err := s.emitActiveDelegationEvent(ctx, delegation); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Service) isSpendConfirmed(ctx context.Context, spendDetail *notifier.SpendDetail) (bool, error) {
+	checkpointParams, err := s.db.GetCheckpointParams(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to get checkpoint params: %w", err)
+	}
+
+	tipHeight, err := s.btc.GetTipHeight(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to get BTC tip height: %w", err)
+	}
+
+	// the block including spending tx is the first confirmation
+	confirmations := int64(tipHeight) - int64(spendDetail.SpendingHeight) + 1
+	return confirmations >= int64(checkpointParams.BtcConfirmationDepth), nil
+}
+
+func (s *Service) spendConfirmationCheckInterval() time.Duration {
+	if s.cfg.BTC.BlockPollingInterval > 0 {
+		return s.cfg.BTC.BlockPollingInterval
+	}
+	return defaultSpendConfirmationCheckInterval
+}
@@ -0,0 +1,220 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/bbnclient"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/btcsuite/btcd/wire"
+	notifier "github.com/lightningnetwork/lnd/chainntnfs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const testStakingTxHash = "2e95583042e18617a65800ba917de386d8d1081211948f06fc53566194e9a365"
+
+// provisionalSpendTestConfig polls BTC tip fast, so watchers don't slow tests down
+var provisionalSpendTestConfig = withBTCConfig(config.BTCConfig{BlockPollingInterval: time.Millisecond})
+
+func testSpendDetail(height int32) *notifier.SpendDetail {
+	tx := wire.NewMsgTx(2)
+	tx.AddTxOut(wire.NewTxOut(1000, []byte{0x51}))
+	return &notifier.SpendDetail{SpendingTx: tx, SpendingHeight: height}
+}
+
+// runWatchSpend starts the watcher and returns channel closed once it exits
+func runWatchSpend(s *Service, spendEvent *notifier.SpendEvent, handle spendHandler) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.watchSpend(context.Background(), spendEvent, testStakingTxHash, handle)
+	}()
+	return done
+}
+
+func waitDone(t *testing.T, done <-chan struct{}) {
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("watcher hasn't finished")
+	}
+}
+
+func TestWatchSpend(t *testing.T) {
+	active := &model.BTCDelegationDetails{
+		StakingTxHashHex: testStakingTxHash,
+		State:            types.StateActive,
+		StateHistory:     []model.StateRecord{{State: types.StatePending}, {State: types.StateActive}},
+	}
+	unbonding := &model.BTCDelegationDetails{
+		StakingTxHashHex: testStakingTxHash,
+		State:            types.StateUnbonding,
+		SubState:         types.SubStateEarlyUnbonding,
+		StateHistory:     append(active.StateHistory, model.StateRecord{State: types.StateUnbonding}),
+	}
+	noopHandler := func(context.Context, *notifier.SpendDetail) error { return nil }
+
+	t.Run("confirmed spend", func(t *testing.T) {
+		s, m := newTestService(t, provisionalSpendTestConfig)
+		spendDetail := testSpendDetail(100)
+		spendingTxHash := spendDetail.SpendingTx.TxHash().String()
+
+		m.db.On("GetBTCDelegationByStakingTxHash", mock.Anything, testStakingTxHash).Return(active, nil).Once()
+		m.db.On("GetBTCDelegationByStakingTxHash", mock.Anything, testStakingTxHash).Return(unbonding, nil).Once()
+		m.db.On("SaveProvisionalSpend", mock.Anything, testStakingTxHash, mock.MatchedBy(func(spend model.ProvisionalSpend) bool {
+			return spend.SpendingTxHash == spendingTxHash &&
+				spend.State == types.StateUnbonding &&
+				spend.PreviousState == types.StateActive &&
+				spend.PreviousStateHistoryLength == 2
+		}), mock.Anything).Return(nil).Once()
+		m.db.On("GetCheckpointParams", mock.Anything).Return(&bbnclient.CheckpointParams{BtcConfirmationDepth: 10}, nil)
+		// first check: 9 confirmations, second check: 10 confirmations
+		m.btc.On("GetTipHeight", mock.Anything).Return(uint64(108), nil).Once()
+		m.btc.On("GetTipHeight", mock.Anything).Return(uint64(109), nil).Once()
+		m.db.On("GetBTCDelegationByStakingTxHash", mock.Anything, testStakingTxHash).Return(unbonding, nil).Once()
+		m.db.On("ConfirmProvisionalSpend", mock.Anything, testStakingTxHash, spendingTxHash, mock.Anything).Return(nil).Once()
+
+		spendEvent := notifier.NewSpendEvent(nil)
+		done := runWatchSpend(s, spendEvent, noopHandler)
+		spendEvent.Spend <- spendDetail
+		waitDone(t, done)
+	})
+	t.Run("reorged spend is reverted", func(t *testing.T) {
+		s, m := newTestService(t, provisionalSpendTestConfig)
+		spendDetail := testSpendDetail(100)
+		spendingTxHash := spendDetail.SpendingTx.TxHash().String()
+
+		m.db.On("GetBTCDelegationByStakingTxHash", mock.Anything, testStakingTxHash).Return(active, nil).Once()
+		m.db.On("GetBTCDelegationByStakingTxHash", mock.Anything, testStakingTxHash).Return(unbonding, nil).Once()
+		saved := make(chan struct{})
+		m.db.On("SaveProvisionalSpend", mock.Anything, testStakingTxHash, mock.Anything, mock.Anything).Return(nil).Once().
+			Run(func(mock.Arguments) { close(saved) })
+		m.db.On("GetCheckpointParams", mock.Anything).Return(&bbnclient.CheckpointParams{BtcConfirmationDepth: 10}, nil).Maybe()
+		m.btc.On("GetTipHeight", mock.Anything).Return(uint64(100), nil).Maybe()
+
+		revertedSpends := []model.ProvisionalSpend{model.NewProvisionalSpend(active, unbonding, spendingTxHash, 100)}
+		m.db.On("RevertProvisionalSpend", mock.Anything, testStakingTxHash, spendingTxHash).Return(revertedSpends, nil).Once()
+		m.db.On("DeleteTimeLockExpire", mock.Anything, testStakingTxHash, types.SubStateEarlyUnbonding).Return(nil).Once()
+		m.db.On("GetBTCDelegationByStakingTxHash", mock.Anything, testStakingTxHash).Return(active, nil).Once()
+		reverted := make(chan struct{})
+		m.consumer.On("PushActiveStakingEvent", mock.Anything, mock.Anything).Return(nil).Once().
+			Run(func(mock.Arguments) { close(reverted) })
+
+		// watcher of the unbonding output created by the spend
+		unbondingWatchCtx, unbondingWatchDone := s.spendWatches.start(context.Background(), spendingTxHash)
+		defer unbondingWatchDone()
+
+		spendEvent := notifier.NewSpendEvent(nil)
+		done := runWatchSpend(s, spendEvent, noopHandler)
+		spendEvent.Spend <- spendDetail
+		<-saved
+		spendEvent.Reorg <- struct{}{}
+		<-reverted
+		assert.Error(t, unbondingWatchCtx.Err())
+
+		// the output is spent again in the new chain, this time the spend doesn't change the state
+		m.db.On("GetBTCDelegationByStakingTxHash", mock.Anything, testStakingTxHash).Return(active, nil).Twice()
+		spendEvent.Spend <- testSpendDetail(101)
+		waitDone(t, done)
+	})
+	t.Run("superseded spend is kept", func(t *testing.T) {
+		s, m := newTestService(t, provisionalSpendTestConfig)
+		spendDetail := testSpendDetail(100)
+		spendingTxHash := spendDetail.SpendingTx.TxHash().String()
+
+		m.db.On("GetBTCDelegationByStakingTxHash", mock.Anything, testStakingTxHash).Return(active, nil).Once()
+		m.db.On("GetBTCDelegationByStakingTxHash", mock.Anything, testStakingTxHash).Return(unbonding, nil).Once()
+		saved := make(chan struct{})
+		m.db.On("SaveProvisionalSpend", mock.Anything, testStakingTxHash, mock.Anything, mock.Anything).Return(nil).Once().
+			Run(func(mock.Arguments) { close(saved) })
+		m.db.On("GetCheckpointParams", mock.Anything).Return(&bbnclient.CheckpointParams{BtcConfirmationDepth: 10}, nil).Maybe()
+		m.btc.On("GetTipHeight", mock.Anything).Return(uint64(100), nil).Maybe()
+		reverted := make(chan struct{})
+		m.db.On("RevertProvisionalSpend", mock.Anything, testStakingTxHash, spendingTxHash).
+			Return(nil, &db.SupersededError{Key: testStakingTxHash, Message: "superseded"}).Once().
+			Run(func(mock.Arguments) { close(reverted) })
+
+		unbondingWatchCtx, unbondingWatchDone := s.spendWatches.start(context.Background(), spendingTxHash)
+		defer unbondingWatchDone()
+
+		spendEvent := notifier.NewSpendEvent(nil)
+		done := runWatchSpend(s, spendEvent, noopHandler)
+		spendEvent.Spend <- spendDetail
+		<-saved
+		spendEvent.Reorg <- struct{}{}
+		<-reverted
+
+		// nothing is reverted, so the watcher of the unbonding output keeps running
+		m.db.On("GetBTCDelegationByStakingTxHash", mock.Anything, testStakingTxHash).Return(unbonding, nil).Twice()
+		spendEvent.Spend <- testSpendDetail(101)
+		waitDone(t, done)
+		assert.NoError(t, unbondingWatchCtx.Err())
+	})
+	t.Run("spend without transition is not provisional", func(t *testing.T) {
+		s, m := newTestService(t, provisionalSpendTestConfig)
+
+		m.db.On("GetBTCDelegationByStakingTxHash", mock.Anything, testStakingTxHash).Return(unbonding, nil).Twice()
+
+		spendEvent := notifier.NewSpendEvent(nil)
+		done := runWatchSpend(s, spendEvent, noopHandler)
+		spendEvent.Spend <- testSpendDetail(100)
+		waitDone(t, done)
+	})
+	t.Run("spend applied before restart", func(t *testing.T) {
+		s, m := newTestService(t, provisionalSpendTestConfig)
+		spendDetail := testSpendDetail(100)
+		spendingTxHash := spendDetail.SpendingTx.TxHash().String()
+
+		applied := *unbonding
+		applied.ProvisionalSpends = []model.ProvisionalSpend{model.NewProvisionalSpend(active, unbonding, spendingTxHash, 100)}
+		m.db.On("GetBTCDelegationByStakingTxHash", mock.Anything, testStakingTxHash).Return(&applied, nil).Once()
+		m.db.On("GetCheckpointParams", mock.Anything).Return(&bbnclient.CheckpointParams{BtcConfirmationDepth: 10}, nil)
+		m.btc.On("GetTipHeight", mock.Anything).Return(uint64(200), nil).Once()
+		m.db.On("GetBTCDelegationByStakingTxHash", mock.Anything, testStakingTxHash).Return(&applied, nil).Once()
+		m.db.On("ConfirmProvisionalSpend", mock.Anything, testStakingTxHash, spendingTxHash, mock.Anything).Return(nil).Once()
+
+		var handled bool
+		spendEvent := notifier.NewSpendEvent(nil)
+		done := runWatchSpend(s, spendEvent, func(context.Context, *notifier.SpendDetail) error {
+			handled = true
+			return nil
+		})
+		spendEvent.Spend <- spendDetail
+		waitDone(t, done)
+		assert.True(t, handled)
+	})
+}
+
+func TestNewProvisionalSpend(t *testing.T) {
+	before := &model.BTCDelegationDetails{
+		State:                types.StateUnbonding,
+		SubState:             types.SubStateEarlyUnbonding,
+		StateHistory:         make([]model.StateRecord, 3),
+		UnbondingStartHeight: 90,
+		SlashingTx:           model.SlashingTx{SlashingTxHex: "aa"},
+	}
+	after := &model.BTCDelegationDetails{
+		State:    types.StateWithdrawn,
+		SubState: types.SubStateEarlyUnbonding,
+	}
+
+	spend := model.NewProvisionalSpend(before, after, "tx", 100)
+	require.Equal(t, "tx", spend.SpendingTxHash)
+	assert.Equal(t, uint32(100), spend.SpendingHeight)
+	assert.Equal(t, types.StateWithdrawn, spend.State)
+	assert.Equal(t, types.StateUnbonding, spend.PreviousState)
+	assert.Equal(t, types.SubStateEarlyUnbonding, spend.PreviousSubState)
+	assert.Equal(t, 3, spend.PreviousStateHistoryLength)
+	assert.Equal(t, uint32(90), spend.PreviousUnbondingStartHeight)
+	assert.Equal(t, "aa", spend.PreviousSlashingTx.SlashingTxHex)
+
+	after.ProvisionalSpends = []model.ProvisionalSpend{spend}
+	assert.Equal(t, 0, after.FindProvisionalSpend("tx"))
+	assert.Equal(t, -1, after.FindProvisionalSpend("other"))
+}
@@ -9,6 +9,8 @@ import (
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/btcclient"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/utils/blocktime"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/utils/executor"
 )
 
 type Service struct {
@@ -20,6 +22,17 @@ type Service struct {
 	queueManager               consumer.EventConsumer
 	latestHeightChan           chan int64
 	stakingParamsLatestVersion uint32
+	// delegationExecutor serializes processing of BBN events and BTC spends of the same delegation
+	delegationExecutor *executor.KeyedExecutor
+	blockTimes         *blocktime.Resolver
+	// eventSchemas picks schema version BBN events are decoded with
+	eventSchemas *eventSchemas
+	// eventHandlers dispatches BBN events to their handlers
+	eventHandlers *EventHandlerRegistry
+	// stakingTxWatches holds confirmation notifications of staking txs waiting for inclusion
+	stakingTxWatches *stakingTxWatches
+	// spendWatches holds watchers of outputs created by spending txs, they are cancelled on reorg
+	spendWatches *spendWatches
 }
 
 func NewService(
@@ -33,7 +46,11 @@ func NewService(
 	latestHeightChan := make(chan int64)
 	// add retry wrapper to the btc notifier
 	btcNotifier = newBtcNotifierWithRetries(btcNotifier)
-	return &Service{
+	var schemaUpgrades []config.EventSchemaUpgrade
+	if cfg != nil {
+		schemaUpgrades = cfg.BBN.EventSchemaUpgrades
+	}
+	s := &Service{
 		cfg:                        cfg,
 		db:                         db,
 		btc:                        btc,
@@ -42,11 +59,18 @@ func NewService(
 		queueManager:               consumer,
 		latestHeightChan:           latestHeightChan,
 		stakingParamsLatestVersion: 0,
+		delegationExecutor:         executor.NewKeyedExecutor(),
+		eventSchemas:               newEventSchemas(schemaUpgrades),
+		stakingTxWatches:           newStakingTxWatches(),
+		spendWatches:               newSpendWatches(),
 	}
+	s.blockTimes = s.newBlockTimeResolver()
+	s.eventHandlers = s.newEventHandlerRegistry()
+	return s
 }
 
 func (s *Service) StartIndexerSync(ctx context.Context) error {
-	if err := s.bbn.Start(); err != nil {
+	if err := s.bbn.Start(ctx); err != nil {
 		return fmt.Errorf("failed to start BBN client: %w", err)
 	}
 
@@ -60,6 +84,10 @@ func (s *Service) StartIndexerSync(ctx context.Context) error {
 	// fetching and storing ChainID, note that this is blocking operation (!)
 	// also if we fail to store chainID after few attempts it will panic
 	s.fetchAndSaveNetworkInfo(ctx)
+	// Seed empty database with the chain state if indexer is configured to start from specific height
+	if err := s.bootstrapFromHeight(ctx); err != nil {
+		return fmt.Errorf("failed to bootstrap from BBN height: %w", err)
+	}
 
 	// Sync global parameters
 	s.SyncGlobalParams(ctx)
@@ -67,8 +95,14 @@ func (s *Service) StartIndexerSync(ctx context.Context) error {
 	s.ResubscribeToMissedBtcNotifications(ctx)
 	// Start the expiry checker
 	s.StartExpiryChecker(ctx)
+	// Start emitting expiring soon events
+	s.StartExpiringSoonNotifier(ctx)
 	// Start the stats poller
 	s.StartStatsPoller(ctx)
+	// Start the unlock projection poller
+	s.StartUnlockProjectionPoller(ctx)
+	// Start tracking staking txs of pre-approval delegations
+	s.StartStakingTxTracker(ctx)
 	// Start the websocket event subscription process
 	if err := s.SubscribeToBbnEvents(ctx); err != nil {
 		return fmt.Errorf("failed to subscribe to BBN events: %w", err)
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/utils/blocktime"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/utils/executor"
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/mocks"
+)
+
+// testMocks are the mocked dependencies of the service built by newTestService
+type testMocks struct {
+	db       *mocks.DbInterface
+	bbn      *mocks.BbnInterface
+	btc      *mocks.BtcInterface
+	notifier *mocks.BtcNotifier
+	consumer *mocks.EventConsumer
+}
+
+// testServiceOption changes the service built by newTestService
+type testServiceOption func(*Service)
+
+// withPollerConfig sets poller config of the service
+func withPollerConfig(cfg config.PollerConfig) testServiceOption {
+	return func(s *Service) {
+		s.cfg.Poller = cfg
+	}
+}
+
+// withBTCConfig sets BTC config of the service
+func withBTCConfig(cfg config.BTCConfig) testServiceOption {
+	return func(s *Service) {
+		s.cfg.BTC = cfg
+	}
+}
+
+// newTestService returns service with all its clients mocked. Mocks assert their expectations
+// once the test finishes, the ones the test doesn't set up must not be called.
+func newTestService(t *testing.T, opts ...testServiceOption) (*Service, *testMocks) {
+	m := &testMocks{
+		db:       mocks.NewDbInterface(t),
+		bbn:      mocks.NewBbnInterface(t),
+		btc:      mocks.NewBtcInterface(t),
+		notifier: mocks.NewBtcNotifier(t),
+		consumer: mocks.NewEventConsumer(t),
+	}
+
+	s := &Service{
+		cfg:                &config.Config{},
+		db:                 m.db,
+		btc:                m.btc,
+		btcNotifier:        m.notifier,
+		bbn:                m.bbn,
+		queueManager:       m.consumer,
+		delegationExecutor: executor.NewKeyedExecutor(),
+		blockTimes:         blocktime.NewResolver(m.bbn, m.btc, blockTimeCacheSize),
+		stakingTxWatches:   newStakingTxWatches(),
+		spendWatches:       newSpendWatches(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s, m
+}
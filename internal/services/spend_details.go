@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/utils"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// newBtcSpendDetails decodes the tx that spent the delegation output of spentValue
+// and looks up the block it was included in
+func (s *Service) newBtcSpendDetails(
+	ctx context.Context, spendingTx *wire.MsgTx, spendingHeight uint32, spentValue int64,
+) (*model.BtcSpendDetails, error) {
+	btcParams, err := utils.GetBTCParams(s.cfg.BTC.NetParams)
+	if err != nil {
+		return nil, err
+	}
+
+	blockHash, blockTimestamp, err := s.blockTimes.BtcBlock(ctx, spendingHeight)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get block: %w", err)
+	}
+
+	details := decodeSpendingTx(spendingTx, spentValue, btcParams)
+	details.BlockHeight = spendingHeight
+	details.BlockHash = blockHash
+	details.BlockTimestamp = blockTimestamp
+
+	return details, nil
+}
+
+// decodeSpendingTx fills the fields of BtcSpendDetails that depend only on the tx
+func decodeSpendingTx(tx *wire.MsgTx, spentValue int64, btcParams *chaincfg.Params) *model.BtcSpendDetails {
+	details := &model.BtcSpendDetails{
+		TxHash:     tx.TxHash().String(),
+		SpentValue: spentValue,
+		Outputs:    make([]model.BtcTxOutput, len(tx.TxOut)),
+	}
+
+	var outputsValue int64
+	for i, out := range tx.TxOut {
+		details.Outputs[i] = model.BtcTxOutput{
+			Address: outputAddress(out.PkScript, btcParams),
+			Value:   out.Value,
+		}
+		outputsValue += out.Value
+	}
+	// values of other inputs are unknown
+	if len(tx.TxIn) == 1 {
+		fee := spentValue - outputsValue
+		details.Fee = &fee
+	}
+
+	return details
+}
+
+// outputAddress encodes the output script as address or returns empty string
+// if the script doesn't pay to a single address
+func outputAddress(pkScript []byte, btcParams *chaincfg.Params) string {
+	_, addrs, _, err := txscript.ExtractPkScriptAddrs(pkScript, btcParams)
+	if err != nil || len(addrs) != 1 {
+		return ""
+	}
+	return addrs[0].EncodeAddress()
+}
+
+// newSlashingTxDetails splits the outputs of the slashing tx into slashed and change amounts
+func (s *Service) newSlashingTxDetails(
+	ctx context.Context, slashingTx *wire.MsgTx, spendingHeight uint32, spentValue int64,
+) (*model.SlashingTxDetails, error) {
+	details, err := s.newBtcSpendDetails(ctx, slashingTx, spendingHeight, spentValue)
+	if err != nil {
+		return nil, err
+	}
+
+	// slashing tx is validated to have slashing output first and change output second
+	slashingDetails := &model.SlashingTxDetails{BtcSpendDetails: *details}
+	if len(slashingTx.TxOut) > 1 {
+		slashingDetails.SlashedAmount = slashingTx.TxOut[0].Value
+		slashingDetails.ChangeAmount = slashingTx.TxOut[1].Value
+	}
+
+	return slashingDetails, nil
+}
+
+// newWithdrawalTx builds withdrawal tx of the delegation output of spentValue
+func (s *Service) newWithdrawalTx(
+	ctx context.Context, withdrawalTx *wire.MsgTx, spendingHeight uint32, spentValue int64,
+) (*model.WithdrawalTx, error) {
+	details, err := s.newBtcSpendDetails(ctx, withdrawalTx, spendingHeight, spentValue)
+	if err != nil {
+		return nil, err
+	}
+
+	var destinationAddress string
+	if len(details.Outputs) > 0 {
+		destinationAddress = details.Outputs[0].Address
+	}
+
+	return &model.WithdrawalTx{
+		TxHash:             details.TxHash,
+		DestinationAddress: destinationAddress,
+		Details:            details,
+	}, nil
+}
+
+// stakingOutputValue returns value of the delegation staking output
+func stakingOutputValue(delegation *model.BTCDelegationDetails) (int64, error) {
+	return txOutputValue(delegation.StakingTxHex, delegation.StakingOutputIdx)
+}
+
+// unbondingOutputValue returns value of the delegation unbonding output
+func unbondingOutputValue(delegation *model.BTCDelegationDetails) (int64, error) {
+	return txOutputValue(delegation.UnbondingTx, 0)
+}
+
+// slashingChangeOutputValue returns value of the change output of the slashing tx
+// that slashed the delegation with the given sub state
+func slashingChangeOutputValue(delegation *model.BTCDelegationDetails, subState types.DelegationSubState) (int64, error) {
+	slashingTxHex := delegation.SlashingTx.SlashingTxHex
+	if subState == types.SubStateEarlyUnbondingSlashing {
+		slashingTxHex = delegation.SlashingTx.UnbondingSlashingTxHex
+	}
+	return txOutputValue(slashingTxHex, 1)
+}
+
+func txOutputValue(txHex string, outputIdx uint32) (int64, error) {
+	_, txOut, err := txOutput(txHex, outputIdx)
+	if err != nil {
+		return 0, err
+	}
+	return txOut.Value, nil
+}
+
+// txOutput deserializes the tx and returns it together with its output at outputIdx
+func txOutput(txHex string, outputIdx uint32) (*wire.MsgTx, *wire.TxOut, error) {
+	tx, err := utils.DeserializeBtcTransactionFromHex(txHex)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to deserialize tx: %w", err)
+	}
+	if int(outputIdx) >= len(tx.TxOut) {
+		return nil, nil, fmt.Errorf("tx %s has no output %d", tx.TxHash(), outputIdx)
+	}
+	return tx, tx.TxOut[outputIdx], nil
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/utils"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testAddressScript(t *testing.T, seed byte) (string, []byte) {
+	t.Helper()
+
+	hash := make([]byte, 20)
+	hash[0] = seed
+	addr, err := btcutil.NewAddressWitnessPubKeyHash(hash, &chaincfg.SigNetParams)
+	require.NoError(t, err)
+	pkScript, err := txscript.PayToAddrScript(addr)
+	require.NoError(t, err)
+	return addr.EncodeAddress(), pkScript
+}
+
+func TestDecodeSpendingTx(t *testing.T) {
+	addr1, script1 := testAddressScript(t, 1)
+	addr2, script2 := testAddressScript(t, 2)
+
+	t.Run("single input", func(t *testing.T) {
+		tx := wire.NewMsgTx(2)
+		tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{}, nil, nil))
+		tx.AddTxOut(wire.NewTxOut(7000, script1))
+		tx.AddTxOut(wire.NewTxOut(2500, script2))
+		// OP_RETURN output has no address
+		tx.AddTxOut(wire.NewTxOut(0, []byte{txscript.OP_RETURN}))
+
+		details := decodeSpendingTx(tx, 10000, &chaincfg.SigNetParams)
+		assert.Equal(t, tx.TxHash().String(), details.TxHash)
+		assert.Equal(t, int64(10000), details.SpentValue)
+		require.NotNil(t, details.Fee)
+		assert.Equal(t, int64(500), *details.Fee)
+		assert.Equal(t, []model.BtcTxOutput{
+			{Address: addr1, Value: 7000},
+			{Address: addr2, Value: 2500},
+			{Value: 0},
+		}, details.Outputs)
+	})
+	t.Run("fee is unknown with other inputs", func(t *testing.T) {
+		tx := wire.NewMsgTx(2)
+		tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{}, nil, nil))
+		tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: 1}, nil, nil))
+		tx.AddTxOut(wire.NewTxOut(15000, script1))
+
+		details := decodeSpendingTx(tx, 10000, &chaincfg.SigNetParams)
+		assert.Nil(t, details.Fee)
+	})
+}
+
+func TestNewSlashingTxDetails(t *testing.T) {
+	ctx := context.Background()
+	slashingAddr, slashingScript := testAddressScript(t, 1)
+	_, changeScript := testAddressScript(t, 2)
+
+	s, m := newTestService(t, withBTCConfig(config.BTCConfig{NetParams: utils.BtcSignet.String()}))
+	m.btc.On("GetBlockHash", ctx, uint32(100)).Return("block_hash", nil).Once()
+	m.btc.On("GetBlockTimestampByHash", ctx, "block_hash").Return(int64(1700000000), nil).Once()
+
+	tx := wire.NewMsgTx(2)
+	tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{}, nil, nil))
+	tx.AddTxOut(wire.NewTxOut(1000, slashingScript))
+	tx.AddTxOut(wire.NewTxOut(8800, changeScript))
+
+	details, err := s.newSlashingTxDetails(ctx, tx, 100, 10000)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1000), details.SlashedAmount)
+	assert.Equal(t, int64(8800), details.ChangeAmount)
+	assert.Equal(t, slashingAddr, details.Outputs[0].Address)
+	assert.Equal(t, int64(200), *details.Fee)
+	assert.Equal(t, uint32(100), details.BlockHeight)
+	assert.Equal(t, "block_hash", details.BlockHash)
+	assert.Equal(t, int64(1700000000), details.BlockTimestamp)
+	assert.Equal(t, uint32(6), details.Confirmations(105))
+	assert.Zero(t, details.Confirmations(99))
+}
@@ -0,0 +1,457 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/utils"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/utils/poller"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	notifier "github.com/lightningnetwork/lnd/chainntnfs"
+	"github.com/rs/zerolog/log"
+)
+
+// statuses of tracked staking txs, used as metric labels
+const (
+	stakingTxStatusIncluded = "included"
+	stakingTxStatusMempool  = "mempool"
+	stakingTxStatusMissing  = "missing"
+	stakingTxStatusStale    = "stale"
+)
+
+// stakingTxWatches holds confirmation notifications registered for staking txs
+// that aren't included yet, keyed by staking tx hash
+type stakingTxWatches struct {
+	mu      sync.Mutex
+	watches map[string]*stakingTxWatch
+}
+
+type stakingTxWatch struct {
+	cancel context.CancelFunc
+}
+
+func newStakingTxWatches() *stakingTxWatches {
+	return &stakingTxWatches{watches: make(map[string]*stakingTxWatch)}
+}
+
+// add stores the watch unless the staking tx is already watched
+func (w *stakingTxWatches) add(stakingTxHashHex string, watch *stakingTxWatch) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, ok := w.watches[stakingTxHashHex]; ok {
+		return false
+	}
+	w.watches[stakingTxHashHex] = watch
+	return true
+}
+
+// has returns true if the staking tx is watched
+func (w *stakingTxWatches) has(stakingTxHashHex string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	_, ok := w.watches[stakingTxHashHex]
+	return ok
+}
+
+// remove deletes the watch if it's still the one stored for the staking tx
+func (w *stakingTxWatches) remove(stakingTxHashHex string, watch *stakingTxWatch) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.watches[stakingTxHashHex] == watch {
+		delete(w.watches, stakingTxHashHex)
+	}
+}
+
+// cancelExcept cancels watches of staking txs that are no longer waiting for inclusion
+func (w *stakingTxWatches) cancelExcept(waiting map[string]bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for stakingTxHashHex, watch := range w.watches {
+		if !waiting[stakingTxHashHex] {
+			watch.cancel()
+			delete(w.watches, stakingTxHashHex)
+		}
+	}
+}
+
+// StartStakingTxTracker starts periodic tracking of staking txs of pre-approval delegations
+func (s *Service) StartStakingTxTracker(ctx context.Context) {
+	trackerPoller := poller.NewPoller(
+		s.cfg.Poller.StakingTxPollingInterval,
+		metrics.RecordPollerDuration("staking_tx_tracker", s.trackStakingTxs),
+	)
+	go trackerPoller.Start(ctx)
+}
+
+// trackStakingTxs updates BTC status of staking txs of PENDING and VERIFIED delegations without
+// inclusion proof, they are processed in pages. Inclusion of the staking tx is reported by the BTC
+// notifier, the poller checks the mempool, detects reorged out inclusions and flags staking txs that
+// haven't been included within the configured number of blocks as stale. Stale staking txs are no
+// longer tracked.
+func (s *Service) trackStakingTxs(ctx context.Context) error {
+	tipHeight, err := s.btc.GetTipHeight(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get BTC tip height: %w", err)
+	}
+	tip := uint32(tipHeight)
+
+	waiting := make(map[string]bool)
+	counts := map[string]int{
+		stakingTxStatusIncluded: 0,
+		stakingTxStatusMempool:  0,
+		stakingTxStatusMissing:  0,
+		stakingTxStatusStale:    0,
+	}
+	limit := int64(s.cfg.Poller.StakingTxPageSize)
+	var afterStakingTxHash string
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		delegations, err := s.db.FindStakingTxTrackedDelegations(ctx, afterStakingTxHash, limit)
+		if err != nil {
+			return fmt.Errorf("failed to find delegations with tracked staking tx: %w", err)
+		}
+		for _, delegation := range delegations {
+			status, err := s.trackStakingTx(ctx, delegation, tip)
+			if err != nil {
+				// a single failing staking tx shouldn't block tracking of the others, its
+				// notification stays registered
+				log.Ctx(ctx).Error().
+					Err(err).
+					Str("staking_tx", delegation.StakingTxHashHex).
+					Msg("failed to track staking tx")
+				waiting[delegation.StakingTxHashHex] = true
+				continue
+			}
+			if status == "" {
+				continue
+			}
+
+			counts[status]++
+			if status == stakingTxStatusMempool || status == stakingTxStatusMissing {
+				waiting[delegation.StakingTxHashHex] = true
+			}
+		}
+
+		if int64(len(delegations)) < limit {
+			break
+		}
+		afterStakingTxHash = delegations[len(delegations)-1].StakingTxHashHex
+	}
+	s.stakingTxWatches.cancelExcept(waiting)
+
+	// stale staking txs flagged by previous runs aren't tracked anymore, they are only counted
+	stale, err := s.db.CountStaleStakingTxs(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to count stale staking txs: %w", err)
+	}
+	counts[stakingTxStatusStale] += int(stale)
+	for status, count := range counts {
+		metrics.RecordTrackedStakingTxs(status, count)
+	}
+
+	return nil
+}
+
+// trackStakingTx refreshes BTC status of the staking tx and watches its inclusion while it's waiting
+// for one. It returns the status of the staking tx or an empty string if it's no longer tracked.
+func (s *Service) trackStakingTx(
+	ctx context.Context, delegation *model.BTCDelegationDetails, tip uint32,
+) (string, error) {
+	var confirmation *model.StakingTxConfirmation
+	err := s.runForDelegation(ctx, delegationTaskSourceBtc, delegation.StakingTxHashHex, func() (err error) {
+		confirmation, err = s.refreshStakingTxConfirmation(ctx, delegation.StakingTxHashHex, tip)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	if confirmation == nil {
+		return "", nil
+	}
+
+	status := stakingTxStatus(confirmation)
+	if status == stakingTxStatusMempool || status == stakingTxStatusMissing {
+		if err := s.watchStakingTxConfirmation(ctx, delegation, confirmation); err != nil {
+			return "", fmt.Errorf("failed to register staking tx confirmation notification: %w", err)
+		}
+	}
+	return status, nil
+}
+
+// stakingTxTrackingStartHeight returns BTC height at which the delegation was created. It's estimated
+// from the creation time and the tip block time with 10 minute blocks, the tip is used if the creation
+// time is unknown.
+func (s *Service) stakingTxTrackingStartHeight(
+	ctx context.Context, delegation *model.BTCDelegationDetails, tip uint32,
+) (uint32, error) {
+	createdAt := delegation.BTCDelegationCreatedBlock.Timestamp
+	if createdAt == 0 {
+		return tip, nil
+	}
+	tipTimestamp, err := s.btcBlockTime(ctx, tip)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get BTC tip timestamp: %w", err)
+	}
+	if createdAt >= tipTimestamp {
+		return tip, nil
+	}
+
+	blocks := uint32((tipTimestamp - createdAt) / int64(btcBlockInterval.Seconds()))
+	if blocks >= tip {
+		return 0, nil
+	}
+	return tip - blocks, nil
+}
+
+// newStakingTxConfirmation returns the stored BTC status of the staking tx or a new one
+// if the staking tx hasn't been tracked yet
+func (s *Service) newStakingTxConfirmation(
+	ctx context.Context, delegation *model.BTCDelegationDetails, tip uint32,
+) (model.StakingTxConfirmation, error) {
+	if delegation.StakingTxConfirmation != nil {
+		return *delegation.StakingTxConfirmation, nil
+	}
+
+	startHeight, err := s.stakingTxTrackingStartHeight(ctx, delegation, tip)
+	if err != nil {
+		return model.StakingTxConfirmation{}, err
+	}
+	return model.StakingTxConfirmation{TrackingStartHeight: startHeight}, nil
+}
+
+// refreshStakingTxConfirmation updates BTC status of the staking tx as of the BTC tip. It returns
+// nil if the delegation is no longer tracked.
+func (s *Service) refreshStakingTxConfirmation(
+	ctx context.Context, stakingTxHashHex string, tip uint32,
+) (*model.StakingTxConfirmation, error) {
+	// the delegation is read again as the notifier could have recorded inclusion meanwhile
+	delegation, err := s.db.GetBTCDelegationByStakingTxHash(ctx, stakingTxHashHex)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get BTC delegation by staking tx hash: %w", err)
+	}
+	if !isStakingTxTracked(delegation) {
+		return nil, nil
+	}
+
+	previous := delegation.StakingTxConfirmation
+	confirmation, err := s.newStakingTxConfirmation(ctx, delegation, tip)
+	if err != nil {
+		return nil, err
+	}
+
+	if confirmation.IsIncluded() {
+		reorged := confirmation.BlockHeight > tip
+		if !reorged {
+			blockHash, err := s.btc.GetBlockHash(ctx, confirmation.BlockHeight)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get block hash at height %d: %w", confirmation.BlockHeight, err)
+			}
+			reorged = blockHash != confirmation.BlockHash
+		}
+		if reorged {
+			log.Ctx(ctx).Warn().
+				Str("staking_tx", stakingTxHashHex).
+				Uint32("block_height", confirmation.BlockHeight).
+				Str("block_hash", confirmation.BlockHash).
+				Msg("staking tx inclusion has been reorged out")
+			confirmation.BlockHeight = 0
+			confirmation.BlockHash = ""
+			confirmation.Confirmations = 0
+		}
+	}
+
+	if confirmation.IsIncluded() {
+		confirmation.InMempool = false
+		confirmation.Confirmations = tip - confirmation.BlockHeight + 1
+		confirmation.Stale = false
+	} else {
+		txHash, err := chainhash.NewHashFromStr(stakingTxHashHex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid staking tx hash: %w", err)
+		}
+		confirmation.InMempool, err = s.btc.IsTxInMempool(ctx, txHash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check staking tx in mempool: %w", err)
+		}
+		confirmation.Stale = tip >= confirmation.TrackingStartHeight+s.cfg.Poller.StakingTxStaleBlocks
+	}
+
+	if previous != nil && *previous == confirmation {
+		return &confirmation, nil
+	}
+	if confirmation.Stale && (previous == nil || !previous.Stale) {
+		log.Ctx(ctx).Warn().
+			Str("staking_tx", stakingTxHashHex).
+			Uint32("tracking_start_height", confirmation.TrackingStartHeight).
+			Uint32("tip_height", tip).
+			Bool("in_mempool", confirmation.InMempool).
+			Msg("staking tx hasn't been included in BTC, the delegation is stale")
+	}
+
+	confirmation.UpdatedAt = time.Now().Unix()
+	if err := s.updateDelegation(ctx, stakingTxHashHex, func(current *model.BTCDelegationDetails) error {
+		return s.db.UpdateStakingTxConfirmation(
+			ctx, stakingTxHashHex, &confirmation, db.WithExpectedVersion(current.Version),
+		)
+	}); err != nil {
+		if db.IsNotFoundError(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to update staking tx confirmation: %w", err)
+	}
+
+	return &confirmation, nil
+}
+
+// watchStakingTxConfirmation registers confirmation notification of the staking tx unless
+// it's already watched. The staking tx of pre-approval delegation is broadcast once the delegation
+// is created, so the notifier starts looking for it at the tracking start height.
+func (s *Service) watchStakingTxConfirmation(
+	ctx context.Context, delegation *model.BTCDelegationDetails, confirmation *model.StakingTxConfirmation,
+) error {
+	// the watch is stored before the notification is registered, so it's registered once per delegation
+	watchCtx, cancel := context.WithCancel(ctx)
+	watch := &stakingTxWatch{cancel: cancel}
+	if !s.stakingTxWatches.add(delegation.StakingTxHashHex, watch) {
+		cancel()
+		return nil
+	}
+
+	confEvent, err := s.registerStakingTxConfirmation(delegation, confirmation.TrackingStartHeight)
+	if err != nil {
+		s.stakingTxWatches.remove(delegation.StakingTxHashHex, watch)
+		cancel()
+		return err
+	}
+
+	go s.waitForStakingTxConfirmation(watchCtx, confEvent, delegation.StakingTxHashHex, watch)
+	return nil
+}
+
+func (s *Service) registerStakingTxConfirmation(
+	delegation *model.BTCDelegationDetails, heightHint uint32,
+) (*notifier.ConfirmationEvent, error) {
+	stakingTx, err := utils.DeserializeBtcTransactionFromHex(delegation.StakingTxHex)
+	if err != nil {
+		return nil, fmt.Errorf("failed to deserialize staking tx: %w", err)
+	}
+	if int(delegation.StakingOutputIdx) >= len(stakingTx.TxOut) {
+		return nil, fmt.Errorf("staking output index %d out of range", delegation.StakingOutputIdx)
+	}
+	txHash := stakingTx.TxHash()
+	pkScript := stakingTx.TxOut[delegation.StakingOutputIdx].PkScript
+
+	return s.btcNotifier.RegisterConfirmationsNtfn(&txHash, pkScript, 1, heightHint)
+}
+
+func (s *Service) waitForStakingTxConfirmation(
+	ctx context.Context, confEvent *notifier.ConfirmationEvent, stakingTxHashHex string, watch *stakingTxWatch,
+) {
+	defer s.stakingTxWatches.remove(stakingTxHashHex, watch)
+	if confEvent.Cancel != nil {
+		defer confEvent.Cancel()
+	}
+
+	select {
+	case confirmation := <-confEvent.Confirmed:
+		log.Ctx(ctx).Debug().
+			Str("staking_tx", stakingTxHashHex).
+			Uint32("block_height", confirmation.BlockHeight).
+			Msg("staking tx has been included in BTC")
+		err := s.runForDelegation(ctx, delegationTaskSourceBtc, stakingTxHashHex, func() error {
+			return s.recordStakingTxInclusion(ctx, stakingTxHashHex, confirmation)
+		})
+		if err != nil {
+			// the poller registers the notification again
+			log.Ctx(ctx).Error().
+				Err(err).
+				Str("staking_tx", stakingTxHashHex).
+				Msg("failed to record staking tx inclusion")
+		}
+	case <-ctx.Done():
+	}
+}
+
+// recordStakingTxInclusion stores the block including the staking tx on the delegation
+func (s *Service) recordStakingTxInclusion(
+	ctx context.Context, stakingTxHashHex string, inclusion *notifier.TxConfirmation,
+) error {
+	delegation, err := s.db.GetBTCDelegationByStakingTxHash(ctx, stakingTxHashHex)
+	if err != nil {
+		return fmt.Errorf("failed to get BTC delegation by staking tx hash: %w", err)
+	}
+	if !isStakingTxTracked(delegation) {
+		return nil
+	}
+
+	tipHeight, err := s.btc.GetTipHeight(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get BTC tip height: %w", err)
+	}
+	tip := uint32(tipHeight)
+
+	confirmation, err := s.newStakingTxConfirmation(ctx, delegation, tip)
+	if err != nil {
+		return err
+	}
+	confirmation.InMempool = false
+	confirmation.BlockHeight = inclusion.BlockHeight
+	confirmation.BlockHash = inclusion.BlockHash.String()
+	confirmation.Confirmations = 1
+	if tip > inclusion.BlockHeight {
+		confirmation.Confirmations = tip - inclusion.BlockHeight + 1
+	}
+	confirmation.Stale = false
+	confirmation.UpdatedAt = time.Now().Unix()
+
+	if err := s.updateDelegation(ctx, stakingTxHashHex, func(current *model.BTCDelegationDetails) error {
+		return s.db.UpdateStakingTxConfirmation(
+			ctx, stakingTxHashHex, &confirmation, db.WithExpectedVersion(current.Version),
+		)
+	}); err != nil {
+		if db.IsNotFoundError(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to update staking tx confirmation: %w", err)
+	}
+
+	return nil
+}
+
+// isStakingTxTracked returns true for pre-approval delegations waiting for the inclusion proof
+// unless their staking tx has been flagged as stale
+func isStakingTxTracked(delegation *model.BTCDelegationDetails) bool {
+	if delegation.State != types.StatePending && delegation.State != types.StateVerified {
+		return false
+	}
+	if delegation.StakingTxConfirmation != nil && delegation.StakingTxConfirmation.Stale {
+		return false
+	}
+	return !delegation.HasInclusionProof()
+}
+
+func stakingTxStatus(confirmation *model.StakingTxConfirmation) string {
+	switch {
+	case confirmation.IsIncluded():
+		return stakingTxStatusIncluded
+	case confirmation.Stale:
+		return stakingTxStatusStale
+	case confirmation.InMempool:
+		return stakingTxStatusMempool
+	default:
+		return stakingTxStatusMissing
+	}
+}
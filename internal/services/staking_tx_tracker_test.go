@@ -0,0 +1,275 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/lightningnetwork/lnd/chainntnfs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const stakingTxTestStaleBlocks = 10
+
+var stakingTxTrackerTestConfig = withPollerConfig(config.PollerConfig{
+	StakingTxStaleBlocks: stakingTxTestStaleBlocks,
+	StakingTxPageSize:    2,
+})
+
+// newPreApprovalTestDelegation returns VERIFIED delegation without inclusion proof
+func newPreApprovalTestDelegation(t *testing.T, confirmation *model.StakingTxConfirmation) *model.BTCDelegationDetails {
+	delegation, _, _ := newWithdrawableTestDelegation(t)
+	delegation.State = types.StateVerified
+	delegation.StartHeight = 0
+	delegation.EndHeight = 0
+	delegation.StakingTxConfirmation = confirmation
+	return delegation
+}
+
+func matchStakingTxConfirmation(expected model.StakingTxConfirmation) any {
+	return mock.MatchedBy(func(confirmation *model.StakingTxConfirmation) bool {
+		actual := *confirmation
+		actual.UpdatedAt = 0
+		return actual == expected && confirmation.UpdatedAt > 0
+	})
+}
+
+func TestRefreshStakingTxConfirmation(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("tracking starts", func(t *testing.T) {
+		s, m := newTestService(t, stakingTxTrackerTestConfig)
+		delegation := newPreApprovalTestDelegation(t, nil)
+		stakingTxHash, err := chainhash.NewHashFromStr(delegation.StakingTxHashHex)
+		require.NoError(t, err)
+
+		m.db.On("GetBTCDelegationByStakingTxHash", ctx, delegation.StakingTxHashHex).Return(delegation, nil).Twice()
+		m.btc.On("IsTxInMempool", ctx, stakingTxHash).Return(true, nil).Once()
+		expected := model.StakingTxConfirmation{TrackingStartHeight: 100, InMempool: true}
+		m.db.On("UpdateStakingTxConfirmation", ctx, delegation.StakingTxHashHex, matchStakingTxConfirmation(expected), mock.Anything).
+			Return(nil).Once()
+
+		confirmation, err := s.refreshStakingTxConfirmation(ctx, delegation.StakingTxHashHex, 100)
+		require.NoError(t, err)
+		assert.Equal(t, stakingTxStatusMempool, stakingTxStatus(confirmation))
+	})
+	t.Run("tracking starts at delegation creation", func(t *testing.T) {
+		s, m := newTestService(t, stakingTxTrackerTestConfig)
+		delegation := newPreApprovalTestDelegation(t, nil)
+		delegation.BTCDelegationCreatedBlock.Timestamp = 1_000_000
+
+		m.db.On("GetBTCDelegationByStakingTxHash", ctx, delegation.StakingTxHashHex).Return(delegation, nil).Twice()
+		m.btc.On("GetBlockHash", ctx, uint32(100)).Return("tip_hash", nil).Once()
+		// the delegation was created 3 blocks and a half before the tip
+		m.btc.On("GetBlockTimestampByHash", ctx, "tip_hash").Return(int64(1_000_000+3*600+300), nil).Once()
+		m.btc.On("IsTxInMempool", ctx, mock.Anything).Return(false, nil).Once()
+		expected := model.StakingTxConfirmation{TrackingStartHeight: 97}
+		m.db.On("UpdateStakingTxConfirmation", ctx, delegation.StakingTxHashHex, matchStakingTxConfirmation(expected), mock.Anything).
+			Return(nil).Once()
+
+		confirmation, err := s.refreshStakingTxConfirmation(ctx, delegation.StakingTxHashHex, 100)
+		require.NoError(t, err)
+		assert.Equal(t, stakingTxStatusMissing, stakingTxStatus(confirmation))
+	})
+	t.Run("unchanged status is not stored", func(t *testing.T) {
+		s, m := newTestService(t, stakingTxTrackerTestConfig)
+		delegation := newPreApprovalTestDelegation(t, &model.StakingTxConfirmation{
+			TrackingStartHeight: 100, InMempool: true, UpdatedAt: 1,
+		})
+
+		m.db.On("GetBTCDelegationByStakingTxHash", ctx, delegation.StakingTxHashHex).Return(delegation, nil).Once()
+		m.btc.On("IsTxInMempool", ctx, mock.Anything).Return(true, nil).Once()
+
+		_, err := s.refreshStakingTxConfirmation(ctx, delegation.StakingTxHashHex, 105)
+		require.NoError(t, err)
+	})
+	t.Run("staking tx becomes stale", func(t *testing.T) {
+		s, m := newTestService(t, stakingTxTrackerTestConfig)
+		delegation := newPreApprovalTestDelegation(t, &model.StakingTxConfirmation{
+			TrackingStartHeight: 100, InMempool: true, UpdatedAt: 1,
+		})
+
+		m.db.On("GetBTCDelegationByStakingTxHash", ctx, delegation.StakingTxHashHex).Return(delegation, nil).Twice()
+		m.btc.On("IsTxInMempool", ctx, mock.Anything).Return(false, nil).Once()
+		expected := model.StakingTxConfirmation{TrackingStartHeight: 100, Stale: true}
+		m.db.On("UpdateStakingTxConfirmation", ctx, delegation.StakingTxHashHex, matchStakingTxConfirmation(expected), mock.Anything).
+			Return(nil).Once()
+
+		confirmation, err := s.refreshStakingTxConfirmation(ctx, delegation.StakingTxHashHex, 100+stakingTxTestStaleBlocks)
+		require.NoError(t, err)
+		assert.Equal(t, stakingTxStatusStale, stakingTxStatus(confirmation))
+	})
+	t.Run("confirmations of included staking tx", func(t *testing.T) {
+		s, m := newTestService(t, stakingTxTrackerTestConfig)
+		delegation := newPreApprovalTestDelegation(t, &model.StakingTxConfirmation{
+			TrackingStartHeight: 100, BlockHeight: 102, BlockHash: "block_hash", Confirmations: 1, UpdatedAt: 1,
+		})
+
+		m.db.On("GetBTCDelegationByStakingTxHash", ctx, delegation.StakingTxHashHex).Return(delegation, nil).Twice()
+		m.btc.On("GetBlockHash", ctx, uint32(102)).Return("block_hash", nil).Once()
+		expected := model.StakingTxConfirmation{
+			TrackingStartHeight: 100, BlockHeight: 102, BlockHash: "block_hash", Confirmations: 4,
+		}
+		m.db.On("UpdateStakingTxConfirmation", ctx, delegation.StakingTxHashHex, matchStakingTxConfirmation(expected), mock.Anything).
+			Return(nil).Once()
+
+		confirmation, err := s.refreshStakingTxConfirmation(ctx, delegation.StakingTxHashHex, 105)
+		require.NoError(t, err)
+		assert.Equal(t, stakingTxStatusIncluded, stakingTxStatus(confirmation))
+	})
+	t.Run("inclusion reorged out", func(t *testing.T) {
+		s, m := newTestService(t, stakingTxTrackerTestConfig)
+		delegation := newPreApprovalTestDelegation(t, &model.StakingTxConfirmation{
+			TrackingStartHeight: 100, BlockHeight: 102, BlockHash: "block_hash", Confirmations: 1, UpdatedAt: 1,
+		})
+
+		m.db.On("GetBTCDelegationByStakingTxHash", ctx, delegation.StakingTxHashHex).Return(delegation, nil).Twice()
+		m.btc.On("GetBlockHash", ctx, uint32(102)).Return("other_block_hash", nil).Once()
+		m.btc.On("IsTxInMempool", ctx, mock.Anything).Return(true, nil).Once()
+		expected := model.StakingTxConfirmation{TrackingStartHeight: 100, InMempool: true}
+		m.db.On("UpdateStakingTxConfirmation", ctx, delegation.StakingTxHashHex, matchStakingTxConfirmation(expected), mock.Anything).
+			Return(nil).Once()
+
+		confirmation, err := s.refreshStakingTxConfirmation(ctx, delegation.StakingTxHashHex, 105)
+		require.NoError(t, err)
+		assert.False(t, confirmation.IsIncluded())
+	})
+	t.Run("delegation with inclusion proof is not tracked", func(t *testing.T) {
+		s, m := newTestService(t, stakingTxTrackerTestConfig)
+		delegation := newPreApprovalTestDelegation(t, nil)
+		delegation.StartHeight, delegation.EndHeight = 100, 200
+
+		m.db.On("GetBTCDelegationByStakingTxHash", ctx, delegation.StakingTxHashHex).Return(delegation, nil).Once()
+
+		confirmation, err := s.refreshStakingTxConfirmation(ctx, delegation.StakingTxHashHex, 105)
+		require.NoError(t, err)
+		assert.Nil(t, confirmation)
+	})
+	t.Run("stale staking tx is not tracked", func(t *testing.T) {
+		s, m := newTestService(t, stakingTxTrackerTestConfig)
+		delegation := newPreApprovalTestDelegation(t, &model.StakingTxConfirmation{
+			TrackingStartHeight: 100, Stale: true, UpdatedAt: 1,
+		})
+
+		m.db.On("GetBTCDelegationByStakingTxHash", ctx, delegation.StakingTxHashHex).Return(delegation, nil).Once()
+
+		confirmation, err := s.refreshStakingTxConfirmation(ctx, delegation.StakingTxHashHex, 120)
+		require.NoError(t, err)
+		assert.Nil(t, confirmation)
+	})
+	t.Run("delegation changed state meanwhile", func(t *testing.T) {
+		s, m := newTestService(t, stakingTxTrackerTestConfig)
+		delegation := newPreApprovalTestDelegation(t, nil)
+
+		m.db.On("GetBTCDelegationByStakingTxHash", ctx, delegation.StakingTxHashHex).Return(delegation, nil).Twice()
+		m.btc.On("IsTxInMempool", ctx, mock.Anything).Return(false, nil).Once()
+		m.db.On("UpdateStakingTxConfirmation", ctx, delegation.StakingTxHashHex, mock.Anything, mock.Anything).
+			Return(&db.NotFoundError{}).Once()
+
+		confirmation, err := s.refreshStakingTxConfirmation(ctx, delegation.StakingTxHashHex, 105)
+		require.NoError(t, err)
+		assert.Nil(t, confirmation)
+	})
+}
+
+func TestTrackStakingTxs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s, m := newTestService(t, stakingTxTrackerTestConfig)
+	delegation := newPreApprovalTestDelegation(t, nil)
+	stakingTxHash, err := chainhash.NewHashFromStr(delegation.StakingTxHashHex)
+	require.NoError(t, err)
+
+	m.btc.On("GetTipHeight", ctx).Return(uint64(100), nil).Once()
+	m.db.On("FindStakingTxTrackedDelegations", ctx, "", int64(2)).
+		Return([]*model.BTCDelegationDetails{delegation}, nil).Once()
+	m.db.On("CountStaleStakingTxs", ctx).Return(int64(0), nil).Twice()
+	m.db.On("GetBTCDelegationByStakingTxHash", mock.Anything, delegation.StakingTxHashHex).Return(delegation, nil).Times(5)
+	m.btc.On("IsTxInMempool", ctx, stakingTxHash).Return(true, nil).Twice()
+	m.db.On("UpdateStakingTxConfirmation", ctx, delegation.StakingTxHashHex,
+		matchStakingTxConfirmation(model.StakingTxConfirmation{TrackingStartHeight: 100, InMempool: true}), mock.Anything,
+	).Return(nil).Once()
+
+	confEvent := chainntnfs.NewConfirmationEvent(1, nil)
+	m.notifier.On("RegisterConfirmationsNtfn", stakingTxHash, mock.Anything, uint32(1), uint32(100)).
+		Return(confEvent, nil).Once()
+
+	require.NoError(t, s.trackStakingTxs(ctx))
+	assert.True(t, s.stakingTxWatches.has(delegation.StakingTxHashHex))
+
+	// the notification is registered once per delegation
+	delegation.StakingTxConfirmation = &model.StakingTxConfirmation{TrackingStartHeight: 100, InMempool: true, UpdatedAt: 1}
+	m.btc.On("GetTipHeight", ctx).Return(uint64(101), nil).Once()
+	m.db.On("FindStakingTxTrackedDelegations", ctx, "", int64(2)).
+		Return([]*model.BTCDelegationDetails{delegation}, nil).Once()
+	require.NoError(t, s.trackStakingTxs(ctx))
+	assert.True(t, s.stakingTxWatches.has(delegation.StakingTxHashHex))
+
+	// inclusion reported by the notifier is stored on the delegation
+	recorded := make(chan struct{})
+	m.btc.On("GetTipHeight", mock.Anything).Return(uint64(103), nil).Once()
+	m.db.On("UpdateStakingTxConfirmation", mock.Anything, delegation.StakingTxHashHex,
+		matchStakingTxConfirmation(model.StakingTxConfirmation{
+			TrackingStartHeight: 100, BlockHeight: 102, BlockHash: chainhash.Hash{0x01}.String(), Confirmations: 2,
+		}), mock.Anything,
+	).Run(func(mock.Arguments) { close(recorded) }).Return(nil).Once()
+
+	confEvent.Confirmed <- &chainntnfs.TxConfirmation{BlockHash: &chainhash.Hash{0x01}, BlockHeight: 102}
+	select {
+	case <-recorded:
+	case <-time.After(time.Second):
+		t.Fatal("staking tx inclusion was not recorded")
+	}
+	assert.Eventually(t, func() bool {
+		return !s.stakingTxWatches.has(delegation.StakingTxHashHex)
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestTrackStakingTxsPages(t *testing.T) {
+	ctx := context.Background()
+
+	s, m := newTestService(t, stakingTxTrackerTestConfig)
+	var delegations []*model.BTCDelegationDetails
+	for i := range 3 {
+		delegation := newPreApprovalTestDelegation(t, &model.StakingTxConfirmation{
+			TrackingStartHeight: 100, BlockHeight: 102, BlockHash: "block_hash", Confirmations: 4, UpdatedAt: 1,
+		})
+		delegation.StakingTxHashHex = chainhash.Hash{byte(i + 1)}.String()
+		delegations = append(delegations, delegation)
+		m.db.On("GetBTCDelegationByStakingTxHash", ctx, delegation.StakingTxHashHex).Return(delegation, nil).Once()
+	}
+
+	m.btc.On("GetTipHeight", ctx).Return(uint64(105), nil).Once()
+	m.btc.On("GetBlockHash", ctx, uint32(102)).Return("block_hash", nil).Times(3)
+	m.db.On("FindStakingTxTrackedDelegations", ctx, "", int64(2)).Return(delegations[:2], nil).Once()
+	m.db.On("FindStakingTxTrackedDelegations", ctx, delegations[1].StakingTxHashHex, int64(2)).
+		Return(delegations[2:], nil).Once()
+	m.db.On("CountStaleStakingTxs", ctx).Return(int64(5), nil).Once()
+
+	require.NoError(t, s.trackStakingTxs(ctx))
+}
+
+func TestStakingTxWatchesCancelExcept(t *testing.T) {
+	watches := newStakingTxWatches()
+
+	var cancelled []string
+	newWatch := func(stakingTxHashHex string) *stakingTxWatch {
+		return &stakingTxWatch{cancel: func() { cancelled = append(cancelled, stakingTxHashHex) }}
+	}
+	require.True(t, watches.add("a", newWatch("a")))
+	require.True(t, watches.add("b", newWatch("b")))
+	require.False(t, watches.add("a", newWatch("a")))
+
+	watches.cancelExcept(map[string]bool{"a": true})
+	assert.Equal(t, []string{"b"}, cancelled)
+	assert.True(t, watches.has("a"))
+	assert.False(t, watches.has("b"))
+}
@@ -0,0 +1,39 @@
+package services
+
+import (
+	"context"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// canTransition checks if the delegation can take the transition. If it can't, the rejected
+// transition is stored for investigation, failure to store it is only logged.
+func (s *Service) canTransition(
+	ctx context.Context, delegation *model.BTCDelegationDetails, transition types.Transition,
+) bool {
+	if transition.Allows(delegation.State) && delegation.SatisfiesGuard(transition.Guard) {
+		return true
+	}
+
+	log := log.Ctx(ctx)
+
+	rejected := model.NewRejectedTransition(delegation, delegation.StakingTxHashHex, transition)
+	log.Debug().
+		Str("staking_tx", delegation.StakingTxHashHex).
+		Stringer("trigger", transition.Trigger).
+		Stringer("current_state", delegation.State).
+		Stringer("new_state", transition.To).
+		Str("reason", rejected.Reason).
+		Msg("delegation can't take state transition")
+
+	if err := s.db.SaveRejectedTransition(ctx, rejected); err != nil {
+		log.Error().
+			Err(err).
+			Str("staking_tx", delegation.StakingTxHashHex).
+			Msg("failed to save rejected transition")
+	}
+
+	return false
+}
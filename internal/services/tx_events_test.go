@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	abcitypes "github.com/cometbft/cometbft/abci/types"
+	ctypes "github.com/cometbft/cometbft/rpc/core/types"
+	cmttypes "github.com/cometbft/cometbft/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func messageEvent(msgIndex, sender string) abcitypes.Event {
+	return abcitypes.Event{
+		Type: "message",
+		Attributes: []abcitypes.EventAttribute{
+			{Key: "action", Value: "/babylon.btcstaking.v1.MsgCreateBTCDelegation"},
+			{Key: "sender", Value: sender},
+			{Key: "msg_index", Value: msgIndex},
+		},
+	}
+}
+
+func msgEvent(eventType, msgIndex string) abcitypes.Event {
+	return abcitypes.Event{
+		Type:       eventType,
+		Attributes: []abcitypes.EventAttribute{{Key: "msg_index", Value: msgIndex}},
+	}
+}
+
+func TestGetEventsFromBlock(t *testing.T) {
+	ctx := context.Background()
+	const height = int64(100)
+
+	txs := cmttypes.Txs{cmttypes.Tx("tx0"), cmttypes.Tx("tx1")}
+	blockResults := &ctypes.ResultBlockResults{
+		TxsResults: []*abcitypes.ExecTxResult{
+			{Events: []abcitypes.Event{
+				messageEvent("0", "bbn1first"),
+				msgEvent("test.EventFirst", "0"),
+				// module event without msg_index, e.g. ante handler fee events
+				{Type: "tx"},
+			}},
+			{Events: []abcitypes.Event{
+				messageEvent("0", "bbn1second"),
+				// another message event of the same msg is emitted by the module
+				messageEvent("0", "bbn1module"),
+				msgEvent("test.EventSecond", "0"),
+				messageEvent("1", "bbn1third"),
+				msgEvent("test.EventThird", "1"),
+			}},
+		},
+		FinalizeBlockEvents: []abcitypes.Event{{Type: "test.EventBlock"}},
+	}
+
+	t.Run("tx events", func(t *testing.T) {
+		s, m := newTestService(t)
+		m.bbn.On("GetBlockResults", ctx, mock.Anything).Return(blockResults, nil)
+		m.bbn.On("GetBlock", ctx, mock.Anything).Return(&ctypes.ResultBlock{
+			Block: &cmttypes.Block{Data: cmttypes.Data{Txs: txs}},
+		}, nil)
+
+		events, err := s.getEventsFromBlock(ctx, height)
+		require.NoError(t, err)
+
+		byType := make(map[string]BbnEvent)
+		for _, event := range events {
+			byType[event.Event.Type] = event
+		}
+
+		first := &model.BbnTx{Hash: fmt.Sprintf("%X", txs[0].Hash()), Index: 0, Sender: "bbn1first"}
+		assert.Equal(t, first, byType["test.EventFirst"].Tx)
+		assert.Equal(t, &model.BbnTx{Hash: first.Hash, Index: 0}, byType["tx"].Tx)
+
+		secondHash := fmt.Sprintf("%X", txs[1].Hash())
+		assert.Equal(t, &model.BbnTx{Hash: secondHash, Index: 1, Sender: "bbn1second"}, byType["test.EventSecond"].Tx)
+		assert.Equal(t, &model.BbnTx{Hash: secondHash, Index: 1, Sender: "bbn1third"}, byType["test.EventThird"].Tx)
+
+		block := byType["test.EventBlock"]
+		assert.Equal(t, BlockCategory, block.Category)
+		assert.Nil(t, block.Tx)
+	})
+	t.Run("txs don't match tx results", func(t *testing.T) {
+		s, m := newTestService(t)
+		m.bbn.On("GetBlockResults", ctx, mock.Anything).Return(blockResults, nil)
+		m.bbn.On("GetBlock", ctx, mock.Anything).Return(&ctypes.ResultBlock{
+			Block: &cmttypes.Block{Data: cmttypes.Data{Txs: txs[:1]}},
+		}, nil)
+
+		_, err := s.getEventsFromBlock(ctx, height)
+		require.Error(t, err)
+	})
+	t.Run("block without txs", func(t *testing.T) {
+		s, m := newTestService(t)
+		m.bbn.On("GetBlockResults", ctx, mock.Anything).Return(&ctypes.ResultBlockResults{
+			FinalizeBlockEvents: []abcitypes.Event{{Type: "test.EventBlock"}},
+		}, nil)
+
+		events, err := s.getEventsFromBlock(ctx, height)
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		assert.Nil(t, events[0].Tx)
+	})
+}
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/utils"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/rs/zerolog/log"
+)
+
+// Reasons why the spend of the delegation output is unknown
+const (
+	unknownSpendReasonInvalidUnbondingOutput = "unbonding path spent by tx that is not the registered unbonding tx"
+	unknownSpendReasonUnrecognizedStaking    = "staking output spent by tx that is not unbonding, withdrawal, slashing or expansion"
+	unknownSpendReasonUnrecognizedUnbonding  = "unbonding output spent by tx that is not withdrawal or slashing"
+	unknownSpendReasonUnknownExpansion       = "unbonding path spent by tx that is not the registered unbonding tx or indexed expansion"
+	unknownSpendReasonMalformedWitness       = "spending input witness doesn't reveal the spent script"
+)
+
+// errMalformedWitness is returned when the witness of the spending input can't unlock any script path
+// of the delegation outputs, such spend is unknown
+var errMalformedWitness = errors.New("malformed spending input witness")
+
+// witnessScript returns the script revealed by the script path spend of the input
+func witnessScript(tx *wire.MsgTx, inputIdx uint32) ([]byte, error) {
+	witness := tx.TxIn[inputIdx].Witness
+	if len(witness) < 2 {
+		return nil, fmt.Errorf("%w: spending tx should have at least 2 elements in witness, got %d",
+			errMalformedWitness, len(witness))
+	}
+	return witness[len(witness)-2], nil
+}
+
+// handleUnknownSpend moves the delegation to the terminal UNKNOWN_SPEND state, so it's not counted
+// as staked anymore, and records the anomaly for investigation. The transition deletes timelock
+// expires of the delegation. The spent output is not watched afterwards as nothing the indexer
+// knows can spend the outputs of the spending tx.
+func (s *Service) handleUnknownSpend(
+	ctx context.Context,
+	delegation *model.BTCDelegationDetails,
+	spendingTx *wire.MsgTx,
+	spendingHeight uint32,
+	spentOutput string,
+	reason string,
+) error {
+	var (
+		spentValue int64
+		err        error
+	)
+	if spentOutput == model.SpentOutputUnbonding {
+		spentValue, err = unbondingOutputValue(delegation)
+	} else {
+		spentValue, err = stakingOutputValue(delegation)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get %s output value: %w", spentOutput, err)
+	}
+
+	details, err := s.newBtcSpendDetails(ctx, spendingTx, spendingHeight, spentValue)
+	if err != nil {
+		return err
+	}
+	spendingTxBytes, err := utils.SerializeBtcTransaction(spendingTx)
+	if err != nil {
+		return fmt.Errorf("failed to serialize spending tx: %w", err)
+	}
+	unknownSpend := &model.UnknownSpend{
+		Reason:        reason,
+		SpentOutput:   spentOutput,
+		SpendingTxHex: hex.EncodeToString(spendingTxBytes),
+		Details:       details,
+	}
+
+	log := log.Ctx(ctx)
+
+	// the anomaly is stored first, so it's not lost if the delegation can't take the transition
+	stored, err := s.db.SaveAnomaly(ctx, model.NewUnknownSpendAnomaly(delegation.StakingTxHashHex, unknownSpend))
+	if err != nil {
+		return fmt.Errorf("failed to save anomaly: %w", err)
+	}
+	// the spend delivered again after restart has been counted already
+	if stored {
+		metrics.IncUnknownSpend(spentOutput)
+	}
+
+	// delegation might have been read when the spend notification was registered
+	var current *model.BTCDelegationDetails
+	err = s.updateDelegation(ctx, delegation.StakingTxHashHex, func(latest *model.BTCDelegationDetails) error {
+		current = latest
+		return s.db.UpdateBTCDelegationState(
+			ctx,
+			delegation.StakingTxHashHex,
+			types.TriggerUnknownSpend,
+			types.StateUnknownSpend,
+			db.WithBtcHeight(spendingHeight),
+			db.WithBtcTimestamp(details.BlockTimestamp),
+			db.WithUnknownSpend(unknownSpend),
+			db.WithExpectedVersion(latest.Version),
+		)
+	})
+	if err != nil {
+		// the delegation itself can't be missing, it was read with the spend notification
+		if !db.IsNotFoundError(err) || current == nil {
+			return fmt.Errorf("failed to update BTC delegation state: %w", err)
+		}
+		// the rejected transition is stored by the update
+		log.Warn().
+			Str("staking_tx", delegation.StakingTxHashHex).
+			Stringer("current_state", current.State).
+			Msg("delegation not in qualified states for unknown spend")
+		return nil
+	}
+
+	log.Error().
+		Str("staking_tx", delegation.StakingTxHashHex).
+		Str("spending_tx", details.TxHash).
+		Uint32("spending_height", spendingHeight).
+		Str("spent_output", spentOutput).
+		Str("reason", reason).
+		Msg("delegation output spent by unknown tx")
+
+	// consumers count active delegations, they are notified the same way as on unbonding
+	if current.State == types.StateActive {
+		return s.emitUnbondingDelegationEvent(ctx, current)
+	}
+
+	return nil
+}
+
+// restoreTimeLockExpire saves timelock expire of the delegation again after the unknown spend, which
+// deleted it, has been reverted. The expire height is derived the same way as when the output was created.
+func (s *Service) restoreTimeLockExpire(ctx context.Context, stakingTxHashHex string) error {
+	delegation, err := s.db.GetBTCDelegationByStakingTxHash(ctx, stakingTxHashHex)
+	if err != nil {
+		return fmt.Errorf("failed to get BTC delegation by staking tx hash: %w", err)
+	}
+
+	var expireHeight uint32
+	switch delegation.SubState {
+	case types.SubStateTimelock:
+		expireHeight = delegation.EndHeight
+	case types.SubStateEarlyUnbonding:
+		expireHeight = delegation.UnbondingStartHeight + delegation.UnbondingTime
+	case types.SubStateTimelockSlashing, types.SubStateEarlyUnbondingSlashing:
+		stakingParams, err := s.db.GetStakingParams(ctx, delegation.ParamsVersion)
+		if err != nil {
+			return fmt.Errorf("failed to get staking params: %w", err)
+		}
+		expireHeight = delegation.SlashingTx.SpendingHeight + stakingParams.UnbondingTimeBlocks
+	default:
+		return fmt.Errorf("delegation %s in %s sub state has no timelock", stakingTxHashHex, delegation.SubState)
+	}
+
+	if err := s.db.SaveNewTimeLockExpire(ctx, stakingTxHashHex, expireHeight, delegation.SubState); err != nil {
+		return fmt.Errorf("failed to save timelock expire: %w", err)
+	}
+	return nil
+}
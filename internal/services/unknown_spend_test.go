@@ -0,0 +1,269 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/bbnclient"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/utils"
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/mocks"
+	"github.com/babylonlabs-io/babylon/v4/btcstaking"
+	bbn "github.com/babylonlabs-io/babylon/v4/types"
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const unknownSpendTestHeight = 100
+
+// stakingUnbondingPathScript returns the unbonding path script of the delegation staking output
+func stakingUnbondingPathScript(
+	t *testing.T, delegation *model.BTCDelegationDetails, params *bbnclient.StakingParams,
+) []byte {
+	t.Helper()
+
+	toPk := func(pkHex string) *btcec.PublicKey {
+		pk, err := bbn.NewBIP340PubKeyFromHex(pkHex)
+		require.NoError(t, err)
+		return pk.MustToBTCPK()
+	}
+	covPks := make([]*btcec.PublicKey, len(params.CovenantPks))
+	for i, pkHex := range params.CovenantPks {
+		covPks[i] = toPk(pkHex)
+	}
+
+	stakingInfo, err := btcstaking.BuildStakingInfo(
+		toPk(delegation.StakerBtcPkHex),
+		[]*btcec.PublicKey{toPk(delegation.FinalityProviderBtcPksHex[0])},
+		covPks,
+		params.CovenantQuorum,
+		uint16(delegation.StakingTime),
+		btcutil.Amount(delegation.StakingAmount),
+		&chaincfg.SigNetParams,
+	)
+	require.NoError(t, err)
+	pathInfo, err := stakingInfo.UnbondingPathSpendInfo()
+	require.NoError(t, err)
+	return pathInfo.GetPkScriptPath()
+}
+
+// testSpendingTx spends the outpoint revealing the script in the witness and sends value to otherScript
+func testSpendingTx(t *testing.T, outpoint *wire.OutPoint, script []byte, value int64) *wire.MsgTx {
+	t.Helper()
+
+	_, otherScript := testAddressScript(t, 3)
+	tx := wire.NewMsgTx(2)
+	tx.AddTxIn(wire.NewTxIn(outpoint, nil, wire.TxWitness{make([]byte, 64), script, make([]byte, 33)}))
+	tx.AddTxOut(wire.NewTxOut(value, otherScript))
+	return tx
+}
+
+func expectUnknownSpendDetails(btcMock *mocks.BtcInterface) {
+	btcMock.On("GetBlockHash", mock.Anything, uint32(unknownSpendTestHeight)).Return("block_hash", nil).Once()
+	btcMock.On("GetBlockTimestampByHash", mock.Anything, "block_hash").Return(int64(1700000000), nil).Once()
+}
+
+func matchUnknownSpend(spendingTx *wire.MsgTx, spentOutput, reason string) any {
+	return mock.MatchedBy(func(anomaly *model.Anomaly) bool {
+		return anomaly.Type == model.AnomalyTypeUnknownSpend &&
+			anomaly.SpendingTxHash == spendingTx.TxHash().String() &&
+			anomaly.SpendingHeight == unknownSpendTestHeight &&
+			anomaly.UnknownSpend.SpentOutput == spentOutput &&
+			anomaly.UnknownSpend.Reason == reason
+	})
+}
+
+func TestHandleSpendingStakingTransactionUnknownSpend(t *testing.T) {
+	ctx := context.Background()
+
+	delegation, params, _ := newWithdrawableTestDelegation(t)
+	delegation.State = types.StateActive
+	stakingTxHash, err := chainhash.NewHashFromStr(delegation.StakingTxHashHex)
+	require.NoError(t, err)
+	stakingOutpoint := wire.NewOutPoint(stakingTxHash, delegation.StakingOutputIdx)
+
+	t.Run("unrecognized spend", func(t *testing.T) {
+		s, m := newTestService(t, withBTCConfig(config.BTCConfig{NetParams: utils.BtcSignet.String()}))
+		spendingTx := testSpendingTx(t, stakingOutpoint, []byte{0x51}, 90000)
+
+		m.db.On("GetBTCDelegationByStakingTxHash", ctx, delegation.StakingTxHashHex).Return(delegation, nil).Twice()
+		m.db.On("GetStakingParams", ctx, delegation.ParamsVersion).Return(params, nil).Once()
+		m.db.On("GetBTCDelegationByStakingTxHash", ctx, spendingTx.TxHash().String()).
+			Return(nil, &db.NotFoundError{}).Once()
+		expectUnknownSpendDetails(m.btc)
+		m.db.On("SaveAnomaly", ctx, matchUnknownSpend(spendingTx, model.SpentOutputStaking, unknownSpendReasonUnrecognizedStaking)).
+			Return(true, nil).Once()
+		m.db.On("UpdateBTCDelegationState", ctx, delegation.StakingTxHashHex,
+			types.TriggerUnknownSpend, types.StateUnknownSpend,
+			mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		).Return(nil).Once()
+		m.consumer.On("PushUnbondingStakingEvent", ctx, mock.Anything).Return(nil).Once()
+
+		err := s.handleSpendingStakingTransaction(ctx, spendingTx, 0, unknownSpendTestHeight, delegation.StakingTxHashHex)
+		require.NoError(t, err)
+	})
+	t.Run("unbonding path spent by unregistered tx", func(t *testing.T) {
+		s, m := newTestService(t, withBTCConfig(config.BTCConfig{NetParams: utils.BtcSignet.String()}))
+		spendingTx := testSpendingTx(t, stakingOutpoint, stakingUnbondingPathScript(t, delegation, params), 90000)
+
+		m.db.On("GetBTCDelegationByStakingTxHash", ctx, delegation.StakingTxHashHex).Return(delegation, nil).Twice()
+		m.db.On("GetStakingParams", ctx, delegation.ParamsVersion).Return(params, nil).Once()
+		expectUnknownSpendDetails(m.btc)
+		m.db.On("SaveAnomaly", ctx, matchUnknownSpend(spendingTx, model.SpentOutputStaking, unknownSpendReasonInvalidUnbondingOutput)).
+			Return(true, nil).Once()
+		m.db.On("UpdateBTCDelegationState", ctx, delegation.StakingTxHashHex,
+			types.TriggerUnknownSpend, types.StateUnknownSpend,
+			mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		).Return(nil).Once()
+		m.consumer.On("PushUnbondingStakingEvent", ctx, mock.Anything).Return(nil).Once()
+
+		err := s.handleSpendingStakingTransaction(ctx, spendingTx, 0, unknownSpendTestHeight, delegation.StakingTxHashHex)
+		require.NoError(t, err)
+	})
+	t.Run("unbonding path spent by unknown expansion", func(t *testing.T) {
+		s, m := newTestService(t, withBTCConfig(config.BTCConfig{NetParams: utils.BtcSignet.String()}))
+		spendingTx := testSpendingTx(t, stakingOutpoint, stakingUnbondingPathScript(t, delegation, params), 150000)
+		// funding input of the expansion
+		spendingTx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: 5}, nil, nil))
+
+		m.db.On("GetBTCDelegationByStakingTxHash", ctx, delegation.StakingTxHashHex).Return(delegation, nil).Twice()
+		m.db.On("GetStakingParams", ctx, delegation.ParamsVersion).Return(params, nil).Once()
+		m.db.On("GetBTCDelegationByStakingTxHash", ctx, spendingTx.TxHash().String()).
+			Return(nil, &db.NotFoundError{}).Once()
+		expectUnknownSpendDetails(m.btc)
+		m.db.On("SaveAnomaly", ctx, matchUnknownSpend(spendingTx, model.SpentOutputStaking, unknownSpendReasonUnknownExpansion)).
+			Return(true, nil).Once()
+		m.db.On("UpdateBTCDelegationState", ctx, delegation.StakingTxHashHex,
+			types.TriggerUnknownSpend, types.StateUnknownSpend,
+			mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		).Return(nil).Once()
+		m.consumer.On("PushUnbondingStakingEvent", ctx, mock.Anything).Return(nil).Once()
+
+		err := s.handleSpendingStakingTransaction(ctx, spendingTx, 0, unknownSpendTestHeight, delegation.StakingTxHashHex)
+		require.NoError(t, err)
+	})
+	t.Run("malformed witness", func(t *testing.T) {
+		s, m := newTestService(t, withBTCConfig(config.BTCConfig{NetParams: utils.BtcSignet.String()}))
+		spendingTx := testSpendingTx(t, stakingOutpoint, nil, 90000)
+		// key path spend reveals only the signature
+		spendingTx.TxIn[0].Witness = wire.TxWitness{make([]byte, 64)}
+
+		m.db.On("GetBTCDelegationByStakingTxHash", ctx, delegation.StakingTxHashHex).Return(delegation, nil).Twice()
+		m.db.On("GetStakingParams", ctx, delegation.ParamsVersion).Return(params, nil).Once()
+		expectUnknownSpendDetails(m.btc)
+		m.db.On("SaveAnomaly", ctx, matchUnknownSpend(spendingTx, model.SpentOutputStaking, unknownSpendReasonMalformedWitness)).
+			Return(true, nil).Once()
+		m.db.On("UpdateBTCDelegationState", ctx, delegation.StakingTxHashHex,
+			types.TriggerUnknownSpend, types.StateUnknownSpend,
+			mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		).Return(nil).Once()
+		m.consumer.On("PushUnbondingStakingEvent", ctx, mock.Anything).Return(nil).Once()
+
+		err := s.handleSpendingStakingTransaction(ctx, spendingTx, 0, unknownSpendTestHeight, delegation.StakingTxHashHex)
+		require.NoError(t, err)
+	})
+	t.Run("delegation lookup failure is not unknown spend", func(t *testing.T) {
+		s, m := newTestService(t, withBTCConfig(config.BTCConfig{NetParams: utils.BtcSignet.String()}))
+		spendingTx := testSpendingTx(t, stakingOutpoint, []byte{0x51}, 90000)
+
+		m.db.On("GetBTCDelegationByStakingTxHash", ctx, delegation.StakingTxHashHex).Return(delegation, nil).Once()
+		m.db.On("GetStakingParams", ctx, delegation.ParamsVersion).Return(params, nil).Once()
+		m.db.On("GetBTCDelegationByStakingTxHash", ctx, spendingTx.TxHash().String()).
+			Return(nil, assert.AnError).Once()
+
+		err := s.handleSpendingStakingTransaction(ctx, spendingTx, 0, unknownSpendTestHeight, delegation.StakingTxHashHex)
+		require.ErrorIs(t, err, assert.AnError)
+	})
+}
+
+func TestHandleSpendingUnbondingTransactionUnknownSpend(t *testing.T) {
+	ctx := context.Background()
+
+	delegation, params, _ := newWithdrawableTestDelegation(t)
+	delegation.State = types.StateUnbonding
+	delegation.SubState = types.SubStateEarlyUnbonding
+	unbondingTx, err := utils.DeserializeBtcTransactionFromHex(delegation.UnbondingTx)
+	require.NoError(t, err)
+	unbondingTxHash := unbondingTx.TxHash()
+
+	t.Run("unrecognized spend", func(t *testing.T) {
+		s, m := newTestService(t, withBTCConfig(config.BTCConfig{NetParams: utils.BtcSignet.String()}))
+		spendingTx := testSpendingTx(t, wire.NewOutPoint(&unbondingTxHash, 0), []byte{0x51}, 90000)
+
+		m.db.On("GetStakingParams", ctx, delegation.ParamsVersion).Return(params, nil).Once()
+		expectUnknownSpendDetails(m.btc)
+		m.db.On("GetBTCDelegationByStakingTxHash", ctx, delegation.StakingTxHashHex).Return(delegation, nil).Once()
+		m.db.On("SaveAnomaly", ctx, matchUnknownSpend(spendingTx, model.SpentOutputUnbonding, unknownSpendReasonUnrecognizedUnbonding)).
+			Return(true, nil).Once()
+		// consumers have been notified when the delegation was unbonded
+		m.db.On("UpdateBTCDelegationState", ctx, delegation.StakingTxHashHex,
+			types.TriggerUnknownSpend, types.StateUnknownSpend,
+			mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		).Return(nil).Once()
+
+		err := s.handleSpendingUnbondingTransaction(ctx, spendingTx, unknownSpendTestHeight, 0, delegation)
+		require.NoError(t, err)
+	})
+	t.Run("delegation not qualified", func(t *testing.T) {
+		s, m := newTestService(t, withBTCConfig(config.BTCConfig{NetParams: utils.BtcSignet.String()}))
+		spendingTx := testSpendingTx(t, wire.NewOutPoint(&unbondingTxHash, 0), []byte{0x51}, 90000)
+
+		m.db.On("GetStakingParams", ctx, delegation.ParamsVersion).Return(params, nil).Once()
+		expectUnknownSpendDetails(m.btc)
+		m.db.On("GetBTCDelegationByStakingTxHash", ctx, delegation.StakingTxHashHex).Return(delegation, nil).Once()
+		// the spend has been recorded before restart
+		m.db.On("SaveAnomaly", ctx, mock.Anything).Return(false, nil).Once()
+		m.db.On("UpdateBTCDelegationState", ctx, delegation.StakingTxHashHex,
+			types.TriggerUnknownSpend, types.StateUnknownSpend,
+			mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		).Return(&db.NotFoundError{}).Once()
+
+		err := s.handleSpendingUnbondingTransaction(ctx, spendingTx, unknownSpendTestHeight, 0, delegation)
+		require.NoError(t, err)
+	})
+}
+
+func TestRestoreTimeLockExpire(t *testing.T) {
+	ctx := context.Background()
+
+	delegation, params, _ := newWithdrawableTestDelegation(t)
+	delegation.State = types.StateUnbonding
+	delegation.EndHeight = 1000
+	delegation.UnbondingStartHeight = 500
+	delegation.UnbondingTime = 101
+	delegation.SlashingTx.SpendingHeight = 600
+	params.UnbondingTimeBlocks = 102
+
+	tests := []struct {
+		subState     types.DelegationSubState
+		expireHeight uint32
+	}{
+		{types.SubStateTimelock, 1000},
+		{types.SubStateEarlyUnbonding, 601},
+		{types.SubStateTimelockSlashing, 702},
+		{types.SubStateEarlyUnbondingSlashing, 702},
+	}
+	for _, tt := range tests {
+		t.Run(tt.subState.String(), func(t *testing.T) {
+			s, m := newTestService(t, withBTCConfig(config.BTCConfig{NetParams: utils.BtcSignet.String()}))
+			reverted := *delegation
+			reverted.SubState = tt.subState
+
+			m.db.On("GetBTCDelegationByStakingTxHash", ctx, delegation.StakingTxHashHex).Return(&reverted, nil).Once()
+			m.db.On("GetStakingParams", ctx, delegation.ParamsVersion).Return(params, nil).Maybe()
+			m.db.On("SaveNewTimeLockExpire", ctx, delegation.StakingTxHashHex, tt.expireHeight, tt.subState).
+				Return(nil).Once()
+
+			require.NoError(t, s.restoreTimeLockExpire(ctx, delegation.StakingTxHashHex))
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/utils/poller"
+	"github.com/rs/zerolog/log"
+)
+
+// expected time between BTC blocks, used to estimate time of future heights
+const btcBlockInterval = 10 * time.Minute
+
+// StartUnlockProjectionPoller starts periodic refresh of upcoming unlock projections
+func (s *Service) StartUnlockProjectionPoller(ctx context.Context) {
+	projectionPoller := poller.NewPoller(
+		s.cfg.Poller.UnlockProjectionPollingInterval,
+		metrics.RecordPollerDuration("unlock_projection", s.refreshUnlockProjections),
+	)
+	go projectionPoller.Start(ctx)
+}
+
+// refreshUnlockProjections projects unlocks of the heights after the current BTC tip
+// and replaces the stored projections
+func (s *Service) refreshUnlockProjections(ctx context.Context) error {
+	tipHeight, err := s.btc.GetTipHeight(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get BTC tip height: %w", err)
+	}
+	tip := uint32(tipHeight)
+	tipTimestamp, err := s.btcBlockTime(ctx, tip)
+	if err != nil {
+		return fmt.Errorf("failed to get BTC tip timestamp: %w", err)
+	}
+
+	bucketBlocks := s.cfg.Poller.UnlockProjectionBucketBlocks
+	buckets := s.cfg.Poller.UnlockProjectionBuckets
+	results, err := s.db.CalculateUnlockBuckets(ctx, tip+1, bucketBlocks, buckets)
+	if err != nil {
+		return fmt.Errorf("failed to calculate unlock buckets: %w", err)
+	}
+
+	projections := newUnlockProjections(results, tip, tipTimestamp, bucketBlocks, buckets, time.Now().Unix())
+	if err := s.db.ReplaceUnlockProjections(ctx, projections); err != nil {
+		return fmt.Errorf("failed to store unlock projections: %w", err)
+	}
+
+	log.Ctx(ctx).Debug().
+		Uint32("tip_height", tip).
+		Int("projections", len(projections)).
+		Msg("Updated unlock projections")
+
+	return nil
+}
+
+// newUnlockProjections builds the overall projection followed by projections of finality
+// providers ordered by BTC public key, every projection has all buckets
+func newUnlockProjections(
+	results []db.UnlockBucketResult, tipHeight uint32, tipTimestamp int64, bucketBlocks, buckets uint32, now int64,
+) []*model.UnlockProjectionDocument {
+	newProjection := func(id string) *model.UnlockProjectionDocument {
+		projection := &model.UnlockProjectionDocument{
+			ID:           id,
+			TipHeight:    tipHeight,
+			TipTimestamp: tipTimestamp,
+			BucketBlocks: bucketBlocks,
+			Buckets:      make([]model.UnlockBucket, buckets),
+			LastUpdated:  now,
+		}
+		for i := range projection.Buckets {
+			bucket := &projection.Buckets[i]
+			bucket.StartHeight = tipHeight + 1 + uint32(i)*bucketBlocks
+			bucket.EndHeight = bucket.StartHeight + bucketBlocks - 1
+			bucket.EstimatedStartTime = estimateBtcHeightTime(tipHeight, tipTimestamp, bucket.StartHeight)
+			bucket.EstimatedEndTime = estimateBtcHeightTime(tipHeight, tipTimestamp, bucket.EndHeight)
+		}
+		return projection
+	}
+
+	byID := map[string]*model.UnlockProjectionDocument{
+		model.OverallUnlockProjectionID: newProjection(model.OverallUnlockProjectionID),
+	}
+	for _, result := range results {
+		if result.Bucket >= buckets {
+			continue
+		}
+
+		id := result.FpBtcPkHex
+		if id == "" {
+			id = model.OverallUnlockProjectionID
+		}
+		projection, ok := byID[id]
+		if !ok {
+			projection = newProjection(id)
+			byID[id] = projection
+		}
+
+		bucket := &projection.Buckets[result.Bucket]
+		bucket.Amount += result.Amount
+		bucket.Delegations += result.Delegations
+		switch result.Kind {
+		case model.UnlockKindExpiry:
+			bucket.ExpiryAmount += result.Amount
+		case model.UnlockKindUnbonding:
+			bucket.UnbondingAmount += result.Amount
+		case model.UnlockKindSlashing:
+			bucket.SlashingAmount += result.Amount
+		}
+	}
+
+	projections := make([]*model.UnlockProjectionDocument, 0, len(byID))
+	projections = append(projections, byID[model.OverallUnlockProjectionID])
+	delete(byID, model.OverallUnlockProjectionID)
+	for _, projection := range byID {
+		projections = append(projections, projection)
+	}
+	sort.Slice(projections[1:], func(i, j int) bool {
+		return projections[i+1].ID < projections[j+1].ID
+	})
+
+	return projections
+}
+
+// estimateBtcHeightTime estimates unix timestamp of the future BTC height
+func estimateBtcHeightTime(tipHeight uint32, tipTimestamp int64, height uint32) int64 {
+	return tipTimestamp + int64(height-tipHeight)*int64(btcBlockInterval/time.Second)
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewUnlockProjections(t *testing.T) {
+	const (
+		tipHeight    = uint32(100)
+		tipTimestamp = int64(1700000000)
+		now          = int64(1700000100)
+	)
+
+	results := []db.UnlockBucketResult{
+		{Bucket: 0, Kind: model.UnlockKindExpiry, Amount: 1000, Delegations: 1},
+		{Bucket: 0, Kind: model.UnlockKindUnbonding, Amount: 2000, Delegations: 2},
+		{Bucket: 1, Kind: model.UnlockKindSlashing, Amount: 500, Delegations: 1},
+		{FpBtcPkHex: "fp_b", Bucket: 1, Kind: model.UnlockKindSlashing, Amount: 500, Delegations: 1},
+		{FpBtcPkHex: "fp_a", Bucket: 0, Kind: model.UnlockKindExpiry, Amount: 1000, Delegations: 1},
+		// out of range buckets are ignored
+		{Bucket: 2, Kind: model.UnlockKindExpiry, Amount: 7000, Delegations: 1},
+	}
+
+	projections := newUnlockProjections(results, tipHeight, tipTimestamp, 10, 2, now)
+	require.Len(t, projections, 3)
+	assert.Equal(t, model.OverallUnlockProjectionID, projections[0].ID)
+	assert.Equal(t, "fp_a", projections[1].ID)
+	assert.Equal(t, "fp_b", projections[2].ID)
+
+	overall := projections[0]
+	assert.Equal(t, tipHeight, overall.TipHeight)
+	assert.Equal(t, uint32(10), overall.BucketBlocks)
+	assert.Equal(t, now, overall.LastUpdated)
+	assert.Equal(t, []model.UnlockBucket{
+		{
+			StartHeight:        101,
+			EndHeight:          110,
+			EstimatedStartTime: tipTimestamp + 600,
+			EstimatedEndTime:   tipTimestamp + 10*600,
+			Amount:             3000,
+			Delegations:        3,
+			ExpiryAmount:       1000,
+			UnbondingAmount:    2000,
+		},
+		{
+			StartHeight:        111,
+			EndHeight:          120,
+			EstimatedStartTime: tipTimestamp + 11*600,
+			EstimatedEndTime:   tipTimestamp + 20*600,
+			Amount:             500,
+			Delegations:        1,
+			SlashingAmount:     500,
+		},
+	}, overall.Buckets)
+
+	// finality providers get all buckets, including empty ones
+	require.Len(t, projections[1].Buckets, 2)
+	assert.Equal(t, uint64(1000), projections[1].Buckets[0].Amount)
+	assert.Zero(t, projections[1].Buckets[1].Amount)
+
+	t.Run("no unlocks", func(t *testing.T) {
+		projections := newUnlockProjections(nil, tipHeight, tipTimestamp, 10, 2, now)
+		require.Len(t, projections, 1)
+		assert.Equal(t, model.OverallUnlockProjectionID, projections[0].ID)
+		assert.Len(t, projections[0].Buckets, 2)
+	})
+}
@@ -5,7 +5,6 @@ import (
 	"context"
 	"encoding/hex"
 	"fmt"
-	"slices"
 
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/bbnclient"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
@@ -24,34 +23,22 @@ import (
 )
 
 func (s *Service) watchForSpendStakingTx(ctx context.Context, spendEvent *notifier.SpendEvent, stakingTxHashHex string) {
-	quitCtx, cancel := context.WithCancel(ctx)
-	defer cancel()
-
-	log := log.Ctx(ctx)
-	// Get spending details
-	select {
-	case spendDetail := <-spendEvent.Spend:
-		log.Debug().
+	s.watchSpend(ctx, spendEvent, stakingTxHashHex, func(ctx context.Context, spendDetail *notifier.SpendDetail) error {
+		log.Ctx(ctx).Debug().
 			Str("staking_tx", stakingTxHashHex).
 			Stringer("spending_tx", spendDetail.SpendingTx.TxHash()).
 			Msg("staking tx has been spent")
 		if err := s.handleSpendingStakingTransaction(
-			quitCtx,
+			ctx,
 			spendDetail.SpendingTx,
 			spendDetail.SpenderInputIndex,
 			uint32(spendDetail.SpendingHeight),
 			stakingTxHashHex,
 		); err != nil {
-			log.Error().
-				Err(err).
-				Str("staking_tx", stakingTxHashHex).
-				Stringer("spending_tx", spendDetail.SpendingTx.TxHash()).
-				Msg("failed to handle spending staking transaction")
-			return
+			return fmt.Errorf("failed to handle spending staking transaction: %w", err)
 		}
-	case <-quitCtx.Done():
-		return
-	}
+		return nil
+	})
 }
 
 func (s *Service) watchForSpendUnbondingTx(
@@ -59,40 +46,23 @@ func (s *Service) watchForSpendUnbondingTx(
 	spendEvent *notifier.SpendEvent,
 	delegation *model.BTCDelegationDetails,
 ) {
-	quitCtx, cancel := context.WithCancel(ctx)
-	defer cancel()
-
-	log := log.Ctx(ctx)
-
-	// Get spending details
-	select {
-	case spendDetail := <-spendEvent.Spend:
-		log.Debug().
+	s.watchSpend(ctx, spendEvent, delegation.StakingTxHashHex, func(ctx context.Context, spendDetail *notifier.SpendDetail) error {
+		log.Ctx(ctx).Debug().
 			Str("staking_tx", delegation.StakingTxHashHex).
 			Stringer("unbonding_tx", spendDetail.SpendingTx.TxHash()).
 			Uint32("spending_height", uint32(spendDetail.SpendingHeight)).
 			Msg("unbonding tx has been spent")
 		if err := s.handleSpendingUnbondingTransaction(
-			quitCtx,
+			ctx,
 			spendDetail.SpendingTx,
 			uint32(spendDetail.SpendingHeight),
 			spendDetail.SpenderInputIndex,
 			delegation,
 		); err != nil {
-			log.Error().
-				Err(err).
-				Str("staking_tx", delegation.StakingTxHashHex).
-				Stringer("unbonding_tx", spendDetail.SpendingTx.TxHash()).
-				Msg("failed to handle spending unbonding transaction")
-			return
+			return fmt.Errorf("failed to handle spending unbonding transaction: %w", err)
 		}
-
-	case <-quitCtx.Done():
-		log.Debug().
-			Str("staking_tx", delegation.StakingTxHashHex).
-			Msg("context cancelled while watching for unbonding tx spend")
-		return
-	}
+		return nil
+	})
 }
 
 func (s *Service) watchForSpendSlashingChange(
@@ -101,56 +71,68 @@ func (s *Service) watchForSpendSlashingChange(
 	delegation *model.BTCDelegationDetails,
 	subState types.DelegationSubState,
 ) {
-	quitCtx, cancel := context.WithCancel(ctx)
-	defer cancel()
-
-	log := log.Ctx(ctx)
-	select {
-	case spendDetail := <-spendEvent.Spend:
-		log.Debug().
+	s.watchSpend(ctx, spendEvent, delegation.StakingTxHashHex, func(ctx context.Context, spendDetail *notifier.SpendDetail) error {
+		log.Ctx(ctx).Debug().
 			Str("staking_tx", delegation.StakingTxHashHex).
 			Stringer("spending_tx", spendDetail.SpendingTx.TxHash()).
 			Msg("slashing change output has been spent")
-		delegationState, err := s.db.GetBTCDelegationState(quitCtx, delegation.StakingTxHashHex)
-		if err != nil {
-			log.Error().
-				Err(err).
-				Str("staking_tx", delegation.StakingTxHashHex).
-				Msg("failed to get delegation state")
-			return
-		}
+		return s.handleSpendingSlashingChange(ctx, spendDetail, delegation, subState)
+	})
+}
 
-		qualifiedStates := types.QualifiedStatesForWithdrawn()
-		if qualifiedStates == nil || !slices.Contains(qualifiedStates, *delegationState) {
-			log.Error().
-				Str("staking_tx", delegation.StakingTxHashHex).
-				Stringer("state", delegationState).
-				Msg("current state is not qualified for slashed withdrawn")
-			return
-		}
+func (s *Service) handleSpendingSlashingChange(
+	ctx context.Context,
+	spendDetail *notifier.SpendDetail,
+	delegation *model.BTCDelegationDetails,
+	subState types.DelegationSubState,
+) error {
+	transition, err := types.FindTransition(types.TriggerSlashingChangeSpend, types.StateWithdrawn, subState)
+	if err != nil {
+		return err
+	}
+
+	// delegation was read before the slashing tx was stored
+	current, err := s.db.GetBTCDelegationByStakingTxHash(ctx, delegation.StakingTxHashHex)
+	if err != nil {
+		return fmt.Errorf("failed to get BTC delegation by staking tx hash: %w", err)
+	}
+	if !s.canTransition(ctx, current, transition) {
+		return fmt.Errorf("current state %s is not qualified for slashed withdrawn", current.State)
+	}
 
-		// Update to withdrawn state
-		delegationSubState := subState
-		if err := s.db.UpdateBTCDelegationState(
-			quitCtx,
+	spendingBtcTimestamp, err := s.btcBlockTime(ctx, uint32(spendDetail.SpendingHeight))
+	if err != nil {
+		return fmt.Errorf("failed to get block timestamp: %w", err)
+	}
+
+	changeValue, err := slashingChangeOutputValue(current, subState)
+	if err != nil {
+		return fmt.Errorf("failed to get slashing change output value: %w", err)
+	}
+	withdrawalTx, err := s.newWithdrawalTx(ctx, spendDetail.SpendingTx, uint32(spendDetail.SpendingHeight), changeValue)
+	if err != nil {
+		return err
+	}
+
+	// Update to withdrawn state
+	err = s.updateDelegation(ctx, delegation.StakingTxHashHex, func(current *model.BTCDelegationDetails) error {
+		return s.db.UpdateBTCDelegationState(
+			ctx,
 			delegation.StakingTxHashHex,
-			types.QualifiedStatesForWithdrawn(),
+			types.TriggerSlashingChangeSpend,
 			types.StateWithdrawn,
-			db.WithSubState(delegationSubState),
+			db.WithSubState(subState),
 			db.WithBtcHeight(uint32(spendDetail.SpendingHeight)),
-			db.WithWithdrawalTx(spendDetail.SpendingTx.TxHash().String()),
-		); err != nil {
-			log.Error().
-				Err(err).
-				Str("staking_tx", delegation.StakingTxHashHex).
-				Stringer("state", types.StateWithdrawn).
-				Stringer("sub_state", delegationSubState).
-				Msg("failed to update delegation state to withdrawn")
-			return
-		}
-	case <-quitCtx.Done():
-		return
+			db.WithBtcTimestamp(spendingBtcTimestamp),
+			db.WithWithdrawalTx(withdrawalTx),
+			db.WithExpectedVersion(current.Version),
+		)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update delegation state to withdrawn: %w", err)
 	}
+
+	return nil
 }
 
 func (s *Service) handleSpendingStakingTransaction(
@@ -172,6 +154,14 @@ func (s *Service) handleSpendingStakingTransaction(
 
 	log := log.Ctx(ctx)
 
+	// the staking output is spent only through script paths, which reveal the script in the witness
+	if _, err := witnessScript(spendingTx, spendingInputIdx); err != nil {
+		return s.handleUnknownSpend(
+			ctx, delegation, spendingTx, spendingHeight,
+			model.SpentOutputStaking, unknownSpendReasonMalformedWitness,
+		)
+	}
+
 	// Try to validate as unbonding transaction
 	isUnbonding, err := s.isSpendingStakingTxUnbondingPath(ctx, spendingTx, delegation, params)
 	if err != nil {
@@ -179,29 +169,57 @@ func (s *Service) handleSpendingStakingTransaction(
 	}
 	if isUnbonding {
 		// early unbonding has been detected, this could be
-		// valid unbonding tx or unexpected unbonding tx
+		// valid unbonding tx or unknown spend of the unbonding path
 		log.Debug().
 			Str("staking_tx", delegation.StakingTxHashHex).
 			Stringer("unbonding_tx", spendingTx.TxHash()).
 			Msg("staking tx has been spent through unbonding path")
 
-		unbondingBtcTimestamp, err := s.btc.GetBlockTimestamp(ctx, spendingHeight)
+		// check if the unbonding tx output is valid before the delegation is updated,
+		// this is important to identify if the spending tx is a valid unbonding tx
+		validUnbondingOutput, err := s.validateUnbondingTxOutput(ctx, spendingTx, delegation, params)
+		if err != nil {
+			return fmt.Errorf("failed to validate unbonding tx output: %w", err)
+		}
+		if !validUnbondingOutput {
+			// we should not subscribe to the unbonding tx spend notification
+			return s.handleUnknownSpend(
+				ctx, delegation, spendingTx, spendingHeight,
+				model.SpentOutputStaking, unknownSpendReasonInvalidUnbondingOutput,
+			)
+		}
+
+		unbondingBtcTimestamp, err := s.btcBlockTime(ctx, spendingHeight)
 		if err != nil {
 			return fmt.Errorf("failed to get block timestamp: %w", err)
 		}
 
+		stakingValue, err := stakingOutputValue(delegation)
+		if err != nil {
+			return fmt.Errorf("failed to get staking output value: %w", err)
+		}
+		unbondingTxDetails, err := s.newBtcSpendDetails(ctx, spendingTx, spendingHeight, stakingValue)
+		if err != nil {
+			return err
+		}
+
 		// update delegation state to unbonding/early unbonding
 		subState := types.SubStateEarlyUnbonding
-		err = s.db.UpdateBTCDelegationState(
-			ctx,
-			delegation.StakingTxHashHex,
-			types.QualifiedStatesForUnbondedEarly(),
-			types.StateUnbonding,
-			db.WithSubState(subState),
-			db.WithBtcHeight(spendingHeight),
-			db.WithUnbondingBTCTimestamp(unbondingBtcTimestamp),
-			db.WithUnbondingStartHeight(spendingHeight),
-		)
+		err = s.updateDelegation(ctx, delegation.StakingTxHashHex, func(current *model.BTCDelegationDetails) error {
+			return s.db.UpdateBTCDelegationState(
+				ctx,
+				delegation.StakingTxHashHex,
+				types.TriggerUnbondingSpend,
+				types.StateUnbonding,
+				db.WithSubState(subState),
+				db.WithBtcHeight(spendingHeight),
+				db.WithBtcTimestamp(unbondingBtcTimestamp),
+				db.WithUnbondingBTCTimestamp(unbondingBtcTimestamp),
+				db.WithUnbondingStartHeight(spendingHeight),
+				db.WithUnbondingTxDetails(unbondingTxDetails),
+				db.WithExpectedVersion(current.Version),
+			)
+		})
 		// handle errors but continue processing in case of NotFoundError.
 		// NotFoundError here typically means the processBTCDelegationUnbondedEarlyEvent
 		// has already processed and updated the state. We still need to proceed with
@@ -211,8 +229,15 @@ func (s *Service) handleSpendingStakingTransaction(
 			if db.IsNotFoundError(err) {
 				log.Debug().
 					Str("staking_tx", delegation.StakingTxHashHex).
-					Interface("qualified_states", types.QualifiedStatesForUnbondedEarly()).
 					Msg("delegation not in qualified states for early unbonding update")
+				// the unbonding tx details are only known from BTC, they're stored without the transition
+				if err := s.updateDelegation(ctx, delegation.StakingTxHashHex, func(current *model.BTCDelegationDetails) error {
+					return s.db.SetUnbondingTxDetails(
+						ctx, delegation.StakingTxHashHex, unbondingTxDetails, db.WithExpectedVersion(current.Version),
+					)
+				}); err != nil {
+					return fmt.Errorf("failed to set unbonding tx details: %w", err)
+				}
 			} else {
 				log.Error().
 					Err(err).
@@ -224,21 +249,6 @@ func (s *Service) handleSpendingStakingTransaction(
 			}
 		}
 
-		// check if the unbonding tx output is valid
-		// this is important to identify if the spending tx is a valid unbonding tx
-		validUnbondingOutput, err := s.validateUnbondingTxOutput(ctx, spendingTx, delegation, params)
-		if err != nil {
-			return fmt.Errorf("failed to validate unbonding tx output: %w", err)
-		}
-		if !validUnbondingOutput {
-			// no action is needed if unexpected unbonding tx is detected
-			// we should not subscribe to the unbonding tx spend notification
-			log.Error().
-				Str("staking_tx", delegation.StakingTxHashHex).
-				Str("spending_tx", spendingTx.TxHash().String()).
-				Msg("detected unexpected unbonding transaction")
-			return nil
-		}
 		// the unbonding output is valid and matches the registered unbonding tx in babylon
 		// emit consumer event to notify API
 		if err := s.emitUnbondingDelegationEvent(ctx, delegation); err != nil {
@@ -273,7 +283,11 @@ func (s *Service) handleSpendingStakingTransaction(
 			Str("staking_tx", delegation.StakingTxHashHex).
 			Stringer("withdrawal_tx", spendingTx.TxHash()).
 			Msg("staking tx has been spent through withdrawal path")
-		return s.handleWithdrawal(ctx, delegation, types.SubStateTimelock, spendingHeight, spendingTx)
+		stakingValue, err := stakingOutputValue(delegation)
+		if err != nil {
+			return fmt.Errorf("failed to get staking output value: %w", err)
+		}
+		return s.handleWithdrawal(ctx, delegation, types.SubStateTimelock, spendingHeight, spendingTx, stakingValue)
 	}
 
 	// Try to validate as slashing transaction
@@ -294,27 +308,35 @@ func (s *Service) handleSpendingStakingTransaction(
 		}
 		slashingTxHex := slashingTx.ToHexStr()
 
-		// TODO: emit slashing event in a dedicated queue
-		// refer https://github.com/babylonlabs-io/babylon-staking-indexer/issues/141
-		if err := s.emitUnbondingDelegationEvent(ctx, delegation); err != nil {
-			return err
+		slashingBtcTimestamp, err := s.btcBlockTime(ctx, spendingHeight)
+		if err != nil {
+			return fmt.Errorf("failed to get block timestamp: %w", err)
 		}
 
-		slashingBtcTimestamp, err := s.btc.GetBlockTimestamp(ctx, spendingHeight)
+		stakingValue, err := stakingOutputValue(delegation)
 		if err != nil {
-			return fmt.Errorf("failed to get block timestamp: %w", err)
+			return fmt.Errorf("failed to get staking output value: %w", err)
+		}
+		slashingTxDetails, err := s.newSlashingTxDetails(ctx, spendingTx, spendingHeight, stakingValue)
+		if err != nil {
+			return err
 		}
 
 		// Update state and slashing related fields
-		if err := s.db.UpdateBTCDelegationState(
-			ctx,
-			delegation.StakingTxHashHex,
-			types.QualifiedStatesForSlashed(),
-			types.StateSlashed,
-			db.WithSubState(types.SubStateTimelockSlashing),
-			db.WithStakingSlashingTx(slashingTxHex, spendingHeight, slashingBtcTimestamp),
-			db.WithBtcHeight(spendingHeight),
-		); err != nil {
+		err = s.updateDelegation(ctx, delegation.StakingTxHashHex, func(current *model.BTCDelegationDetails) error {
+			return s.db.UpdateBTCDelegationState(
+				ctx,
+				delegation.StakingTxHashHex,
+				types.TriggerSlashingSpend,
+				types.StateSlashed,
+				db.WithSubState(types.SubStateTimelockSlashing),
+				db.WithStakingSlashingTx(slashingTxHex, spendingHeight, slashingBtcTimestamp, slashingTxDetails),
+				db.WithBtcHeight(spendingHeight),
+				db.WithBtcTimestamp(slashingBtcTimestamp),
+				db.WithExpectedVersion(current.Version),
+			)
+		})
+		if err != nil {
 			log.Error().
 				Err(err).
 				Str("staking_tx", delegation.StakingTxHashHex).
@@ -324,6 +346,12 @@ func (s *Service) handleSpendingStakingTransaction(
 			return fmt.Errorf("failed to update BTC delegation state: %w", err)
 		}
 
+		// TODO: emit slashing event in a dedicated queue
+		// refer https://github.com/babylonlabs-io/babylon-staking-indexer/issues/141
+		if err := s.emitUnbondingDelegationEvent(ctx, delegation); err != nil {
+			return err
+		}
+
 		// It's a valid slashing tx, watch for spending change output
 		// IMPORTANT: Use context.Background() instead of ctx to prevent context cancellation
 		// when the staking spend handler returns
@@ -337,18 +365,42 @@ func (s *Service) handleSpendingStakingTransaction(
 	}
 
 	newDelegation, err := s.db.GetBTCDelegationByStakingTxHash(ctx, spendingTx.TxHash().String())
-	if err != nil {
-		log.Warn().Stringer("spendingTxHash", spendingTx.TxHash()).
-			Err(err).Msg("Failed to get btc delegation in handleSpendingStakingTransaction")
-	} else if newDelegation != nil && newDelegation.PreviousStakingTxHashHex != "" {
-		// that's ok new delegation is actually delegation expansion
+	if err != nil && !db.IsNotFoundError(err) {
+		return fmt.Errorf("failed to get BTC delegation by spending tx hash: %w", err)
+	}
+	if err == nil && newDelegation.PreviousStakingTxHashHex == delegation.StakingTxHashHex {
+		// that's ok new delegation is actually delegation expansion, link the expanded
+		// delegation to it (it might have been linked already by the unbonded early event)
+		if err := s.updateDelegation(ctx, delegation.StakingTxHashHex, func(current *model.BTCDelegationDetails) error {
+			return s.db.SetNextStakingTxHash(
+				ctx, delegation.StakingTxHashHex, newDelegation.StakingTxHashHex, db.WithExpectedVersion(current.Version),
+			)
+		}); err != nil {
+			return fmt.Errorf("failed to set next staking tx hash: %w", err)
+		}
 		log.Info().Str("new_delegation_id", newDelegation.StakingTxHashHex).
 			Str("previous_staking_tx_hash_hex", newDelegation.PreviousStakingTxHashHex).
 			Msg("handled spending staking transaction for expansion delegation")
 		return nil
 	}
 
-	return fmt.Errorf("spending tx is not unbonding, withdrawal, slashing or delegation expansion")
+	// covenants sign the unbonding path only for the registered unbonding tx and stake expansion tx,
+	// the spending tx is neither the registered unbonding tx nor an indexed expansion
+	unlocksUnbondingPath, err := s.unlocksStakingUnbondingPath(ctx, spendingTx, spendingInputIdx, delegation, params)
+	if err != nil {
+		return fmt.Errorf("failed to check staking tx unbonding path: %w", err)
+	}
+	if unlocksUnbondingPath {
+		return s.handleUnknownSpend(
+			ctx, delegation, spendingTx, spendingHeight,
+			model.SpentOutputStaking, unknownSpendReasonUnknownExpansion,
+		)
+	}
+
+	return s.handleUnknownSpend(
+		ctx, delegation, spendingTx, spendingHeight,
+		model.SpentOutputStaking, unknownSpendReasonUnrecognizedStaking,
+	)
 }
 
 func (s *Service) handleSpendingUnbondingTransaction(
@@ -365,6 +417,14 @@ func (s *Service) handleSpendingUnbondingTransaction(
 
 	log := log.Ctx(ctx)
 
+	// the unbonding output is spent only through script paths, which reveal the script in the witness
+	if _, err := witnessScript(spendingTx, spendingInputIdx); err != nil {
+		return s.handleUnknownSpend(
+			ctx, delegation, spendingTx, spendingHeight,
+			model.SpentOutputUnbonding, unknownSpendReasonMalformedWitness,
+		)
+	}
+
 	// First try to validate as withdrawal transaction
 	isWithdrawal, err := s.isSpendingUnbondingTxTimeLockPath(spendingTx, delegation, spendingInputIdx, params)
 	if err != nil {
@@ -376,7 +436,11 @@ func (s *Service) handleSpendingUnbondingTransaction(
 			Str("staking_tx", delegation.StakingTxHashHex).
 			Stringer("unbonding_tx", spendingTx.TxHash()).
 			Msg("unbonding tx has been spent through withdrawal path")
-		return s.handleWithdrawal(ctx, delegation, types.SubStateEarlyUnbonding, spendingHeight, spendingTx)
+		unbondingValue, err := unbondingOutputValue(delegation)
+		if err != nil {
+			return fmt.Errorf("failed to get unbonding output value: %w", err)
+		}
+		return s.handleWithdrawal(ctx, delegation, types.SubStateEarlyUnbonding, spendingHeight, spendingTx, unbondingValue)
 	}
 
 	// Try to validate as slashing transaction
@@ -397,21 +461,37 @@ func (s *Service) handleSpendingUnbondingTransaction(
 		}
 		unbondingSlashingTxHex := unbondingSlashingTx.ToHexStr()
 
-		unbondingSlashingBtcTimestamp, err := s.btc.GetBlockTimestamp(ctx, spendingHeight)
+		unbondingSlashingBtcTimestamp, err := s.btcBlockTime(ctx, spendingHeight)
 		if err != nil {
 			return fmt.Errorf("failed to get block timestamp: %w", err)
 		}
 
-		// Update state and slashing related fields
-		if err := s.db.UpdateBTCDelegationState(
-			ctx,
-			delegation.StakingTxHashHex,
-			types.QualifiedStatesForSlashed(),
-			types.StateSlashed,
-			db.WithSubState(types.SubStateEarlyUnbondingSlashing),
-			db.WithUnbondingSlashingTx(unbondingSlashingTxHex, spendingHeight, unbondingSlashingBtcTimestamp),
-			db.WithBtcHeight(spendingHeight),
-		); err != nil {
+		unbondingValue, err := unbondingOutputValue(delegation)
+		if err != nil {
+			return fmt.Errorf("failed to get unbonding output value: %w", err)
+		}
+		unbondingSlashingTxDetails, err := s.newSlashingTxDetails(ctx, spendingTx, spendingHeight, unbondingValue)
+		if err != nil {
+			return err
+		}
+
+		// Update state and slashing related fields, delegation was read when the unbonding spend was registered
+		err = s.updateDelegation(ctx, delegation.StakingTxHashHex, func(current *model.BTCDelegationDetails) error {
+			return s.db.UpdateBTCDelegationState(
+				ctx,
+				delegation.StakingTxHashHex,
+				types.TriggerSlashingSpend,
+				types.StateSlashed,
+				db.WithSubState(types.SubStateEarlyUnbondingSlashing),
+				db.WithUnbondingSlashingTx(
+					unbondingSlashingTxHex, spendingHeight, unbondingSlashingBtcTimestamp, unbondingSlashingTxDetails,
+				),
+				db.WithBtcHeight(spendingHeight),
+				db.WithBtcTimestamp(unbondingSlashingBtcTimestamp),
+				db.WithExpectedVersion(current.Version),
+			)
+		})
+		if err != nil {
 			log.Error().
 				Err(err).
 				Str("staking_tx", delegation.StakingTxHashHex).
@@ -433,7 +513,10 @@ func (s *Service) handleSpendingUnbondingTransaction(
 		)
 	}
 
-	return fmt.Errorf("spending tx is neither withdrawal nor slashing")
+	return s.handleUnknownSpend(
+		ctx, delegation, spendingTx, spendingHeight,
+		model.SpentOutputUnbonding, unknownSpendReasonUnrecognizedUnbonding,
+	)
 }
 
 func (s *Service) handleWithdrawal(
@@ -442,21 +525,36 @@ func (s *Service) handleWithdrawal(
 	subState types.DelegationSubState,
 	spendingHeight uint32,
 	spendingTx *wire.MsgTx,
+	spentValue int64,
 ) error {
-	delegationState, err := s.db.GetBTCDelegationState(ctx, delegation.StakingTxHashHex)
+	transition, err := types.FindTransition(types.TriggerWithdrawalSpend, types.StateWithdrawn, subState)
 	if err != nil {
-		return fmt.Errorf("failed to get delegation state: %w", err)
+		return err
+	}
+
+	current, err := s.db.GetBTCDelegationByStakingTxHash(ctx, delegation.StakingTxHashHex)
+	if err != nil {
+		return fmt.Errorf("failed to get BTC delegation by staking tx hash: %w", err)
 	}
 
 	log := log.Ctx(ctx)
 
-	qualifiedStates := types.QualifiedStatesForWithdrawn()
-	if qualifiedStates == nil || !slices.Contains(qualifiedStates, *delegationState) {
+	if !s.canTransition(ctx, current, transition) {
 		log.Error().
 			Str("staking_tx", delegation.StakingTxHashHex).
-			Stringer("current_state", delegationState).
+			Stringer("current_state", current.State).
 			Msg("current state is not qualified for withdrawal")
-		return fmt.Errorf("current state %s is not qualified for withdrawal", *delegationState)
+		return fmt.Errorf("current state %s is not qualified for withdrawal", current.State)
+	}
+
+	withdrawalBtcTimestamp, err := s.btcBlockTime(ctx, spendingHeight)
+	if err != nil {
+		return fmt.Errorf("failed to get block timestamp: %w", err)
+	}
+
+	withdrawalTx, err := s.newWithdrawalTx(ctx, spendingTx, spendingHeight, spentValue)
+	if err != nil {
+		return err
 	}
 
 	// Update to withdrawn state
@@ -466,15 +564,19 @@ func (s *Service) handleWithdrawal(
 		Stringer("sub_state", subState).
 		Msg("updating delegation state to withdrawn")
 
-	return s.db.UpdateBTCDelegationState(
-		ctx,
-		delegation.StakingTxHashHex,
-		types.QualifiedStatesForWithdrawn(),
-		types.StateWithdrawn,
-		db.WithSubState(subState),
-		db.WithBtcHeight(spendingHeight),
-		db.WithWithdrawalTx(spendingTx.TxHash().String()),
-	)
+	return s.updateDelegation(ctx, delegation.StakingTxHashHex, func(current *model.BTCDelegationDetails) error {
+		return s.db.UpdateBTCDelegationState(
+			ctx,
+			delegation.StakingTxHashHex,
+			types.TriggerWithdrawalSpend,
+			types.StateWithdrawn,
+			db.WithSubState(subState),
+			db.WithBtcHeight(spendingHeight),
+			db.WithBtcTimestamp(withdrawalBtcTimestamp),
+			db.WithWithdrawalTx(withdrawalTx),
+			db.WithExpectedVersion(current.Version),
+		)
+	})
 }
 
 func (s *Service) startWatchingSlashingChange(
@@ -513,7 +615,9 @@ func (s *Service) startWatchingSlashingChange(
 		return fmt.Errorf("failed to save timelock expire: %w", err)
 	}
 
+	ctx, done := s.spendWatches.start(ctx, slashingTx.TxHash().String())
 	go func() {
+		defer done()
 		// Register spend notification for the change output
 		spendEv, err := s.btcNotifier.RegisterSpendNtfn(
 			&changeOutpoint,
@@ -565,6 +669,24 @@ func (s *Service) isSpendingStakingTxUnbondingPath(
 		return false, nil
 	}
 
+	// 3. an unbonding tx must unlock the unbonding path
+	return s.unlocksStakingUnbondingPath(ctx, tx, 0, delegation, params)
+}
+
+// unlocksStakingUnbondingPath checks if the input of the transaction spending the staking output
+// unlocks its unbonding path, it's the case of unbonding and stake expansion txs
+func (s *Service) unlocksStakingUnbondingPath(
+	ctx context.Context,
+	tx *wire.MsgTx,
+	spendingInputIdx uint32,
+	delegation *model.BTCDelegationDetails,
+	params *bbnclient.StakingParams,
+) (bool, error) {
+	stakingTx, err := utils.DeserializeBtcTransactionFromHex(delegation.StakingTxHex)
+	if err != nil {
+		return false, fmt.Errorf("failed to deserialize staking tx: %w", err)
+	}
+
 	stakerPk, err := bbn.NewBIP340PubKeyFromHex(delegation.StakerBtcPkHex)
 	if err != nil {
 		return false, fmt.Errorf("failed to convert staker btc pkh to a public key: %w", err)
@@ -595,7 +717,7 @@ func (s *Service) isSpendingStakingTxUnbondingPath(
 
 	stakingValue := btcutil.Amount(stakingTx.TxOut[delegation.StakingOutputIdx].Value)
 
-	// 3. re-build the unbonding path script and check whether the script from
+	// re-build the unbonding path script and check whether the script from
 	// the witness matches
 	stakingInfo, err := btcstaking.BuildStakingInfo(
 		stakerPk.MustToBTCPK(),
@@ -614,13 +736,11 @@ func (s *Service) isSpendingStakingTxUnbondingPath(
 		return false, fmt.Errorf("failed to get the unbonding path spend info: %w", err)
 	}
 
-	witness := tx.TxIn[0].Witness
-	if len(witness) < 2 {
-		panic(fmt.Errorf("spending tx should have at least 2 elements in witness, got %d", len(witness)))
+	scriptFromWitness, err := witnessScript(tx, spendingInputIdx)
+	if err != nil {
+		return false, err
 	}
 
-	scriptFromWitness := tx.TxIn[0].Witness[len(tx.TxIn[0].Witness)-2]
-
 	if !bytes.Equal(unbondingPathInfo.GetPkScriptPath(), scriptFromWitness) {
 		// not unbonding tx as it does not unlock the unbonding path
 		log.Ctx(ctx).Debug().
@@ -796,13 +916,11 @@ func (s *Service) isSpendingStakingTxTimeLockPath(ctx context.Context, tx *wire.
 		return false, fmt.Errorf("failed to get the unbonding path spend info: %w", err)
 	}
 
-	witness := tx.TxIn[spendingInputIdx].Witness
-	if len(witness) < 2 {
-		panic(fmt.Errorf("spending tx should have at least 2 elements in witness, got %d", len(witness)))
+	scriptFromWitness, err := witnessScript(tx, spendingInputIdx)
+	if err != nil {
+		return false, err
 	}
 
-	scriptFromWitness := tx.TxIn[spendingInputIdx].Witness[len(tx.TxIn[spendingInputIdx].Witness)-2]
-
 	if !bytes.Equal(timelockPathInfo.GetPkScriptPath(), scriptFromWitness) {
 		log.Ctx(ctx).Debug().
 			Str("staking_tx", delegation.StakingTxHashHex).
@@ -875,13 +993,11 @@ func (s *Service) isSpendingUnbondingTxTimeLockPath(
 		return false, fmt.Errorf("failed to get the unbonding path spend info: %w", err)
 	}
 
-	witness := tx.TxIn[spendingInputIdx].Witness
-	if len(witness) < 2 {
-		panic(fmt.Errorf("spending tx should have at least 2 elements in witness, got %d", len(witness)))
+	scriptFromWitness, err := witnessScript(tx, spendingInputIdx)
+	if err != nil {
+		return false, err
 	}
 
-	scriptFromWitness := tx.TxIn[spendingInputIdx].Witness[len(tx.TxIn[spendingInputIdx].Witness)-2]
-
 	if !bytes.Equal(timelockPathInfo.GetPkScriptPath(), scriptFromWitness) {
 		log.Debug().
 			Str("staking_tx", delegation.StakingTxHashHex).
@@ -929,7 +1045,7 @@ func (s *Service) isSpendingStakingTxSlashingPath(ctx context.Context, tx *wire.
 
 	stakingValue := btcutil.Amount(stakingTx.TxOut[delegation.StakingOutputIdx].Value)
 
-	// 3. re-build the unbonding path script and check whether the script from
+	// re-build the unbonding path script and check whether the script from
 	// the witness matches
 	stakingInfo, err := btcstaking.BuildStakingInfo(
 		stakerPk.MustToBTCPK(),
@@ -949,13 +1065,11 @@ func (s *Service) isSpendingStakingTxSlashingPath(ctx context.Context, tx *wire.
 		return false, fmt.Errorf("failed to get the slashing path spend info: %w", err)
 	}
 
-	witness := tx.TxIn[spendingInputIdx].Witness
-	if len(witness) < 2 {
-		panic(fmt.Errorf("spending tx should have at least 2 elements in witness, got %d", len(witness)))
+	scriptFromWitness, err := witnessScript(tx, spendingInputIdx)
+	if err != nil {
+		return false, err
 	}
 
-	scriptFromWitness := tx.TxIn[spendingInputIdx].Witness[len(tx.TxIn[spendingInputIdx].Witness)-2]
-
 	if !bytes.Equal(slashingPathInfo.GetPkScriptPath(), scriptFromWitness) {
 		log.Ctx(ctx).Debug().
 			Str("staking_tx", delegation.StakingTxHashHex).
@@ -1023,13 +1137,11 @@ func (s *Service) isSpendingUnbondingTxSlashingPath(ctx context.Context, tx *wir
 		return false, fmt.Errorf("failed to get the slashing path spend info: %w", err)
 	}
 
-	witness := tx.TxIn[spendingInputIdx].Witness
-	if len(witness) < 2 {
-		panic(fmt.Errorf("spending tx should have at least 2 elements in witness, got %d", len(witness)))
+	scriptFromWitness, err := witnessScript(tx, spendingInputIdx)
+	if err != nil {
+		return false, err
 	}
 
-	scriptFromWitness := tx.TxIn[spendingInputIdx].Witness[len(tx.TxIn[spendingInputIdx].Witness)-2]
-
 	if !bytes.Equal(slashingPathInfo.GetPkScriptPath(), scriptFromWitness) {
 		log.Ctx(ctx).Debug().
 			Str("staking_tx", delegation.StakingTxHashHex).
@@ -0,0 +1,308 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/bbnclient"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/utils"
+	"github.com/babylonlabs-io/babylon/v4/btcstaking"
+	bbn "github.com/babylonlabs-io/babylon/v4/types"
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/mempool"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// withdrawalWitnessSignatureSize is the size of the staker schnorr signature with the default sighash,
+// used to estimate the size of the signed withdrawal tx
+const withdrawalWitnessSignatureSize = 64
+
+// WithdrawalPsbt is an unsigned tx that withdraws a delegation through the timelock path
+type WithdrawalPsbt struct {
+	// Base64 encoded PSBT, ready to be signed by the staker
+	Psbt string
+	// Output spent by the withdrawal
+	SpentTxHashHex string
+	SpentOutputIdx uint32
+	SpentAmount    int64
+	// Amount sent to the destination address
+	WithdrawalAmount int64
+	Fee              int64
+}
+
+// withdrawalInput is the delegation output that can be withdrawn and its timelock script
+type withdrawalInput struct {
+	outpoint  wire.OutPoint
+	prevOut   *wire.TxOut
+	stakerPk  *btcec.PublicKey
+	spendInfo *btcstaking.SpendInfo
+	lockTime  uint16
+}
+
+// BuildWithdrawalPsbt constructs an unsigned PSBT that sends the withdrawable output of the delegation
+// to the destination address. The output depends on the sub state of the delegation: staking output,
+// unbonding output or change output of one of the slashing txs. feeRate is in sat/vB.
+func (s *Service) BuildWithdrawalPsbt(
+	ctx context.Context,
+	stakingTxHashHex string,
+	destinationAddress string,
+	feeRate int64,
+) (*WithdrawalPsbt, error) {
+	if feeRate <= 0 {
+		return nil, types.NewErrorWithMsg(http.StatusBadRequest, types.BadRequest, "fee rate must be positive")
+	}
+
+	btcParams, err := utils.GetBTCParams(s.cfg.BTC.NetParams)
+	if err != nil {
+		return nil, types.NewInternalServiceError(err)
+	}
+
+	destination, err := btcutil.DecodeAddress(destinationAddress, btcParams)
+	if err != nil || !destination.IsForNet(btcParams) {
+		return nil, types.NewErrorWithMsg(
+			http.StatusBadRequest,
+			types.BadRequest,
+			fmt.Sprintf("invalid destination address %s for network %s", destinationAddress, btcParams.Name),
+		)
+	}
+	destinationPkScript, err := txscript.PayToAddrScript(destination)
+	if err != nil {
+		return nil, types.NewErrorWithMsg(
+			http.StatusBadRequest,
+			types.BadRequest,
+			fmt.Sprintf("unsupported destination address %s: %v", destinationAddress, err),
+		)
+	}
+
+	delegation, err := s.db.GetBTCDelegationByStakingTxHash(ctx, stakingTxHashHex)
+	if err != nil {
+		if db.IsNotFoundError(err) {
+			return nil, types.NewErrorWithMsg(
+				http.StatusNotFound,
+				types.NotFound,
+				fmt.Sprintf("delegation %s not found", stakingTxHashHex),
+			)
+		}
+		return nil, types.NewInternalServiceError(fmt.Errorf("failed to get delegation: %w", err))
+	}
+
+	if delegation.State != types.StateWithdrawable {
+		return nil, types.NewErrorWithMsg(
+			http.StatusConflict,
+			types.InvalidDelegationState,
+			fmt.Sprintf("delegation %s is %s, only %s delegations can be withdrawn",
+				stakingTxHashHex, delegation.State, types.StateWithdrawable),
+		)
+	}
+
+	params, err := s.db.GetStakingParams(ctx, delegation.ParamsVersion)
+	if err != nil {
+		return nil, types.NewInternalServiceError(fmt.Errorf("failed to get staking params: %w", err))
+	}
+
+	input, err := newWithdrawalInput(delegation, params, btcParams)
+	if err != nil {
+		return nil, types.NewInternalServiceError(err)
+	}
+
+	return buildWithdrawalPsbt(input, destinationPkScript, feeRate)
+}
+
+// newWithdrawalInput rebuilds the timelock script of the output that is withdrawable in the
+// delegation sub state and checks it matches the output of the stored tx
+func newWithdrawalInput(
+	delegation *model.BTCDelegationDetails,
+	params *bbnclient.StakingParams,
+	btcParams *chaincfg.Params,
+) (*withdrawalInput, error) {
+	stakerPk, fpPks, covPks, err := delegationScriptKeys(delegation, params)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		txHex     string
+		outputIdx uint32
+		lockTime  uint16
+	)
+	switch delegation.SubState {
+	case types.SubStateTimelock:
+		txHex, outputIdx, lockTime = delegation.StakingTxHex, delegation.StakingOutputIdx, uint16(delegation.StakingTime)
+	case types.SubStateEarlyUnbonding:
+		txHex, outputIdx, lockTime = delegation.UnbondingTx, 0, uint16(delegation.UnbondingTime)
+	case types.SubStateTimelockSlashing:
+		// change output of the slashing tx is always second and locked for the unbonding time
+		txHex, outputIdx, lockTime = delegation.SlashingTx.SlashingTxHex, 1, uint16(delegation.UnbondingTime)
+	case types.SubStateEarlyUnbondingSlashing:
+		txHex, outputIdx, lockTime = delegation.SlashingTx.UnbondingSlashingTxHex, 1, uint16(delegation.UnbondingTime)
+	default:
+		return nil, fmt.Errorf("unknown sub state %q of withdrawable delegation %s", delegation.SubState, delegation.StakingTxHashHex)
+	}
+
+	tx, prevOut, err := txOutput(txHex, outputIdx)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		spendInfo *btcstaking.SpendInfo
+		pkScript  []byte
+	)
+	switch delegation.SubState {
+	case types.SubStateTimelock:
+		stakingInfo, err := btcstaking.BuildStakingInfo(
+			stakerPk, fpPks, covPks, params.CovenantQuorum, lockTime, btcutil.Amount(prevOut.Value), btcParams,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to rebuild the staking info: %w", err)
+		}
+		spendInfo, err = stakingInfo.TimeLockPathSpendInfo()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the staking time-lock path spend info: %w", err)
+		}
+		pkScript = stakingInfo.StakingOutput.PkScript
+	case types.SubStateEarlyUnbonding:
+		unbondingInfo, err := btcstaking.BuildUnbondingInfo(
+			stakerPk, fpPks, covPks, params.CovenantQuorum, lockTime, btcutil.Amount(prevOut.Value), btcParams,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to rebuild the unbonding info: %w", err)
+		}
+		spendInfo, err = unbondingInfo.TimeLockPathSpendInfo()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the unbonding time-lock path spend info: %w", err)
+		}
+		pkScript = unbondingInfo.UnbondingOutput.PkScript
+	default:
+		changeInfo, err := btcstaking.BuildRelativeTimelockTaprootScript(stakerPk, lockTime, btcParams)
+		if err != nil {
+			return nil, fmt.Errorf("failed to rebuild the slashing change script: %w", err)
+		}
+		spendInfo, pkScript = changeInfo.SpendInfo, changeInfo.PkScript
+	}
+
+	if !bytes.Equal(prevOut.PkScript, pkScript) {
+		return nil, fmt.Errorf("output %s:%d of delegation %s doesn't match the rebuilt time-lock script",
+			tx.TxHash(), outputIdx, delegation.StakingTxHashHex)
+	}
+
+	return &withdrawalInput{
+		outpoint:  wire.OutPoint{Hash: tx.TxHash(), Index: outputIdx},
+		prevOut:   prevOut,
+		stakerPk:  stakerPk,
+		spendInfo: spendInfo,
+		lockTime:  lockTime,
+	}, nil
+}
+
+// buildWithdrawalPsbt creates the PSBT spending the input to destinationPkScript, the fee
+// is deducted from the withdrawn amount
+func buildWithdrawalPsbt(input *withdrawalInput, destinationPkScript []byte, feeRate int64) (*WithdrawalPsbt, error) {
+	controlBlock, err := input.spendInfo.ControlBlock.ToBytes()
+	if err != nil {
+		return nil, types.NewInternalServiceError(fmt.Errorf("failed to serialize control block: %w", err))
+	}
+	leaf := input.spendInfo.RevealedLeaf
+
+	tx := wire.NewMsgTx(2)
+	txIn := wire.NewTxIn(&input.outpoint, nil, nil)
+	// relative timelock of the script is enforced by the sequence of the input
+	txIn.Sequence = uint32(input.lockTime)
+	tx.AddTxIn(txIn)
+	tx.AddTxOut(wire.NewTxOut(0, destinationPkScript))
+
+	fee := estimateWithdrawalVsize(tx, leaf.Script, controlBlock) * feeRate
+	withdrawalAmount := input.prevOut.Value - fee
+	tx.TxOut[0].Value = withdrawalAmount
+	if withdrawalAmount <= 0 || mempool.IsDust(tx.TxOut[0], mempool.DefaultMinRelayTxFee) {
+		return nil, types.NewErrorWithMsg(
+			http.StatusBadRequest,
+			types.BadRequest,
+			fmt.Sprintf("output of %d sat can't pay fee of %d sat without creating dust", input.prevOut.Value, fee),
+		)
+	}
+
+	packet, err := psbt.NewFromUnsignedTx(tx)
+	if err != nil {
+		return nil, types.NewInternalServiceError(fmt.Errorf("failed to create psbt: %w", err))
+	}
+	packet.Inputs[0].WitnessUtxo = input.prevOut
+	packet.Inputs[0].TaprootInternalKey = schnorr.SerializePubKey(input.spendInfo.ControlBlock.InternalKey)
+	packet.Inputs[0].TaprootLeafScript = []*psbt.TaprootTapLeafScript{{
+		ControlBlock: controlBlock,
+		Script:       leaf.Script,
+		LeafVersion:  leaf.LeafVersion,
+	}}
+	// lets the signer find the key that signs the timelock leaf
+	leafHash := leaf.TapHash()
+	packet.Inputs[0].TaprootBip32Derivation = []*psbt.TaprootBip32Derivation{{
+		XOnlyPubKey: schnorr.SerializePubKey(input.stakerPk),
+		LeafHashes:  [][]byte{leafHash[:]},
+	}}
+
+	encoded, err := packet.B64Encode()
+	if err != nil {
+		return nil, types.NewInternalServiceError(fmt.Errorf("failed to encode psbt: %w", err))
+	}
+
+	return &WithdrawalPsbt{
+		Psbt:             encoded,
+		SpentTxHashHex:   input.outpoint.Hash.String(),
+		SpentOutputIdx:   input.outpoint.Index,
+		SpentAmount:      input.prevOut.Value,
+		WithdrawalAmount: withdrawalAmount,
+		Fee:              fee,
+	}, nil
+}
+
+// estimateWithdrawalVsize returns the virtual size of tx once the staker signs the timelock path
+func estimateWithdrawalVsize(tx *wire.MsgTx, script, controlBlock []byte) int64 {
+	signedTx := tx.Copy()
+	signedTx.TxIn[0].Witness = wire.TxWitness{
+		make([]byte, withdrawalWitnessSignatureSize),
+		script,
+		controlBlock,
+	}
+	return mempool.GetTxVirtualSize(btcutil.NewTx(signedTx))
+}
+
+// delegationScriptKeys parses the staker, finality provider and covenant keys the delegation scripts commit to
+func delegationScriptKeys(
+	delegation *model.BTCDelegationDetails,
+	params *bbnclient.StakingParams,
+) (*btcec.PublicKey, []*btcec.PublicKey, []*btcec.PublicKey, error) {
+	stakerPk, err := bbn.NewBIP340PubKeyFromHex(delegation.StakerBtcPkHex)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to convert staker btc pkh to a public key: %w", err)
+	}
+
+	finalityProviderPks := make([]*btcec.PublicKey, len(delegation.FinalityProviderBtcPksHex))
+	for i, hex := range delegation.FinalityProviderBtcPksHex {
+		fpPk, err := bbn.NewBIP340PubKeyFromHex(hex)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to convert finality provider pk hex to a public key: %w", err)
+		}
+		finalityProviderPks[i] = fpPk.MustToBTCPK()
+	}
+
+	covPks := make([]*btcec.PublicKey, len(params.CovenantPks))
+	for i, hex := range params.CovenantPks {
+		covPk, err := bbn.NewBIP340PubKeyFromHex(hex)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to convert covenant pk hex to a public key: %w", err)
+		}
+		covPks[i] = covPk.MustToBTCPK()
+	}
+
+	return stakerPk.MustToBTCPK(), finalityProviderPks, covPks, nil
+}
@@ -0,0 +1,279 @@
+package services
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/bbnclient"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/utils"
+	"github.com/babylonlabs-io/babylon/v4/btcstaking"
+	bbn "github.com/babylonlabs-io/babylon/v4/types"
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/mempool"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	withdrawalTestStakingTime   = 1000
+	withdrawalTestUnbondingTime = 101
+	withdrawalTestStakingAmount = 100000
+	withdrawalTestUnbondingFee  = 1000
+)
+
+// newWithdrawableTestDelegation creates a delegation with valid staking, unbonding and slashing txs
+// and returns it with its params and the staker key
+func newWithdrawableTestDelegation(t *testing.T) (*model.BTCDelegationDetails, *bbnclient.StakingParams, *btcec.PrivateKey) {
+	t.Helper()
+	net := &chaincfg.SigNetParams
+
+	newKey := func() *btcec.PrivateKey {
+		key, err := btcec.NewPrivateKey()
+		require.NoError(t, err)
+		return key
+	}
+	pkHex := func(key *btcec.PrivateKey) string {
+		return bbn.NewBIP340PubKeyFromBTCPK(key.PubKey()).MarshalHex()
+	}
+
+	stakerKey, fpKey := newKey(), newKey()
+	covKeys := []*btcec.PrivateKey{newKey(), newKey(), newKey()}
+	covPks := make([]*btcec.PublicKey, len(covKeys))
+	params := &bbnclient.StakingParams{CovenantQuorum: 2, UnbondingFeeSat: withdrawalTestUnbondingFee}
+	for i, key := range covKeys {
+		covPks[i] = key.PubKey()
+		params.CovenantPks = append(params.CovenantPks, pkHex(key))
+	}
+	fpPks := []*btcec.PublicKey{fpKey.PubKey()}
+
+	stakingInfo, err := btcstaking.BuildStakingInfo(
+		stakerKey.PubKey(), fpPks, covPks, params.CovenantQuorum,
+		withdrawalTestStakingTime, withdrawalTestStakingAmount, net,
+	)
+	require.NoError(t, err)
+	_, otherScript := testAddressScript(t, 1)
+	stakingTx := wire.NewMsgTx(2)
+	stakingTx.AddTxIn(wire.NewTxIn(&wire.OutPoint{}, nil, nil))
+	stakingTx.AddTxOut(wire.NewTxOut(5000, otherScript))
+	stakingTx.AddTxOut(stakingInfo.StakingOutput)
+	stakingTxHash := stakingTx.TxHash()
+
+	unbondingInfo, err := btcstaking.BuildUnbondingInfo(
+		stakerKey.PubKey(), fpPks, covPks, params.CovenantQuorum,
+		withdrawalTestUnbondingTime, withdrawalTestStakingAmount-withdrawalTestUnbondingFee, net,
+	)
+	require.NoError(t, err)
+	unbondingTx := wire.NewMsgTx(2)
+	unbondingTx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&stakingTxHash, 1), nil, nil))
+	unbondingTx.AddTxOut(unbondingInfo.UnbondingOutput)
+	unbondingTxHash := unbondingTx.TxHash()
+
+	changeInfo, err := btcstaking.BuildRelativeTimelockTaprootScript(stakerKey.PubKey(), withdrawalTestUnbondingTime, net)
+	require.NoError(t, err)
+	slashingTx := wire.NewMsgTx(2)
+	slashingTx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&stakingTxHash, 1), nil, nil))
+	slashingTx.AddTxOut(wire.NewTxOut(10000, otherScript))
+	slashingTx.AddTxOut(wire.NewTxOut(89000, changeInfo.PkScript))
+	unbondingSlashingTx := wire.NewMsgTx(2)
+	unbondingSlashingTx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&unbondingTxHash, 0), nil, nil))
+	unbondingSlashingTx.AddTxOut(wire.NewTxOut(10000, otherScript))
+	unbondingSlashingTx.AddTxOut(wire.NewTxOut(88000, changeInfo.PkScript))
+
+	toHex := func(tx *wire.MsgTx) string {
+		txBytes, err := utils.SerializeBtcTransaction(tx)
+		require.NoError(t, err)
+		return hex.EncodeToString(txBytes)
+	}
+
+	delegation := &model.BTCDelegationDetails{
+		StakingTxHashHex:          stakingTxHash.String(),
+		StakingTxHex:              toHex(stakingTx),
+		StakingTime:               withdrawalTestStakingTime,
+		StakingAmount:             withdrawalTestStakingAmount,
+		StakingOutputIdx:          1,
+		StakerBtcPkHex:            pkHex(stakerKey),
+		FinalityProviderBtcPksHex: []string{pkHex(fpKey)},
+		State:                     types.StateWithdrawable,
+		ParamsVersion:             1,
+		UnbondingTime:             withdrawalTestUnbondingTime,
+		UnbondingTx:               toHex(unbondingTx),
+		SlashingTx: model.SlashingTx{
+			SlashingTxHex:          toHex(slashingTx),
+			UnbondingSlashingTxHex: toHex(unbondingSlashingTx),
+		},
+	}
+	return delegation, params, stakerKey
+}
+
+func TestBuildWithdrawalPsbt(t *testing.T) {
+	ctx := context.Background()
+	destinationAddress, destinationScript := testAddressScript(t, 7)
+	const feeRate = 5
+
+	tests := []struct {
+		subState    types.DelegationSubState
+		spentTxHex  func(d *model.BTCDelegationDetails) string
+		spentIdx    uint32
+		spentAmount int64
+		lockTime    uint32
+	}{
+		{
+			subState:    types.SubStateTimelock,
+			spentTxHex:  func(d *model.BTCDelegationDetails) string { return d.StakingTxHex },
+			spentIdx:    1,
+			spentAmount: withdrawalTestStakingAmount,
+			lockTime:    withdrawalTestStakingTime,
+		},
+		{
+			subState:    types.SubStateEarlyUnbonding,
+			spentTxHex:  func(d *model.BTCDelegationDetails) string { return d.UnbondingTx },
+			spentIdx:    0,
+			spentAmount: withdrawalTestStakingAmount - withdrawalTestUnbondingFee,
+			lockTime:    withdrawalTestUnbondingTime,
+		},
+		{
+			subState:    types.SubStateTimelockSlashing,
+			spentTxHex:  func(d *model.BTCDelegationDetails) string { return d.SlashingTx.SlashingTxHex },
+			spentIdx:    1,
+			spentAmount: 89000,
+			lockTime:    withdrawalTestUnbondingTime,
+		},
+		{
+			subState:    types.SubStateEarlyUnbondingSlashing,
+			spentTxHex:  func(d *model.BTCDelegationDetails) string { return d.SlashingTx.UnbondingSlashingTxHex },
+			spentIdx:    1,
+			spentAmount: 88000,
+			lockTime:    withdrawalTestUnbondingTime,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.subState.String(), func(t *testing.T) {
+			delegation, params, stakerKey := newWithdrawableTestDelegation(t)
+			delegation.SubState = tt.subState
+			s, m := newTestService(t, withBTCConfig(config.BTCConfig{NetParams: utils.BtcSignet.String()}))
+			m.db.On("GetBTCDelegationByStakingTxHash", ctx, delegation.StakingTxHashHex).Return(delegation, nil).Once()
+			m.db.On("GetStakingParams", ctx, uint32(1)).Return(params, nil).Once()
+
+			result, err := s.BuildWithdrawalPsbt(ctx, delegation.StakingTxHashHex, destinationAddress, feeRate)
+			require.NoError(t, err)
+
+			spentTx, err := utils.DeserializeBtcTransactionFromHex(tt.spentTxHex(delegation))
+			require.NoError(t, err)
+			prevOut := spentTx.TxOut[tt.spentIdx]
+			assert.Equal(t, spentTx.TxHash().String(), result.SpentTxHashHex)
+			assert.Equal(t, tt.spentIdx, result.SpentOutputIdx)
+			assert.Equal(t, tt.spentAmount, result.SpentAmount)
+			assert.Equal(t, tt.spentAmount-result.Fee, result.WithdrawalAmount)
+
+			packet, err := psbt.NewFromRawBytes(strings.NewReader(result.Psbt), true)
+			require.NoError(t, err)
+			tx := packet.UnsignedTx
+			require.Len(t, tx.TxIn, 1)
+			require.Len(t, tx.TxOut, 1)
+			assert.Equal(t, wire.OutPoint{Hash: spentTx.TxHash(), Index: tt.spentIdx}, tx.TxIn[0].PreviousOutPoint)
+			assert.Equal(t, tt.lockTime, tx.TxIn[0].Sequence)
+			assert.Equal(t, destinationScript, tx.TxOut[0].PkScript)
+			assert.Equal(t, result.WithdrawalAmount, tx.TxOut[0].Value)
+			assert.Equal(t, prevOut, packet.Inputs[0].WitnessUtxo)
+			require.Len(t, packet.Inputs[0].TaprootLeafScript, 1)
+			leafScript := packet.Inputs[0].TaprootLeafScript[0]
+
+			// the staker signature is the only missing part of a valid witness
+			leaf := txscript.NewTapLeaf(leafScript.LeafVersion, leafScript.Script)
+			prevOutFetcher := txscript.NewCannedPrevOutputFetcher(prevOut.PkScript, prevOut.Value)
+			sigHashes := txscript.NewTxSigHashes(tx, prevOutFetcher)
+			sig, err := txscript.RawTxInTapscriptSignature(
+				tx, sigHashes, 0, prevOut.Value, prevOut.PkScript, leaf, txscript.SigHashDefault, stakerKey,
+			)
+			require.NoError(t, err)
+			tx.TxIn[0].Witness = wire.TxWitness{sig, leafScript.Script, leafScript.ControlBlock}
+			engine, err := txscript.NewEngine(
+				prevOut.PkScript, tx, 0, txscript.StandardVerifyFlags, nil, sigHashes, prevOut.Value, prevOutFetcher,
+			)
+			require.NoError(t, err)
+			require.NoError(t, engine.Execute())
+
+			assert.Equal(t, mempool.GetTxVirtualSize(btcutil.NewTx(tx))*feeRate, result.Fee)
+		})
+	}
+}
+
+func TestBuildWithdrawalPsbtErrors(t *testing.T) {
+	ctx := context.Background()
+	destinationAddress, _ := testAddressScript(t, 7)
+
+	requireError := func(t *testing.T, err error, statusCode int, errorCode types.ErrorCode) {
+		t.Helper()
+		var typedErr *types.Error
+		require.True(t, errors.As(err, &typedErr), "unexpected error %v", err)
+		assert.Equal(t, statusCode, typedErr.StatusCode)
+		assert.Equal(t, errorCode, typedErr.ErrorCode)
+	}
+
+	t.Run("invalid fee rate", func(t *testing.T) {
+		s, _ := newTestService(t, withBTCConfig(config.BTCConfig{NetParams: utils.BtcSignet.String()}))
+		_, err := s.BuildWithdrawalPsbt(ctx, "hash", destinationAddress, 0)
+		requireError(t, err, http.StatusBadRequest, types.BadRequest)
+	})
+	t.Run("address of another network", func(t *testing.T) {
+		hash := make([]byte, 20)
+		mainnetAddr, err := btcutil.NewAddressWitnessPubKeyHash(hash, &chaincfg.MainNetParams)
+		require.NoError(t, err)
+
+		s, _ := newTestService(t, withBTCConfig(config.BTCConfig{NetParams: utils.BtcSignet.String()}))
+		_, err = s.BuildWithdrawalPsbt(ctx, "hash", mainnetAddr.EncodeAddress(), 1)
+		requireError(t, err, http.StatusBadRequest, types.BadRequest)
+	})
+	t.Run("delegation not found", func(t *testing.T) {
+		s, m := newTestService(t, withBTCConfig(config.BTCConfig{NetParams: utils.BtcSignet.String()}))
+		m.db.On("GetBTCDelegationByStakingTxHash", ctx, "hash").
+			Return(nil, &db.NotFoundError{Key: "hash", Message: "not found"}).Once()
+
+		_, err := s.BuildWithdrawalPsbt(ctx, "hash", destinationAddress, 1)
+		requireError(t, err, http.StatusNotFound, types.NotFound)
+	})
+	t.Run("delegation not withdrawable", func(t *testing.T) {
+		delegation, _, _ := newWithdrawableTestDelegation(t)
+		delegation.State = types.StateUnbonding
+		delegation.SubState = types.SubStateTimelock
+		s, m := newTestService(t, withBTCConfig(config.BTCConfig{NetParams: utils.BtcSignet.String()}))
+		m.db.On("GetBTCDelegationByStakingTxHash", ctx, delegation.StakingTxHashHex).Return(delegation, nil).Once()
+
+		_, err := s.BuildWithdrawalPsbt(ctx, delegation.StakingTxHashHex, destinationAddress, 1)
+		requireError(t, err, http.StatusConflict, types.InvalidDelegationState)
+	})
+	t.Run("fee exceeds output", func(t *testing.T) {
+		delegation, params, _ := newWithdrawableTestDelegation(t)
+		delegation.SubState = types.SubStateTimelock
+		s, m := newTestService(t, withBTCConfig(config.BTCConfig{NetParams: utils.BtcSignet.String()}))
+		m.db.On("GetBTCDelegationByStakingTxHash", ctx, delegation.StakingTxHashHex).Return(delegation, nil).Once()
+		m.db.On("GetStakingParams", ctx, uint32(1)).Return(params, nil).Once()
+
+		_, err := s.BuildWithdrawalPsbt(ctx, delegation.StakingTxHashHex, destinationAddress, 1000)
+		requireError(t, err, http.StatusBadRequest, types.BadRequest)
+	})
+	t.Run("script mismatch", func(t *testing.T) {
+		delegation, params, _ := newWithdrawableTestDelegation(t)
+		delegation.SubState = types.SubStateTimelock
+		delegation.StakingTime++
+		s, m := newTestService(t, withBTCConfig(config.BTCConfig{NetParams: utils.BtcSignet.String()}))
+		m.db.On("GetBTCDelegationByStakingTxHash", ctx, delegation.StakingTxHashHex).Return(delegation, nil).Once()
+		m.db.On("GetStakingParams", ctx, uint32(1)).Return(params, nil).Once()
+
+		_, err := s.BuildWithdrawalPsbt(ctx, delegation.StakingTxHashHex, destinationAddress, 1)
+		requireError(t, err, http.StatusInternalServerError, types.InternalServiceError)
+	})
+}
@@ -0,0 +1,314 @@
+package snapshot
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// FormatVersion is incremented on every incompatible change of the archive layout
+const FormatVersion = 1
+
+const (
+	manifestFileName = "manifest.json"
+	collectionsDir   = "collections"
+	// maximum size of a single bson document accepted by mongo
+	maxDocumentSize = 16 * 1024 * 1024
+)
+
+// Manifest is the first entry of every snapshot archive
+type Manifest struct {
+	FormatVersion       int              `json:"format_version"`
+	CreatedAt           time.Time        `json:"created_at"`
+	ChainID             string           `json:"chain_id"`
+	LastProcessedHeight uint64           `json:"last_processed_height"`
+	Collections         []CollectionInfo `json:"collections"`
+}
+
+// CollectionInfo describes dump of a single collection stored in the archive
+type CollectionInfo struct {
+	Name      string `json:"name"`
+	File      string `json:"file"`
+	Documents int64  `json:"documents"`
+	// SHA256 is hex encoded checksum of the file
+	SHA256 string `json:"sha256"`
+}
+
+// collectionFile returns path of the collection dump inside the archive
+func collectionFile(name string) string {
+	return path.Join(collectionsDir, name+".bson")
+}
+
+// dumpWriter writes documents as concatenated bson (the same layout mongodump uses) calculating checksum
+type dumpWriter struct {
+	w     *bufio.Writer
+	hash  hash.Hash
+	count int64
+}
+
+func newDumpWriter(w io.Writer) *dumpWriter {
+	h := sha256.New()
+	return &dumpWriter{
+		w:    bufio.NewWriter(io.MultiWriter(w, h)),
+		hash: h,
+	}
+}
+
+func (d *dumpWriter) Write(doc bson.Raw) error {
+	d.count++
+	_, err := d.w.Write(doc)
+	return err
+}
+
+func (d *dumpWriter) Close() (string, error) {
+	if err := d.w.Flush(); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(d.hash.Sum(nil)), nil
+}
+
+// readDocuments reads concatenated bson documents calling f for each of them
+func readDocuments(r io.Reader, f func(doc bson.Raw) error) error {
+	br := bufio.NewReader(r)
+	var lengthBuf [4]byte
+	for {
+		if _, err := io.ReadFull(br, lengthBuf[:]); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		length := binary.LittleEndian.Uint32(lengthBuf[:])
+		if length < 5 || length > maxDocumentSize { //nolint:mnd
+			return fmt.Errorf("invalid document length %d", length)
+		}
+
+		doc := make([]byte, length)
+		copy(doc, lengthBuf[:])
+		if _, err := io.ReadFull(br, doc[4:]); err != nil {
+			return err
+		}
+		if err := bson.Raw(doc).Validate(); err != nil {
+			return fmt.Errorf("invalid document: %w", err)
+		}
+
+		if err := f(doc); err != nil {
+			return err
+		}
+	}
+}
+
+// writeArchive writes manifest followed by collection dumps (paths maps collection name to local dump file)
+// into tar.gz file. The file is written under temporary name and renamed in the end, so
+// partially written archive is never left at the destination.
+func writeArchive(dest string, manifest *Manifest, paths map[string]string) (err error) {
+	tmp := dest + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			file.Close()
+			os.Remove(tmp)
+		}
+	}()
+
+	gw := gzip.NewWriter(file)
+	tw := tar.NewWriter(gw)
+
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = tw.WriteHeader(&tar.Header{
+		Name:    manifestFileName,
+		Mode:    0o644, //nolint:mnd
+		Size:    int64(len(manifestData)),
+		ModTime: manifest.CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := tw.Write(manifestData); err != nil {
+		return err
+	}
+
+	for _, info := range manifest.Collections {
+		if err := addFile(tw, info.File, paths[info.Name], manifest.CreatedAt); err != nil {
+			return fmt.Errorf("failed to add %s to archive: %w", info.Name, err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gw.Close(); err != nil {
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, dest)
+}
+
+func addFile(tw *tar.Writer, name, localPath string, modTime time.Time) error {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	err = tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0o644, //nolint:mnd
+		Size:    stat.Size(),
+		ModTime: modTime,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(tw, file)
+	return err
+}
+
+// archiveReader iterates over archive entries, manifest is read on open
+type archiveReader struct {
+	file     *os.File
+	gr       *gzip.Reader
+	tr       *tar.Reader
+	manifest *Manifest
+}
+
+func openArchive(src string) (*archiveReader, error) {
+	file, err := os.Open(src)
+	if err != nil {
+		return nil, err
+	}
+
+	gr, err := gzip.NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to open gzip stream: %w", err)
+	}
+
+	ar := &archiveReader{
+		file: file,
+		gr:   gr,
+		tr:   tar.NewReader(gr),
+	}
+
+	header, err := ar.tr.Next()
+	if err != nil {
+		ar.Close()
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	if header.Name != manifestFileName {
+		ar.Close()
+		return nil, fmt.Errorf("first archive entry is %q, expected %q", header.Name, manifestFileName)
+	}
+
+	var manifest Manifest
+	if err := json.NewDecoder(ar.tr).Decode(&manifest); err != nil {
+		ar.Close()
+		return nil, fmt.Errorf("failed to decode manifest: %w", err)
+	}
+	if manifest.FormatVersion != FormatVersion {
+		ar.Close()
+		return nil, fmt.Errorf("unsupported snapshot format version %d, expected %d", manifest.FormatVersion, FormatVersion)
+	}
+	ar.manifest = &manifest
+
+	return ar, nil
+}
+
+// next returns the next collection entry and reader of its content, io.EOF is returned in the end
+func (a *archiveReader) next() (CollectionInfo, io.Reader, error) {
+	header, err := a.tr.Next()
+	if err != nil {
+		return CollectionInfo{}, nil, err
+	}
+
+	for _, info := range a.manifest.Collections {
+		if info.File == header.Name {
+			return info, a.tr, nil
+		}
+	}
+
+	return CollectionInfo{}, nil, fmt.Errorf("unexpected archive entry %q", header.Name)
+}
+
+func (a *archiveReader) Close() error {
+	return errors.Join(a.gr.Close(), a.file.Close())
+}
+
+// Verify checks that archive contains every collection listed in manifest with matching
+// checksum and number of documents. It returns the manifest if archive is valid.
+func Verify(src string) (*Manifest, error) {
+	ar, err := openArchive(src)
+	if err != nil {
+		return nil, err
+	}
+	defer ar.Close()
+
+	seen := make(map[string]bool)
+	for {
+		info, r, err := ar.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if seen[info.Name] {
+			return nil, fmt.Errorf("duplicate archive entry %q", info.File)
+		}
+		seen[info.Name] = true
+
+		h := sha256.New()
+		var count int64
+		err = readDocuments(io.TeeReader(r, h), func(bson.Raw) error {
+			count++
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", info.File, err)
+		}
+
+		if checksum := hex.EncodeToString(h.Sum(nil)); checksum != info.SHA256 {
+			return nil, fmt.Errorf("checksum mismatch for %s: expected %s, got %s", info.File, info.SHA256, checksum)
+		}
+		if count != info.Documents {
+			return nil, fmt.Errorf("documents count mismatch for %s: expected %d, got %d", info.File, info.Documents, count)
+		}
+	}
+
+	for _, info := range ar.manifest.Collections {
+		if !seen[info.Name] {
+			return nil, fmt.Errorf("archive entry %q is missing", info.File)
+		}
+	}
+
+	return ar.manifest, nil
+}
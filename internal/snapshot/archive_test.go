@@ -0,0 +1,107 @@
+package snapshot
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestArchive(t *testing.T) {
+	dir := t.TempDir()
+
+	collections := map[string][]bson.D{
+		"first": {
+			{{Key: "_id", Value: "a"}, {Key: "value", Value: int64(1)}},
+			{{Key: "_id", Value: "b"}, {Key: "nested", Value: bson.D{{Key: "list", Value: bson.A{"x", "y"}}}}},
+		},
+		"second": {},
+	}
+
+	manifest := &Manifest{
+		FormatVersion:       FormatVersion,
+		CreatedAt:           time.Now().UTC().Truncate(time.Second),
+		ChainID:             "chain-test",
+		LastProcessedHeight: 100,
+	}
+	paths := make(map[string]string)
+	for _, name := range []string{"first", "second"} {
+		localPath := filepath.Join(dir, name+".bson")
+		file, err := os.Create(localPath)
+		require.NoError(t, err)
+
+		w := newDumpWriter(file)
+		for _, doc := range collections[name] {
+			data, err := bson.Marshal(doc)
+			require.NoError(t, err)
+			require.NoError(t, w.Write(data))
+		}
+		checksum, err := w.Close()
+		require.NoError(t, err)
+		require.NoError(t, file.Close())
+
+		manifest.Collections = append(manifest.Collections, CollectionInfo{
+			Name:      name,
+			File:      collectionFile(name),
+			Documents: w.count,
+			SHA256:    checksum,
+		})
+		paths[name] = localPath
+	}
+
+	archivePath := filepath.Join(dir, "snapshot.tar.gz")
+	require.NoError(t, writeArchive(archivePath, manifest, paths))
+
+	t.Run("verify", func(t *testing.T) {
+		actual, err := Verify(archivePath)
+		require.NoError(t, err)
+		assert.Equal(t, manifest, actual)
+	})
+	t.Run("read documents", func(t *testing.T) {
+		ar, err := openArchive(archivePath)
+		require.NoError(t, err)
+		defer ar.Close()
+
+		info, r, err := ar.next()
+		require.NoError(t, err)
+		assert.Equal(t, "first", info.Name)
+
+		var ids []string
+		err = readDocuments(r, func(doc bson.Raw) error {
+			ids = append(ids, doc.Lookup("_id").StringValue())
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a", "b"}, ids)
+	})
+	t.Run("checksum mismatch", func(t *testing.T) {
+		corrupted := *manifest
+		corrupted.Collections = append([]CollectionInfo(nil), manifest.Collections...)
+		corrupted.Collections[0].SHA256 = corrupted.Collections[1].SHA256
+
+		corruptedPath := filepath.Join(dir, "corrupted.tar.gz")
+		require.NoError(t, writeArchive(corruptedPath, &corrupted, paths))
+
+		_, err := Verify(corruptedPath)
+		assert.ErrorContains(t, err, "checksum mismatch")
+	})
+	t.Run("missing collection", func(t *testing.T) {
+		incomplete := *manifest
+		incomplete.Collections = append([]CollectionInfo(nil), manifest.Collections...)
+		incomplete.Collections = append(incomplete.Collections, CollectionInfo{
+			Name: "third",
+			File: collectionFile("third"),
+		})
+		incompletePath := filepath.Join(dir, "incomplete.tar.gz")
+		paths["third"] = filepath.Join(dir, "missing.bson")
+
+		err := writeArchive(incompletePath, &incomplete, paths)
+		require.Error(t, err)
+		_, err = os.Stat(incompletePath)
+		assert.True(t, os.IsNotExist(err))
+	})
+}
@@ -0,0 +1,206 @@
+package snapshot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// number of documents inserted with a single request during restore
+const insertBatchSize = 1000
+
+// Create writes all indexer collections into checksummed tar.gz archive at dest.
+// All collections are read within a single snapshot session, so the archive
+// is consistent with the last processed height stored in it.
+func Create(ctx context.Context, database *mongo.Database, dest string) (*Manifest, error) {
+	tmpDir, err := os.MkdirTemp("", "indexer-snapshot-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	session, err := database.Client().StartSession(options.Session().SetSnapshot(true))
+	if err != nil {
+		return nil, fmt.Errorf("failed to start snapshot session: %w", err)
+	}
+	defer session.EndSession(ctx)
+
+	manifest := &Manifest{
+		FormatVersion: FormatVersion,
+		CreatedAt:     time.Now().UTC().Truncate(time.Second),
+	}
+	paths := make(map[string]string)
+
+	err = mongo.WithSession(ctx, session, func(sc mongo.SessionContext) error {
+		var networkInfo model.NetworkInfo
+		err := database.Collection(model.NetworkInfoCollection).FindOne(sc, bson.M{}).Decode(&networkInfo)
+		if err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				return errors.New("network info not found, indexer has never been started on this database")
+			}
+			return fmt.Errorf("failed to read network info: %w", err)
+		}
+		manifest.ChainID = networkInfo.ChainID
+
+		var lastHeight model.LastProcessedHeight
+		err = database.Collection(model.LastProcessedHeightCollection).FindOne(sc, bson.M{}).Decode(&lastHeight)
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			return fmt.Errorf("failed to read last processed height: %w", err)
+		}
+		manifest.LastProcessedHeight = lastHeight.Height
+
+		for _, name := range model.CollectionNames() {
+			localPath := filepath.Join(tmpDir, name+".bson")
+			info, err := dumpCollection(sc, database.Collection(name), localPath)
+			if err != nil {
+				return fmt.Errorf("failed to dump %s: %w", name, err)
+			}
+
+			manifest.Collections = append(manifest.Collections, info)
+			paths[name] = localPath
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := writeArchive(dest, manifest, paths); err != nil {
+		return nil, err
+	}
+
+	log.Ctx(ctx).Info().
+		Str("path", dest).
+		Str("chain_id", manifest.ChainID).
+		Uint64("last_processed_height", manifest.LastProcessedHeight).
+		Msg("snapshot created")
+	return manifest, nil
+}
+
+func dumpCollection(ctx context.Context, collection *mongo.Collection, localPath string) (info CollectionInfo, err error) {
+	file, err := os.Create(localPath)
+	if err != nil {
+		return CollectionInfo{}, err
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	cursor, err := collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return CollectionInfo{}, err
+	}
+	defer cursor.Close(ctx)
+
+	w := newDumpWriter(file)
+	for cursor.Next(ctx) {
+		if err := w.Write(cursor.Current); err != nil {
+			return CollectionInfo{}, err
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return CollectionInfo{}, err
+	}
+
+	checksum, err := w.Close()
+	if err != nil {
+		return CollectionInfo{}, err
+	}
+
+	return CollectionInfo{
+		Name:      collection.Name(),
+		File:      collectionFile(collection.Name()),
+		Documents: w.count,
+		SHA256:    checksum,
+	}, nil
+}
+
+// Restore loads archive created by Create into empty database.
+// The archive is verified before any data is written and its chain ID must be equal to the expected one
+// (chain ID of the BBN node indexer is going to be connected to).
+func Restore(ctx context.Context, database *mongo.Database, src, expectedChainID string) (*Manifest, error) {
+	manifest, err := Verify(src)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot verification failed: %w", err)
+	}
+
+	if manifest.ChainID != expectedChainID {
+		return nil, fmt.Errorf("snapshot chain ID %q is different from BBN node chain ID %q", manifest.ChainID, expectedChainID)
+	}
+
+	for _, name := range model.CollectionNames() {
+		count, err := database.Collection(name).EstimatedDocumentCount(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if count != 0 {
+			return nil, fmt.Errorf("database is not empty: collection %s has %d documents", name, count)
+		}
+	}
+
+	ar, err := openArchive(src)
+	if err != nil {
+		return nil, err
+	}
+	defer ar.Close()
+
+	log := log.Ctx(ctx)
+	for {
+		info, r, err := ar.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if err := restoreCollection(ctx, database.Collection(info.Name), r); err != nil {
+			return nil, fmt.Errorf("failed to restore %s: %w", info.Name, err)
+		}
+		log.Info().Str("collection", info.Name).Int64("documents", info.Documents).Msg("collection restored")
+	}
+
+	log.Info().
+		Str("chain_id", manifest.ChainID).
+		Uint64("last_processed_height", manifest.LastProcessedHeight).
+		Msg("snapshot restored")
+	return manifest, nil
+}
+
+func restoreCollection(ctx context.Context, collection *mongo.Collection, r io.Reader) error {
+	batch := make([]any, 0, insertBatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		_, err := collection.InsertMany(ctx, batch)
+		batch = batch[:0]
+		return err
+	}
+
+	err := readDocuments(r, func(doc bson.Raw) error {
+		batch = append(batch, doc)
+		if len(batch) == insertBatchSize {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return flush()
+}
@@ -0,0 +1,118 @@
+package types
+
+// EventMessage is a BBN event decoded into the indexer struct of its type. Events of every
+// schema version are decoded into the same struct, fields the schema doesn't have are left empty.
+// Attributes of the events are mapped to the struct fields by their json tags.
+type EventMessage interface {
+	EventType() EventType
+}
+
+type FinalityProviderCreatedEvent struct {
+	BtcPkHex        string `json:"btc_pk_hex"`
+	Addr            string `json:"addr"`
+	Commission      string `json:"commission"`
+	Moniker         string `json:"moniker"`
+	Identity        string `json:"identity"`
+	Website         string `json:"website"`
+	SecurityContact string `json:"security_contact"`
+	Details         string `json:"details"`
+}
+
+func (*FinalityProviderCreatedEvent) EventType() EventType {
+	return EventFinalityProviderCreatedType
+}
+
+type FinalityProviderEditedEvent struct {
+	BtcPkHex        string `json:"btc_pk_hex"`
+	Commission      string `json:"commission"`
+	Moniker         string `json:"moniker"`
+	Identity        string `json:"identity"`
+	Website         string `json:"website"`
+	SecurityContact string `json:"security_contact"`
+	Details         string `json:"details"`
+}
+
+func (*FinalityProviderEditedEvent) EventType() EventType {
+	return EventFinalityProviderEditedType
+}
+
+type FinalityProviderStatusChangeEvent struct {
+	BtcPk    string `json:"btc_pk"`
+	NewState string `json:"new_state"`
+}
+
+func (*FinalityProviderStatusChangeEvent) EventType() EventType {
+	return EventFinalityProviderStatusChange
+}
+
+type BTCDelegationCreatedEvent struct {
+	StakingTxHex              string   `json:"staking_tx_hex"`
+	StakingOutputIndex        string   `json:"staking_output_index"`
+	ParamsVersion             string   `json:"params_version"`
+	FinalityProviderBtcPksHex []string `json:"finality_provider_btc_pks_hex"`
+	StakerBtcPkHex            string   `json:"staker_btc_pk_hex"`
+	StakingTime               string   `json:"staking_time"`
+	UnbondingTime             string   `json:"unbonding_time"`
+	UnbondingTx               string   `json:"unbonding_tx"`
+	NewState                  string   `json:"new_state"`
+	StakerAddr                string   `json:"staker_addr"`
+	// PreviousStakingTxHashHex is set if the delegation expands the previous one, since EventSchemaV2
+	PreviousStakingTxHashHex string `json:"previous_staking_tx_hash_hex"`
+}
+
+func (*BTCDelegationCreatedEvent) EventType() EventType {
+	return EventBTCDelegationCreated
+}
+
+type CovenantSignatureReceivedEvent struct {
+	StakingTxHash                 string `json:"staking_tx_hash"`
+	CovenantBtcPkHex              string `json:"covenant_btc_pk_hex"`
+	CovenantUnbondingSignatureHex string `json:"covenant_unbonding_signature_hex"`
+	// CovenantStakeExpansionSignatureHex is set for stake expansions, since EventSchemaV2
+	CovenantStakeExpansionSignatureHex string `json:"covenant_stake_expansion_signature_hex"`
+}
+
+func (*CovenantSignatureReceivedEvent) EventType() EventType {
+	return EventCovenantSignatureReceived
+}
+
+type CovenantQuorumReachedEvent struct {
+	StakingTxHash string `json:"staking_tx_hash"`
+	NewState      string `json:"new_state"`
+}
+
+func (*CovenantQuorumReachedEvent) EventType() EventType {
+	return EventCovenantQuorumReached
+}
+
+type BTCDelegationInclusionProofReceivedEvent struct {
+	StakingTxHash string `json:"staking_tx_hash"`
+	StartHeight   string `json:"start_height"`
+	EndHeight     string `json:"end_height"`
+	NewState      string `json:"new_state"`
+}
+
+func (*BTCDelegationInclusionProofReceivedEvent) EventType() EventType {
+	return EventBTCDelegationInclusionProofReceived
+}
+
+type BTCDelegationUnbondedEarlyEvent struct {
+	StakingTxHash string `json:"staking_tx_hash"`
+	StartHeight   string `json:"start_height"`
+	NewState      string `json:"new_state"`
+	// StakeExpansionTxHash is set if the delegation was unbonded by its expansion, since EventSchemaV2
+	StakeExpansionTxHash string `json:"stake_expansion_tx_hash"`
+}
+
+func (*BTCDelegationUnbondedEarlyEvent) EventType() EventType {
+	return EventBTCDelegationUnbondedEarly
+}
+
+type BTCDelegationExpiredEvent struct {
+	StakingTxHash string `json:"staking_tx_hash"`
+	NewState      string `json:"new_state"`
+}
+
+func (*BTCDelegationExpiredEvent) EventType() EventType {
+	return EventBTCDelegationExpired
+}
@@ -16,6 +16,9 @@ const (
 	InternalServiceError ErrorCode = "INTERNAL_SERVICE_ERROR"
 	BadRequest           ErrorCode = "BAD_REQUEST"
 	RequestTimeout       ErrorCode = "REQUEST_TIMEOUT"
+	// 4XX
+	NotFound               ErrorCode = "NOT_FOUND"
+	InvalidDelegationState ErrorCode = "INVALID_DELEGATION_STATE"
 )
 
 // ApiError represents an error with an HTTP status code and an application-specific error code.
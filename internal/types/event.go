@@ -25,6 +25,30 @@ const (
 	EventFinalityProviderStatusChange EventType = "babylon.btcstaking.v1.EventFinalityProviderStatusChange"
 )
 
+// EventSchemaVersion identifies the schema of Babylon events. Versions are numbered by the indexer
+// as event fields changed across Babylon upgrades, heights they apply from are configured.
+type EventSchemaVersion string
+
+const (
+	// EventSchemaV1 is the schema the chain started with, events don't have stake expansion fields
+	EventSchemaV1 EventSchemaVersion = "v1"
+	// EventSchemaV2 adds stake expansion fields, it's the schema of the Babylon version the indexer is built with
+	EventSchemaV2 EventSchemaVersion = "v2"
+
+	LatestEventSchema = EventSchemaV2
+)
+
+// EventSchemaVersions lists the schema versions from the oldest one
+var EventSchemaVersions = []EventSchemaVersion{EventSchemaV1, EventSchemaV2}
+
+// BootstrapEventType is stored as event type of state history records created when
+// indexer state is bootstrapped from chain queries at a specific BBN height
+const BootstrapEventType = "Bootstrap"
+
+// MigrationEventType is stored as event type of history records a migration created
+// from the documents indexed before the history was kept
+const MigrationEventType = "Migration"
+
 // ShortName returns the event name without the "babylon.btcstaking.v1." prefix
 // e.g., "babylon.btcstaking.v1.EventBTCDelegationCreated" -> "EventBTCDelegationCreated"
 func (e EventType) ShortName() string {
@@ -1,11 +1,5 @@
 package types
 
-import (
-	"fmt"
-
-	bbntypes "github.com/babylonlabs-io/babylon/v4/x/btcstaking/types"
-)
-
 // Enum values for Delegation State
 type DelegationState string
 
@@ -19,82 +13,15 @@ const (
 	// these are terminal states - there is no more state transition once a delegation reached one of them
 	StateWithdrawn DelegationState = "WITHDRAWN"
 	StateExpanded  DelegationState = "EXPANDED"
+	// StateUnknownSpend is the staking or unbonding output spent by a tx the indexer can't
+	// classify, details are kept in delegation's unknown_spend field and anomalies collection
+	StateUnknownSpend DelegationState = "UNKNOWN_SPEND"
 )
 
 func (s DelegationState) String() string {
 	return string(s)
 }
 
-// QualifiedStatesForCovenantQuorumReached returns the qualified current states for CovenantQuorumReached event
-func QualifiedStatesForCovenantQuorumReached(babylonState string) []DelegationState {
-	switch babylonState {
-	case bbntypes.BTCDelegationStatus_VERIFIED.String(), bbntypes.BTCDelegationStatus_ACTIVE.String():
-		return []DelegationState{StatePending}
-	default:
-		return nil
-	}
-}
-
-// QualifiedStatesForInclusionProofReceived returns the qualified current states for InclusionProofReceived event
-func QualifiedStatesForInclusionProofReceived(babylonState string) []DelegationState {
-	switch babylonState {
-	case bbntypes.BTCDelegationStatus_ACTIVE.String():
-		return []DelegationState{StateVerified}
-	case bbntypes.BTCDelegationStatus_PENDING.String():
-		return []DelegationState{StatePending}
-	default:
-		return nil
-	}
-}
-
-// QualifiedStatesForUnbondedEarly returns the qualified current states for UnbondedEarly event
-func QualifiedStatesForUnbondedEarly() []DelegationState {
-	return []DelegationState{StateActive, StateUnbonding}
-}
-
-// QualifiedStatesForExpired returns the qualified current states for Expired event
-func QualifiedStatesForExpired() []DelegationState {
-	return []DelegationState{StateActive}
-}
-
-// QualifiedStatesForWithdrawn returns the qualified current states for Withdrawn event
-func QualifiedStatesForWithdrawn() []DelegationState {
-	// StateActive/StateUnbonding/StateSlashed is included b/c its possible that expiry checker
-	// or babylon notifications are slow and in meanwhile the btc subscription encounters
-	// the spending/withdrawal tx
-	return []DelegationState{StateActive, StateUnbonding, StateWithdrawable, StateSlashed}
-}
-
-// QualifiedStatesForWithdrawable returns the qualified states that can transition to Withdrawable
-// based on the delegation's sub-state.
-func QualifiedStatesForWithdrawable(subState DelegationSubState) ([]DelegationState, error) {
-	switch subState {
-	case SubStateEarlyUnbonding, SubStateTimelock:
-		// For normal unbonding flows (early unbonding or timelock expiry),
-		// we expect the delegation to be in the Unbonding state.
-		// State transition: Active -> Unbonding -> Withdrawable
-		return []DelegationState{StateUnbonding}, nil
-
-	case SubStateTimelockSlashing, SubStateEarlyUnbondingSlashing:
-		// For slashing flows, we expect the delegation to be in the Slashed state.
-		// This handles multiple scenarios:
-		// 1. Active -> Slashed -> Withdrawable
-		// 2. Active -> Unbonding -> Slashed -> Withdrawable
-		// 3. Active -> Unbonding -> Withdrawable -> Slashed -> Withdrawable
-		//    (SubState transitions from Timelock -> TimelockSlashing or
-		//     EarlyUnbonding -> EarlyUnbondingSlashing)
-		return []DelegationState{StateSlashed}, nil
-
-	default:
-		return nil, fmt.Errorf("unknown delegation sub state: %s", subState)
-	}
-}
-
-// QualifiedStatesForSlashed returns the qualified current states for Slashed transition
-func QualifiedStatesForSlashed() []DelegationState {
-	return []DelegationState{StateActive, StateUnbonding, StateWithdrawable}
-}
-
 type DelegationSubState string
 
 const (
@@ -0,0 +1,177 @@
+package types
+
+import (
+	"fmt"
+	"slices"
+)
+
+//go:generate go run ../../cmd/state-docs -out ../../docs/states/transitions.md
+
+// TriggerKind is the source of the state transition
+type TriggerKind string
+
+const (
+	TriggerKindBbnEvent TriggerKind = "BBN_EVENT"
+	TriggerKindBtcSpend TriggerKind = "BTC_SPEND"
+	TriggerKindExpiry   TriggerKind = "EXPIRY"
+)
+
+// Trigger is what causes the delegation state transition
+type Trigger string
+
+const (
+	// BBN events
+	TriggerCovenantQuorumReached  Trigger = "COVENANT_QUORUM_REACHED"
+	TriggerInclusionProofReceived Trigger = "INCLUSION_PROOF_RECEIVED"
+	TriggerUnbondedEarly          Trigger = "UNBONDED_EARLY"
+	TriggerExpired                Trigger = "EXPIRED"
+
+	// BTC spends
+	// TriggerUnbondingSpend is the staking output spent through unbonding path
+	TriggerUnbondingSpend Trigger = "UNBONDING_SPEND"
+	// TriggerWithdrawalSpend is the staking or unbonding output spent through timelock path
+	TriggerWithdrawalSpend Trigger = "WITHDRAWAL_SPEND"
+	// TriggerSlashingSpend is the staking or unbonding output spent through slashing path
+	TriggerSlashingSpend Trigger = "SLASHING_SPEND"
+	// TriggerSlashingChangeSpend is the change output of the slashing tx spent after its timelock
+	TriggerSlashingChangeSpend Trigger = "SLASHING_CHANGE_SPEND"
+	// TriggerUnknownSpend is the staking or unbonding output spent by unrecognized tx
+	TriggerUnknownSpend Trigger = "UNKNOWN_SPEND"
+
+	// TriggerTimelockExpiry is the timelock expiration detected by expiry checker
+	TriggerTimelockExpiry Trigger = "TIMELOCK_EXPIRY"
+)
+
+func (t Trigger) String() string {
+	return string(t)
+}
+
+func (t Trigger) Kind() TriggerKind {
+	switch t {
+	case TriggerCovenantQuorumReached, TriggerInclusionProofReceived, TriggerUnbondedEarly, TriggerExpired:
+		return TriggerKindBbnEvent
+	case TriggerTimelockExpiry:
+		return TriggerKindExpiry
+	default:
+		return TriggerKindBtcSpend
+	}
+}
+
+// Guard is an additional condition on the delegation the transition requires besides its current state
+type Guard string
+
+const (
+	GuardNone              Guard = ""
+	GuardNoInclusionProof  Guard = "NO_INCLUSION_PROOF"
+	GuardHasInclusionProof Guard = "HAS_INCLUSION_PROOF"
+)
+
+func (g Guard) String() string {
+	return string(g)
+}
+
+func (g Guard) Description() string {
+	switch g {
+	case GuardNoInclusionProof:
+		return "inclusion proof not received yet"
+	case GuardHasInclusionProof:
+		return "inclusion proof received"
+	default:
+		return ""
+	}
+}
+
+// Transition moves delegation in one of From states to To state with SubState
+// once Trigger happens and Guard holds
+type Transition struct {
+	Trigger Trigger
+	From    []DelegationState
+	To      DelegationState
+	// SubState set by the transition, empty for states without sub state
+	SubState DelegationSubState
+	Guard    Guard
+}
+
+// Allows checks if delegation in the given state can take the transition
+func (t Transition) Allows(state DelegationState) bool {
+	return slices.Contains(t.From, state)
+}
+
+// statesBeforeWithdrawn are the states the staking, unbonding or slashing output can be withdrawn from.
+// StateActive/StateUnbonding/StateSlashed are included b/c its possible that expiry checker
+// or babylon notifications are slow and in meanwhile the btc subscription encounters
+// the spending/withdrawal tx
+var statesBeforeWithdrawn = []DelegationState{StateActive, StateUnbonding, StateWithdrawable, StateSlashed}
+
+// statesBeforeSlashed are the states with the staking or unbonding output not spent yet
+var statesBeforeSlashed = []DelegationState{StateActive, StateUnbonding, StateWithdrawable}
+
+// Transitions is the delegation state machine. Every state change except reverting
+// reorged BTC spends must match exactly one of the transitions.
+var Transitions = []Transition{
+	// Pre-approval flow: covenant signatures are received before the inclusion proof
+	{Trigger: TriggerCovenantQuorumReached, From: []DelegationState{StatePending}, To: StateVerified, Guard: GuardNoInclusionProof},
+	// Old flow: inclusion proof is received in MsgCreateBTCDelegation
+	{Trigger: TriggerCovenantQuorumReached, From: []DelegationState{StatePending}, To: StateActive, Guard: GuardHasInclusionProof},
+	{Trigger: TriggerInclusionProofReceived, From: []DelegationState{StateVerified}, To: StateActive, Guard: GuardNoInclusionProof},
+	// Old flow emits PENDING state, it only sets the inclusion proof
+	{Trigger: TriggerInclusionProofReceived, From: []DelegationState{StatePending}, To: StatePending, Guard: GuardNoInclusionProof},
+
+	// Unbonding tx can be discovered by BTC notifier before the event, so unbonding is qualified as well
+	{Trigger: TriggerUnbondedEarly, From: []DelegationState{StateActive, StateUnbonding}, To: StateUnbonding, SubState: SubStateEarlyUnbonding},
+	{Trigger: TriggerUnbondedEarly, From: []DelegationState{StateActive, StateUnbonding}, To: StateExpanded, SubState: SubStateEarlyUnbonding},
+	{Trigger: TriggerUnbondingSpend, From: []DelegationState{StateActive, StateUnbonding}, To: StateUnbonding, SubState: SubStateEarlyUnbonding},
+	{Trigger: TriggerExpired, From: []DelegationState{StateActive}, To: StateUnbonding, SubState: SubStateTimelock},
+
+	// For normal unbonding flows (early unbonding or timelock expiry) the delegation is unbonding
+	{Trigger: TriggerTimelockExpiry, From: []DelegationState{StateUnbonding}, To: StateWithdrawable, SubState: SubStateTimelock},
+	{Trigger: TriggerTimelockExpiry, From: []DelegationState{StateUnbonding}, To: StateWithdrawable, SubState: SubStateEarlyUnbonding},
+	// For slashing flows the delegation is slashed, it can happen after it was unbonding or withdrawable
+	{Trigger: TriggerTimelockExpiry, From: []DelegationState{StateSlashed}, To: StateWithdrawable, SubState: SubStateTimelockSlashing},
+	{Trigger: TriggerTimelockExpiry, From: []DelegationState{StateSlashed}, To: StateWithdrawable, SubState: SubStateEarlyUnbondingSlashing},
+
+	{Trigger: TriggerSlashingSpend, From: statesBeforeSlashed, To: StateSlashed, SubState: SubStateTimelockSlashing},
+	{Trigger: TriggerSlashingSpend, From: statesBeforeSlashed, To: StateSlashed, SubState: SubStateEarlyUnbondingSlashing},
+
+	{Trigger: TriggerWithdrawalSpend, From: statesBeforeWithdrawn, To: StateWithdrawn, SubState: SubStateTimelock},
+	{Trigger: TriggerWithdrawalSpend, From: statesBeforeWithdrawn, To: StateWithdrawn, SubState: SubStateEarlyUnbonding},
+	{Trigger: TriggerSlashingChangeSpend, From: statesBeforeWithdrawn, To: StateWithdrawn, SubState: SubStateTimelockSlashing},
+	{Trigger: TriggerSlashingChangeSpend, From: statesBeforeWithdrawn, To: StateWithdrawn, SubState: SubStateEarlyUnbondingSlashing},
+	// anomalies
+	{Trigger: TriggerUnknownSpend, From: statesBeforeSlashed, To: StateUnknownSpend},
+}
+
+// FindTransition returns the transition caused by trigger that leads to the given state and sub state
+func FindTransition(trigger Trigger, to DelegationState, subState DelegationSubState) (Transition, error) {
+	for _, t := range Transitions {
+		if t.Trigger == trigger && t.To == to && t.SubState == subState {
+			return t, nil
+		}
+	}
+
+	if subState == "" {
+		return Transition{}, fmt.Errorf("no %s transition to %s state", trigger, to)
+	}
+	return Transition{}, fmt.Errorf("no %s transition to %s(%s) state", trigger, to, subState)
+}
+
+// ResolveTransition returns the only transition trigger takes the delegation in the given state through,
+// guardHolds tells if the guard holds for the delegation. It fails if there is no such transition or
+// the target state can't be decided without more information (e.g. unbonding vs expansion).
+func ResolveTransition(trigger Trigger, from DelegationState, guardHolds func(Guard) bool) (Transition, error) {
+	var matches []Transition
+	for _, t := range Transitions {
+		if t.Trigger == trigger && t.Allows(from) && guardHolds(t.Guard) {
+			matches = append(matches, t)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return Transition{}, fmt.Errorf("no %s transition from %s state", trigger, from)
+	case 1:
+		return matches[0], nil
+	default:
+		return Transition{}, fmt.Errorf("%d %s transitions from %s state", len(matches), trigger, from)
+	}
+}
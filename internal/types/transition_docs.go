@@ -0,0 +1,85 @@
+package types
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteTransitionDocs renders Transitions as markdown document with mermaid state diagram
+// and transition table. The output is stored in docs/states/transitions.md, run
+// `go generate ./internal/types` after changing the table.
+func WriteTransitionDocs(w io.Writer) error {
+	var b strings.Builder
+
+	b.WriteString("<!-- Code generated by cmd/state-docs from internal/types/transition.go. DO NOT EDIT. -->\n\n")
+	b.WriteString("# Delegation State Transitions\n\n")
+	b.WriteString("Every delegation state change is one of the transitions below. A transition is applied\n")
+	b.WriteString("only if the delegation is in one of its source states and the guard holds, otherwise\n")
+	b.WriteString("it's stored in `rejected_transitions` collection. Reverting transitions of reorged BTC\n")
+	b.WriteString("spends is not part of the table.\n\n")
+
+	b.WriteString("## Diagram\n\n")
+	b.WriteString("```mermaid\nstateDiagram-v2\n")
+	fmt.Fprintf(&b, "    [*] --> %s\n", StatePending)
+	for _, e := range transitionEdges() {
+		fmt.Fprintf(&b, "    %s --> %s: %s\n", e.from, e.to, strings.Join(e.labels, "<br/>"))
+	}
+	fmt.Fprintf(&b, "    %s --> [*]\n", StateWithdrawn)
+	fmt.Fprintf(&b, "    %s --> [*]\n", StateExpanded)
+	fmt.Fprintf(&b, "    %s --> [*]\n", StateUnknownSpend)
+	b.WriteString("```\n\n")
+
+	b.WriteString("## Transitions\n\n")
+	b.WriteString("| Trigger | Kind | From | To | Sub-State | Guard |\n")
+	b.WriteString("|---------|------|------|----|-----------|-------|\n")
+	for _, t := range Transitions {
+		from := make([]string, len(t.From))
+		for i, state := range t.From {
+			from[i] = state.String()
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %s |\n",
+			t.Trigger, t.Trigger.Kind(), strings.Join(from, ", "), t.To,
+			orDash(t.SubState.String()), orDash(t.Guard.Description()),
+		)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+type transitionEdge struct {
+	from, to DelegationState
+	labels   []string
+}
+
+// transitionEdges merges transitions between the same states into a single edge, edges
+// are ordered by the first transition they contain
+func transitionEdges() []*transitionEdge {
+	var edges []*transitionEdge
+	index := make(map[[2]DelegationState]*transitionEdge)
+	for _, t := range Transitions {
+		label := t.Trigger.String()
+		if t.SubState != "" {
+			label += fmt.Sprintf(" (%s)", t.SubState)
+		}
+		for _, from := range t.From {
+			key := [2]DelegationState{from, t.To}
+			e, ok := index[key]
+			if !ok {
+				e = &transitionEdge{from: from, to: t.To}
+				index[key] = e
+				edges = append(edges, e)
+			}
+			e.labels = append(e.labels, label)
+		}
+	}
+	return edges
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
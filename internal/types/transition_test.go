@@ -0,0 +1,88 @@
+package types
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransitions(t *testing.T) {
+	t.Run("transitions are unique", func(t *testing.T) {
+		type key struct {
+			trigger  Trigger
+			to       DelegationState
+			subState DelegationSubState
+		}
+		seen := make(map[key]bool)
+		for _, transition := range Transitions {
+			k := key{transition.Trigger, transition.To, transition.SubState}
+			assert.False(t, seen[k], "duplicate transition %v", k)
+			seen[k] = true
+			assert.NotEmpty(t, transition.From)
+		}
+	})
+	t.Run("terminal states have no outgoing transitions", func(t *testing.T) {
+		for _, transition := range Transitions {
+			assert.False(t, transition.Allows(StateWithdrawn), transition.Trigger)
+			assert.False(t, transition.Allows(StateExpanded), transition.Trigger)
+			assert.False(t, transition.Allows(StateUnknownSpend), transition.Trigger)
+		}
+	})
+	t.Run("find", func(t *testing.T) {
+		transition, err := FindTransition(TriggerTimelockExpiry, StateWithdrawable, SubStateEarlyUnbondingSlashing)
+		require.NoError(t, err)
+		assert.Equal(t, []DelegationState{StateSlashed}, transition.From)
+		assert.True(t, transition.Allows(StateSlashed))
+		assert.False(t, transition.Allows(StateUnbonding))
+
+		transition, err = FindTransition(TriggerCovenantQuorumReached, StateVerified, "")
+		require.NoError(t, err)
+		assert.Equal(t, GuardNoInclusionProof, transition.Guard)
+
+		_, err = FindTransition(TriggerTimelockExpiry, StateWithdrawable, "")
+		assert.ErrorContains(t, err, "no TIMELOCK_EXPIRY transition to WITHDRAWABLE state")
+		_, err = FindTransition(TriggerExpired, StateUnbonding, SubStateEarlyUnbonding)
+		assert.ErrorContains(t, err, "no EXPIRED transition to UNBONDING(EARLY_UNBONDING) state")
+	})
+	t.Run("resolve", func(t *testing.T) {
+		hasInclusionProof := func(guard Guard) bool { return guard != GuardNoInclusionProof }
+		noInclusionProof := func(guard Guard) bool { return guard != GuardHasInclusionProof }
+
+		transition, err := ResolveTransition(TriggerCovenantQuorumReached, StatePending, hasInclusionProof)
+		require.NoError(t, err)
+		assert.Equal(t, StateActive, transition.To)
+		transition, err = ResolveTransition(TriggerCovenantQuorumReached, StatePending, noInclusionProof)
+		require.NoError(t, err)
+		assert.Equal(t, StateVerified, transition.To)
+
+		transition, err = ResolveTransition(TriggerInclusionProofReceived, StateVerified, noInclusionProof)
+		require.NoError(t, err)
+		assert.Equal(t, StateActive, transition.To)
+		transition, err = ResolveTransition(TriggerInclusionProofReceived, StatePending, noInclusionProof)
+		require.NoError(t, err)
+		assert.Equal(t, StatePending, transition.To)
+
+		_, err = ResolveTransition(TriggerInclusionProofReceived, StateActive, hasInclusionProof)
+		assert.ErrorContains(t, err, "no INCLUSION_PROOF_RECEIVED transition from ACTIVE state")
+		// unbonding and expansion can't be told apart by the state
+		_, err = ResolveTransition(TriggerUnbondedEarly, StateActive, hasInclusionProof)
+		assert.ErrorContains(t, err, "2 UNBONDED_EARLY transitions from ACTIVE state")
+	})
+	t.Run("trigger kind", func(t *testing.T) {
+		assert.Equal(t, TriggerKindBbnEvent, TriggerUnbondedEarly.Kind())
+		assert.Equal(t, TriggerKindBtcSpend, TriggerUnbondingSpend.Kind())
+		assert.Equal(t, TriggerKindExpiry, TriggerTimelockExpiry.Kind())
+	})
+}
+
+func TestTransitionDocsUpToDate(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteTransitionDocs(&buf))
+
+	committed, err := os.ReadFile("../../docs/states/transitions.md")
+	require.NoError(t, err)
+	assert.Equal(t, string(committed), buf.String(), "run `go generate ./internal/types` to update the docs")
+}
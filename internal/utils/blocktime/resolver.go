@@ -0,0 +1,76 @@
+package blocktime
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/bbnclient"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/btcclient"
+	lru "github.com/hashicorp/golang-lru/v2"
+)
+
+// Resolver returns timestamps of BBN and BTC blocks through the nodes and caches them.
+// BBN blocks are final, so they are cached by height. BTC blocks at the same height can
+// be replaced by reorg, so they are cached by hash, which is resolved on every lookup.
+type Resolver struct {
+	bbn bbnclient.BbnInterface
+	btc btcclient.BtcInterface
+	// height -> timestamp
+	bbnTimes *lru.Cache[int64, int64]
+	// block hash -> timestamp
+	btcTimes *lru.Cache[string, int64]
+}
+
+// NewResolver creates resolver keeping up to cacheSize timestamps of each chain
+func NewResolver(bbn bbnclient.BbnInterface, btc btcclient.BtcInterface, cacheSize int) *Resolver {
+	// New only fails on non-positive size
+	bbnTimes, _ := lru.New[int64, int64](max(cacheSize, 1))
+	btcTimes, _ := lru.New[string, int64](max(cacheSize, 1))
+	return &Resolver{bbn: bbn, btc: btc, bbnTimes: bbnTimes, btcTimes: btcTimes}
+}
+
+// BbnTimestamp returns unix timestamp of the BBN block at the given height
+func (r *Resolver) BbnTimestamp(ctx context.Context, height int64) (int64, error) {
+	if timestamp, ok := r.bbnTimes.Get(height); ok {
+		return timestamp, nil
+	}
+
+	block, err := r.bbn.GetBlock(ctx, &height)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get bbn block %d: %w", height, err)
+	}
+	timestamp := block.Block.Time.Unix()
+	r.bbnTimes.Add(height, timestamp)
+
+	return timestamp, nil
+}
+
+// AddBbnTimestamp caches timestamp of the BBN block fetched by the caller
+func (r *Resolver) AddBbnTimestamp(height, timestamp int64) {
+	r.bbnTimes.Add(height, timestamp)
+}
+
+// BtcBlock returns hash and unix timestamp of the BTC block at the given height
+func (r *Resolver) BtcBlock(ctx context.Context, height uint32) (string, int64, error) {
+	hash, err := r.btc.GetBlockHash(ctx, height)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to get btc block hash at height %d: %w", height, err)
+	}
+	if timestamp, ok := r.btcTimes.Get(hash); ok {
+		return hash, timestamp, nil
+	}
+
+	timestamp, err := r.btc.GetBlockTimestampByHash(ctx, hash)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to get btc block %s timestamp: %w", hash, err)
+	}
+	r.btcTimes.Add(hash, timestamp)
+
+	return hash, timestamp, nil
+}
+
+// BtcTimestamp returns unix timestamp of the BTC block at the given height
+func (r *Resolver) BtcTimestamp(ctx context.Context, height uint32) (int64, error) {
+	_, timestamp, err := r.BtcBlock(ctx, height)
+	return timestamp, err
+}
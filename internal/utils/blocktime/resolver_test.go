@@ -0,0 +1,70 @@
+package blocktime
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/mocks"
+	ctypes "github.com/cometbft/cometbft/rpc/core/types"
+	cmttypes "github.com/cometbft/cometbft/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolver(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("bbn", func(t *testing.T) {
+		bbn := mocks.NewBbnInterface(t)
+		r := NewResolver(bbn, nil, 10)
+
+		height := int64(10)
+		blockTime := time.Unix(1700000000, 0)
+		bbn.On("GetBlock", ctx, &height).Return(&ctypes.ResultBlock{
+			Block: &cmttypes.Block{Header: cmttypes.Header{Time: blockTime}},
+		}, nil).Once()
+
+		for range 2 {
+			timestamp, err := r.BbnTimestamp(ctx, height)
+			require.NoError(t, err)
+			assert.Equal(t, blockTime.Unix(), timestamp)
+		}
+	})
+	t.Run("btc", func(t *testing.T) {
+		btc := mocks.NewBtcInterface(t)
+		r := NewResolver(nil, btc, 10)
+
+		btc.On("GetBlockHash", ctx, uint32(100)).Return("hash1", nil).Times(3)
+		btc.On("GetBlockTimestampByHash", ctx, "hash1").Return(int64(0), errors.New("rpc error")).Once()
+		_, err := r.BtcTimestamp(ctx, 100)
+		require.Error(t, err)
+
+		// failed lookup is not cached
+		btc.On("GetBlockTimestampByHash", ctx, "hash1").Return(int64(1000), nil).Once()
+		for range 2 {
+			hash, timestamp, err := r.BtcBlock(ctx, 100)
+			require.NoError(t, err)
+			assert.Equal(t, "hash1", hash)
+			assert.Equal(t, int64(1000), timestamp)
+		}
+	})
+	t.Run("btc reorg", func(t *testing.T) {
+		btc := mocks.NewBtcInterface(t)
+		r := NewResolver(nil, btc, 10)
+
+		btc.On("GetBlockHash", ctx, uint32(100)).Return("hash1", nil).Once()
+		btc.On("GetBlockTimestampByHash", ctx, "hash1").Return(int64(1000), nil).Once()
+		timestamp, err := r.BtcTimestamp(ctx, 100)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1000), timestamp)
+
+		// the block at the height has been replaced, its timestamp is not taken from the cache
+		btc.On("GetBlockHash", ctx, uint32(100)).Return("hash2", nil).Once()
+		btc.On("GetBlockTimestampByHash", ctx, "hash2").Return(int64(1100), nil).Once()
+		timestamp, err = r.BtcTimestamp(ctx, 100)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1100), timestamp)
+	})
+}
@@ -0,0 +1,101 @@
+package executor
+
+import (
+	"context"
+	"sync"
+)
+
+// KeyedExecutor serializes tasks with the same key in the order they were submitted,
+// tasks with different keys run in parallel. Tasks run in goroutines of their callers,
+// so a task must not submit another task with the same key and wait for it.
+type KeyedExecutor struct {
+	mu     sync.Mutex
+	queues map[string]*keyQueue
+}
+
+// keyQueue is FIFO of tasks with the same key, each task waits for the previous one to be done
+type keyQueue struct {
+	// tail is closed once the last submitted task is done
+	tail chan struct{}
+	// size is the number of submitted tasks that aren't done yet, including the running one
+	size int
+}
+
+func NewKeyedExecutor() *KeyedExecutor {
+	return &KeyedExecutor{
+		queues: make(map[string]*keyQueue),
+	}
+}
+
+// Do runs fn once all tasks submitted earlier with the same key are done and returns its error.
+// If ctx is cancelled before fn starts, fn is skipped and the context error is returned.
+func (e *KeyedExecutor) Do(ctx context.Context, key string, fn func() error) error {
+	prev, done := e.enqueue(key)
+
+	if prev != nil {
+		select {
+		case <-prev:
+		case <-ctx.Done():
+			// tasks submitted later still have to wait for the previous one
+			go func() {
+				<-prev
+				e.release(key, done)
+			}()
+			return ctx.Err()
+		}
+	}
+	defer e.release(key, done)
+
+	return fn()
+}
+
+// enqueue appends the task to the queue of the key. It returns channel closed once
+// the previous task is done (nil if there is none) and channel to close once the task is done
+func (e *KeyedExecutor) enqueue(key string) (<-chan struct{}, chan struct{}) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	q, ok := e.queues[key]
+	if !ok {
+		q = &keyQueue{}
+		e.queues[key] = q
+	}
+
+	prev := q.tail
+	done := make(chan struct{})
+	q.tail = done
+	q.size++
+
+	return prev, done
+}
+
+func (e *KeyedExecutor) release(key string, done chan struct{}) {
+	e.mu.Lock()
+	q := e.queues[key]
+	q.size--
+	if q.size == 0 {
+		delete(e.queues, key)
+	}
+	e.mu.Unlock()
+
+	close(done)
+}
+
+// QueueDepth returns the number of tasks with the key that are running or waiting to run
+func (e *KeyedExecutor) QueueDepth(key string) int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if q, ok := e.queues[key]; ok {
+		return q.size
+	}
+	return 0
+}
+
+// ActiveKeys returns the number of keys with running or waiting tasks
+func (e *KeyedExecutor) ActiveKeys() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return len(e.queues)
+}
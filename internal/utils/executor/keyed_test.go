@@ -0,0 +1,156 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// recorder keeps the order tasks were run in
+type recorder struct {
+	mu    sync.Mutex
+	order []string
+}
+
+func (r *recorder) task(name string) func() error {
+	return func() error {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.order = append(r.order, name)
+		return nil
+	}
+}
+
+func (r *recorder) get() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.order...)
+}
+
+// blockingTask returns task that signals once it's started and waits for release
+func blockingTask(fn func() error) (task func() error, started, release chan struct{}) {
+	started = make(chan struct{})
+	release = make(chan struct{})
+	task = func() error {
+		close(started)
+		<-release
+		return fn()
+	}
+	return task, started, release
+}
+
+// submit runs Do in a new goroutine and waits until the task is queued,
+// so the order of submitted tasks is deterministic
+func submit(t *testing.T, ctx context.Context, e *KeyedExecutor, key string, fn func() error) <-chan error {
+	t.Helper()
+
+	depth := e.QueueDepth(key)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- e.Do(ctx, key, fn)
+	}()
+	require.Eventually(t, func() bool {
+		return e.QueueDepth(key) == depth+1
+	}, time.Second, time.Millisecond)
+
+	return errCh
+}
+
+func waitErr(t *testing.T, errCh <-chan error) error {
+	t.Helper()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-time.After(time.Second):
+		t.Fatal("task hasn't finished")
+		return nil
+	}
+}
+
+func TestKeyedExecutor(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("tasks with the same key run in submission order", func(t *testing.T) {
+		e := NewKeyedExecutor()
+		var r recorder
+
+		first, started, release := blockingTask(r.task("first"))
+		firstErr := submit(t, ctx, e, "key", first)
+		<-started
+		secondErr := submit(t, ctx, e, "key", r.task("second"))
+		thirdErr := submit(t, ctx, e, "key", r.task("third"))
+
+		require.Equal(t, 3, e.QueueDepth("key"))
+		require.Empty(t, r.get())
+
+		close(release)
+		require.NoError(t, waitErr(t, firstErr))
+		require.NoError(t, waitErr(t, secondErr))
+		require.NoError(t, waitErr(t, thirdErr))
+
+		require.Equal(t, []string{"first", "second", "third"}, r.get())
+		require.Equal(t, 0, e.QueueDepth("key"))
+		require.Equal(t, 0, e.ActiveKeys())
+	})
+	t.Run("tasks with different keys run in parallel", func(t *testing.T) {
+		e := NewKeyedExecutor()
+		var r recorder
+
+		first, started, release := blockingTask(r.task("first"))
+		firstErr := submit(t, ctx, e, "key1", first)
+		<-started
+
+		require.NoError(t, e.Do(ctx, "key2", r.task("second")))
+		require.Equal(t, []string{"second"}, r.get())
+		require.Equal(t, 1, e.ActiveKeys())
+
+		close(release)
+		require.NoError(t, waitErr(t, firstErr))
+		require.Equal(t, []string{"second", "first"}, r.get())
+	})
+	t.Run("task error is returned and doesn't block the queue", func(t *testing.T) {
+		e := NewKeyedExecutor()
+		var r recorder
+		taskErr := errors.New("task failed")
+
+		first, started, release := blockingTask(func() error { return taskErr })
+		firstErr := submit(t, ctx, e, "key", first)
+		<-started
+		secondErr := submit(t, ctx, e, "key", r.task("second"))
+
+		close(release)
+		require.ErrorIs(t, waitErr(t, firstErr), taskErr)
+		require.NoError(t, waitErr(t, secondErr))
+		require.Equal(t, []string{"second"}, r.get())
+	})
+	t.Run("cancelled task is skipped and keeps the order", func(t *testing.T) {
+		e := NewKeyedExecutor()
+		var r recorder
+
+		first, started, release := blockingTask(r.task("first"))
+		firstErr := submit(t, ctx, e, "key", first)
+		<-started
+		cancelCtx, cancel := context.WithCancel(ctx)
+		secondErr := submit(t, cancelCtx, e, "key", r.task("second"))
+		thirdErr := submit(t, ctx, e, "key", r.task("third"))
+
+		cancel()
+		require.ErrorIs(t, waitErr(t, secondErr), context.Canceled)
+		// the third task still waits for the first one
+		require.Equal(t, 3, e.QueueDepth("key"))
+		require.Empty(t, r.get())
+
+		close(release)
+		require.NoError(t, waitErr(t, firstErr))
+		require.NoError(t, waitErr(t, thirdErr))
+		require.Equal(t, []string{"first", "third"}, r.get())
+		require.Eventually(t, func() bool {
+			return e.ActiveKeys() == 0
+		}, time.Second, time.Millisecond)
+	})
+}
@@ -0,0 +1,11 @@
+// Package sdk reads data of the babylon staking indexer.
+//
+// It's a separate Go module released with pkg/sdk/vX.Y.Z tags, independently of
+// the indexer releases. Exported read models, the Reader interface and helpers
+// follow semantic versioning: within a major version fields and functions are only
+// added. The models don't mirror the indexer storage, changes of the storage are
+// absorbed by the readers.
+//
+// Data is read either from the indexer API with NewHTTPReader or directly from
+// the indexer database with mongoreader.New. Both readers return the same models.
+package sdk
@@ -0,0 +1,157 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultHTTPTimeout = 10 * time.Second
+	// error responses are short, longer bodies don't come from the indexer
+	maxErrorBodySize = 64 << 10
+)
+
+// APIError is error response of the indexer API
+type APIError struct {
+	StatusCode int    `json:"-"`
+	ErrorCode  string `json:"error_code"`
+	Message    string `json:"message"`
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("indexer api error %d %s: %s", e.StatusCode, e.ErrorCode, e.Message)
+}
+
+// Is matches ErrNotFound for not found responses
+func (e *APIError) Is(target error) bool {
+	return target == ErrNotFound && e.StatusCode == http.StatusNotFound
+}
+
+// DelegationsResponse is a page of delegations listed by the indexer
+type DelegationsResponse struct {
+	Delegations []*Delegation `json:"delegations"`
+	// NextPageToken requests the following page, it's empty on the last page
+	NextPageToken string `json:"next_page_token,omitempty"`
+}
+
+// FinalityProvidersResponse is a page of finality providers listed by the indexer
+type FinalityProvidersResponse struct {
+	FinalityProviders []*FinalityProvider `json:"finality_providers"`
+	// NextPageToken requests the following page, it's empty on the last page
+	NextPageToken string `json:"next_page_token,omitempty"`
+}
+
+// HTTPReader is Reader of the indexer API
+type HTTPReader struct {
+	baseURL string
+	client  *http.Client
+}
+
+var _ Reader = (*HTTPReader)(nil)
+
+// NewHTTPReader returns reader of the indexer API at the base URL, e.g. http://localhost:8080.
+// Requests are sent with the client or with a client with default timeout if it's nil.
+func NewHTTPReader(baseURL string, client *http.Client) (*HTTPReader, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid indexer api url: %w", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid indexer api url %q: expected http(s)://host[:port]", baseURL)
+	}
+	if client == nil {
+		client = &http.Client{Timeout: defaultHTTPTimeout}
+	}
+
+	return &HTTPReader{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  client,
+	}, nil
+}
+
+func (r *HTTPReader) Delegation(ctx context.Context, stakingTxHashHex string) (*Delegation, error) {
+	var delegation Delegation
+	if err := r.get(ctx, "/v1/delegations/"+url.PathEscape(stakingTxHashHex), &delegation); err != nil {
+		return nil, err
+	}
+	return &delegation, nil
+}
+
+func (r *HTTPReader) DelegationsByFinalityProvider(
+	ctx context.Context, fpBtcPkHex string, page PageRequest,
+) (*DelegationsResponse, error) {
+	var resp DelegationsResponse
+	path := "/v1/finality-providers/" + url.PathEscape(fpBtcPkHex) + "/delegations" + pageQuery(page)
+	if err := r.get(ctx, path, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (r *HTTPReader) FinalityProvider(ctx context.Context, btcPkHex string) (*FinalityProvider, error) {
+	var fp FinalityProvider
+	if err := r.get(ctx, "/v1/finality-providers/"+url.PathEscape(btcPkHex), &fp); err != nil {
+		return nil, err
+	}
+	return &fp, nil
+}
+
+func (r *HTTPReader) FinalityProviders(ctx context.Context, page PageRequest) (*FinalityProvidersResponse, error) {
+	var resp FinalityProvidersResponse
+	if err := r.get(ctx, "/v1/finality-providers"+pageQuery(page), &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// pageQuery returns query string of the page request, the zero request has none
+func pageQuery(page PageRequest) string {
+	query := url.Values{}
+	if page.Limit != 0 {
+		query.Set("limit", strconv.Itoa(page.Limit))
+	}
+	if page.Token != "" {
+		query.Set("page_token", page.Token)
+	}
+	if len(query) == 0 {
+		return ""
+	}
+	return "?" + query.Encode()
+}
+
+// get decodes JSON response of the path into result, error responses are returned as *APIError
+func (r *HTTPReader) get(ctx context.Context, path string, result any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.baseURL+path, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		apiErr := &APIError{StatusCode: resp.StatusCode}
+		body, readErr := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		// the body isn't JSON if the error doesn't come from the indexer, e.g. from a proxy
+		if readErr != nil || json.Unmarshal(body, apiErr) != nil {
+			apiErr.Message = strings.TrimSpace(string(body))
+		}
+		return apiErr
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPReader(t *testing.T) {
+	ctx := context.Background()
+
+	delegation := &Delegation{
+		StakingTxHashHex:          "staking_tx",
+		StakingAmount:             1000,
+		FinalityProviderBtcPksHex: []string{"fp"},
+		State:                     StateActive,
+		StateHistory:              []StateRecord{{State: StateActive, BtcHeight: 100}},
+	}
+	fp := &FinalityProvider{
+		BtcPkHex:    "fp",
+		State:       FinalityProviderStateActive,
+		Description: Description{Moniker: "moniker"},
+	}
+
+	mux := http.NewServeMux()
+	writeJSON := func(w http.ResponseWriter, statusCode int, body any) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(statusCode)
+		require.NoError(t, json.NewEncoder(w).Encode(body))
+	}
+	mux.HandleFunc("GET /v1/delegations/{hash}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("hash") != delegation.StakingTxHashHex {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error_code": "NOT_FOUND", "message": "not found"})
+			return
+		}
+		writeJSON(w, http.StatusOK, delegation)
+	})
+	mux.HandleFunc("GET /v1/finality-providers", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "", r.URL.RawQuery)
+		writeJSON(w, http.StatusOK, FinalityProvidersResponse{FinalityProviders: []*FinalityProvider{fp}})
+	})
+	mux.HandleFunc("GET /v1/finality-providers/{pk}", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, http.StatusOK, fp)
+	})
+	mux.HandleFunc("GET /v1/finality-providers/{pk}/delegations", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "10", r.URL.Query().Get("limit"))
+		assert.Equal(t, "token+/", r.URL.Query().Get("page_token"))
+		writeJSON(w, http.StatusOK, DelegationsResponse{Delegations: []*Delegation{delegation}, NextPageToken: "next"})
+	})
+	mux.HandleFunc("GET /broken/v1/delegations/{hash}", func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "bad gateway", http.StatusBadGateway)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	reader, err := NewHTTPReader(server.URL+"/", nil)
+	require.NoError(t, err)
+
+	t.Run("delegation", func(t *testing.T) {
+		actual, err := reader.Delegation(ctx, delegation.StakingTxHashHex)
+		require.NoError(t, err)
+		assert.Equal(t, delegation, actual)
+
+		page, err := reader.DelegationsByFinalityProvider(ctx, fp.BtcPkHex, PageRequest{Limit: 10, Token: "token+/"})
+		require.NoError(t, err)
+		assert.Equal(t, []*Delegation{delegation}, page.Delegations)
+		assert.Equal(t, "next", page.NextPageToken)
+	})
+	t.Run("finality provider", func(t *testing.T) {
+		actual, err := reader.FinalityProvider(ctx, fp.BtcPkHex)
+		require.NoError(t, err)
+		assert.Equal(t, fp, actual)
+
+		page, err := reader.FinalityProviders(ctx, PageRequest{})
+		require.NoError(t, err)
+		assert.Equal(t, []*FinalityProvider{fp}, page.FinalityProviders)
+		assert.Empty(t, page.NextPageToken)
+	})
+	t.Run("not found", func(t *testing.T) {
+		_, err := reader.Delegation(ctx, "unknown")
+		require.ErrorIs(t, err, ErrNotFound)
+		assert.True(t, IsNotFound(err))
+
+		var apiErr *APIError
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, "NOT_FOUND", apiErr.ErrorCode)
+	})
+	t.Run("non api error", func(t *testing.T) {
+		brokenReader, err := NewHTTPReader(server.URL+"/broken", nil)
+		require.NoError(t, err)
+
+		_, err = brokenReader.Delegation(ctx, delegation.StakingTxHashHex)
+		var apiErr *APIError
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, http.StatusBadGateway, apiErr.StatusCode)
+		assert.Equal(t, "bad gateway", apiErr.Message)
+		assert.False(t, IsNotFound(err))
+	})
+	t.Run("invalid url", func(t *testing.T) {
+		_, err := NewHTTPReader("localhost:8080", nil)
+		require.Error(t, err)
+	})
+}
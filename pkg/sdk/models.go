@@ -0,0 +1,79 @@
+package sdk
+
+import "strings"
+
+// Delegation is BTC delegation as indexed from Babylon and BTC
+type Delegation struct {
+	StakingTxHashHex          string   `json:"staking_tx_hash_hex"`
+	StakingTxHex              string   `json:"staking_tx_hex"`
+	StakingOutputIdx          uint32   `json:"staking_output_idx"`
+	StakingAmount             uint64   `json:"staking_amount"` // in satoshis
+	StakingTime               uint32   `json:"staking_time"`   // in BTC blocks
+	StakingBtcTimestamp       int64    `json:"staking_btc_timestamp"`
+	StakerBtcPkHex            string   `json:"staker_btc_pk_hex"`
+	StakerBabylonAddress      string   `json:"staker_babylon_address"`
+	FinalityProviderBtcPksHex []string `json:"finality_provider_btc_pks_hex"`
+	ParamsVersion             uint32   `json:"params_version"`
+	// BTC heights of the staking timelock, zero until the delegation is active
+	StartHeight uint32 `json:"start_height"`
+	EndHeight   uint32 `json:"end_height"`
+
+	State        DelegationState    `json:"state"`
+	SubState     DelegationSubState `json:"sub_state,omitempty"`
+	StateHistory []StateRecord      `json:"state_history"`
+
+	UnbondingTime         uint32 `json:"unbonding_time"` // in BTC blocks
+	UnbondingTxHex        string `json:"unbonding_tx_hex"`
+	UnbondingStartHeight  uint32 `json:"unbonding_start_height,omitempty"`
+	UnbondingBtcTimestamp int64  `json:"unbonding_btc_timestamp,omitempty"`
+
+	// Babylon block the delegation was created in
+	CreatedBbnHeight    int64 `json:"created_bbn_height"`
+	CreatedBbnTimestamp int64 `json:"created_bbn_timestamp"`
+
+	SlashingTxHex          string `json:"slashing_tx_hex,omitempty"`
+	UnbondingSlashingTxHex string `json:"unbonding_slashing_tx_hex,omitempty"`
+	WithdrawalTxHash       string `json:"withdrawal_tx_hash,omitempty"`
+
+	// Staking tx hash of the delegation this one expanded, empty if it isn't an expansion
+	PreviousStakingTxHashHex string `json:"previous_staking_tx_hash_hex,omitempty"`
+	// Staking tx hash of the expansion of this delegation, empty if it wasn't expanded
+	NextStakingTxHashHex string `json:"next_staking_tx_hash_hex,omitempty"`
+}
+
+// StateRecord is a state transition of the delegation
+type StateRecord struct {
+	State        DelegationState    `json:"state"`
+	SubState     DelegationSubState `json:"sub_state,omitempty"`
+	BbnHeight    int64              `json:"bbn_height,omitempty"`    // Babylon block height when applicable
+	BtcHeight    uint32             `json:"btc_height,omitempty"`    // Bitcoin block height when applicable
+	BbnTimestamp int64              `json:"bbn_timestamp,omitempty"` // Unix time of the Babylon block
+	BtcTimestamp int64              `json:"btc_timestamp,omitempty"` // Unix time of the Bitcoin block
+}
+
+// HasFinalityProvider returns true if the delegation is to the finality provider
+func (d *Delegation) HasFinalityProvider(btcPkHex string) bool {
+	for _, pk := range d.FinalityProviderBtcPksHex {
+		if strings.EqualFold(pk, btcPkHex) {
+			return true
+		}
+	}
+	return false
+}
+
+// FinalityProvider is finality provider registered on Babylon
+type FinalityProvider struct {
+	BtcPkHex       string                `json:"btc_pk_hex"`
+	BabylonAddress string                `json:"babylon_address"`
+	Commission     string                `json:"commission"`
+	State          FinalityProviderState `json:"state"`
+	Description    Description           `json:"description"`
+}
+
+type Description struct {
+	Moniker         string `json:"moniker"`
+	Identity        string `json:"identity"`
+	Website         string `json:"website"`
+	SecurityContact string `json:"security_contact"`
+	Details         string `json:"details"`
+}
@@ -0,0 +1,117 @@
+package mongoreader
+
+import "github.com/babylonlabs-io/babylon-staking-indexer/pkg/sdk"
+
+// delegationDocument is the part of the indexer delegation document the reader needs
+type delegationDocument struct {
+	StakingTxHashHex          string                  `bson:"_id"`
+	StakingTxHex              string                  `bson:"staking_tx_hex"`
+	StakingTime               uint32                  `bson:"staking_time"`
+	StakingAmount             uint64                  `bson:"staking_amount"`
+	StakingOutputIdx          uint32                  `bson:"staking_output_idx"`
+	StakingBTCTimestamp       int64                   `bson:"staking_btc_timestamp"`
+	StakerBtcPkHex            string                  `bson:"staker_btc_pk_hex"`
+	StakerBabylonAddress      string                  `bson:"staker_babylon_address"`
+	FinalityProviderBtcPksHex []string                `bson:"finality_provider_btc_pks_hex"`
+	StartHeight               uint32                  `bson:"start_height"`
+	EndHeight                 uint32                  `bson:"end_height"`
+	State                     sdk.DelegationState     `bson:"state"`
+	SubState                  sdk.DelegationSubState  `bson:"sub_state"`
+	StateHistory              []stateRecordDocument   `bson:"state_history"`
+	ParamsVersion             uint32                  `bson:"params_version"`
+	UnbondingTime             uint32                  `bson:"unbonding_time"`
+	UnbondingTx               string                  `bson:"unbonding_tx"`
+	UnbondingStartHeight      uint32                  `bson:"unbonding_start_height"`
+	UnbondingBTCTimestamp     int64                   `bson:"unbonding_btc_timestamp"`
+	CreatedBbnBlock           createdBbnBlockDocument `bson:"btc_delegation_created_bbn_block"`
+	SlashingTx                slashingTxDocument      `bson:"slashing_tx"`
+	WithdrawalTx              withdrawalTxDocument    `bson:"withdrawal_tx"`
+	PreviousStakingTxHashHex  string                  `bson:"previous_staking_tx_hash_hex"`
+	NextStakingTxHashHex      string                  `bson:"next_staking_tx_hash_hex"`
+}
+
+type stateRecordDocument struct {
+	State        sdk.DelegationState    `bson:"state"`
+	SubState     sdk.DelegationSubState `bson:"sub_state"`
+	BbnHeight    int64                  `bson:"bbn_height"`
+	BtcHeight    uint32                 `bson:"btc_height"`
+	BbnTimestamp int64                  `bson:"bbn_timestamp"`
+	BtcTimestamp int64                  `bson:"btc_timestamp"`
+}
+
+type createdBbnBlockDocument struct {
+	Height    int64 `bson:"height"`
+	Timestamp int64 `bson:"timestamp"`
+}
+
+type slashingTxDocument struct {
+	SlashingTxHex          string `bson:"slashing_tx_hex"`
+	UnbondingSlashingTxHex string `bson:"unbonding_slashing_tx_hex"`
+}
+
+type withdrawalTxDocument struct {
+	TxHash string `bson:"tx_hash"`
+}
+
+func (d *delegationDocument) toDelegation() *sdk.Delegation {
+	history := make([]sdk.StateRecord, 0, len(d.StateHistory))
+	for _, record := range d.StateHistory {
+		history = append(history, sdk.StateRecord(record))
+	}
+
+	return &sdk.Delegation{
+		StakingTxHashHex:          d.StakingTxHashHex,
+		StakingTxHex:              d.StakingTxHex,
+		StakingOutputIdx:          d.StakingOutputIdx,
+		StakingAmount:             d.StakingAmount,
+		StakingTime:               d.StakingTime,
+		StakingBtcTimestamp:       d.StakingBTCTimestamp,
+		StakerBtcPkHex:            d.StakerBtcPkHex,
+		StakerBabylonAddress:      d.StakerBabylonAddress,
+		FinalityProviderBtcPksHex: d.FinalityProviderBtcPksHex,
+		ParamsVersion:             d.ParamsVersion,
+		StartHeight:               d.StartHeight,
+		EndHeight:                 d.EndHeight,
+		State:                     d.State,
+		SubState:                  d.SubState,
+		StateHistory:              history,
+		UnbondingTime:             d.UnbondingTime,
+		UnbondingTxHex:            d.UnbondingTx,
+		UnbondingStartHeight:      d.UnbondingStartHeight,
+		UnbondingBtcTimestamp:     d.UnbondingBTCTimestamp,
+		CreatedBbnHeight:          d.CreatedBbnBlock.Height,
+		CreatedBbnTimestamp:       d.CreatedBbnBlock.Timestamp,
+		SlashingTxHex:             d.SlashingTx.SlashingTxHex,
+		UnbondingSlashingTxHex:    d.SlashingTx.UnbondingSlashingTxHex,
+		WithdrawalTxHash:          d.WithdrawalTx.TxHash,
+		PreviousStakingTxHashHex:  d.PreviousStakingTxHashHex,
+		NextStakingTxHashHex:      d.NextStakingTxHashHex,
+	}
+}
+
+// finalityProviderDocument is the part of the indexer finality provider document the reader needs
+type finalityProviderDocument struct {
+	BtcPk          string                    `bson:"_id"`
+	BabylonAddress string                    `bson:"babylon_address"`
+	Commission     string                    `bson:"commission"`
+	State          sdk.FinalityProviderState `bson:"state"`
+	Description    descriptionDocument       `bson:"description"`
+}
+
+type descriptionDocument struct {
+	Moniker         string `bson:"moniker"`
+	Identity        string `bson:"identity"`
+	Website         string `bson:"website"`
+	SecurityContact string `bson:"security_contact"`
+	Details         string `bson:"details"`
+}
+
+func (d *finalityProviderDocument) toFinalityProvider() *sdk.FinalityProvider {
+	return &sdk.FinalityProvider{
+		BtcPkHex:       d.BtcPk,
+		BabylonAddress: d.BabylonAddress,
+		Commission:     d.Commission,
+		State:          d.State,
+		Description:    sdk.Description(d.Description),
+	}
+}
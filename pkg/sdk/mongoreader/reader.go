@@ -0,0 +1,125 @@
+// Package mongoreader reads data of the babylon staking indexer directly from its database.
+// It only depends on the document fields it reads, the indexer keeps them compatible
+// within a major version of the sdk.
+package mongoreader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/pkg/sdk"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Collections of the indexer database read by Reader
+const (
+	delegationsCollection       = "btc_delegation_details"
+	finalityProvidersCollection = "finality_provider_details"
+)
+
+// Reader is sdk.Reader of the indexer database. The database is written by the indexer
+// only, it has to be used with read only credentials.
+type Reader struct {
+	db *mongo.Database
+}
+
+var _ sdk.Reader = (*Reader)(nil)
+
+// New returns reader of the indexer database
+func New(database *mongo.Database) *Reader {
+	return &Reader{db: database}
+}
+
+func (r *Reader) Delegation(ctx context.Context, stakingTxHashHex string) (*sdk.Delegation, error) {
+	var doc delegationDocument
+	err := r.db.Collection(delegationsCollection).
+		FindOne(ctx, bson.M{"_id": stakingTxHashHex}).
+		Decode(&doc)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, fmt.Errorf("delegation %s: %w", stakingTxHashHex, sdk.ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get delegation: %w", err)
+	}
+
+	return doc.toDelegation(), nil
+}
+
+func (r *Reader) DelegationsByFinalityProvider(
+	ctx context.Context, fpBtcPkHex string, page sdk.PageRequest,
+) (*sdk.DelegationsResponse, error) {
+	afterStakingTxHash, limit, err := sdk.ParsePageRequest(page)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{
+		"_id":                           bson.M{"$gt": afterStakingTxHash},
+		"finality_provider_btc_pks_hex": fpBtcPkHex,
+	}
+	var docs []delegationDocument
+	if err := r.find(ctx, delegationsCollection, filter, limit, &docs); err != nil {
+		return nil, fmt.Errorf("failed to find delegations: %w", err)
+	}
+
+	resp := &sdk.DelegationsResponse{Delegations: make([]*sdk.Delegation, 0, len(docs))}
+	for i := range docs {
+		resp.Delegations = append(resp.Delegations, docs[i].toDelegation())
+	}
+	if len(docs) > 0 {
+		resp.NextPageToken = sdk.NextPageToken(docs[len(docs)-1].StakingTxHashHex, len(docs), limit)
+	}
+	return resp, nil
+}
+
+func (r *Reader) FinalityProvider(ctx context.Context, btcPkHex string) (*sdk.FinalityProvider, error) {
+	var doc finalityProviderDocument
+	err := r.db.Collection(finalityProvidersCollection).
+		FindOne(ctx, bson.M{"_id": btcPkHex}).
+		Decode(&doc)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, fmt.Errorf("finality provider %s: %w", btcPkHex, sdk.ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get finality provider: %w", err)
+	}
+
+	return doc.toFinalityProvider(), nil
+}
+
+func (r *Reader) FinalityProviders(ctx context.Context, page sdk.PageRequest) (*sdk.FinalityProvidersResponse, error) {
+	afterBtcPk, limit, err := sdk.ParsePageRequest(page)
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []finalityProviderDocument
+	err = r.find(ctx, finalityProvidersCollection, bson.M{"_id": bson.M{"$gt": afterBtcPk}}, limit, &docs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find finality providers: %w", err)
+	}
+
+	resp := &sdk.FinalityProvidersResponse{FinalityProviders: make([]*sdk.FinalityProvider, 0, len(docs))}
+	for i := range docs {
+		resp.FinalityProviders = append(resp.FinalityProviders, docs[i].toFinalityProvider())
+	}
+	if len(docs) > 0 {
+		resp.NextPageToken = sdk.NextPageToken(docs[len(docs)-1].BtcPk, len(docs), limit)
+	}
+	return resp, nil
+}
+
+// find decodes the page of documents matching the filter ordered by their ids into docs
+func (r *Reader) find(ctx context.Context, collection string, filter bson.M, limit int64, docs any) error {
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(limit)
+	cursor, err := r.db.Collection(collection).Find(ctx, filter, opts)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	return cursor.All(ctx, docs)
+}
@@ -0,0 +1,48 @@
+package sdk
+
+import (
+	"encoding/base64"
+	"fmt"
+)
+
+const (
+	// DefaultPageLimit is the number of items of a page when the limit isn't requested
+	DefaultPageLimit = 100
+	// MaxPageLimit is the maximum number of items of a page
+	MaxPageLimit = 1000
+)
+
+// PageRequest selects a page of a list ordered by the item keys
+type PageRequest struct {
+	// Limit is the maximum number of items of the page, zero means DefaultPageLimit
+	Limit int
+	// Token is NextPageToken of the previous page, empty for the first page
+	Token string
+}
+
+// ParsePageRequest returns key of the item the requested page follows and the page limit.
+// Page tokens are opaque to the clients, they encode key of the last item of the previous page.
+func ParsePageRequest(page PageRequest) (string, int64, error) {
+	limit := page.Limit
+	if limit == 0 {
+		limit = DefaultPageLimit
+	}
+	if limit < 0 || limit > MaxPageLimit {
+		return "", 0, fmt.Errorf("page limit must be between 1 and %d", MaxPageLimit)
+	}
+
+	afterKey, err := base64.RawURLEncoding.DecodeString(page.Token)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid page token")
+	}
+	return string(afterKey), int64(limit), nil
+}
+
+// NextPageToken returns token of the page following the item key. Only a full page
+// can be followed by another one, the token of a shorter page is empty.
+func NextPageToken(lastKey string, count int, limit int64) string {
+	if count == 0 || int64(count) < limit {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(lastKey))
+}
@@ -0,0 +1,30 @@
+package sdk
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPageTokens(t *testing.T) {
+	afterKey, limit, err := ParsePageRequest(PageRequest{})
+	require.NoError(t, err)
+	assert.Empty(t, afterKey)
+	assert.Equal(t, int64(DefaultPageLimit), limit)
+
+	// only a full page is followed by another one
+	assert.Empty(t, NextPageToken("b", 1, 2))
+	token := NextPageToken("b", 2, 2)
+	require.NotEmpty(t, token)
+
+	afterKey, limit, err = ParsePageRequest(PageRequest{Limit: 2, Token: token})
+	require.NoError(t, err)
+	assert.Equal(t, "b", afterKey)
+	assert.Equal(t, int64(2), limit)
+
+	_, _, err = ParsePageRequest(PageRequest{Limit: MaxPageLimit + 1})
+	require.Error(t, err)
+	_, _, err = ParsePageRequest(PageRequest{Token: "not a token"})
+	require.Error(t, err)
+}
@@ -0,0 +1,28 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNotFound is returned when the requested delegation or finality provider isn't indexed
+var ErrNotFound = errors.New("not found")
+
+// IsNotFound returns true if the error means the requested data isn't indexed
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
+
+// Reader reads data of the indexer
+type Reader interface {
+	// Delegation returns the delegation by its staking tx hash
+	Delegation(ctx context.Context, stakingTxHashHex string) (*Delegation, error)
+	// DelegationsByFinalityProvider returns the page of delegations to the finality provider
+	DelegationsByFinalityProvider(
+		ctx context.Context, fpBtcPkHex string, page PageRequest,
+	) (*DelegationsResponse, error)
+	// FinalityProvider returns the finality provider by its BTC public key
+	FinalityProvider(ctx context.Context, btcPkHex string) (*FinalityProvider, error)
+	// FinalityProviders returns the page of finality providers
+	FinalityProviders(ctx context.Context, page PageRequest) (*FinalityProvidersResponse, error)
+}
@@ -0,0 +1,94 @@
+package sdk
+
+// DelegationState is the state of BTC delegation
+type DelegationState string
+
+const (
+	StatePending      DelegationState = "PENDING"
+	StateVerified     DelegationState = "VERIFIED"
+	StateActive       DelegationState = "ACTIVE"
+	StateUnbonding    DelegationState = "UNBONDING"
+	StateWithdrawable DelegationState = "WITHDRAWABLE"
+	StateSlashed      DelegationState = "SLASHED"
+	StateWithdrawn    DelegationState = "WITHDRAWN"
+	StateExpanded     DelegationState = "EXPANDED"
+	// StateUnknownSpend is the staking or unbonding output spent by a tx the indexer can't classify
+	StateUnknownSpend DelegationState = "UNKNOWN_SPEND"
+)
+
+func (s DelegationState) String() string {
+	return string(s)
+}
+
+// IsKnown returns false for states added by newer indexer versions
+func (s DelegationState) IsKnown() bool {
+	switch s {
+	case StatePending, StateVerified, StateActive, StateUnbonding, StateWithdrawable,
+		StateSlashed, StateWithdrawn, StateExpanded, StateUnknownSpend:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsAwaitingActivation returns true if the delegation waits for covenant signatures
+// or inclusion of the staking tx in BTC
+func (s DelegationState) IsAwaitingActivation() bool {
+	return s == StatePending || s == StateVerified
+}
+
+// IsActive returns true if the delegation has voting power
+func (s DelegationState) IsActive() bool {
+	return s == StateActive
+}
+
+// IsUnbonding returns true if the delegation left the active set and waits for the unbonding timelock
+func (s DelegationState) IsUnbonding() bool {
+	return s == StateUnbonding
+}
+
+// IsWithdrawable returns true if the staker can withdraw the BTC of the delegation
+func (s DelegationState) IsWithdrawable() bool {
+	return s == StateWithdrawable
+}
+
+// IsTerminal returns true if the delegation won't change its state anymore. Unknown spend
+// is only left if the spending tx is reorged out of BTC.
+func (s DelegationState) IsTerminal() bool {
+	return s == StateWithdrawn || s == StateExpanded || s == StateUnknownSpend
+}
+
+// DelegationSubState tells how the delegation reached the state
+type DelegationSubState string
+
+const (
+	SubStateTimelock       DelegationSubState = "TIMELOCK"
+	SubStateEarlyUnbonding DelegationSubState = "EARLY_UNBONDING"
+
+	// Used only for WITHDRAWABLE and WITHDRAWN states
+	SubStateTimelockSlashing       DelegationSubState = "TIMELOCK_SLASHING"
+	SubStateEarlyUnbondingSlashing DelegationSubState = "EARLY_UNBONDING_SLASHING"
+)
+
+func (s DelegationSubState) String() string {
+	return string(s)
+}
+
+// IsSlashed returns true if the delegation was slashed
+func (s DelegationSubState) IsSlashed() bool {
+	return s == SubStateTimelockSlashing || s == SubStateEarlyUnbondingSlashing
+}
+
+// FinalityProviderState is the status of finality provider on Babylon
+type FinalityProviderState string
+
+const (
+	FinalityProviderStateInactive FinalityProviderState = "FINALITY_PROVIDER_STATUS_INACTIVE"
+	FinalityProviderStateActive   FinalityProviderState = "FINALITY_PROVIDER_STATUS_ACTIVE"
+	FinalityProviderStateJailed   FinalityProviderState = "FINALITY_PROVIDER_STATUS_JAILED"
+	FinalityProviderStateSlashed  FinalityProviderState = "FINALITY_PROVIDER_STATUS_SLASHED"
+)
+
+func (s FinalityProviderState) String() string {
+	return string(s)
+}
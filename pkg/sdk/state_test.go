@@ -0,0 +1,34 @@
+package sdk
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDelegationState(t *testing.T) {
+	assert.True(t, StatePending.IsAwaitingActivation())
+	assert.True(t, StateVerified.IsAwaitingActivation())
+	assert.False(t, StateActive.IsAwaitingActivation())
+
+	assert.True(t, StateActive.IsActive())
+	assert.True(t, StateUnbonding.IsUnbonding())
+	assert.True(t, StateWithdrawable.IsWithdrawable())
+
+	for _, state := range []DelegationState{StateWithdrawn, StateExpanded, StateUnknownSpend} {
+		assert.True(t, state.IsTerminal(), state)
+	}
+	for _, state := range []DelegationState{StateActive, StateSlashed, StateWithdrawable} {
+		assert.False(t, state.IsTerminal(), state)
+	}
+
+	assert.True(t, StateUnknownSpend.IsKnown())
+	assert.False(t, DelegationState("NEW_STATE").IsKnown())
+
+	assert.True(t, SubStateEarlyUnbondingSlashing.IsSlashed())
+	assert.False(t, SubStateEarlyUnbonding.IsSlashed())
+
+	d := &Delegation{FinalityProviderBtcPksHex: []string{"ABCD"}}
+	assert.True(t, d.HasFinalityProvider("abcd"))
+	assert.False(t, d.HasFinalityProvider("ef"))
+}
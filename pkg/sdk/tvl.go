@@ -0,0 +1,43 @@
+package sdk
+
+import "strings"
+
+// ActiveTVL returns total staking amount in satoshis of the active delegations. Delegation
+// with active expansion among the delegations is skipped, as the expansion stakes its BTC.
+func ActiveTVL(delegations []*Delegation) uint64 {
+	var tvl uint64
+	forEachActive(delegations, func(d *Delegation) {
+		tvl += d.StakingAmount
+	})
+	return tvl
+}
+
+// ActiveTVLByFinalityProvider returns ActiveTVL of every finality provider, keyed by
+// lowercase finality provider BTC public key. Delegation to several finality providers
+// counts fully towards each of them.
+func ActiveTVLByFinalityProvider(delegations []*Delegation) map[string]uint64 {
+	tvl := make(map[string]uint64)
+	forEachActive(delegations, func(d *Delegation) {
+		for _, pk := range d.FinalityProviderBtcPksHex {
+			tvl[strings.ToLower(pk)] += d.StakingAmount
+		}
+	})
+	return tvl
+}
+
+// forEachActive calls fn for active delegations without active expansion
+func forEachActive(delegations []*Delegation, fn func(d *Delegation)) {
+	expanded := make(map[string]bool)
+	for _, d := range delegations {
+		if d.State.IsActive() && d.PreviousStakingTxHashHex != "" {
+			expanded[d.PreviousStakingTxHashHex] = true
+		}
+	}
+
+	for _, d := range delegations {
+		if !d.State.IsActive() || expanded[d.StakingTxHashHex] {
+			continue
+		}
+		fn(d)
+	}
+}
@@ -0,0 +1,32 @@
+package sdk
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestActiveTVL(t *testing.T) {
+	delegations := []*Delegation{
+		{StakingTxHashHex: "a", State: StateActive, StakingAmount: 100, FinalityProviderBtcPksHex: []string{"FP1"}},
+		{StakingTxHashHex: "b", State: StateActive, StakingAmount: 200, FinalityProviderBtcPksHex: []string{"fp1", "fp2"}},
+		{StakingTxHashHex: "c", State: StateUnbonding, StakingAmount: 400, FinalityProviderBtcPksHex: []string{"fp1"}},
+		{StakingTxHashHex: "d", State: StatePending, StakingAmount: 800, FinalityProviderBtcPksHex: []string{"fp2"}},
+		// active expansion of b stakes its BTC, so b isn't counted
+		{
+			StakingTxHashHex: "e", State: StateActive, StakingAmount: 250,
+			FinalityProviderBtcPksHex: []string{"fp2"}, PreviousStakingTxHashHex: "b",
+		},
+		// expansion of a waiting for activation doesn't stake yet
+		{
+			StakingTxHashHex: "f", State: StateVerified, StakingAmount: 150,
+			FinalityProviderBtcPksHex: []string{"fp1"}, PreviousStakingTxHashHex: "a",
+		},
+	}
+
+	assert.Equal(t, uint64(350), ActiveTVL(delegations))
+	assert.Equal(t, map[string]uint64{"fp1": 100, "fp2": 250}, ActiveTVLByFinalityProvider(delegations))
+
+	assert.Zero(t, ActiveTVL(nil))
+	assert.Empty(t, ActiveTVLByFinalityProvider(nil))
+}
@@ -12,6 +12,8 @@ import (
 	mock "github.com/stretchr/testify/mock"
 
 	time "time"
+
+	types "github.com/babylonlabs-io/babylon/v4/x/btcstaking/types"
 )
 
 // BbnInterface is an autogenerated mock type for the BbnInterface type
@@ -47,6 +49,36 @@ func (_m *BbnInterface) BabylonStakerAddress(ctx context.Context, stakingTxHashH
 	return r0, r1
 }
 
+// GetActiveFinalityProvidersAtHeight provides a mock function with given fields: ctx, height
+func (_m *BbnInterface) GetActiveFinalityProvidersAtHeight(ctx context.Context, height int64) ([]string, error) {
+	ret := _m.Called(ctx, height)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetActiveFinalityProvidersAtHeight")
+	}
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) ([]string, error)); ok {
+		return rf(ctx, height)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []string); ok {
+		r0 = rf(ctx, height)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, height)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetAllStakingParams provides a mock function with given fields: ctx
 func (_m *BbnInterface) GetAllStakingParams(ctx context.Context) (map[uint32]*bbnclient.StakingParams, error) {
 	ret := _m.Called(ctx)
@@ -77,6 +109,45 @@ func (_m *BbnInterface) GetAllStakingParams(ctx context.Context) (map[uint32]*bb
 	return r0, r1
 }
 
+// GetBTCDelegationsAtHeight provides a mock function with given fields: ctx, height, pageKey
+func (_m *BbnInterface) GetBTCDelegationsAtHeight(ctx context.Context, height int64, pageKey []byte) ([]*types.BTCDelegationResponse, []byte, error) {
+	ret := _m.Called(ctx, height, pageKey)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetBTCDelegationsAtHeight")
+	}
+
+	var r0 []*types.BTCDelegationResponse
+	var r1 []byte
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, []byte) ([]*types.BTCDelegationResponse, []byte, error)); ok {
+		return rf(ctx, height, pageKey)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, []byte) []*types.BTCDelegationResponse); ok {
+		r0 = rf(ctx, height, pageKey)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*types.BTCDelegationResponse)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, []byte) []byte); ok {
+		r1 = rf(ctx, height, pageKey)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).([]byte)
+		}
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, int64, []byte) error); ok {
+		r2 = rf(ctx, height, pageKey)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
 // GetBlock provides a mock function with given fields: ctx, blockHeight
 func (_m *BbnInterface) GetBlock(ctx context.Context, blockHeight *int64) (*coretypes.ResultBlock, error) {
 	ret := _m.Called(ctx, blockHeight)
@@ -195,6 +266,36 @@ func (_m *BbnInterface) GetCheckpointParams(ctx context.Context) (*bbnclient.Che
 	return r0, r1
 }
 
+// GetFinalityProvidersAtHeight provides a mock function with given fields: ctx, height
+func (_m *BbnInterface) GetFinalityProvidersAtHeight(ctx context.Context, height int64) ([]*types.FinalityProviderResponse, error) {
+	ret := _m.Called(ctx, height)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetFinalityProvidersAtHeight")
+	}
+
+	var r0 []*types.FinalityProviderResponse
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) ([]*types.FinalityProviderResponse, error)); ok {
+		return rf(ctx, height)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []*types.FinalityProviderResponse); ok {
+		r0 = rf(ctx, height)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*types.FinalityProviderResponse)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, height)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetLatestBlockNumber provides a mock function with given fields: ctx
 func (_m *BbnInterface) GetLatestBlockNumber(ctx context.Context) (int64, error) {
 	ret := _m.Called(ctx)
@@ -271,17 +372,17 @@ func (_m *BbnInterface) IsRunning() bool {
 	return r0
 }
 
-// Start provides a mock function with no fields
-func (_m *BbnInterface) Start() error {
-	ret := _m.Called()
+// Start provides a mock function with given fields: ctx
+func (_m *BbnInterface) Start(ctx context.Context) error {
+	ret := _m.Called(ctx)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Start")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func() error); ok {
-		r0 = rf()
+	if rf, ok := ret.Get(0).(func(context.Context) error); ok {
+		r0 = rf(ctx)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -5,6 +5,8 @@ package mocks
 import (
 	context "context"
 
+	chainhash "github.com/btcsuite/btcd/chaincfg/chainhash"
+
 	mock "github.com/stretchr/testify/mock"
 )
 
@@ -13,6 +15,34 @@ type BtcInterface struct {
 	mock.Mock
 }
 
+// GetBlockHash provides a mock function with given fields: ctx, height
+func (_m *BtcInterface) GetBlockHash(ctx context.Context, height uint32) (string, error) {
+	ret := _m.Called(ctx, height)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetBlockHash")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint32) (string, error)); ok {
+		return rf(ctx, height)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uint32) string); ok {
+		r0 = rf(ctx, height)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uint32) error); ok {
+		r1 = rf(ctx, height)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetBlockTimestamp provides a mock function with given fields: ctx, height
 func (_m *BtcInterface) GetBlockTimestamp(ctx context.Context, height uint32) (int64, error) {
 	ret := _m.Called(ctx, height)
@@ -41,6 +71,34 @@ func (_m *BtcInterface) GetBlockTimestamp(ctx context.Context, height uint32) (i
 	return r0, r1
 }
 
+// GetBlockTimestampByHash provides a mock function with given fields: ctx, hash
+func (_m *BtcInterface) GetBlockTimestampByHash(ctx context.Context, hash string) (int64, error) {
+	ret := _m.Called(ctx, hash)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetBlockTimestampByHash")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (int64, error)); ok {
+		return rf(ctx, hash)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) int64); ok {
+		r0 = rf(ctx, hash)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, hash)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetTipHeight provides a mock function with given fields: ctx
 func (_m *BtcInterface) GetTipHeight(ctx context.Context) (uint64, error) {
 	ret := _m.Called(ctx)
@@ -69,6 +127,34 @@ func (_m *BtcInterface) GetTipHeight(ctx context.Context) (uint64, error) {
 	return r0, r1
 }
 
+// IsTxInMempool provides a mock function with given fields: ctx, txHash
+func (_m *BtcInterface) IsTxInMempool(ctx context.Context, txHash *chainhash.Hash) (bool, error) {
+	ret := _m.Called(ctx, txHash)
+
+	if len(ret) == 0 {
+		panic("no return value specified for IsTxInMempool")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *chainhash.Hash) (bool, error)); ok {
+		return rf(ctx, txHash)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *chainhash.Hash) bool); ok {
+		r0 = rf(ctx, txHash)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *chainhash.Hash) error); ok {
+		r1 = rf(ctx, txHash)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NewBtcInterface creates a new instance of BtcInterface. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewBtcInterface(t interface {
@@ -3,7 +3,9 @@
 package mocks
 
 import (
+	chainhash "github.com/btcsuite/btcd/chaincfg/chainhash"
 	chainntnfs "github.com/lightningnetwork/lnd/chainntnfs"
+
 	mock "github.com/stretchr/testify/mock"
 
 	wire "github.com/btcsuite/btcd/wire"
@@ -14,6 +16,43 @@ type BtcNotifier struct {
 	mock.Mock
 }
 
+// RegisterConfirmationsNtfn provides a mock function with given fields: txid, pkScript, numConfs, heightHint, opts
+func (_m *BtcNotifier) RegisterConfirmationsNtfn(txid *chainhash.Hash, pkScript []byte, numConfs uint32, heightHint uint32, opts ...chainntnfs.NotifierOption) (*chainntnfs.ConfirmationEvent, error) {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, txid, pkScript, numConfs, heightHint)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RegisterConfirmationsNtfn")
+	}
+
+	var r0 *chainntnfs.ConfirmationEvent
+	var r1 error
+	if rf, ok := ret.Get(0).(func(*chainhash.Hash, []byte, uint32, uint32, ...chainntnfs.NotifierOption) (*chainntnfs.ConfirmationEvent, error)); ok {
+		return rf(txid, pkScript, numConfs, heightHint, opts...)
+	}
+	if rf, ok := ret.Get(0).(func(*chainhash.Hash, []byte, uint32, uint32, ...chainntnfs.NotifierOption) *chainntnfs.ConfirmationEvent); ok {
+		r0 = rf(txid, pkScript, numConfs, heightHint, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*chainntnfs.ConfirmationEvent)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(*chainhash.Hash, []byte, uint32, uint32, ...chainntnfs.NotifierOption) error); ok {
+		r1 = rf(txid, pkScript, numConfs, heightHint, opts...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // RegisterSpendNtfn provides a mock function with given fields: outpoint, pkScript, heightHint
 func (_m *BtcNotifier) RegisterSpendNtfn(outpoint *wire.OutPoint, pkScript []byte, heightHint uint32) (*chainntnfs.SpendEvent, error) {
 	ret := _m.Called(outpoint, pkScript, heightHint)
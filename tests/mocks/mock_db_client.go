@@ -13,6 +13,8 @@ import (
 
 	model "github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
 
+	primitive "go.mongodb.org/mongo-driver/bson/primitive"
+
 	types "github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
 )
 
@@ -21,6 +23,191 @@ type DbInterface struct {
 	mock.Mock
 }
 
+// BulkUpdateBTCDelegationState provides a mock function with given fields: ctx, updates
+func (_m *DbInterface) BulkUpdateBTCDelegationState(ctx context.Context, updates []db.BTCDelegationStateUpdate) ([]error, error) {
+	ret := _m.Called(ctx, updates)
+
+	if len(ret) == 0 {
+		panic("no return value specified for BulkUpdateBTCDelegationState")
+	}
+
+	var r0 []error
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []db.BTCDelegationStateUpdate) ([]error, error)); ok {
+		return rf(ctx, updates)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []db.BTCDelegationStateUpdate) []error); ok {
+		r0 = rf(ctx, updates)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]error)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []db.BTCDelegationStateUpdate) error); ok {
+		r1 = rf(ctx, updates)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CalculateActiveStatsAggregated provides a mock function with given fields: ctx
+func (_m *DbInterface) CalculateActiveStatsAggregated(ctx context.Context) (uint64, uint64, []*db.FinalityProviderStatsResult, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CalculateActiveStatsAggregated")
+	}
+
+	var r0 uint64
+	var r1 uint64
+	var r2 []*db.FinalityProviderStatsResult
+	var r3 error
+	if rf, ok := ret.Get(0).(func(context.Context) (uint64, uint64, []*db.FinalityProviderStatsResult, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) uint64); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(uint64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) uint64); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Get(1).(uint64)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context) []*db.FinalityProviderStatsResult); ok {
+		r2 = rf(ctx)
+	} else {
+		if ret.Get(2) != nil {
+			r2 = ret.Get(2).([]*db.FinalityProviderStatsResult)
+		}
+	}
+
+	if rf, ok := ret.Get(3).(func(context.Context) error); ok {
+		r3 = rf(ctx)
+	} else {
+		r3 = ret.Error(3)
+	}
+
+	return r0, r1, r2, r3
+}
+
+// CalculateUnlockBuckets provides a mock function with given fields: ctx, fromHeight, bucketBlocks, buckets
+func (_m *DbInterface) CalculateUnlockBuckets(ctx context.Context, fromHeight uint32, bucketBlocks uint32, buckets uint32) ([]db.UnlockBucketResult, error) {
+	ret := _m.Called(ctx, fromHeight, bucketBlocks, buckets)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CalculateUnlockBuckets")
+	}
+
+	var r0 []db.UnlockBucketResult
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint32, uint32, uint32) ([]db.UnlockBucketResult, error)); ok {
+		return rf(ctx, fromHeight, bucketBlocks, buckets)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uint32, uint32, uint32) []db.UnlockBucketResult); ok {
+		r0 = rf(ctx, fromHeight, bucketBlocks, buckets)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]db.UnlockBucketResult)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uint32, uint32, uint32) error); ok {
+		r1 = rf(ctx, fromHeight, bucketBlocks, buckets)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ConfirmProvisionalSpend provides a mock function with given fields: ctx, stakingTxHash, spendingTxHash, opts
+func (_m *DbInterface) ConfirmProvisionalSpend(ctx context.Context, stakingTxHash string, spendingTxHash string, opts ...db.UpdateOption) error {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, stakingTxHash, spendingTxHash)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ConfirmProvisionalSpend")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, ...db.UpdateOption) error); ok {
+		r0 = rf(ctx, stakingTxHash, spendingTxHash, opts...)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CountExpiredDelegations provides a mock function with given fields: ctx, btcTipHeight
+func (_m *DbInterface) CountExpiredDelegations(ctx context.Context, btcTipHeight uint64) (int64, error) {
+	ret := _m.Called(ctx, btcTipHeight)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CountExpiredDelegations")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint64) (int64, error)); ok {
+		return rf(ctx, btcTipHeight)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uint64) int64); ok {
+		r0 = rf(ctx, btcTipHeight)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uint64) error); ok {
+		r1 = rf(ctx, btcTipHeight)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CountStaleStakingTxs provides a mock function with given fields: ctx
+func (_m *DbInterface) CountStaleStakingTxs(ctx context.Context) (int64, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CountStaleStakingTxs")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (int64, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) int64); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // DeleteExpiredDelegation provides a mock function with given fields: ctx, stakingTxHashHex
 func (_m *DbInterface) DeleteExpiredDelegation(ctx context.Context, stakingTxHashHex string) error {
 	ret := _m.Called(ctx, stakingTxHashHex)
@@ -39,9 +226,75 @@ func (_m *DbInterface) DeleteExpiredDelegation(ctx context.Context, stakingTxHas
 	return r0
 }
 
-// FindExpiredDelegations provides a mock function with given fields: ctx, btcTipHeight, limit
-func (_m *DbInterface) FindExpiredDelegations(ctx context.Context, btcTipHeight uint64, limit uint64) ([]model.TimeLockDocument, error) {
-	ret := _m.Called(ctx, btcTipHeight, limit)
+// DeleteTimeLockExpire provides a mock function with given fields: ctx, stakingTxHashHex, subState
+func (_m *DbInterface) DeleteTimeLockExpire(ctx context.Context, stakingTxHashHex string, subState types.DelegationSubState) error {
+	ret := _m.Called(ctx, stakingTxHashHex, subState)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteTimeLockExpire")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, types.DelegationSubState) error); ok {
+		r0 = rf(ctx, stakingTxHashHex, subState)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteTimeLockExpires provides a mock function with given fields: ctx, ids
+func (_m *DbInterface) DeleteTimeLockExpires(ctx context.Context, ids []primitive.ObjectID) error {
+	ret := _m.Called(ctx, ids)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteTimeLockExpires")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, []primitive.ObjectID) error); ok {
+		r0 = rf(ctx, ids)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// FindDelegationsByFinalityProvider provides a mock function with given fields: ctx, fpBtcPkHex, afterStakingTxHash, limit
+func (_m *DbInterface) FindDelegationsByFinalityProvider(ctx context.Context, fpBtcPkHex string, afterStakingTxHash string, limit int64) ([]*model.BTCDelegationDetails, error) {
+	ret := _m.Called(ctx, fpBtcPkHex, afterStakingTxHash, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FindDelegationsByFinalityProvider")
+	}
+
+	var r0 []*model.BTCDelegationDetails
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, int64) ([]*model.BTCDelegationDetails, error)); ok {
+		return rf(ctx, fpBtcPkHex, afterStakingTxHash, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, int64) []*model.BTCDelegationDetails); ok {
+		r0 = rf(ctx, fpBtcPkHex, afterStakingTxHash, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.BTCDelegationDetails)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, int64) error); ok {
+		r1 = rf(ctx, fpBtcPkHex, afterStakingTxHash, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FindExpiredDelegations provides a mock function with given fields: ctx, btcTipHeight, afterID, limit
+func (_m *DbInterface) FindExpiredDelegations(ctx context.Context, btcTipHeight uint64, afterID primitive.ObjectID, limit uint64) ([]model.TimeLockDocument, error) {
+	ret := _m.Called(ctx, btcTipHeight, afterID, limit)
 
 	if len(ret) == 0 {
 		panic("no return value specified for FindExpiredDelegations")
@@ -49,19 +302,109 @@ func (_m *DbInterface) FindExpiredDelegations(ctx context.Context, btcTipHeight
 
 	var r0 []model.TimeLockDocument
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, uint64, uint64) ([]model.TimeLockDocument, error)); ok {
-		return rf(ctx, btcTipHeight, limit)
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, primitive.ObjectID, uint64) ([]model.TimeLockDocument, error)); ok {
+		return rf(ctx, btcTipHeight, afterID, limit)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, uint64, uint64) []model.TimeLockDocument); ok {
-		r0 = rf(ctx, btcTipHeight, limit)
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, primitive.ObjectID, uint64) []model.TimeLockDocument); ok {
+		r0 = rf(ctx, btcTipHeight, afterID, limit)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]model.TimeLockDocument)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, uint64, uint64) error); ok {
-		r1 = rf(ctx, btcTipHeight, limit)
+	if rf, ok := ret.Get(1).(func(context.Context, uint64, primitive.ObjectID, uint64) error); ok {
+		r1 = rf(ctx, btcTipHeight, afterID, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FindExpiringSoonDelegations provides a mock function with given fields: ctx, btcTip, threshold, limit
+func (_m *DbInterface) FindExpiringSoonDelegations(ctx context.Context, btcTip uint32, threshold uint32, limit int64) ([]*model.BTCDelegationDetails, error) {
+	ret := _m.Called(ctx, btcTip, threshold, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FindExpiringSoonDelegations")
+	}
+
+	var r0 []*model.BTCDelegationDetails
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint32, uint32, int64) ([]*model.BTCDelegationDetails, error)); ok {
+		return rf(ctx, btcTip, threshold, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uint32, uint32, int64) []*model.BTCDelegationDetails); ok {
+		r0 = rf(ctx, btcTip, threshold, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.BTCDelegationDetails)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uint32, uint32, int64) error); ok {
+		r1 = rf(ctx, btcTip, threshold, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FindFinalityProviders provides a mock function with given fields: ctx, afterBtcPk, limit
+func (_m *DbInterface) FindFinalityProviders(ctx context.Context, afterBtcPk string, limit int64) ([]*model.FinalityProviderDetails, error) {
+	ret := _m.Called(ctx, afterBtcPk, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FindFinalityProviders")
+	}
+
+	var r0 []*model.FinalityProviderDetails
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int64) ([]*model.FinalityProviderDetails, error)); ok {
+		return rf(ctx, afterBtcPk, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, int64) []*model.FinalityProviderDetails); ok {
+		r0 = rf(ctx, afterBtcPk, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.FinalityProviderDetails)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, int64) error); ok {
+		r1 = rf(ctx, afterBtcPk, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FindStakingTxTrackedDelegations provides a mock function with given fields: ctx, afterStakingTxHash, limit
+func (_m *DbInterface) FindStakingTxTrackedDelegations(ctx context.Context, afterStakingTxHash string, limit int64) ([]*model.BTCDelegationDetails, error) {
+	ret := _m.Called(ctx, afterStakingTxHash, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FindStakingTxTrackedDelegations")
+	}
+
+	var r0 []*model.BTCDelegationDetails
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int64) ([]*model.BTCDelegationDetails, error)); ok {
+		return rf(ctx, afterStakingTxHash, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, int64) []*model.BTCDelegationDetails); ok {
+		r0 = rf(ctx, afterStakingTxHash, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.BTCDelegationDetails)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, int64) error); ok {
+		r1 = rf(ctx, afterStakingTxHash, limit)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -99,6 +442,36 @@ func (_m *DbInterface) GetAllFinalityProviders(ctx context.Context) ([]*model.Fi
 	return r0, r1
 }
 
+// GetAnomalies provides a mock function with given fields: ctx, stakingTxHashHex
+func (_m *DbInterface) GetAnomalies(ctx context.Context, stakingTxHashHex string) ([]model.Anomaly, error) {
+	ret := _m.Called(ctx, stakingTxHashHex)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAnomalies")
+	}
+
+	var r0 []model.Anomaly
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]model.Anomaly, error)); ok {
+		return rf(ctx, stakingTxHashHex)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []model.Anomaly); ok {
+		r0 = rf(ctx, stakingTxHashHex)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]model.Anomaly)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, stakingTxHashHex)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetBTCDelegationByStakingTxHash provides a mock function with given fields: ctx, stakingTxHash
 func (_m *DbInterface) GetBTCDelegationByStakingTxHash(ctx context.Context, stakingTxHash string) (*model.BTCDelegationDetails, error) {
 	ret := _m.Called(ctx, stakingTxHash)
@@ -189,6 +562,36 @@ func (_m *DbInterface) GetBTCDelegationsByStates(ctx context.Context, states []t
 	return r0, r1
 }
 
+// GetCheckpointParams provides a mock function with given fields: ctx
+func (_m *DbInterface) GetCheckpointParams(ctx context.Context) (*bbnclient.CheckpointParams, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetCheckpointParams")
+	}
+
+	var r0 *bbnclient.CheckpointParams
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (*bbnclient.CheckpointParams, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) *bbnclient.CheckpointParams); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*bbnclient.CheckpointParams)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetDelegationsByFinalityProvider provides a mock function with given fields: ctx, fpBtcPkHex
 func (_m *DbInterface) GetDelegationsByFinalityProvider(ctx context.Context, fpBtcPkHex string) ([]*model.BTCDelegationDetails, error) {
 	ret := _m.Called(ctx, fpBtcPkHex)
@@ -197,21 +600,81 @@ func (_m *DbInterface) GetDelegationsByFinalityProvider(ctx context.Context, fpB
 		panic("no return value specified for GetDelegationsByFinalityProvider")
 	}
 
-	var r0 []*model.BTCDelegationDetails
+	var r0 []*model.BTCDelegationDetails
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]*model.BTCDelegationDetails, error)); ok {
+		return rf(ctx, fpBtcPkHex)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []*model.BTCDelegationDetails); ok {
+		r0 = rf(ctx, fpBtcPkHex)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.BTCDelegationDetails)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, fpBtcPkHex)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetExpansionChain provides a mock function with given fields: ctx, stakingTxHash
+func (_m *DbInterface) GetExpansionChain(ctx context.Context, stakingTxHash string) ([]*model.BTCDelegationDetails, error) {
+	ret := _m.Called(ctx, stakingTxHash)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetExpansionChain")
+	}
+
+	var r0 []*model.BTCDelegationDetails
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]*model.BTCDelegationDetails, error)); ok {
+		return rf(ctx, stakingTxHash)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []*model.BTCDelegationDetails); ok {
+		r0 = rf(ctx, stakingTxHash)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.BTCDelegationDetails)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, stakingTxHash)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetFinalityProviderByBtcPk provides a mock function with given fields: ctx, btcPk
+func (_m *DbInterface) GetFinalityProviderByBtcPk(ctx context.Context, btcPk string) (*model.FinalityProviderDetails, error) {
+	ret := _m.Called(ctx, btcPk)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetFinalityProviderByBtcPk")
+	}
+
+	var r0 *model.FinalityProviderDetails
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) ([]*model.BTCDelegationDetails, error)); ok {
-		return rf(ctx, fpBtcPkHex)
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*model.FinalityProviderDetails, error)); ok {
+		return rf(ctx, btcPk)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string) []*model.BTCDelegationDetails); ok {
-		r0 = rf(ctx, fpBtcPkHex)
+	if rf, ok := ret.Get(0).(func(context.Context, string) *model.FinalityProviderDetails); ok {
+		r0 = rf(ctx, btcPk)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]*model.BTCDelegationDetails)
+			r0 = ret.Get(0).(*model.FinalityProviderDetails)
 		}
 	}
 
 	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = rf(ctx, fpBtcPkHex)
+		r1 = rf(ctx, btcPk)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -219,29 +682,27 @@ func (_m *DbInterface) GetDelegationsByFinalityProvider(ctx context.Context, fpB
 	return r0, r1
 }
 
-// GetDelegationsWithEmptyStakerAddress provides a mock function with given fields: ctx
-func (_m *DbInterface) GetDelegationsWithEmptyStakerAddress(ctx context.Context) ([]model.BTCDelegationDetails, error) {
-	ret := _m.Called(ctx)
+// GetFinalityProviderCommissionAtHeight provides a mock function with given fields: ctx, btcPk, bbnHeight
+func (_m *DbInterface) GetFinalityProviderCommissionAtHeight(ctx context.Context, btcPk string, bbnHeight int64) (string, error) {
+	ret := _m.Called(ctx, btcPk, bbnHeight)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetDelegationsWithEmptyStakerAddress")
+		panic("no return value specified for GetFinalityProviderCommissionAtHeight")
 	}
 
-	var r0 []model.BTCDelegationDetails
+	var r0 string
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context) ([]model.BTCDelegationDetails, error)); ok {
-		return rf(ctx)
+	if rf, ok := ret.Get(0).(func(context.Context, string, int64) (string, error)); ok {
+		return rf(ctx, btcPk, bbnHeight)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context) []model.BTCDelegationDetails); ok {
-		r0 = rf(ctx)
+	if rf, ok := ret.Get(0).(func(context.Context, string, int64) string); ok {
+		r0 = rf(ctx, btcPk, bbnHeight)
 	} else {
-		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]model.BTCDelegationDetails)
-		}
+		r0 = ret.Get(0).(string)
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
-		r1 = rf(ctx)
+	if rf, ok := ret.Get(1).(func(context.Context, string, int64) error); ok {
+		r1 = rf(ctx, btcPk, bbnHeight)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -249,24 +710,24 @@ func (_m *DbInterface) GetDelegationsWithEmptyStakerAddress(ctx context.Context)
 	return r0, r1
 }
 
-// GetFinalityProviderByBtcPk provides a mock function with given fields: ctx, btcPk
-func (_m *DbInterface) GetFinalityProviderByBtcPk(ctx context.Context, btcPk string) (*model.FinalityProviderDetails, error) {
+// GetFinalityProviderHistory provides a mock function with given fields: ctx, btcPk
+func (_m *DbInterface) GetFinalityProviderHistory(ctx context.Context, btcPk string) ([]model.FinalityProviderHistoryRecord, error) {
 	ret := _m.Called(ctx, btcPk)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetFinalityProviderByBtcPk")
+		panic("no return value specified for GetFinalityProviderHistory")
 	}
 
-	var r0 *model.FinalityProviderDetails
+	var r0 []model.FinalityProviderHistoryRecord
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) (*model.FinalityProviderDetails, error)); ok {
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]model.FinalityProviderHistoryRecord, error)); ok {
 		return rf(ctx, btcPk)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string) *model.FinalityProviderDetails); ok {
+	if rf, ok := ret.Get(0).(func(context.Context, string) []model.FinalityProviderHistoryRecord); ok {
 		r0 = rf(ctx, btcPk)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*model.FinalityProviderDetails)
+			r0 = ret.Get(0).([]model.FinalityProviderHistoryRecord)
 		}
 	}
 
@@ -337,6 +798,36 @@ func (_m *DbInterface) GetNetworkInfo(ctx context.Context) (*model.NetworkInfo,
 	return r0, r1
 }
 
+// GetRejectedTransitions provides a mock function with given fields: ctx, stakingTxHashHex
+func (_m *DbInterface) GetRejectedTransitions(ctx context.Context, stakingTxHashHex string) ([]model.RejectedTransition, error) {
+	ret := _m.Called(ctx, stakingTxHashHex)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetRejectedTransitions")
+	}
+
+	var r0 []model.RejectedTransition
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]model.RejectedTransition, error)); ok {
+		return rf(ctx, stakingTxHashHex)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []model.RejectedTransition); ok {
+		r0 = rf(ctx, stakingTxHashHex)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]model.RejectedTransition)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, stakingTxHashHex)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetStakingParams provides a mock function with given fields: ctx, version
 func (_m *DbInterface) GetStakingParams(ctx context.Context, version uint32) (*bbnclient.StakingParams, error) {
 	ret := _m.Called(ctx, version)
@@ -367,6 +858,61 @@ func (_m *DbInterface) GetStakingParams(ctx context.Context, version uint32) (*b
 	return r0, r1
 }
 
+// GetUnlockProjection provides a mock function with given fields: ctx, id
+func (_m *DbInterface) GetUnlockProjection(ctx context.Context, id string) (*model.UnlockProjectionDocument, error) {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUnlockProjection")
+	}
+
+	var r0 *model.UnlockProjectionDocument
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*model.UnlockProjectionDocument, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *model.UnlockProjectionDocument); ok {
+		r0 = rf(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.UnlockProjectionDocument)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MarkExpiringSoonNotified provides a mock function with given fields: ctx, stakingTxHash, thresholds, opts
+func (_m *DbInterface) MarkExpiringSoonNotified(ctx context.Context, stakingTxHash string, thresholds []uint32, opts ...db.UpdateOption) error {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, stakingTxHash, thresholds)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for MarkExpiringSoonNotified")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, []uint32, ...db.UpdateOption) error); ok {
+		r0 = rf(ctx, stakingTxHash, thresholds, opts...)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Ping provides a mock function with given fields: ctx
 func (_m *DbInterface) Ping(ctx context.Context) error {
 	ret := _m.Called(ctx)
@@ -385,17 +931,100 @@ func (_m *DbInterface) Ping(ctx context.Context) error {
 	return r0
 }
 
-// SaveBTCDelegationCovenantSignature provides a mock function with given fields: ctx, stakingTxHash, covenantBtcPkHex, signatureHex, stakeExpansionSignatureHex
-func (_m *DbInterface) SaveBTCDelegationCovenantSignature(ctx context.Context, stakingTxHash string, covenantBtcPkHex string, signatureHex string, stakeExpansionSignatureHex string) error {
-	ret := _m.Called(ctx, stakingTxHash, covenantBtcPkHex, signatureHex, stakeExpansionSignatureHex)
+// ReplaceUnlockProjections provides a mock function with given fields: ctx, projections
+func (_m *DbInterface) ReplaceUnlockProjections(ctx context.Context, projections []*model.UnlockProjectionDocument) error {
+	ret := _m.Called(ctx, projections)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ReplaceUnlockProjections")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, []*model.UnlockProjectionDocument) error); ok {
+		r0 = rf(ctx, projections)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RevertProvisionalSpend provides a mock function with given fields: ctx, stakingTxHash, spendingTxHash
+func (_m *DbInterface) RevertProvisionalSpend(ctx context.Context, stakingTxHash string, spendingTxHash string) ([]model.ProvisionalSpend, error) {
+	ret := _m.Called(ctx, stakingTxHash, spendingTxHash)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RevertProvisionalSpend")
+	}
+
+	var r0 []model.ProvisionalSpend
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) ([]model.ProvisionalSpend, error)); ok {
+		return rf(ctx, stakingTxHash, spendingTxHash)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) []model.ProvisionalSpend); ok {
+		r0 = rf(ctx, stakingTxHash, spendingTxHash)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]model.ProvisionalSpend)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, stakingTxHash, spendingTxHash)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SaveAnomaly provides a mock function with given fields: ctx, anomaly
+func (_m *DbInterface) SaveAnomaly(ctx context.Context, anomaly *model.Anomaly) (bool, error) {
+	ret := _m.Called(ctx, anomaly)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveAnomaly")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.Anomaly) (bool, error)); ok {
+		return rf(ctx, anomaly)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *model.Anomaly) bool); ok {
+		r0 = rf(ctx, anomaly)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *model.Anomaly) error); ok {
+		r1 = rf(ctx, anomaly)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SaveBTCDelegationCovenantSignature provides a mock function with given fields: ctx, stakingTxHash, covenantBtcPkHex, signatureHex, stakeExpansionSignatureHex, bbnTx, opts
+func (_m *DbInterface) SaveBTCDelegationCovenantSignature(ctx context.Context, stakingTxHash string, covenantBtcPkHex string, signatureHex string, stakeExpansionSignatureHex string, bbnTx *model.BbnTx, opts ...db.UpdateOption) error {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, stakingTxHash, covenantBtcPkHex, signatureHex, stakeExpansionSignatureHex, bbnTx)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
 
 	if len(ret) == 0 {
 		panic("no return value specified for SaveBTCDelegationCovenantSignature")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, string) error); ok {
-		r0 = rf(ctx, stakingTxHash, covenantBtcPkHex, signatureHex, stakeExpansionSignatureHex)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, string, *model.BbnTx, ...db.UpdateOption) error); ok {
+		r0 = rf(ctx, stakingTxHash, covenantBtcPkHex, signatureHex, stakeExpansionSignatureHex, bbnTx, opts...)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -421,6 +1050,24 @@ func (_m *DbInterface) SaveCheckpointParams(ctx context.Context, params *bbnclie
 	return r0
 }
 
+// SaveFinalityProviderHistory provides a mock function with given fields: ctx, record
+func (_m *DbInterface) SaveFinalityProviderHistory(ctx context.Context, record *model.FinalityProviderHistoryRecord) error {
+	ret := _m.Called(ctx, record)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveFinalityProviderHistory")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.FinalityProviderHistoryRecord) error); ok {
+		r0 = rf(ctx, record)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // SaveNewBTCDelegation provides a mock function with given fields: ctx, delegationDoc
 func (_m *DbInterface) SaveNewBTCDelegation(ctx context.Context, delegationDoc *model.BTCDelegationDetails) error {
 	ret := _m.Called(ctx, delegationDoc)
@@ -475,6 +1122,49 @@ func (_m *DbInterface) SaveNewTimeLockExpire(ctx context.Context, stakingTxHashH
 	return r0
 }
 
+// SaveProvisionalSpend provides a mock function with given fields: ctx, stakingTxHash, spend, opts
+func (_m *DbInterface) SaveProvisionalSpend(ctx context.Context, stakingTxHash string, spend model.ProvisionalSpend, opts ...db.UpdateOption) error {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, stakingTxHash, spend)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveProvisionalSpend")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, model.ProvisionalSpend, ...db.UpdateOption) error); ok {
+		r0 = rf(ctx, stakingTxHash, spend, opts...)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SaveRejectedTransition provides a mock function with given fields: ctx, rejected
+func (_m *DbInterface) SaveRejectedTransition(ctx context.Context, rejected *model.RejectedTransition) error {
+	ret := _m.Called(ctx, rejected)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveRejectedTransition")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.RejectedTransition) error); ok {
+		r0 = rf(ctx, rejected)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // SaveStakingParams provides a mock function with given fields: ctx, version, params
 func (_m *DbInterface) SaveStakingParams(ctx context.Context, version uint32, params *bbnclient.StakingParams) error {
 	ret := _m.Called(ctx, version, params)
@@ -493,24 +1183,49 @@ func (_m *DbInterface) SaveStakingParams(ctx context.Context, version uint32, pa
 	return r0
 }
 
-// UpdateBTCDelegationState provides a mock function with given fields: ctx, stakingTxHash, qualifiedPreviousStates, newState, opts
-func (_m *DbInterface) UpdateBTCDelegationState(ctx context.Context, stakingTxHash string, qualifiedPreviousStates []types.DelegationState, newState types.DelegationState, opts ...db.UpdateOption) error {
+// SetNextStakingTxHash provides a mock function with given fields: ctx, stakingTxHash, nextStakingTxHash, opts
+func (_m *DbInterface) SetNextStakingTxHash(ctx context.Context, stakingTxHash string, nextStakingTxHash string, opts ...db.UpdateOption) error {
 	_va := make([]interface{}, len(opts))
 	for _i := range opts {
 		_va[_i] = opts[_i]
 	}
 	var _ca []interface{}
-	_ca = append(_ca, ctx, stakingTxHash, qualifiedPreviousStates, newState)
+	_ca = append(_ca, ctx, stakingTxHash, nextStakingTxHash)
 	_ca = append(_ca, _va...)
 	ret := _m.Called(_ca...)
 
 	if len(ret) == 0 {
-		panic("no return value specified for UpdateBTCDelegationState")
+		panic("no return value specified for SetNextStakingTxHash")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, ...db.UpdateOption) error); ok {
+		r0 = rf(ctx, stakingTxHash, nextStakingTxHash, opts...)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetUnbondingTxDetails provides a mock function with given fields: ctx, stakingTxHash, details, opts
+func (_m *DbInterface) SetUnbondingTxDetails(ctx context.Context, stakingTxHash string, details *model.BtcSpendDetails, opts ...db.UpdateOption) error {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, stakingTxHash, details)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetUnbondingTxDetails")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, []types.DelegationState, types.DelegationState, ...db.UpdateOption) error); ok {
-		r0 = rf(ctx, stakingTxHash, qualifiedPreviousStates, newState, opts...)
+	if rf, ok := ret.Get(0).(func(context.Context, string, *model.BtcSpendDetails, ...db.UpdateOption) error); ok {
+		r0 = rf(ctx, stakingTxHash, details, opts...)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -518,17 +1233,24 @@ func (_m *DbInterface) UpdateBTCDelegationState(ctx context.Context, stakingTxHa
 	return r0
 }
 
-// UpdateDelegationStakerBabylonAddress provides a mock function with given fields: ctx, stakingTxHash, stakerBabylonAddress
-func (_m *DbInterface) UpdateDelegationStakerBabylonAddress(ctx context.Context, stakingTxHash string, stakerBabylonAddress string) error {
-	ret := _m.Called(ctx, stakingTxHash, stakerBabylonAddress)
+// UpdateBTCDelegationState provides a mock function with given fields: ctx, stakingTxHash, trigger, newState, opts
+func (_m *DbInterface) UpdateBTCDelegationState(ctx context.Context, stakingTxHash string, trigger types.Trigger, newState types.DelegationState, opts ...db.UpdateOption) error {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, stakingTxHash, trigger, newState)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
 
 	if len(ret) == 0 {
-		panic("no return value specified for UpdateDelegationStakerBabylonAddress")
+		panic("no return value specified for UpdateBTCDelegationState")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
-		r0 = rf(ctx, stakingTxHash, stakerBabylonAddress)
+	if rf, ok := ret.Get(0).(func(context.Context, string, types.Trigger, types.DelegationState, ...db.UpdateOption) error); ok {
+		r0 = rf(ctx, stakingTxHash, trigger, newState, opts...)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -590,6 +1312,49 @@ func (_m *DbInterface) UpdateLastProcessedBbnHeight(ctx context.Context, height
 	return r0
 }
 
+// UpdateStakingTxConfirmation provides a mock function with given fields: ctx, stakingTxHash, confirmation, opts
+func (_m *DbInterface) UpdateStakingTxConfirmation(ctx context.Context, stakingTxHash string, confirmation *model.StakingTxConfirmation, opts ...db.UpdateOption) error {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, stakingTxHash, confirmation)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateStakingTxConfirmation")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, *model.StakingTxConfirmation, ...db.UpdateOption) error); ok {
+		r0 = rf(ctx, stakingTxHash, confirmation, opts...)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpsertFinalityProviderStats provides a mock function with given fields: ctx, fpBtcPkHex, activeTvl, activeDelegations
+func (_m *DbInterface) UpsertFinalityProviderStats(ctx context.Context, fpBtcPkHex string, activeTvl uint64, activeDelegations uint64) error {
+	ret := _m.Called(ctx, fpBtcPkHex, activeTvl, activeDelegations)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpsertFinalityProviderStats")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, uint64, uint64) error); ok {
+		r0 = rf(ctx, fpBtcPkHex, activeTvl, activeDelegations)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // UpsertNetworkInfo provides a mock function with given fields: ctx, networkInfo
 func (_m *DbInterface) UpsertNetworkInfo(ctx context.Context, networkInfo *model.NetworkInfo) error {
 	ret := _m.Called(ctx, networkInfo)
@@ -608,6 +1373,24 @@ func (_m *DbInterface) UpsertNetworkInfo(ctx context.Context, networkInfo *model
 	return r0
 }
 
+// UpsertOverallStats provides a mock function with given fields: ctx, activeTvl, activeDelegations
+func (_m *DbInterface) UpsertOverallStats(ctx context.Context, activeTvl uint64, activeDelegations uint64) error {
+	ret := _m.Called(ctx, activeTvl, activeDelegations)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpsertOverallStats")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, uint64) error); ok {
+		r0 = rf(ctx, activeTvl, activeDelegations)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // NewDbInterface creates a new instance of DbInterface. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewDbInterface(t interface {
@@ -3,6 +3,7 @@
 package mocks
 
 import (
+	consumer "github.com/babylonlabs-io/babylon-staking-indexer/consumer"
 	client "github.com/babylonlabs-io/staking-queue-client/client"
 
 	context "context"
@@ -33,6 +34,24 @@ func (_m *EventConsumer) PushActiveStakingEvent(ctx context.Context, ev *client.
 	return r0
 }
 
+// PushExpiringSoonStakingEvent provides a mock function with given fields: ctx, ev
+func (_m *EventConsumer) PushExpiringSoonStakingEvent(ctx context.Context, ev *consumer.ExpiringSoonStakingEvent) error {
+	ret := _m.Called(ctx, ev)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PushExpiringSoonStakingEvent")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *consumer.ExpiringSoonStakingEvent) error); ok {
+		r0 = rf(ctx, ev)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // PushUnbondingStakingEvent provides a mock function with given fields: ctx, ev
 func (_m *EventConsumer) PushUnbondingStakingEvent(ctx context.Context, ev *client.StakingEvent) error {
 	ret := _m.Called(ctx, ev)
@@ -0,0 +1,112 @@
+//go:build integration
+
+package testutil
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	mongoUsername     = "user"
+	mongoPassword     = "password"
+	mongoDatabaseName = "test-database"
+
+	// this version corresponds to docker tag for mongodb
+	// it should be in sync with mongo version used in production
+	mongoVersion = "7.0.5"
+)
+
+// SetupMongoContainer setups container with mongodb returning db credentials through config.DbConfig, cleanup function
+// and an error if any. Cleanup function MUST be called in the end to cleanup docker resources
+func SetupMongoContainer() (*config.DbConfig, func(), error) {
+	pool, err := dockertest.NewPool("")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// generate random string for container name
+	randomString, err := RandomAlphaNum(3)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// there can be only 1 container with the same name, so we add
+	// random string in the end in case there is still old container running
+	containerName := "mongo-integration-tests-db-" + randomString
+	resource, err := pool.RunWithOptions(&dockertest.RunOptions{
+		Name:       containerName,
+		Repository: "mongo",
+		Tag:        mongoVersion,
+		Env: []string{
+			"MONGO_INITDB_ROOT_USERNAME=" + mongoUsername,
+			"MONGO_INITDB_ROOT_PASSWORD=" + mongoPassword,
+			"MONGO_INITDB_DATABASE=" + mongoDatabaseName,
+		},
+	}, func(config *docker.HostConfig) {
+		config.AutoRemove = true
+		config.RestartPolicy = docker.RestartPolicy{
+			Name: "no",
+		}
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cleanup := func() {
+		err := pool.Purge(resource)
+		if err != nil {
+			log.Fatalf("failed to purge resource: %v", err)
+		}
+	}
+
+	// get host port (randomly chosen) that is mapped to mongo port inside container
+	hostPort := resource.GetPort("27017/tcp")
+
+	return &config.DbConfig{
+		Username: mongoUsername,
+		Password: mongoPassword,
+		DbName:   mongoDatabaseName,
+		Address:  fmt.Sprintf("mongodb://localhost:%s/", hostPort),
+	}, cleanup, nil
+}
+
+// ConnectMongo returns mongo database of the config, used for preparing/cleaning data
+func ConnectMongo(cfg *config.DbConfig) (*mongo.Database, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	credential := options.Credential{
+		Username: cfg.Username,
+		Password: cfg.Password,
+	}
+	clientOps := options.Client().ApplyURI(cfg.Address).SetAuth(credential)
+	client, err := mongo.Connect(ctx, clientOps)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.Database(cfg.DbName), nil
+}
+
+// ResetCollections deletes all documents of the collections
+func ResetCollections(t *testing.T, db *mongo.Database, collections ...string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for _, collection := range collections {
+		_, err := db.Collection(collection).DeleteMany(ctx, bson.M{})
+		require.NoError(t, err)
+	}
+}